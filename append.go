@@ -0,0 +1,95 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+// AppendOption configures a write made with Client.AppendToStream.
+type AppendOption func(*appendOptions)
+
+// appendOptions holds the configuration of a write.
+type appendOptions struct {
+	expectedVersion ExpectedVersion
+	contentType     string
+	headers         map[string]string
+	recreate        bool
+}
+
+// WithExpectedVersion makes the write conditional on the version of the stream,
+// which is an event number or one of the named versions such as ExpectNoStream.
+// By default the write is not conditional.
+func WithExpectedVersion(version ExpectedVersion) AppendOption {
+	return func(o *appendOptions) {
+		o.expectedVersion = version
+	}
+}
+
+// WithContentType serializes the data and metadata of the events with the codec
+// registered on the client for the content type. By default events are
+// serialized as JSON. See Client.RegisterCodec.
+func WithContentType(contentType string) AppendOption {
+	return func(o *appendOptions) {
+		o.contentType = contentType
+	}
+}
+
+// WithHeaders adds headers to the write request, such as ES-RequireMaster.
+// Headers set on the client with SetHeader are also sent. The Content-Type and
+// ES-ExpectedVersion headers are set by the client and cannot be overridden.
+func WithHeaders(headers map[string]string) AppendOption {
+	return func(o *appendOptions) {
+		if o.headers == nil {
+			o.headers = make(map[string]string)
+		}
+		for k, v := range headers {
+			o.headers[k] = v
+		}
+	}
+}
+
+// WithRequireMaster requires the write to be handled by the master of the
+// cluster. See Client.SetRequireMaster.
+func WithRequireMaster() AppendOption {
+	return WithHeaders(map[string]string{"ES-RequireMaster": "True"})
+}
+
+// WithRecreateSoftDeleted treats a soft deleted stream as a stream that does
+// not exist when the expected version is ExpectNoStream, so that the write
+// recreates the stream. See StreamWriter.SetRecreateSoftDeleted.
+func WithRecreateSoftDeleted() AppendOption {
+	return func(o *appendOptions) {
+		o.recreate = true
+	}
+}
+
+// AppendToStream appends events to a stream and returns a *WriteResult
+// describing the write.
+//
+// It is a convenience for writes that do not need a StreamWriter. The write is
+// configured with options.
+//
+//	result, err := client.AppendToStream("order-1", events,
+//		goes.WithExpectedVersion(3),
+//		goes.WithRequireMaster())
+//
+// If the expected version does not match an *ErrConcurrencyViolation is
+// returned. If the stream has been hard deleted an *ErrStreamHardDeleted is
+// returned.
+func (c *Client) AppendToStream(stream string, events []*Event, opts ...AppendOption) (*WriteResult, error) {
+	o := &appendOptions{expectedVersion: ExpectAny}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	w := c.NewStreamWriter(stream)
+	if o.contentType != "" {
+		codec, err := c.codec(o.contentType)
+		if err != nil {
+			return nil, err
+		}
+		w.SetCodec(codec)
+	}
+	w.SetRecreateSoftDeleted(o.recreate)
+	return w.appendWithResult(o.expectedVersion, events, o.headers)
+}
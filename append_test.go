@@ -0,0 +1,80 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&AppendSuite{})
+
+type AppendSuite struct{}
+
+func (s *AppendSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *AppendSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *AppendSuite) TestAppendToStreamWithOptions(c *C) {
+	stream := "append-options"
+	var got *http.Request
+	var body []map[string]interface{}
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		c.Assert(json.NewDecoder(r.Body).Decode(&body), IsNil)
+		w.Header().Set("Location", server.URL+"/streams/"+stream+"/4")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	result, err := client.AppendToStream(stream,
+		[]*Event{NewEvent("", "Foo", "raw data", nil)},
+		WithExpectedVersion(3),
+		WithContentType("application/octet-stream"),
+		WithHeaders(map[string]string{"ES-RequireMaster": "True"}),
+	)
+	c.Assert(err, IsNil)
+	c.Assert(result.NextExpectedVersion, Equals, 4)
+
+	c.Assert(got.Header.Get("ES-ExpectedVersion"), Equals, "3")
+	c.Assert(got.Header.Get("ES-RequireMaster"), Equals, "True")
+	c.Assert(got.Header.Get("Content-Type"), Equals, "application/vnd.eventstore.events+json")
+	c.Assert(body, HasLen, 1)
+	meta := body[0]["metadata"].(map[string]interface{})
+	c.Assert(meta[ContentTypeMetaDataKey], Equals, "application/octet-stream")
+}
+
+func (s *AppendSuite) TestAppendToStreamDefaults(c *C) {
+	stream := "append-defaults"
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Header.Get("ES-ExpectedVersion"), Equals, "")
+		w.Header().Set("Location", server.URL+"/streams/"+stream+"/0")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	result, err := client.AppendToStream(stream, []*Event{NewEvent("", "Foo", &FooEvent{}, nil)})
+	c.Assert(err, IsNil)
+	c.Assert(result.NextExpectedVersion, Equals, 0)
+}
+
+func (s *AppendSuite) TestAppendToStreamReturnsConcurrencyViolation(c *C) {
+	stream := "append-conflict"
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	_, err := client.AppendToStream(stream, []*Event{NewEvent("", "Foo", &FooEvent{}, nil)}, WithExpectedVersion(0))
+	c.Assert(err, FitsTypeOf, &ErrConcurrencyViolation{})
+}
+
+func (s *AppendSuite) TestAppendToStreamUnknownContentType(c *C) {
+	_, err := client.AppendToStream("append-unknown", []*Event{NewEvent("", "Foo", &FooEvent{}, nil)}, WithContentType("application/x-unknown"))
+	c.Assert(err, NotNil)
+}
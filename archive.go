@@ -0,0 +1,415 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ArchiveFormatVersion is the version of the archive format written by
+	// ExportArchive.
+	ArchiveFormatVersion = 1
+
+	// ArchiveManifestFile is the name of the manifest file of an archive.
+	ArchiveManifestFile = "manifest.json"
+
+	// archiveCompression is the compression of the chunks written by
+	// ExportArchive.
+	archiveCompression = "zstd"
+
+	// defaultArchiveChunkSize is the number of events in a chunk.
+	defaultArchiveChunkSize = 10000
+)
+
+// ArchiveManifest describes the contents of an archive.
+//
+// An archive is a directory holding the manifest and a number of chunk files.
+// Each chunk holds a contiguous range of the events of one stream as
+// newline delimited JSON, one record per event, compressed as given by
+// Compression. The manifest records the streams in the archive and, for each
+// chunk, the range of event numbers it holds, the number of events and the
+// SHA-256 hash of its uncompressed content, so that an archive can be verified
+// before it is restored.
+type ArchiveManifest struct {
+	FormatVersion int             `json:"formatVersion"`
+	Compression   string          `json:"compression"`
+	Created       time.Time       `json:"created"`
+	Streams       []ArchiveStream `json:"streams"`
+}
+
+// ArchiveStream describes the events of a stream in an archive.
+type ArchiveStream struct {
+	Stream string         `json:"stream"`
+	Count  int            `json:"count"`
+	Chunks []ArchiveChunk `json:"chunks"`
+}
+
+// ArchiveChunk describes a chunk file in an archive.
+//
+// From and To are the event numbers of the first and last events in the
+// chunk.
+type ArchiveChunk struct {
+	File   string `json:"file"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Count  int    `json:"count"`
+	SHA256 string `json:"sha256"`
+}
+
+// archiveRecord is a line of a chunk file.
+//
+// JSON data and metadata are written as compacted JSON so that chunks can be
+// inspected with ordinary tools. Other content is written base64 encoded.
+type archiveRecord struct {
+	EventID             string          `json:"eventId"`
+	EventType           string          `json:"eventType"`
+	EventNumber         int             `json:"eventNumber"`
+	Stream              string          `json:"stream"`
+	ContentType         string          `json:"contentType"`
+	Data                json.RawMessage `json:"data,omitempty"`
+	DataBase64          []byte          `json:"dataBase64,omitempty"`
+	MetaDataContentType string          `json:"metaDataContentType"`
+	MetaData            json.RawMessage `json:"metadata,omitempty"`
+	MetaDataBase64      []byte          `json:"metadataBase64,omitempty"`
+}
+
+func newArchiveRecord(e *RawEvent) *archiveRecord {
+	r := &archiveRecord{
+		EventID:             e.EventID,
+		EventType:           e.EventType,
+		EventNumber:         e.EventNumber,
+		Stream:              e.Stream,
+		ContentType:         e.ContentType,
+		MetaDataContentType: e.MetaDataContentType,
+	}
+	if e.ContentType == "application/json" && json.Valid(e.Data) {
+		r.Data = json.RawMessage(e.Data)
+	} else {
+		r.DataBase64 = e.Data
+	}
+	if e.MetaDataContentType == "application/json" && json.Valid(e.MetaData) {
+		r.MetaData = json.RawMessage(e.MetaData)
+	} else {
+		r.MetaDataBase64 = e.MetaData
+	}
+	return r
+}
+
+func (r *archiveRecord) rawEvent() *RawEvent {
+	e := &RawEvent{
+		EventID:             r.EventID,
+		EventType:           r.EventType,
+		EventNumber:         r.EventNumber,
+		Stream:              r.Stream,
+		ContentType:         r.ContentType,
+		Data:                r.DataBase64,
+		MetaDataContentType: r.MetaDataContentType,
+		MetaData:            r.MetaDataBase64,
+	}
+	if r.Data != nil {
+		e.Data = []byte(r.Data)
+	}
+	if r.MetaData != nil {
+		e.MetaData = []byte(r.MetaData)
+	}
+	return e
+}
+
+// ExportArchive writes the events of the streams to an archive in the
+// directory dir, which is created if it does not exist, and returns the
+// manifest of the archive.
+//
+// Each chunk holds up to chunkSize events. If chunkSize is 0 or below a chunk
+// holds up to 10000 events. Streams that do not exist are recorded in the
+// manifest with no events. The manifest is written last, so a directory
+// without a manifest holds an incomplete export.
+func (c *Client) ExportArchive(dir string, streams []string, chunkSize int) (*ArchiveManifest, error) {
+	if chunkSize <= 0 {
+		chunkSize = defaultArchiveChunkSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	m := &ArchiveManifest{
+		FormatVersion: ArchiveFormatVersion,
+		Compression:   archiveCompression,
+		Created:       time.Now().UTC(),
+		Streams:       []ArchiveStream{},
+	}
+
+	files := 0
+	for _, stream := range streams {
+		as := ArchiveStream{Stream: stream, Chunks: []ArchiveChunk{}}
+
+		var w *chunkWriter
+		flush := func() error {
+			if w == nil {
+				return nil
+			}
+			chunk, err := w.close()
+			if err != nil {
+				return err
+			}
+			as.Chunks = append(as.Chunks, chunk)
+			w = nil
+			return nil
+		}
+
+		err := c.ForEachEvent(stream, 0, "forward", func(er *EventResponse) error {
+			e, err := NewRawEvent(er)
+			if err != nil {
+				return err
+			}
+			if w == nil {
+				files++
+				w, err = newChunkWriter(dir, fmt.Sprintf("chunk-%06d.ndjson.zst", files))
+				if err != nil {
+					return err
+				}
+			}
+			if err := w.write(newArchiveRecord(e)); err != nil {
+				return err
+			}
+			as.Count++
+			if w.count >= chunkSize {
+				return flush()
+			}
+			return nil
+		})
+		if _, ok := err.(*ErrNotFound); ok {
+			err = nil
+		}
+		if err == nil {
+			err = flush()
+		} else if w != nil {
+			w.close()
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.Streams = append(m.Streams, as)
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileSync(filepath.Join(dir, ArchiveManifestFile), b); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// chunkWriter writes a chunk file, hashing its uncompressed content.
+type chunkWriter struct {
+	name  string
+	f     *os.File
+	zw    *zstd.Encoder
+	hash  hash.Hash
+	enc   *json.Encoder
+	count int
+	from  int
+	to    int
+}
+
+func newChunkWriter(dir, name string) (*chunkWriter, error) {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	h := sha256.New()
+	return &chunkWriter{
+		name: name,
+		f:    f,
+		zw:   zw,
+		hash: h,
+		enc:  json.NewEncoder(io.MultiWriter(zw, h)),
+	}, nil
+}
+
+func (w *chunkWriter) write(r *archiveRecord) error {
+	if w.count == 0 {
+		w.from = r.EventNumber
+	}
+	w.to = r.EventNumber
+	w.count++
+	return w.enc.Encode(r)
+}
+
+func (w *chunkWriter) close() (ArchiveChunk, error) {
+	err := w.zw.Close()
+	if err == nil {
+		err = w.f.Sync()
+	}
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return ArchiveChunk{
+		File:   w.name,
+		From:   w.from,
+		To:     w.to,
+		Count:  w.count,
+		SHA256: hex.EncodeToString(w.hash.Sum(nil)),
+	}, err
+}
+
+func writeFileSync(name string, b []byte) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadArchiveManifest reads the manifest of the archive in the directory dir.
+func ReadArchiveManifest(dir string) (*ArchiveManifest, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ArchiveManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	m := &ArchiveManifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, &ErrArchiveCorrupt{File: ArchiveManifestFile, Reason: err.Error()}
+	}
+	if m.FormatVersion != ArchiveFormatVersion {
+		return nil, &ErrArchiveCorrupt{
+			File:   ArchiveManifestFile,
+			Reason: fmt.Sprintf("unsupported format version %d", m.FormatVersion),
+		}
+	}
+	if m.Compression != archiveCompression {
+		return nil, &ErrArchiveCorrupt{
+			File:   ArchiveManifestFile,
+			Reason: fmt.Sprintf("unsupported compression %s", m.Compression),
+		}
+	}
+	return m, nil
+}
+
+// VerifyArchive checks that the archive in the directory dir matches its
+// manifest and returns the manifest.
+//
+// Every chunk is read in full. The hash and the number of events of each chunk
+// must match the manifest, the events must belong to the stream of the chunk
+// and their event numbers must increase through the range of the chunk. If the
+// archive does not match an *ErrArchiveCorrupt is returned.
+func VerifyArchive(dir string) (*ArchiveManifest, error) {
+	return readArchive(dir, nil)
+}
+
+// ReadArchive reads the archive in the directory dir and calls fn for each
+// event, stream by stream in the order of the manifest and in stream order
+// within each stream.
+//
+// Each chunk is checked against the manifest when it has been read, after fn
+// has been called for its events. Importers should verify the archive with
+// VerifyArchive before they write any events.
+func ReadArchive(dir string, fn func(*RawEvent) error) error {
+	_, err := readArchive(dir, fn)
+	return err
+}
+
+func readArchive(dir string, fn func(*RawEvent) error) (*ArchiveManifest, error) {
+	m, err := ReadArchiveManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range m.Streams {
+		count := 0
+		for _, chunk := range s.Chunks {
+			if err := readChunk(dir, s.Stream, chunk, fn); err != nil {
+				return nil, err
+			}
+			count += chunk.Count
+		}
+		if count != s.Count {
+			return nil, &ErrArchiveCorrupt{
+				File:   ArchiveManifestFile,
+				Reason: fmt.Sprintf("stream %s has %d events in its chunks and a count of %d", s.Stream, count, s.Count),
+			}
+		}
+	}
+	return m, nil
+}
+
+func readChunk(dir, stream string, chunk ArchiveChunk, fn func(*RawEvent) error) error {
+	corrupt := func(format string, args ...interface{}) error {
+		return &ErrArchiveCorrupt{File: chunk.File, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	f, err := os.Open(filepath.Join(dir, chunk.File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return corrupt("%v", err)
+	}
+	defer zr.Close()
+
+	h := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(zr, h))
+	scanner.Buffer(nil, 64<<20)
+
+	count, last := 0, -1
+	for scanner.Scan() {
+		r := &archiveRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			return corrupt("line %d: %v", count+1, err)
+		}
+		if r.Stream != stream {
+			return corrupt("event %d belongs to stream %s", r.EventNumber, r.Stream)
+		}
+		if r.EventNumber < chunk.From || r.EventNumber > chunk.To || r.EventNumber <= last {
+			return corrupt("event %d is out of order or outside the range %d to %d", r.EventNumber, chunk.From, chunk.To)
+		}
+		last = r.EventNumber
+		count++
+		if fn != nil {
+			if err := fn(r.rawEvent()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return corrupt("%v", err)
+	}
+	if count != chunk.Count {
+		return corrupt("holds %d events, the manifest records %d", count, chunk.Count)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != chunk.SHA256 {
+		return corrupt("hash %s does not match the manifest", sum)
+	}
+	return nil
+}
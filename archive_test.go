@@ -0,0 +1,173 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ArchiveSuite{})
+
+type ArchiveSuite struct{}
+
+func (s *ArchiveSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *ArchiveSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// exportTestArchive exports a stream of five events and a missing stream in
+// chunks of two events.
+func exportTestArchive(c *C) (string, []*Event) {
+	es := CreateTestEvents(5, "archived", server.URL, "Foo")
+	setupSimulator(es, nil)
+	mux.HandleFunc("/streams/missing/", http.NotFound)
+
+	dir := filepath.Join(c.MkDir(), "archive")
+	m, err := client.ExportArchive(dir, []string{"archived", "missing"}, 2)
+	c.Assert(err, IsNil)
+	c.Assert(m.Streams, HasLen, 2)
+	return dir, es
+}
+
+func (s *ArchiveSuite) TestExportArchiveWritesManifest(c *C) {
+	dir, _ := exportTestArchive(c)
+
+	m, err := ReadArchiveManifest(dir)
+	c.Assert(err, IsNil)
+	c.Assert(m.FormatVersion, Equals, ArchiveFormatVersion)
+	c.Assert(m.Compression, Equals, "zstd")
+
+	archived := m.Streams[0]
+	c.Assert(archived.Stream, Equals, "archived")
+	c.Assert(archived.Count, Equals, 5)
+	c.Assert(archived.Chunks, HasLen, 3)
+	ranges := [][3]int{}
+	for _, chunk := range archived.Chunks {
+		ranges = append(ranges, [3]int{chunk.From, chunk.To, chunk.Count})
+		c.Assert(chunk.SHA256, HasLen, 64)
+	}
+	c.Assert(ranges, DeepEquals, [][3]int{{0, 1, 2}, {2, 3, 2}, {4, 4, 1}})
+
+	// Chunks are zstd frames.
+	b, err := ioutil.ReadFile(filepath.Join(dir, archived.Chunks[0].File))
+	c.Assert(err, IsNil)
+	c.Assert(archived.Chunks[0].File, Equals, "chunk-000001.ndjson.zst")
+	c.Assert(bytes.HasPrefix(b, []byte{0x28, 0xb5, 0x2f, 0xfd}), Equals, true)
+
+	c.Assert(m.Streams[1], DeepEquals, ArchiveStream{Stream: "missing", Chunks: []ArchiveChunk{}})
+}
+
+func (s *ArchiveSuite) TestVerifyAndReadArchive(c *C) {
+	dir, es := exportTestArchive(c)
+
+	_, err := VerifyArchive(dir)
+	c.Assert(err, IsNil)
+
+	got := []*RawEvent{}
+	err = ReadArchive(dir, func(e *RawEvent) error {
+		got = append(got, e)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 5)
+	for i, e := range got {
+		want, err := NewRawEvent(&EventResponse{Event: es[i]})
+		c.Assert(err, IsNil)
+		c.Assert(e.EventID, Equals, want.EventID)
+		c.Assert(e.EventNumber, Equals, i)
+		c.Assert(e.ContentType, Equals, "application/json")
+		// JSON is compacted so that each record is a single line.
+		var compact bytes.Buffer
+		c.Assert(json.Compact(&compact, want.Data), IsNil)
+		c.Assert(e.Data, DeepEquals, compact.Bytes())
+	}
+}
+
+func (s *ArchiveSuite) TestArchivePreservesEncodedContent(c *C) {
+	payload := []byte{0x00, 0xff, 0x10}
+	er := appendAndCaptureWithMetaData(c, RawCodec{}, JSONCodec{}, NewEvent("", "Binary", payload, map[string]interface{}{"k": "v"}))
+	raw, err := NewRawEvent(er)
+	c.Assert(err, IsNil)
+
+	r := newArchiveRecord(raw)
+	c.Assert(r.Data, IsNil)
+	c.Assert(r.DataBase64, DeepEquals, payload)
+	c.Assert(string(r.MetaData), Equals, `{"k":"v"}`)
+	c.Assert(r.rawEvent(), DeepEquals, raw)
+}
+
+func (s *ArchiveSuite) TestVerifyArchiveDetectsModifiedChunk(c *C) {
+	dir, _ := exportTestArchive(c)
+	m, err := ReadArchiveManifest(dir)
+	c.Assert(err, IsNil)
+	name := m.Streams[0].Chunks[1].File
+
+	// The chunk is replaced with a valid chunk holding the events of another.
+	other, err := ioutil.ReadFile(filepath.Join(dir, m.Streams[0].Chunks[0].File))
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, name), other, 0644), IsNil)
+
+	_, err = VerifyArchive(dir)
+	c.Assert(err, FitsTypeOf, &ErrArchiveCorrupt{})
+	c.Assert(err.(*ErrArchiveCorrupt).File, Equals, name)
+}
+
+func (s *ArchiveSuite) TestVerifyArchiveDetectsTruncatedChunk(c *C) {
+	dir, _ := exportTestArchive(c)
+	m, err := ReadArchiveManifest(dir)
+	c.Assert(err, IsNil)
+	name := m.Streams[0].Chunks[0].File
+
+	f, err := os.Create(filepath.Join(dir, name))
+	c.Assert(err, IsNil)
+	zw, err := zstd.NewWriter(f)
+	c.Assert(err, IsNil)
+	zw.Close()
+	f.Close()
+
+	_, err = VerifyArchive(dir)
+	c.Assert(err, DeepEquals, &ErrArchiveCorrupt{File: name, Reason: "holds 0 events, the manifest records 2"})
+}
+
+func (s *ArchiveSuite) TestVerifyArchiveDetectsTamperedHash(c *C) {
+	dir, _ := exportTestArchive(c)
+	m, err := ReadArchiveManifest(dir)
+	c.Assert(err, IsNil)
+
+	// Changing the hash in the manifest must be detected as well.
+	m.Streams[0].Chunks[2].SHA256 = "00"
+	writeManifest(c, dir, m)
+
+	_, err = VerifyArchive(dir)
+	c.Assert(err, FitsTypeOf, &ErrArchiveCorrupt{})
+}
+
+func (s *ArchiveSuite) TestReadArchiveManifestRejectsUnknownFormat(c *C) {
+	dir, _ := exportTestArchive(c)
+	m, err := ReadArchiveManifest(dir)
+	c.Assert(err, IsNil)
+	m.FormatVersion = 99
+	writeManifest(c, dir, m)
+
+	_, err = ReadArchiveManifest(dir)
+	c.Assert(err, FitsTypeOf, &ErrArchiveCorrupt{})
+}
+
+func writeManifest(c *C, dir string, m *ArchiveManifest) {
+	b, err := json.MarshalIndent(m, "", "  ")
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, ArchiveManifestFile), b, 0644), IsNil)
+}
@@ -0,0 +1,316 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAsyncBatchSize is the number of events an AsyncWriter buffers for
+	// a stream before writing them.
+	defaultAsyncBatchSize = 100
+
+	// defaultAsyncInterval is the longest an AsyncWriter buffers events for a
+	// stream before writing them.
+	defaultAsyncInterval = 50 * time.Millisecond
+)
+
+// AppendFuture is the result of an append made with an AsyncWriter, which is
+// available once the events have been written.
+type AppendFuture struct {
+	done   chan struct{}
+	result *WriteResult
+	err    error
+}
+
+// Done returns a channel that is closed when the append has completed.
+func (f *AppendFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the append to complete and returns a *WriteResult describing
+// the events of the append, or the error that prevented them being written.
+func (f *AppendFuture) Wait() (*WriteResult, error) {
+	<-f.done
+	return f.result, f.err
+}
+
+// asyncAppend is an append buffered by an AsyncWriter.
+type asyncAppend struct {
+	events []*Event
+	future *AppendFuture
+}
+
+// asyncStream buffers the appends to a single stream. The appends are written
+// by a goroutine that runs while there are appends buffered.
+type asyncStream struct {
+	stream  string
+	writer  *StreamWriter
+	pending []*asyncAppend
+	count   int
+	flush   chan struct{}
+}
+
+// AsyncWriter buffers the events appended to streams and writes them in
+// batches.
+//
+// The events appended to a stream are buffered until the batch size is reached
+// or the interval has passed since the first event was buffered, and are then
+// written with as few requests as possible. The events of a single append are
+// always written in the same request, so they are written atomically, and
+// appends to the same stream are written in the order they were made. Appends
+// to different streams are written concurrently.
+//
+// Append returns an *AppendFuture for the result of the write. A completion
+// handler can also be set to be called with the result of each request, and
+// failed requests are reported on the Errors channel. The
+// writes are not conditional on the version of the stream. If a request fails
+// every append in it fails with the error, and the appends that follow are
+// still written.
+//
+// An AsyncWriter is safe for concurrent use.
+type AsyncWriter struct {
+	client    *Client
+	mu        sync.Mutex
+	streams   map[string]*asyncStream
+	batchSize int
+	interval  time.Duration
+	completed func(stream string, events []*Event, result *WriteResult, err error)
+	closed    bool
+	wg        sync.WaitGroup
+	reporter  ErrorReporter
+}
+
+// NewAsyncWriter returns a new *AsyncWriter.
+func (c *Client) NewAsyncWriter() *AsyncWriter {
+	return &AsyncWriter{
+		client:    c,
+		streams:   make(map[string]*asyncStream),
+		batchSize: defaultAsyncBatchSize,
+		interval:  defaultAsyncInterval,
+	}
+}
+
+// SetBatchSize sets the number of events buffered for a stream before they
+// are written. The default is 100. An append with more events than the batch
+// size is written on its own.
+func (w *AsyncWriter) SetBatchSize(n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if n < 1 {
+		n = 1
+	}
+	w.batchSize = n
+}
+
+// SetInterval sets the longest time events are buffered for a stream before
+// they are written. The default is 50 milliseconds.
+func (w *AsyncWriter) SetInterval(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.interval = d
+}
+
+// SetCompletionHandler sets a function that is called after each request with
+// the events written, or not written if err is not nil, and the *WriteResult
+// of the request. It is called from the goroutine writing to the stream, so
+// results for a stream are reported in order.
+func (w *AsyncWriter) SetCompletionHandler(fn func(stream string, events []*Event, result *WriteResult, err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.completed = fn
+}
+
+// Append buffers events to be written to the stream and returns an
+// *AppendFuture for the result.
+//
+// If the writer is closed the future completes immediately with an error.
+func (w *AsyncWriter) Append(stream string, events ...*Event) *AppendFuture {
+	f := &AppendFuture{done: make(chan struct{})}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		f.err = fmt.Errorf("AsyncWriter is closed")
+		close(f.done)
+		return f
+	}
+
+	s, ok := w.streams[stream]
+	if !ok {
+		s = &asyncStream{
+			stream: stream,
+			writer: w.client.NewStreamWriter(stream),
+			flush:  make(chan struct{}, 1),
+		}
+		w.streams[stream] = s
+		w.wg.Add(1)
+		go w.run(s, w.interval)
+	}
+	s.pending = append(s.pending, &asyncAppend{events: events, future: f})
+	s.count += len(events)
+	if s.count >= w.batchSize {
+		s.signal()
+	}
+	return f
+}
+
+// Flush writes the events buffered for every stream without waiting for the
+// interval and waits until they have been written.
+func (w *AsyncWriter) Flush() {
+	w.mu.Lock()
+	futures := []*AppendFuture{}
+	for _, s := range w.streams {
+		for _, a := range s.pending {
+			futures = append(futures, a.future)
+		}
+		s.signal()
+	}
+	w.mu.Unlock()
+
+	for _, f := range futures {
+		<-f.done
+	}
+}
+
+// Close writes the events buffered for every stream and stops the writer.
+// Appends made after Close is called fail.
+func (w *AsyncWriter) Close() {
+	w.mu.Lock()
+	w.closed = true
+	for _, s := range w.streams {
+		s.signal()
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+}
+
+// Shutdown closes the writer like Close and returns once the buffered events
+// have been written.
+//
+// If ctx is done before then ctx.Err() is returned. The buffered events are
+// still written in the background and their futures complete when they are.
+func (w *AsyncWriter) Shutdown(ctx context.Context) error {
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// signal wakes the goroutine of the stream to write the buffered events.
+func (s *asyncStream) signal() {
+	select {
+	case s.flush <- struct{}{}:
+	default:
+	}
+}
+
+// run writes the appends buffered for the stream until there are none left.
+func (w *AsyncWriter) run(s *asyncStream, interval time.Duration) {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.flush:
+		case <-timer.C:
+		}
+
+		w.mu.Lock()
+		pending := s.pending
+		s.pending = nil
+		s.count = 0
+		size := w.batchSize
+		completed := w.completed
+		if len(pending) == 0 {
+			delete(w.streams, s.stream)
+			w.mu.Unlock()
+			return
+		}
+		w.mu.Unlock()
+
+		for len(pending) > 0 {
+			n, events := 1, pending[0].events
+			for n < len(pending) && len(events)+len(pending[n].events) <= size {
+				events = append(events[:len(events):len(events)], pending[n].events...)
+				n++
+			}
+			w.write(s, pending[:n], events, completed)
+			pending = pending[n:]
+		}
+
+		// The goroutine keeps running for appends made while the batch was
+		// being written, and exits at the next wake up if there are none.
+		w.mu.Lock()
+		if w.closed && len(s.pending) == 0 {
+			delete(w.streams, s.stream)
+			w.mu.Unlock()
+			return
+		}
+		w.mu.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(interval)
+	}
+}
+
+// write writes the events of the appends in a single request and completes
+// their futures.
+func (w *AsyncWriter) write(s *asyncStream, appends []*asyncAppend, events []*Event, completed func(string, []*Event, *WriteResult, error)) {
+	result, err := s.writer.AppendWithResult(ExpectAny, events...)
+	if completed != nil {
+		completed(s.stream, events, result, err)
+	}
+	if err != nil {
+		w.reporter.Report("AsyncWriter for "+s.stream, err, false)
+	}
+
+	// The result of each append describes its own events.
+	first := 0
+	if result != nil {
+		first = result.NextExpectedVersion - len(events) + 1
+	}
+	for _, a := range appends {
+		a.future.err = err
+		if result != nil {
+			a.future.result = &WriteResult{
+				NextExpectedVersion: first + len(a.events) - 1,
+				Location:            eventLocation(result.Location, first),
+				CommitPosition:      result.CommitPosition,
+			}
+		}
+		first += len(a.events)
+		close(a.future.done)
+	}
+}
+
+// eventLocation returns the location of the event with the event number in
+// the same stream as the event at location.
+func eventLocation(location string, eventNumber int) string {
+	i := strings.LastIndex(location, "/")
+	if i < 0 {
+		return location
+	}
+	return location[:i+1] + strconv.Itoa(eventNumber)
+}
@@ -0,0 +1,208 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&AsyncWriterSuite{})
+
+type AsyncWriterSuite struct{}
+
+func (s *AsyncWriterSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *AsyncWriterSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// batchRecorder accepts writes to any stream and records the event types
+// written in each request.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches map[string][][]string
+	fail    map[string]bool
+}
+
+func recordBatches(c *C) *batchRecorder {
+	b := &batchRecorder{batches: make(map[string][][]string), fail: make(map[string]bool)}
+	mux.HandleFunc("/streams/", func(w http.ResponseWriter, r *http.Request) {
+		stream := strings.TrimPrefix(r.URL.Path, "/streams/")
+		c.Check(r.Header.Get("ES-ExpectedVersion"), Equals, "")
+		events := []*Event{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&events), IsNil)
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.fail[stream] {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		first := 0
+		for _, batch := range b.batches[stream] {
+			first += len(batch)
+		}
+		types := []string{}
+		for _, e := range events {
+			types = append(types, e.EventType)
+		}
+		b.batches[stream] = append(b.batches[stream], types)
+		w.Header().Set("Location", fmt.Sprintf("%s/streams/%s/%d", server.URL, stream, first))
+		w.WriteHeader(http.StatusCreated)
+	})
+	return b
+}
+
+func (b *batchRecorder) get(stream string) [][]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]string{}, b.batches[stream]...)
+}
+
+// typed returns events of the types provided.
+func typed(types ...string) []*Event {
+	es := make([]*Event, len(types))
+	for i, t := range types {
+		es[i] = NewEvent("", t, nil, nil)
+	}
+	return es
+}
+
+func (s *AsyncWriterSuite) TestAppendsAreBatchedBySize(c *C) {
+	b := recordBatches(c)
+	w := client.NewAsyncWriter()
+	w.SetBatchSize(3)
+	w.SetInterval(time.Hour)
+	defer w.Close()
+
+	f1 := w.Append("async-1", typed("a", "b")...)
+	f2 := w.Append("async-1", typed("c")...)
+	r, err := f2.Wait()
+	c.Assert(err, IsNil)
+	c.Assert(r.NextExpectedVersion, Equals, 2)
+	c.Assert(r.Location, Equals, server.URL+"/streams/async-1/2")
+	r, err = f1.Wait()
+	c.Assert(err, IsNil)
+	c.Assert(r.NextExpectedVersion, Equals, 1)
+	c.Assert(r.Location, Equals, server.URL+"/streams/async-1/0")
+
+	c.Assert(b.get("async-1"), DeepEquals, [][]string{{"a", "b", "c"}})
+}
+
+func (s *AsyncWriterSuite) TestAppendsAreWrittenAfterInterval(c *C) {
+	b := recordBatches(c)
+	w := client.NewAsyncWriter()
+	w.SetInterval(20 * time.Millisecond)
+	defer w.Close()
+
+	start := time.Now()
+	w.Append("async-2", typed("a")...)
+	_, err := w.Append("async-2", typed("b")...).Wait()
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(start) >= 20*time.Millisecond, Equals, true)
+	c.Assert(b.get("async-2"), DeepEquals, [][]string{{"a", "b"}})
+}
+
+func (s *AsyncWriterSuite) TestAppendsAreNotSplitAndKeepOrder(c *C) {
+	b := recordBatches(c)
+	var mu sync.Mutex
+	completed := [][]string{}
+	w := client.NewAsyncWriter()
+	w.SetBatchSize(3)
+	w.SetInterval(time.Hour)
+	w.SetCompletionHandler(func(stream string, events []*Event, result *WriteResult, err error) {
+		c.Check(err, IsNil)
+		mu.Lock()
+		defer mu.Unlock()
+		types := []string{}
+		for _, e := range events {
+			types = append(types, e.EventType)
+		}
+		completed = append(completed, types)
+	})
+
+	w.Append("async-3", typed("a", "b")...)
+	w.Append("async-3", typed("c", "d")...)
+	w.Append("async-3", typed("e", "f", "g", "h")...)
+	w.Append("async-4", typed("x")...)
+	w.Flush()
+
+	c.Assert(b.get("async-3"), DeepEquals, [][]string{{"a", "b"}, {"c", "d"}, {"e", "f", "g", "h"}})
+	c.Assert(b.get("async-4"), DeepEquals, [][]string{{"x"}})
+	mu.Lock()
+	c.Assert(completed, HasLen, 4)
+	mu.Unlock()
+
+	w.Close()
+	_, err := w.Append("async-3", typed("i")...).Wait()
+	c.Assert(err, ErrorMatches, "AsyncWriter is closed")
+}
+
+func (s *AsyncWriterSuite) TestFailedWriteFailsItsAppends(c *C) {
+	b := recordBatches(c)
+	b.fail["async-5"] = true
+	w := client.NewAsyncWriter()
+	w.SetInterval(time.Hour)
+
+	f1 := w.Append("async-5", typed("a")...)
+	f2 := w.Append("async-5", typed("b")...)
+	w.Close()
+
+	_, err := f1.Wait()
+	c.Assert(err, FitsTypeOf, &ErrUnexpected{})
+	_, err = f2.Wait()
+	c.Assert(err, FitsTypeOf, &ErrUnexpected{})
+	select {
+	case <-f2.Done():
+	default:
+		c.Fatal("future is not done")
+	}
+}
+
+func (s *AsyncWriterSuite) TestShutdownWritesBufferedEvents(c *C) {
+	b := recordBatches(c)
+	w := client.NewAsyncWriter()
+	w.SetInterval(time.Hour)
+
+	f := w.Append("async-6", typed("a", "b")...)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c.Assert(w.Shutdown(ctx), IsNil)
+	select {
+	case <-f.Done():
+	default:
+		c.Fatal("future is not done")
+	}
+	c.Assert(b.get("async-6"), DeepEquals, [][]string{{"a", "b"}})
+}
+
+func (s *AsyncWriterSuite) TestShutdownReturnsWhenContextIsDone(c *C) {
+	release := make(chan struct{})
+	mux.HandleFunc("/streams/async-7", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Location", server.URL+"/streams/async-7/0")
+		w.WriteHeader(http.StatusCreated)
+	})
+	w := client.NewAsyncWriter()
+
+	f := w.Append("async-7", typed("a")...)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c.Assert(w.Shutdown(ctx), Equals, context.DeadlineExceeded)
+
+	close(release)
+	_, err := f.Wait()
+	c.Assert(err, IsNil)
+}
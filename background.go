@@ -0,0 +1,147 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import "sync"
+
+// errorsBuffer is the number of errors held by the Errors channel of a
+// background component.
+const errorsBuffer = 16
+
+// ErrorReporter holds the Errors channel of a background component and sends
+// errors on it as *ErrBackground, as described for ErrBackground. The channel
+// is never closed, as components can be started again after they stop.
+//
+// The zero value is ready to use. ErrorReporter is exported so that components
+// built on this package, such as the bridge in the bridge package, report
+// their errors in the same way.
+type ErrorReporter struct {
+	once sync.Once
+	ch   chan error
+}
+
+func (r *ErrorReporter) errors() chan error {
+	r.once.Do(func() {
+		r.ch = make(chan error, errorsBuffer)
+	})
+	return r.ch
+}
+
+// Errors returns the channel on which the errors are sent.
+func (r *ErrorReporter) Errors() <-chan error {
+	return r.errors()
+}
+
+// Report sends the error of the component on the channel as an
+// *ErrBackground if there is room, and discards it otherwise.
+func (r *ErrorReporter) Report(component string, err error, fatal bool) {
+	select {
+	case r.errors() <- &ErrBackground{Component: component, Err: err, Fatal: fatal}:
+	default:
+	}
+}
+
+// reportRun reports the error of a periodic run of the component, or the
+// errors it encountered for individual streams, as retryable errors.
+func (r *ErrorReporter) reportRun(component string, err error, streams map[string]error) {
+	if err != nil {
+		r.Report(component, err, false)
+		return
+	}
+	for stream, err := range streams {
+		r.Report(component+" for "+stream, err, false)
+	}
+}
+
+// Errors returns a channel on which the errors encountered by the subscription
+// are sent as *ErrBackground. Errors reading the stream, which the
+// subscription recovers from by backing off, are retryable. The error that
+// stops the subscription is fatal.
+func (s *Subscription) Errors() <-chan error {
+	return s.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors encountered by the instance are
+// sent as *ErrBackground. Errors reading the lease stream are retryable. The
+// error that stops the instance is fatal.
+func (s *StandbyConsumer) Errors() <-chan error {
+	return s.reporter.Errors()
+}
+
+// Errors returns a channel on which the error that stops the replay is sent as
+// a fatal *ErrBackground.
+func (p *PriorityReplay) Errors() <-chan error {
+	return p.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors of failed jobs are sent as
+// fatal *ErrBackground, with the job id in the component name.
+func (r *JobRunner) Errors() <-chan error {
+	return r.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors encountered by the dispatcher
+// are sent as *ErrBackground. Errors reading the stream, which the dispatcher
+// recovers from by backing off, are retryable. The error that stops the
+// dispatcher, such as a failure to store its checkpoint, is fatal.
+func (d *Dispatcher) Errors() <-chan error {
+	return d.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors encountered by the projector
+// are sent as *ErrBackground. Errors reading the stream, which the projector
+// recovers from by backing off, are retryable. The error that stops the
+// projector, such as an error returned by a handler, is fatal.
+func (p *Projector) Errors() <-chan error {
+	return p.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors encountered by the consumer
+// are sent as *ErrBackground. Errors reading messages and events and
+// acknowledging messages are retryable. The error that stops the consumer is
+// fatal.
+func (p *PersistentSubscriptionConsumer) Errors() <-chan error {
+	return p.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors of failed writes are sent as
+// *ErrBackground, with the stream in the component name. The errors are not
+// fatal: the appends in the failed request fail, and the writer goes on to
+// write the appends that follow.
+func (w *AsyncWriter) Errors() <-chan error {
+	return w.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors of failed appends are sent as
+// *ErrBackground, with the stream in the component name, as well as being
+// returned by Append. The errors are not fatal, and the writer of the stream
+// goes on to write the appends that follow.
+func (p *WriterPool) Errors() <-chan error {
+	return p.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors reading the heads of streams
+// are sent as *ErrBackground, as well as being returned by Lag. The errors are
+// retryable, as the heads are read again when the lag is next measured.
+func (m *LagMonitor) Errors() <-chan error {
+	return m.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors of reconciliations run by Start
+// are sent as *ErrBackground, as well as being passed to the report function.
+// The errors are retryable, as the streams are reconciled again at the next
+// interval.
+func (r *Reconciler) Errors() <-chan error {
+	return r.reporter.Errors()
+}
+
+// Errors returns a channel on which the errors of refreshes run by Start are
+// sent as *ErrBackground, as well as being passed to the report function. The
+// errors of a refresh and of refreshing individual streams are retryable, as
+// the snapshots are refreshed again at the next interval.
+func (s *SnapshotRefresher) Errors() <-chan error {
+	return s.reporter.Errors()
+}
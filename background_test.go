@@ -0,0 +1,188 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&BackgroundSuite{})
+
+type BackgroundSuite struct{}
+
+func (s *BackgroundSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *BackgroundSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// nextError returns the next error sent on the channel.
+func nextError(c *C, errs <-chan error) *ErrBackground {
+	select {
+	case err := <-errs:
+		e, ok := err.(*ErrBackground)
+		c.Assert(ok, Equals, true, Commentf("%T", err))
+		return e
+	case <-time.After(5 * time.Second):
+		c.Fatal("no error received")
+	}
+	return nil
+}
+
+func (s *BackgroundSuite) TestSubscriptionReportsRetryableAndFatalErrors(c *C) {
+	sim := newTestSimulator(CreateTestEvents(5, "bg-1", server.URL, "Foo"), nil)
+	var mu sync.Mutex
+	failures := 1
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := failures > 0
+		failures--
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		sim.ServeHTTP(w, r)
+	})
+
+	errBad := errors.New("bad event")
+	sub := client.NewCatchUpSubscription("bg-1", 0, func(er *EventResponse) error {
+		if er.Event.EventNumber == 3 {
+			return errBad
+		}
+		return nil
+	})
+	sub.SetBackoff(time.Millisecond, time.Millisecond)
+	sub.Start()
+	<-sub.Done()
+
+	e := nextError(c, sub.Errors())
+	c.Assert(e.Fatal, Equals, false)
+	c.Assert(e.Retryable(), Equals, true)
+	c.Assert(e.Component, Equals, "Subscription to bg-1")
+	c.Assert(e.Err, FitsTypeOf, &ErrTemporarilyUnavailable{})
+
+	e = nextError(c, sub.Errors())
+	c.Assert(IsFatal(e), Equals, true)
+	c.Assert(e.Err, Equals, errBad)
+	c.Assert(e.Error(), Equals, "Subscription to bg-1 stopped: bad event")
+}
+
+func (s *BackgroundSuite) TestErrorsDoNotBlockWhenNotRead(c *C) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	dropped := make(chan struct{}, 100)
+	sub := client.NewCatchUpSubscription("bg-2", 0, func(er *EventResponse) error { return nil })
+	sub.SetBackoff(time.Millisecond, time.Millisecond)
+	sub.SetDroppedHandler(func(error) {
+		select {
+		case dropped <- struct{}{}:
+		default:
+		}
+	})
+	sub.Start()
+	eventually(func() bool { return len(dropped) > errorsBuffer+2 })
+	sub.Stop()
+
+	c.Assert(len(sub.Errors()), Equals, errorsBuffer)
+}
+
+func (s *BackgroundSuite) TestJobRunnerReportsFailedJobs(c *C) {
+	r := newMemoryJobRunner(&memoryJobStore{})
+	errStep := errors.New("step failed")
+	c.Assert(r.Start("export", func(int) (int, bool, error) { return 0, false, errStep }), IsNil)
+	_, err := r.Wait("export")
+	c.Assert(err, IsNil)
+
+	e := nextError(c, r.Errors())
+	c.Assert(e.Fatal, Equals, true)
+	c.Assert(e.Component, Equals, "Job export")
+	c.Assert(e.Err, Equals, errStep)
+}
+
+func (s *BackgroundSuite) TestPriorityReplayReportsFatalError(c *C) {
+	setupSimulator(CreateTestEvents(3, "bg-3", server.URL, "Foo"), nil)
+	errBad := errors.New("bad event")
+	p := client.NewPriorityReplay("bg-3", 2, func(er *EventResponse) error { return errBad })
+	p.Start()
+	<-p.Done()
+
+	e := nextError(c, p.Errors())
+	c.Assert(e.Fatal, Equals, true)
+	c.Assert(e.Err, Equals, errBad)
+}
+
+func (s *BackgroundSuite) TestIsFatal(c *C) {
+	c.Assert(IsFatal(&ErrBackground{Err: errors.New("x"), Fatal: true}), Equals, true)
+	c.Assert(IsFatal(&ErrBackground{Err: errors.New("x")}), Equals, false)
+	c.Assert(IsFatal(errors.New("x")), Equals, false)
+	c.Assert(ErrBackground{Component: "C", Err: errors.New("x")}.Error(), Equals, "C will retry: x")
+}
+
+func (s *BackgroundSuite) TestWritersReportFailedWrites(c *C) {
+	mux.HandleFunc("/streams/bg-4", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	aw := client.NewAsyncWriter()
+	_, err := aw.Append("bg-4", NewEvent("", "Foo", nil, nil)).Wait()
+	c.Assert(err, NotNil)
+	aw.Close()
+	e := nextError(c, aw.Errors())
+	c.Assert(e.Fatal, Equals, false)
+	c.Assert(e.Component, Equals, "AsyncWriter for bg-4")
+	c.Assert(e.Err, Equals, err)
+
+	pool := client.NewWriterPool()
+	defer pool.Close()
+	err = pool.Append("bg-4", ExpectAny, NewEvent("", "Foo", nil, nil))
+	c.Assert(err, NotNil)
+	e = nextError(c, pool.Errors())
+	c.Assert(e.Fatal, Equals, false)
+	c.Assert(e.Component, Equals, "WriterPool writer for bg-4")
+	c.Assert(e.Err, Equals, err)
+}
+
+func (s *BackgroundSuite) TestLagMonitorReportsHeadErrors(c *C) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	m := client.NewLagMonitor()
+	m.Track("projector", "bg-5", func() int { return 0 })
+	_, err := m.Lag()
+	c.Assert(err, NotNil)
+
+	e := nextError(c, m.Errors())
+	c.Assert(e.Retryable(), Equals, true)
+	c.Assert(e.Component, Equals, "LagMonitor for bg-5")
+	c.Assert(e.Err, Equals, err)
+}
+
+func (s *BackgroundSuite) TestSnapshotRefresherReportsStreamErrors(c *C) {
+	serveCategoryStreams(map[string]int{"bg-1": 3})
+	failed := errors.New("snapshot failed")
+	snapshotter := &memorySnapshotter{
+		versions: map[string]int{},
+		fail:     map[string]error{"bg-1": failed},
+	}
+
+	refresher := client.NewSnapshotRefresher("bg", snapshotter)
+	refresher.Start(time.Hour, func(*SnapshotRefreshReport, error) {})
+	e := nextError(c, refresher.Errors())
+	refresher.Stop()
+	c.Assert(e.Retryable(), Equals, true)
+	c.Assert(e.Component, Equals, "Snapshot refresher of bg for bg-1")
+	c.Assert(e.Err, Equals, failed)
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SetRewriteLinks sets whether the links returned by the server, such as the
+// paging links of feeds and the links of events, are rewritten to the server
+// url of the client before they are followed.
+//
+// The eventstore builds its links from the address it is served on. Behind a
+// reverse proxy or ingress that address is usually not the one the client can
+// reach, and does not include the path the eventstore is mounted under, so
+// following the links fails. When links are rewritten the scheme and host of
+// each link are replaced with those of the server url, and the path of the
+// server url is added to the path of the link unless it is already there.
+//
+// Links are not rewritten by default, as in a cluster they may refer to other
+// nodes.
+func (c *Client) SetRewriteLinks(rewrite bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rewriteLinks = rewrite
+}
+
+// resolveURL returns the url a request for u is sent to.
+//
+// Relative urls are resolved against the server url, or the preferred master
+// for writes. The paths of the API, such as /streams/{stream}, are relative to
+// the path of the server url, so that a server mounted under a path prefix
+// such as https://host/es/ can be used. Absolute urls are returned unchanged
+// unless links are rewritten. See SetRewriteLinks.
+func (c *Client) resolveURL(u *url.URL, write bool) *url.URL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !u.IsAbs() {
+		if write && c.master != nil {
+			return c.master.ResolveReference(u)
+		}
+		return c.baseURL.ResolveReference(withBasePath(c.baseURL, u))
+	}
+	if !c.rewriteLinks {
+		return u
+	}
+	ret := *u
+	ret.Scheme = c.baseURL.Scheme
+	ret.Host = c.baseURL.Host
+	ret.User = c.baseURL.User
+	return withBasePath(c.baseURL, &ret)
+}
+
+// withBasePath returns u with the path of base added to the start of its path
+// if the path of u is absolute and does not already start with it.
+func withBasePath(base, u *url.URL) *url.URL {
+	prefix := strings.TrimRight(base.Path, "/")
+	if prefix == "" || !strings.HasPrefix(u.Path, "/") || u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/") {
+		return u
+	}
+	ret := *u
+	ret.Path = prefix + u.Path
+	if u.RawPath != "" {
+		ret.RawPath = strings.TrimRight(base.EscapedPath(), "/") + u.RawPath
+	}
+	return &ret
+}
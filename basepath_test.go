@@ -0,0 +1,75 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"net/url"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&BasePathSuite{})
+
+type BasePathSuite struct{}
+
+func (s *BasePathSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *BasePathSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// readCount reads the stream from the start and returns the number of events
+// read and the error that stopped the read.
+func readCount(client *Client, stream string) (int, error) {
+	n := 0
+	reader := client.NewStreamReader(stream)
+	for reader.Next() {
+		if reader.Err() != nil {
+			return n, reader.Err()
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *BasePathSuite) TestResolveURL(c *C) {
+	cl, err := NewClient(nil, "https://proxy.example.com/es/")
+	c.Assert(err, IsNil)
+	resolve := func(rawurl string, write bool) string {
+		u, err := url.Parse(rawurl)
+		c.Assert(err, IsNil)
+		return cl.resolveURL(u, write).String()
+	}
+
+	c.Assert(resolve("/streams/foo", false), Equals, "https://proxy.example.com/es/streams/foo")
+	c.Assert(resolve("/es/streams/foo", false), Equals, "https://proxy.example.com/es/streams/foo")
+	c.Assert(resolve("/streams/"+url.PathEscape("a/b"), false), Equals, "https://proxy.example.com/es/streams/a%2Fb")
+	c.Assert(resolve("http://10.0.0.1:2113/streams/foo/0/forward/20", false), Equals, "http://10.0.0.1:2113/streams/foo/0/forward/20")
+
+	cl.SetRewriteLinks(true)
+	c.Assert(resolve("http://10.0.0.1:2113/streams/foo/0/forward/20", false), Equals, "https://proxy.example.com/es/streams/foo/0/forward/20")
+	c.Assert(resolve("http://proxy.example.com/es/streams/foo/1", false), Equals, "https://proxy.example.com/es/streams/foo/1")
+}
+
+func (s *BasePathSuite) TestPagingBehindPathPrefix(c *C) {
+	es := CreateTestEvents(45, "prefixed", server.URL, "Foo")
+	// The simulator builds links without the prefix, as an eventstore behind
+	// a proxy does.
+	mux.Handle("/es/", http.StripPrefix("/es", newTestSimulator(es, nil)))
+
+	cl, err := NewClient(nil, server.URL+"/es/")
+	c.Assert(err, IsNil)
+	n, err := readCount(cl, "prefixed")
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+	c.Assert(n < 45, Equals, true)
+
+	cl.SetRewriteLinks(true)
+	n, err = readCount(cl, "prefixed")
+	c.Assert(err, FitsTypeOf, &ErrNoMoreEvents{})
+	c.Assert(n, Equals, 45)
+}
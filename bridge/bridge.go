@@ -0,0 +1,268 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+// Package bridge publishes the events in an eventstore stream to a message
+// broker such as Kafka or NATS.
+//
+// A Bridge consumes a stream with a catch-up subscription and hands each event
+// to a Publisher as a *Message. The package has no dependency on any broker
+// client; a Publisher is a small adapter over the client of the broker in use,
+// for example a Kafka producer that sends the message synchronously, or a NATS
+// JetStream publish that waits for the acknowledgement.
+//
+// Delivery is at least once. The bridge records the position in the stream of
+// the last event published in a CheckpointStore and resumes after it when it
+// is restarted. The position is that of the event in the feed of the stream,
+// which for a stream such as $ce-order is not the event number of the event.
+// Events published after the last checkpoint stored are published again, so
+// consumers of the topic should deduplicate, for example by the event id in
+// the message headers.
+package bridge
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/jetbasrawi/go.geteventstore"
+)
+
+// Message headers set on every message published by a Bridge.
+const (
+	HeaderEventID     = "es-event-id"
+	HeaderEventType   = "es-event-type"
+	HeaderEventNumber = "es-event-number"
+	HeaderStream      = "es-stream"
+	HeaderContentType = "content-type"
+)
+
+// Message is an event to be published to a message broker.
+//
+// Value is the data of the event as it was written, without decoding. Key is
+// used by brokers that partition topics, such as Kafka, to keep the messages
+// with the same key in order.
+type Message struct {
+	Topic   string
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
+
+// Publisher publishes messages to a message broker.
+//
+// Publish must return only when the broker has accepted the message, as the
+// bridge may checkpoint the event once Publish returns. An error stops the
+// bridge; retries should be made by the Publisher.
+type Publisher interface {
+	Publish(m *Message) error
+}
+
+// PublisherFunc is an adapter to allow the use of an ordinary function as a
+// Publisher.
+type PublisherFunc func(m *Message) error
+
+// Publish calls f(m).
+func (f PublisherFunc) Publish(m *Message) error {
+	return f(m)
+}
+
+// Bridge publishes the events in a stream to a Publisher.
+//
+// By default every message is published to a topic with the name of the stream
+// and keyed by the stream id of the event, so that events from a category or
+// projection stream such as $ce-order keep their per-stream order. Use
+// SetTopicMapper and SetKeyMapper to change the mapping.
+type Bridge struct {
+	client    *goes.Client
+	name      string
+	stream    string
+	publisher Publisher
+	store     CheckpointStore
+	topic     func(*goes.RawEvent) string
+	key       func(*goes.RawEvent) string
+	every     int
+	mu        sync.Mutex
+	pending   int
+	err       error
+	reporter  goes.ErrorReporter
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New returns a bridge named name that publishes the events in the stream to
+// publisher and records its checkpoints in store under name.
+func New(client *goes.Client, name, stream string, publisher Publisher, store CheckpointStore) *Bridge {
+	b := &Bridge{
+		client:    client,
+		name:      name,
+		stream:    stream,
+		publisher: publisher,
+		store:     store,
+		every:     1,
+	}
+	b.topic = func(*goes.RawEvent) string { return b.stream }
+	b.key = func(e *goes.RawEvent) string { return e.Stream }
+	return b
+}
+
+// SetTopicMapper sets the function that returns the topic to which an event is
+// published.
+func (b *Bridge) SetTopicMapper(fn func(*goes.RawEvent) string) {
+	b.topic = fn
+}
+
+// SetKeyMapper sets the function that returns the key of the message for an
+// event.
+func (b *Bridge) SetKeyMapper(fn func(*goes.RawEvent) string) {
+	b.key = fn
+}
+
+// SetCheckpointInterval sets the number of events published between
+// checkpoints. A larger interval stores fewer checkpoints, at the cost of more
+// events being published again after a restart. The default is 1. A checkpoint
+// is also stored when the bridge is stopped.
+func (b *Bridge) SetCheckpointInterval(n int) {
+	if n < 1 {
+		n = 1
+	}
+	b.every = n
+}
+
+// Err returns the error that stopped the bridge, or nil.
+func (b *Bridge) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// Start loads the checkpoint of the bridge and starts publishing the events
+// after it. An error is returned if the checkpoint cannot be loaded.
+func (b *Bridge) Start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stop != nil {
+		return nil
+	}
+	cp, err := b.store.Load(b.name)
+	if err != nil {
+		return err
+	}
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	b.err = nil
+	b.pending = 0
+	sub := b.client.NewCatchUpSubscription(b.stream, cp+1, nil)
+	sub.SetContextHandler(b.publish)
+	sub.Start()
+	go b.run(sub, b.stop, b.done)
+	return nil
+}
+
+// Stop stops the bridge and stores the checkpoint of the last event published.
+func (b *Bridge) Stop() {
+	b.mu.Lock()
+	stop, done := b.stop, b.done
+	b.stop = nil
+	b.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Errors returns a channel on which the errors encountered by the bridge are
+// sent as *goes.ErrBackground. Errors reading the stream, which the bridge
+// recovers from by backing off, are retryable. The error that stops the bridge,
+// such as an error returned by the Publisher, is fatal.
+func (b *Bridge) Errors() <-chan error {
+	return b.reporter.Errors()
+}
+
+// Done returns a channel that is closed when the bridge stops.
+func (b *Bridge) Done() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done
+}
+
+func (b *Bridge) run(sub *goes.Subscription, stop, done chan struct{}) {
+	defer close(done)
+
+	var err error
+	for stopped := false; !stopped; {
+		select {
+		case <-stop:
+			sub.Stop()
+			stopped = true
+		case <-sub.Done():
+			err = sub.Err()
+			stopped = true
+		case e := <-sub.Errors():
+			if !goes.IsFatal(e) {
+				b.reporter.Report(b.component(), e.(*goes.ErrBackground).Err, false)
+			}
+		}
+	}
+
+	// The last event processed by the subscription is the last event that was
+	// published successfully.
+	b.mu.Lock()
+	pending := b.pending
+	b.mu.Unlock()
+	if pending > 0 {
+		if cerr := b.store.Store(b.name, sub.LastProcessed()); err == nil {
+			err = cerr
+		}
+	}
+
+	b.mu.Lock()
+	b.err = err
+	b.mu.Unlock()
+	if err != nil {
+		b.reporter.Report(b.component(), err, true)
+	}
+}
+
+func (b *Bridge) component() string {
+	return "Bridge " + b.name
+}
+
+// publish publishes the event and stores a checkpoint every checkpoint
+// interval.
+func (b *Bridge) publish(ctx context.Context, er *goes.EventResponse) error {
+	e, err := goes.NewRawEvent(er)
+	if err != nil {
+		return err
+	}
+	m := &Message{
+		Topic: b.topic(e),
+		Key:   b.key(e),
+		Value: e.Data,
+		Headers: map[string]string{
+			HeaderEventID:     e.EventID,
+			HeaderEventType:   e.EventType,
+			HeaderEventNumber: strconv.Itoa(e.EventNumber),
+			HeaderStream:      e.Stream,
+			HeaderContentType: e.ContentType,
+		},
+	}
+	if err := b.publisher.Publish(m); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.pending++
+	due := b.pending >= b.every
+	if due {
+		b.pending = 0
+	}
+	b.mu.Unlock()
+	if due {
+		pos, _ := goes.FeedPosition(ctx)
+		return b.store.Store(b.name, pos)
+	}
+	return nil
+}
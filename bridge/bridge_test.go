@@ -0,0 +1,261 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+	"github.com/jetbasrawi/go.geteventstore/estest"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&BridgeSuite{})
+
+type BridgeSuite struct {
+	sim    *estest.Simulator
+	server *httptest.Server
+	client *goes.Client
+}
+
+type OrderPlaced struct {
+	ID int `json:"id"`
+}
+
+func (s *BridgeSuite) SetUpTest(c *C) {
+	s.sim = estest.NewSimulator()
+	s.server = httptest.NewServer(s.sim)
+	client, err := goes.NewClient(nil, s.server.URL)
+	c.Assert(err, IsNil)
+	s.client = client
+}
+
+func (s *BridgeSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func (s *BridgeSuite) appendOrders(c *C, stream string, from, n int) {
+	for i := from; i < from+n; i++ {
+		c.Assert(s.sim.Append(stream, goes.NewEvent("", "OrderPlaced", &OrderPlaced{ID: i}, nil)), IsNil)
+	}
+}
+
+// recorder is a Publisher that records the messages published and fails when
+// fail returns an error.
+type recorder struct {
+	mu   sync.Mutex
+	msgs []*Message
+	fail func(m *Message) error
+}
+
+func (r *recorder) Publish(m *Message) error {
+	if r.fail != nil {
+		if err := r.fail(m); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, m)
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.msgs)
+}
+
+func (r *recorder) ids(c *C) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := []int{}
+	for _, m := range r.msgs {
+		o := OrderPlaced{}
+		c.Assert(json.Unmarshal(m.Value, &o), IsNil)
+		ret = append(ret, o.ID)
+	}
+	return ret
+}
+
+func eventually(c *C, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			c.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (s *BridgeSuite) TestPublishesEventsWithDefaultMapping(c *C) {
+	s.appendOrders(c, "orders", 0, 3)
+	pub := &recorder{}
+	b := New(s.client, "orders-bridge", "orders", pub, NewMemoryCheckpointStore())
+	c.Assert(b.Start(), IsNil)
+	defer b.Stop()
+
+	eventually(c, func() bool { return pub.count() == 3 })
+	c.Assert(pub.ids(c), DeepEquals, []int{0, 1, 2})
+
+	m := pub.msgs[2]
+	c.Assert(m.Topic, Equals, "orders")
+	c.Assert(m.Key, Equals, "orders")
+	c.Assert(m.Headers[HeaderEventType], Equals, "OrderPlaced")
+	c.Assert(m.Headers[HeaderEventNumber], Equals, "2")
+	c.Assert(m.Headers[HeaderStream], Equals, "orders")
+	c.Assert(m.Headers[HeaderContentType], Equals, "application/json")
+	c.Assert(m.Headers[HeaderEventID], Not(Equals), "")
+}
+
+func (s *BridgeSuite) TestTopicAndKeyMappers(c *C) {
+	s.appendOrders(c, "orders", 0, 2)
+	pub := &recorder{}
+	b := New(s.client, "orders-bridge", "orders", pub, NewMemoryCheckpointStore())
+	b.SetTopicMapper(func(e *goes.RawEvent) string { return "es." + e.EventType })
+	b.SetKeyMapper(func(e *goes.RawEvent) string { return e.EventID })
+	c.Assert(b.Start(), IsNil)
+	defer b.Stop()
+
+	eventually(c, func() bool { return pub.count() == 2 })
+	c.Assert(pub.msgs[0].Topic, Equals, "es.OrderPlaced")
+	c.Assert(pub.msgs[0].Key, Equals, pub.msgs[0].Headers[HeaderEventID])
+}
+
+func (s *BridgeSuite) TestResumesFromCheckpoint(c *C) {
+	s.appendOrders(c, "orders", 0, 3)
+	store := NewStreamCheckpointStore(s.client)
+	pub := &recorder{}
+	b := New(s.client, "orders-bridge", "orders", pub, store)
+	c.Assert(b.Start(), IsNil)
+	eventually(c, func() bool { return pub.count() == 3 })
+	b.Stop()
+
+	cp, err := store.Load("orders-bridge")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 2)
+
+	s.appendOrders(c, "orders", 3, 2)
+	pub = &recorder{}
+	b = New(s.client, "orders-bridge", "orders", pub, store)
+	c.Assert(b.Start(), IsNil)
+	defer b.Stop()
+	eventually(c, func() bool { return pub.count() == 2 })
+	c.Assert(pub.ids(c), DeepEquals, []int{3, 4})
+}
+
+func (s *BridgeSuite) TestResumesCategoryStreamFromFeedPosition(c *C) {
+	s.appendOrders(c, "order-1", 0, 3)
+	s.appendOrders(c, "order-2", 10, 3)
+	for i := 0; i < 2; i++ {
+		c.Assert(s.sim.LinkTo("$ce-order", "order-1", i), IsNil)
+		c.Assert(s.sim.LinkTo("$ce-order", "order-2", i), IsNil)
+	}
+	store := NewMemoryCheckpointStore()
+	pub := &recorder{}
+	b := New(s.client, "orders-bridge", "$ce-order", pub, store)
+	c.Assert(b.Start(), IsNil)
+	eventually(c, func() bool { return pub.count() == 4 })
+	b.Stop()
+
+	cp, err := store.Load("orders-bridge")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 3)
+
+	c.Assert(s.sim.LinkTo("$ce-order", "order-1", 2), IsNil)
+	c.Assert(s.sim.LinkTo("$ce-order", "order-2", 2), IsNil)
+	pub = &recorder{}
+	b = New(s.client, "orders-bridge", "$ce-order", pub, store)
+	c.Assert(b.Start(), IsNil)
+	defer b.Stop()
+	eventually(c, func() bool { return pub.count() == 2 })
+	time.Sleep(20 * time.Millisecond)
+	c.Assert(pub.ids(c), DeepEquals, []int{2, 12})
+	c.Assert(pub.msgs[0].Headers[HeaderEventNumber], Equals, "2")
+}
+
+func (s *BridgeSuite) TestCheckpointIntervalStoresOnStop(c *C) {
+	s.appendOrders(c, "orders", 0, 5)
+	store := NewMemoryCheckpointStore()
+	pub := &recorder{}
+	b := New(s.client, "orders-bridge", "orders", pub, store)
+	b.SetCheckpointInterval(3)
+	c.Assert(b.Start(), IsNil)
+	eventually(c, func() bool { return pub.count() == 5 })
+
+	cp, _ := store.Load("orders-bridge")
+	c.Assert(cp, Equals, 2)
+
+	b.Stop()
+	cp, _ = store.Load("orders-bridge")
+	c.Assert(cp, Equals, 4)
+}
+
+func (s *BridgeSuite) TestPublishErrorStopsAfterLastPublished(c *C) {
+	s.appendOrders(c, "orders", 0, 4)
+	store := NewMemoryCheckpointStore()
+	errBroker := errors.New("broker unavailable")
+	pub := &recorder{fail: func(m *Message) error {
+		if m.Headers[HeaderEventNumber] == "2" {
+			return errBroker
+		}
+		return nil
+	}}
+	b := New(s.client, "orders-bridge", "orders", pub, store)
+	b.SetCheckpointInterval(10)
+	c.Assert(b.Start(), IsNil)
+
+	select {
+	case <-b.Done():
+	case <-time.After(5 * time.Second):
+		c.Fatal("bridge did not stop")
+	}
+	c.Assert(b.Err(), Equals, errBroker)
+	c.Assert(pub.ids(c), DeepEquals, []int{0, 1})
+
+	cp, _ := store.Load("orders-bridge")
+	c.Assert(cp, Equals, 1)
+}
+
+func (s *BridgeSuite) TestStreamCheckpointStore(c *C) {
+	store := NewStreamCheckpointStore(s.client)
+	cp, err := store.Load("b")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, -1)
+
+	c.Assert(store.Store("b", 7), IsNil)
+	c.Assert(store.Store("b", 9), IsNil)
+	cp, err = store.Load("b")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 9)
+	c.Assert(s.sim.Events(CheckpointStreamName("b")), HasLen, 2)
+}
+
+func (s *BridgeSuite) TestPublishErrorIsReportedAsFatal(c *C) {
+	s.appendOrders(c, "orders", 0, 2)
+	errBroker := errors.New("broker unavailable")
+	pub := &recorder{fail: func(m *Message) error { return errBroker }}
+	b := New(s.client, "orders-bridge", "orders", pub, NewMemoryCheckpointStore())
+	c.Assert(b.Start(), IsNil)
+	<-b.Done()
+
+	select {
+	case err := <-b.Errors():
+		c.Assert(goes.IsFatal(err), Equals, true)
+		c.Assert(err.(*goes.ErrBackground).Err, Equals, errBroker)
+		c.Assert(err.(*goes.ErrBackground).Component, Equals, "Bridge orders-bridge")
+	default:
+		c.Fatal("no error reported")
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package bridge
+
+import (
+	"github.com/jetbasrawi/go.geteventstore"
+)
+
+// CheckpointStore stores the event number of the last event published by a
+// bridge so that a bridge that is restarted resumes after it. It is the
+// goes.CheckpointStore shared with other consumers such as goes.Projector.
+type CheckpointStore = goes.CheckpointStore
+
+// MemoryCheckpointStore is a CheckpointStore that holds checkpoints in memory.
+type MemoryCheckpointStore = goes.MemoryCheckpointStore
+
+// StreamCheckpointStore is a CheckpointStore that appends checkpoints to a
+// stream in the eventstore.
+type StreamCheckpointStore = goes.StreamCheckpointStore
+
+// NewMemoryCheckpointStore returns a new *MemoryCheckpointStore.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return goes.NewMemoryCheckpointStore()
+}
+
+// NewStreamCheckpointStore returns a new *StreamCheckpointStore that stores
+// checkpoints through the client.
+func NewStreamCheckpointStore(client *goes.Client) *StreamCheckpointStore {
+	return goes.NewStreamCheckpointStore(client)
+}
+
+// CheckpointStreamName returns the name of the stream in which a
+// StreamCheckpointStore stores the checkpoints of the bridge named name.
+func CheckpointStreamName(name string) string {
+	return goes.CheckpointStreamName(name)
+}
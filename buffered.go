@@ -0,0 +1,38 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+// SetMaxBufferedEvents sets the largest number of events that
+// ReadCategoryTimeWindow and AppendWithRetryOnWrongVersion will hold in memory.
+// A read that would hold more events stops and returns an *ErrTooManyEvents
+// rather than exhausting memory on a stream that is much longer than expected.
+// ReadFeedForward and ReadFeedBackward return an *ErrTooManyEvents, with Next
+// set to the from of the read, if the page size requested is larger than the
+// limit.
+//
+// A limit of 0 or below, the default, means no limit. Readers that do not
+// accumulate events, such as StreamReader and ForEachEvent, and slices, which
+// are bounded by the count requested, are not affected.
+func (c *Client) SetMaxBufferedEvents(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	c.maxBuffered = n
+}
+
+// checkBuffered returns an *ErrTooManyEvents if a read of the stream that holds
+// held events cannot hold the event with the event number next.
+func (c *Client) checkBuffered(held int, stream string, next int) error {
+	c.mu.RLock()
+	limit := c.maxBuffered
+	c.mu.RUnlock()
+	if limit > 0 && held >= limit {
+		return &ErrTooManyEvents{Limit: limit, Stream: stream, Next: next}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&BufferedSuite{})
+
+type BufferedSuite struct{}
+
+func (s *BufferedSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *BufferedSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *BufferedSuite) TestCategoryTimeWindowIsLimited(c *C) {
+	serveCategory("limited", "limited-1", "limited-2")
+	serveTimedStream(c, "limited-1", minutes(0, 1, 2))
+	serveTimedStream(c, "limited-2", minutes(-1, 3, 4, 5, 6))
+	to := windowStart.Add(time.Hour)
+
+	client.SetMaxBufferedEvents(6)
+	_, err := client.ReadCategoryTimeWindow("limited", windowStart, to)
+	c.Assert(err, DeepEquals, &ErrTooManyEvents{Limit: 6, Stream: "limited-2", Next: 4})
+	c.Assert(err, ErrorMatches, "Reading stream limited-2 would hold more than 6 events in memory, stopped at event 4.")
+
+	client.SetMaxBufferedEvents(7)
+	got, err := client.ReadCategoryTimeWindow("limited", windowStart, to)
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 7)
+
+	client.SetMaxBufferedEvents(0)
+	got, err = client.ReadCategoryTimeWindow("limited", windowStart, to)
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 7)
+}
+
+func (s *BufferedSuite) TestAppendWithRetryIsLimited(c *C) {
+	stream := "limited-append"
+	serveWritableStream(stream)
+	writer := client.NewStreamWriter(stream)
+	c.Assert(writer.Append(ExpectAny, typed("A", "B", "C")...), IsNil)
+
+	client.SetMaxBufferedEvents(2)
+	called := false
+	_, err := writer.AppendWithRetryOnWrongVersion(1, func(current []*EventResponse) ([]*Event, error) {
+		called = true
+		return typed("D"), nil
+	})
+	c.Assert(err, DeepEquals, &ErrTooManyEvents{Limit: 2, Stream: stream, Next: 2})
+	c.Assert(called, Equals, false)
+}
+
+func (s *BufferedSuite) TestFeedPagesAreLimited(c *C) {
+	setupSimulator(CreateTestEvents(10, "limited-feed", server.URL, "Foo"), nil)
+
+	client.SetMaxBufferedEvents(5)
+	_, _, err := client.ReadFeedForward("limited-feed", 3)
+	c.Assert(err, DeepEquals, &ErrTooManyEvents{Limit: 5, Stream: "limited-feed", Next: 3})
+	_, _, err = client.ReadFeedBackward("limited-feed", -1)
+	c.Assert(err, DeepEquals, &ErrTooManyEvents{Limit: 5, Stream: "limited-feed", Next: -1})
+
+	f, _, err := client.ReadFeedForward("limited-feed", 3, WithPageSize(5))
+	c.Assert(err, IsNil)
+	c.Assert(f.Entry, HasLen, 5)
+	f, _, err = client.ReadFeedBackward("limited-feed", -1, WithPageSize(5))
+	c.Assert(err, IsNil)
+	c.Assert(f.Entry, HasLen, 5)
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRetryAfter is the longest Retry-After the client will honor. Longer
+// delays asked for by the server are shortened to it.
+const maxRetryAfter = 5 * time.Minute
+
+// busyGate holds back the requests of a client while the server has asked it
+// to wait with a Retry-After header.
+//
+// The gate is shared by the sessions of a client, so that a busy server is
+// given time to recover by all of the requests made through the client.
+type busyGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// hold holds back requests for d from now. A hold is only ever extended.
+func (g *busyGate) hold(d time.Duration) {
+	if g == nil || d <= 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until := time.Now().Add(d); until.After(g.until) {
+		g.until = until
+	}
+}
+
+// wait waits until the gate is open and returns the time spent waiting. An
+// error is returned if the context is done first.
+func (g *busyGate) wait(ctx context.Context) (time.Duration, error) {
+	if g == nil {
+		return 0, nil
+	}
+	g.mu.Lock()
+	d := time.Until(g.until)
+	g.mu.Unlock()
+	if d <= 0 {
+		return 0, nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return d, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// retryAfter returns the time to wait given by the Retry-After header of the
+// response, which is either a number of seconds or an HTTP date. 0 is returned
+// if the header is missing or cannot be parsed.
+func retryAfter(r *http.Response, now time.Time) time.Duration {
+	h := strings.TrimSpace(r.Header.Get("Retry-After"))
+	if h == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if s, err := strconv.Atoi(h); err == nil {
+		d = time.Duration(s) * time.Second
+	} else if t, err := http.ParseTime(h); err == nil {
+		d = t.Sub(now)
+	}
+
+	if d < 0 {
+		return 0
+	}
+	if d > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return d
+}
+
+// RetryAfter returns the time the server asked the client to wait before
+// retrying if err is an *ErrServerBusy, and false otherwise.
+//
+// The client waits for the time itself before making any other request, so
+// callers retrying in a loop slow down without waiting themselves.
+func RetryAfter(err error) (time.Duration, bool) {
+	if e, ok := err.(*ErrServerBusy); ok {
+		return e.RetryAfter, true
+	}
+	return 0, false
+}
@@ -0,0 +1,166 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&BusySuite{})
+
+type BusySuite struct{}
+
+func (s *BusySuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *BusySuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *BusySuite) TestTooManyRequestsReturnsErrServerBusy(c *C) {
+	mux.HandleFunc("/streams/busy-1/0", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, _, err := client.GetEvent("/streams/busy-1/0")
+	c.Assert(err, FitsTypeOf, &ErrServerBusy{})
+	c.Assert(err, ErrorMatches, "The server is busy.")
+	d, ok := RetryAfter(err)
+	c.Assert(ok, Equals, true)
+	c.Assert(d, Equals, time.Duration(0))
+}
+
+func (s *BusySuite) TestServiceUnavailableWithRetryAfterReturnsErrServerBusy(c *C) {
+	retry := ""
+	mux.HandleFunc("/streams/busy-2/0", func(w http.ResponseWriter, r *http.Request) {
+		if retry != "" {
+			w.Header().Set("Retry-After", retry)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, _, err := client.GetEvent("/streams/busy-2/0")
+	c.Assert(err, FitsTypeOf, &ErrTemporarilyUnavailable{})
+	_, ok := RetryAfter(err)
+	c.Assert(ok, Equals, false)
+
+	retry = "0"
+	_, _, err = client.GetEvent("/streams/busy-2/0")
+	c.Assert(err, FitsTypeOf, &ErrServerBusy{})
+	c.Assert(err.(*ErrServerBusy).ErrorResponse.StatusCode, Equals, http.StatusServiceUnavailable)
+}
+
+func (s *BusySuite) TestParseRetryAfter(c *C) {
+	now := time.Date(2016, 6, 1, 14, 0, 0, 0, time.UTC)
+	for h, want := range map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-3":                            0,
+		"3600":                          maxRetryAfter,
+		"Wed, 01 Jun 2016 14:00:10 GMT": 10 * time.Second,
+		"Wed, 01 Jun 2016 13:59:50 GMT": 0,
+		"soon":                          0,
+	} {
+		r := &http.Response{Header: http.Header{}}
+		r.Header.Set("Retry-After", h)
+		c.Assert(retryAfter(r, now), Equals, want, Commentf("%q", h))
+	}
+}
+
+func (s *BusySuite) TestClientWaitsForBusyServer(c *C) {
+	var mu sync.Mutex
+	requests := []time.Time{}
+	mux.HandleFunc("/streams/busy-3/0", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, time.Now())
+		if len(requests) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, _, err := client.GetEvent("/streams/busy-3/0")
+	d, ok := RetryAfter(err)
+	c.Assert(ok, Equals, true)
+	c.Assert(d, Equals, time.Second)
+	c.Assert(err, ErrorMatches, "The server is busy. Retry after 1s.")
+
+	// Sessions wait for the server along with the client.
+	_, resp, err := client.NewSession().GetEvent("/streams/busy-3/0")
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+	c.Assert(resp.Waited > 500*time.Millisecond, Equals, true, Commentf("%v", resp.Waited))
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(requests, HasLen, 2)
+	c.Assert(requests[1].Sub(requests[0]) > 500*time.Millisecond, Equals, true)
+}
+
+func (s *BusySuite) TestWaitForBusyServerIsCancelled(c *C) {
+	g := &busyGate{}
+	g.hold(time.Minute)
+	g.hold(time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := g.wait(ctx)
+	c.Assert(err, Equals, context.DeadlineExceeded)
+
+	d, err := (&busyGate{}).wait(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, time.Duration(0))
+}
+
+func (s *BusySuite) TestSubscriptionWaitsForBusyServer(c *C) {
+	stream := "busy-4"
+	sim := newTestSimulator(CreateTestEvents(3, stream, server.URL, "Foo"), nil)
+	var mu sync.Mutex
+	busy := true
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		b := busy
+		busy = false
+		mu.Unlock()
+		if b {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		sim.ServeHTTP(w, r)
+	})
+
+	var dropped, reconnected time.Time
+	done := make(chan struct{})
+	sub := client.NewCatchUpSubscription(stream, 0, func(er *EventResponse) error {
+		if er.Event.EventNumber == 2 {
+			close(done)
+		}
+		return nil
+	})
+	sub.SetBackoff(time.Millisecond, time.Millisecond)
+	sub.SetDroppedHandler(func(err error) {
+		c.Check(err, FitsTypeOf, &ErrServerBusy{})
+		dropped = time.Now()
+	})
+	sub.SetReconnectedHandler(func(int) { reconnected = time.Now() })
+	sub.Start()
+	defer sub.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("subscription did not recover")
+	}
+	c.Assert(reconnected.Sub(dropped) > 500*time.Millisecond, Equals, true)
+}
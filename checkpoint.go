@@ -0,0 +1,116 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"sync"
+)
+
+// checkpointHistory is the $maxCount set on checkpoint streams.
+const checkpointHistory = 10
+
+// CheckpointStore stores the event number of the last event processed by a
+// named consumer, such as a Projector or a bridge, so that a consumer that is
+// restarted resumes after it.
+//
+// Load returns -1 if no checkpoint has been stored for the name.
+type CheckpointStore interface {
+	Load(name string) (int, error)
+	Store(name string, checkpoint int) error
+}
+
+// MemoryCheckpointStore is a CheckpointStore that holds checkpoints in memory.
+// It is useful for tests and for consumers that start from the beginning of the
+// stream each time they run.
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]int
+}
+
+// NewMemoryCheckpointStore returns a new *MemoryCheckpointStore.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]int)}
+}
+
+// Load returns the checkpoint stored for the name, or -1.
+func (m *MemoryCheckpointStore) Load(name string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cp, ok := m.checkpoints[name]; ok {
+		return cp, nil
+	}
+	return -1, nil
+}
+
+// Store stores the checkpoint for the name.
+func (m *MemoryCheckpointStore) Store(name string, checkpoint int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoints[name] = checkpoint
+	return nil
+}
+
+// checkpoint is the data of an event in a checkpoint stream.
+type checkpoint struct {
+	Checkpoint int `json:"checkpoint"`
+}
+
+// StreamCheckpointStore is a CheckpointStore that appends checkpoints to a
+// stream in the eventstore, so that a consumer needs no storage of its own.
+//
+// The checkpoints of the consumer named name are written to the stream returned
+// by CheckpointStreamName. The $maxCount of the stream is set by the first
+// Store made by the store so that only recent checkpoints are retained.
+type StreamCheckpointStore struct {
+	client *Client
+	mu     sync.Mutex
+	capped map[string]bool
+}
+
+// NewStreamCheckpointStore returns a new *StreamCheckpointStore that stores
+// checkpoints through the client.
+func NewStreamCheckpointStore(client *Client) *StreamCheckpointStore {
+	return &StreamCheckpointStore{client: client, capped: make(map[string]bool)}
+}
+
+// CheckpointStreamName returns the name of the stream in which a
+// StreamCheckpointStore stores the checkpoints of the consumer named name.
+func CheckpointStreamName(name string) string {
+	return "checkpoint-" + name
+}
+
+// Load returns the last checkpoint in the checkpoint stream, or -1 if the
+// stream does not exist.
+func (s *StreamCheckpointStore) Load(name string) (int, error) {
+	cp := checkpoint{Checkpoint: -1}
+	_, err := s.client.ReadLast(CheckpointStreamName(name), &cp)
+	switch err.(type) {
+	case nil:
+		return cp.Checkpoint, nil
+	case *ErrNotFound, *ErrNoMoreEvents:
+		return -1, nil
+	}
+	return -1, err
+}
+
+// Store appends the checkpoint to the checkpoint stream.
+func (s *StreamCheckpointStore) Store(name string, cp int) error {
+	stream := CheckpointStreamName(name)
+	writer := s.client.NewStreamWriter(stream)
+	if err := writer.Append(ExpectAny, NewEvent("", "Checkpoint", &checkpoint{Checkpoint: cp}, nil)); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.capped[name] {
+		return nil
+	}
+	if err := writer.WriteMetaData(stream, &StreamMetadata{MaxCount: Int(checkpointHistory)}); err != nil {
+		return err
+	}
+	s.capped[name] = true
+	return nil
+}
@@ -7,6 +7,7 @@ package goes
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -15,6 +16,9 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/jetbasrawi/go.geteventstore/internal/atom"
 )
@@ -29,10 +33,30 @@ import (
 // It is intended to provide access to data about the response in case
 // the user wants to inspect the response such as the status or the raw http
 // response.
+//
+// Waited is the time the request waited for the rate and concurrency limits of
+// the client before it was sent.
+//
+// The eventstore headers of the response are parsed into the remaining fields.
+// ETag is the ETag header, used to make conditional reads of feed pages and
+// metadata. CurrentVersion is the version of the stream reported in the
+// ES-CurrentVersion header when a write is rejected because of its expected
+// version. CommitPosition and PreparePosition are the position of a write in
+// the transaction log from the ES-CommitPosition and ES-PreparePosition
+// headers. Each of these is -1 when the header is absent. MaxAge is the time
+// for which the response may be cached according to its Cache-Control header,
+// which is long for feed pages that can no longer change and 0 for pages such
+// as the head of a stream.
 type Response struct {
 	*http.Response
-	Status     string
-	StatusCode int
+	Status          string
+	StatusCode      int
+	Waited          time.Duration
+	ETag            string
+	CurrentVersion  int
+	CommitPosition  int64
+	PreparePosition int64
+	MaxAge          time.Duration
 }
 
 // ErrorResponse encapsulates data about an interaction with the eventstore that
@@ -60,6 +84,14 @@ type basicAuthCredentials struct {
 	Password string
 }
 
+// defaultPageSize is the number of events requested in each feed page.
+const defaultPageSize = 20
+
+// maxDrain is the maximum number of bytes of an unread response body that are
+// read before the body is closed. Connections whose response bodies are read
+// to the end are returned to the connection pool for reuse.
+const maxDrain = 64 << 10
+
 // Client is the interface that the client should implement
 // type Client interface {
 // 	NewStreamReader(streamName string) *StreamReader
@@ -69,7 +101,7 @@ type basicAuthCredentials struct {
 // 	Do(req *http.Request, v io.Writer) (*Response, error)
 // 	GetEvent(url string) (*EventResponse, *Response, error)
 // 	GetMetadataURL(stream string) (string, *Response, error)
-// 	ReadFeed(url string) (*atom.Feed, *Response, error)
+// 	ReadFeed(url string) (*Feed, *Response, error)
 // 	SetHeader(key, value string)
 // 	DeleteHeader(key string)
 // }
@@ -86,20 +118,49 @@ type basicAuthCredentials struct {
 // on the client, however you can also directly use methods on the client
 // to interact with the eventstore if you want to create some custom behaviour.
 type Client struct {
-	client      *http.Client
-	baseURL     *url.URL
-	credentials *basicAuthCredentials
-	headers     map[string]string
+	client        *http.Client
+	baseURL       *url.URL
+	mu            sync.RWMutex
+	credentials   *basicAuthCredentials
+	trustedAuth   string
+	headers       map[string]string
+	features      map[Feature]bool
+	codecs        map[string]Codec
+	sem           chan struct{}
+	bucket        *tokenBucket
+	registry      *TypeRegistry
+	feedCache     FeedCache
+	compression   bool
+	compressAbove int
+	serverInfo    *ServerInfo
+	events        *eventCache
+	middleware    []Middleware
+	credProvider  CredentialsProvider
+	refreshMu     sync.Mutex
+	busy          *busyGate
+	requireMaster bool
+	preferMaster  bool
+	master        *url.URL
+	debug         *debugLog
+	feedFormat    FeedFormat
+	rewriteLinks  bool
+	listener      ConnectionListener
+	nodes         *nodeTracker
+	strict        bool
+	maxBuffered   int
 }
 
 // NewClient returns a new client.
 //
 // httpClient will usually be nil and the client will use the http.DefaultClient.
 // Should you want to implement behaviours at the transport level you can provide
-// your own *http.Client
+// your own *http.Client. Redirects are followed by the client rather than the
+// http.Client, so its CheckRedirect is not used.
 //
 // serverURL is the full URL to your eventstore server including protocol scheme and
-// port number.
+// port number. If the server is mounted under a path, such as behind a reverse
+// proxy, the path is included and the paths of the API are resolved relative
+// to it. See also SetRewriteLinks.
 func NewClient(httpClient *http.Client, serverURL string) (*Client, error) {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
@@ -113,17 +174,25 @@ func NewClient(httpClient *http.Client, serverURL string) (*Client, error) {
 		client:  httpClient,
 		baseURL: baseURL,
 		headers: make(map[string]string),
+		codecs:  defaultCodecs(),
+		busy:    &busyGate{},
+		nodes:   &nodeTracker{},
 	}
 	return c, nil
 }
 
 // NewStreamReader returns a new *StreamReader.
-func (c *Client) NewStreamReader(streamName string) *StreamReader {
+//
+// The reader is configured with options, such as WithPageSize.
+func (c *Client) NewStreamReader(streamName string, opts ...ReadOption) *StreamReader {
+	o := newReadOptions(opts)
 	return &StreamReader{
 		streamName: streamName,
 		client:     c,
 		version:    -1,
-		pageSize:   20,
+		pageSize:   o.pageSize,
+		eventTypes: o.eventTypes,
+		parent:     o.context(),
 	}
 }
 
@@ -138,11 +207,38 @@ func (c *Client) NewStreamWriter(streamName string) *StreamWriter {
 // SetBasicAuth sets the credentials for requests.
 //
 // Credentials will be read from the client before each request.
+// Setting basic authentication credentials replaces any trusted
+// authentication set with SetTrustedAuth.
 func (c *Client) SetBasicAuth(username, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.credentials = &basicAuthCredentials{
 		Username: username,
 		Password: password,
 	}
+	c.trustedAuth = ""
+}
+
+// SetTrustedAuth causes requests to be sent with the ES-TrustedAuth header
+// instead of basic authentication.
+//
+// The eventstore accepts the user and groups in the header when the request
+// comes from a trusted intermediary such as a gateway that has already
+// authenticated the user. The server must be configured to trust the header.
+// Setting trusted authentication replaces any credentials set with
+// SetBasicAuth. An empty user removes the trusted authentication.
+func (c *Client) SetTrustedAuth(user string, groups []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credentials = nil
+	c.trustedAuth = ""
+	if user == "" {
+		return
+	}
+	c.trustedAuth = user
+	if len(groups) > 0 {
+		c.trustedAuth += "; " + strings.Join(groups, ", ")
+	}
 }
 
 // GetEvent reads a single event from the eventstore.
@@ -153,12 +249,28 @@ func (c *Client) SetBasicAuth(username, password string) {
 // If an error occurs during the http request an *ErrorResponse will be returned
 // as the error. The *ErrorResponse will contain the raw http response and status
 // and a description of the error.
+// When the event is returned from the event cache of a Session the *Response
+// is nil.
 func (c *Client) GetEvent(url string) (*EventResponse, *Response, error) {
+	return c.getEvent(context.Background(), url)
+}
+
+// getEvent reads a single event with a request that is cancelled when ctx is
+// done.
+func (c *Client) getEvent(ctx context.Context, url string) (*EventResponse, *Response, error) {
+
+	cache := c.getEventCache()
+	if cache != nil {
+		if er, ok := cache.get(url); ok {
+			return er, nil, nil
+		}
+	}
 
 	r, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, nil, err
 	}
+	r = r.WithContext(ctx)
 
 	r.Header.Set("Accept", "application/vnd.eventstore.atom+json")
 
@@ -193,18 +305,28 @@ func (c *Client) GetEvent(url string) (*EventResponse, *Response, error) {
 	if err != nil {
 		return nil, resp, err
 	}
+	readEnvelope(ev)
 
 	e := EventResponse{}
 	e.Title = er.Title
 	e.ID = er.ID
 	e.Updated = er.Updated
+	if t, err := er.Updated.Time(); err == nil {
+		e.Updated = FormatTime(t)
+	}
 	e.Summary = er.Summary
 	e.Event = ev
+	if err := c.checkEvent(url, &e); err != nil {
+		return nil, resp, err
+	}
 
+	if cache != nil {
+		cache.add(url, &e)
+	}
 	return &e, resp, nil
 }
 
-// ReadFeed reads the atom feed for a stream and returns an *atom.Feed.
+// ReadFeed reads a page of the atom feed for a stream and returns a *Feed.
 //
 // The feed object returned may be nil in case of an error.
 // The *Response may also be nil if the error occurred before the http request.
@@ -212,27 +334,85 @@ func (c *Client) GetEvent(url string) (*EventResponse, *Response, error) {
 // raw http response and status.
 // If the error occurred during the http request an *ErrorResponse will be returned
 // and this will also contain the raw http request and status and an error message.
-func (c *Client) ReadFeed(url string) (*atom.Feed, *Response, error) {
+// If a feed cache is set the *Response is nil when the page is returned from the
+// cache without a request. See SetFeedCache.
+func (c *Client) ReadFeed(url string) (*Feed, *Response, error) {
+	return c.readFeed(context.Background(), url)
+}
+
+// readFeed reads a feed page with a request that is cancelled when ctx is
+// done.
+func (c *Client) readFeed(ctx context.Context, url string) (*Feed, *Response, error) {
 
 	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	req.Header.Set("Accept", "application/atom+xml")
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Accept", c.feedAccept())
+	if c.embedBody() {
+		q := req.URL.Query()
+		q.Set("embed", "body")
+		req.URL.RawQuery = q.Encode()
+	}
+
+	cache := c.getFeedCache()
+	var cached *CachedPage
+	if cache != nil {
+		key := req.URL.String()
+		if p, ok := cache.Get(key); ok {
+			if time.Now().Before(p.Expires) {
+				feed, err := parseFeed("", p.Body)
+				if err != nil {
+					return nil, nil, err
+				}
+				f := newFeed(feed, p.ETag)
+				if err := c.checkFeed(url, f); err != nil {
+					return nil, nil, err
+				}
+				return f, nil, nil
+			}
+			if p.ETag != "" {
+				cached = p
+				req.Header.Set("If-None-Match", p.ETag)
+			}
+		}
+	}
 
 	var b bytes.Buffer
 	resp, err := c.do(req, &b)
+	if e, ok := err.(*ErrUnexpected); ok && cached != nil && e.ErrorResponse.StatusCode == http.StatusNotModified {
+		feed, err := parseFeed("", cached.Body)
+		if err != nil {
+			return nil, resp, err
+		}
+		f := newFeed(feed, cached.ETag)
+		if err := c.checkFeed(url, f); err != nil {
+			return nil, resp, err
+		}
+		return f, resp, nil
+	}
 	if err != nil {
 		return nil, resp, err
 	}
 
-	feed, err := unmarshalFeed(bytes.NewReader(b.Bytes()))
+	if cache != nil {
+		if p := newCachedPage(resp.Response, b.Bytes(), time.Now()); p != nil {
+			cache.Add(req.URL.String(), p)
+		}
+	}
+
+	feed, err := parseFeed(resp.Header.Get("Content-Type"), b.Bytes())
 	if err != nil {
 		return nil, resp, err
 	}
 
-	return feed, resp, nil
+	f := newFeed(feed, resp.ETag)
+	if err := c.checkFeed(url, f); err != nil {
+		return nil, resp, err
+	}
+	return f, resp, nil
 }
 
 // GetFeedPath returns the path for a feedpage
@@ -241,8 +421,13 @@ func (c *Client) ReadFeed(url string) (*atom.Feed, *Response, error) {
 //
 // To get the path to the head of the stream, pass a negative integer in the version
 // argument and "backward" as the direction.
+//
+// The page size must be between 1 and MaxPageSize.
 func (c *Client) GetFeedPath(stream, direction string, version int, pageSize int) (string, error) {
 	ps := pageSize
+	if ps < 1 || ps > MaxPageSize {
+		return "", fmt.Errorf("Invalid page size %d. Allowed values are 1 to %d", ps, MaxPageSize)
+	}
 
 	dir := ""
 	switch direction {
@@ -280,23 +465,161 @@ func (c *Client) GetMetadataURL(stream string) (string, *Response, error) {
 	if err != nil {
 		return "", resp, err
 	}
-	for _, v := range f.Link {
-		if v.Rel == "metadata" {
-			return v.Href, resp, nil
-		}
+	return f.Links.Metadata, resp, nil
+}
+
+// ReadFirst reads the first event in a stream and deserializes the event data
+// into dest.
+//
+// The *EventResponse for the event is returned so that the event number, type and
+// metadata are available to the caller. dest may be nil if the event data does not
+// need to be deserialized.
+// If the stream contains no events an *ErrNoMoreEvents is returned.
+func (c *Client) ReadFirst(stream string, dest interface{}) (*EventResponse, error) {
+	url, err := c.GetFeedPath(stream, "forward", 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	e, _, err := c.readSingle(url, dest)
+	return e, err
+}
+
+// ReadLast reads the most recent event in a stream and deserializes the event
+// data into dest.
+//
+// The *EventResponse for the event is returned so that the event number, type and
+// metadata are available to the caller. dest may be nil if the event data does not
+// need to be deserialized.
+// If the stream contains no events an *ErrNoMoreEvents is returned.
+func (c *Client) ReadLast(stream string, dest interface{}) (*EventResponse, error) {
+	url, err := c.GetFeedPath(stream, "backward", -1, 1)
+	if err != nil {
+		return nil, err
+	}
+	e, _, err := c.readSingle(url, dest)
+	return e, err
+}
+
+// readLast reads the most recent event in a stream like ReadLast and also
+// returns the version of the stream read from the head of its feed.
+func (c *Client) readLast(stream string, dest interface{}) (*EventResponse, int, error) {
+	url, err := c.GetFeedPath(stream, "backward", -1, 1)
+	if err != nil {
+		return nil, -1, err
+	}
+	e, f, err := c.readSingle(url, dest)
+	if err != nil {
+		return nil, -1, err
+	}
+	return e, headVersion(f), nil
+}
+
+// ReadLastEvent returns the most recent event in a stream.
+//
+// If the stream contains no events an *ErrNoEvents is returned. If the stream
+// does not exist an *ErrNotFound is returned.
+func (c *Client) ReadLastEvent(stream string) (*EventResponse, error) {
+	e, err := c.ReadLast(stream, nil)
+	if _, ok := err.(*ErrNoMoreEvents); ok {
+		return nil, &ErrNoEvents{}
+	}
+	return e, err
+}
+
+// GetStreamHeadVersion returns the version of the most recent event in a
+// stream.
+//
+// The version is read from the head of the stream feed with a single request.
+// It is the position of the event in the stream, which for streams such as
+// $ce-order, whose entries link to events in other streams, differs from the
+// event number of the event it links to.
+// If the stream contains no events an *ErrNoEvents is returned. If the stream
+// does not exist an *ErrNotFound is returned.
+func (c *Client) GetStreamHeadVersion(stream string) (int, error) {
+	url, err := c.GetFeedPath(stream, "backward", -1, 1)
+	if err != nil {
+		return -1, err
+	}
+
+	f, _, err := c.ReadFeed(url)
+	if err != nil {
+		return -1, err
+	}
+	if len(f.Entry) <= 0 {
+		return -1, &ErrNoEvents{}
+	}
+	return headVersion(f), nil
+}
+
+// headVersion returns the version of the first entry of a page read backward
+// from the head of a stream.
+//
+// The previous link of the page points at the version after it. The url of the
+// entry is only used if the page has no previous link, as the entries of a
+// stream such as $ce-order link to events in other streams.
+func headVersion(f *Feed) int {
+	if v := feedLinkVersion(f.Links.Previous); v > 0 {
+		return v - 1
+	}
+	return eventNumberFromURL(f.Entry[0].Link[1].Href)
+}
+
+// feedLinkVersion returns the version a feed page link such as
+// /streams/order-1/5/forward/20 starts at, or -1 if the link is empty or does
+// not start at a version.
+func feedLinkVersion(link string) int {
+	parts := strings.Split(strings.TrimRight(link, "/"), "/")
+	if len(parts) < 3 {
+		return -1
+	}
+	v, err := strconv.Atoi(parts[len(parts)-3])
+	if err != nil {
+		return -1
 	}
-	return "", resp, nil
+	return v
+}
+
+// readSingle reads the feed page at the url provided and returns the first
+// entry on the page with its data deserialized into dest, along with the feed.
+func (c *Client) readSingle(url string, dest interface{}) (*EventResponse, *Feed, error) {
+	f, _, err := c.ReadFeed(url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(f.Entry) <= 0 {
+		return nil, nil, &ErrNoMoreEvents{}
+	}
+
+	eventURL := strings.TrimRight(f.Entry[0].Link[1].Href, "/")
+	e, _, err := c.GetEvent(eventURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e == nil {
+		return nil, nil, &ErrNoMoreEvents{}
+	}
+
+	if err := c.decodeEvent(e, dest, nil); err != nil {
+		return nil, nil, err
+	}
+
+	return e, f, nil
 }
 
 // SetHeader adds a header to the collection of headers that will be used on http requests.
 //
 // Any headers that are set on the client will be included in requests to the eventstore.
 func (c *Client) SetHeader(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.headers[key] = value
 }
 
 // DeleteHeader deletes a header from the collection of headers.
 func (c *Client) DeleteHeader(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	delete(c.headers, key)
 }
 
@@ -312,6 +635,19 @@ func (c *Client) DeleteHeader(key string) {
 //
 // http://docs.geteventstore.com/http-api/3.8.0/deleting-a-stream/
 func (c *Client) DeleteStream(streamName string, hardDelete bool) (*Response, error) {
+	return c.deleteStream(context.Background(), streamName, hardDelete, ExpectAny)
+}
+
+// DeleteStreamContext deletes a stream like DeleteStream with a request that
+// is cancelled when ctx is done and carries the headers of ctx. See
+// WithRequestHeaders.
+func (c *Client) DeleteStreamContext(ctx context.Context, streamName string, hardDelete bool) (*Response, error) {
+	return c.deleteStream(ctx, streamName, hardDelete, ExpectAny)
+}
+
+// deleteStream deletes a stream. Unless expectedVersion is ExpectAny the stream
+// is only deleted if its version matches.
+func (c *Client) deleteStream(ctx context.Context, streamName string, hardDelete bool, expectedVersion ExpectedVersion) (*Response, error) {
 
 	url := fmt.Sprintf("/streams/%s", streamName)
 
@@ -319,10 +655,14 @@ func (c *Client) DeleteStream(streamName string, hardDelete bool) (*Response, er
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	if hardDelete {
 		req.Header.Set("ES-HardDelete", "true")
 	}
+	if err := setExpectedVersion(req, expectedVersion); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.do(req, nil)
 	if err != nil {
@@ -341,9 +681,8 @@ func (c *Client) newRequest(method, urlString string, body interface{}) (*http.R
 		return nil, err
 	}
 
-	if !url.IsAbs() {
-		url = c.baseURL.ResolveReference(url)
-	}
+	write := isWrite(method)
+	url = c.resolveURL(url, write)
 
 	var buf io.ReadWriter
 	if body != nil {
@@ -359,13 +698,25 @@ func (c *Client) newRequest(method, urlString string, body interface{}) (*http.R
 		return nil, err
 	}
 
+	c.mu.RLock()
 	if c.credentials != nil {
 		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
 	}
+	if c.trustedAuth != "" {
+		req.Header.Set("ES-TrustedAuth", c.trustedAuth)
+	}
+
+	if c.compression {
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+	}
+	if write && c.requireMaster {
+		req.Header.Set("ES-RequireMaster", "True")
+	}
 
 	for k, v := range c.headers {
 		req.Header.Set(k, v)
 	}
+	c.mu.RUnlock()
 
 	return req, nil
 }
@@ -382,27 +733,90 @@ func (c *Client) do(req *http.Request, v io.Writer) (*Response, error) {
 	// with the response for diagnostic purposes.
 	// send will be used to make the request.
 	var keep, send io.ReadCloser
+	var sendBuf []byte
 
 	if req.Body != nil {
 		if buf, err := ioutil.ReadAll(req.Body); err == nil {
 			keep = ioutil.NopCloser(bytes.NewReader(buf))
-			send = ioutil.NopCloser(bytes.NewReader(buf))
+			sendBuf = buf
+			if min := c.requestCompression(); min > 0 && len(buf) >= min {
+				if z, err := gzipBytes(buf); err == nil {
+					sendBuf = z
+					req.Header.Set("Content-Encoding", "gzip")
+				}
+			}
+			send = ioutil.NopCloser(bytes.NewReader(sendBuf))
 			req.Body = send
+			req.ContentLength = int64(len(sendBuf))
 		}
 	}
 
 	// An error is returned if caused by client policy (such as CheckRedirect),
 	// or if there was an HTTP protocol error. A non-2xx response doesn't cause
 	// an error.
-	resp, err := c.client.Do(req)
+	setRequestHeaders(req)
+
+	busy, err := c.busy.wait(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	waited, release, err := c.acquire(req.Context())
 	if err != nil {
 		return nil, err
 	}
+	waited += busy
+	defer release()
+
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		if isWrite(req.Method) {
+			c.forgetMaster(req.URL)
+		}
+		return nil, err
+	}
+
+	// A request rejected because the credentials have been rotated is sent
+	// again once with credentials from the provider.
+	if p := c.credentialsProvider(); p != nil && resp.StatusCode == http.StatusUnauthorized {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrain))
+		resp.Body.Close()
+		if err := c.refreshCredentials(req, p); err != nil {
+			return nil, err
+		}
+		if send != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(sendBuf))
+		}
+		resp, err = c.roundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	defer resp.Body.Close()
+	// Requests sent to a node that is not the master may be redirected to the
+	// master.
+	var body []byte
+	if send != nil {
+		body = sendBuf
+	}
+	resp, err = c.followRedirects(req, resp, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Any part of the body that is not read is discarded before the body is
+	// closed so that the connection can be reused.
+	defer func(body io.ReadCloser) {
+		io.Copy(ioutil.Discard, io.LimitReader(body, maxDrain))
+		body.Close()
+	}(resp.Body)
+
+	if err := decompress(resp); err != nil {
+		return nil, err
+	}
 
 	// Create a *Response to wrap the http.Response
 	response := newResponse(resp)
+	response.Waited = waited
 
 	// After the request has been made the req.Body will be unreadable.
 	// assign keep to the request body so that it can be returned in the
@@ -414,6 +828,13 @@ func (c *Client) do(req *http.Request, v io.Writer) (*Response, error) {
 	// If the request returned an error status checkResponse will return an
 	// *errorResponse containing the original request, status code and status message
 	err = getError(resp, req)
+	switch e := err.(type) {
+	case *ErrServerBusy:
+		c.busy.hold(e.RetryAfter)
+		c.notify(&ConnectionEvent{Kind: RetryScheduled, Node: nodeOf(req.URL), Err: err, RetryAfter: e.RetryAfter})
+	case *ErrUnauthorized:
+		c.notify(&ConnectionEvent{Kind: AuthFailed, Node: nodeOf(req.URL), Err: err})
+	}
 	if err != nil {
 		// even though there was an error, we still return the response
 		// in case the caller wants to inspect it further
@@ -449,7 +870,12 @@ func getError(r *http.Response, req *http.Request) error {
 		return &ErrBadRequest{ErrorResponse: errorResponse}
 	case http.StatusUnauthorized:
 		return &ErrUnauthorized{ErrorResponse: errorResponse}
+	case http.StatusTooManyRequests:
+		return &ErrServerBusy{ErrorResponse: errorResponse, RetryAfter: retryAfter(r, time.Now())}
 	case http.StatusServiceUnavailable:
+		if r.Header.Get("Retry-After") != "" {
+			return &ErrServerBusy{ErrorResponse: errorResponse, RetryAfter: retryAfter(r, time.Now())}
+		}
 		return &ErrTemporarilyUnavailable{ErrorResponse: errorResponse}
 	case http.StatusNotFound:
 		return &ErrNotFound{ErrorResponse: errorResponse}
@@ -477,5 +903,49 @@ func newResponse(r *http.Response) *Response {
 	response := &Response{Response: r}
 	response.Status = r.Status
 	response.StatusCode = r.StatusCode
+	response.ETag = r.Header.Get("ETag")
+	response.CurrentVersion = int(headerInt(r.Header, "ES-CurrentVersion"))
+	response.CommitPosition = headerInt(r.Header, "ES-CommitPosition")
+	response.PreparePosition = headerInt(r.Header, "ES-PreparePosition")
+	response.MaxAge = maxAge(r.Header)
 	return response
 }
+
+// headerInt returns the integer value of the header, or -1 if the header is
+// absent or is not an integer.
+func headerInt(h http.Header, key string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(h.Get(key)), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// maxAge returns the max-age of the Cache-Control header, or 0 if the response
+// must not be cached without revalidation.
+func maxAge(h http.Header) time.Duration {
+	d, noStore, noCache := cacheControl(h)
+	if noStore || noCache {
+		return 0
+	}
+	return d
+}
+
+// cacheControl parses the Cache-Control header and returns its max-age, or 0
+// if it has none, and whether it has the no-store and no-cache directives.
+func cacheControl(h http.Header) (maxAge time.Duration, noStore, noCache bool) {
+	for _, v := range strings.Split(h.Get("Cache-Control"), ",") {
+		v = strings.TrimSpace(strings.ToLower(v))
+		switch {
+		case v == "no-store":
+			noStore = true
+		case v == "no-cache":
+			noCache = true
+		case strings.HasPrefix(v, "max-age="):
+			if s, err := strconv.Atoi(v[len("max-age="):]); err == nil && s > 0 {
+				maxAge = time.Duration(s) * time.Second
+			}
+		}
+	}
+	return maxAge, noStore, noCache
+}
@@ -16,6 +16,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -165,9 +167,12 @@ func (s *ClientSuite) TestDo(c *C) {
 	c.Assert(err, IsNil)
 
 	want := &Response{
-		Response:   resp.Response,
-		StatusCode: http.StatusCreated,
-		Status:     "201 Created"}
+		Response:        resp.Response,
+		StatusCode:      http.StatusCreated,
+		Status:          "201 Created",
+		CurrentVersion:  -1,
+		CommitPosition:  -1,
+		PreparePosition: -1}
 
 	c.Assert(want, DeepEquals, resp)
 }
@@ -218,6 +223,50 @@ func (s *ClientSuite) TestNewResponse(c *C) {
 
 	c.Assert(resp.Status, Equals, "201 Created")
 	c.Assert(resp.StatusCode, Equals, http.StatusCreated)
+	c.Assert(resp.ETag, Equals, "")
+	c.Assert(resp.CurrentVersion, Equals, -1)
+	c.Assert(resp.CommitPosition, Equals, int64(-1))
+	c.Assert(resp.PreparePosition, Equals, int64(-1))
+	c.Assert(resp.MaxAge, Equals, time.Duration(0))
+}
+
+func (s *ClientSuite) TestNewResponseParsesHeaders(c *C) {
+	r := http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Etag":               []string{`"12;-1296467268"`},
+			"Es-Currentversion":  []string{"12"},
+			"Es-Commitposition":  []string{"4096"},
+			"Es-Prepareposition": []string{"4000"},
+			"Cache-Control":      []string{"max-age=31536000, public"},
+		},
+	}
+
+	resp := newResponse(&r)
+
+	c.Assert(resp.ETag, Equals, `"12;-1296467268"`)
+	c.Assert(resp.CurrentVersion, Equals, 12)
+	c.Assert(resp.CommitPosition, Equals, int64(4096))
+	c.Assert(resp.PreparePosition, Equals, int64(4000))
+	c.Assert(resp.MaxAge, Equals, 31536000*time.Second)
+
+	r.Header.Set("Cache-Control", "max-age=0, no-cache, must-revalidate")
+	r.Header.Set("ES-CurrentVersion", "twelve")
+	resp = newResponse(&r)
+	c.Assert(resp.MaxAge, Equals, time.Duration(0))
+	c.Assert(resp.CurrentVersion, Equals, -1)
+}
+
+func (s *ClientSuite) TestConcurrencyViolationResponseHasCurrentVersion(c *C) {
+	mux.HandleFunc("/streams/current-version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ES-CurrentVersion", "7")
+		http.Error(w, "Wrong expected EventNumber", http.StatusBadRequest)
+	})
+
+	expected := 3
+	resp, err := client.NewStreamWriter("current-version").appendWithHeaders(ExpectedVersion(expected), []*Event{typed("Foo")[0]}, nil)
+	c.Assert(err, FitsTypeOf, &ErrConcurrencyViolation{})
+	c.Assert(resp.CurrentVersion, Equals, 7)
 }
 
 func (s *ClientSuite) TestGetEvent(c *C) {
@@ -368,3 +417,145 @@ func (s *ClientSuite) TestGetFeedPathInvalidDirectionAndVersion(c *C) {
 	c.Assert(got, DeepEquals, want)
 	c.Assert(err, DeepEquals, fmt.Errorf("Invalid Direction (%s) and version (head) combination.\n", direction))
 }
+
+func (s *ClientSuite) TestReadFirst(c *C) {
+	stream := "read-first"
+	es := CreateTestEvents(5, stream, server.URL, "EventTypeX")
+	setupSimulator(es, nil)
+
+	want := &FooEvent{}
+	raw, _ := es[0].Data.(*json.RawMessage)
+	_ = json.Unmarshal(*raw, want)
+
+	got := &FooEvent{}
+	e, err := client.ReadFirst(stream, got)
+	c.Assert(err, IsNil)
+	c.Assert(e.Event.EventNumber, Equals, 0)
+	c.Assert(got, DeepEquals, want)
+}
+
+func (s *ClientSuite) TestReadLast(c *C) {
+	stream := "read-last"
+	es := CreateTestEvents(5, stream, server.URL, "EventTypeX")
+	setupSimulator(es, nil)
+
+	want := &FooEvent{}
+	raw, _ := es[4].Data.(*json.RawMessage)
+	_ = json.Unmarshal(*raw, want)
+
+	got := &FooEvent{}
+	e, err := client.ReadLast(stream, got)
+	c.Assert(err, IsNil)
+	c.Assert(e.Event.EventNumber, Equals, 4)
+	c.Assert(got, DeepEquals, want)
+}
+
+func (s *ClientSuite) TestReadLastReturnsErrNotFoundWhenStreamDoesNotExist(c *C) {
+	e, err := client.ReadLast("does-not-exist", nil)
+	c.Assert(e, IsNil)
+	c.Assert(typeOf(err), Equals, "ErrNotFound")
+}
+
+func (s *ClientSuite) TestReadLastEvent(c *C) {
+	stream := "read-last-event"
+	es := CreateTestEvents(5, stream, server.URL, "EventTypeX")
+	setupSimulator(es, nil)
+
+	e, err := client.ReadLastEvent(stream)
+	c.Assert(err, IsNil)
+	c.Assert(e.Event.EventNumber, Equals, 4)
+	c.Assert(e.Event.EventID, Equals, es[4].EventID)
+}
+
+func (s *ClientSuite) TestGetStreamHeadVersion(c *C) {
+	stream := "head-version"
+	es := CreateTestEvents(25, stream, server.URL, "EventTypeX")
+	setupSimulator(es, nil)
+
+	v, err := client.GetStreamHeadVersion(stream)
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, 24)
+}
+
+// serveLinkedStream serves a stream, such as $ce-order, whose entries link to
+// the events provided, in the order provided, and serves the streams of the
+// events. The events of each stream must be numbered from 0.
+func serveLinkedStream(stream string, linked []*Event) {
+	links := make([]*Event, len(linked))
+	streams := make(map[string][]*Event)
+	for i, e := range linked {
+		links[i] = CreateTestEvent(stream, server.URL, "$>", i, nil, nil)
+		links[i].Links = e.Links
+		streams[e.EventStreamID] = append(streams[e.EventStreamID], e)
+	}
+	mux.Handle("/streams/"+stream+"/", newTestSimulator(links, nil))
+	for name, es := range streams {
+		mux.Handle("/streams/"+name+"/", newTestSimulator(es, nil))
+	}
+}
+
+// interleaved returns n events in each of the streams with the events of the
+// streams taking turns, so that events with the same number in different
+// streams follow each other.
+func interleaved(n int, streams ...string) []*Event {
+	es := []*Event{}
+	for i := 0; i < n; i++ {
+		for _, stream := range streams {
+			raw := json.RawMessage(fmt.Sprintf(`{"stream":%q,"n":%d}`, stream, i))
+			es = append(es, CreateTestEvent(stream, server.URL, "Foo", i, &raw, nil))
+		}
+	}
+	return es
+}
+
+func (s *ClientSuite) TestGetStreamHeadVersionOfLinkedStream(c *C) {
+	serveLinkedStream("$ce-order", interleaved(3, "order-1", "order-2"))
+
+	v, err := client.GetStreamHeadVersion("$ce-order")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, 5)
+
+	e, err := client.ReadLastEvent("$ce-order")
+	c.Assert(err, IsNil)
+	c.Assert(e.Event.EventStreamID, Equals, "order-2")
+	c.Assert(e.Event.EventNumber, Equals, 2)
+}
+
+func (s *ClientSuite) TestHeadOfEmptyStreamReturnsErrNoEvents(c *C) {
+	stream := "empty-stream"
+	mux.HandleFunc("/streams/"+stream+"/head/backward/1", func(w http.ResponseWriter, r *http.Request) {
+		f := &atom.Feed{StreamID: stream}
+		fmt.Fprint(w, f.PrettyPrint())
+	})
+
+	_, err := client.GetStreamHeadVersion(stream)
+	c.Assert(err, FitsTypeOf, &ErrNoEvents{})
+
+	e, err := client.ReadLastEvent(stream)
+	c.Assert(e, IsNil)
+	c.Assert(err, FitsTypeOf, &ErrNoEvents{})
+}
+
+func (s *ClientSuite) TestHeadOfMissingStreamReturnsErrNotFound(c *C) {
+	_, err := client.GetStreamHeadVersion("does-not-exist")
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+}
+
+func (s *ClientSuite) TestRequestsAreSentWithTrustedAuthIfSet(c *C) {
+	client.SetBasicAuth("user", "pass")
+	client.SetTrustedAuth("alice", []string{"ops", "$admins"})
+
+	req, err := client.newRequest(http.MethodGet, "/streams/something", nil)
+	c.Assert(err, IsNil)
+	c.Assert(req.Header.Get("ES-TrustedAuth"), Equals, "alice; ops, $admins")
+	c.Assert(req.Header.Get("Authorization"), Equals, "")
+
+	client.SetTrustedAuth("bob", nil)
+	req, _ = client.newRequest(http.MethodGet, "/streams/something", nil)
+	c.Assert(req.Header.Get("ES-TrustedAuth"), Equals, "bob")
+
+	client.SetBasicAuth("user", "pass")
+	req, _ = client.newRequest(http.MethodGet, "/streams/something", nil)
+	c.Assert(req.Header.Get("ES-TrustedAuth"), Equals, "")
+	c.Assert(req.Header.Get("Authorization"), Not(Equals), "")
+}
@@ -0,0 +1,280 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CloudEventsSpecVersion is the version of the CloudEvents specification
+// implemented by CloudEvent.
+const CloudEventsSpecVersion = "1.0"
+
+// cloudEventAttributes contains the names of the context attributes defined by
+// the CloudEvents specification. Metadata keys with these names are not mapped
+// to extension attributes.
+var cloudEventAttributes = map[string]bool{
+	"specversion":     true,
+	"id":              true,
+	"source":          true,
+	"type":            true,
+	"subject":         true,
+	"time":            true,
+	"datacontenttype": true,
+	"dataschema":      true,
+	"data":            true,
+	"data_base64":     true,
+}
+
+// CloudEvent is a CloudEvents v1.0 event in the structured JSON format.
+//
+// For more information on the format see:
+// https://github.com/cloudevents/spec/blob/v1.0/json-format.md
+type CloudEvent struct {
+	SpecVersion     string
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	Time            string
+	DataContentType string
+	DataSchema      string
+	Data            json.RawMessage
+	DataBase64      string
+	Extensions      map[string]interface{}
+}
+
+// MarshalJSON renders the event in the CloudEvents structured JSON format with
+// extension attributes as top level members.
+func (ce *CloudEvent) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(ce.Extensions)+10)
+	for k, v := range ce.Extensions {
+		m[k] = v
+	}
+	m["specversion"] = ce.SpecVersion
+	m["id"] = ce.ID
+	m["source"] = ce.Source
+	m["type"] = ce.Type
+	set := func(k, v string) {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	set("subject", ce.Subject)
+	set("time", ce.Time)
+	set("datacontenttype", ce.DataContentType)
+	set("dataschema", ce.DataSchema)
+	set("data_base64", ce.DataBase64)
+	if len(ce.Data) > 0 {
+		m["data"] = ce.Data
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON parses an event in the CloudEvents structured JSON format.
+// Members that are not context attributes are returned as extensions.
+func (ce *CloudEvent) UnmarshalJSON(b []byte) error {
+	m := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	str := func(k string, dst *string) error {
+		if v, ok := m[k]; ok {
+			if err := json.Unmarshal(v, dst); err != nil {
+				return fmt.Errorf("CloudEvent attribute %s must be a string", k)
+			}
+		}
+		return nil
+	}
+
+	*ce = CloudEvent{}
+	for k, dst := range map[string]*string{
+		"specversion":     &ce.SpecVersion,
+		"id":              &ce.ID,
+		"source":          &ce.Source,
+		"type":            &ce.Type,
+		"subject":         &ce.Subject,
+		"time":            &ce.Time,
+		"datacontenttype": &ce.DataContentType,
+		"dataschema":      &ce.DataSchema,
+		"data_base64":     &ce.DataBase64,
+	} {
+		if err := str(k, dst); err != nil {
+			return err
+		}
+	}
+	ce.Data = m["data"]
+
+	for k, v := range m {
+		if cloudEventAttributes[k] {
+			continue
+		}
+		var ext interface{}
+		if err := json.Unmarshal(v, &ext); err != nil {
+			return err
+		}
+		if ce.Extensions == nil {
+			ce.Extensions = make(map[string]interface{})
+		}
+		ce.Extensions[k] = ext
+	}
+	return nil
+}
+
+// ToCloudEvent converts an event to a CloudEvent.
+//
+// The event id is mapped to id, the event type to type and the stream to
+// subject. source identifies the producer of the event and is required by the
+// CloudEvents specification.
+//
+// Metadata members with names that are valid CloudEvents attribute names and
+// primitive values are mapped to extension attributes. Other metadata members
+// are not mapped.
+//
+// JSON event data is mapped to data with a datacontenttype of application/json.
+// Event data written with a codec other than JSON is mapped to data_base64 with
+// the content type recorded with the event.
+func ToCloudEvent(e *Event, source string) (*CloudEvent, error) {
+	ce := &CloudEvent{
+		SpecVersion: CloudEventsSpecVersion,
+		ID:          e.EventID,
+		Source:      source,
+		Type:        e.EventType,
+		Subject:     e.EventStreamID,
+	}
+
+	meta := make(map[string]interface{})
+	if e.MetaData != nil {
+		b, err := json.Marshal(e.MetaData)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 0 && b[0] == '{' {
+			if err := json.Unmarshal(b, &meta); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if ct, ok := meta[ContentTypeMetaDataKey].(string); ok && ct != "" {
+		var raw []byte
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		ce.DataContentType = ct
+		ce.DataBase64 = base64.StdEncoding.EncodeToString(raw)
+	} else if string(data) != "null" {
+		ce.DataContentType = "application/json"
+		ce.Data = json.RawMessage(data)
+	}
+
+	for k, v := range meta {
+		if cloudEventAttributes[k] || !validCloudEventAttribute(k) {
+			continue
+		}
+		switch v.(type) {
+		case string, float64, bool:
+			if ce.Extensions == nil {
+				ce.Extensions = make(map[string]interface{})
+			}
+			ce.Extensions[k] = v
+		}
+	}
+
+	return ce, nil
+}
+
+// CloudEvent converts the event in the EventResponse to a CloudEvent using
+// ToCloudEvent and sets the time of the CloudEvent to the time the event was
+// updated.
+func (e *EventResponse) CloudEvent(source string) (*CloudEvent, error) {
+	ce, err := ToCloudEvent(e.Event, source)
+	if err != nil {
+		return nil, err
+	}
+	if e.Updated != "" {
+		t, err := e.Updated.Time()
+		if err == nil {
+			ce.Time = t.Format(time.RFC3339Nano)
+		}
+	}
+	return ce, nil
+}
+
+// FromCloudEvent converts a CloudEvent to an event that can be appended to a
+// stream.
+//
+// id is mapped to the event id, type to the event type and subject to the
+// stream. Extension attributes are mapped to members of the event metadata.
+// data_base64 is mapped to event data with the content type of the CloudEvent
+// recorded so that the data can be read with the matching codec.
+func FromCloudEvent(ce *CloudEvent) (*Event, error) {
+	if ce.SpecVersion != CloudEventsSpecVersion {
+		return nil, fmt.Errorf("Unsupported CloudEvents spec version %s", ce.SpecVersion)
+	}
+
+	e := &Event{
+		EventID:       ce.ID,
+		EventType:     ce.Type,
+		EventStreamID: ce.Subject,
+	}
+
+	var meta map[string]interface{}
+	if len(ce.Extensions) > 0 {
+		meta = make(map[string]interface{}, len(ce.Extensions))
+		for k, v := range ce.Extensions {
+			meta[k] = v
+		}
+	}
+
+	switch {
+	case ce.DataBase64 != "":
+		raw, err := base64.StdEncoding.DecodeString(ce.DataBase64)
+		if err != nil {
+			return nil, err
+		}
+		ct := ce.DataContentType
+		if ct == "" {
+			ct = RawCodec{}.ContentType()
+		}
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+		meta[ContentTypeMetaDataKey] = ct
+		e.Data = raw
+	case len(ce.Data) > 0:
+		data := ce.Data
+		e.Data = &data
+	}
+
+	if meta != nil {
+		e.MetaData = meta
+	}
+	return e, nil
+}
+
+// validCloudEventAttribute returns true if name is a valid CloudEvents
+// attribute name consisting only of lower case letters and digits.
+func validCloudEventAttribute(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&CloudEventSuite{})
+
+type CloudEventSuite struct{}
+
+func (s *CloudEventSuite) TestToCloudEvent(c *C) {
+	data := &MyDataType{Field1: 1, Field2: "two"}
+	meta := map[string]interface{}{"traceid": "abc", "retries": 2, "Not-Valid": "x", "nested": map[string]int{"a": 1}}
+	id := NewUUID()
+	e := NewEvent(id, "SomeType", data, meta)
+	e.EventStreamID = "some-stream"
+
+	ce, err := ToCloudEvent(e, "/orders")
+	c.Assert(err, IsNil)
+	c.Assert(ce.SpecVersion, Equals, "1.0")
+	c.Assert(ce.ID, Equals, id)
+	c.Assert(ce.Type, Equals, "SomeType")
+	c.Assert(ce.Subject, Equals, "some-stream")
+	c.Assert(ce.Source, Equals, "/orders")
+	c.Assert(ce.DataContentType, Equals, "application/json")
+	c.Assert(string(ce.Data), Equals, `{"my_field_1":1,"my_field_2":"two"}`)
+	c.Assert(ce.Extensions, DeepEquals, map[string]interface{}{"traceid": "abc", "retries": float64(2)})
+}
+
+func (s *CloudEventSuite) TestStructuredJSONRoundTrip(c *C) {
+	in := `{"specversion":"1.0","id":"1","source":"/src","type":"T","subject":"s",` +
+		`"time":"2016-01-01T00:00:00Z","data":{"a":1},"traceid":"abc"}`
+
+	ce := &CloudEvent{}
+	c.Assert(json.Unmarshal([]byte(in), ce), IsNil)
+	c.Assert(ce.Time, Equals, "2016-01-01T00:00:00Z")
+	c.Assert(string(ce.Data), Equals, `{"a":1}`)
+	c.Assert(ce.Extensions, DeepEquals, map[string]interface{}{"traceid": "abc"})
+
+	out, err := json.Marshal(ce)
+	c.Assert(err, IsNil)
+	got := map[string]interface{}{}
+	want := map[string]interface{}{}
+	c.Assert(json.Unmarshal(out, &got), IsNil)
+	c.Assert(json.Unmarshal([]byte(in), &want), IsNil)
+	c.Assert(got, DeepEquals, want)
+}
+
+func (s *CloudEventSuite) TestFromCloudEvent(c *C) {
+	ce := &CloudEvent{
+		SpecVersion: "1.0",
+		ID:          "1",
+		Source:      "/src",
+		Type:        "T",
+		Subject:     "s",
+		Data:        json.RawMessage(`{"my_field_1":5}`),
+		Extensions:  map[string]interface{}{"traceid": "abc"},
+	}
+
+	e, err := FromCloudEvent(ce)
+	c.Assert(err, IsNil)
+	c.Assert(e.EventID, Equals, "1")
+	c.Assert(e.EventType, Equals, "T")
+	c.Assert(e.EventStreamID, Equals, "s")
+	c.Assert(e.MetaData, DeepEquals, map[string]interface{}{"traceid": "abc"})
+
+	back, err := ToCloudEvent(e, "/src")
+	c.Assert(err, IsNil)
+	c.Assert(back, DeepEquals, &CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "1",
+		Source:          "/src",
+		Type:            "T",
+		Subject:         "s",
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"my_field_1":5}`),
+		Extensions:      map[string]interface{}{"traceid": "abc"},
+	})
+}
+
+func (s *CloudEventSuite) TestBinaryDataRoundTrip(c *C) {
+	ce := &CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "1",
+		Source:          "/src",
+		Type:            "T",
+		DataContentType: "application/octet-stream",
+		DataBase64:      "AP8Q",
+	}
+
+	e, err := FromCloudEvent(ce)
+	c.Assert(err, IsNil)
+	c.Assert(e.Data, DeepEquals, []byte{0x00, 0xff, 0x10})
+
+	back, err := ToCloudEvent(e, "/src")
+	c.Assert(err, IsNil)
+	c.Assert(back.DataBase64, Equals, "AP8Q")
+	c.Assert(back.DataContentType, Equals, "application/octet-stream")
+	c.Assert(back.Data, IsNil)
+	c.Assert(back.Extensions, IsNil)
+}
+
+func (s *CloudEventSuite) TestEventResponseCloudEventSetsTime(c *C) {
+	er := &EventResponse{Updated: "2016-01-02T03:04:05+00:00", Event: NewEvent("", "T", nil, nil)}
+	ce, err := er.CloudEvent("/src")
+	c.Assert(err, IsNil)
+	c.Assert(ce.Time, Equals, "2016-01-02T03:04:05Z")
+}
+
+func (s *CloudEventSuite) TestFromCloudEventRejectsOtherSpecVersions(c *C) {
+	_, err := FromCloudEvent(&CloudEvent{SpecVersion: "0.3"})
+	c.Assert(err, ErrorMatches, "Unsupported CloudEvents spec version 0.3")
+}
@@ -0,0 +1,284 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+)
+
+// ContentTypeMetaDataKey is the event metadata key used to record the content
+// type of an event that was serialized with a codec other than JSON.
+const ContentTypeMetaDataKey = "goesContentType"
+
+// encodedMetaDataKey is the event metadata key used to hold the serialized
+// metadata of an event that was serialized with a codec other than JSON.
+const encodedMetaDataKey = "goesMetaData"
+
+// metaDataContentTypeKey is the event metadata key used to record the content
+// type of the serialized metadata of an event when it differs from the content
+// type of the data.
+const metaDataContentTypeKey = "goesMetaDataContentType"
+
+// Codec serializes and deserializes event data and metadata.
+//
+// The content type returned by ContentType is recorded with each event written
+// using the codec and is used to select the codec when the event is read.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec serializes event data and metadata as JSON. This is the default.
+type JSONCodec struct{}
+
+// ContentType returns application/json.
+func (JSONCodec) ContentType() string { return "application/json" }
+
+// Marshal returns the JSON encoding of v.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal parses the JSON encoded data into v.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// RawCodec writes event data and metadata as raw bytes.
+//
+// Values to be marshaled must be a []byte, a string or implement
+// encoding.BinaryMarshaler. Values to be unmarshaled into must be a *[]byte,
+// a *string or implement encoding.BinaryUnmarshaler.
+type RawCodec struct{}
+
+// ContentType returns application/octet-stream.
+func (RawCodec) ContentType() string { return "application/octet-stream" }
+
+// Marshal returns the bytes of v.
+func (RawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case []byte:
+		return t, nil
+	case string:
+		return []byte(t), nil
+	case encoding.BinaryMarshaler:
+		return t.MarshalBinary()
+	}
+	return nil, fmt.Errorf("Cannot marshal %T as raw bytes", v)
+}
+
+// Unmarshal copies data into v.
+func (RawCodec) Unmarshal(data []byte, v interface{}) error {
+	switch t := v.(type) {
+	case *[]byte:
+		*t = append([]byte(nil), data...)
+		return nil
+	case *string:
+		*t = string(data)
+		return nil
+	case encoding.BinaryUnmarshaler:
+		return t.UnmarshalBinary(data)
+	}
+	return fmt.Errorf("Cannot unmarshal raw bytes into %T", v)
+}
+
+// defaultCodecs returns the codecs that are registered on a new client.
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		JSONCodec{}.ContentType(): JSONCodec{},
+		RawCodec{}.ContentType():  RawCodec{},
+	}
+}
+
+// RegisterCodec registers a codec with the client.
+//
+// Events are deserialized using the codec registered for the content type
+// recorded with the event. JSONCodec and RawCodec are registered by default.
+// The protocol buffer and MessagePack codecs in the codecs package must be
+// registered before the events written with them can be read.
+func (c *Client) RegisterCodec(codec Codec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.codecs == nil {
+		c.codecs = defaultCodecs()
+	}
+	c.codecs[codec.ContentType()] = codec
+}
+
+// codec returns the codec registered for the content type.
+func (c *Client) codec(contentType string) (Codec, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	codecs := c.codecs
+	if codecs == nil {
+		codecs = defaultCodecs()
+	}
+	codec, ok := codecs[contentType]
+	if !ok {
+		return nil, fmt.Errorf("No codec registered for content type %s", contentType)
+	}
+	return codec, nil
+}
+
+// SetCodec sets the codec used to serialize the data and metadata of events
+// appended by the writer.
+//
+// When a codec other than JSONCodec is used the event data is written as a
+// base64 encoded string and the content type and serialized metadata are
+// recorded in the event metadata. Readers deserialize such events with the
+// codec registered on the client for the recorded content type.
+func (s *StreamWriter) SetCodec(codec Codec) {
+	s.codec = codec
+}
+
+// SetMetaDataCodec sets the codec used to serialize the metadata of events
+// appended by the writer, when it should differ from the codec used for the
+// data.
+//
+// For example, the data can be written with a codec that encrypts it while the
+// metadata is written as JSON so that it can still be read by the server, by
+// projections and by tools that do not have the key. When the metadata codec is
+// JSONCodec the metadata of each event must be nil or serialize to a JSON
+// object. By default the metadata is serialized with the same codec as the
+// data.
+func (s *StreamWriter) SetMetaDataCodec(codec Codec) {
+	s.metaCodec = codec
+}
+
+// isJSONCodec returns true if events serialized with the codec are written as
+// JSON.
+func isJSONCodec(codec Codec) bool {
+	if codec == nil {
+		return true
+	}
+	_, ok := codec.(JSONCodec)
+	return ok
+}
+
+// encodeEvent returns a copy of the event with the data serialized using the
+// codec and the metadata serialized using metaCodec. If metaCodec is nil the
+// metadata is serialized using codec.
+func encodeEvent(codec, metaCodec Codec, e *Event) (*Event, error) {
+	if metaCodec == nil {
+		metaCodec = codec
+	}
+	encodeData := !isJSONCodec(codec)
+	encodeMeta := !isJSONCodec(metaCodec) && e.MetaData != nil
+	if !encodeData && !encodeMeta {
+		return e, nil
+	}
+
+	ret := *e
+	meta := map[string]interface{}{}
+
+	if e.MetaData != nil && !encodeMeta {
+		b, err := json.Marshal(e.MetaData)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &meta); err != nil {
+			return nil, fmt.Errorf("Event metadata must be a JSON object when it is serialized separately from the data: %v", err)
+		}
+	}
+
+	if encodeData {
+		data, err := codec.Marshal(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		ret.Data = data
+		meta[ContentTypeMetaDataKey] = codec.ContentType()
+	}
+
+	if encodeMeta {
+		m, err := metaCodec.Marshal(e.MetaData)
+		if err != nil {
+			return nil, err
+		}
+		meta[encodedMetaDataKey] = m
+		if !encodeData || metaCodec.ContentType() != codec.ContentType() {
+			meta[metaDataContentTypeKey] = metaCodec.ContentType()
+		}
+	}
+
+	ret.MetaData = meta
+	return &ret, nil
+}
+
+// codecEnvelope is used to unmarshal the metadata of an event that was
+// serialized using a codec other than JSON.
+type codecEnvelope struct {
+	ContentType         string `json:"goesContentType"`
+	MetaData            []byte `json:"goesMetaData"`
+	MetaDataContentType string `json:"goesMetaDataContentType"`
+}
+
+// decodeEncoded deserializes the data and metadata of an event that was
+// serialized using a codec other than JSON.
+//
+// If neither the data nor the metadata of the event were serialized with a
+// codec handled is false and the event should be deserialized as JSON.
+func (c *Client) decodeEncoded(data, meta *json.RawMessage, e, m interface{}) (handled bool, err error) {
+	if meta == nil || len(*meta) == 0 {
+		return false, nil
+	}
+
+	env := codecEnvelope{}
+	if err := json.Unmarshal(*meta, &env); err != nil || (env.ContentType == "" && env.MetaData == nil) {
+		return false, nil
+	}
+
+	if e != nil {
+		if env.ContentType == "" {
+			if err := json.Unmarshal(*data, e); err != nil {
+				return true, err
+			}
+		} else {
+			codec, err := c.codec(env.ContentType)
+			if err != nil {
+				return true, err
+			}
+			var b []byte
+			if err := json.Unmarshal(*data, &b); err != nil {
+				return true, err
+			}
+			if err := codec.Unmarshal(b, e); err != nil {
+				return true, err
+			}
+		}
+	}
+
+	if m == nil {
+		return true, nil
+	}
+
+	if env.MetaData != nil {
+		ct := env.MetaDataContentType
+		if ct == "" {
+			ct = env.ContentType
+		}
+		codec, err := c.codec(ct)
+		if err != nil {
+			return true, err
+		}
+		return true, codec.Unmarshal(env.MetaData, m)
+	}
+
+	// The metadata was written as JSON alongside the content type of the
+	// data, which is removed before the metadata is returned.
+	plain := map[string]json.RawMessage{}
+	if err := json.Unmarshal(*meta, &plain); err != nil {
+		return true, err
+	}
+	delete(plain, ContentTypeMetaDataKey)
+	if len(plain) == 0 {
+		return true, nil
+	}
+	b, err := json.Marshal(plain)
+	if err != nil {
+		return true, err
+	}
+	return true, json.Unmarshal(b, m)
+}
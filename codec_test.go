@@ -0,0 +1,178 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&CodecSuite{})
+
+type CodecSuite struct{}
+
+func (s *CodecSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *CodecSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// upperCodec is a codec used to test that custom codecs are honored on read.
+type upperCodec struct{}
+
+func (upperCodec) ContentType() string { return "text/x-upper" }
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(string))), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*string) = strings.ToLower(string(data))
+	return nil
+}
+
+// appendAndCapture appends the event using the codec and returns the event as
+// it would be returned when read back from the server.
+func appendAndCapture(c *C, codec Codec, ev *Event) *EventResponse {
+	return appendAndCaptureWithMetaData(c, codec, nil, ev)
+}
+
+// appendAndCaptureWithMetaData is appendAndCapture with a separate codec for
+// the metadata.
+func appendAndCaptureWithMetaData(c *C, codec, metaCodec Codec, ev *Event) *EventResponse {
+	var posted []json.RawMessage
+	mux.HandleFunc("/streams/codec-stream", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		c.Assert(json.Unmarshal(b, &posted), IsNil)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, "")
+	})
+
+	writer := client.NewStreamWriter("codec-stream")
+	writer.SetCodec(codec)
+	writer.SetMetaDataCodec(metaCodec)
+	c.Assert(writer.Append(ExpectAny, ev), IsNil)
+	c.Assert(posted, HasLen, 1)
+
+	var d json.RawMessage
+	var m json.RawMessage
+	got := &Event{Data: &d, MetaData: &m}
+	c.Assert(json.Unmarshal(posted[0], got), IsNil)
+	return &EventResponse{Event: got}
+}
+
+func (s *CodecSuite) TestRawCodecRoundTrip(c *C) {
+	payload := []byte{0x00, 0xff, 0x10, 0x80}
+	er := appendAndCapture(c, RawCodec{}, NewEvent("", "Binary", payload, "some meta"))
+
+	meta := map[string]interface{}{}
+	c.Assert(json.Unmarshal(*er.Event.MetaData.(*json.RawMessage), &meta), IsNil)
+	c.Assert(meta[ContentTypeMetaDataKey], Equals, "application/octet-stream")
+
+	var data []byte
+	var m string
+	err := client.decodeEvent(er, &data, &m)
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, payload)
+	c.Assert(m, Equals, "some meta")
+}
+
+func (s *CodecSuite) TestJSONCodecWritesEventsUnchanged(c *C) {
+	data := &MyDataType{Field1: 1, Field2: "two"}
+	er := appendAndCapture(c, JSONCodec{}, NewEvent("", "", data, nil))
+
+	c.Assert(string(*er.Event.Data.(*json.RawMessage)), Equals, `{"my_field_1":1,"my_field_2":"two"}`)
+
+	got := &MyDataType{}
+	c.Assert(client.decodeEvent(er, got, nil), IsNil)
+	c.Assert(got, DeepEquals, data)
+}
+
+func (s *CodecSuite) TestCustomCodecMustBeRegisteredToRead(c *C) {
+	er := appendAndCapture(c, upperCodec{}, NewEvent("", "Shout", "hello", nil))
+
+	var got string
+	err := client.decodeEvent(er, &got, nil)
+	c.Assert(err, ErrorMatches, "No codec registered for content type text/x-upper")
+
+	client.RegisterCodec(upperCodec{})
+	c.Assert(client.decodeEvent(er, &got, nil), IsNil)
+	c.Assert(got, Equals, "hello")
+}
+
+func (s *CodecSuite) TestRawCodecRejectsUnsupportedTypes(c *C) {
+	_, err := RawCodec{}.Marshal(42)
+	c.Assert(err, ErrorMatches, "Cannot marshal int as raw bytes")
+
+	var i int
+	err = RawCodec{}.Unmarshal([]byte{1}, &i)
+	c.Assert(err, ErrorMatches, `Cannot unmarshal raw bytes into \*int`)
+}
+
+func (s *CodecSuite) TestMetaDataWrittenAsJSONWithEncodedData(c *C) {
+	payload := []byte{0x01, 0x02}
+	er := appendAndCaptureWithMetaData(c, RawCodec{}, JSONCodec{},
+		NewEvent("", "Binary", payload, map[string]string{"tenant": "acme"}))
+
+	meta := map[string]interface{}{}
+	c.Assert(json.Unmarshal(*er.Event.MetaData.(*json.RawMessage), &meta), IsNil)
+	c.Assert(meta, DeepEquals, map[string]interface{}{
+		"tenant":               "acme",
+		ContentTypeMetaDataKey: "application/octet-stream",
+	})
+
+	var data []byte
+	m := map[string]string{}
+	c.Assert(client.decodeEvent(er, &data, &m), IsNil)
+	c.Assert(data, DeepEquals, payload)
+	c.Assert(m, DeepEquals, map[string]string{"tenant": "acme"})
+}
+
+func (s *CodecSuite) TestMetaDataEncodedWithJSONData(c *C) {
+	client.RegisterCodec(upperCodec{})
+	data := &MyDataType{Field1: 1, Field2: "two"}
+	er := appendAndCaptureWithMetaData(c, nil, upperCodec{}, NewEvent("", "Foo", data, "quiet"))
+
+	c.Assert(string(*er.Event.Data.(*json.RawMessage)), Equals, `{"my_field_1":1,"my_field_2":"two"}`)
+	meta := map[string]interface{}{}
+	c.Assert(json.Unmarshal(*er.Event.MetaData.(*json.RawMessage), &meta), IsNil)
+	c.Assert(meta[metaDataContentTypeKey], Equals, "text/x-upper")
+	c.Assert(meta[ContentTypeMetaDataKey], IsNil)
+
+	got := &MyDataType{}
+	var m string
+	c.Assert(client.decodeEvent(er, got, &m), IsNil)
+	c.Assert(got, DeepEquals, data)
+	c.Assert(m, Equals, "quiet")
+}
+
+func (s *CodecSuite) TestMetaDataAndDataWithDifferentCodecs(c *C) {
+	client.RegisterCodec(upperCodec{})
+	er := appendAndCaptureWithMetaData(c, RawCodec{}, upperCodec{}, NewEvent("", "Binary", []byte{0x07}, "quiet"))
+
+	var data []byte
+	var m string
+	c.Assert(client.decodeEvent(er, &data, &m), IsNil)
+	c.Assert(data, DeepEquals, []byte{0x07})
+	c.Assert(m, Equals, "quiet")
+}
+
+func (s *CodecSuite) TestJSONMetaDataCodecRequiresObject(c *C) {
+	mux.HandleFunc("/streams/codec-object", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	writer := client.NewStreamWriter("codec-object")
+	writer.SetCodec(RawCodec{})
+	writer.SetMetaDataCodec(JSONCodec{})
+	err := writer.Append(ExpectAny, NewEvent("", "Binary", []byte{0x01}, "not an object"))
+	c.Assert(err, ErrorMatches, "Event metadata must be a JSON object.*")
+}
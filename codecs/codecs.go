@@ -0,0 +1,82 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+// Package codecs provides goes.Codec implementations that serialize event data
+// and metadata as protocol buffers and as MessagePack.
+//
+// The codecs are kept out of the goes package so that applications that do not
+// use them do not depend on the protocol buffer and MessagePack libraries. A
+// codec is registered on the client so that the events written with it can be
+// read, and is set on the writers that write with it:
+//
+//	client.RegisterCodec(codecs.Protobuf{})
+//	writer := client.NewStreamWriter("order-1")
+//	writer.SetCodec(codecs.Protobuf{})
+//
+// Events can also be written with goes.WithContentType and the content type of
+// a registered codec.
+package codecs
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protobuf serializes event data and metadata as protocol buffers.
+//
+// Values to be marshaled and unmarshaled into must implement proto.Message.
+type Protobuf struct{}
+
+// ContentType returns application/x-protobuf.
+func (Protobuf) ContentType() string { return "application/x-protobuf" }
+
+// Marshal returns the protocol buffer encoding of v.
+func (Protobuf) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("Cannot marshal %T as a protocol buffer", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal parses the protocol buffer encoded data into v.
+func (Protobuf) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("Cannot unmarshal a protocol buffer into %T", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// Msgpack serializes event data and metadata as MessagePack.
+//
+// Struct fields are encoded using their msgpack tags, or their json tags if
+// they have no msgpack tags, so that types written as JSON can be written as
+// MessagePack without change.
+type Msgpack struct{}
+
+// ContentType returns application/x-msgpack.
+func (Msgpack) ContentType() string { return "application/x-msgpack" }
+
+// Marshal returns the MessagePack encoding of v.
+func (Msgpack) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := msgpack.NewEncoder(&b)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Unmarshal parses the MessagePack encoded data into v.
+func (Msgpack) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package codecs
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jetbasrawi/go.geteventstore"
+	"github.com/jetbasrawi/go.geteventstore/estest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&CodecsSuite{})
+
+type CodecsSuite struct {
+	server *httptest.Server
+	client *goes.Client
+}
+
+type OrderPlaced struct {
+	ID       int    `json:"id"`
+	Customer string `json:"customer"`
+}
+
+func (s *CodecsSuite) SetUpTest(c *C) {
+	s.server = httptest.NewServer(estest.NewSimulator())
+	client, err := goes.NewClient(nil, s.server.URL)
+	c.Assert(err, IsNil)
+	s.client = client
+}
+
+func (s *CodecsSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+// roundTrip writes the event with the codec and scans it back into data and
+// meta.
+func (s *CodecsSuite) roundTrip(c *C, codec goes.Codec, e *goes.Event, data, meta interface{}) error {
+	writer := s.client.NewStreamWriter("order-1")
+	writer.SetCodec(codec)
+	c.Assert(writer.Append(goes.ExpectAny, e), IsNil)
+
+	reader := s.client.NewStreamReader("order-1")
+	c.Assert(reader.Next(), Equals, true)
+	c.Assert(reader.Err(), IsNil)
+	return reader.Scan(data, meta)
+}
+
+func (s *CodecsSuite) TestProtobufRoundTrip(c *C) {
+	data := wrapperspb.String("order placed")
+	e := goes.NewEvent("", "OrderPlaced", data, wrapperspb.Int64(42))
+
+	got := &wrapperspb.StringValue{}
+	m := &wrapperspb.Int64Value{}
+	err := s.roundTrip(c, Protobuf{}, e, got, m)
+	c.Assert(err, ErrorMatches, "No codec registered for content type application/x-protobuf")
+
+	s.client.RegisterCodec(Protobuf{})
+	reader := s.client.NewStreamReader("order-1")
+	c.Assert(reader.Next(), Equals, true)
+	c.Assert(reader.Scan(got, m), IsNil)
+	c.Assert(proto.Equal(got, data), Equals, true)
+	c.Assert(m.GetValue(), Equals, int64(42))
+}
+
+func (s *CodecsSuite) TestProtobufRejectsOtherTypes(c *C) {
+	_, err := Protobuf{}.Marshal("order placed")
+	c.Assert(err, ErrorMatches, "Cannot marshal string as a protocol buffer")
+	var str string
+	err = Protobuf{}.Unmarshal(nil, &str)
+	c.Assert(err, ErrorMatches, `Cannot unmarshal a protocol buffer into \*string`)
+}
+
+func (s *CodecsSuite) TestMsgpackRoundTrip(c *C) {
+	s.client.RegisterCodec(Msgpack{})
+	data := &OrderPlaced{ID: 1, Customer: "acme"}
+
+	got := &OrderPlaced{}
+	m := map[string]string{}
+	err := s.roundTrip(c, Msgpack{}, goes.NewEvent("", "", data, map[string]string{"k": "v"}), got, &m)
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, data)
+	c.Assert(m, DeepEquals, map[string]string{"k": "v"})
+
+	// Fields are keyed by their json tags.
+	b, err := Msgpack{}.Marshal(data)
+	c.Assert(err, IsNil)
+	keyed := map[string]interface{}{}
+	c.Assert(Msgpack{}.Unmarshal(b, &keyed), IsNil)
+	c.Assert(keyed["customer"], Equals, "acme")
+}
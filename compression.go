@@ -0,0 +1,95 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SetCompression enables compressed responses.
+//
+// When enabled requests are sent with Accept-Encoding: gzip, deflate and
+// compressed responses are decompressed before they are read. Feed pages and
+// events compress well, so this reduces the data transferred when reading over
+// slow links. The http.Transport of the default client already requests gzip
+// responses, so this is mostly useful with transports that do not, or to also
+// accept deflate.
+func (c *Client) SetCompression(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compression = enabled
+}
+
+// SetRequestCompression causes request bodies of at least minSize bytes to be
+// compressed with gzip. A minSize of 0 or less disables request compression,
+// which is the default.
+//
+// The server must support compressed request bodies.
+func (c *Client) SetRequestCompression(minSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compressAbove = minSize
+}
+
+// requestCompression returns the minimum size of request bodies to compress,
+// or 0 if request compression is disabled.
+func (c *Client) requestCompression() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.compressAbove
+}
+
+// compressedBody is a response body that is decompressed as it is read.
+type compressedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *compressedBody) Close() error {
+	return b.body.Close()
+}
+
+// decompress replaces the body of a compressed response with a reader that
+// decompresses it.
+func decompress(resp *http.Response) error {
+	var r io.Reader
+	var err error
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip":
+		r, err = gzip.NewReader(resp.Body)
+	case "deflate":
+		r, err = zlib.NewReader(resp.Body)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	resp.Body = &compressedBody{Reader: r, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	return nil
+}
+
+// gzipBytes returns b compressed with gzip.
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
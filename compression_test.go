@@ -0,0 +1,114 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&CompressionSuite{})
+
+type CompressionSuite struct{}
+
+func (s *CompressionSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *CompressionSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// compressingHandler serves the responses of h compressed with the first
+// encoding accepted by the request.
+type compressingHandler struct {
+	h http.Handler
+}
+
+type compressedWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+func (w *compressedWriter) Write(b []byte) (int, error) {
+	return w.w.Write(b)
+}
+
+func (h compressingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var cw io.WriteCloser
+	switch r.Header.Get("Accept-Encoding") {
+	case "gzip, deflate":
+		w.Header().Set("Content-Encoding", "gzip")
+		cw = gzip.NewWriter(w)
+	case "deflate":
+		w.Header().Set("Content-Encoding", "deflate")
+		cw = zlib.NewWriter(w)
+	default:
+		h.h.ServeHTTP(w, r)
+		return
+	}
+	defer cw.Close()
+	h.h.ServeHTTP(&compressedWriter{ResponseWriter: w, w: cw}, r)
+}
+
+func (s *CompressionSuite) TestCompressedResponsesAreDecompressed(c *C) {
+	es := CreateTestEvents(5, "compressed-1", server.URL, "Foo")
+	mux.Handle("/", compressingHandler{newTestSimulator(es, nil)})
+
+	client.SetCompression(true)
+	reader := client.NewStreamReader("compressed-1")
+	c.Assert(readAll(c, reader), DeepEquals, sequence(5))
+}
+
+func (s *CompressionSuite) TestDeflateResponsesAreDecompressed(c *C) {
+	es := CreateTestEvents(1, "compressed-2", server.URL, "Foo")
+	mux.Handle("/", compressingHandler{newTestSimulator(es, nil)})
+
+	client.SetHeader("Accept-Encoding", "deflate")
+	e, _, err := client.GetEvent("/streams/compressed-2/0")
+	c.Assert(err, IsNil)
+	c.Assert(e.Event.EventID, Equals, es[0].EventID)
+}
+
+func (s *CompressionSuite) TestCompressedErrorResponsesAreDecoded(c *C) {
+	mux.Handle("/", compressingHandler{http.NotFoundHandler()})
+
+	client.SetCompression(true)
+	_, _, err := client.ReadFeed("/streams/missing/head/backward/20")
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+}
+
+func (s *CompressionSuite) TestLargeRequestBodiesAreCompressed(c *C) {
+	encodings := []string{}
+	mux.HandleFunc("/streams/compressed-3", func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			z, err := gzip.NewReader(r.Body)
+			c.Assert(err, IsNil)
+			body = z
+		}
+		es := []Event{}
+		c.Assert(json.NewDecoder(body).Decode(&es), IsNil)
+		c.Assert(es, HasLen, 1)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	client.SetRequestCompression(1000)
+	writer := client.NewStreamWriter("compressed-3")
+	c.Assert(writer.Append(ExpectAny, NewEvent("", "Small", "x", nil)), IsNil)
+	large := make([]byte, 2000)
+	for i := range large {
+		large[i] = 'a'
+	}
+	c.Assert(writer.Append(ExpectAny, NewEvent("", "Large", string(large), nil)), IsNil)
+
+	c.Assert(encodings, DeepEquals, []string{"", "gzip"})
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// tokenPollInterval is the interval at which WaitForToken reads the head of a
+// stream.
+const tokenPollInterval = 50 * time.Millisecond
+
+// ConsistencyToken is an opaque token returned from a write that identifies
+// the state of the stream after the write.
+//
+// A token can be passed to another service, which can then use it to read the
+// stream at least as fresh as it was after the write, with
+// Client.WaitForToken or StreamReader.SetConsistencyToken.
+//
+// The token encodes the stream and the version of the stream after the write.
+// The HTTP API does not report the position of a write in the transaction log,
+// so tokens are only meaningful for the stream that was written.
+type ConsistencyToken string
+
+// tokenData is the content of a ConsistencyToken.
+type tokenData struct {
+	Stream  string `json:"s"`
+	Version int    `json:"v"`
+}
+
+// newConsistencyToken returns a token for the version of the stream.
+func newConsistencyToken(stream string, version int) ConsistencyToken {
+	b, _ := json.Marshal(tokenData{Stream: stream, Version: version})
+	return ConsistencyToken(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// parse returns the stream and version encoded in the token.
+func (t ConsistencyToken) parse() (*tokenData, error) {
+	b, err := base64.RawURLEncoding.DecodeString(string(t))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid consistency token: %v", err)
+	}
+	d := &tokenData{}
+	if err := json.Unmarshal(b, d); err != nil || d.Stream == "" {
+		return nil, fmt.Errorf("Invalid consistency token")
+	}
+	return d, nil
+}
+
+// AppendWithToken appends events to the stream like Append and returns a
+// ConsistencyToken for the state of the stream after the write.
+//
+// If the events were written but the type index could not be updated the token
+// is returned with an *ErrTypeIndex.
+func (s *StreamWriter) AppendWithToken(expectedVersion ExpectedVersion, events ...*Event) (ConsistencyToken, error) {
+	result, err := s.AppendWithResult(expectedVersion, events...)
+	if result == nil {
+		return "", err
+	}
+	return newConsistencyToken(s.streamName, result.NextExpectedVersion), err
+}
+
+// WaitForToken waits until the stream in the token has reached at least the
+// version in the token.
+//
+// The head of the stream is read until it reaches the version or the timeout
+// expires, in which case an *ErrStaleRead is returned. A timeout of 0 checks the
+// stream once.
+func (c *Client) WaitForToken(token ConsistencyToken, timeout time.Duration) error {
+	d, err := token.parse()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		v, err := c.GetStreamHeadVersion(d.Stream)
+		switch err.(type) {
+		case nil:
+		case *ErrNotFound, *ErrNoEvents:
+			v = -1
+		default:
+			return err
+		}
+		if v >= d.Version {
+			return nil
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return &ErrStaleRead{Stream: d.Stream, Version: v, Required: d.Version}
+		}
+		if remaining > tokenPollInterval {
+			remaining = tokenPollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// SetConsistencyToken causes the reader to wait on the first call to Next()
+// until the stream is at least as fresh as the token, for up to timeout.
+//
+// If the stream does not reach the version of the token in time, Next() returns
+// true and Err() returns an *ErrStaleRead. See Client.WaitForToken.
+func (s *StreamReader) SetConsistencyToken(token ConsistencyToken, timeout time.Duration) {
+	s.token = token
+	s.tokenTimeout = timeout
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ConsistencySuite{})
+
+type ConsistencySuite struct{}
+
+func (s *ConsistencySuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *ConsistencySuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// appendForToken appends three events to the stream, which the server reports
+// as written at event number 5, and returns the token.
+func appendForToken(c *C, stream string) ConsistencyToken {
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", server.URL+"/streams/"+stream+"/5")
+		w.WriteHeader(http.StatusCreated)
+	})
+	token, err := client.NewStreamWriter(stream).AppendWithToken(ExpectAny,
+		NewEvent("", "Foo", nil, nil),
+		NewEvent("", "Foo", nil, nil),
+		NewEvent("", "Foo", nil, nil),
+	)
+	c.Assert(err, IsNil)
+	c.Assert(token, Not(Equals), ConsistencyToken(""))
+	return token
+}
+
+func (s *ConsistencySuite) TestWaitForTokenSucceedsWhenStreamIsFresh(c *C) {
+	token := appendForToken(c, "orders-1")
+	setupSimulator(CreateTestEvents(8, "orders-1", server.URL, "Foo"), nil)
+
+	c.Assert(client.WaitForToken(token, 0), IsNil)
+}
+
+func (s *ConsistencySuite) TestWaitForTokenReturnsErrStaleRead(c *C) {
+	token := appendForToken(c, "orders-1")
+	setupSimulator(CreateTestEvents(7, "orders-1", server.URL, "Foo"), nil)
+
+	start := time.Now()
+	err := client.WaitForToken(token, 2*tokenPollInterval)
+	c.Assert(time.Since(start) >= 2*tokenPollInterval, Equals, true)
+	c.Assert(err, DeepEquals, &ErrStaleRead{Stream: "orders-1", Version: 6, Required: 7})
+}
+
+func (s *ConsistencySuite) TestReaderWithTokenReturnsErrStaleRead(c *C) {
+	token := appendForToken(c, "orders-1")
+	setupSimulator(CreateTestEvents(2, "orders-1", server.URL, "Foo"), nil)
+
+	reader := client.NewStreamReader("orders-1")
+	reader.SetConsistencyToken(token, 0)
+	c.Assert(reader.Next(), Equals, true)
+	c.Assert(reader.Err(), FitsTypeOf, &ErrStaleRead{})
+}
+
+func (s *ConsistencySuite) TestReaderWithTokenReadsWhenStreamIsFresh(c *C) {
+	token := appendForToken(c, "orders-1")
+	setupSimulator(CreateTestEvents(8, "orders-1", server.URL, "Foo"), nil)
+
+	reader := client.NewStreamReader("orders-1")
+	reader.SetConsistencyToken(token, 0)
+	c.Assert(reader.Next(), Equals, true)
+	c.Assert(reader.Err(), IsNil)
+	c.Assert(reader.EventResponse().Event.EventNumber, Equals, 0)
+}
+
+func (s *ConsistencySuite) TestWaitForInvalidTokenReturnsError(c *C) {
+	c.Assert(client.WaitForToken("not a token", 0), NotNil)
+}
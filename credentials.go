@@ -0,0 +1,83 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"net/http"
+)
+
+// CredentialsProvider provides the credentials for basic authentication when
+// the server rejects the credentials of the client.
+//
+// Implementations can fetch credentials from a secret store such as Vault so
+// that credentials rotated there are picked up without recreating the client.
+type CredentialsProvider interface {
+	GetCredentials(ctx context.Context) (username, password string, err error)
+}
+
+// CredentialsProviderFunc is a function that implements CredentialsProvider.
+type CredentialsProviderFunc func(ctx context.Context) (username, password string, err error)
+
+// GetCredentials calls f(ctx).
+func (f CredentialsProviderFunc) GetCredentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// SetCredentialsProvider sets the provider consulted when a request is
+// rejected with 401 Unauthorized.
+//
+// When a request is rejected the client gets new credentials from the
+// provider, sets them as if by SetBasicAuth and sends the request again once
+// with the new credentials. If the request is rejected again an
+// ErrUnauthorized is returned. If the provider returns an error, that error is
+// returned.
+//
+// When several requests are rejected at the same time the provider is called
+// once, and the other requests are sent again with the credentials it
+// returned. A nil provider removes the provider.
+func (c *Client) SetCredentialsProvider(p CredentialsProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credProvider = p
+}
+
+// credentialsProvider returns the credentials provider of the client.
+func (c *Client) credentialsProvider() CredentialsProvider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.credProvider
+}
+
+// refreshCredentials sets new credentials from the provider on the request and
+// the client.
+//
+// If the credentials of the client have changed since the request was made,
+// the request was rejected with stale credentials, so the current credentials
+// are used without consulting the provider.
+func (c *Client) refreshCredentials(req *http.Request, p CredentialsProvider) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	current := c.credentials
+	c.mu.RUnlock()
+
+	username, password, sent := req.BasicAuth()
+	if current != nil && (!sent || current.Username != username || current.Password != password) {
+		req.SetBasicAuth(current.Username, current.Password)
+		return nil
+	}
+
+	username, password, err := p.GetCredentials(req.Context())
+	if err != nil {
+		return err
+	}
+	c.SetBasicAuth(username, password)
+	req.SetBasicAuth(username, password)
+	req.Header.Del("ES-TrustedAuth")
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&CredentialsSuite{})
+
+type CredentialsSuite struct{}
+
+func (s *CredentialsSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *CredentialsSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// rotatingProvider is a CredentialsProvider that returns the current password
+// and counts the times it is called.
+type rotatingProvider struct {
+	mu       sync.Mutex
+	password string
+	calls    int
+	err      error
+}
+
+func (p *rotatingProvider) GetCredentials(ctx context.Context) (string, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return "admin", p.password, p.err
+}
+
+func (p *rotatingProvider) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// serveWithPassword serves 200 OK for requests to path with the password and
+// 401 Unauthorized otherwise. The bodies of the accepted requests are
+// recorded.
+func serveWithPassword(path, password string) func() []string {
+	var mu sync.Mutex
+	bodies := []string{}
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if _, p, ok := r.BasicAuth(); !ok || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, bodies...)
+	}
+}
+
+func (s *CredentialsSuite) TestRefreshesCredentialsOnUnauthorized(c *C) {
+	bodies := serveWithPassword("/rotated", "new")
+	p := &rotatingProvider{password: "new"}
+	client.SetBasicAuth("admin", "old")
+	client.SetCredentialsProvider(p)
+
+	req, err := client.newRequest(http.MethodPost, "/rotated", map[string]string{"a": "b"})
+	c.Assert(err, IsNil)
+	_, err = client.do(req, nil)
+	c.Assert(err, IsNil)
+	c.Assert(bodies(), DeepEquals, []string{"{\"a\":\"b\"}\n"})
+	c.Assert(p.count(), Equals, 1)
+
+	// The new credentials are used for later requests.
+	req, err = client.newRequest(http.MethodGet, "/rotated", nil)
+	c.Assert(err, IsNil)
+	_, err = client.do(req, nil)
+	c.Assert(err, IsNil)
+	c.Assert(p.count(), Equals, 1)
+}
+
+func (s *CredentialsSuite) TestRetriesOnlyOnce(c *C) {
+	serveWithPassword("/rejected", "secret")
+	p := &rotatingProvider{password: "still wrong"}
+	client.SetBasicAuth("admin", "wrong")
+	client.SetCredentialsProvider(p)
+
+	req, err := client.newRequest(http.MethodGet, "/rejected", nil)
+	c.Assert(err, IsNil)
+	_, err = client.do(req, nil)
+	c.Assert(err, FitsTypeOf, &ErrUnauthorized{})
+	c.Assert(p.count(), Equals, 1)
+}
+
+func (s *CredentialsSuite) TestReturnsProviderError(c *C) {
+	serveWithPassword("/vault-down", "secret")
+	errVault := errors.New("vault sealed")
+	client.SetCredentialsProvider(&rotatingProvider{err: errVault})
+
+	req, err := client.newRequest(http.MethodGet, "/vault-down", nil)
+	c.Assert(err, IsNil)
+	_, err = client.do(req, nil)
+	c.Assert(err, Equals, errVault)
+}
+
+func (s *CredentialsSuite) TestWithoutProviderUnauthorizedIsReturned(c *C) {
+	serveWithPassword("/no-provider", "secret")
+	client.SetBasicAuth("admin", "wrong")
+
+	req, err := client.newRequest(http.MethodGet, "/no-provider", nil)
+	c.Assert(err, IsNil)
+	_, err = client.do(req, nil)
+	c.Assert(err, FitsTypeOf, &ErrUnauthorized{})
+}
+
+func (s *CredentialsSuite) TestConcurrentRejectionsRefreshOnce(c *C) {
+	serveWithPassword("/concurrent", "new")
+	p := &rotatingProvider{password: "new"}
+	client.SetBasicAuth("admin", "old")
+	client.SetCredentialsProvider(CredentialsProviderFunc(p.GetCredentials))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := client.newRequest(http.MethodGet, "/concurrent", nil)
+			if err == nil {
+				_, err = client.do(req, nil)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(p.count(), Equals, 1)
+}
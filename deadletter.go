@@ -0,0 +1,138 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"time"
+)
+
+// Metadata keys recorded on events appended to a dead letter stream by a
+// Subscription with an EventErrorPolicy.
+const (
+	DeadLetterStreamMetaDataKey      = "deadLetterStream"
+	DeadLetterEventNumberMetaDataKey = "deadLetterEventNumber"
+	DeadLetterErrorMetaDataKey       = "deadLetterError"
+)
+
+// EventErrorAction is the action taken when a subscription handler returns an
+// error for an event.
+type EventErrorAction int
+
+const (
+	// EventErrorStop stops the subscription with the error returned by the
+	// handler.
+	EventErrorStop EventErrorAction = iota
+
+	// EventErrorSkip skips the event and continues with the next event.
+	EventErrorSkip
+
+	// EventErrorDeadLetter appends the event to the dead letter stream of the
+	// policy and continues with the next event.
+	EventErrorDeadLetter
+)
+
+// EventErrorPolicy is applied when a subscription handler returns an error.
+//
+// The handler is called again for the event up to Retries times, waiting
+// RetryDelay before each retry. If the handler still fails the Action is taken.
+// A policy with the action EventErrorStop and a number of retries retries the
+// event and then stops the subscription.
+//
+// DeadLetterStream is the stream to which events are appended by
+// EventErrorDeadLetter. If it is empty the stream returned by
+// DeadLetterStreamName is used.
+type EventErrorPolicy struct {
+	Action           EventErrorAction
+	Retries          int
+	RetryDelay       time.Duration
+	DeadLetterStream string
+}
+
+// DeadLetterStreamName returns the name of the default dead letter stream of
+// a subscription to the stream.
+func DeadLetterStreamName(stream string) string {
+	return stream + "-dlq"
+}
+
+// SetEventErrorPolicy sets the policy applied when the handler returns an
+// error. The default policy stops the subscription without retrying.
+//
+// Events appended to the dead letter stream keep their event type, data and
+// metadata. When the metadata is a JSON object, or the event has none, the
+// stream and number of the event and the error returned by the handler are
+// recorded in it under the DeadLetter metadata keys.
+//
+// The policy does not apply to handler timeouts, which are governed by the
+// TimeoutPolicy, or to events that cannot be upcast, which are governed by
+// SetHoldStream.
+func (s *Subscription) SetEventErrorPolicy(p EventErrorPolicy) {
+	s.onError = p
+}
+
+// process delivers the event at position pos in the stream to the handler,
+// applying the event error policy. ctx is cancelled when the subscription is
+// stopped, which abandons retries.
+func (s *Subscription) process(ctx context.Context, pos int, er *EventResponse) error {
+	return s.client.applyEventErrorPolicy(ctx, s.onError, s.stream, er, func() error {
+		return s.handle(pos, er)
+	})
+}
+
+// applyEventErrorPolicy calls handle for the event, applying the policy to
+// the error it returns. Events are dead lettered to the dead letter stream of
+// stream unless the policy names one. Cancelling ctx abandons retries.
+func (c *Client) applyEventErrorPolicy(ctx context.Context, p EventErrorPolicy, stream string, er *EventResponse, handle func() error) error {
+	err := handle()
+	if err == nil {
+		return nil
+	}
+	switch err.(type) {
+	case *ErrHandlerTimeout, *ErrSchemaVersion:
+		return err
+	}
+
+	for i := 0; i < p.Retries; i++ {
+		if p.RetryDelay > 0 {
+			t := time.NewTimer(p.RetryDelay)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			}
+		}
+		if err = handle(); err == nil {
+			return nil
+		}
+	}
+
+	switch p.Action {
+	case EventErrorSkip:
+		return nil
+	case EventErrorDeadLetter:
+		return c.deadLetter(p, stream, er, err)
+	}
+	return err
+}
+
+// deadLetter appends the event to the dead letter stream of the policy.
+func (c *Client) deadLetter(p EventErrorPolicy, stream string, er *EventResponse, cause error) error {
+	dlq := p.DeadLetterStream
+	if dlq == "" {
+		dlq = DeadLetterStreamName(stream)
+	}
+
+	e := copyEvent(er.Event)
+	if m, err := mergeMetaData(map[string]interface{}{
+		DeadLetterStreamMetaDataKey:      er.Event.EventStreamID,
+		DeadLetterEventNumberMetaDataKey: er.Event.EventNumber,
+		DeadLetterErrorMetaDataKey:       cause.Error(),
+	}, e); err == nil {
+		e = m
+	}
+	return c.NewStreamWriter(dlq).Append(ExpectAny, e)
+}
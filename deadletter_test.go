@@ -0,0 +1,167 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&DeadLetterSuite{})
+
+type DeadLetterSuite struct{}
+
+func (s *DeadLetterSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *DeadLetterSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+var errHandler = errors.New("handler failed")
+
+// failOn returns a handler that records the events it processes and fails for
+// the first failures calls with the event number provided. calls returns the
+// number of calls made for the event.
+func failOn(number, failures int, got *received) (handler func(*EventResponse) error, calls func() int) {
+	var mu sync.Mutex
+	n := 0
+	handler = func(er *EventResponse) error {
+		if er.Event.EventNumber == number {
+			mu.Lock()
+			n++
+			fail := n <= failures
+			mu.Unlock()
+			if fail {
+				return errHandler
+			}
+		}
+		return got.handle(er)
+	}
+	calls = func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return n
+	}
+	return handler, calls
+}
+
+// captureDeadLetters records the events appended to the stream.
+func captureDeadLetters(c *C, stream string) func() []map[string]interface{} {
+	var mu sync.Mutex
+	letters := []map[string]interface{}{}
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		es := []map[string]interface{}{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&es), IsNil)
+		mu.Lock()
+		letters = append(letters, es...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+	return func() []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]interface{}{}, letters...)
+	}
+}
+
+func (s *DeadLetterSuite) newSubscription(stream string, handler func(*EventResponse) error, p EventErrorPolicy) *Subscription {
+	setupSimulator(CreateTestEvents(5, stream, server.URL, "Foo"), nil)
+	sub := client.NewCatchUpSubscription(stream, 0, handler)
+	sub.SetEventErrorPolicy(p)
+	return sub
+}
+
+func (s *DeadLetterSuite) TestDefaultPolicyStopsSubscription(c *C) {
+	got := &received{}
+	handler, calls := failOn(2, 1, got)
+	sub := s.newSubscription("dlq-1", handler, EventErrorPolicy{})
+	sub.Start()
+	<-sub.Done()
+
+	c.Assert(sub.Err(), Equals, errHandler)
+	c.Assert(sub.LastProcessed(), Equals, 1)
+	c.Assert(calls(), Equals, 1)
+}
+
+func (s *DeadLetterSuite) TestRetrySucceeds(c *C) {
+	got := &received{}
+	handler, calls := failOn(2, 2, got)
+	sub := s.newSubscription("dlq-2", handler, EventErrorPolicy{Retries: 2, RetryDelay: time.Millisecond})
+	sub.Start()
+	eventually(func() bool { return sub.LastProcessed() == 4 })
+	sub.Stop()
+
+	c.Assert(got.get(), DeepEquals, []int{0, 1, 2, 3, 4})
+	c.Assert(calls(), Equals, 3)
+}
+
+func (s *DeadLetterSuite) TestRetryStopsWhenRetriesAreExhausted(c *C) {
+	got := &received{}
+	handler, calls := failOn(2, 3, got)
+	sub := s.newSubscription("dlq-3", handler, EventErrorPolicy{Retries: 2})
+	sub.Start()
+	<-sub.Done()
+
+	c.Assert(sub.Err(), Equals, errHandler)
+	c.Assert(calls(), Equals, 3)
+}
+
+func (s *DeadLetterSuite) TestSkipContinuesWithNextEvent(c *C) {
+	got := &received{}
+	handler, _ := failOn(2, 1, got)
+	sub := s.newSubscription("dlq-4", handler, EventErrorPolicy{Action: EventErrorSkip})
+	sub.Start()
+	eventually(func() bool { return sub.LastProcessed() == 4 })
+	sub.Stop()
+
+	c.Assert(got.get(), DeepEquals, []int{0, 1, 3, 4})
+	c.Assert(sub.Err(), IsNil)
+}
+
+func (s *DeadLetterSuite) TestDeadLetterWritesToDefaultStream(c *C) {
+	letters := captureDeadLetters(c, DeadLetterStreamName("dlq-5"))
+	got := &received{}
+	handler, _ := failOn(2, 10, got)
+	sub := s.newSubscription("dlq-5", handler, EventErrorPolicy{Action: EventErrorDeadLetter, Retries: 1})
+	sub.Start()
+	eventually(func() bool { return sub.LastProcessed() == 4 })
+	sub.Stop()
+
+	c.Assert(got.get(), DeepEquals, []int{0, 1, 3, 4})
+	ls := letters()
+	c.Assert(ls, HasLen, 1)
+	c.Assert(ls[0]["eventType"], Equals, "Foo")
+	meta := ls[0]["metadata"].(map[string]interface{})
+	c.Assert(meta[DeadLetterStreamMetaDataKey], Equals, "dlq-5")
+	c.Assert(meta[DeadLetterEventNumberMetaDataKey], Equals, float64(2))
+	c.Assert(meta[DeadLetterErrorMetaDataKey], Equals, "handler failed")
+	c.Assert(meta["bar"], NotNil)
+}
+
+func (s *DeadLetterSuite) TestDeadLetterToConfiguredStream(c *C) {
+	letters := captureDeadLetters(c, "parked")
+	got := &received{}
+	handler, _ := failOn(3, 1, got)
+	sub := s.newSubscription("dlq-6", handler, EventErrorPolicy{
+		Action:           EventErrorDeadLetter,
+		DeadLetterStream: "parked",
+	})
+	sub.Start()
+	eventually(func() bool { return sub.LastProcessed() == 4 })
+	sub.Stop()
+
+	c.Assert(letters(), HasLen, 1)
+}
+
+func (s *DeadLetterSuite) TestDeadLetterStreamName(c *C) {
+	c.Assert(DeadLetterStreamName("orders"), Equals, "orders-dlq")
+}
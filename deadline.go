@@ -0,0 +1,130 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"time"
+)
+
+// TimeoutAction is the action taken when a subscription handler does not
+// return within the timeout of a TimeoutPolicy.
+type TimeoutAction int
+
+const (
+	// TimeoutHalt stops the subscription with an *ErrHandlerTimeout.
+	TimeoutHalt TimeoutAction = iota
+
+	// TimeoutRetry calls the handler again, up to the number of retries in the
+	// policy, and then stops the subscription.
+	TimeoutRetry
+
+	// TimeoutSkip skips the event.
+	TimeoutSkip
+
+	// TimeoutDeadLetter appends the event to the dead letter stream of the
+	// policy and continues with the next event.
+	TimeoutDeadLetter
+)
+
+// TimeoutPolicy limits the time a subscription handler may take to process an
+// event.
+type TimeoutPolicy struct {
+	Timeout          time.Duration
+	Action           TimeoutAction
+	Retries          int
+	DeadLetterStream string
+}
+
+// SetContextHandler sets a handler that receives a context.Context, replacing
+// the handler the subscription was created with.
+//
+// The context carries the Lineage of the event, so events appended with
+// StreamWriter.AppendContext using the context record the event that caused
+// them, and the position of the event in the stream, which is returned by
+// FeedPosition. When a TimeoutPolicy is set the context has a deadline of the
+// policy timeout, and handlers should pass it to any calls they make so that
+// the calls are abandoned when the deadline passes.
+func (s *Subscription) SetContextHandler(fn func(ctx context.Context, er *EventResponse) error) {
+	s.ctxHandler = fn
+}
+
+// SetTimeoutPolicy sets the policy applied when the handler takes longer than
+// the policy timeout to process an event. A zero timeout disables the policy,
+// which is the default.
+//
+// A handler that has timed out cannot be stopped. If it does not observe the
+// deadline of its context it continues to run while the subscription moves on,
+// so handlers used with a timeout policy should be written with
+// SetContextHandler.
+func (s *Subscription) SetTimeoutPolicy(p TimeoutPolicy) {
+	s.timeout = p
+}
+
+// handle delivers the event at position pos to the handler, applying the
+// timeout policy.
+func (s *Subscription) handle(pos int, er *EventResponse) error {
+	er, err := s.upcast(er)
+	if err != nil || er == nil {
+		return err
+	}
+
+	if s.timeout.Timeout <= 0 {
+		return s.call(context.Background(), pos, er)
+	}
+
+	attempts := 1
+	if s.timeout.Action == TimeoutRetry {
+		attempts += s.timeout.Retries
+	}
+
+	for i := 0; i < attempts; i++ {
+		err = s.callWithTimeout(pos, er)
+		if _, ok := err.(*ErrHandlerTimeout); !ok {
+			return err
+		}
+	}
+
+	switch s.timeout.Action {
+	case TimeoutSkip:
+		return nil
+	case TimeoutDeadLetter:
+		return s.client.NewStreamWriter(s.timeout.DeadLetterStream).Append(ExpectAny, copyEvent(er.Event))
+	}
+	return err
+}
+
+// callWithTimeout calls the handler and returns an *ErrHandlerTimeout if it
+// does not return within the policy timeout.
+func (s *Subscription) callWithTimeout(pos int, er *EventResponse) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout.Timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- s.call(ctx, pos, er)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return &ErrHandlerTimeout{
+			Stream:      s.stream,
+			EventNumber: er.Event.EventNumber,
+			Timeout:     s.timeout.Timeout,
+		}
+	}
+}
+
+// call calls the context handler if one is set, otherwise the handler.
+func (s *Subscription) call(ctx context.Context, pos int, er *EventResponse) error {
+	if s.ctxHandler != nil {
+		ctx = context.WithValue(WithLineage(ctx, LineageOf(er)), feedPositionKey{}, pos)
+		return s.ctxHandler(ctx, er)
+	}
+	return s.handler(er)
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&DeadlineSuite{})
+
+type DeadlineSuite struct{}
+
+func (s *DeadlineSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *DeadlineSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// hangOn returns a context handler that records the events it processes and
+// blocks until its context is done for the first hangs calls with the event
+// number provided.
+func hangOn(number, hangs int, got *received) func(context.Context, *EventResponse) error {
+	var mu sync.Mutex
+	return func(ctx context.Context, er *EventResponse) error {
+		mu.Lock()
+		hang := er.Event.EventNumber == number && hangs > 0
+		if hang {
+			hangs--
+		}
+		mu.Unlock()
+		if hang {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return got.handle(er)
+	}
+}
+
+func (s *DeadlineSuite) newSubscription(stream string, got *received, policy TimeoutPolicy, hangs int) *Subscription {
+	setupSimulator(CreateTestEvents(5, stream, server.URL, "Foo"), nil)
+	sub := client.NewCatchUpSubscription(stream, 0, nil)
+	sub.SetContextHandler(hangOn(2, hangs, got))
+	sub.SetTimeoutPolicy(policy)
+	return sub
+}
+
+func (s *DeadlineSuite) TestTimeoutHaltStopsSubscription(c *C) {
+	got := &received{}
+	sub := s.newSubscription("deadline-1", got, TimeoutPolicy{Timeout: 10 * time.Millisecond}, 1)
+	sub.Start()
+	<-sub.Done()
+
+	c.Assert(sub.Err(), DeepEquals, &ErrHandlerTimeout{
+		Stream:      "deadline-1",
+		EventNumber: 2,
+		Timeout:     10 * time.Millisecond,
+	})
+	c.Assert(sub.LastProcessed(), Equals, 1)
+}
+
+func (s *DeadlineSuite) TestTimeoutSkipContinuesWithNextEvent(c *C) {
+	got := &received{}
+	sub := s.newSubscription("deadline-2", got, TimeoutPolicy{
+		Timeout: 10 * time.Millisecond,
+		Action:  TimeoutSkip,
+	}, 1)
+	sub.Start()
+	eventually(func() bool { return sub.LastProcessed() == 4 })
+	sub.Stop()
+
+	c.Assert(got.get(), DeepEquals, []int{0, 1, 3, 4})
+	c.Assert(sub.Err(), IsNil)
+}
+
+func (s *DeadlineSuite) TestTimeoutRetryCallsHandlerAgain(c *C) {
+	got := &received{}
+	sub := s.newSubscription("deadline-3", got, TimeoutPolicy{
+		Timeout: 10 * time.Millisecond,
+		Action:  TimeoutRetry,
+		Retries: 2,
+	}, 2)
+	sub.Start()
+	eventually(func() bool { return sub.LastProcessed() == 4 })
+	sub.Stop()
+
+	c.Assert(got.get(), DeepEquals, []int{0, 1, 2, 3, 4})
+}
+
+func (s *DeadlineSuite) TestTimeoutRetryHaltsWhenRetriesAreExhausted(c *C) {
+	got := &received{}
+	sub := s.newSubscription("deadline-4", got, TimeoutPolicy{
+		Timeout: 10 * time.Millisecond,
+		Action:  TimeoutRetry,
+		Retries: 1,
+	}, 2)
+	sub.Start()
+	<-sub.Done()
+
+	c.Assert(sub.Err(), FitsTypeOf, &ErrHandlerTimeout{})
+	c.Assert(got.get(), DeepEquals, []int{0, 1})
+}
+
+func (s *DeadlineSuite) TestTimeoutDeadLetterWritesEvent(c *C) {
+	var mu sync.Mutex
+	var letters []Event
+	mux.HandleFunc("/streams/deadline-dlq", func(w http.ResponseWriter, r *http.Request) {
+		es := []Event{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&es), IsNil)
+		mu.Lock()
+		letters = append(letters, es...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	got := &received{}
+	sub := s.newSubscription("deadline-5", got, TimeoutPolicy{
+		Timeout:          10 * time.Millisecond,
+		Action:           TimeoutDeadLetter,
+		DeadLetterStream: "deadline-dlq",
+	}, 1)
+	sub.Start()
+	eventually(func() bool { return sub.LastProcessed() == 4 })
+	sub.Stop()
+
+	c.Assert(got.get(), DeepEquals, []int{0, 1, 3, 4})
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(letters, HasLen, 1)
+	c.Assert(letters[0].EventType, Equals, "Foo")
+}
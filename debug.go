@@ -0,0 +1,152 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugBodyLimit is the number of bytes of a request or response body written
+// by the debug dump. Longer bodies are truncated.
+const debugBodyLimit = 512
+
+// redactedHeaders are the headers whose values are not written by the debug
+// dump because they contain credentials.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Es-Trustedauth":      true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// debugLog writes the debug dump of a client. The entries of concurrent
+// requests are written whole, one after the other.
+type debugLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// SetDebug writes a dump of every request the client sends and the response it
+// receives to w, for troubleshooting. A nil w turns the dump off.
+//
+// Each entry has the method and URL of the request, the status of the response
+// and the time taken, followed by the headers and the first 512 bytes of the
+// body of each. The values of the Authorization and ES-TrustedAuth headers are
+// redacted. Requests are dumped as they are sent on the wire, after the
+// middleware, so a request that is redirected or retried has an entry for each
+// attempt.
+func (c *Client) SetDebug(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w == nil {
+		c.debug = nil
+		return
+	}
+	c.debug = &debugLog{w: w}
+}
+
+// roundTrip sends the request with next and writes the request and the
+// response to the log.
+func (l *debugLog) roundTrip(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--> %s %s\n", req.Method, redactURL(req.URL))
+	writeDebugHeaders(&b, req.Header)
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		writeDebugBody(&b, req.Header, body)
+	}
+
+	start := time.Now()
+	resp, err := next(req)
+	elapsed := time.Since(start).Round(time.Microsecond)
+	if err != nil {
+		fmt.Fprintf(&b, "<-- %s %s error after %v: %v\n", req.Method, redactURL(req.URL), elapsed, err)
+		l.write(b.Bytes())
+		return resp, err
+	}
+
+	fmt.Fprintf(&b, "<-- %s %s %s (%v)\n", resp.Status, req.Method, redactURL(req.URL), elapsed)
+	writeDebugHeaders(&b, resp.Header)
+	if resp.Body != nil {
+		// Only the start of the body is read, the rest is left for the
+		// client to read from the connection.
+		head, _ := ioutil.ReadAll(io.LimitReader(resp.Body, debugBodyLimit+1))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+		writeDebugBody(&b, resp.Header, head)
+	}
+	l.write(b.Bytes())
+	return resp, nil
+}
+
+func (l *debugLog) write(p []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(p)
+}
+
+// writeDebugHeaders writes the headers in name order, redacting credentials.
+func writeDebugHeaders(b *bytes.Buffer, h http.Header) {
+	names := make([]string, 0, len(h))
+	for k := range h {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		v := strings.Join(h[k], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(k)] {
+			v = "[redacted]"
+		}
+		fmt.Fprintf(b, "    %s: %s\n", k, v)
+	}
+}
+
+// writeDebugBody writes the start of a body. Compressed bodies are described
+// rather than written.
+func writeDebugBody(b *bytes.Buffer, h http.Header, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	if enc := h.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		fmt.Fprintf(b, "    [%s body]\n", enc)
+		return
+	}
+	if len(body) > debugBodyLimit {
+		fmt.Fprintf(b, "    %s... [truncated]\n", body[:debugBodyLimit])
+		return
+	}
+	fmt.Fprintf(b, "    %s\n", body)
+}
+
+// redactURL returns the URL with the password of its user info replaced.
+func redactURL(u *url.URL) string {
+	if u.User == nil {
+		return u.String()
+	}
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+	r := *u
+	r.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return r.String()
+}
@@ -0,0 +1,127 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// EventRef identifies an event in a stream.
+type EventRef struct {
+	Stream      string `json:"stream"`
+	EventNumber int    `json:"eventNumber"`
+	EventID     string `json:"eventId"`
+}
+
+// DuplicateGroup is a set of events with different event IDs and the same
+// event type and data.
+//
+// Hash is the hex encoded SHA-256 hash of the event type and data. The events
+// are in the order in which they were read from the category.
+type DuplicateGroup struct {
+	Hash      string     `json:"hash"`
+	EventType string     `json:"eventType"`
+	Events    []EventRef `json:"events"`
+}
+
+// DuplicateReport is the result of FindDuplicates.
+//
+// Scanned is the number of events read. Duplicates is the number of events
+// that have the same content as an earlier event, which is the number of
+// events that would be removed if only the first event in each group were
+// kept.
+type DuplicateReport struct {
+	Category   string           `json:"category"`
+	Scanned    int              `json:"scanned"`
+	Duplicates int              `json:"duplicates"`
+	Groups     []DuplicateGroup `json:"groups"`
+}
+
+// FindDuplicates reads all of the events in a category and reports the events
+// that have the same content but different event IDs.
+//
+// Events with the same ID are the same event and are not reported. This finds
+// events that were published more than once with new IDs, for example by a
+// producer that retries a write after a timeout without reusing the event ID.
+//
+// The category is read from the $ce-<category> stream maintained by the
+// $by_category system projection, which must be running. The data of each
+// event is decoded and encoded again as JSON before it is hashed, so
+// differences in formatting and key order do not hide duplicates. Event
+// metadata is ignored. See CategoryOf.
+func (c *Client) FindDuplicates(category string) (*DuplicateReport, error) {
+	report := &DuplicateReport{Category: category, Groups: []DuplicateGroup{}}
+
+	groups := make(map[string]int)
+	seen := make(map[string]bool)
+
+	reader := c.NewStreamReader("$ce-" + category)
+	for reader.Next() {
+		if reader.Err() != nil {
+			if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+				break
+			}
+			return nil, reader.Err()
+		}
+		er := reader.EventResponse()
+		e := er.Event
+		report.Scanned++
+		if seen[e.EventID] {
+			continue
+		}
+		seen[e.EventID] = true
+
+		hash, err := c.contentHash(er)
+		if err != nil {
+			return nil, err
+		}
+		ref := EventRef{Stream: e.EventStreamID, EventNumber: e.EventNumber, EventID: e.EventID}
+
+		i, ok := groups[hash]
+		if !ok {
+			groups[hash] = len(report.Groups)
+			report.Groups = append(report.Groups, DuplicateGroup{
+				Hash:      hash,
+				EventType: e.EventType,
+				Events:    []EventRef{ref},
+			})
+			continue
+		}
+		report.Groups[i].Events = append(report.Groups[i].Events, ref)
+		report.Duplicates++
+	}
+
+	// Only groups of more than one event are duplicates.
+	dups := []DuplicateGroup{}
+	for _, g := range report.Groups {
+		if len(g.Events) > 1 {
+			dups = append(dups, g)
+		}
+	}
+	report.Groups = dups
+	return report, nil
+}
+
+// contentHash returns the hash of the event type and the normalized data of
+// the event.
+func (c *Client) contentHash(er *EventResponse) (string, error) {
+	var data interface{}
+	if err := c.decodeEvent(er, &data, nil); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(er.Event.EventType))
+	h.Write([]byte{0})
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&DedupSuite{})
+
+type DedupSuite struct{}
+
+func (s *DedupSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *DedupSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// categoryEvent returns an event in the $ce-order stream that originates in
+// the stream provided with the data provided as raw JSON.
+func categoryEvent(n int, stream, eventType, data string) *Event {
+	raw := json.RawMessage(data)
+	e := CreateTestEvent("$ce-order", server.URL, eventType, n, &raw, nil)
+	e.EventStreamID = stream
+	return e
+}
+
+func (s *DedupSuite) TestFindDuplicatesReportsSameContentWithDifferentIDs(c *C) {
+	es := []*Event{
+		categoryEvent(0, "order-1", "OrderPlaced", `{"id":1,"total":10}`),
+		categoryEvent(1, "order-2", "OrderPlaced", `{"id":2,"total":20}`),
+		categoryEvent(2, "order-1", "OrderPlaced", `{"total":10, "id":1}`),
+		categoryEvent(3, "order-1", "OrderShipped", `{"id":1,"total":10}`),
+		categoryEvent(4, "order-3", "OrderPlaced", `{"id":1,"total":10}`),
+	}
+	// The same event read twice is not a duplicate.
+	es = append(es, categoryEvent(5, "order-2", "OrderPlaced", `{"id":2,"total":20}`))
+	es[5].EventID = es[1].EventID
+	setupSimulator(es, nil)
+
+	report, err := client.FindDuplicates("order")
+	c.Assert(err, IsNil)
+	c.Assert(report.Category, Equals, "order")
+	c.Assert(report.Scanned, Equals, 6)
+	c.Assert(report.Duplicates, Equals, 2)
+	c.Assert(report.Groups, HasLen, 1)
+
+	g := report.Groups[0]
+	c.Assert(g.EventType, Equals, "OrderPlaced")
+	c.Assert(g.Events, DeepEquals, []EventRef{
+		{Stream: "order-1", EventNumber: 0, EventID: es[0].EventID},
+		{Stream: "order-1", EventNumber: 2, EventID: es[2].EventID},
+		{Stream: "order-3", EventNumber: 4, EventID: es[4].EventID},
+	})
+}
+
+func (s *DedupSuite) TestFindDuplicatesWithNoDuplicates(c *C) {
+	setupSimulator([]*Event{
+		categoryEvent(0, "order-1", "OrderPlaced", `{"id":1}`),
+		categoryEvent(1, "order-2", "OrderPlaced", `{"id":2}`),
+	}, nil)
+
+	report, err := client.FindDuplicates("order")
+	c.Assert(err, IsNil)
+	c.Assert(report.Scanned, Equals, 2)
+	c.Assert(report.Duplicates, Equals, 0)
+	c.Assert(report.Groups, HasLen, 0)
+}
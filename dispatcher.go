@@ -0,0 +1,409 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Delivery is an event delivered to a consumer group of a Dispatcher.
+//
+// Position is the position of the event in the stream of the dispatcher, which
+// for a stream such as $ce-order differs from the event number of the event.
+// See FeedPosition.
+type Delivery struct {
+	Event    *EventResponse
+	Group    string
+	Position int
+	run      *dispatchRun
+	d        *Dispatcher
+	done     bool
+}
+
+// Done records that the consumer has finished with the event. The checkpoint
+// of the dispatcher moves past an event once every group it was delivered to
+// has called Done for it and for every event before it. Calling Done more than
+// once has no effect.
+func (dl *Delivery) Done() {
+	dl.d.markDone(dl)
+}
+
+// consumerGroup is a consumer group registered with a Dispatcher.
+type consumerGroup struct {
+	name   string
+	filter func(*EventResponse) bool
+	ch     chan *Delivery
+}
+
+// dispatchRun holds the state of a running dispatcher. The fields other than
+// ctx and failed are guarded by the mutex of the dispatcher. inflight and
+// remaining hold the positions of the events in the stream.
+type dispatchRun struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	inflight  []int
+	remaining map[int]int
+	failed    chan struct{}
+	err       error
+}
+
+// Dispatcher reads a stream with a single catch-up subscription and fans its
+// events out to the consumer groups registered in the process, so that any
+// number of consumers of a stream make one set of requests to the server.
+//
+// Each group receives the events that match its filter on its own channel, in
+// stream order. The consumers of a group read from the channel and compete for
+// its events, so a group with several consumers shares its work between them.
+// An event is delivered to every group it matches before the next event is
+// delivered, so a group whose channel is full holds up the others.
+//
+// The dispatcher records a single checkpoint for all of the groups in a
+// CheckpointStore under its name: the last event before which every delivery
+// has been marked Done. When it is started it resumes after the checkpoint, so
+// events delivered but not done before a restart are delivered again and
+// consumers should be idempotent.
+//
+//	d := client.NewDispatcher("orders", "$ce-order", store)
+//	placed := d.Register("placed", func(er *goes.EventResponse) bool {
+//		return er.Event.EventType == "OrderPlaced"
+//	}, 16)
+//	err := d.Start()
+//	for dl := range placed {
+//		process(dl.Event)
+//		dl.Done()
+//	}
+type Dispatcher struct {
+	client   *Client
+	name     string
+	stream   string
+	store    CheckpointStore
+	groups   []*consumerGroup
+	every    int
+	reporter ErrorReporter
+	storeMu  sync.Mutex
+	stored   int
+	mu       sync.Mutex
+	started  bool
+	pending  int
+	last     int
+	run      *dispatchRun
+	err      error
+	stop     chan struct{}
+	drain    chan struct{}
+	done     chan struct{}
+}
+
+// NewDispatcher returns a dispatcher named name that delivers the events in
+// the stream to its consumer groups and records its checkpoint in store under
+// name.
+func (c *Client) NewDispatcher(name, stream string, store CheckpointStore) *Dispatcher {
+	return &Dispatcher{
+		client: c,
+		name:   name,
+		stream: stream,
+		store:  store,
+		every:  1,
+		last:   -1,
+	}
+}
+
+// Register registers a consumer group and returns the channel on which its
+// events are delivered. The channel holds up to buffer events and is closed
+// when the dispatcher stops.
+//
+// filter selects the events delivered to the group; if it is nil every event is
+// delivered. Register panics if a group of the same name is already
+// registered. Groups must be registered before the dispatcher is started.
+func (d *Dispatcher) Register(group string, filter func(*EventResponse) bool, buffer int) <-chan *Delivery {
+	for _, g := range d.groups {
+		if g.name == group {
+			panic(fmt.Sprintf("goes: consumer group %s is already registered", group))
+		}
+	}
+	if buffer < 0 {
+		buffer = 0
+	}
+	g := &consumerGroup{name: group, filter: filter, ch: make(chan *Delivery, buffer)}
+	d.groups = append(d.groups, g)
+	return g.ch
+}
+
+// SetCheckpointInterval sets the number of events done between checkpoints.
+// See Projector.SetCheckpointInterval.
+func (d *Dispatcher) SetCheckpointInterval(n int) {
+	if n < 1 {
+		n = 1
+	}
+	d.every = n
+}
+
+// LastProcessed returns the position in the stream of the last event before
+// which every delivery has been marked Done, including events that matched no
+// group.
+func (d *Dispatcher) LastProcessed() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.last
+}
+
+// Err returns the error that stopped the dispatcher, or nil.
+func (d *Dispatcher) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Start loads the checkpoint of the dispatcher and starts delivering the
+// events after it. An error is returned if the checkpoint cannot be loaded.
+//
+// The channels of the groups are closed when the dispatcher stops, so a
+// dispatcher cannot be started again once it has stopped; create a new one
+// instead.
+func (d *Dispatcher) Start() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.started {
+		if d.stop != nil {
+			return nil
+		}
+		return fmt.Errorf("Dispatcher %s has stopped and cannot be started again", d.name)
+	}
+	cp, err := d.store.Load(d.name)
+	if err != nil {
+		return err
+	}
+	d.started = true
+	d.stop = make(chan struct{})
+	d.drain = make(chan struct{})
+	d.done = make(chan struct{})
+	d.last = cp
+	d.stored = cp
+
+	run := &dispatchRun{
+		remaining: make(map[int]int),
+		failed:    make(chan struct{}),
+	}
+	run.ctx, run.cancel = context.WithCancel(context.Background())
+	d.run = run
+
+	sub := d.client.NewCatchUpSubscription(d.stream, cp+1, nil)
+	sub.SetContextHandler(d.dispatch)
+	sub.Start()
+	go d.loop(sub, run, d.stop, d.drain, d.done)
+	return nil
+}
+
+// Stop stops the dispatcher, closes the channels of the groups and stores the
+// checkpoint of the events done. Events still in the channels are discarded.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	stop, done := d.stop, d.done
+	d.stop = nil
+	d.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Shutdown stops the dispatcher gracefully. The event being delivered is
+// delivered to every group it matches, which waits for room in their channels,
+// before the channels are closed. The checkpoint of the events done is then
+// stored and Shutdown returns Err.
+//
+// If ctx is done before then the dispatcher is stopped as with Stop and
+// ctx.Err() is returned.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	stop, drain, done := d.stop, d.drain, d.done
+	d.stop = nil
+	d.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	close(drain)
+	select {
+	case <-done:
+		return d.Err()
+	case <-ctx.Done():
+		close(stop)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed when the dispatcher stops.
+func (d *Dispatcher) Done() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.done
+}
+
+func (d *Dispatcher) loop(sub *Subscription, run *dispatchRun, stop, drain, done chan struct{}) {
+	defer close(done)
+
+	var err error
+	draining := false
+	for stopped := false; !stopped; {
+		select {
+		case <-stop:
+			stopped = true
+		case <-drain:
+			draining = true
+			stopped = true
+		case <-sub.Done():
+			err = sub.Err()
+			stopped = true
+		case <-run.failed:
+			stopped = true
+		case e := <-sub.Errors():
+			if !IsFatal(e) {
+				d.reporter.Report(d.component(), e.(*ErrBackground).Err, false)
+			}
+		}
+	}
+
+	if draining {
+		// The event being delivered is delivered to every group unless the
+		// shutdown is cut short by Stop.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+				run.cancel()
+			case <-ctx.Done():
+			}
+		}()
+		sub.Shutdown(ctx)
+		err = sub.Err()
+	}
+	run.cancel()
+	sub.Stop()
+	for _, g := range d.groups {
+		close(g.ch)
+	}
+
+	d.mu.Lock()
+	if run.err != nil {
+		err = run.err
+	}
+	pending, last := d.pending, d.last
+	d.pending = 0
+	d.mu.Unlock()
+	if pending > 0 {
+		if cerr := d.storeCheckpoint(last); err == nil {
+			err = cerr
+		}
+	}
+
+	d.mu.Lock()
+	d.err = err
+	d.mu.Unlock()
+	if err != nil {
+		d.reporter.Report(d.component(), err, true)
+	}
+}
+
+func (d *Dispatcher) component() string {
+	return "Dispatcher " + d.name
+}
+
+// dispatch delivers the event to the groups it matches. It returns the error
+// that stopped the dispatcher, which stops the subscription.
+func (d *Dispatcher) dispatch(ctx context.Context, er *EventResponse) error {
+	groups := make([]*consumerGroup, 0, len(d.groups))
+	for _, g := range d.groups {
+		if g.filter == nil || g.filter(er) {
+			groups = append(groups, g)
+		}
+	}
+
+	pos, _ := FeedPosition(ctx)
+	d.mu.Lock()
+	run := d.run
+	if run.err != nil {
+		d.mu.Unlock()
+		return run.err
+	}
+	run.inflight = append(run.inflight, pos)
+	run.remaining[pos] = len(groups)
+	d.mu.Unlock()
+
+	if len(groups) == 0 {
+		return d.advance(run)
+	}
+	for _, g := range groups {
+		select {
+		case g.ch <- &Delivery{Event: er, Group: g.name, Position: pos, run: run, d: d}:
+		case <-run.ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// markDone records that the delivery has been done and advances the
+// checkpoint.
+func (d *Dispatcher) markDone(dl *Delivery) {
+	d.mu.Lock()
+	if dl.done {
+		d.mu.Unlock()
+		return
+	}
+	dl.done = true
+	dl.run.remaining[dl.Position]--
+	d.mu.Unlock()
+
+	if err := d.advance(dl.run); err != nil {
+		d.mu.Lock()
+		if dl.run.err == nil {
+			dl.run.err = err
+			close(dl.run.failed)
+		}
+		d.mu.Unlock()
+	}
+}
+
+// advance moves the last event before which every delivery has been done and
+// stores a checkpoint every checkpoint interval.
+func (d *Dispatcher) advance(run *dispatchRun) error {
+	d.mu.Lock()
+	for len(run.inflight) > 0 && run.remaining[run.inflight[0]] == 0 {
+		delete(run.remaining, run.inflight[0])
+		d.last = run.inflight[0]
+		run.inflight = run.inflight[1:]
+		d.pending++
+	}
+	due := d.pending >= d.every
+	if due {
+		d.pending = 0
+	}
+	last := d.last
+	d.mu.Unlock()
+	if due {
+		return d.storeCheckpoint(last)
+	}
+	return nil
+}
+
+// storeCheckpoint stores the checkpoint unless a later one has been stored.
+func (d *Dispatcher) storeCheckpoint(cp int) error {
+	d.storeMu.Lock()
+	defer d.storeMu.Unlock()
+	if cp <= d.stored {
+		return nil
+	}
+	if err := d.store.Store(d.name, cp); err != nil {
+		return err
+	}
+	d.stored = cp
+	return nil
+}
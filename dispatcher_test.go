@@ -0,0 +1,211 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&DispatcherSuite{})
+
+type DispatcherSuite struct{}
+
+func (s *DispatcherSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *DispatcherSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// even selects the events with an even event number.
+func even(er *EventResponse) bool {
+	return er.Event.EventNumber%2 == 0
+}
+
+// eventReads counts the requests for events made to a simulator.
+type eventReads struct {
+	sync.Mutex
+	sim http.Handler
+	n   int
+}
+
+func (h *eventReads) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if eventPath.MatchString(r.URL.Path) {
+		h.Lock()
+		h.n++
+		h.Unlock()
+	}
+	h.sim.ServeHTTP(w, r)
+}
+
+func (h *eventReads) count() int {
+	h.Lock()
+	defer h.Unlock()
+	return h.n
+}
+
+// deliveries records the event numbers received by each group.
+type deliveries struct {
+	mu  sync.Mutex
+	got map[string][]int
+	wg  sync.WaitGroup
+}
+
+// consume reads the channel until it is closed, marking each delivery done
+// unless hold returns true for it.
+func (d *deliveries) consume(ch <-chan *Delivery, hold func(*Delivery) bool) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for dl := range ch {
+			d.mu.Lock()
+			d.got[dl.Group] = append(d.got[dl.Group], dl.Event.Event.EventNumber)
+			d.mu.Unlock()
+			if hold == nil || !hold(dl) {
+				dl.Done()
+			}
+		}
+	}()
+}
+
+func (s *DispatcherSuite) TestEventsAreFannedOutToGroups(c *C) {
+	stream := "dispatch-1"
+	h := &eventReads{sim: newTestSimulator(CreateTestEvents(10, stream, server.URL, "Foo"), nil)}
+	mux.Handle("/", h)
+	store := NewMemoryCheckpointStore()
+
+	d := client.NewDispatcher("dispatch", stream, store)
+	evens := d.Register("even", even, 0)
+	all := d.Register("all", nil, 4)
+	c.Assert(d.Start(), IsNil)
+
+	// Two consumers compete for the events of the all group.
+	rec := &deliveries{got: make(map[string][]int)}
+	rec.consume(evens, nil)
+	rec.consume(all, nil)
+	rec.consume(all, nil)
+
+	eventually(func() bool { return d.LastProcessed() == 9 })
+	d.Stop()
+	rec.wg.Wait()
+
+	c.Assert(rec.got["even"], DeepEquals, []int{0, 2, 4, 6, 8})
+	sort.Ints(rec.got["all"])
+	c.Assert(rec.got["all"], DeepEquals, sequence(10))
+	cp, err := store.Load("dispatch")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 9)
+
+	// Each event is read once for all of the groups.
+	c.Assert(h.count(), Equals, 10)
+
+	c.Assert(d.Start(), ErrorMatches, "Dispatcher dispatch has stopped and cannot be started again")
+}
+
+func (s *DispatcherSuite) TestCheckpointWaitsForEveryGroup(c *C) {
+	stream := "dispatch-2"
+	setupSimulator(CreateTestEvents(6, stream, server.URL, "Foo"), nil)
+	store := NewMemoryCheckpointStore()
+
+	d := client.NewDispatcher("dispatch", stream, store)
+	evens := d.Register("even", even, 0)
+	all := d.Register("all", nil, 0)
+	c.Assert(d.Start(), IsNil)
+
+	// The even group does not finish event 2.
+	rec := &deliveries{got: make(map[string][]int)}
+	rec.consume(evens, func(dl *Delivery) bool { return dl.Event.Event.EventNumber == 2 })
+	rec.consume(all, nil)
+
+	eventually(func() bool {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		return len(rec.got["all"]) == 6
+	})
+	time.Sleep(20 * time.Millisecond)
+	c.Assert(d.LastProcessed(), Equals, 1)
+	c.Assert(d.Shutdown(context.Background()), IsNil)
+	rec.wg.Wait()
+
+	cp, err := store.Load("dispatch")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 1)
+
+	// A new dispatcher resumes after the checkpoint.
+	d = client.NewDispatcher("dispatch", stream, store)
+	all = d.Register("all", nil, 0)
+	c.Assert(d.Start(), IsNil)
+	rec = &deliveries{got: make(map[string][]int)}
+	rec.consume(all, nil)
+	eventually(func() bool { return d.LastProcessed() == 5 })
+	d.Stop()
+	rec.wg.Wait()
+	c.Assert(rec.got["all"], DeepEquals, []int{2, 3, 4, 5})
+}
+
+func (s *DispatcherSuite) TestDeliveryDoneIsIdempotent(c *C) {
+	stream := "dispatch-3"
+	setupSimulator(CreateTestEvents(2, stream, server.URL, "Foo"), nil)
+
+	d := client.NewDispatcher("dispatch", stream, NewMemoryCheckpointStore())
+	a := d.Register("a", nil, 0)
+	b := d.Register("b", nil, 2)
+	c.Assert(d.Start(), IsNil)
+	defer d.Stop()
+
+	// Event 0 is done twice by group a, which must not count for group b.
+	dl := <-a
+	dl.Done()
+	dl.Done()
+	time.Sleep(20 * time.Millisecond)
+	c.Assert(d.LastProcessed(), Equals, -1)
+	(<-b).Done()
+	eventually(func() bool { return d.LastProcessed() == 0 })
+	c.Assert(d.LastProcessed(), Equals, 0)
+}
+
+func (s *DispatcherSuite) TestRegisterRejectsDuplicateGroups(c *C) {
+	d := client.NewDispatcher("dispatch", "dispatch-4", NewMemoryCheckpointStore())
+	d.Register("a", nil, 0)
+	c.Assert(func() { d.Register("a", nil, 0) }, PanicMatches, "goes: consumer group a is already registered")
+}
+
+func (s *DispatcherSuite) TestCategoryStreamIsTrackedByFeedPosition(c *C) {
+	// The events of the two streams have the same event numbers.
+	serveLinkedStream("$ce-order", interleaved(2, "order-1", "order-2"))
+	store := NewMemoryCheckpointStore()
+
+	d := client.NewDispatcher("dispatch", "$ce-order", store)
+	all := d.Register("all", nil, 4)
+	c.Assert(d.Start(), IsNil)
+	defer d.Stop()
+
+	held := []*Delivery{}
+	positions := []int{}
+	for i := 0; i < 4; i++ {
+		dl := <-all
+		positions = append(positions, dl.Position)
+		if dl.Event.Event.EventStreamID == "order-1" && dl.Event.Event.EventNumber == 0 {
+			held = append(held, dl)
+			continue
+		}
+		dl.Done()
+	}
+	c.Assert(positions, DeepEquals, []int{0, 1, 2, 3})
+	c.Assert(held, HasLen, 1)
+	time.Sleep(20 * time.Millisecond)
+	c.Assert(d.LastProcessed(), Equals, -1)
+
+	held[0].Done()
+	eventually(func() bool { return d.LastProcessed() == 3 })
+	c.Assert(d.LastProcessed(), Equals, 3)
+}
@@ -5,7 +5,10 @@
 
 package goes
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type errInvalidVersion int
 
@@ -21,6 +24,13 @@ func (e ErrNoMoreEvents) Error() string {
 	return "There are no more events to load."
 }
 
+// ErrNoEvents is returned when a stream exists but contains no events.
+type ErrNoEvents struct{}
+
+func (e ErrNoEvents) Error() string {
+	return "The stream contains no events."
+}
+
 // ErrNotFound is returned when a stream is not found.
 type ErrNotFound struct {
 	ErrorResponse *ErrorResponse
@@ -64,6 +74,23 @@ func (e ErrTemporarilyUnavailable) Error() string {
 	return "Server Is Not Ready"
 }
 
+// ErrServerBusy is returned when the server is shedding load, either with
+// TooManyRequests or with ServiceUnavailable and a Retry-After header.
+//
+// RetryAfter is the time the server asked the client to wait before making
+// another request, or 0 if the server did not say.
+type ErrServerBusy struct {
+	ErrorResponse *ErrorResponse
+	RetryAfter    time.Duration
+}
+
+func (e ErrServerBusy) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("The server is busy. Retry after %v.", e.RetryAfter)
+	}
+	return "The server is busy."
+}
+
 // ErrUnexpected is returned when a request to the eventstore returns an error that
 // is not explicitly represented by a goes Error type such as UnauthorisedError or
 // ErrNotFound
@@ -93,3 +120,220 @@ type ErrConcurrencyViolation struct {
 func (e ErrConcurrencyViolation) Error() string {
 	return "Concurrency Error."
 }
+
+// ErrStreamHardDeleted is returned when events are appended to a stream that
+// has been hard deleted. A hard deleted stream can never be written to again.
+//
+// Appends to a hard deleted stream returned an *ErrDeleted before
+// ErrStreamHardDeleted was added. The *ErrDeleted is embedded, so its
+// ErrorResponse is available as before, and it is returned by Unwrap so that
+// errors.As(err, &deleted) with deleted of type *ErrDeleted still matches.
+// Code that asserts the type of the error with err.(*ErrDeleted) must assert
+// *ErrStreamHardDeleted instead.
+type ErrStreamHardDeleted struct {
+	*ErrDeleted
+	Stream string
+}
+
+func (e ErrStreamHardDeleted) Error() string {
+	return fmt.Sprintf("Stream %s has been hard deleted and cannot be written to.", e.Stream)
+}
+
+// Unwrap returns the *ErrDeleted returned by the eventstore.
+func (e ErrStreamHardDeleted) Unwrap() error {
+	return e.ErrDeleted
+}
+
+// ErrUnregisteredType is returned when an event is decoded using a TypeRegistry
+// and no type has been registered for the event type.
+type ErrUnregisteredType struct {
+	EventType string
+}
+
+func (e ErrUnregisteredType) Error() string {
+	return fmt.Sprintf("No type is registered for event type %s.", e.EventType)
+}
+
+// ErrSchemaVersion is returned when an event has a schema version below the
+// minimum version supported by the reader and cannot be upcast to it.
+type ErrSchemaVersion struct {
+	EventType  string
+	Version    int
+	MinVersion int
+}
+
+func (e ErrSchemaVersion) Error() string {
+	return fmt.Sprintf("Event type %s has schema version %d, the minimum supported version is %d.",
+		e.EventType, e.Version, e.MinVersion)
+}
+
+// ErrInvalidExpectedVersion is returned when a write is made with an expected
+// version that is neither an event number nor one of the named versions, such
+// as ExpectAny. The write is not sent to the server.
+type ErrInvalidExpectedVersion struct {
+	Version int
+}
+
+func (e ErrInvalidExpectedVersion) Error() string {
+	return fmt.Sprintf("Expected version %d is not valid.", e.Version)
+}
+
+// ErrInvalidUUID is returned when a value that should be a UUID, such as the id
+// of an event, is not. Events with an invalid id are rejected before they are
+// sent to the server.
+type ErrInvalidUUID struct {
+	Value string
+}
+
+func (e ErrInvalidUUID) Error() string {
+	return fmt.Sprintf("%q is not a valid UUID.", e.Value)
+}
+
+// ErrInvalidEvent is returned when an event of a write is missing a field the
+// server requires. Index is the position of the event in the write and Field
+// is the name of the missing field in the events+json format.
+type ErrInvalidEvent struct {
+	Index int
+	Field string
+}
+
+func (e ErrInvalidEvent) Error() string {
+	return fmt.Sprintf("Event %d of the write has no %s.", e.Index, e.Field)
+}
+
+// ErrArchiveCorrupt is returned when an archive does not match its manifest.
+type ErrArchiveCorrupt struct {
+	File   string
+	Reason string
+}
+
+func (e ErrArchiveCorrupt) Error() string {
+	return fmt.Sprintf("Archive file %s is corrupt: %s", e.File, e.Reason)
+}
+
+// ErrTooManyEvents is returned when a read that returns its events in a slice
+// would hold more events in memory than the limit set with
+// SetMaxBufferedEvents.
+//
+// Stream and Next are the continuation of the read: the stream being read and
+// the event number of the first event that was not read. The rest of the
+// stream can be read from there with a StreamReader, which holds one page of
+// events at a time, or in slices with ReadStreamEventsForward.
+type ErrTooManyEvents struct {
+	Limit  int
+	Stream string
+	Next   int
+}
+
+func (e ErrTooManyEvents) Error() string {
+	return fmt.Sprintf("Reading stream %s would hold more than %d events in memory, stopped at event %d.", e.Stream, e.Limit, e.Next)
+}
+
+// ErrStaleRead is returned when a stream has not reached the version of a
+// ConsistencyToken within the time allowed.
+type ErrStaleRead struct {
+	Stream   string
+	Version  int
+	Required int
+}
+
+func (e ErrStaleRead) Error() string {
+	return fmt.Sprintf("Stream %s is at version %d but version %d is required", e.Stream, e.Version, e.Required)
+}
+
+// ErrHandlerTimeout is returned when a subscription handler does not return
+// within the timeout of the subscription's TimeoutPolicy.
+type ErrHandlerTimeout struct {
+	Stream      string
+	EventNumber int
+	Timeout     time.Duration
+}
+
+func (e ErrHandlerTimeout) Error() string {
+	return fmt.Sprintf("Handler for event %d in stream %s did not return within %v",
+		e.EventNumber, e.Stream, e.Timeout)
+}
+
+// ErrJobNotFound is returned by a JobRunner for a job id that is not known to
+// the runner.
+type ErrJobNotFound struct {
+	ID string
+}
+
+func (e ErrJobNotFound) Error() string {
+	return fmt.Sprintf("Job %s not found", e.ID)
+}
+
+// ErrPlanConflict is returned from ExecutePlan when the stream of an operation
+// is no longer in the state that the operation was planned against.
+type ErrPlanConflict struct {
+	Operation int
+	Stream    string
+	Err       error
+}
+
+func (e ErrPlanConflict) Error() string {
+	return fmt.Sprintf("Operation %d on stream %s conflicts with the current state of the stream: %v",
+		e.Operation, e.Stream, e.Err)
+}
+
+// ErrProtocol is returned in strict mode when a response from the server does
+// not satisfy the invariants of the protocol, which usually means it was
+// corrupted by a proxy or cache between the client and the server. URL is the
+// url of the response and Reason describes what is wrong with it.
+type ErrProtocol struct {
+	URL    string
+	Reason string
+}
+
+func (e ErrProtocol) Error() string {
+	return fmt.Sprintf("Invalid response from %s: %s.", e.URL, e.Reason)
+}
+
+// ErrTypeIndex is returned from StreamWriter.Append when the events were written
+// to the stream but the type index could not be updated.
+type ErrTypeIndex struct {
+	Err error
+}
+
+func (e ErrTypeIndex) Error() string {
+	return fmt.Sprintf("The events were written but the type index was not updated: %v", e.Err)
+}
+
+// ErrBackground is sent on the Errors channel of a background component, such
+// as a Subscription, StandbyConsumer, PriorityReplay or JobRunner, when it
+// encounters an error.
+//
+// Component identifies the component and Err is the error encountered. A fatal
+// error stopped the component, or in the case of a JobRunner the job. A
+// retryable error did not; the component recovers by itself, for example by
+// backing off and reading the stream again.
+//
+// Errors are sent on the Errors channel without blocking, so a component is
+// never held up by a channel that is not read, and are discarded while the
+// channel is full. The channel is never closed; use the Done channel of the
+// component to wait for it to stop.
+type ErrBackground struct {
+	Component string
+	Err       error
+	Fatal     bool
+}
+
+func (e ErrBackground) Error() string {
+	if e.Fatal {
+		return fmt.Sprintf("%s stopped: %v", e.Component, e.Err)
+	}
+	return fmt.Sprintf("%s will retry: %v", e.Component, e.Err)
+}
+
+// Retryable returns true if the component recovers from the error by itself.
+func (e ErrBackground) Retryable() bool {
+	return !e.Fatal
+}
+
+// IsFatal returns true if err is an *ErrBackground for an error that stopped
+// the component that reported it.
+func IsFatal(err error) bool {
+	e, ok := err.(*ErrBackground)
+	return ok && e.Fatal
+}
@@ -0,0 +1,252 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+)
+
+// EventBuilder builds test events, event responses and feed pages for a stream.
+//
+// By default the events built are deterministic. Event ids are derived from the
+// event number, event types are assigned in rotation from the types configured
+// and event timestamps are spaced at a fixed interval from a fixed start time.
+// The data and metadata of each event can be customized using functions that
+// receive the event number.
+//
+// The events built have the shape of events returned from the eventstore: the
+// stream id, event number and links are populated and the data and metadata are
+// *json.RawMessage.
+type EventBuilder struct {
+	stream     string
+	server     string
+	eventTypes []string
+	randomIDs  bool
+	start      time.Time
+	interval   time.Duration
+	data       func(n int) interface{}
+	meta       func(n int) interface{}
+}
+
+// NewEventBuilder returns an EventBuilder for the stream.
+func NewEventBuilder(stream string) *EventBuilder {
+	return &EventBuilder{
+		stream:     stream,
+		server:     "http://localhost:2113",
+		eventTypes: []string{"TestEvent"},
+		start:      time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+		interval:   time.Second,
+		data: func(n int) interface{} {
+			return map[string]string{"foo": strconv.Itoa(n)}
+		},
+		meta: func(n int) interface{} {
+			return map[string]string{"bar": strconv.Itoa(n)}
+		},
+	}
+}
+
+// WithServer sets the server url used in the links of the events and feeds built.
+func (b *EventBuilder) WithServer(serverURL string) *EventBuilder {
+	b.server = strings.TrimRight(serverURL, "/")
+	return b
+}
+
+// WithEventTypes sets the event types assigned to events. Types are assigned in
+// rotation in the order provided.
+func (b *EventBuilder) WithEventTypes(eventTypes ...string) *EventBuilder {
+	if len(eventTypes) > 0 {
+		b.eventTypes = eventTypes
+	}
+	return b
+}
+
+// WithRandomIDs causes a new random uuid to be generated for each event built.
+func (b *EventBuilder) WithRandomIDs() *EventBuilder {
+	b.randomIDs = true
+	return b
+}
+
+// WithSequentialIDs causes event ids to be derived from the event number. This
+// is the default.
+func (b *EventBuilder) WithSequentialIDs() *EventBuilder {
+	b.randomIDs = false
+	return b
+}
+
+// WithTimestamps sets the time of the first event and the interval between the
+// times of subsequent events.
+func (b *EventBuilder) WithTimestamps(start time.Time, interval time.Duration) *EventBuilder {
+	b.start = start
+	b.interval = interval
+	return b
+}
+
+// WithData sets the function used to create the data for each event. The data
+// returned is serialized to JSON.
+func (b *EventBuilder) WithData(fn func(n int) interface{}) *EventBuilder {
+	b.data = fn
+	return b
+}
+
+// WithMetaData sets the function used to create the metadata for each event.
+// The metadata returned is serialized to JSON. If the function returns nil the
+// event will have no metadata.
+func (b *EventBuilder) WithMetaData(fn func(n int) interface{}) *EventBuilder {
+	b.meta = fn
+	return b
+}
+
+// ID returns the sequential event id for the event number n.
+func (b *EventBuilder) ID(n int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012x", n)
+}
+
+// Time returns the timestamp of the event number n.
+func (b *EventBuilder) Time(n int) time.Time {
+	return b.start.Add(time.Duration(n) * b.interval)
+}
+
+// Clock returns a function that returns the timestamps of the events built in
+// turn. It can be passed to Simulator.SetClock so that events appended to the
+// simulator have the timestamps of the builder.
+func (b *EventBuilder) Clock() func() time.Time {
+	var mu sync.Mutex
+	n := 0
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		t := b.Time(n)
+		n++
+		return t
+	}
+}
+
+// Build returns n events numbered from 0.
+func (b *EventBuilder) Build(n int) []*goes.Event {
+	ret := make([]*goes.Event, n)
+	for i := 0; i < n; i++ {
+		ret[i] = b.Event(i)
+	}
+	return ret
+}
+
+// Event returns the event with the event number n.
+func (b *EventBuilder) Event(n int) *goes.Event {
+	e := &goes.Event{
+		EventStreamID: b.stream,
+		EventNumber:   n,
+		EventType:     b.eventTypes[n%len(b.eventTypes)],
+		EventID:       b.ID(n),
+	}
+	if b.randomIDs {
+		e.EventID = goes.NewUUID()
+	}
+
+	e.Data = rawJSON(b.data(n))
+	if b.meta != nil {
+		if m := b.meta(n); m != nil {
+			e.MetaData = rawJSON(m)
+		}
+	}
+
+	u := fmt.Sprintf("%s/streams/%s/%d", b.server, b.stream, n)
+	e.Links = []goes.Link{
+		{URI: u, Relation: "edit"},
+		{URI: u, Relation: "alternate"},
+	}
+	return e
+}
+
+// Responses returns the EventResponses for the events provided with the Updated
+// time of each response set to the builder timestamp of the event.
+func (b *EventBuilder) Responses(events []*goes.Event) []*goes.EventResponse {
+	ret := make([]*goes.EventResponse, len(events))
+	for i, e := range events {
+		ret[i] = &goes.EventResponse{
+			Title:   fmt.Sprintf("%d@%s", e.EventNumber, e.EventStreamID),
+			ID:      e.Links[0].URI,
+			Updated: goes.Time(b.Time(e.EventNumber)),
+			Summary: e.EventType,
+			Event:   e,
+		}
+	}
+	return ret
+}
+
+// AtomResponse returns the *EventAtomResponse the eventstore returns for the
+// event, with the Updated time set to the builder timestamp of the event. It
+// can be encoded with encoding/json to serve the event from a test handler.
+func (b *EventBuilder) AtomResponse(e *goes.Event) *EventAtomResponse {
+	return newEventAtomResponse(e, b.Time(e.EventNumber))
+}
+
+// Feed returns the feed page at feedURL over the events provided.
+//
+// feedURL may be the url of the stream, which returns the head page, or a paged
+// url of the form {server}/streams/{stream}/{version|head}/{direction}/{count}.
+// The events must be numbered from 0 in stream order such as the events returned
+// from Build. The feed page contains paging links as returned by the eventstore.
+func (b *EventBuilder) Feed(events []*goes.Event, feedURL string) (*atom.Feed, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	seg := strings.Split(strings.Trim(strings.TrimPrefix(u.Path, "/streams/"), "/"), "/")
+	version, direction, count := -1, "backward", defaultPageSize
+	switch len(seg) {
+	case 1:
+	case 4:
+		if seg[1] != "head" {
+			if version, err = strconv.Atoi(seg[1]); err != nil {
+				return nil, err
+			}
+		}
+		direction = seg[2]
+		if count, err = strconv.Atoi(seg[3]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a feed url", feedURL)
+	}
+
+	recs := make([]*record, len(events))
+	for i, e := range events {
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		recs[i] = &record{
+			Number:  e.EventNumber,
+			ID:      e.EventID,
+			Type:    e.EventType,
+			Data:    data,
+			Created: b.Time(e.EventNumber),
+		}
+	}
+
+	host := u.Scheme + "://" + u.Host
+	return buildFeed(host, seg[0], recs, 0, version, direction, count, b.Time(len(events))), nil
+}
+
+// rawJSON serializes v and returns the result as a *json.RawMessage.
+func rawJSON(v interface{}) *json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	raw := json.RawMessage(b)
+	return &raw
+}
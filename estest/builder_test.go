@@ -0,0 +1,123 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&BuilderSuite{})
+
+type BuilderSuite struct{}
+
+func (s *BuilderSuite) TestBuildIsDeterministic(c *C) {
+	b := NewEventBuilder("some-stream").WithEventTypes("A", "B")
+	es1 := b.Build(3)
+	es2 := b.Build(3)
+	c.Assert(es1, DeepEquals, es2)
+
+	c.Assert(es1[0].EventType, Equals, "A")
+	c.Assert(es1[1].EventType, Equals, "B")
+	c.Assert(es1[2].EventType, Equals, "A")
+	c.Assert(es1[2].EventID, Equals, "00000000-0000-4000-8000-000000000002")
+	c.Assert(es1[2].EventNumber, Equals, 2)
+	c.Assert(es1[2].Links[1].URI, Equals, "http://localhost:2113/streams/some-stream/2")
+}
+
+func (s *BuilderSuite) TestRandomIDs(c *C) {
+	es := NewEventBuilder("some-stream").WithRandomIDs().Build(2)
+	c.Assert(es[0].EventID, Not(Equals), es[1].EventID)
+	c.Assert(es[0].EventID, Not(Equals), "00000000-0000-4000-8000-000000000000")
+}
+
+func (s *BuilderSuite) TestCustomDataAndMetaData(c *C) {
+	type Order struct {
+		ID int `json:"id"`
+	}
+	b := NewEventBuilder("orders").
+		WithData(func(n int) interface{} { return &Order{ID: n * 10} }).
+		WithMetaData(func(n int) interface{} { return nil })
+
+	e := b.Event(4)
+	got := &Order{}
+	err := json.Unmarshal(*e.Data.(*json.RawMessage), got)
+	c.Assert(err, IsNil)
+	c.Assert(got.ID, Equals, 40)
+	c.Assert(e.MetaData, IsNil)
+}
+
+func (s *BuilderSuite) TestResponsesUseBuilderTimestamps(c *C) {
+	start := time.Date(2016, 6, 1, 12, 0, 0, 0, time.UTC)
+	b := NewEventBuilder("timed").WithTimestamps(start, time.Minute)
+
+	rs := b.Responses(b.Build(3))
+	c.Assert(rs[2].Updated, Equals, goes.Time(start.Add(2*time.Minute)))
+	c.Assert(rs[2].Title, Equals, "2@timed")
+}
+
+func (s *BuilderSuite) TestFeedHasPagingLinks(c *C) {
+	b := NewEventBuilder("paged")
+	es := b.Build(50)
+
+	f, err := b.Feed(es, "http://localhost:2113/streams/paged/20/forward/10")
+	c.Assert(err, IsNil)
+	c.Assert(f.Entry, HasLen, 10)
+	c.Assert(f.Entry[0].Title, Equals, "29@paged")
+	c.Assert(f.Entry[9].Title, Equals, "20@paged")
+	c.Assert(f.GetLink("previous").Href, Equals, "http://localhost:2113/streams/paged/30/forward/10")
+	c.Assert(f.GetLink("next").Href, Equals, "http://localhost:2113/streams/paged/19/backward/10")
+	c.Assert(f.GetLink("last").Href, Equals, "http://localhost:2113/streams/paged/0/forward/10")
+	c.Assert(f.HeadOfStream, Equals, false)
+
+	f, err = b.Feed(es, "http://localhost:2113/streams/paged")
+	c.Assert(err, IsNil)
+	c.Assert(f.Entry, HasLen, 20)
+	c.Assert(f.Entry[0].Title, Equals, "49@paged")
+	c.Assert(f.HeadOfStream, Equals, true)
+}
+
+func (s *BuilderSuite) TestBuiltEventsCanSeedTheSimulator(c *C) {
+	b := NewEventBuilder("seeded")
+	sim := NewSimulator()
+	sim.SetClock(b.Clock())
+	c.Assert(sim.Append("seeded", b.Build(3)...), IsNil)
+
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	client, _ := goes.NewClient(nil, server.URL)
+
+	e, err := client.ReadLast("seeded", nil)
+	c.Assert(err, IsNil)
+	c.Assert(e.Event.EventID, Equals, b.ID(2))
+	c.Assert(e.Updated, Equals, goes.Time(b.Time(2)))
+}
+
+func (s *BuilderSuite) TestAtomResponsesCanBeServed(c *C) {
+	b := NewEventBuilder("served").WithEventTypes("Created")
+	server := httptest.NewServer(nil)
+	defer server.Close()
+	b.WithServer(server.URL)
+	es := b.Build(2)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(b.AtomResponse(es[1]))
+	})
+	client, _ := goes.NewClient(nil, server.URL)
+
+	er, _, err := client.GetEvent(es[1].Links[0].URI)
+	c.Assert(err, IsNil)
+	c.Assert(er.Title, Equals, "1@served")
+	c.Assert(er.Summary, Equals, "Created")
+	c.Assert(er.Updated, Equals, goes.Time(b.Time(1)))
+	c.Assert(er.Event.EventID, Equals, b.ID(1))
+}
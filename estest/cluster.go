@@ -0,0 +1,324 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cluster simulates an eventstore cluster of several nodes, each served by its
+// own test server.
+//
+// Each node holds a copy of the database in a Simulator. Writes are accepted
+// only by a node that believes it is the master, and are replicated to the
+// other nodes the master can reach before the response is returned. Reads are
+// served by any node from its own copy, so a follower that has not received
+// the most recent writes returns stale results, as a real follower would.
+//
+// The network between the nodes, and between clients and the nodes, can be
+// changed during a test to script partition scenarios deterministically. See
+// IsolateMaster, SplitBrain, SlowFollower and Heal.
+//
+// A Cluster is safe for concurrent use.
+type Cluster struct {
+	mu    sync.Mutex
+	nodes []*Node
+}
+
+// Node is a node of a simulated Cluster.
+type Node struct {
+	cluster  *Cluster
+	index    int
+	sim      *Simulator
+	server   *httptest.Server
+	master   bool
+	isolated bool
+	group    int
+	lagging  bool
+	latency  time.Duration
+}
+
+// NewCluster starts a cluster of size nodes. Node 0 is the master.
+//
+// The cluster should be closed when it is no longer required.
+func NewCluster(size int) *Cluster {
+	c := &Cluster{}
+	for i := 0; i < size; i++ {
+		n := &Node{
+			cluster: c,
+			index:   i,
+			sim:     NewSimulator(),
+			master:  i == 0,
+		}
+		n.server = httptest.NewServer(n)
+		c.nodes = append(c.nodes, n)
+	}
+	return c
+}
+
+// Close stops the servers of all nodes.
+func (c *Cluster) Close() {
+	for _, n := range c.nodes {
+		n.server.CloseClientConnections()
+		n.server.Close()
+	}
+}
+
+// Node returns the node at index i.
+func (c *Cluster) Node(i int) *Node {
+	return c.nodes[i]
+}
+
+// Nodes returns the nodes of the cluster.
+func (c *Cluster) Nodes() []*Node {
+	return append([]*Node(nil), c.nodes...)
+}
+
+// Master returns the lowest numbered node that believes it is the master, or
+// nil if there is none.
+func (c *Cluster) Master() *Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, n := range c.nodes {
+		if n.master {
+			return n
+		}
+	}
+	return nil
+}
+
+// IsolateMaster disconnects the master from the other nodes and from clients,
+// and elects the lowest numbered remaining node as the new master.
+//
+// Requests to the isolated node fail because the connection is closed without
+// a response. The isolated node still believes it is the master until Heal is
+// called. The new master is returned.
+func (c *Cluster) IsolateMaster() *Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var elected *Node
+	for _, n := range c.nodes {
+		if n.master && !n.isolated {
+			n.isolated = true
+			continue
+		}
+		if elected == nil && !n.isolated {
+			elected = n
+		}
+	}
+	if elected != nil {
+		elected.master = true
+	}
+	return elected
+}
+
+// SplitBrain partitions the nodes into the two groups of node indexes provided
+// and makes the lowest numbered node of each group a master.
+//
+// Clients can reach the nodes on both sides of the partition. Writes accepted
+// on one side are replicated only to the nodes on that side, so the copies of
+// the database diverge until Heal is called. Nodes not in either group are
+// partitioned on their own.
+func (c *Cluster) SplitBrain(a, b []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, n := range c.nodes {
+		n.master = false
+		n.group = -1 - n.index
+	}
+	for g, indexes := range [][]int{a, b} {
+		for i, idx := range indexes {
+			c.nodes[idx].group = g + 1
+			if i == 0 {
+				c.nodes[idx].master = true
+			}
+		}
+	}
+}
+
+// SlowFollower delays every response from the node by latency and stops
+// replication to the node until CatchUp or Heal is called.
+func (c *Cluster) SlowFollower(i int, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[i].latency = latency
+	c.nodes[i].lagging = true
+}
+
+// CatchUp replicates the writes the node has missed from the master it can
+// reach, leaving any latency in place.
+func (c *Cluster) CatchUp(i int) {
+	c.mu.Lock()
+	n := c.nodes[i]
+	n.lagging = false
+	var master *Node
+	for _, m := range c.nodes {
+		if m.master && c.reachable(m, n) {
+			master = m
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if master != nil && master != n {
+		n.sim.restore(master.sim)
+	}
+}
+
+// Heal removes all partitions, isolation and latency and makes the node at
+// index master the only master.
+//
+// Every other node is then replicated from the master. Any writes accepted by
+// another node while it believed it was the master are discarded, as they are
+// when a deposed master rejoins a real cluster.
+func (c *Cluster) Heal(master int) {
+	c.mu.Lock()
+	for _, n := range c.nodes {
+		n.master = n.index == master
+		n.isolated = false
+		n.group = 0
+		n.lagging = false
+		n.latency = 0
+	}
+	c.mu.Unlock()
+
+	for _, n := range c.nodes {
+		if n.index != master {
+			n.sim.restore(c.nodes[master].sim)
+		}
+	}
+}
+
+// reachable returns true if the nodes can communicate. The caller must hold
+// the lock.
+func (c *Cluster) reachable(a, b *Node) bool {
+	return !a.isolated && !b.isolated && a.group == b.group
+}
+
+// replicate copies the database of the master to the followers it can reach.
+func (c *Cluster) replicate(master *Node) {
+	c.mu.Lock()
+	followers := []*Node{}
+	for _, n := range c.nodes {
+		if n != master && !n.lagging && c.reachable(master, n) {
+			followers = append(followers, n)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, n := range followers {
+		n.sim.restore(master.sim)
+	}
+}
+
+// URL returns the base url of the node.
+func (n *Node) URL() string {
+	return n.server.URL
+}
+
+// Simulator returns the copy of the database held by the node.
+func (n *Node) Simulator() *Simulator {
+	return n.sim
+}
+
+// IsMaster returns true if the node believes it is the master.
+func (n *Node) IsMaster() bool {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+	return n.master
+}
+
+// ServeHTTP serves requests to the node.
+//
+// A node that is isolated closes the connection without responding. A node
+// that is not the master redirects writes that require the master to the
+// master it can reach with 307 Temporary Redirect, and responds to other
+// writes with 503 Service Unavailable.
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.cluster.mu.Lock()
+	isolated, master, latency := n.isolated, n.master, n.latency
+	n.cluster.mu.Unlock()
+
+	if isolated {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		http.Error(w, "Node isolated", http.StatusServiceUnavailable)
+		return
+	}
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	write := r.Method == http.MethodPost || r.Method == http.MethodDelete
+	if write && !master {
+		if m := n.reachableMaster(); m != nil && strings.EqualFold(r.Header.Get("ES-RequireMaster"), "true") {
+			http.Redirect(w, r, m.URL()+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+			return
+		}
+		http.Error(w, "Not master", http.StatusServiceUnavailable)
+		return
+	}
+	if !write {
+		n.sim.ServeHTTP(w, r)
+		return
+	}
+
+	// The response is held until the write has been replicated so that a
+	// client that has received it can read the write from any follower in
+	// the same partition.
+	rec := httptest.NewRecorder()
+	n.sim.ServeHTTP(rec, r)
+	if rec.Code < 300 {
+		n.cluster.replicate(n)
+	}
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+// reachableMaster returns the master the node can reach, or nil if there is
+// none.
+func (n *Node) reachableMaster() *Node {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+	for _, m := range n.cluster.nodes {
+		if m.master && n.cluster.reachable(m, n) {
+			return m
+		}
+	}
+	return nil
+}
+
+// restore replaces the database of the simulator with a copy of the database
+// of from and wakes any long polling requests.
+func (s *Simulator) restore(from *Simulator) {
+	from.mu.Lock()
+	streams := make(map[string]*stream, len(from.streams))
+	for name, st := range from.streams {
+		cp := *st
+		cp.events = append([]*record(nil), st.events...)
+		streams[name] = &cp
+	}
+	from.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams = streams
+	s.notify()
+}
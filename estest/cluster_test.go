@@ -0,0 +1,127 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ClusterSuite{})
+
+type ClusterSuite struct {
+	cluster *Cluster
+	clients []*goes.Client
+}
+
+func (s *ClusterSuite) SetUpTest(c *C) {
+	s.cluster = NewCluster(3)
+	s.clients = nil
+	for _, n := range s.cluster.Nodes() {
+		client, err := goes.NewClient(nil, n.URL())
+		c.Assert(err, IsNil)
+		s.clients = append(s.clients, client)
+	}
+}
+
+func (s *ClusterSuite) TearDownTest(c *C) {
+	s.cluster.Close()
+}
+
+// count returns the number of events in the stream read through the client.
+func count(c *C, client *goes.Client, stream string) int {
+	got, err := readAll(client, stream)
+	c.Assert(err, FitsTypeOf, &goes.ErrNoMoreEvents{})
+	return len(got)
+}
+
+func (s *ClusterSuite) TestWritesAreReplicatedToFollowers(c *C) {
+	c.Assert(s.clients[0].NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(3)...), IsNil)
+
+	for i := range s.clients {
+		c.Assert(count(c, s.clients[i], "orders"), Equals, 3)
+	}
+
+	err := s.clients[1].NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(1)...)
+	c.Assert(err, FitsTypeOf, &goes.ErrTemporarilyUnavailable{})
+}
+
+func (s *ClusterSuite) TestWritesRequiringMasterAreRedirected(c *C) {
+	client := s.clients[2]
+	client.SetRequireMaster(true)
+	client.SetPreferMaster(true)
+
+	c.Assert(client.NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(2)...), IsNil)
+	c.Assert(client.Master(), Equals, s.cluster.Node(0).URL())
+	c.Assert(count(c, client, "orders"), Equals, 2)
+
+	// Once the master is isolated writes are redirected to the new master by
+	// the node of the client.
+	elected := s.cluster.IsolateMaster()
+	c.Assert(client.NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(1)...), NotNil)
+	c.Assert(client.Master(), Equals, "")
+	c.Assert(client.NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(1)...), IsNil)
+	c.Assert(client.Master(), Equals, elected.URL())
+	c.Assert(elected.Simulator().Events("orders"), HasLen, 3)
+}
+
+func (s *ClusterSuite) TestIsolateMaster(c *C) {
+	c.Assert(s.clients[0].NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(2)...), IsNil)
+
+	elected := s.cluster.IsolateMaster()
+	c.Assert(elected, Equals, s.cluster.Node(1))
+	c.Assert(s.cluster.Node(0).IsMaster(), Equals, true)
+
+	_, err := readAll(s.clients[0], "orders")
+	c.Assert(err, NotNil)
+	_, isServerError := err.(*goes.ErrTemporarilyUnavailable)
+	c.Assert(isServerError, Equals, false)
+
+	c.Assert(s.clients[1].NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(1)...), IsNil)
+	c.Assert(count(c, s.clients[2], "orders"), Equals, 3)
+	c.Assert(s.cluster.Node(0).Simulator().Events("orders"), HasLen, 2)
+
+	s.cluster.Heal(1)
+	c.Assert(count(c, s.clients[0], "orders"), Equals, 3)
+	c.Assert(s.cluster.Node(0).IsMaster(), Equals, false)
+}
+
+func (s *ClusterSuite) TestSplitBrainDivergesUntilHealed(c *C) {
+	c.Assert(s.clients[0].NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(1)...), IsNil)
+
+	s.cluster.SplitBrain([]int{0}, []int{1, 2})
+	c.Assert(s.cluster.Node(0).IsMaster(), Equals, true)
+	c.Assert(s.cluster.Node(1).IsMaster(), Equals, true)
+
+	// Both sides accept a write at the same expected version.
+	expected := 0
+	c.Assert(s.clients[0].NewStreamWriter("orders").Append(goes.ExpectedVersion(expected), fooEvents(1)...), IsNil)
+	c.Assert(s.clients[1].NewStreamWriter("orders").Append(goes.ExpectedVersion(expected), fooEvents(2)...), IsNil)
+
+	c.Assert(count(c, s.clients[0], "orders"), Equals, 2)
+	c.Assert(count(c, s.clients[2], "orders"), Equals, 3)
+
+	s.cluster.Heal(1)
+	for i := range s.clients {
+		c.Assert(count(c, s.clients[i], "orders"), Equals, 3)
+	}
+}
+
+func (s *ClusterSuite) TestSlowFollowerServesStaleReads(c *C) {
+	s.cluster.SlowFollower(2, 50*time.Millisecond)
+	c.Assert(s.clients[0].NewStreamWriter("orders").Append(goes.ExpectAny, fooEvents(2)...), IsNil)
+
+	start := time.Now()
+	_, err := readAll(s.clients[2], "orders")
+	c.Assert(err, FitsTypeOf, &goes.ErrNotFound{})
+	c.Assert(time.Since(start) >= 50*time.Millisecond, Equals, true)
+
+	s.cluster.CatchUp(2)
+	c.Assert(count(c, s.clients[2], "orders"), Equals, 2)
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+)
+
+// FaultKind is the kind of failure injected by a Fault.
+type FaultKind int
+
+const (
+	// FaultServerError responds with 500 Internal Server Error.
+	FaultServerError FaultKind = iota
+
+	// FaultSlow delays the response by the Delay of the fault and then
+	// responds normally.
+	FaultSlow
+
+	// FaultTruncate responds normally but closes the connection after half of
+	// the body has been written, so the client reads an incomplete body.
+	FaultTruncate
+
+	// FaultDrop closes the connection without responding.
+	FaultDrop
+)
+
+// Fault describes a failure injected into the responses of a Simulator.
+//
+// A fault applies to the requests for which Match returns true, or to every
+// request if Match is nil. The matching requests are counted and the fault is
+// injected into those after the first After, into every Every-th of them if
+// Every is greater than 0, and then with the given Probability if it is
+// greater than 0. Once the fault has been injected Times times it no longer
+// applies, unless Times is 0.
+//
+// For example, Fault{Kind: FaultServerError, Times: 2} fails the next two
+// requests and Fault{Kind: FaultDrop, Probability: 0.1} drops one in ten
+// requests on average.
+type Fault struct {
+	Kind        FaultKind
+	Match       func(*http.Request) bool
+	After       int
+	Every       int
+	Probability float64
+	Times       int
+	Delay       time.Duration
+}
+
+// activeFault is a fault injected into a simulator with its counters.
+type activeFault struct {
+	Fault
+	seen     int
+	injected int
+}
+
+// fires counts the request and returns true if the fault is injected into its
+// response. The caller must hold the lock of the simulator.
+func (f *activeFault) fires(r *http.Request, rnd *rand.Rand) bool {
+	if f.Match != nil && !f.Match(r) {
+		return false
+	}
+	f.seen++
+	n := f.seen - f.After
+	switch {
+	case n <= 0:
+		return false
+	case f.Times > 0 && f.injected >= f.Times:
+		return false
+	case f.Every > 0 && n%f.Every != 0:
+		return false
+	case f.Probability > 0 && rnd.Float64() >= f.Probability:
+		return false
+	}
+	f.injected++
+	return true
+}
+
+// InjectFault adds a fault to the responses of the simulator. When several
+// faults apply to a request the first one added is injected.
+func (s *Simulator) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &activeFault{Fault: f})
+}
+
+// ClearFaults removes the faults injected into the simulator.
+func (s *Simulator) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetFaultSeed seeds the random numbers used to inject faults with a
+// Probability, so that a failing test can be reproduced. The default seed is 1.
+func (s *Simulator) SetFaultSeed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rnd = rand.New(rand.NewSource(seed))
+}
+
+// InjectedFaults returns the number of faults injected into responses.
+func (s *Simulator) InjectedFaults() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, f := range s.faults {
+		n += f.injected
+	}
+	return n
+}
+
+// fault returns the fault to inject into the response to the request, or nil.
+func (s *Simulator) fault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.faults {
+		if f.fires(r, s.rnd) {
+			return &f.Fault
+		}
+	}
+	return nil
+}
+
+// serveFault serves the request with the fault injected and returns false if
+// the request must then be served normally.
+func (s *Simulator) serveFault(w http.ResponseWriter, r *http.Request, f *Fault) bool {
+	switch f.Kind {
+	case FaultServerError:
+		http.Error(w, "Injected fault", http.StatusInternalServerError)
+	case FaultSlow:
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return true
+		}
+		return false
+	case FaultTruncate:
+		rec := httptest.NewRecorder()
+		s.serve(rec, r)
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		// The declared length is longer than the body written, so the server
+		// closes the connection once the handler returns.
+		body := rec.Body.Bytes()
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(rec.Code)
+		w.Write(body[:len(body)/2])
+	case FaultDrop:
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+		http.Error(w, "Connection dropped", http.StatusServiceUnavailable)
+	}
+	return true
+}
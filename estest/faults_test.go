@@ -0,0 +1,107 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&FaultSuite{})
+
+type FaultSuite struct {
+	sim    *Simulator
+	server *httptest.Server
+}
+
+func (s *FaultSuite) SetUpTest(c *C) {
+	s.sim = NewSimulator()
+	s.server = httptest.NewServer(s.sim)
+	c.Assert(s.sim.Append("faulty", fooEvents(3)...), IsNil)
+}
+
+func (s *FaultSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+// statuses requests the first event of the stream n times and returns the
+// status of each response, or 0 if the request failed.
+func (s *FaultSuite) statuses(n int) []int {
+	ret := make([]int, n)
+	for i := range ret {
+		resp, err := http.Get(s.server.URL + "/streams/faulty/0")
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		ret[i] = resp.StatusCode
+	}
+	return ret
+}
+
+func (s *FaultSuite) TestServerErrorsOnSchedule(c *C) {
+	s.sim.InjectFault(Fault{Kind: FaultServerError, After: 1, Every: 2, Times: 2})
+	c.Assert(s.statuses(7), DeepEquals, []int{200, 200, 500, 200, 500, 200, 200})
+	c.Assert(s.sim.InjectedFaults(), Equals, 2)
+
+	s.sim.ClearFaults()
+	s.sim.InjectFault(Fault{Kind: FaultServerError, Times: 1})
+	c.Assert(s.statuses(2), DeepEquals, []int{500, 200})
+}
+
+func (s *FaultSuite) TestFaultsOnlyApplyToMatchingRequests(c *C) {
+	s.sim.InjectFault(Fault{
+		Kind:  FaultServerError,
+		Match: func(r *http.Request) bool { return strings.HasSuffix(r.URL.Path, "/1") },
+	})
+	c.Assert(s.statuses(2), DeepEquals, []int{200, 200})
+	resp, err := http.Get(s.server.URL + "/streams/faulty/1")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+}
+
+func (s *FaultSuite) TestProbabilityIsSeeded(c *C) {
+	s.sim.InjectFault(Fault{Kind: FaultServerError, Probability: 0.5})
+	s.sim.SetFaultSeed(42)
+	first := s.statuses(20)
+	s.sim.SetFaultSeed(42)
+	c.Assert(s.statuses(20), DeepEquals, first)
+
+	failed := 0
+	for _, code := range first {
+		if code == http.StatusInternalServerError {
+			failed++
+		}
+	}
+	c.Assert(failed > 0 && failed < 20, Equals, true, Commentf("%d", failed))
+}
+
+func (s *FaultSuite) TestSlowResponses(c *C) {
+	s.sim.InjectFault(Fault{Kind: FaultSlow, Delay: 50 * time.Millisecond})
+	start := time.Now()
+	c.Assert(s.statuses(1), DeepEquals, []int{200})
+	c.Assert(time.Since(start) >= 50*time.Millisecond, Equals, true)
+}
+
+func (s *FaultSuite) TestTruncatedBody(c *C) {
+	s.sim.InjectFault(Fault{Kind: FaultTruncate, Times: 1})
+	resp, err := http.Get(s.server.URL + "/streams/faulty/0")
+	c.Assert(err, IsNil)
+	_, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, NotNil)
+}
+
+func (s *FaultSuite) TestDroppedConnection(c *C) {
+	s.sim.InjectFault(Fault{Kind: FaultDrop, Times: 1})
+	c.Assert(s.statuses(2), DeepEquals, []int{0, 200})
+}
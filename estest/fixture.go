@@ -0,0 +1,214 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fixtureExt is the extension of the stream files in a DirFixture.
+const fixtureExt = ".json"
+
+// FixtureEvent is an event stored in a fixture.
+type FixtureEvent struct {
+	EventID   string          `json:"eventId"`
+	EventType string          `json:"eventType"`
+	Data      json.RawMessage `json:"data"`
+	MetaData  json.RawMessage `json:"metadata,omitempty"`
+	Created   time.Time       `json:"created"`
+}
+
+// FixtureStream is a stream stored in a fixture. The events are in stream
+// order, so the event number of each event is its index.
+type FixtureStream struct {
+	Name        string          `json:"name"`
+	SoftDeleted bool            `json:"softDeleted,omitempty"`
+	HardDeleted bool            `json:"hardDeleted,omitempty"`
+	Events      []*FixtureEvent `json:"events"`
+}
+
+// Fixture loads and saves the state of a Simulator, so that test scenarios can
+// be kept as data rather than built in code by each test.
+//
+// DirFixture stores the state in a directory. Other implementations can store
+// it elsewhere, for example embedded in the test binary.
+type Fixture interface {
+	Load() ([]*FixtureStream, error)
+	Save(streams []*FixtureStream) error
+}
+
+// Load replaces the state of the simulator with the state loaded from the
+// fixture.
+//
+// Loading is much faster than appending the events of a large scenario over
+// HTTP, and the events keep the timestamps recorded in the fixture.
+func (s *Simulator) Load(f Fixture) error {
+	streams, err := f.Load()
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]*stream, len(streams))
+	for _, fs := range streams {
+		if fs.Name == "" {
+			return fmt.Errorf("Fixture stream has no name")
+		}
+		st := &stream{
+			name:        fs.Name,
+			softDeleted: fs.SoftDeleted,
+			hardDeleted: fs.HardDeleted,
+			events:      make([]*record, len(fs.Events)),
+		}
+		for i, e := range fs.Events {
+			if e.EventID == "" || e.EventType == "" {
+				return fmt.Errorf("Event %d in fixture stream %s must have an eventId and an eventType", i, fs.Name)
+			}
+			data, err := compact(e.Data)
+			if err != nil {
+				return err
+			}
+			meta, err := compact(e.MetaData)
+			if err != nil {
+				return err
+			}
+			st.events[i] = &record{
+				Number:   i,
+				ID:       e.EventID,
+				Type:     e.EventType,
+				Data:     data,
+				MetaData: meta,
+				Created:  e.Created,
+			}
+		}
+		loaded[fs.Name] = st
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams = loaded
+	s.notify()
+	return nil
+}
+
+// compact removes the indentation added to JSON when a fixture is saved.
+func compact(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Save saves the state of the simulator to the fixture. Streams are saved in
+// order of name.
+func (s *Simulator) Save(f Fixture) error {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.streams))
+	for name := range s.streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	streams := make([]*FixtureStream, len(names))
+	for i, name := range names {
+		st := s.streams[name]
+		fs := &FixtureStream{
+			Name:        name,
+			SoftDeleted: st.softDeleted,
+			HardDeleted: st.hardDeleted,
+			Events:      make([]*FixtureEvent, len(st.events)),
+		}
+		for j, r := range st.events {
+			fs.Events[j] = &FixtureEvent{
+				EventID:   r.ID,
+				EventType: r.Type,
+				Data:      r.Data,
+				MetaData:  r.MetaData,
+				Created:   r.Created,
+			}
+		}
+		streams[i] = fs
+	}
+	s.mu.Unlock()
+
+	return f.Save(streams)
+}
+
+// DirFixture is a Fixture stored in a directory with one JSON file for each
+// stream, so that changes to a scenario can be reviewed in version control.
+// The file names are the escaped stream names.
+type DirFixture string
+
+// Load reads the stream files in the directory.
+func (d DirFixture) Load() ([]*FixtureStream, error) {
+	files, err := ioutil.ReadDir(string(d))
+	if err != nil {
+		return nil, err
+	}
+	streams := []*FixtureStream{}
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), fixtureExt) {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(string(d), fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		fs := &FixtureStream{}
+		if err := json.Unmarshal(b, fs); err != nil {
+			return nil, fmt.Errorf("Fixture file %s is invalid: %v", fi.Name(), err)
+		}
+		streams = append(streams, fs)
+	}
+	return streams, nil
+}
+
+// Save writes a file for each stream, creating the directory if required.
+// Stream files in the directory for streams that are not saved are removed.
+func (d DirFixture) Save(streams []*FixtureStream) error {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool, len(streams))
+	for _, fs := range streams {
+		b, err := json.MarshalIndent(fs, "", "  ")
+		if err != nil {
+			return err
+		}
+		name := url.PathEscape(fs.Name) + fixtureExt
+		keep[name] = true
+		if err := ioutil.WriteFile(filepath.Join(string(d), name), append(b, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+
+	files, err := ioutil.ReadDir(string(d))
+	if err != nil {
+		return err
+	}
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), fixtureExt) || keep[fi.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(string(d), fi.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
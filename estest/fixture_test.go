@@ -0,0 +1,118 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&FixtureSuite{})
+
+type FixtureSuite struct {
+	dir string
+}
+
+func (s *FixtureSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+// serve returns a client for the simulator and a function that stops the
+// server.
+func serve(c *C, sim *Simulator) (*goes.Client, func()) {
+	server := httptest.NewServer(sim)
+	client, err := goes.NewClient(nil, server.URL)
+	c.Assert(err, IsNil)
+	return client, server.Close
+}
+
+func (s *FixtureSuite) TestSaveAndLoadDirFixture(c *C) {
+	created := time.Date(2016, 6, 1, 12, 0, 0, 0, time.UTC)
+	sim := NewSimulator()
+	sim.SetClock(func() time.Time { return created })
+	c.Assert(sim.Append("orders", fooEvents(3)...), IsNil)
+	c.Assert(sim.Append("$ce-order", fooEvents(1)...), IsNil)
+	c.Assert(sim.SetMetaData("orders", map[string]interface{}{"$maxCount": 2}), IsNil)
+	c.Assert(sim.Save(DirFixture(s.dir)), IsNil)
+
+	files, err := ioutil.ReadDir(s.dir)
+	c.Assert(err, IsNil)
+	names := []string{}
+	for _, fi := range files {
+		names = append(names, fi.Name())
+	}
+	c.Assert(names, DeepEquals, []string{"$$orders.json", "$ce-order.json", "orders.json"})
+
+	loaded := NewSimulator()
+	c.Assert(loaded.Load(DirFixture(s.dir)), IsNil)
+	c.Assert(loaded.Events("orders"), DeepEquals, sim.Events("orders"))
+	c.Assert(loaded.Events("$ce-order"), HasLen, 1)
+
+	client, stop := serve(c, loaded)
+	defer stop()
+
+	// The $maxCount loaded with the metadata stream still applies.
+	got, err := readAll(client, "orders")
+	c.Assert(err, FitsTypeOf, &goes.ErrNoMoreEvents{})
+	c.Assert(got, HasLen, 2)
+	c.Assert(string(got[0].Updated), Equals, string(goes.Time(created)))
+
+	// Appends continue from the loaded version.
+	c.Assert(client.NewStreamWriter("orders").Append(goes.ExpectedVersion(2), fooEvents(1)...), IsNil)
+}
+
+func (s *FixtureSuite) TestSaveRemovesStreamsNoLongerPresent(c *C) {
+	sim := NewSimulator()
+	c.Assert(sim.Append("a", fooEvents(1)...), IsNil)
+	c.Assert(sim.Append("b", fooEvents(1)...), IsNil)
+	c.Assert(sim.Save(DirFixture(s.dir)), IsNil)
+
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "README"), []byte("scenario"), 0644), IsNil)
+
+	sim = NewSimulator()
+	c.Assert(sim.Append("a", fooEvents(1)...), IsNil)
+	c.Assert(sim.Save(DirFixture(s.dir)), IsNil)
+
+	_, err := os.Stat(filepath.Join(s.dir, "b.json"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(filepath.Join(s.dir, "README"))
+	c.Assert(err, IsNil)
+}
+
+func (s *FixtureSuite) TestLoadPreservesDeletedStreams(c *C) {
+	sim := NewSimulator()
+	c.Assert(sim.Append("gone", fooEvents(1)...), IsNil)
+	client, stop := serve(c, sim)
+	_, err := client.DeleteStream("gone", true)
+	c.Assert(err, IsNil)
+	stop()
+	c.Assert(sim.Save(DirFixture(s.dir)), IsNil)
+
+	loaded := NewSimulator()
+	c.Assert(loaded.Load(DirFixture(s.dir)), IsNil)
+	client, stop = serve(c, loaded)
+	defer stop()
+
+	err = client.NewStreamWriter("gone").Append(goes.ExpectAny, fooEvents(1)...)
+	c.Assert(err, NotNil)
+}
+
+func (s *FixtureSuite) TestLoadInvalidFixture(c *C) {
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "bad.json"), []byte("{"), 0644), IsNil)
+	err := NewSimulator().Load(DirFixture(s.dir))
+	c.Assert(err, ErrorMatches, "Fixture file bad.json is invalid: .*")
+
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "bad.json"), []byte(`{"name":"x","events":[{"eventType":"A"}]}`), 0644), IsNil)
+	err = NewSimulator().Load(DirFixture(s.dir))
+	c.Assert(err, ErrorMatches, "Event 0 in fixture stream x must have an eventId and an eventType")
+}
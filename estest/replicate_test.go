@@ -0,0 +1,156 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ReplicateSuite{})
+
+// ReplicateSuite replicates streams between two simulators.
+type ReplicateSuite struct {
+	src, dst             *Simulator
+	srcServer, dstServer *httptest.Server
+	srcClient, dstClient *goes.Client
+	mux                  *http.ServeMux
+}
+
+func (s *ReplicateSuite) SetUpTest(c *C) {
+	s.src, s.dst = NewSimulator(), NewSimulator()
+	s.mux = http.NewServeMux()
+	s.mux.Handle("/", s.src)
+	s.srcServer = httptest.NewServer(s.mux)
+	s.dstServer = httptest.NewServer(s.dst)
+
+	var err error
+	s.srcClient, err = goes.NewClient(nil, s.srcServer.URL)
+	c.Assert(err, IsNil)
+	s.dstClient, err = goes.NewClient(nil, s.dstServer.URL)
+	c.Assert(err, IsNil)
+}
+
+func (s *ReplicateSuite) TearDownTest(c *C) {
+	s.srcServer.Close()
+	s.dstServer.Close()
+}
+
+// assertReplicated checks that the destination stream holds the events of the
+// source stream.
+func (s *ReplicateSuite) assertReplicated(c *C, stream string) {
+	want, got := s.src.Events(stream), s.dst.Events(stream)
+	c.Assert(got, HasLen, len(want))
+	for i := range want {
+		c.Assert(got[i].EventID, Equals, want[i].EventID)
+		c.Assert(got[i].EventType, Equals, want[i].EventType)
+		c.Assert(got[i].Data, DeepEquals, want[i].Data)
+		c.Assert(got[i].MetaData, DeepEquals, want[i].MetaData)
+	}
+}
+
+func (s *ReplicateSuite) TestReplicateStream(c *C) {
+	ctx := context.Background()
+	c.Assert(s.src.Append("orders-1", fooEvents(45)...), IsNil)
+
+	n, err := goes.ReplicateStream(ctx, s.srcClient, s.dstClient, "orders-1", nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 45)
+	s.assertReplicated(c, "orders-1")
+
+	// The events already copied are found without a checkpoint store.
+	n, err = goes.ReplicateStream(ctx, s.srcClient, s.dstClient, "orders-1", nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 0)
+
+	c.Assert(s.src.Append("orders-1", fooEvents(5)...), IsNil)
+	n, err = goes.ReplicateStream(ctx, s.srcClient, s.dstClient, "orders-1", nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 5)
+	s.assertReplicated(c, "orders-1")
+}
+
+func (s *ReplicateSuite) TestReplicateResumesFromCheckpoint(c *C) {
+	ctx := context.Background()
+	c.Assert(s.src.Append("orders-1", fooEvents(30)...), IsNil)
+
+	// An earlier run wrote 25 events but stored its checkpoint after 20.
+	c.Assert(s.dst.Append("orders-1", s.src.Events("orders-1")[:25]...), IsNil)
+	checkpoints := goes.NewMemoryCheckpointStore()
+	name := goes.ReplicationCheckpointName("orders-1")
+	c.Assert(checkpoints.Store(name, 19), IsNil)
+
+	opts := &goes.ReplicateOptions{BatchSize: 5, Checkpoints: checkpoints}
+	n, err := goes.ReplicateStream(ctx, s.srcClient, s.dstClient, "orders-1", opts)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 5)
+	s.assertReplicated(c, "orders-1")
+	cp, err := checkpoints.Load(name)
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 29)
+}
+
+func (s *ReplicateSuite) TestReplicateRejectsForeignDestination(c *C) {
+	c.Assert(s.src.Append("orders-1", fooEvents(3)...), IsNil)
+	c.Assert(s.dst.Append("orders-1", fooEvents(1)...), IsNil)
+
+	n, err := goes.ReplicateStream(context.Background(), s.srcClient, s.dstClient, "orders-1", nil)
+	c.Assert(err, ErrorMatches, "Stream orders-1 in the destination has events that are not in the source")
+	c.Assert(n, Equals, 0)
+}
+
+func (s *ReplicateSuite) TestReplicateIsThrottled(c *C) {
+	c.Assert(s.src.Append("orders-1", fooEvents(30)...), IsNil)
+
+	start := time.Now()
+	opts := &goes.ReplicateOptions{BatchSize: 10, EventsPerSecond: 200}
+	n, err := goes.ReplicateStream(context.Background(), s.srcClient, s.dstClient, "orders-1", opts)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 30)
+	c.Assert(time.Since(start) >= 90*time.Millisecond, Equals, true)
+}
+
+func (s *ReplicateSuite) TestReplicateAll(c *C) {
+	streams := []string{"orders-1", "$stats-127.0.0.1:2113", "users-1", "orders-2"}
+	for _, st := range streams {
+		c.Assert(s.src.Append(st, fooEvents(3)...), IsNil)
+	}
+
+	// The $streams stream links to the first event of each stream, most
+	// recent first.
+	s.mux.HandleFunc("/streams/$streams/0/forward/20", func(w http.ResponseWriter, r *http.Request) {
+		f := &atom.Feed{}
+		for i := len(streams) - 1; i >= 0; i-- {
+			u := fmt.Sprintf("%s/streams/%s/0", s.srcServer.URL, streams[i])
+			f.Entry = append(f.Entry, &atom.Entry{
+				Title: "0@" + streams[i],
+				Link:  []atom.Link{{Rel: "edit", Href: u}, {Rel: "alternate", Href: u}},
+			})
+		}
+		fmt.Fprint(w, f.PrettyPrint())
+	})
+
+	n, err := goes.ReplicateAll(context.Background(), s.srcClient, s.dstClient, &goes.ReplicateOptions{Category: "orders"})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 6)
+	s.assertReplicated(c, "orders-1")
+	s.assertReplicated(c, "orders-2")
+	c.Assert(s.dst.Events("users-1"), IsNil)
+
+	n, err = goes.ReplicateAll(context.Background(), s.srcClient, s.dstClient, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 3)
+	s.assertReplicated(c, "users-1")
+	c.Assert(s.dst.Events("$stats-127.0.0.1:2113"), IsNil)
+}
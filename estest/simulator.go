@@ -0,0 +1,851 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+// Package estest provides an in-memory simulation of the eventstore HTTP API
+// for use in tests.
+//
+// The Simulator is an http.Handler and is intended to be served with the
+// net/http/httptest package. A goes.Client pointed at the test server can read
+// and write events, read and write stream metadata and delete streams as it
+// would against a real eventstore.
+//
+//	sim := estest.NewSimulator()
+//	server := httptest.NewServer(sim)
+//	defer server.Close()
+//
+//	client, _ := goes.NewClient(nil, server.URL)
+//
+// The simulator emulates feed paging links, expected version checks, soft and
+// hard deletes, truncation using the $tb and $maxCount metadata, ES-LongPoll and
+// the caching headers of feed pages. Events can be appended with AppendEvery
+// while a test is reading, to test live subscriptions, and streams of links to
+// events in other streams, such as $ce-order, are built with LinkTo.
+// The state of a simulator can be saved to and loaded from a Fixture, such as a
+// DirFixture, so that large scenarios can be kept as data.
+//
+// A Cluster runs several simulated nodes with a master and followers, and can
+// partition them to test the behaviour of clients when the network fails.
+// Failures of a single server, such as errors, slow responses, truncated
+// bodies and dropped connections, are injected with Simulator.InjectFault.
+package estest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+)
+
+// defaultPageSize is the page size used for feed requests that do not specify
+// a page size.
+const defaultPageSize = 20
+
+// linkEventType is the type of the events that link to events in other
+// streams, such as those in $ce-order.
+const linkEventType = "$>"
+
+// record is a stored event.
+//
+// stream is set on the records of a feed that are resolved from links, and is
+// the stream of the event linked to.
+type record struct {
+	Number   int
+	ID       string
+	Type     string
+	Data     json.RawMessage
+	MetaData json.RawMessage
+	Created  time.Time
+	stream   string
+}
+
+// link returns the stream and event number of the event a link event links
+// to, which are its data in the form number@stream.
+func (r *record) link() (string, int, bool) {
+	if r.Type != linkEventType {
+		return "", 0, false
+	}
+	ref := string(r.Data)
+	var s string
+	if err := json.Unmarshal(r.Data, &s); err == nil {
+		ref = s
+	}
+	i := strings.Index(ref, "@")
+	if i < 0 {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(ref[:i])
+	if err != nil {
+		return "", 0, false
+	}
+	return ref[i+1:], n, true
+}
+
+// stream holds the state of a simulated stream.
+type stream struct {
+	name        string
+	events      []*record
+	softDeleted bool
+	hardDeleted bool
+}
+
+// version returns the event number of the last event in the stream or -1 if
+// the stream has no events.
+func (s *stream) version() int {
+	return len(s.events) - 1
+}
+
+// Simulator is an in-memory eventstore that serves the eventstore HTTP API.
+//
+// A Simulator is safe for concurrent use.
+type Simulator struct {
+	mu      sync.Mutex
+	streams map[string]*stream
+	changed chan struct{}
+	now     func() time.Time
+	faults  []*activeFault
+	rnd     *rand.Rand
+}
+
+// NewSimulator returns a new, empty Simulator.
+func NewSimulator() *Simulator {
+	return &Simulator{
+		streams: make(map[string]*stream),
+		changed: make(chan struct{}),
+		now:     time.Now,
+		rnd:     rand.New(rand.NewSource(1)),
+	}
+}
+
+// SetClock sets the function used to timestamp events appended to the simulator.
+// By default events are timestamped with time.Now.
+func (s *Simulator) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// Append appends events to a stream directly, bypassing HTTP.
+//
+// Append is intended for seeding a stream with events before a test. The
+// events are appended regardless of the current version of the stream.
+func (s *Simulator) Append(streamName string, events ...*goes.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	in := make([]*inboundEvent, len(events))
+	for i, e := range events {
+		ie, err := newInboundEvent(e)
+		if err != nil {
+			return err
+		}
+		in[i] = ie
+	}
+
+	_, err := s.append(streamName, -2, in)
+	return err
+}
+
+// AppendEvery appends the events to a stream one at a time, one every
+// interval, while readers and subscriptions are polling the stream. It
+// returns a function that stops appending and waits until the appends have
+// stopped.
+//
+// AppendEvery is intended for testing the transition of subscriptions from
+// catching up to following the head of a stream. Appending stops after the
+// last event or when an append fails, such as when the stream is deleted.
+func (s *Simulator) AppendEvery(streamName string, interval time.Duration, events ...*goes.Event) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for _, e := range events {
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return
+			}
+			if err := s.Append(streamName, e); err != nil {
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(quit) })
+		<-done
+	}
+}
+
+// LinkTo appends links to events of the target stream to a stream directly,
+// bypassing HTTP, as the system projections of the eventstore do for streams
+// such as $ce-order.
+//
+// The feed of the stream lists the events linked to, so the events read from
+// it have the stream and event number of the target stream while their
+// position in the feed is that of the link.
+func (s *Simulator) LinkTo(streamName, target string, eventNumbers ...int) error {
+	events := make([]*goes.Event, len(eventNumbers))
+	for i, n := range eventNumbers {
+		events[i] = goes.NewEvent("", linkEventType, fmt.Sprintf("%d@%s", n, target), nil)
+	}
+	return s.Append(streamName, events...)
+}
+
+// SetMetaData writes the stream metadata for a stream directly, bypassing HTTP.
+func (s *Simulator) SetMetaData(streamName string, metadata interface{}) error {
+	e := goes.NewEvent("", "$metadata", metadata, nil)
+	return s.Append(metaStreamName(streamName), e)
+}
+
+// Events returns the events stored in a stream in stream order.
+//
+// The events returned include any events hidden by truncation. The data and
+// metadata of the events are *json.RawMessage.
+func (s *Simulator) Events(streamName string) []*goes.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.streams[streamName]
+	if !ok {
+		return nil
+	}
+
+	ret := make([]*goes.Event, len(st.events))
+	for i, r := range st.events {
+		ret[i] = r.event(streamName, "")
+	}
+	return ret
+}
+
+// ServeHTTP serves requests to the eventstore HTTP API. Faults added with
+// InjectFault are injected into the responses.
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f := s.fault(r); f != nil && s.serveFault(w, r, f) {
+		return
+	}
+	s.serve(w, r)
+}
+
+// serve serves a request to the eventstore HTTP API.
+func (s *Simulator) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/streams/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	seg := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/streams/"), "/"), "/")
+	name := seg[0]
+	host := baseURL(r)
+
+	switch {
+	case len(seg) == 1 && r.Method == http.MethodGet:
+		s.serveFeed(w, r, host, name, "head", "backward", defaultPageSize)
+	case len(seg) == 1 && r.Method == http.MethodPost:
+		s.serveAppend(w, r, host, name)
+	case len(seg) == 1 && r.Method == http.MethodDelete:
+		s.serveDelete(w, r, name)
+	case len(seg) == 2 && seg[1] == "metadata" && r.Method == http.MethodGet:
+		s.serveMetaData(w, r, host, name)
+	case len(seg) == 2 && seg[1] == "metadata" && r.Method == http.MethodPost:
+		s.serveAppend(w, r, host, metaStreamName(name))
+	case len(seg) == 2 && r.Method == http.MethodGet:
+		n, err := strconv.Atoi(seg[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.serveEvent(w, r, host, name, n, false)
+	case len(seg) == 3 && seg[2] == "data" && r.Method == http.MethodGet:
+		n, err := strconv.Atoi(seg[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.serveEvent(w, r, host, name, n, true)
+	case len(seg) == 4 && r.Method == http.MethodGet:
+		count, err := strconv.Atoi(seg[3])
+		if err != nil || count <= 0 {
+			http.Error(w, "Invalid page size", http.StatusBadRequest)
+			return
+		}
+		s.serveFeed(w, r, host, name, seg[1], seg[2], count)
+	default:
+		http.Error(w, "Not Implemented", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Simulator) serveFeed(w http.ResponseWriter, r *http.Request, host, name, version, direction string, count int) {
+	if direction != "forward" && direction != "backward" {
+		http.Error(w, "Invalid direction", http.StatusBadRequest)
+		return
+	}
+	if version == "head" && direction == "forward" {
+		http.Error(w, "Invalid direction for head", http.StatusBadRequest)
+		return
+	}
+
+	v := -1
+	if version != "head" {
+		n, err := strconv.Atoi(version)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+		v = n
+	}
+
+	var wait time.Duration
+	if lp := r.Header.Get("ES-LongPoll"); lp != "" {
+		secs, err := strconv.Atoi(lp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		wait = time.Duration(secs) * time.Second
+	}
+	deadline := time.After(wait)
+
+	// Feeds are served as JSON when it is the preferred type.
+	accept := strings.TrimSpace(strings.Split(r.Header.Get("Accept"), ",")[0])
+	asJSON := strings.HasPrefix(accept, "application/vnd.eventstore.atom+json")
+	format := "xml"
+	if asJSON {
+		format = "json"
+	}
+
+	for {
+		s.mu.Lock()
+		f, status := s.feed(host, name, v, direction, count)
+		var etag string
+		if status == http.StatusOK {
+			st := s.streams[name]
+			etag = fmt.Sprintf(`"%d;%d;%s"`, st.version(), s.firstVisible(st), format)
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		// A long poll with the ETag of the page waits for the page to change,
+		// as for an empty page.
+		notModified := r.Header.Get("If-None-Match") == etag
+		if (len(f.Entry) > 0 && !notModified) || wait <= 0 {
+			// Pages that are full and are not the head of the stream never
+			// change, and are cached like the eventstore caches them.
+			if len(f.Entry) == count && !f.HeadOfStream {
+				w.Header().Set("Cache-Control", "max-age=31536000, public")
+			} else {
+				w.Header().Set("Cache-Control", "max-age=0, no-cache, must-revalidate")
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Vary", "Accept")
+			if notModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			if asJSON {
+				w.Header().Set("Content-Type", "application/vnd.eventstore.atom+json; charset=utf-8")
+				json.NewEncoder(w).Encode(f)
+				return
+			}
+			w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+			fmt.Fprint(w, f.PrettyPrint())
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			wait = 0
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// feed builds the feed page for a stream. The caller must hold the lock.
+//
+// v is the version the page starts at or -1 for the head of the stream.
+func (s *Simulator) feed(host, name string, v int, direction string, count int) (*atom.Feed, int) {
+	st, ok := s.streams[name]
+	if !ok {
+		return nil, http.StatusNotFound
+	}
+	if st.hardDeleted {
+		return nil, http.StatusGone
+	}
+	if st.softDeleted {
+		return nil, http.StatusNotFound
+	}
+
+	recs := s.resolveLinks(st.events)
+	return buildFeed(host, name, recs, s.firstVisible(st), v, direction, count, s.now()), http.StatusOK
+}
+
+// resolveLinks returns the records with the link events replaced by the
+// events they link to, as they are listed in a feed. Links to events that do
+// not exist are listed as they are. The caller must hold the lock.
+func (s *Simulator) resolveLinks(recs []*record) []*record {
+	var ret []*record
+	for i, r := range recs {
+		target, n, ok := r.link()
+		if !ok {
+			continue
+		}
+		st, ok := s.streams[target]
+		if !ok || n < 0 || n > st.version() {
+			continue
+		}
+		if ret == nil {
+			ret = append([]*record{}, recs...)
+		}
+		resolved := *st.events[n]
+		resolved.stream = target
+		ret[i] = &resolved
+	}
+	if ret == nil {
+		return recs
+	}
+	return ret
+}
+
+// buildFeed builds a feed page over the records provided. The records must be
+// in stream order with the number of each record equal to its index.
+//
+// first is the number of the first record that is visible in the feed. v is the
+// version the page starts at or -1 for the head of the stream.
+func buildFeed(host, name string, recs []*record, first, v int, direction string, count int, now time.Time) *atom.Feed {
+	last := len(recs) - 1
+
+	var lo, hi int
+	switch direction {
+	case "forward":
+		lo = max(v, first)
+		hi = min(lo+count-1, last)
+	default:
+		hi = last
+		if v >= 0 {
+			hi = min(v, last)
+		}
+		lo = max(hi-count+1, first)
+	}
+
+	u := fmt.Sprintf("%s/streams/%s", host, name)
+
+	f := &atom.Feed{}
+	f.Title = fmt.Sprintf("Event stream '%s'", name)
+	f.ID = u
+	f.Updated = atom.Time(now)
+	f.Author = &atom.Person{Name: "EventStore"}
+	f.StreamID = name
+
+	l := []atom.Link{}
+	l = append(l, atom.Link{Href: u, Rel: "self"})
+	l = append(l, atom.Link{Href: fmt.Sprintf("%s/head/backward/%d", u, count), Rel: "first"})
+	if lo > first && lo <= last+1 {
+		l = append(l, atom.Link{Href: fmt.Sprintf("%s/%d/forward/%d", u, first, count), Rel: "last"})
+		l = append(l, atom.Link{Href: fmt.Sprintf("%s/%d/backward/%d", u, lo-1, count), Rel: "next"})
+	}
+	if hi >= lo {
+		l = append(l, atom.Link{Href: fmt.Sprintf("%s/%d/forward/%d", u, hi+1, count), Rel: "previous"})
+	} else {
+		l = append(l, atom.Link{Href: fmt.Sprintf("%s/%d/forward/%d", u, max(v, last+1), count), Rel: "previous"})
+	}
+	l = append(l, atom.Link{Href: fmt.Sprintf("%s/metadata", u), Rel: "metadata"})
+	f.Link = l
+
+	f.HeadOfStream = hi >= last
+
+	for i := hi; i >= lo; i-- {
+		rec := recs[i]
+		stream := name
+		if rec.stream != "" {
+			stream = rec.stream
+		}
+		eu := fmt.Sprintf("%s/streams/%s/%d", host, stream, rec.Number)
+		e := &atom.Entry{}
+		e.Title = fmt.Sprintf("%d@%s", rec.Number, stream)
+		e.ID = eu
+		e.Updated = atom.Time(rec.Created)
+		e.Author = &atom.Person{Name: "EventStore"}
+		e.Summary = &atom.Text{Body: rec.Type}
+		e.Link = append(e.Link, atom.Link{Rel: "edit", Href: eu})
+		e.Link = append(e.Link, atom.Link{Rel: "alternate", Href: eu})
+		f.Entry = append(f.Entry, e)
+	}
+
+	return f
+}
+
+// firstVisible returns the number of the first event in the stream that is
+// not hidden by truncation. The caller must hold the lock.
+func (s *Simulator) firstVisible(st *stream) int {
+	first := 0
+	m := s.metaData(st.name)
+	if tb, ok := m["$tb"].(float64); ok && int(tb) > first {
+		first = int(tb)
+	}
+	if mc, ok := m["$maxCount"].(float64); ok {
+		if n := st.version() - int(mc) + 1; n > first {
+			first = n
+		}
+	}
+	return first
+}
+
+// metaData returns the current metadata for a stream decoded into a map. The
+// caller must hold the lock.
+func (s *Simulator) metaData(name string) map[string]interface{} {
+	m := make(map[string]interface{})
+	ms, ok := s.streams[metaStreamName(name)]
+	if !ok || len(ms.events) == 0 {
+		return m
+	}
+	_ = json.Unmarshal(ms.events[len(ms.events)-1].Data, &m)
+	return m
+}
+
+// serveEvent serves the event, or only its data if data is true.
+func (s *Simulator) serveEvent(w http.ResponseWriter, r *http.Request, host, name string, n int, data bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.streams[name]
+	if !ok || st.softDeleted {
+		http.NotFound(w, r)
+		return
+	}
+	if st.hardDeleted {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	if n > st.version() || n < s.firstVisible(st) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if data {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(st.events[n].Data)
+		return
+	}
+	writeEventResponse(w, host, name, st.events[n])
+}
+
+func (s *Simulator) serveMetaData(w http.ResponseWriter, r *http.Request, host, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if st, ok := s.streams[name]; ok && st.hardDeleted {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	ms, ok := s.streams[metaStreamName(name)]
+	if !ok || len(ms.events) == 0 {
+		w.Header().Set("Content-Type", "application/vnd.eventstore.atom+json")
+		fmt.Fprint(w, "{}")
+		return
+	}
+
+	writeEventResponse(w, host, ms.name, ms.events[len(ms.events)-1])
+}
+
+func (s *Simulator) serveAppend(w http.ResponseWriter, r *http.Request, host, name string) {
+	in, err := decodeInbound(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expected := -2
+	if ev := r.Header.Get("ES-ExpectedVersion"); ev != "" {
+		n, err := strconv.Atoi(ev)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		expected = n
+	}
+
+	s.mu.Lock()
+	first, err := s.append(name, expected, in)
+	current := -1
+	if st, ok := s.streams[name]; ok {
+		current = st.version()
+	}
+	s.mu.Unlock()
+
+	switch err.(type) {
+	case nil:
+	case errWrongExpectedVersion:
+		w.Header().Set("ES-CurrentVersion", strconv.Itoa(current))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errStreamDeleted:
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/streams/%s/%d", host, name, first))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Simulator) serveDelete(w http.ResponseWriter, r *http.Request, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.streams[name]
+	if !ok || st.softDeleted {
+		http.NotFound(w, r)
+		return
+	}
+	if st.hardDeleted {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	if r.Header.Get("ES-HardDelete") == "true" {
+		st.hardDeleted = true
+	} else {
+		st.softDeleted = true
+		m := s.metaData(name)
+		m["$tb"] = st.version() + 1
+		b, _ := json.Marshal(m)
+		s.appendToStream(metaStreamName(name), []*inboundEvent{{
+			EventID:   goes.NewUUID(),
+			EventType: "$metadata",
+			Data:      json.RawMessage(b),
+		}})
+	}
+
+	s.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type errWrongExpectedVersion struct {
+	expected int
+	current  int
+}
+
+func (e errWrongExpectedVersion) Error() string {
+	return fmt.Sprintf("Wrong expected EventNumber. Expected %d, current %d", e.expected, e.current)
+}
+
+type errStreamDeleted string
+
+func (e errStreamDeleted) Error() string {
+	return fmt.Sprintf("Stream %s has been deleted", string(e))
+}
+
+// append checks the expected version and appends the events to the stream.
+// The number of the first event written is returned. The caller must hold the
+// lock.
+func (s *Simulator) append(name string, expected int, in []*inboundEvent) (int, error) {
+	for _, e := range in {
+		if e.EventID == "" || e.EventType == "" {
+			return 0, fmt.Errorf("Events must have an eventId and an eventType")
+		}
+	}
+
+	st, exists := s.streams[name]
+	if exists && st.hardDeleted {
+		return 0, errStreamDeleted(name)
+	}
+
+	current := -1
+	if exists && !st.softDeleted {
+		current = st.version()
+	}
+
+	switch {
+	case expected == -2:
+	case expected == -1 && current != -1:
+		return 0, errWrongExpectedVersion{expected, current}
+	case expected == -4 && current == -1:
+		return 0, errWrongExpectedVersion{expected, current}
+	case expected >= 0 && expected != current:
+		return 0, errWrongExpectedVersion{expected, current}
+	}
+
+	first := s.appendToStream(name, in)
+	s.notify()
+	return first, nil
+}
+
+// appendToStream appends events to a stream creating the stream if required.
+// The caller must hold the lock.
+func (s *Simulator) appendToStream(name string, in []*inboundEvent) int {
+	st, ok := s.streams[name]
+	if !ok {
+		st = &stream{name: name}
+		s.streams[name] = st
+	}
+	st.softDeleted = false
+
+	first := len(st.events)
+	for _, e := range in {
+		st.events = append(st.events, &record{
+			Number:   len(st.events),
+			ID:       e.EventID,
+			Type:     e.EventType,
+			Data:     e.Data,
+			MetaData: e.MetaData,
+			Created:  s.now(),
+		})
+	}
+	return first
+}
+
+// notify wakes any long polling requests. The caller must hold the lock.
+func (s *Simulator) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// inboundEvent is an event as posted in an application/vnd.eventstore.events+json
+// request body.
+type inboundEvent struct {
+	EventID   string          `json:"eventId"`
+	EventType string          `json:"eventType"`
+	Data      json.RawMessage `json:"data"`
+	MetaData  json.RawMessage `json:"metadata,omitempty"`
+}
+
+// decodeInbound decodes the events in the request body. The body may contain
+// an array of events or a single event.
+func decodeInbound(r *http.Request) ([]*inboundEvent, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	in := []*inboundEvent{}
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "{") {
+		e := &inboundEvent{}
+		if err := json.Unmarshal(raw, e); err != nil {
+			return nil, err
+		}
+		return append(in, e), nil
+	}
+
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+func newInboundEvent(e *goes.Event) (*inboundEvent, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	ie := &inboundEvent{}
+	if err := json.Unmarshal(b, ie); err != nil {
+		return nil, err
+	}
+	return ie, nil
+}
+
+// event returns the goes.Event representation of the record.
+func (r *record) event(stream, host string) *goes.Event {
+	data := r.Data
+	meta := r.MetaData
+	e := &goes.Event{
+		EventStreamID: stream,
+		EventNumber:   r.Number,
+		EventType:     r.Type,
+		EventID:       r.ID,
+		Data:          &data,
+	}
+	if len(meta) > 0 {
+		e.MetaData = &meta
+	}
+	if host != "" {
+		u := fmt.Sprintf("%s/streams/%s/%d", host, stream, r.Number)
+		e.Links = []goes.Link{
+			{URI: u, Relation: "edit"},
+			{URI: u, Relation: "alternate"},
+		}
+	}
+	return e
+}
+
+// EventAtomResponse is the application/vnd.eventstore.atom+json representation
+// of an event, as returned by the eventstore when an event is read.
+type EventAtomResponse struct {
+	Title   string      `json:"title"`
+	ID      string      `json:"id"`
+	Updated string      `json:"updated"`
+	Summary string      `json:"summary"`
+	Content *goes.Event `json:"content"`
+}
+
+// newEventAtomResponse returns the *EventAtomResponse for the event created at
+// the time provided. The event must have links.
+func newEventAtomResponse(e *goes.Event, created time.Time) *EventAtomResponse {
+	return &EventAtomResponse{
+		Title:   fmt.Sprintf("%d@%s", e.EventNumber, e.EventStreamID),
+		ID:      e.Links[0].URI,
+		Updated: string(goes.Time(created)),
+		Summary: e.EventType,
+		Content: e,
+	}
+}
+
+func writeEventResponse(w http.ResponseWriter, host, stream string, r *record) {
+	resp := newEventAtomResponse(r.event(stream, host), r.Created)
+
+	// Events never change once written.
+	w.Header().Set("Cache-Control", "max-age=31536000, public")
+	w.Header().Set("Content-Type", "application/vnd.eventstore.atom+json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// metaStreamName returns the name of the metadata stream for a stream.
+func metaStreamName(stream string) string {
+	return "$$" + stream
+}
+
+// baseURL returns the scheme and host of the server the request was made to.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,313 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package estest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&SimulatorSuite{})
+
+type SimulatorSuite struct {
+	sim    *Simulator
+	server *httptest.Server
+	client *goes.Client
+}
+
+type FooEvent struct {
+	Foo string `json:"foo"`
+}
+
+func (s *SimulatorSuite) SetUpTest(c *C) {
+	s.sim = NewSimulator()
+	s.server = httptest.NewServer(s.sim)
+	client, err := goes.NewClient(nil, s.server.URL)
+	c.Assert(err, IsNil)
+	s.client = client
+}
+
+func (s *SimulatorSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+func fooEvents(n int) []*goes.Event {
+	es := make([]*goes.Event, n)
+	for i := range es {
+		es[i] = goes.NewEvent("", "", &FooEvent{Foo: goes.NewUUID()}, map[string]string{"bar": "baz"})
+	}
+	return es
+}
+
+// readAll reads the stream from the start and returns the events read and
+// the error that stopped the read.
+func readAll(client *goes.Client, stream string) ([]*goes.EventResponse, error) {
+	ret := []*goes.EventResponse{}
+	reader := client.NewStreamReader(stream)
+	for reader.Next() {
+		if reader.Err() != nil {
+			return ret, reader.Err()
+		}
+		ret = append(ret, reader.EventResponse())
+	}
+	return ret, nil
+}
+
+func (s *SimulatorSuite) TestWriteAndReadAcrossPages(c *C) {
+	es := fooEvents(45)
+	err := s.client.NewStreamWriter("foo-stream").Append(goes.ExpectAny, es...)
+	c.Assert(err, IsNil)
+
+	got, err := readAll(s.client, "foo-stream")
+	c.Assert(err, FitsTypeOf, &goes.ErrNoMoreEvents{})
+	c.Assert(got, HasLen, len(es))
+	for i, e := range got {
+		c.Assert(e.Event.EventNumber, Equals, i)
+		c.Assert(e.Event.EventID, Equals, es[i].EventID)
+		c.Assert(e.Event.EventType, Equals, "FooEvent")
+	}
+
+	foo := &FooEvent{}
+	meta := map[string]string{}
+	reader := s.client.NewStreamReader("foo-stream")
+	reader.Next()
+	c.Assert(reader.Scan(foo, &meta), IsNil)
+	c.Assert(foo, DeepEquals, es[0].Data)
+	c.Assert(meta, DeepEquals, map[string]string{"bar": "baz"})
+}
+
+func (s *SimulatorSuite) TestReadingMissingStreamReturnsErrNotFound(c *C) {
+	_, err := readAll(s.client, "missing")
+	c.Assert(err, FitsTypeOf, &goes.ErrNotFound{})
+}
+
+func (s *SimulatorSuite) TestExpectedVersionConflict(c *C) {
+	c.Assert(s.sim.Append("versioned", fooEvents(3)...), IsNil)
+	writer := s.client.NewStreamWriter("versioned")
+
+	v := 1
+	err := writer.Append(goes.ExpectedVersion(v), fooEvents(1)...)
+	c.Assert(err, FitsTypeOf, &goes.ErrConcurrencyViolation{})
+
+	v = -1
+	err = writer.Append(goes.ExpectedVersion(v), fooEvents(1)...)
+	c.Assert(err, FitsTypeOf, &goes.ErrConcurrencyViolation{})
+
+	v = 2
+	err = writer.Append(goes.ExpectedVersion(v), fooEvents(1)...)
+	c.Assert(err, IsNil)
+	c.Assert(s.sim.Events("versioned"), HasLen, 4)
+}
+
+func (s *SimulatorSuite) TestNamedExpectedVersions(c *C) {
+	_, err := s.client.AppendToStream("named", fooEvents(1), goes.WithExpectedVersion(goes.ExpectStreamExists))
+	c.Assert(err, FitsTypeOf, &goes.ErrConcurrencyViolation{})
+	_, err = s.client.AppendToStream("named", fooEvents(2), goes.WithExpectedVersion(goes.ExpectNoStream))
+	c.Assert(err, IsNil)
+	_, err = s.client.AppendToStream("named", fooEvents(1), goes.WithExpectedVersion(goes.ExpectNoStream))
+	c.Assert(err, FitsTypeOf, &goes.ErrConcurrencyViolation{})
+	result, err := s.client.AppendToStream("named", fooEvents(1), goes.WithExpectedVersion(goes.ExpectStreamExists))
+	c.Assert(err, IsNil)
+	c.Assert(result.NextExpectedVersion, Equals, 2)
+}
+
+func (s *SimulatorSuite) TestLinkedStreamListsEventsLinkedTo(c *C) {
+	c.Assert(s.sim.Append("order-1", fooEvents(2)...), IsNil)
+	c.Assert(s.sim.Append("order-2", fooEvents(1)...), IsNil)
+	c.Assert(s.sim.LinkTo("$ce-order", "order-1", 0), IsNil)
+	c.Assert(s.sim.LinkTo("$ce-order", "order-2", 0), IsNil)
+	c.Assert(s.sim.LinkTo("$ce-order", "order-1", 1), IsNil)
+
+	got, err := readAll(s.client, "$ce-order")
+	c.Assert(err, FitsTypeOf, &goes.ErrNoMoreEvents{})
+	c.Assert(got, HasLen, 3)
+	refs := []string{}
+	for _, er := range got {
+		refs = append(refs, fmt.Sprintf("%d@%s", er.Event.EventNumber, er.Event.EventStreamID))
+	}
+	c.Assert(refs, DeepEquals, []string{"0@order-1", "0@order-2", "1@order-1"})
+
+	head, err := s.client.GetStreamHeadVersion("$ce-order")
+	c.Assert(err, IsNil)
+	c.Assert(head, Equals, 2)
+	c.Assert(s.sim.Events("$ce-order")[1].EventType, Equals, "$>")
+}
+
+func (s *SimulatorSuite) TestReadJSONFeeds(c *C) {
+	c.Assert(s.sim.Append("json-feeds", fooEvents(30)...), IsNil)
+	s.client.SetFeedFormat(goes.FeedJSON)
+
+	events, err := readAll(s.client, "json-feeds")
+	c.Assert(err, FitsTypeOf, &goes.ErrNoMoreEvents{})
+	c.Assert(events, HasLen, 30)
+	for i, e := range events {
+		c.Assert(e.Event.EventNumber, Equals, i)
+	}
+}
+
+func (s *SimulatorSuite) TestStreamMetaData(c *C) {
+	c.Assert(s.sim.Append("meta-stream", fooEvents(1)...), IsNil)
+
+	reader := s.client.NewStreamReader("meta-stream")
+	m, err := reader.MetaData()
+	c.Assert(err, IsNil)
+	c.Assert(m, IsNil)
+
+	err = s.client.NewStreamWriter("meta-stream").WriteMetaData("meta-stream", map[string]int{"$maxCount": 10})
+	c.Assert(err, IsNil)
+
+	m, err = reader.MetaData()
+	c.Assert(err, IsNil)
+	c.Assert(m, NotNil)
+	c.Assert(m.Event.EventStreamID, Equals, "$$meta-stream")
+}
+
+func (s *SimulatorSuite) TestMaxCountTruncatesStream(c *C) {
+	c.Assert(s.sim.Append("truncated", fooEvents(10)...), IsNil)
+	c.Assert(s.sim.SetMetaData("truncated", map[string]int{"$maxCount": 3}), IsNil)
+
+	e, err := s.client.ReadFirst("truncated", nil)
+	c.Assert(err, IsNil)
+	c.Assert(e.Event.EventNumber, Equals, 7)
+
+	_, _, err = s.client.GetEvent(s.server.URL + "/streams/truncated/2")
+	c.Assert(err, FitsTypeOf, &goes.ErrNotFound{})
+}
+
+func (s *SimulatorSuite) TestSoftDeleteAndRecreate(c *C) {
+	c.Assert(s.sim.Append("soft", fooEvents(3)...), IsNil)
+
+	_, err := s.client.DeleteStream("soft", false)
+	c.Assert(err, IsNil)
+
+	_, err = readAll(s.client, "soft")
+	c.Assert(err, FitsTypeOf, &goes.ErrNotFound{})
+
+	v := -1
+	err = s.client.NewStreamWriter("soft").Append(goes.ExpectedVersion(v), fooEvents(2)...)
+	c.Assert(err, IsNil)
+
+	e, err := s.client.ReadFirst("soft", nil)
+	c.Assert(err, IsNil)
+	c.Assert(e.Event.EventNumber, Equals, 3)
+}
+
+func (s *SimulatorSuite) TestHardDelete(c *C) {
+	c.Assert(s.sim.Append("hard", fooEvents(3)...), IsNil)
+
+	_, err := s.client.DeleteStream("hard", true)
+	c.Assert(err, IsNil)
+
+	_, err = readAll(s.client, "hard")
+	c.Assert(err, FitsTypeOf, &goes.ErrDeleted{})
+
+	err = s.client.NewStreamWriter("hard").Append(goes.ExpectAny, fooEvents(1)...)
+	c.Assert(err, FitsTypeOf, &goes.ErrStreamHardDeleted{})
+}
+
+func (s *SimulatorSuite) TestLongPollReturnsEventsAppendedWhilePolling(c *C) {
+	c.Assert(s.sim.Append("polled", fooEvents(1)...), IsNil)
+
+	reader := s.client.NewStreamReader("polled")
+	reader.Next()
+	c.Assert(reader.Err(), IsNil)
+	reader.Next()
+	c.Assert(reader.Err(), FitsTypeOf, &goes.ErrNoMoreEvents{})
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s.sim.Append("polled", fooEvents(1)...)
+	}()
+
+	reader.LongPoll(5)
+	defer reader.LongPoll(0)
+
+	start := time.Now()
+	reader.Next()
+	c.Assert(reader.Err(), IsNil)
+	c.Assert(reader.EventResponse().Event.EventNumber, Equals, 1)
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+}
+
+func (s *SimulatorSuite) TestFeedPagesHaveCachingHeaders(c *C) {
+	c.Assert(s.sim.Append("cached", fooEvents(5)...), IsNil)
+
+	get := func(path, etag string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, s.server.URL+path, nil)
+		req.Header.Set("Accept", "application/atom+xml")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("/streams/cached/0/forward/2", "")
+	c.Assert(resp.Header.Get("Cache-Control"), Equals, "max-age=31536000, public")
+
+	resp = get("/streams/cached/head/backward/2", "")
+	c.Assert(resp.Header.Get("Cache-Control"), Equals, "max-age=0, no-cache, must-revalidate")
+	etag := resp.Header.Get("ETag")
+	c.Assert(etag, Not(Equals), "")
+	c.Assert(get("/streams/cached/head/backward/2", etag).StatusCode, Equals, http.StatusNotModified)
+
+	c.Assert(s.sim.Append("cached", fooEvents(1)...), IsNil)
+	resp = get("/streams/cached/head/backward/2", etag)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("ETag"), Not(Equals), etag)
+}
+
+func (s *SimulatorSuite) TestSubscriptionFollowsContinuousAppends(c *C) {
+	b := NewEventBuilder("live").WithServer(s.server.URL)
+	c.Assert(s.sim.Append("live", b.Build(10)...), IsNil)
+
+	var mu sync.Mutex
+	handled := []int{}
+	sub := s.client.NewCatchUpSubscription("live", 0, func(er *goes.EventResponse) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, er.Event.EventNumber)
+		return nil
+	})
+	sub.SetLongPoll(5)
+	sub.Start()
+	defer sub.Stop()
+
+	stop := s.sim.AppendEvery("live", 5*time.Millisecond, fooEvents(10)...)
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sub.LastProcessed() < 19 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(handled, HasLen, 20)
+	for i, n := range handled {
+		c.Assert(n, Equals, i)
+	}
+}
+
+func (s *SimulatorSuite) TestEventDataEndpoint(c *C) {
+	c.Assert(s.sim.Append("data", fooEvents(1)...), IsNil)
+
+	got, _, err := s.client.GetEventData(s.server.URL + "/streams/data/0")
+	c.Assert(err, IsNil)
+	c.Assert(got.ContentType, Equals, "application/json; charset=utf-8")
+	c.Assert(string(got.Body), Matches, `\{"foo":".+"\}`)
+}
@@ -9,8 +9,6 @@ import (
 	"encoding/json"
 	"reflect"
 	"time"
-
-	"github.com/jetbasrawi/go.geteventstore/internal/uuid"
 )
 
 // EventResponse encapsulates the response for an event reflecting the atom
@@ -67,6 +65,8 @@ func (e *eventAtomResponse) PrettyPrint() string {
 // Data contains the data of the event.
 // Links contains the urls of the event on the evenstore
 // MetaData contains the metadata for the event.
+// CorrelationID and CausationID are written as the $correlationId and
+// $causationId metadata keys and are set from them when the event is read.
 type Event struct {
 	EventStreamID string      `json:"eventStreamId,omitempty"`
 	EventNumber   int         `json:"eventNumber,omitempty"`
@@ -75,6 +75,8 @@ type Event struct {
 	Data          interface{} `json:"data"`
 	Links         []Link      `json:"links,omitempty"`
 	MetaData      interface{} `json:"metadata,omitempty"`
+	CorrelationID string      `json:"-"`
+	CausationID   string      `json:"-"`
 }
 
 // PrettyPrint renders an indented json view of the Event object.
@@ -92,18 +94,27 @@ type Link struct {
 	Relation string `json:"relation"`
 }
 
-// TimeStr is a type used to format feed dates.
+// TimeStr is a type used to format feed dates. Use its Time method to get the
+// time as a time.Time.
 type TimeStr string
 
-// Time returns a TimeStr version of the time.Time argument t.
+// Time returns a TimeStr version of the time.Time argument t, in UTC to the
+// second. See FormatTime to keep fractional seconds.
+//
+// Time used to keep the offset of t, formatting a time in CEST as
+// "2016-06-01T16:00:00+02:00". It now returns the same instant in UTC, as
+// "2016-06-01T14:00:00Z", which is the form readers normalize the Updated time
+// of an EventResponse to, so that the two can be compared.
 func Time(t time.Time) TimeStr {
-	return TimeStr(t.Format("2006-01-02T15:04:05-07:00"))
+	return FormatTime(t.Truncate(time.Second))
 }
 
 // NewEvent creates a new event object.
 //
 // If an empty eventId is provided a new uuid will be generated automatically
-// and retured in the event.
+// and retured in the event. An eventId that is not empty must be a UUID other
+// than NilUUID; NewEvent panics with an *ErrInvalidUUID if it is not. Use
+// ParseUUID to check ids from untrusted sources first.
 // If an empty eventType is provided the eventType will be set to the
 // name of the type provided.
 // data and meta can be nil.
@@ -113,6 +124,8 @@ func NewEvent(eventID, eventType string, data interface{}, meta interface{}) *Ev
 	e.EventID = eventID
 	if eventID == "" {
 		e.EventID = NewUUID()
+	} else if !validEventID(eventID) {
+		panic(&ErrInvalidUUID{Value: eventID})
 	}
 
 	e.EventType = eventType
@@ -125,9 +138,25 @@ func NewEvent(eventID, eventType string, data interface{}, meta interface{}) *Ev
 	return e
 }
 
+// NewEventWithID creates a new event object with the id provided. It is
+// otherwise the same as NewEvent. NilUUID cannot be the id of an event and
+// returns an *ErrInvalidUUID.
+func NewEventWithID(id UUID, eventType string, data interface{}, meta interface{}) (*Event, error) {
+	if id == NilUUID {
+		return nil, &ErrInvalidUUID{Value: id.String()}
+	}
+	return NewEvent(id.String(), eventType, data, meta), nil
+}
+
+// UUID returns the id of the event as a UUID. If the id is not a UUID an
+// *ErrInvalidUUID is returned.
+func (e *Event) UUID() (UUID, error) {
+	return ParseUUID(e.EventID)
+}
+
 // NewUUID returns a new V4 uuid as a string.
 func NewUUID() string {
-	return uuid.NewV4().String()
+	return NewUUIDv4().String()
 }
 
 // typeOf is a helper to get the names of types.
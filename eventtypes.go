@@ -0,0 +1,61 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+)
+
+// eventTypes is the set of event types read by a reader. A nil set reads
+// events of every type.
+type eventTypes map[string]bool
+
+// WithEventTypes reads only the events of the event types provided.
+//
+// The HTTP API cannot filter a stream by event type, so the events are
+// filtered by the reader. The feed pages of the stream are still read, but the
+// event type of each entry is taken from the page and the events of other types
+// are skipped without being read, decoded or delivered. The position of the
+// reader still advances over the skipped events.
+//
+// To read the events of a type from every stream rather than from one stream,
+// read the stream named by EventTypeStreamName, which the server maintains
+// when the $by_event_type projection is running.
+func WithEventTypes(types ...string) ReadOption {
+	return func(o *readOptions) {
+		if o.eventTypes == nil {
+			o.eventTypes = make(eventTypes)
+		}
+		for _, t := range types {
+			o.eventTypes[t] = true
+		}
+	}
+}
+
+// EventTypeStreamName returns the name of the stream of links to the events of
+// eventType in every stream, which is maintained by the $by_event_type system
+// projection.
+func EventTypeStreamName(eventType string) string {
+	return "$et-" + eventType
+}
+
+// skipsEntry reports whether the feed entry is for an event that is not read.
+// An entry without an event type is not skipped, so that its event can be
+// checked once it has been read.
+func (t eventTypes) skipsEntry(e *atom.Entry) bool {
+	if t == nil || e.Summary == nil || e.Summary.Body == "" {
+		return false
+	}
+	return !t[e.Summary.Body]
+}
+
+// skipsEvent reports whether the event is of a type that is not read.
+func (t eventTypes) skipsEvent(er *EventResponse) bool {
+	if t == nil || er == nil || er.Event == nil {
+		return false
+	}
+	return !t[er.Event.EventType]
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&EventTypesSuite{})
+
+type EventTypesSuite struct{}
+
+func (s *EventTypesSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *EventTypesSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// mixedEvents serves a stream of n events in which every third event is an
+// OrderCancelled and the others are OrderPlaced. It returns a counter of the
+// events read.
+func mixedEvents(stream string, n int) *int32 {
+	es := make([]*Event, n)
+	for i := range es {
+		t := "OrderPlaced"
+		if i%3 == 2 {
+			t = "OrderCancelled"
+		}
+		es[i] = CreateTestEvent(stream, server.URL, t, i, nil, nil)
+	}
+	setupSimulator(es, nil)
+
+	reads := new(int32)
+	client.Use(func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		if !strings.Contains(req.URL.Path, "/forward/") && !strings.Contains(req.URL.Path, "/backward/") {
+			atomic.AddInt32(reads, 1)
+		}
+		return next(req)
+	})
+	return reads
+}
+
+// readTypes reads the stream to its end and returns the numbers of the events
+// read.
+func readTypes(c *C, r *StreamReader) []int {
+	got := []int{}
+	for r.Next() {
+		if r.Err() != nil {
+			c.Assert(r.Err(), FitsTypeOf, &ErrNoMoreEvents{})
+			break
+		}
+		got = append(got, r.EventResponse().Event.EventNumber)
+	}
+	return got
+}
+
+func (s *EventTypesSuite) TestReaderSkipsOtherTypes(c *C) {
+	reads := mixedEvents("types-1", 25)
+
+	r := client.NewStreamReader("types-1", WithEventTypes("OrderCancelled"), WithPageSize(10))
+	c.Assert(readTypes(c, r), DeepEquals, []int{2, 5, 8, 11, 14, 17, 20, 23})
+	c.Assert(atomic.LoadInt32(reads), Equals, int32(8))
+	c.Assert(r.Position(), Equals, 25)
+}
+
+func (s *EventTypesSuite) TestReaderWithSeveralTypes(c *C) {
+	mixedEvents("types-2", 6)
+
+	r := client.NewStreamReader("types-2", WithEventTypes("OrderCancelled", "OrderPlaced"))
+	c.Assert(readTypes(c, r), DeepEquals, []int{0, 1, 2, 3, 4, 5})
+}
+
+func (s *EventTypesSuite) TestPrefetchingReaderSkipsOtherTypes(c *C) {
+	reads := mixedEvents("types-3", 25)
+
+	r := client.NewStreamReader("types-3", WithEventTypes("OrderCancelled"), WithPageSize(10))
+	r.Prefetch(4)
+	r.SetFetchConcurrency(3)
+	defer r.Close()
+	c.Assert(readTypes(c, r), DeepEquals, []int{2, 5, 8, 11, 14, 17, 20, 23})
+	c.Assert(atomic.LoadInt32(reads), Equals, int32(8))
+	c.Assert(r.Position(), Equals, 25)
+}
+
+func (s *EventTypesSuite) TestReadWindowSkipsOtherTypes(c *C) {
+	reads := mixedEvents("types-4", 12)
+
+	got := []int{}
+	err := client.ReadWindow(Window{Stream: "types-4", From: 3, To: 9}, func(er *EventResponse) error {
+		got = append(got, er.Event.EventNumber)
+		return nil
+	}, WithEventTypes("OrderCancelled"))
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, []int{5, 8})
+	c.Assert(atomic.LoadInt32(reads), Equals, int32(2))
+}
+
+func (s *EventTypesSuite) TestEventTypeStreamName(c *C) {
+	c.Assert(EventTypeStreamName("OrderPlaced"), Equals, "$et-OrderPlaced")
+}
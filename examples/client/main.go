@@ -29,7 +29,7 @@ func main() {
 	a := goes.NewEvent("", "", &FooEvent{goes.NewUUID()}, nil)
 	b := goes.NewEvent("", "", &FooEvent{goes.NewUUID()}, nil)
 	c := goes.NewEvent("", "", &FooEvent{goes.NewUUID()}, nil)
-	streamWriter.Append(nil, a, b, c)
+	streamWriter.Append(goes.ExpectAny, a, b, c)
 
 	// Get the path for the atom feed at the head of the stream.
 	path, err := client.GetFeedPath(streamName, "backward", -1, 10)
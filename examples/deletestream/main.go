@@ -7,6 +7,7 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -31,7 +32,7 @@ func main() {
 	log.Println("1. Write an event to a new stream.")
 	writer := client.NewStreamWriter(streamName)
 	ev1 := goes.NewEvent("", "", &FooEvent{"Event 1"}, nil)
-	err = writer.Append(nil, ev1)
+	err = writer.Append(goes.ExpectAny, ev1)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -87,7 +88,7 @@ func main() {
 	// This should result in the stream being undeleted and the second event
 	// being appended to the stream.
 	ev2 := goes.NewEvent("", "", &FooEvent{"Event 2"}, nil)
-	err = writer.Append(nil, ev2)
+	err = writer.Append(goes.ExpectAny, ev2)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -127,9 +128,10 @@ func main() {
 
 	log.Println("9. Try to write to the hard deleted stream. This should result in an ErrDeleted")
 	ev3 := goes.NewEvent("", "", &FooEvent{"Event 3"}, nil)
-	err = writer.Append(nil, ev3)
+	err = writer.Append(goes.ExpectAny, ev3)
 	if err != nil {
-		if _, ok := err.(*goes.ErrDeleted); ok {
+		var deleted *goes.ErrDeleted
+		if errors.As(err, &deleted) {
 			log.Println(" - As expected, an attempt to write to the hard deleted stream fails.")
 		} else {
 			log.Fatal(err)
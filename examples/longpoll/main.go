@@ -113,7 +113,7 @@ func writeEvents(client *goes.Client, streamName string) {
 	existingEvents := createTestEvents(10, streamName, serverURL, "FooEvent")
 
 	writer := client.NewStreamWriter(streamName)
-	err := writer.Append(nil, existingEvents...)
+	err := writer.Append(goes.ExpectAny, existingEvents...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -123,7 +123,7 @@ func writeEvents(client *goes.Client, streamName string) {
 		for _ = range ticker.C {
 			num := rand.Intn(5)
 			newEvents := createTestEvents(num, streamName, serverURL, "FooEvent")
-			writer.Append(nil, newEvents...)
+			writer.Append(goes.ExpectAny, newEvents...)
 		}
 	}()
 
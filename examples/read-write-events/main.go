@@ -215,7 +215,7 @@ func writeEvents(client *goes.Client, streamName string) {
 	// The first argument allows you to specify the expected version. Here expected version
 	// is nil and so the events will be appended at the head of the stream regardless of the
 	// version of the stream.
-	err := writer.Append(nil, goesEvent1, goesEvent2)
+	err := writer.Append(goes.ExpectAny, goesEvent1, goesEvent2)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -226,8 +226,8 @@ func writeEvents(client *goes.Client, streamName string) {
 	// Lets repeat this but using an expected version that will cause an error
 	// to demonstrate handling concurrency errors
 	// This should result in a goes.ErrConcurrencyViolation
-	v := 0
-	err = writer.Append(&v, goesEvent1)
+	v := goes.ExpectedVersion(0)
+	err = writer.Append(v, goesEvent1)
 	if err != nil {
 		log.Printf(" - Received expected error. %#v\n", err)
 	}
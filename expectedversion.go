@@ -0,0 +1,73 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// ExpectedVersion is the version a stream is expected to have for a write to
+// succeed. It is sent to the server in the ES-ExpectedVersion header.
+//
+// A version of 0 or more is the event number of the last event in the stream.
+// The named versions have the special meanings given to them by the server.
+// ExpectAny is the default of the server and is sent by omitting the header.
+type ExpectedVersion int
+
+const (
+	// ExpectAny makes the write succeed whatever the version of the stream.
+	ExpectAny ExpectedVersion = -2
+
+	// ExpectNoStream makes the write succeed only if the stream does not exist.
+	// The write creates the stream.
+	ExpectNoStream ExpectedVersion = -1
+
+	// ExpectEmptyStream makes the write succeed only if the stream has no
+	// events. The server treats a stream with no events as a stream that does
+	// not exist, so it is the same as ExpectNoStream.
+	ExpectEmptyStream ExpectedVersion = -1
+
+	// ExpectStreamExists makes the write succeed only if the stream exists.
+	ExpectStreamExists ExpectedVersion = -4
+)
+
+// Valid reports whether the version is an event number or one of the named
+// versions.
+func (v ExpectedVersion) Valid() bool {
+	switch v {
+	case ExpectAny, ExpectNoStream, ExpectStreamExists:
+		return true
+	}
+	return v >= 0
+}
+
+func (v ExpectedVersion) String() string {
+	switch v {
+	case ExpectAny:
+		return "Any"
+	case ExpectNoStream:
+		return "NoStream"
+	case ExpectStreamExists:
+		return "StreamExists"
+	}
+	return strconv.Itoa(int(v))
+}
+
+// setExpectedVersion sets the ES-ExpectedVersion header of the request. If the
+// version is ExpectAny the header is not set and the write is not
+// conditional. A version that is not valid returns an
+// *ErrInvalidExpectedVersion rather than being sent to the server.
+func setExpectedVersion(req *http.Request, v ExpectedVersion) error {
+	if v == ExpectAny {
+		return nil
+	}
+	if !v.Valid() {
+		return &ErrInvalidExpectedVersion{Version: int(v)}
+	}
+	req.Header.Set("ES-ExpectedVersion", strconv.Itoa(int(v)))
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ExpectedVersionSuite{})
+
+type ExpectedVersionSuite struct{}
+
+func (s *ExpectedVersionSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *ExpectedVersionSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *ExpectedVersionSuite) TestValid(c *C) {
+	for v, valid := range map[ExpectedVersion]bool{
+		ExpectAny:          true,
+		ExpectNoStream:     true,
+		ExpectStreamExists: true,
+		0:                  true,
+		42:                 true,
+		-3:                 false,
+		-5:                 false,
+	} {
+		c.Assert(v.Valid(), Equals, valid, Commentf("%d", v))
+	}
+	c.Assert(ExpectStreamExists.String(), Equals, "StreamExists")
+	c.Assert(ExpectedVersion(7).String(), Equals, "7")
+}
+
+func (s *ExpectedVersionSuite) TestNamedVersionsAreSentAsHeaders(c *C) {
+	stream := "expected-named"
+	versions := []string{}
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		versions = append(versions, r.Header.Get("ES-ExpectedVersion"))
+		w.Header().Set("Location", server.URL+"/streams/"+stream+"/0")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, v := range []ExpectedVersion{ExpectAny, ExpectNoStream, ExpectStreamExists, 3} {
+		_, err := client.AppendToStream(stream, []*Event{NewEvent("", "Foo", nil, nil)}, WithExpectedVersion(v))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(client.NewStreamWriter(stream).Append(ExpectEmptyStream, NewEvent("", "Foo", nil, nil)), IsNil)
+	c.Assert(versions, DeepEquals, []string{"", "-1", "-4", "3", "-1"})
+}
+
+func (s *ExpectedVersionSuite) TestInvalidVersionIsNotSent(c *C) {
+	stream := "expected-invalid"
+	mux.HandleFunc("/streams/", func(w http.ResponseWriter, r *http.Request) {
+		c.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+
+	_, err := client.AppendToStream(stream, []*Event{NewEvent("", "Foo", nil, nil)}, WithExpectedVersion(-3))
+	c.Assert(err, DeepEquals, &ErrInvalidExpectedVersion{Version: -3})
+	c.Assert(err, ErrorMatches, `Expected version -3 is not valid\.`)
+
+	v := -5
+	err = client.NewStreamWriter(stream).Append(ExpectedVersion(v), NewEvent("", "Foo", nil, nil))
+	c.Assert(err, FitsTypeOf, &ErrInvalidExpectedVersion{})
+	_, err = client.deleteStream(context.Background(), stream, false, ExpectedVersion(v))
+	c.Assert(err, FitsTypeOf, &ErrInvalidExpectedVersion{})
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Feature identifies an experimental behaviour of the client.
+//
+// Experimental behaviours are disabled by default and must be enabled on each
+// client that should use them. This allows new behaviours to ship in the package
+// without affecting existing deployments until they are explicitly enabled.
+type Feature string
+
+const (
+	// FeatureEmbedBody requests feed pages with embed=body so that the events
+	// on a page are read from the page rather than with a request per event.
+	// Bodies are only embedded in JSON feed pages, so the feature also requests
+	// FeedJSON unless another format has been set with SetFeedFormat. Events
+	// whose bodies are missing from the page are read with a request each, as
+	// are all events if Ping has found a server that does not support
+	// embed=body.
+	FeatureEmbedBody Feature = "embed-body"
+
+	// FeatureJSONFeeds requests feed pages as application/vnd.eventstore.atom+json
+	// rather than application/atom+xml.
+	FeatureJSONFeeds Feature = "json-feeds"
+)
+
+// knownFeatures contains all of the features recognised by the client.
+var knownFeatures = map[Feature]bool{
+	FeatureEmbedBody: true,
+	FeatureJSONFeeds: true,
+}
+
+// ParseFeatures parses a comma separated list of feature names such as
+// "embed-body,json-feeds".
+//
+// ParseFeatures is intended to be used to enable features from deployment
+// configuration such as an environment variable. An error is returned if any of
+// the names is not a known feature.
+func ParseFeatures(s string) ([]Feature, error) {
+	ret := []Feature{}
+	for _, v := range strings.Split(s, ",") {
+		name := strings.TrimSpace(v)
+		if name == "" {
+			continue
+		}
+		f := Feature(name)
+		if !knownFeatures[f] {
+			return nil, fmt.Errorf("Unknown feature %s", name)
+		}
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+// EnableExperimental enables experimental features on the client.
+//
+// Features can be enabled and disabled at any time. Requests made after the
+// change will use the new set of features.
+func (c *Client) EnableExperimental(features ...Feature) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.features == nil {
+		c.features = make(map[Feature]bool)
+	}
+	for _, f := range features {
+		c.features[f] = true
+	}
+}
+
+// DisableExperimental disables experimental features on the client.
+func (c *Client) DisableExperimental(features ...Feature) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range features {
+		delete(c.features, f)
+	}
+}
+
+// Experimental returns true if the feature is enabled on the client.
+func (c *Client) Experimental(f Feature) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.features[f]
+}
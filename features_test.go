@@ -0,0 +1,176 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&FeatureSuite{})
+
+type FeatureSuite struct{}
+
+func (s *FeatureSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *FeatureSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *FeatureSuite) TestFeaturesAreDisabledByDefault(c *C) {
+	c.Assert(client.Experimental(FeatureEmbedBody), Equals, false)
+	c.Assert(client.Experimental(FeatureJSONFeeds), Equals, false)
+}
+
+func (s *FeatureSuite) TestEnableAndDisableExperimental(c *C) {
+	client.EnableExperimental(FeatureEmbedBody, FeatureJSONFeeds)
+	c.Assert(client.Experimental(FeatureEmbedBody), Equals, true)
+	c.Assert(client.Experimental(FeatureJSONFeeds), Equals, true)
+
+	client.DisableExperimental(FeatureJSONFeeds)
+	c.Assert(client.Experimental(FeatureEmbedBody), Equals, true)
+	c.Assert(client.Experimental(FeatureJSONFeeds), Equals, false)
+}
+
+func (s *FeatureSuite) TestParseFeatures(c *C) {
+	got, err := ParseFeatures(" embed-body, json-feeds,")
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, []Feature{FeatureEmbedBody, FeatureJSONFeeds})
+
+	got, err = ParseFeatures("")
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 0)
+}
+
+func (s *FeatureSuite) TestParseFeaturesRejectsUnknownFeatures(c *C) {
+	got, err := ParseFeatures("embed-body,warp-drive")
+	c.Assert(err, ErrorMatches, "Unknown feature warp-drive")
+	c.Assert(got, IsNil)
+}
+
+// serveEmbeddedFeed serves a stream of n events as a single JSON feed page
+// followed by an empty page. The bodies of the events are embedded in the page
+// if it is requested with embed=body, except for those in missing. The event
+// numbers of the events read with a request each are returned by fetched.
+func serveEmbeddedFeed(c *C, stream string, n int, missing ...int) (fetched func() []int) {
+	var mu sync.Mutex
+	reads := []int{}
+	skip := map[int]bool{}
+	for _, i := range missing {
+		skip[i] = true
+	}
+
+	es := make([]*Event, n)
+	for i := range es {
+		raw := json.RawMessage(fmt.Sprintf(`{"n":%d}`, i))
+		es[i] = CreateTestEvent(stream, server.URL, "Foo", i, &raw, nil)
+	}
+
+	page := func(w http.ResponseWriter, r *http.Request, from int) {
+		f := &atom.Feed{
+			ID:   r.URL.String(),
+			Link: []atom.Link{{Rel: "self", Href: server.URL + r.URL.Path}},
+		}
+		if from == 0 {
+			f.Link = append(f.Link, atom.Link{Rel: "previous", Href: fmt.Sprintf("%s/streams/%s/%d/forward/20", server.URL, stream, n)})
+			for i := n - 1; i >= 0; i-- {
+				url := fmt.Sprintf("%s/streams/%s/%d", server.URL, stream, i)
+				e := &atom.Entry{
+					Title: fmt.Sprintf("%d@%s", i, stream),
+					ID:    url,
+					Link:  []atom.Link{{Rel: "edit", Href: url}, {Rel: "alternate", Href: url}},
+				}
+				if r.URL.Query().Get("embed") == "body" && !skip[i] {
+					data, _ := json.Marshal(fmt.Sprintf(`{"n":%d}`, i))
+					e.Body = &atom.Body{
+						EventID:     es[i].EventID,
+						EventType:   "Foo",
+						EventNumber: i,
+						StreamID:    stream,
+						IsJSON:      true,
+						Data:        data,
+					}
+				}
+				f.Entry = append(f.Entry, e)
+			}
+		}
+		w.Header().Set("Content-Type", string(FeedJSON))
+		c.Assert(json.NewEncoder(w).Encode(f), IsNil)
+	}
+
+	mux.HandleFunc("/streams/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		var i, from int
+		if _, err := fmt.Sscanf(r.URL.Path, "/streams/"+stream+"/%d/forward/20", &from); err == nil {
+			page(w, r, from)
+			return
+		}
+		if _, err := fmt.Sscanf(r.URL.Path, "/streams/"+stream+"/%d", &i); err != nil || i >= n {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		reads = append(reads, i)
+		mu.Unlock()
+		er, err := CreateTestEventAtomResponse(es[i], nil)
+		c.Assert(err, IsNil)
+		json.NewEncoder(w).Encode(er)
+	})
+
+	return func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return reads
+	}
+}
+
+// readData reads the stream until the head and returns the n values of the
+// data of the events, which are checked against their event numbers.
+func readData(c *C, stream string) []int {
+	ret := []int{}
+	reader := client.NewStreamReader(stream)
+	for reader.Next() {
+		if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+			break
+		}
+		c.Assert(reader.Err(), IsNil)
+		d := struct{ N int }{}
+		c.Assert(reader.Scan(&d, nil), IsNil)
+		c.Assert(reader.EventResponse().Event.EventNumber, Equals, d.N)
+		ret = append(ret, d.N)
+	}
+	return ret
+}
+
+func (s *FeatureSuite) TestEmbedBodyReadsEventsFromFeedPage(c *C) {
+	fetched := serveEmbeddedFeed(c, "embedded", 4, 2)
+	client.EnableExperimental(FeatureEmbedBody)
+
+	c.Assert(readData(c, "embedded"), DeepEquals, []int{0, 1, 2, 3})
+	c.Assert(fetched(), DeepEquals, []int{2})
+}
+
+func (s *FeatureSuite) TestEventsAreReadWithARequestEachWithoutEmbedBody(c *C) {
+	fetched := serveEmbeddedFeed(c, "embedded", 3)
+
+	c.Assert(readData(c, "embedded"), DeepEquals, []int{0, 1, 2})
+	c.Assert(fetched(), DeepEquals, []int{0, 1, 2})
+}
+
+func (s *FeatureSuite) TestEmbedBodyRequestsJSONFeeds(c *C) {
+	c.Assert(client.embedBody(), Equals, false)
+	client.EnableExperimental(FeatureEmbedBody)
+	c.Assert(client.embedBody(), Equals, true)
+	c.Assert(client.feedAccept(), Equals, string(FeedJSON))
+
+	client.SetFeedFormat(FeedXML)
+	c.Assert(client.embedBody(), Equals, false)
+}
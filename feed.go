@@ -0,0 +1,82 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+)
+
+// FeedLinks are the navigation links of a feed page.
+//
+// The eventstore orders feed pages most recent first, so Next links to the
+// page of older events and Previous links to the page of more recent events.
+// Links that are not present on the page are empty. For example, the last page
+// of a stream has no Next link.
+type FeedLinks struct {
+	Self     string
+	First    string
+	Last     string
+	Next     string
+	Previous string
+	Metadata string
+}
+
+// Feed is a page of the atom feed of a stream.
+//
+// The fields of the atom feed, including the entries and HeadOfStream, which is
+// true when the page is at the head of the stream, are available through the
+// embedded *atom.Feed. ETag is the ETag returned by the server with the page,
+// which is empty if the server did not return one.
+type Feed struct {
+	*atom.Feed
+	Links FeedLinks
+	ETag  string
+}
+
+// newFeed returns a *Feed for the atom feed.
+func newFeed(f *atom.Feed, etag string) *Feed {
+	ret := &Feed{Feed: f, ETag: etag}
+	for _, l := range f.Link {
+		switch l.Rel {
+		case "self":
+			ret.Links.Self = l.Href
+		case "first":
+			ret.Links.First = l.Href
+		case "last":
+			ret.Links.Last = l.Href
+		case "next":
+			ret.Links.Next = l.Href
+		case "previous":
+			ret.Links.Previous = l.Href
+		case "metadata":
+			ret.Links.Metadata = l.Href
+		}
+	}
+	return ret
+}
+
+// FollowNext reads the feed page linked as next from the page provided, which
+// is the page of older events.
+//
+// If the page has no next link an *ErrNoMoreEvents is returned.
+func (c *Client) FollowNext(f *Feed) (*Feed, *Response, error) {
+	return c.follow(f.Links.Next)
+}
+
+// FollowPrevious reads the feed page linked as previous from the page
+// provided, which is the page of more recent events.
+//
+// If the page has no previous link an *ErrNoMoreEvents is returned.
+func (c *Client) FollowPrevious(f *Feed) (*Feed, *Response, error) {
+	return c.follow(f.Links.Previous)
+}
+
+func (c *Client) follow(url string) (*Feed, *Response, error) {
+	if url == "" {
+		return nil, nil, &ErrNoMoreEvents{}
+	}
+	return c.ReadFeed(url)
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&FeedSuite{})
+
+type FeedSuite struct{}
+
+func (s *FeedSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *FeedSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *FeedSuite) TestReadFeedReturnsTypedLinks(c *C) {
+	stream := "feed-links"
+	es := CreateTestEvents(50, stream, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	u := fmt.Sprintf("%s/streams/%s", server.URL, stream)
+	f, _, err := client.ReadFeed(fmt.Sprintf("%s/20/forward/20", u))
+	c.Assert(err, IsNil)
+
+	c.Assert(f.Links, DeepEquals, FeedLinks{
+		Self:     u,
+		First:    fmt.Sprintf("%s/head/backward/20", u),
+		Last:     fmt.Sprintf("%s/0/forward/20", u),
+		Next:     fmt.Sprintf("%s/19/backward/20", u),
+		Previous: fmt.Sprintf("%s/40/forward/20", u),
+		Metadata: fmt.Sprintf("%s/metadata", u),
+	})
+	c.Assert(f.HeadOfStream, Equals, false)
+	c.Assert(f.Entry, HasLen, 20)
+}
+
+func (s *FeedSuite) TestFollowTraversesPages(c *C) {
+	stream := "feed-follow"
+	es := CreateTestEvents(45, stream, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	f, _, err := client.ReadFeed(fmt.Sprintf("%s/streams/%s/head/backward/20", server.URL, stream))
+	c.Assert(err, IsNil)
+	c.Assert(f.HeadOfStream, Equals, true)
+
+	pages := 1
+	for {
+		next, _, err := client.FollowNext(f)
+		if _, ok := err.(*ErrNoMoreEvents); ok {
+			break
+		}
+		c.Assert(err, IsNil)
+		f = next
+		pages++
+	}
+	c.Assert(pages, Equals, 3)
+	c.Assert(f.Links.Next, Equals, "")
+
+	prev, _, err := client.FollowPrevious(f)
+	c.Assert(err, IsNil)
+	c.Assert(prev.Entry[len(prev.Entry)-1].Title, Equals, fmt.Sprintf("5@%s", stream))
+}
+
+func (s *FeedSuite) TestReadFeedReturnsETag(c *C) {
+	stream := "feed-etag"
+	es := CreateTestEvents(2, stream, server.URL, "FooEvent")
+	path := fmt.Sprintf("/streams/%s/head/backward/20", stream)
+	f, _ := CreateTestFeed(es, server.URL+path)
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1;-1296467268"`)
+		fmt.Fprint(w, f.PrettyPrint())
+	})
+
+	feed, _, err := client.ReadFeed(path)
+	c.Assert(err, IsNil)
+	c.Assert(feed.ETag, Equals, `"1;-1296467268"`)
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CachedPage is a feed page held in a FeedCache.
+//
+// Body is the body of the response. ETag is the entity tag of the page, used to
+// revalidate the page once it has expired. Expires is the time until which the
+// page can be used without revalidating it.
+type CachedPage struct {
+	Body    []byte
+	ETag    string
+	Expires time.Time
+}
+
+// FeedCache stores feed pages by URL.
+//
+// Implementations must be safe for concurrent use.
+type FeedCache interface {
+	Get(url string) (*CachedPage, bool)
+	Add(url string, page *CachedPage)
+}
+
+// SetFeedCache sets the cache used to store feed pages. A nil cache disables
+// caching, which is the default.
+//
+// The eventstore marks feed pages that are full, and so can never change, as
+// cacheable for a long time, and these pages are returned from the cache without
+// a request to the server. Pages that may change, such as the head of a stream,
+// are revalidated with a conditional request using their ETag, and the cached
+// page is used if the server reports that it has not been modified.
+//
+// When a page is returned from the cache without a request the *Response
+// returned from ReadFeed is nil.
+func (c *Client) SetFeedCache(cache FeedCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.feedCache = cache
+}
+
+// getFeedCache returns the feed cache of the client or nil.
+func (c *Client) getFeedCache() FeedCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.feedCache
+}
+
+// newCachedPage returns a CachedPage for the response body if the response
+// allows it to be cached, or nil.
+func newCachedPage(resp *http.Response, body []byte, now time.Time) *CachedPage {
+	page := &CachedPage{
+		Body: body,
+		ETag: resp.Header.Get("ETag"),
+	}
+
+	maxAge, noStore, noCache := cacheControl(resp.Header)
+	if noStore {
+		return nil
+	}
+	if maxAge > 0 && !noCache {
+		page.Expires = now.Add(maxAge)
+	}
+
+	if page.ETag == "" && page.Expires.IsZero() {
+		return nil
+	}
+	return page
+}
+
+// lruFeedCache is a FeedCache that holds a limited number of pages and evicts
+// the least recently used page.
+type lruFeedCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	pages map[string]*list.Element
+}
+
+type lruEntry struct {
+	url  string
+	page *CachedPage
+}
+
+// NewLRUFeedCache returns an in memory FeedCache that holds up to size pages.
+func NewLRUFeedCache(size int) FeedCache {
+	return &lruFeedCache{
+		size:  size,
+		order: list.New(),
+		pages: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the page cached for the url.
+func (l *lruFeedCache) Get(url string) (*CachedPage, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.pages[url]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return el.Value.(*lruEntry).page, true
+}
+
+// Add caches the page for the url, evicting the least recently used page if
+// the cache is full.
+func (l *lruFeedCache) Add(url string, page *CachedPage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.pages[url]; ok {
+		el.Value.(*lruEntry).page = page
+		l.order.MoveToFront(el)
+		return
+	}
+	l.pages[url] = l.order.PushFront(&lruEntry{url: url, page: page})
+	for l.order.Len() > l.size {
+		el := l.order.Back()
+		l.order.Remove(el)
+		delete(l.pages, el.Value.(*lruEntry).url)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&FeedCacheSuite{})
+
+type FeedCacheSuite struct{}
+
+func (s *FeedCacheSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *FeedCacheSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// serveCacheableFeed serves a feed page with the cache control header
+// provided and an ETag. It returns a pointer to the number of full responses
+// and the number of not modified responses.
+func serveCacheableFeed(c *C, path, cacheControl string) (full, notModified *int) {
+	full, notModified = new(int), new(int)
+	es := CreateTestEvents(3, "cached-stream", server.URL, "Foo")
+	f, err := CreateTestFeed(es, server.URL+path)
+	c.Assert(err, IsNil)
+
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"page-etag"` {
+			*notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		*full++
+		w.Header().Set("ETag", `"page-etag"`)
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Write([]byte(f.PrettyPrint()))
+	})
+	return full, notModified
+}
+
+func (s *FeedCacheSuite) TestImmutablePageIsServedFromCache(c *C) {
+	path := "/streams/cached-stream/0/forward/3"
+	full, notModified := serveCacheableFeed(c, path, "max-age=31536000, public")
+	client.SetFeedCache(NewLRUFeedCache(10))
+
+	f1, resp, err := client.ReadFeed(path)
+	c.Assert(err, IsNil)
+	c.Assert(resp, NotNil)
+	f2, resp, err := client.ReadFeed(path)
+	c.Assert(err, IsNil)
+	c.Assert(resp, IsNil)
+
+	c.Assert(f2, DeepEquals, f1)
+	c.Assert(*full, Equals, 1)
+	c.Assert(*notModified, Equals, 0)
+}
+
+func (s *FeedCacheSuite) TestHeadPageIsRevalidated(c *C) {
+	path := "/streams/cached-stream/head/backward/3"
+	full, notModified := serveCacheableFeed(c, path, "max-age=0, no-cache, must-revalidate")
+	client.SetFeedCache(NewLRUFeedCache(10))
+
+	f1, _, err := client.ReadFeed(path)
+	c.Assert(err, IsNil)
+	f2, resp, err := client.ReadFeed(path)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusNotModified)
+
+	c.Assert(f2, DeepEquals, f1)
+	c.Assert(*full, Equals, 1)
+	c.Assert(*notModified, Equals, 1)
+}
+
+func (s *FeedCacheSuite) TestPagesAreNotCachedWithoutCache(c *C) {
+	path := "/streams/cached-stream/0/forward/3"
+	full, _ := serveCacheableFeed(c, path, "max-age=31536000, public")
+
+	client.ReadFeed(path)
+	client.ReadFeed(path)
+	c.Assert(*full, Equals, 2)
+}
+
+func (s *FeedCacheSuite) TestLRUFeedCacheEvictsLeastRecentlyUsed(c *C) {
+	cache := NewLRUFeedCache(2)
+	cache.Add("a", &CachedPage{ETag: "a"})
+	cache.Add("b", &CachedPage{ETag: "b"})
+	cache.Get("a")
+	cache.Add("c", &CachedPage{ETag: "c"})
+
+	_, ok := cache.Get("b")
+	c.Assert(ok, Equals, false)
+	p, ok := cache.Get("a")
+	c.Assert(ok, Equals, true)
+	c.Assert(p.ETag, Equals, "a")
+	_, ok = cache.Get("c")
+	c.Assert(ok, Equals, true)
+}
+
+func (s *FeedCacheSuite) TestNewCachedPage(c *C) {
+	now := time.Now()
+	resp := func(etag, cc string) *http.Response {
+		r := &http.Response{Header: http.Header{}}
+		if etag != "" {
+			r.Header.Set("ETag", etag)
+		}
+		r.Header.Set("Cache-Control", cc)
+		return r
+	}
+
+	p := newCachedPage(resp(`"x"`, "max-age=60"), nil, now)
+	c.Assert(p.Expires, Equals, now.Add(time.Minute))
+
+	p = newCachedPage(resp(`"x"`, "max-age=60, no-cache"), nil, now)
+	c.Assert(p.Expires.IsZero(), Equals, true)
+	c.Assert(p.ETag, Equals, `"x"`)
+
+	c.Assert(newCachedPage(resp(`"x"`, "no-store"), nil, now), IsNil)
+	c.Assert(newCachedPage(resp("", "max-age=0"), nil, now), IsNil)
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"strings"
+
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+)
+
+// FeedFormat is the format in which feed pages are requested from the server.
+type FeedFormat string
+
+const (
+	// FeedDefault requests feed pages as FeedXML, or as FeedJSON if
+	// FeatureJSONFeeds or FeatureEmbedBody is enabled.
+	FeedDefault FeedFormat = ""
+
+	// FeedXML requests feed pages as application/atom+xml.
+	FeedXML FeedFormat = "application/atom+xml"
+
+	// FeedJSON requests feed pages as application/vnd.eventstore.atom+json.
+	FeedJSON FeedFormat = "application/vnd.eventstore.atom+json"
+
+	// FeedAny accepts feed pages in either format, preferring XML. It is
+	// useful behind proxies that rewrite or restrict the Accept header.
+	FeedAny FeedFormat = "application/atom+xml, application/vnd.eventstore.atom+json;q=0.9"
+)
+
+// SetFeedFormat sets the format in which feed pages are requested.
+//
+// Some proxies and older servers serve only one of the formats. Whatever the
+// format requested, the page returned is parsed according to its Content-Type,
+// so a server that ignores the Accept header is still read correctly.
+func (c *Client) SetFeedFormat(f FeedFormat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.feedFormat = f
+}
+
+// feedAccept returns the Accept header for feed page requests.
+func (c *Client) feedAccept() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return string(c.acceptedFeedFormat())
+}
+
+// embedBody returns true if event bodies are requested to be embedded in feed
+// pages. Bodies are only embedded in JSON feed pages.
+func (c *Client) embedBody() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.embedBodySupported() && c.acceptedFeedFormat() == FeedJSON
+}
+
+// embedBodySupported returns true if FeatureEmbedBody is enabled and the
+// server is not known from Ping to lack support for embed=body. The caller
+// must hold c.mu.
+func (c *Client) embedBodySupported() bool {
+	if !c.features[FeatureEmbedBody] {
+		return false
+	}
+	return c.serverInfo == nil || c.serverInfo.Supports(FeatureEmbedBody)
+}
+
+// acceptedFeedFormat returns the format in which feed pages are requested. The
+// caller must hold c.mu.
+func (c *Client) acceptedFeedFormat() FeedFormat {
+	if c.feedFormat != FeedDefault {
+		return c.feedFormat
+	}
+	if c.features[FeatureJSONFeeds] || c.embedBodySupported() {
+		return FeedJSON
+	}
+	return FeedXML
+}
+
+// parseFeed decodes a feed page in the format given by contentType. If the
+// content type does not identify the format, as for pages read from a feed
+// cache, it is detected from the body.
+func parseFeed(contentType string, body []byte) (*atom.Feed, error) {
+	isJSON := false
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mt, "json"):
+		isJSON = true
+	case strings.HasSuffix(mt, "xml"):
+	default:
+		trimmed := bytes.TrimSpace(body)
+		isJSON = len(trimmed) > 0 && trimmed[0] == '{'
+	}
+
+	f := &atom.Feed{}
+	if isJSON {
+		if err := json.Unmarshal(body, f); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if err := xml.Unmarshal(body, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&FeedFormatSuite{})
+
+type FeedFormatSuite struct{}
+
+func (s *FeedFormatSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *FeedFormatSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *FeedFormatSuite) TestFeedAcceptHeader(c *C) {
+	c.Assert(client.feedAccept(), Equals, "application/atom+xml")
+	client.EnableExperimental(FeatureJSONFeeds)
+	c.Assert(client.feedAccept(), Equals, "application/vnd.eventstore.atom+json")
+	client.SetFeedFormat(FeedXML)
+	c.Assert(client.feedAccept(), Equals, "application/atom+xml")
+	client.SetFeedFormat(FeedAny)
+	c.Assert(client.feedAccept(), Equals, string(FeedAny))
+}
+
+func (s *FeedFormatSuite) TestJSONFeedRoundTrip(c *C) {
+	es := CreateTestEvents(3, "json-feed", server.URL, "Foo")
+	want, err := CreateTestFeed(es, server.URL+"/streams/json-feed/head/backward/3")
+	c.Assert(err, IsNil)
+
+	b, err := json.Marshal(want)
+	c.Assert(err, IsNil)
+	got, err := parseFeed("application/vnd.eventstore.atom+json; charset=utf-8", b)
+	c.Assert(err, IsNil)
+	c.Assert(got.PrettyPrint(), Equals, want.PrettyPrint())
+}
+
+func (s *FeedFormatSuite) TestFormatIsDetectedFromBody(c *C) {
+	es := CreateTestEvents(2, "detect", server.URL, "Foo")
+	want, _ := CreateTestFeed(es, server.URL+"/streams/detect/head/backward/2")
+
+	b, _ := json.Marshal(want)
+	got, err := parseFeed("", b)
+	c.Assert(err, IsNil)
+	c.Assert(got.PrettyPrint(), Equals, want.PrettyPrint())
+
+	got, err = parseFeed("text/plain", []byte(want.PrettyPrint()))
+	c.Assert(err, IsNil)
+	c.Assert(got.PrettyPrint(), Equals, want.PrettyPrint())
+}
+
+func (s *FeedFormatSuite) TestFeedIsParsedByContentType(c *C) {
+	es := CreateTestEvents(2, "quirky", server.URL, "Foo")
+	f, _ := CreateTestFeed(es, server.URL+"/streams/quirky/head/backward/2")
+
+	// The server ignores the Accept header and only serves JSON.
+	mux.HandleFunc("/streams/quirky/head/backward/2", func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("Accept"), Equals, "application/atom+xml")
+		w.Header().Set("Content-Type", "application/vnd.eventstore.atom+json")
+		json.NewEncoder(w).Encode(f)
+	})
+
+	got, _, err := client.ReadFeed(fmt.Sprintf("%s/streams/quirky/head/backward/2", server.URL))
+	c.Assert(err, IsNil)
+	c.Assert(got.Entry, HasLen, 2)
+	c.Assert(got.Entry[0].Summary.Body, Equals, "Foo")
+	c.Assert(got.HeadOfStream, Equals, f.HeadOfStream)
+}
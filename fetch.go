@@ -0,0 +1,128 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+)
+
+// SetFetchConcurrency sets the number of events on a feed page that are
+// fetched concurrently.
+//
+// Feed pages contain links to their events and each event is read with a
+// separate request. By default the events are fetched one at a time as Next()
+// is called. When the concurrency is greater than 1 all of the events on a page
+// are fetched when the page is loaded, using up to n concurrent requests, and
+// are then returned in order. This reduces the time taken to read a stream over
+// high latency connections. It also applies to readers that are prefetching.
+//
+// Any client wide concurrency limit set with Client.SetConcurrencyLimit still
+// applies.
+func (s *StreamReader) SetFetchConcurrency(n int) {
+	s.stopPrefetch()
+	s.fetchConcurrency = n
+}
+
+// fetchEntries reads the events linked from the entries using up to workers
+// concurrent requests. The events and errors returned are in the same order
+// as the entries. The events of entries skipped by types are not read and are
+// returned as nil.
+func (c *Client) fetchEntries(ctx context.Context, entries []*atom.Entry, workers int, types eventTypes) ([]*EventResponse, []error) {
+	events := make([]*EventResponse, len(entries))
+	errs := make([]error, len(entries))
+	if workers > len(entries) {
+		workers = len(entries)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				events[i], errs[i] = c.entryEvent(ctx, entries[i])
+			}
+		}()
+	}
+	for i, e := range entries {
+		if !types.skipsEntry(e) {
+			indexes <- i
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return events, errs
+}
+
+// entryEvent returns the event linked from a feed page entry. If the body of
+// the event is embedded in the entry the event is taken from the entry,
+// otherwise it is read with a request.
+func (c *Client) entryEvent(ctx context.Context, entry *atom.Entry) (*EventResponse, error) {
+	url := strings.TrimRight(entry.Link[1].Href, "/")
+	if er := embeddedEvent(entry); er != nil {
+		if err := c.checkEvent(url, er); err != nil {
+			return nil, err
+		}
+		return er, nil
+	}
+	er, _, err := c.getEvent(ctx, url)
+	return er, err
+}
+
+// embeddedEvent returns the event embedded in a feed page entry read with
+// embed=body, or nil if the entry has no body or the body is not JSON.
+func embeddedEvent(entry *atom.Entry) *EventResponse {
+	b := entry.Body
+	if b == nil || !b.IsJSON {
+		return nil
+	}
+
+	d := embeddedJSON(b.Data)
+	m := embeddedJSON(b.MetaData)
+	ev := &Event{
+		EventStreamID: b.StreamID,
+		EventNumber:   b.EventNumber,
+		EventType:     b.EventType,
+		EventID:       b.EventID,
+		Data:          &d,
+		MetaData:      &m,
+	}
+	for _, l := range entry.Link {
+		ev.Links = append(ev.Links, Link{URI: l.Href, Relation: l.Rel})
+	}
+	readEnvelope(ev)
+
+	er := &EventResponse{
+		Title:   entry.Title,
+		ID:      entry.ID,
+		Updated: TimeStr(entry.Updated),
+		Event:   ev,
+	}
+	if t, err := er.Updated.Time(); err == nil {
+		er.Updated = FormatTime(t)
+	}
+	if entry.Summary != nil {
+		er.Summary = entry.Summary.Body
+	}
+	return er
+}
+
+// embeddedJSON returns the JSON of embedded event data or metadata. The server
+// embeds JSON as a string containing the JSON text.
+func embeddedJSON(raw json.RawMessage) json.RawMessage {
+	var s string
+	if len(raw) > 0 && raw[0] == '"' && json.Unmarshal(raw, &s) == nil {
+		return json.RawMessage(s)
+	}
+	return raw
+}
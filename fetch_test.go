@@ -0,0 +1,138 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&FetchSuite{})
+
+type FetchSuite struct{}
+
+func (s *FetchSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *FetchSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// inflightCounter serves the events through a simulator and records the
+// maximum number of event requests in progress at once. Requests for the
+// event numbers in fail are failed once.
+type inflightCounter struct {
+	sync.Mutex
+	sim      *AtomFeedSimulator
+	inflight int
+	max      int
+	fail     map[string]bool
+}
+
+var eventPath = regexp.MustCompile(`/streams/[^/]+/(\d+)/?$`)
+
+func (h *inflightCounter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m := eventPath.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		h.sim.ServeHTTP(w, r)
+		return
+	}
+
+	h.Lock()
+	if h.fail[m[1]] {
+		delete(h.fail, m[1])
+		h.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	h.inflight++
+	if h.inflight > h.max {
+		h.max = h.inflight
+	}
+	h.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+	h.sim.ServeHTTP(w, r)
+
+	h.Lock()
+	h.inflight--
+	h.Unlock()
+}
+
+// readAll reads the stream until the head and returns the event numbers read.
+func readAll(c *C, reader *StreamReader) []int {
+	got := []int{}
+	for reader.Next() {
+		if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+			break
+		}
+		c.Assert(reader.Err(), IsNil)
+		got = append(got, reader.EventResponse().Event.EventNumber)
+	}
+	return got
+}
+
+func sequence(n int) []int {
+	ret := make([]int, n)
+	for i := range ret {
+		ret[i] = i
+	}
+	return ret
+}
+
+func (s *FetchSuite) TestConcurrentFetchReturnsEventsInOrder(c *C) {
+	es := CreateTestEvents(45, "fetch-1", server.URL, "Foo")
+	h := &inflightCounter{sim: newTestSimulator(es, nil)}
+	mux.Handle("/", h)
+
+	reader := client.NewStreamReader("fetch-1")
+	reader.SetFetchConcurrency(5)
+	c.Assert(readAll(c, reader), DeepEquals, sequence(45))
+	c.Assert(h.max > 1, Equals, true)
+	c.Assert(h.max <= 5, Equals, true)
+}
+
+func (s *FetchSuite) TestConcurrentFetchWithPrefetch(c *C) {
+	es := CreateTestEvents(45, "fetch-2", server.URL, "Foo")
+	h := &inflightCounter{sim: newTestSimulator(es, nil)}
+	mux.Handle("/", h)
+
+	reader := client.NewStreamReader("fetch-2")
+	reader.Prefetch(10)
+	reader.SetFetchConcurrency(4)
+	defer reader.Close()
+	c.Assert(readAll(c, reader), DeepEquals, sequence(45))
+	c.Assert(h.max > 1, Equals, true)
+	c.Assert(h.max <= 4, Equals, true)
+}
+
+func (s *FetchSuite) TestFailedConcurrentFetchIsFetchedAgain(c *C) {
+	es := CreateTestEvents(10, "fetch-3", server.URL, "Foo")
+	h := &inflightCounter{sim: newTestSimulator(es, nil), fail: map[string]bool{"3": true}}
+	mux.Handle("/", h)
+
+	reader := client.NewStreamReader("fetch-3")
+	reader.SetFetchConcurrency(5)
+
+	got := []int{}
+	errors := 0
+	for reader.Next() {
+		if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+			break
+		}
+		if reader.Err() != nil {
+			errors++
+			continue
+		}
+		got = append(got, reader.EventResponse().Event.EventNumber)
+	}
+	c.Assert(got, DeepEquals, sequence(10))
+	c.Assert(errors, Equals, 0)
+}
@@ -67,16 +67,29 @@ func setup() {
 		client:  http.DefaultClient,
 		baseURL: baseURL,
 		headers: make(map[string]string),
+		busy:    &busyGate{},
 	}
 }
 
 func setupSimulator(es []*Event, m *Event) {
+	mux.Handle("/", newTestSimulator(es, m))
+}
+
+func newTestSimulator(es []*Event, m *Event) *AtomFeedSimulator {
 	u, _ := url.Parse(server.URL)
 	handler, err := NewAtomFeedSimulator(es, u, m, len(es))
 	if err != nil {
 		log.Fatal(err)
 	}
-	mux.Handle("/", handler)
+	return handler
+}
+
+// eventually polls cond until it returns true or a second has elapsed.
+func eventually(cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
 }
 
 func teardown() {
@@ -1134,5 +1147,11 @@ func resolveEvent(events []*Event, url string) (*Event, error) {
 	if err != nil {
 		return nil, err
 	}
-	return events[i], nil
+	// The events of a truncated stream do not start at event number 0.
+	for _, e := range events {
+		if e.EventNumber == int(i) {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("Event %d not found", i)
 }
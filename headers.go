@@ -0,0 +1,76 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"net/http"
+)
+
+// fixedHeaders are the headers set by the client for each request that
+// per-request headers cannot override.
+var fixedHeaders = map[string]bool{
+	"Content-Type":       true,
+	"Content-Encoding":   true,
+	"Es-Expectedversion": true,
+}
+
+type requestHeadersKey struct{}
+
+// WithRequestHeaders returns a copy of ctx carrying headers that are sent with
+// every request made with the context, such as a tenant id, a tracing header
+// or an API gateway key that differs per operation.
+//
+// The headers are added to those set on the client with SetHeader, replacing
+// any with the same name, and to those already carried by ctx. The
+// Content-Type, Content-Encoding and ES-ExpectedVersion headers are set by the
+// client and cannot be overridden.
+func WithRequestHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := make(map[string]string)
+	for k, v := range requestHeaders(ctx) {
+		merged[k] = v
+	}
+	for k, v := range headers {
+		merged[http.CanonicalHeaderKey(k)] = v
+	}
+	return context.WithValue(ctx, requestHeadersKey{}, merged)
+}
+
+// requestHeaders returns the headers carried by ctx.
+func requestHeaders(ctx context.Context) map[string]string {
+	h, _ := ctx.Value(requestHeadersKey{}).(map[string]string)
+	return h
+}
+
+// setRequestHeaders sets the headers carried by the context of the request.
+func setRequestHeaders(req *http.Request) {
+	for k, v := range requestHeaders(req.Context()) {
+		if !fixedHeaders[k] {
+			req.Header.Set(k, v)
+		}
+	}
+}
+
+// WithReadHeaders sends headers with every request made by the read. See
+// WithRequestHeaders.
+func WithReadHeaders(headers map[string]string) ReadOption {
+	return func(o *readOptions) {
+		if o.headers == nil {
+			o.headers = make(map[string]string)
+		}
+		for k, v := range headers {
+			o.headers[k] = v
+		}
+	}
+}
+
+// context returns the context for the requests of the read.
+func (o *readOptions) context() context.Context {
+	if len(o.headers) == 0 {
+		return context.Background()
+	}
+	return WithRequestHeaders(context.Background(), o.headers)
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&HeadersSuite{})
+
+type HeadersSuite struct{}
+
+func (s *HeadersSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *HeadersSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// recordHeader records the value of the header sent with each request, keyed
+// by method and path.
+func recordHeader(name string) func() map[string]string {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	client.Use(func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		mu.Lock()
+		seen[req.Method+" "+req.URL.Path] = req.Header.Get(name)
+		mu.Unlock()
+		return next(req)
+	})
+	return func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+}
+
+func (s *HeadersSuite) TestRequestHeadersAreMerged(c *C) {
+	ctx := WithRequestHeaders(context.Background(), map[string]string{"x-tenant": "a", "X-Trace": "1"})
+	ctx = WithRequestHeaders(ctx, map[string]string{"X-Tenant": "b"})
+	c.Assert(requestHeaders(ctx), DeepEquals, map[string]string{"X-Tenant": "b", "X-Trace": "1"})
+	c.Assert(requestHeaders(context.Background()), IsNil)
+}
+
+func (s *HeadersSuite) TestReadHeadersAreSentWithEveryRequest(c *C) {
+	es := CreateTestEvents(3, "tenanted", server.URL, "Foo")
+	setupSimulator(es, nil)
+	client.SetHeader("X-Tenant", "default")
+	seen := recordHeader("X-Tenant")
+
+	reader := client.NewStreamReader("tenanted", WithReadHeaders(map[string]string{"X-Tenant": "acme"}))
+	for i := 0; i < 3; i++ {
+		c.Assert(reader.Next(), Equals, true)
+		c.Assert(reader.Err(), IsNil)
+	}
+	_, _, err := client.ReadFeedBackward("tenanted", -1)
+	c.Assert(err, IsNil)
+
+	got := seen()
+	c.Assert(got["GET /streams/tenanted/0"], Equals, "acme")
+	c.Assert(got["GET /streams/tenanted/2"], Equals, "acme")
+	c.Assert(got["GET /streams/tenanted/0/forward/20"], Equals, "acme")
+	c.Assert(got["GET /streams/tenanted/head/backward/20"], Equals, "default")
+}
+
+func (s *HeadersSuite) TestWriteAndDeleteHeaders(c *C) {
+	mux.HandleFunc("/streams/tenanted", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			c.Check(r.Header.Get("Content-Type"), Equals, "application/vnd.eventstore.events+json")
+			w.Header().Set("Location", server.URL+"/streams/tenanted/0")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	seen := recordHeader("X-Tenant")
+	ctx := WithRequestHeaders(context.Background(), map[string]string{
+		"X-Tenant":     "acme",
+		"Content-Type": "text/plain",
+	})
+
+	w := client.NewStreamWriter("tenanted")
+	c.Assert(w.AppendContext(ctx, ExpectAny, NewEvent("", "Foo", nil, nil)), IsNil)
+	c.Assert(seen()["POST /streams/tenanted"], Equals, "acme")
+
+	_, err := client.DeleteStreamContext(ctx, "tenanted", false)
+	c.Assert(err, IsNil)
+	c.Assert(seen()["DELETE /streams/tenanted"], Equals, "acme")
+}
@@ -2,6 +2,7 @@
 package atom
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"strings"
 	"time"
@@ -63,6 +64,19 @@ type Entry struct {
 	Author    *Person `xml:"author"`
 	Summary   *Text   `xml:"summary"`
 	Content   *Text   `xml:"content"`
+	Body      *Body   `xml:"-"`
+}
+
+// Body is the event embedded in an entry of a JSON feed page requested with
+// embed=body. Data and MetaData hold the JSON of the event data and metadata.
+type Body struct {
+	EventID     string
+	EventType   string
+	EventNumber int
+	StreamID    string
+	IsJSON      bool
+	Data        json.RawMessage
+	MetaData    json.RawMessage
 }
 
 // Link represents a Link entry in the feed.
@@ -0,0 +1,154 @@
+package atom
+
+import (
+	"encoding/json"
+)
+
+// jsonFeed is the application/vnd.eventstore.atom+json representation of a
+// feed page.
+type jsonFeed struct {
+	Title        string       `json:"title"`
+	ID           string       `json:"id"`
+	Updated      TimeStr      `json:"updated"`
+	StreamID     string       `json:"streamId,omitempty"`
+	Author       *jsonPerson  `json:"author,omitempty"`
+	HeadOfStream bool         `json:"headOfStream"`
+	Links        []jsonLink   `json:"links"`
+	Entries      []*jsonEntry `json:"entries"`
+}
+
+type jsonEntry struct {
+	Title       string          `json:"title"`
+	ID          string          `json:"id"`
+	Updated     TimeStr         `json:"updated"`
+	Author      *jsonPerson     `json:"author,omitempty"`
+	Summary     string          `json:"summary"`
+	Links       []jsonLink      `json:"links"`
+	EventID     string          `json:"eventId,omitempty"`
+	EventType   string          `json:"eventType,omitempty"`
+	EventNumber int             `json:"eventNumber,omitempty"`
+	StreamID    string          `json:"streamId,omitempty"`
+	IsJSON      bool            `json:"isJson,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	MetaData    json.RawMessage `json:"metaData,omitempty"`
+}
+
+type jsonLink struct {
+	URI      string `json:"uri"`
+	Relation string `json:"relation"`
+}
+
+type jsonPerson struct {
+	Name string `json:"name"`
+}
+
+// MarshalJSON encodes the feed in the application/vnd.eventstore.atom+json
+// format.
+func (f *Feed) MarshalJSON() ([]byte, error) {
+	j := &jsonFeed{
+		Title:        f.Title,
+		ID:           f.ID,
+		Updated:      f.Updated,
+		StreamID:     f.StreamID,
+		Author:       toJSONPerson(f.Author),
+		HeadOfStream: f.HeadOfStream,
+		Links:        toJSONLinks(f.Link),
+		Entries:      make([]*jsonEntry, len(f.Entry)),
+	}
+	for i, e := range f.Entry {
+		je := &jsonEntry{
+			Title:   e.Title,
+			ID:      e.ID,
+			Updated: e.Updated,
+			Author:  toJSONPerson(e.Author),
+			Links:   toJSONLinks(e.Link),
+		}
+		if e.Summary != nil {
+			je.Summary = e.Summary.Body
+		}
+		if b := e.Body; b != nil {
+			je.EventID = b.EventID
+			je.EventType = b.EventType
+			je.EventNumber = b.EventNumber
+			je.StreamID = b.StreamID
+			je.IsJSON = b.IsJSON
+			je.Data = b.Data
+			je.MetaData = b.MetaData
+		}
+		j.Entries[i] = je
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a feed in the application/vnd.eventstore.atom+json
+// format.
+func (f *Feed) UnmarshalJSON(b []byte) error {
+	j := &jsonFeed{}
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	*f = Feed{
+		Title:        j.Title,
+		ID:           j.ID,
+		StreamID:     j.StreamID,
+		HeadOfStream: j.HeadOfStream,
+		Link:         fromJSONLinks(j.Links),
+		Updated:      j.Updated,
+		Author:       fromJSONPerson(j.Author),
+	}
+	for _, je := range j.Entries {
+		e := &Entry{
+			Title:   je.Title,
+			ID:      je.ID,
+			Link:    fromJSONLinks(je.Links),
+			Updated: je.Updated,
+			Author:  fromJSONPerson(je.Author),
+		}
+		if je.Summary != "" {
+			e.Summary = &Text{Body: je.Summary}
+		}
+		if je.EventType != "" && je.Data != nil {
+			e.Body = &Body{
+				EventID:     je.EventID,
+				EventType:   je.EventType,
+				EventNumber: je.EventNumber,
+				StreamID:    je.StreamID,
+				IsJSON:      je.IsJSON,
+				Data:        je.Data,
+				MetaData:    je.MetaData,
+			}
+		}
+		f.Entry = append(f.Entry, e)
+	}
+	return nil
+}
+
+func toJSONLinks(ls []Link) []jsonLink {
+	ret := make([]jsonLink, len(ls))
+	for i, l := range ls {
+		ret[i] = jsonLink{URI: l.Href, Relation: l.Rel}
+	}
+	return ret
+}
+
+func fromJSONLinks(ls []jsonLink) []Link {
+	ret := make([]Link, len(ls))
+	for i, l := range ls {
+		ret[i] = Link{Rel: l.Relation, Href: l.URI}
+	}
+	return ret
+}
+
+func toJSONPerson(p *Person) *jsonPerson {
+	if p == nil {
+		return nil
+	}
+	return &jsonPerson{Name: p.Name}
+}
+
+func fromJSONPerson(p *jsonPerson) *Person {
+	if p == nil {
+		return nil
+	}
+	return &Person{Name: p.Name}
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import "strings"
+
+// ForEachEvent reads the events in a stream page by page and calls fn for each
+// event in order.
+//
+// direction is "forward" or "backward". Reading forward begins at the event
+// number from and continues to the head of the stream. Reading backward begins
+// at the event number from, or at the head of the stream if from is negative,
+// and continues to the start of the stream.
+//
+// Only one feed page is held in memory at a time, so ForEachEvent can be used to
+// read streams of any length. If fn returns an error the iteration stops and the
+// error is returned. If the stream does not exist an *ErrNotFound is returned.
+func (c *Client) ForEachEvent(stream string, from int, direction string, fn func(*EventResponse) error) error {
+	if direction == "forward" && from < 0 {
+		from = 0
+	}
+
+	url, err := c.GetFeedPath(stream, direction, from, defaultPageSize)
+	if err != nil {
+		return err
+	}
+
+	forward := direction == "forward"
+	for url != "" {
+		f, _, err := c.ReadFeed(url)
+		if err != nil {
+			return err
+		}
+		if len(f.Entry) == 0 {
+			return nil
+		}
+
+		// Entries are ordered most recent first.
+		for i := range f.Entry {
+			entry := f.Entry[i]
+			if forward {
+				entry = f.Entry[len(f.Entry)-1-i]
+			}
+
+			e, _, err := c.GetEvent(strings.TrimRight(entry.Link[1].Href, "/"))
+			if err != nil {
+				return err
+			}
+			if e == nil {
+				continue
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+
+		rel := "next"
+		if forward {
+			rel = "previous"
+		}
+		url = ""
+		if l := f.GetLink(rel); l != nil {
+			url = l.Href
+		}
+	}
+	return nil
+}
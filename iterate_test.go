@@ -0,0 +1,87 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"errors"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&IterateSuite{})
+
+type IterateSuite struct{}
+
+func (s *IterateSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *IterateSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *IterateSuite) TestForEachEventForward(c *C) {
+	es := CreateTestEvents(45, "iterated", server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	got := []int{}
+	err := client.ForEachEvent("iterated", 3, "forward", func(e *EventResponse) error {
+		got = append(got, e.Event.EventNumber)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 42)
+	for i, n := range got {
+		c.Assert(n, Equals, i+3)
+	}
+}
+
+func (s *IterateSuite) TestForEachEventBackwardFromHead(c *C) {
+	es := CreateTestEvents(45, "iterated", server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	got := []int{}
+	err := client.ForEachEvent("iterated", -1, "backward", func(e *EventResponse) error {
+		got = append(got, e.Event.EventNumber)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 45)
+	for i, n := range got {
+		c.Assert(n, Equals, 44-i)
+	}
+}
+
+func (s *IterateSuite) TestForEachEventStopsOnError(c *C) {
+	es := CreateTestEvents(45, "iterated", server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	stop := errors.New("stop")
+	count := 0
+	err := client.ForEachEvent("iterated", 0, "forward", func(e *EventResponse) error {
+		count++
+		if count == 25 {
+			return stop
+		}
+		return nil
+	})
+	c.Assert(err, Equals, stop)
+	c.Assert(count, Equals, 25)
+}
+
+func (s *IterateSuite) TestForEachEventMissingStream(c *C) {
+	mux.HandleFunc("/streams/missing/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	err := client.ForEachEvent("missing", 0, "forward", func(e *EventResponse) error { return nil })
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+}
+
+func (s *IterateSuite) TestForEachEventInvalidDirection(c *C) {
+	err := client.ForEachEvent("iterated", 0, "sideways", func(e *EventResponse) error { return nil })
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,392 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobState is the state of a job run by a JobRunner.
+type JobState string
+
+const (
+	// JobRunning indicates that the job is running.
+	JobRunning JobState = "running"
+
+	// JobPaused indicates that the job has been paused and can be resumed.
+	JobPaused JobState = "paused"
+
+	// JobCancelled indicates that the job was cancelled before it completed.
+	JobCancelled JobState = "cancelled"
+
+	// JobCompleted indicates that the job completed.
+	JobCompleted JobState = "completed"
+
+	// JobFailed indicates that a step of the job returned an error. A failed job
+	// can be started again and will continue from its last position.
+	JobFailed JobState = "failed"
+)
+
+// jobHistory is the $maxCount set on job streams.
+const jobHistory = 10
+
+// JobStatus is the persisted state of a job.
+//
+// Position is the position returned by the last step of the job to complete.
+// Its meaning is defined by the job, for example the event number of the last
+// event exported.
+type JobStatus struct {
+	ID       string    `json:"id"`
+	State    JobState  `json:"state"`
+	Position int       `json:"position"`
+	Error    string    `json:"error,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// JobStep performs the next unit of work of a job.
+//
+// position is the position returned by the previous step, or -1 for the first
+// step of the job. The step returns the position reached and true if the job
+// is complete. The position is persisted after each step, so a job that is
+// interrupted continues from the last step to complete. Steps should be small
+// enough that repeating one is acceptable.
+type JobStep func(position int) (next int, done bool, err error)
+
+// JobStreamName returns the name of the stream that the status of the job is
+// written to.
+func JobStreamName(id string) string {
+	return "job-" + id
+}
+
+// JobRunner runs long running jobs whose progress is persisted in the
+// eventstore.
+//
+// The status of each job is appended to the stream named by JobStreamName after
+// every step and whenever the job is paused, resumed or cancelled. When a job is
+// started the runner reads its last status, so a job that was interrupted by
+// the process exiting continues from its last position, a paused job stays
+// paused and a completed or cancelled job is not run again.
+//
+// JobRunner implements http.Handler to report the status of jobs and to pause,
+// resume and cancel them. GET returns the status of the job selected by the id
+// query parameter, or of all jobs if there is no id. POST with an id and an
+// action of pause, resume or cancel controls the job.
+//
+// A JobRunner is safe for concurrent use.
+type JobRunner struct {
+	client   *Client
+	mu       sync.Mutex
+	jobs     map[string]*job
+	load     func(id string) (*JobStatus, error)
+	save     func(status *JobStatus) error
+	reporter ErrorReporter
+}
+
+// job holds the state of a job in a JobRunner.
+type job struct {
+	step    JobStep
+	mu      sync.Mutex
+	cond    *sync.Cond
+	status  JobStatus
+	request JobState
+	done    chan struct{}
+}
+
+// NewJobRunner returns a new *JobRunner.
+func (c *Client) NewJobRunner() *JobRunner {
+	r := &JobRunner{
+		client: c,
+		jobs:   make(map[string]*job),
+	}
+	r.load = r.loadStatus
+	r.save = r.saveStatus
+	return r
+}
+
+// Start starts the job with the id provided.
+//
+// If the job has run before it continues from its persisted status. A job that
+// is already running in the runner is not started again.
+func (r *JobRunner) Start(id string, step JobStep) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if j, ok := r.jobs[id]; ok {
+		j.mu.Lock()
+		state := j.status.State
+		j.mu.Unlock()
+		if state == JobRunning || state == JobPaused {
+			return nil
+		}
+	}
+
+	status, err := r.load(id)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		status = &JobStatus{ID: id, State: JobRunning, Position: -1}
+	}
+
+	j := &job{step: step, status: *status, done: make(chan struct{})}
+	j.cond = sync.NewCond(&j.mu)
+	r.jobs[id] = j
+
+	switch status.State {
+	case JobCompleted, JobCancelled:
+		close(j.done)
+		return nil
+	case JobFailed:
+		j.status.State = JobRunning
+		j.status.Error = ""
+	}
+
+	go r.run(j)
+	return nil
+}
+
+// run executes the steps of the job until it completes, fails or is cancelled.
+func (r *JobRunner) run(j *job) {
+	defer close(j.done)
+	for {
+		j.mu.Lock()
+		for j.status.State == JobPaused && j.request == "" {
+			j.cond.Wait()
+		}
+		req := j.request
+		j.request = ""
+		state := j.status.State
+		j.mu.Unlock()
+
+		switch {
+		case req == JobCancelled:
+			r.update(j, JobCancelled, j.status.Position, nil)
+			return
+		case req == JobPaused && state == JobRunning:
+			if !r.update(j, JobPaused, j.status.Position, nil) {
+				return
+			}
+			continue
+		case req == JobRunning && state == JobPaused:
+			if !r.update(j, JobRunning, j.status.Position, nil) {
+				return
+			}
+			continue
+		case state == JobPaused:
+			continue
+		}
+
+		next, done, err := j.step(j.status.Position)
+		if err != nil {
+			r.update(j, JobFailed, j.status.Position, err)
+			return
+		}
+		if done {
+			r.update(j, JobCompleted, next, nil)
+			return
+		}
+		if !r.update(j, JobRunning, next, nil) {
+			return
+		}
+	}
+}
+
+// update persists a new status for the job. If the status cannot be persisted
+// the job is marked as failed and false is returned.
+func (r *JobRunner) update(j *job, state JobState, position int, err error) bool {
+	j.mu.Lock()
+	status := j.status
+	j.mu.Unlock()
+
+	status.State = state
+	status.Position = position
+	status.Updated = time.Now().UTC()
+	status.Error = ""
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	ok := true
+	if serr := r.save(&status); serr != nil {
+		status.State = JobFailed
+		status.Error = serr.Error()
+		err = serr
+		ok = false
+	}
+
+	j.mu.Lock()
+	j.status = status
+	j.mu.Unlock()
+	if status.State == JobFailed {
+		r.reporter.Report("Job "+status.ID, err, true)
+	}
+	return ok
+}
+
+// control requests a change of state of a running job.
+func (r *JobRunner) control(id string, request JobState) error {
+	r.mu.Lock()
+	j, ok := r.jobs[id]
+	r.mu.Unlock()
+	if !ok {
+		return &ErrJobNotFound{ID: id}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.status.State {
+	case JobRunning, JobPaused:
+		j.request = request
+		j.cond.Broadcast()
+		return nil
+	}
+	return fmt.Errorf("Job %s is %s", id, j.status.State)
+}
+
+// Pause pauses the job after its current step.
+func (r *JobRunner) Pause(id string) error {
+	return r.control(id, JobPaused)
+}
+
+// Resume resumes a paused job.
+func (r *JobRunner) Resume(id string) error {
+	return r.control(id, JobRunning)
+}
+
+// Cancel cancels the job after its current step. A cancelled job cannot be
+// resumed.
+func (r *JobRunner) Cancel(id string) error {
+	return r.control(id, JobCancelled)
+}
+
+// Wait blocks until the job has completed, failed or been cancelled and
+// returns its final status.
+func (r *JobRunner) Wait(id string) (*JobStatus, error) {
+	r.mu.Lock()
+	j, ok := r.jobs[id]
+	r.mu.Unlock()
+	if !ok {
+		return nil, &ErrJobNotFound{ID: id}
+	}
+	<-j.done
+	return r.Status(id)
+}
+
+// Status returns the status of a job started in the runner.
+func (r *JobRunner) Status(id string) (*JobStatus, error) {
+	r.mu.Lock()
+	j, ok := r.jobs[id]
+	r.mu.Unlock()
+	if !ok {
+		return nil, &ErrJobNotFound{ID: id}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := j.status
+	return &status, nil
+}
+
+// Jobs returns the status of all jobs started in the runner in order of id.
+func (r *JobRunner) Jobs() []JobStatus {
+	r.mu.Lock()
+	ids := make([]string, 0, len(r.jobs))
+	for id := range r.jobs {
+		ids = append(ids, id)
+	}
+	r.mu.Unlock()
+	sort.Strings(ids)
+
+	ret := make([]JobStatus, 0, len(ids))
+	for _, id := range ids {
+		if s, err := r.Status(id); err == nil {
+			ret = append(ret, *s)
+		}
+	}
+	return ret
+}
+
+// ServeHTTP reports the status of jobs and handles requests to pause, resume
+// and cancel them.
+func (r *JobRunner) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	id := req.URL.Query().Get("id")
+
+	switch req.Method {
+	case http.MethodGet:
+		var v interface{} = r.Jobs()
+		if id != "" {
+			s, err := r.Status(id)
+			if err != nil {
+				http.NotFound(w, req)
+				return
+			}
+			v = s
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+
+	case http.MethodPost:
+		var err error
+		switch req.URL.Query().Get("action") {
+		case "pause":
+			err = r.Pause(id)
+		case "resume":
+			err = r.Resume(id)
+		case "cancel":
+			err = r.Cancel(id)
+		default:
+			http.Error(w, "Unknown action", http.StatusBadRequest)
+			return
+		}
+		if _, ok := err.(*ErrJobNotFound); ok {
+			http.NotFound(w, req)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// loadStatus reads the last status of the job from its stream. If the job has
+// no status nil is returned.
+func (r *JobRunner) loadStatus(id string) (*JobStatus, error) {
+	status := &JobStatus{}
+	_, err := r.client.ReadLast(JobStreamName(id), status)
+	switch err.(type) {
+	case nil:
+		return status, nil
+	case *ErrNotFound, *ErrNoMoreEvents:
+		return nil, nil
+	}
+	return nil, err
+}
+
+// saveStatus appends the status of the job to its stream. When the stream is
+// created its $maxCount is set so that only recent statuses are retained.
+func (r *JobRunner) saveStatus(status *JobStatus) error {
+	name := JobStreamName(status.ID)
+	writer := r.client.NewStreamWriter(name)
+	e := NewEvent("", "JobStatus", status, nil)
+
+	err := writer.Append(ExpectNoStream, e)
+	if _, ok := err.(*ErrConcurrencyViolation); ok {
+		return writer.Append(ExpectAny, e)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writer.WriteMetaData(name, &StreamMetadata{MaxCount: Int(jobHistory)})
+}
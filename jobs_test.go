@@ -0,0 +1,213 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&JobsSuite{})
+
+type JobsSuite struct{}
+
+func (s *JobsSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *JobsSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// memoryJobStore holds job statuses in memory.
+type memoryJobStore struct {
+	mu    sync.Mutex
+	saved map[string][]JobStatus
+}
+
+// newMemoryJobRunner returns a runner that persists job statuses in memory.
+func newMemoryJobRunner(store *memoryJobStore) *JobRunner {
+	r := client.NewJobRunner()
+	store.saved = make(map[string][]JobStatus)
+	r.load = func(id string) (*JobStatus, error) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		if s := store.saved[id]; len(s) > 0 {
+			last := s[len(s)-1]
+			return &last, nil
+		}
+		return nil, nil
+	}
+	r.save = func(status *JobStatus) error {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		store.saved[status.ID] = append(store.saved[status.ID], *status)
+		return nil
+	}
+	return r
+}
+
+// countTo returns a step that advances the position by 10 until it reaches
+// max. The positions each step starts from are recorded in from.
+func countTo(max int, from *[]int) JobStep {
+	return func(position int) (int, bool, error) {
+		*from = append(*from, position)
+		next := position + 10
+		return next, next >= max, nil
+	}
+}
+
+func (s *JobsSuite) TestJobRunsToCompletion(c *C) {
+	store := &memoryJobStore{}
+	r := newMemoryJobRunner(store)
+
+	from := []int{}
+	c.Assert(r.Start("export", countTo(39, &from)), IsNil)
+	status, err := r.Wait("export")
+	c.Assert(err, IsNil)
+	c.Assert(status.State, Equals, JobCompleted)
+	c.Assert(status.Position, Equals, 39)
+	c.Assert(from, DeepEquals, []int{-1, 9, 19, 29})
+	c.Assert(store.saved["export"], HasLen, 4)
+}
+
+func (s *JobsSuite) TestJobResumesFromPersistedPosition(c *C) {
+	store := &memoryJobStore{}
+	r := newMemoryJobRunner(store)
+	store.saved["export"] = []JobStatus{{ID: "export", State: JobRunning, Position: 19}}
+
+	from := []int{}
+	c.Assert(r.Start("export", countTo(39, &from)), IsNil)
+	status, _ := r.Wait("export")
+	c.Assert(status.State, Equals, JobCompleted)
+	c.Assert(from, DeepEquals, []int{19, 29})
+}
+
+func (s *JobsSuite) TestCompletedJobIsNotRunAgain(c *C) {
+	store := &memoryJobStore{}
+	r := newMemoryJobRunner(store)
+	store.saved["export"] = []JobStatus{{ID: "export", State: JobCompleted, Position: 39}}
+
+	from := []int{}
+	c.Assert(r.Start("export", countTo(39, &from)), IsNil)
+	status, _ := r.Wait("export")
+	c.Assert(status.State, Equals, JobCompleted)
+	c.Assert(from, HasLen, 0)
+}
+
+func (s *JobsSuite) TestFailedJobRecordsError(c *C) {
+	store := &memoryJobStore{}
+	r := newMemoryJobRunner(store)
+
+	c.Assert(r.Start("export", func(position int) (int, bool, error) {
+		return position, false, errors.New("disk full")
+	}), IsNil)
+	status, _ := r.Wait("export")
+	c.Assert(status.State, Equals, JobFailed)
+	c.Assert(status.Error, Equals, "disk full")
+}
+
+func (s *JobsSuite) TestPauseResumeAndCancel(c *C) {
+	store := &memoryJobStore{}
+	r := newMemoryJobRunner(store)
+
+	stepped := make(chan int)
+	proceed := make(chan struct{})
+	c.Assert(r.Start("export", func(position int) (int, bool, error) {
+		stepped <- position
+		<-proceed
+		return position + 1, false, nil
+	}), IsNil)
+
+	c.Assert(<-stepped, Equals, -1)
+	c.Assert(r.Pause("export"), IsNil)
+	proceed <- struct{}{}
+	eventually(func() bool {
+		status, _ := r.Status("export")
+		return status.State == JobPaused
+	})
+	status, _ := r.Status("export")
+	c.Assert(status.State, Equals, JobPaused)
+	c.Assert(status.Position, Equals, 0)
+
+	c.Assert(r.Resume("export"), IsNil)
+	c.Assert(<-stepped, Equals, 0)
+	c.Assert(r.Cancel("export"), IsNil)
+	proceed <- struct{}{}
+
+	status, _ = r.Wait("export")
+	c.Assert(status.State, Equals, JobCancelled)
+	c.Assert(status.Position, Equals, 1)
+	c.Assert(r.Resume("export"), NotNil)
+}
+
+func (s *JobsSuite) TestServeHTTP(c *C) {
+	store := &memoryJobStore{}
+	r := newMemoryJobRunner(store)
+	store.saved["export"] = []JobStatus{{ID: "export", State: JobPaused, Position: 5}}
+	c.Assert(r.Start("export", countTo(10, &[]int{})), IsNil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	got := []JobStatus{}
+	c.Assert(json.NewDecoder(w.Body).Decode(&got), IsNil)
+	c.Assert(got, HasLen, 1)
+	c.Assert(got[0].State, Equals, JobPaused)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs?id=other&action=cancel", nil))
+	c.Assert(w.Code, Equals, http.StatusNotFound)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs?id=export&action=resume", nil))
+	c.Assert(w.Code, Equals, http.StatusAccepted)
+
+	status, _ := r.Wait("export")
+	c.Assert(status.State, Equals, JobCompleted)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jobs?id=export", nil))
+	one := JobStatus{}
+	c.Assert(json.NewDecoder(w.Body).Decode(&one), IsNil)
+	c.Assert(one.State, Equals, JobCompleted)
+	c.Assert(one.Position, Equals, 15)
+}
+
+func (s *JobsSuite) TestStatusIsPersistedToJobStream(c *C) {
+	var saved []JobStatus
+	mux.HandleFunc("/streams/"+JobStreamName("export"), func(w http.ResponseWriter, r *http.Request) {
+		// Report that the stream exists so that no metadata is written.
+		if r.Header.Get("ES-ExpectedVersion") == "-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		es := []struct {
+			EventType string    `json:"eventType"`
+			Data      JobStatus `json:"data"`
+		}{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&es), IsNil)
+		c.Assert(es[0].EventType, Equals, "JobStatus")
+		saved = append(saved, es[0].Data)
+		w.WriteHeader(http.StatusCreated)
+	})
+	prev := CreateTestEventFromData(JobStreamName("export"), server.URL, 0,
+		&JobStatus{ID: "export", State: JobRunning, Position: 9}, nil)
+	setupSimulator([]*Event{prev}, nil)
+
+	r := client.NewJobRunner()
+	from := []int{}
+	c.Assert(r.Start("export", countTo(20, &from)), IsNil)
+	status, _ := r.Wait("export")
+	c.Assert(status.State, Equals, JobCompleted)
+	c.Assert(from, DeepEquals, []int{9, 19})
+	c.Assert(saved, HasLen, 2)
+	c.Assert(saved[1].State, Equals, JobCompleted)
+	c.Assert(saved[1].Position, Equals, 29)
+}
@@ -0,0 +1,200 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConsumerLag describes how far a consumer of a stream is behind the head of
+// the stream.
+//
+// Head is the event number of the last event in the stream and Position is the
+// event number of the last event processed by the consumer. Both are -1 when
+// there are no events. Throughput is the number of events per second processed
+// by the consumer since the previous measurement.
+type ConsumerLag struct {
+	Consumer   string  `json:"consumer"`
+	Stream     string  `json:"stream"`
+	Head       int     `json:"head"`
+	Position   int     `json:"position"`
+	Lag        int     `json:"lag"`
+	Throughput float64 `json:"throughput"`
+}
+
+// LagReport is the document served by a LagMonitor.
+type LagReport struct {
+	TotalLag   int           `json:"totalLag"`
+	Throughput float64       `json:"throughput"`
+	Consumers  []ConsumerLag `json:"consumers"`
+}
+
+// lagConsumer holds the state of a consumer tracked by a LagMonitor.
+type lagConsumer struct {
+	stream     string
+	position   func() int
+	lastPos    int
+	lastTime   time.Time
+	throughput float64
+}
+
+// LagMonitor measures the lag of stream consumers.
+//
+// The lag of a consumer is the number of events between the position of the
+// consumer and the head of its stream. LagMonitor implements http.Handler and
+// serves a LagReport as JSON, which is suitable for use by autoscalers that
+// scale on external metrics read from an http endpoint. A single consumer can be
+// selected with the consumer query parameter.
+//
+// A LagMonitor is safe for concurrent use.
+type LagMonitor struct {
+	client    *Client
+	mu        sync.Mutex
+	consumers map[string]*lagConsumer
+	now       func() time.Time
+	reporter  ErrorReporter
+}
+
+// NewLagMonitor returns a new *LagMonitor.
+func (c *Client) NewLagMonitor() *LagMonitor {
+	return &LagMonitor{
+		client:    c,
+		consumers: make(map[string]*lagConsumer),
+		now:       time.Now,
+	}
+}
+
+// Track adds a consumer to the monitor.
+//
+// position must return the event number of the last event in the stream that
+// the consumer has processed, or -1 if the consumer has not processed any events.
+// It is usually a function that returns the consumer's checkpoint. Tracking a
+// consumer with the name of a consumer that is already tracked replaces it.
+func (m *LagMonitor) Track(consumer, stream string, position func() int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.consumers[consumer] = &lagConsumer{
+		stream:   stream,
+		position: position,
+		lastPos:  -2,
+	}
+}
+
+// Untrack removes a consumer from the monitor.
+func (m *LagMonitor) Untrack(consumer string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.consumers, consumer)
+}
+
+// Lag measures the lag of each tracked consumer.
+//
+// The head of each stream is read once per call. The consumers are returned in
+// order of name.
+func (m *LagMonitor) Lag() ([]ConsumerLag, error) {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.consumers))
+	for name := range m.consumers {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+	sort.Strings(names)
+
+	heads := make(map[string]int)
+	ret := make([]ConsumerLag, 0, len(names))
+	for _, name := range names {
+		m.mu.Lock()
+		lc, ok := m.consumers[name]
+		m.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		head, ok := heads[lc.stream]
+		if !ok {
+			h, err := m.head(lc.stream)
+			if err != nil {
+				m.reporter.Report("LagMonitor for "+lc.stream, err, false)
+				return nil, err
+			}
+			heads[lc.stream] = h
+			head = h
+		}
+
+		pos := lc.position()
+		lag := head - pos
+		if lag < 0 {
+			lag = 0
+		}
+
+		m.mu.Lock()
+		now := m.now()
+		if lc.lastPos > -2 {
+			if elapsed := now.Sub(lc.lastTime).Seconds(); elapsed > 0 {
+				lc.throughput = float64(pos-lc.lastPos) / elapsed
+			}
+		}
+		lc.lastPos = pos
+		lc.lastTime = now
+		throughput := lc.throughput
+		m.mu.Unlock()
+
+		ret = append(ret, ConsumerLag{
+			Consumer:   name,
+			Stream:     lc.stream,
+			Head:       head,
+			Position:   pos,
+			Lag:        lag,
+			Throughput: throughput,
+		})
+	}
+	return ret, nil
+}
+
+// head returns the event number of the last event in the stream or -1 if the
+// stream has no events.
+func (m *LagMonitor) head(stream string) (int, error) {
+	v, err := m.client.GetStreamHeadVersion(stream)
+	switch err.(type) {
+	case nil:
+		return v, nil
+	case *ErrNoEvents, *ErrNotFound:
+		return -1, nil
+	}
+	return -1, err
+}
+
+// ServeHTTP serves the LagReport for the tracked consumers.
+func (m *LagMonitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lags, err := m.Lag()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	report := LagReport{Consumers: []ConsumerLag{}}
+	consumer := r.URL.Query().Get("consumer")
+	for _, l := range lags {
+		if consumer != "" && l.Consumer != consumer {
+			continue
+		}
+		report.TotalLag += l.Lag
+		report.Throughput += l.Throughput
+		report.Consumers = append(report.Consumers, l)
+	}
+
+	if consumer != "" && len(report.Consumers) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
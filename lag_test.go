@@ -0,0 +1,94 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&LagSuite{})
+
+type LagSuite struct{}
+
+func (s *LagSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *LagSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *LagSuite) TestLagIsMeasuredFromHeadOfStream(c *C) {
+	es := CreateTestEvents(25, "lagged", server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	clock := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := client.NewLagMonitor()
+	m.now = func() time.Time { return clock }
+
+	slow, fast := 4, 24
+	m.Track("slow", "lagged", func() int { return slow })
+	m.Track("fast", "lagged", func() int { return fast })
+
+	lags, err := m.Lag()
+	c.Assert(err, IsNil)
+	c.Assert(lags, DeepEquals, []ConsumerLag{
+		{Consumer: "fast", Stream: "lagged", Head: 24, Position: 24, Lag: 0},
+		{Consumer: "slow", Stream: "lagged", Head: 24, Position: 4, Lag: 20},
+	})
+
+	clock = clock.Add(2 * time.Second)
+	slow = 14
+	lags, err = m.Lag()
+	c.Assert(err, IsNil)
+	c.Assert(lags[1].Lag, Equals, 10)
+	c.Assert(lags[1].Throughput, Equals, 5.0)
+}
+
+func (s *LagSuite) TestServeHTTPReportsLag(c *C) {
+	es := CreateTestEvents(10, "served", server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	m := client.NewLagMonitor()
+	m.Track("a", "served", func() int { return 2 })
+	m.Track("b", "served", func() int { return -1 })
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lag", nil))
+	c.Assert(rec.Code, Equals, http.StatusOK)
+
+	report := LagReport{}
+	c.Assert(json.NewDecoder(rec.Body).Decode(&report), IsNil)
+	c.Assert(report.TotalLag, Equals, 17)
+	c.Assert(report.Consumers, HasLen, 2)
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lag?consumer=a", nil))
+	report = LagReport{}
+	c.Assert(json.NewDecoder(rec.Body).Decode(&report), IsNil)
+	c.Assert(report.TotalLag, Equals, 7)
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lag?consumer=nobody", nil))
+	c.Assert(rec.Code, Equals, http.StatusNotFound)
+}
+
+func (s *LagSuite) TestMissingStreamHasNoLag(c *C) {
+	mux.HandleFunc("/streams/empty/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	m := client.NewLagMonitor()
+	m.Track("a", "empty", func() int { return -1 })
+	lags, err := m.Lag()
+	c.Assert(err, IsNil)
+	c.Assert(lags[0].Head, Equals, -1)
+	c.Assert(lags[0].Lag, Equals, 0)
+}
@@ -0,0 +1,155 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ConnectionEventKind identifies a change in the connection of a client to the
+// nodes of the server.
+type ConnectionEventKind string
+
+const (
+	// NodeConnected is sent when a node responds for the first time, or for the
+	// first time after it was unreachable.
+	NodeConnected ConnectionEventKind = "NodeConnected"
+
+	// NodeUnreachable is sent when a request to a node that was reachable, or
+	// had not been contacted, fails without a response. Err is the error.
+	NodeUnreachable ConnectionEventKind = "NodeUnreachable"
+
+	// NodeSwitched is sent when writes move to another node, either because a
+	// write was redirected to the master or because the master could not be
+	// reached and writes go back to the server of the client. See
+	// SetPreferMaster.
+	NodeSwitched ConnectionEventKind = "NodeSwitched"
+
+	// AuthFailed is sent when the server rejects a request with 401
+	// Unauthorized, after any refresh from the credentials provider. Err is the
+	// *ErrUnauthorized returned for the request.
+	AuthFailed ConnectionEventKind = "AuthFailed"
+
+	// RetryScheduled is sent when the server asks the client to wait before
+	// sending more requests. RetryAfter is the time requests are held back.
+	RetryScheduled ConnectionEventKind = "RetryScheduled"
+)
+
+// ConnectionEvent describes a change in the connection of a client to the
+// server.
+//
+// Node is the base url of the node concerned, such as http://10.0.0.2:2113.
+// For NodeSwitched, Previous is the node that writes were sent to before.
+type ConnectionEvent struct {
+	Kind       ConnectionEventKind
+	Node       string
+	Previous   string
+	Err        error
+	RetryAfter time.Duration
+	Time       time.Time
+}
+
+// ConnectionListener is notified of changes in the connection of a client to
+// the server, so that applications can log and alert on them separately from
+// the errors returned by each call.
+//
+// OnConnectionEvent is called on the goroutine making the request that caused
+// the change, so it should return quickly and must be safe for concurrent use.
+type ConnectionListener interface {
+	OnConnectionEvent(e *ConnectionEvent)
+}
+
+// ConnectionListenerFunc is a function that implements ConnectionListener.
+type ConnectionListenerFunc func(e *ConnectionEvent)
+
+// OnConnectionEvent calls f(e).
+func (f ConnectionListenerFunc) OnConnectionEvent(e *ConnectionEvent) {
+	f(e)
+}
+
+// SetConnectionListener sets the listener notified of connection events. A nil
+// listener removes the listener. Sessions created with NewSession use the
+// listener of the client at the time they were created.
+func (c *Client) SetConnectionListener(l ConnectionListener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listener = l
+	if c.nodes == nil {
+		c.nodes = &nodeTracker{}
+	}
+}
+
+// notify sends the event to the listener of the client, if there is one.
+func (c *Client) notify(e *ConnectionEvent) {
+	c.mu.RLock()
+	l := c.listener
+	c.mu.RUnlock()
+	if l == nil {
+		return
+	}
+	e.Time = time.Now()
+	l.OnConnectionEvent(e)
+}
+
+// nodeTracker records which nodes have responded, so that a NodeConnected or
+// NodeUnreachable event is only sent when the state of a node changes. It is
+// shared by the sessions of a client.
+type nodeTracker struct {
+	mu        sync.Mutex
+	reachable map[string]bool
+}
+
+// set records whether the node is reachable and returns true if that is a
+// change. The first outcome for a node is always a change, so that a node that
+// is down from the start is reported.
+func (t *nodeTracker) set(node string, reachable bool) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reachable == nil {
+		t.reachable = make(map[string]bool)
+	}
+	was, seen := t.reachable[node]
+	t.reachable[node] = reachable
+	if !seen {
+		return true
+	}
+	return was != reachable
+}
+
+// observe notifies the listener if the outcome of a round trip changes the
+// state of the node it was sent to. Requests that fail because their context
+// is done say nothing about the node.
+func (c *Client) observe(req *http.Request, err error) {
+	c.mu.RLock()
+	nodes := c.nodes
+	c.mu.RUnlock()
+
+	node := nodeOf(req.URL)
+	switch {
+	case err == nil:
+		if nodes.set(node, true) {
+			c.notify(&ConnectionEvent{Kind: NodeConnected, Node: node})
+		}
+	case req.Context().Err() == nil:
+		if nodes.set(node, false) {
+			c.notify(&ConnectionEvent{Kind: NodeUnreachable, Node: node, Err: err})
+		}
+	}
+}
+
+// nodeOf returns the base url of the node of u.
+func nodeOf(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+}
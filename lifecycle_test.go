@@ -0,0 +1,126 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&LifecycleSuite{})
+
+type LifecycleSuite struct{}
+
+func (s *LifecycleSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *LifecycleSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// connectionRecorder records the connection events of a client.
+type connectionRecorder struct {
+	mu     sync.Mutex
+	events []*ConnectionEvent
+}
+
+func recordConnectionEvents(cl *Client) *connectionRecorder {
+	r := &connectionRecorder{}
+	cl.SetConnectionListener(ConnectionListenerFunc(func(e *ConnectionEvent) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, e)
+	}))
+	return r
+}
+
+func (r *connectionRecorder) kinds() []ConnectionEventKind {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kinds := []ConnectionEventKind{}
+	for _, e := range r.events {
+		kinds = append(kinds, e.Kind)
+	}
+	return kinds
+}
+
+func (r *connectionRecorder) last() *ConnectionEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[len(r.events)-1]
+}
+
+func (s *LifecycleSuite) TestNodeStateChangesAreReported(c *C) {
+	mux.HandleFunc("/streams/lifecycle-1/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	refused := errors.New("connection refused")
+	fail := true
+	client.Use(func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		if fail {
+			return nil, refused
+		}
+		return next(req)
+	})
+	rec := recordConnectionEvents(client)
+
+	client.GetStreamHeadVersion("lifecycle-1")
+	client.GetStreamHeadVersion("lifecycle-1")
+	c.Assert(rec.kinds(), DeepEquals, []ConnectionEventKind{NodeUnreachable})
+	c.Assert(rec.last().Node, Equals, server.URL)
+	c.Assert(rec.last().Err, Equals, refused)
+	c.Assert(rec.last().Time.IsZero(), Equals, false)
+
+	fail = false
+	client.GetStreamHeadVersion("lifecycle-1")
+	client.GetStreamHeadVersion("lifecycle-1")
+	c.Assert(rec.kinds(), DeepEquals, []ConnectionEventKind{NodeUnreachable, NodeConnected})
+	c.Assert(rec.last().Node, Equals, server.URL)
+}
+
+func (s *LifecycleSuite) TestAuthFailureAndRetryAreReported(c *C) {
+	mux.HandleFunc("/streams/lifecycle-2/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/streams/lifecycle-3/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	rec := recordConnectionEvents(client)
+
+	_, err := client.GetStreamHeadVersion("lifecycle-2")
+	c.Assert(rec.kinds(), DeepEquals, []ConnectionEventKind{NodeConnected, AuthFailed})
+	c.Assert(rec.last().Err, Equals, err)
+
+	client.GetStreamHeadVersion("lifecycle-3")
+	c.Assert(rec.kinds(), DeepEquals, []ConnectionEventKind{NodeConnected, AuthFailed, RetryScheduled})
+	c.Assert(rec.last().RetryAfter, Equals, time.Second)
+}
+
+func (s *LifecycleSuite) TestNodeSwitchesAreReported(c *C) {
+	master := newMasterNode(c)
+	redirectTo(master)
+	client.SetRequireMaster(true)
+	client.SetPreferMaster(true)
+	rec := recordConnectionEvents(client)
+
+	events := CreateTestEvents(1, "lifecycle-4", server.URL, "Foo")
+	c.Assert(client.NewStreamWriter("lifecycle-4").Append(ExpectAny, events...), IsNil)
+	c.Assert(client.NewStreamWriter("lifecycle-4").Append(ExpectAny, events...), IsNil)
+	c.Assert(rec.kinds(), DeepEquals, []ConnectionEventKind{NodeConnected, NodeConnected, NodeSwitched})
+	c.Assert(rec.last().Node, Equals, master.URL)
+	c.Assert(rec.last().Previous, Equals, server.URL)
+
+	master.Close()
+	client.NewStreamWriter("lifecycle-4").Append(ExpectAny, events...)
+	c.Assert(rec.kinds(), DeepEquals, []ConnectionEventKind{NodeConnected, NodeConnected, NodeSwitched, NodeUnreachable, NodeSwitched})
+	c.Assert(rec.last().Node, Equals, server.URL)
+	c.Assert(rec.last().Previous, Equals, master.URL)
+}
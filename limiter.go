@@ -0,0 +1,129 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a token bucket rate limiter.
+//
+// Tokens are added at rate per second up to burst. Each request takes a token,
+// waiting for one to be added if the bucket is empty. Tokens are reserved as
+// requests arrive so that waiting requests are served in order.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token from the bucket and returns the time the caller must
+// wait before the token is available.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// SetConcurrencyLimit limits the number of requests the client will have in
+// flight at once. Requests made when the limit is reached wait until another
+// request completes. A value of 0 or less removes the limit, which is the
+// default.
+//
+// The limit applies to requests started after the call.
+func (c *Client) SetConcurrencyLimit(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n <= 0 {
+		c.sem = nil
+		return
+	}
+	c.sem = make(chan struct{}, n)
+}
+
+// SetRateLimit limits the rate of requests made by the client to perSecond
+// requests per second, allowing bursts of up to burst requests. Requests made
+// faster than the rate wait until they are permitted. A rate of 0 or less
+// removes the limit, which is the default.
+func (c *Client) SetRateLimit(perSecond float64, burst int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if perSecond <= 0 {
+		c.bucket = nil
+		return
+	}
+	c.bucket = newTokenBucket(perSecond, burst)
+}
+
+// acquire waits until the rate and concurrency limits of the client permit a
+// request. It returns the time spent waiting and a function that must be called
+// when the request completes.
+//
+// If ctx is done before the request is permitted the error of ctx is returned.
+func (c *Client) acquire(ctx context.Context) (time.Duration, func(), error) {
+	c.mu.RLock()
+	sem := c.sem
+	bucket := c.bucket
+	c.mu.RUnlock()
+
+	if sem == nil && bucket == nil {
+		return 0, func() {}, nil
+	}
+
+	start := time.Now()
+	if bucket != nil {
+		if d := bucket.reserve(); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return 0, nil, ctx.Err()
+			}
+		}
+	}
+
+	release := func() {}
+	if sem != nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+		release = func() { <-sem }
+	}
+	return time.Since(start), release, nil
+}
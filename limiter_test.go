@@ -0,0 +1,133 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&LimiterSuite{})
+
+type LimiterSuite struct{}
+
+func (s *LimiterSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *LimiterSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *LimiterSuite) TestConcurrencyLimit(c *C) {
+	var inflight, maxInflight int32
+	mux.HandleFunc("/streams/limited/0", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInflight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInflight, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inflight, -1)
+		fmt.Fprint(w, "{}")
+	})
+
+	client.SetConcurrencyLimit(2)
+
+	var wg sync.WaitGroup
+	var waited int64
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, resp, err := client.GetEvent("/streams/limited/0")
+			c.Check(err, IsNil)
+			atomic.AddInt64(&waited, int64(resp.Waited))
+		}()
+	}
+	wg.Wait()
+
+	c.Assert(atomic.LoadInt32(&maxInflight), Equals, int32(2))
+	c.Assert(atomic.LoadInt64(&waited) > 0, Equals, true)
+}
+
+func (s *LimiterSuite) TestRateLimitDelaysRequests(c *C) {
+	mux.HandleFunc("/streams/rated/0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "{}")
+	})
+
+	client.SetRateLimit(100, 1)
+
+	start := time.Now()
+	var resp *Response
+	for i := 0; i < 5; i++ {
+		var err error
+		_, resp, err = client.GetEvent("/streams/rated/0")
+		c.Assert(err, IsNil)
+	}
+	c.Assert(time.Since(start) >= 35*time.Millisecond, Equals, true)
+	c.Assert(resp.Waited > 0, Equals, true)
+
+	client.SetRateLimit(0, 0)
+	_, resp, err := client.GetEvent("/streams/rated/0")
+	c.Assert(err, IsNil)
+	c.Assert(resp.Waited, Equals, time.Duration(0))
+}
+
+func (s *LimiterSuite) TestCancelledRequestStopsWaitingForRateLimit(c *C) {
+	mux.HandleFunc("/streams/rated/0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "{}")
+	})
+	client.SetRateLimit(1, 1)
+	_, _, err := client.GetEvent("/streams/rated/0")
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err = client.getEvent(ctx, "/streams/rated/0")
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	c.Assert(time.Since(start) < 500*time.Millisecond, Equals, true)
+}
+
+func (s *LimiterSuite) TestCancelledRequestStopsWaitingForConcurrencyLimit(c *C) {
+	block := make(chan struct{})
+	mux.HandleFunc("/streams/limited/0", func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		fmt.Fprint(w, "{}")
+	})
+	defer close(block)
+	client.SetConcurrencyLimit(1)
+	go client.GetEvent("/streams/limited/0")
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, _, err := client.getEvent(ctx, "/streams/limited/0")
+	c.Assert(err, Equals, context.Canceled)
+}
+
+func (s *LimiterSuite) TestTokenBucket(c *C) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newTokenBucket(10, 2)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	c.Assert(b.reserve(), Equals, time.Duration(0))
+	c.Assert(b.reserve(), Equals, time.Duration(0))
+	c.Assert(b.reserve(), Equals, 100*time.Millisecond)
+	c.Assert(b.reserve(), Equals, 200*time.Millisecond)
+
+	now = now.Add(time.Second)
+	c.Assert(b.reserve(), Equals, time.Duration(0))
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Metadata keys used to record the lineage of events. The correlation and
+// causation keys are the keys used by the eventstore projections.
+const (
+	CorrelationIDMetaDataKey = "$correlationId"
+	CausationIDMetaDataKey   = "$causationId"
+	SourceStreamMetaDataKey  = "sourceStream"
+	SourceVersionMetaDataKey = "sourceVersion"
+)
+
+// Lineage identifies the event that caused events to be written.
+//
+// CausationID is the id of the event being processed. CorrelationID is the
+// correlation id of that event, or its id if it has none, so that all events
+// caused directly or indirectly by an event share a correlation id.
+// SourceStream and SourceVersion locate the event being processed.
+type Lineage struct {
+	CorrelationID string
+	CausationID   string
+	SourceStream  string
+	SourceVersion int
+}
+
+// LineageOf returns the lineage of events caused by the event provided.
+func LineageOf(er *EventResponse) Lineage {
+	e := er.Event
+	l := Lineage{
+		CorrelationID: e.EventID,
+		CausationID:   e.EventID,
+		SourceStream:  e.EventStreamID,
+		SourceVersion: e.EventNumber,
+	}
+
+	if raw, ok := e.MetaData.(*json.RawMessage); ok && raw != nil {
+		m := struct {
+			CorrelationID string `json:"$correlationId"`
+		}{}
+		if json.Unmarshal(*raw, &m) == nil && m.CorrelationID != "" {
+			l.CorrelationID = m.CorrelationID
+		}
+	}
+	return l
+}
+
+// NewEventCausedBy creates a new event caused by the parent event, like
+// NewEvent, with the correlation and causation ids of the event set from the
+// lineage of the parent. The causation id is the id of the parent and the
+// correlation id is the correlation id of the parent, or its id if it has
+// none.
+func NewEventCausedBy(parent *EventResponse, eventID, eventType string, data interface{}, meta interface{}) *Event {
+	e := NewEvent(eventID, eventType, data, meta)
+	l := LineageOf(parent)
+	e.CorrelationID = l.CorrelationID
+	e.CausationID = l.CausationID
+	return e
+}
+
+// stampEnvelope returns a copy of the event with its correlation and causation
+// ids merged into its metadata. The ids replace any values for the keys
+// already in the metadata. If neither id is set the event is returned
+// unchanged.
+func stampEnvelope(e *Event) (*Event, error) {
+	ids := make(map[string]interface{}, 2)
+	if e.CorrelationID != "" {
+		ids[CorrelationIDMetaDataKey] = e.CorrelationID
+	}
+	if e.CausationID != "" {
+		ids[CausationIDMetaDataKey] = e.CausationID
+	}
+	if len(ids) == 0 {
+		return e, nil
+	}
+
+	ret, err := mergeMetaData(ids, e)
+	if err != nil {
+		return nil, err
+	}
+	meta := ret.MetaData.(map[string]interface{})
+	for k, v := range ids {
+		meta[k] = v
+	}
+	return ret, nil
+}
+
+// readEnvelope sets the correlation and causation ids of an event read from
+// the server from its metadata.
+func readEnvelope(e *Event) {
+	raw, ok := e.MetaData.(*json.RawMessage)
+	if !ok || raw == nil || len(*raw) == 0 {
+		return
+	}
+	m := struct {
+		CorrelationID string `json:"$correlationId"`
+		CausationID   string `json:"$causationId"`
+	}{}
+	if json.Unmarshal(*raw, &m) == nil {
+		e.CorrelationID = m.CorrelationID
+		e.CausationID = m.CausationID
+	}
+}
+
+// metaData returns the lineage as event metadata.
+func (l Lineage) metaData() map[string]interface{} {
+	return map[string]interface{}{
+		CorrelationIDMetaDataKey: l.CorrelationID,
+		CausationIDMetaDataKey:   l.CausationID,
+		SourceStreamMetaDataKey:  l.SourceStream,
+		SourceVersionMetaDataKey: l.SourceVersion,
+	}
+}
+
+type lineageKey struct{}
+
+// WithLineage returns a copy of ctx carrying the lineage.
+func WithLineage(ctx context.Context, l Lineage) context.Context {
+	return context.WithValue(ctx, lineageKey{}, l)
+}
+
+// LineageFromContext returns the lineage carried by ctx, if any.
+func LineageFromContext(ctx context.Context) (Lineage, bool) {
+	l, ok := ctx.Value(lineageKey{}).(Lineage)
+	return l, ok
+}
+
+// AppendContext appends events to the stream like Append and stamps them with
+// the lineage carried by ctx.
+//
+// Subscriptions pass the lineage of each event to handlers set with
+// SetContextHandler, so events appended with the handler's context record the
+// event that caused them. The lineage is merged into the metadata of each event
+// in the same way as the writer's default metadata, and keys already present in
+// the metadata of an event are not replaced. If ctx carries no lineage the
+// events are appended unchanged. The headers carried by ctx are sent with the
+// write. See WithRequestHeaders.
+func (s *StreamWriter) AppendContext(ctx context.Context, expectedVersion ExpectedVersion, events ...*Event) error {
+	l, ok := LineageFromContext(ctx)
+	if !ok {
+		return s.appendIndexed(expectedVersion, events, requestHeaders(ctx))
+	}
+
+	stamped := make([]*Event, len(events))
+	for i, e := range events {
+		ev, err := mergeMetaData(l.metaData(), e)
+		if err != nil {
+			return err
+		}
+		stamped[i] = ev
+	}
+	return s.appendIndexed(expectedVersion, stamped, requestHeaders(ctx))
+}
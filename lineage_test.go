@@ -0,0 +1,150 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&LineageSuite{})
+
+type LineageSuite struct{}
+
+func (s *LineageSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *LineageSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *LineageSuite) TestLineageOf(c *C) {
+	e := CreateTestEventFromData("orders-1", server.URL, 3, &MyDataType{}, nil)
+	l := LineageOf(CreateTestEventResponse(e, nil))
+	c.Assert(l, DeepEquals, Lineage{
+		CorrelationID: e.EventID,
+		CausationID:   e.EventID,
+		SourceStream:  "orders-1",
+		SourceVersion: 3,
+	})
+
+	e = CreateTestEventFromData("orders-1", server.URL, 4, &MyDataType{},
+		map[string]string{CorrelationIDMetaDataKey: "request-1"})
+	l = LineageOf(CreateTestEventResponse(e, nil))
+	c.Assert(l.CorrelationID, Equals, "request-1")
+	c.Assert(l.CausationID, Equals, e.EventID)
+}
+
+func (s *LineageSuite) TestSubscriptionHandlerPropagatesLineage(c *C) {
+	es := CreateTestEvents(2, "orders-2", server.URL, "OrderPlaced")
+	setupSimulator(es, nil)
+
+	var mu sync.Mutex
+	var written []map[string]interface{}
+	mux.HandleFunc("/streams/invoices-2", func(w http.ResponseWriter, r *http.Request) {
+		got := []struct {
+			MetaData map[string]interface{} `json:"metadata"`
+		}{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&got), IsNil)
+		mu.Lock()
+		for _, e := range got {
+			written = append(written, e.MetaData)
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	writer := client.NewStreamWriter("invoices-2")
+	sub := client.NewCatchUpSubscription("orders-2", 0, nil)
+	sub.SetContextHandler(func(ctx context.Context, er *EventResponse) error {
+		return writer.AppendContext(ctx, ExpectAny,
+			NewEvent("", "InvoiceRaised", nil, map[string]interface{}{"tenant": "acme"}))
+	})
+	sub.Start()
+	eventually(func() bool { return sub.LastProcessed() == 1 })
+	sub.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(written, HasLen, 2)
+	c.Assert(written[1], DeepEquals, map[string]interface{}{
+		"tenant":                 "acme",
+		CorrelationIDMetaDataKey: es[1].EventID,
+		CausationIDMetaDataKey:   es[1].EventID,
+		SourceStreamMetaDataKey:  "orders-2",
+		SourceVersionMetaDataKey: float64(1),
+	})
+}
+
+func (s *LineageSuite) TestAppendContextWithoutLineage(c *C) {
+	mux.HandleFunc("/streams/invoices-3", func(w http.ResponseWriter, r *http.Request) {
+		got := []Event{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&got), IsNil)
+		c.Assert(got[0].MetaData, IsNil)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := client.NewStreamWriter("invoices-3").AppendContext(context.Background(), ExpectAny,
+		NewEvent("", "InvoiceRaised", nil, nil))
+	c.Assert(err, IsNil)
+}
+
+func (s *LineageSuite) TestNewEventCausedBy(c *C) {
+	parent := CreateTestEventFromData("orders-4", server.URL, 2, &MyDataType{},
+		map[string]string{CorrelationIDMetaDataKey: "request-4"})
+
+	e := NewEventCausedBy(CreateTestEventResponse(parent, nil), "", "InvoiceRaised", nil, nil)
+	c.Assert(e.EventType, Equals, "InvoiceRaised")
+	c.Assert(e.CorrelationID, Equals, "request-4")
+	c.Assert(e.CausationID, Equals, parent.EventID)
+}
+
+func (s *LineageSuite) TestEnvelopeIsWrittenAndRead(c *C) {
+	stream := "invoices-5"
+	var written map[string]interface{}
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		got := []struct {
+			MetaData map[string]interface{} `json:"metadata"`
+		}{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&got), IsNil)
+		written = got[0].MetaData
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	e := NewEvent("", "InvoiceRaised", nil, map[string]interface{}{
+		"tenant":               "acme",
+		CausationIDMetaDataKey: "replaced",
+	})
+	e.CorrelationID = "request-5"
+	e.CausationID = "order-5"
+	err := client.NewStreamWriter(stream).Append(ExpectAny, e)
+	c.Assert(err, IsNil)
+	c.Assert(written, DeepEquals, map[string]interface{}{
+		"tenant":                 "acme",
+		CorrelationIDMetaDataKey: "request-5",
+		CausationIDMetaDataKey:   "order-5",
+	})
+	c.Assert(e.MetaData, HasLen, 2)
+
+	read := CreateTestEventFromData(stream, server.URL, 0, &MyDataType{}, written)
+	setupSimulator([]*Event{read}, nil)
+	er, _, err := client.GetEvent(fmt.Sprintf("/streams/%s/0", stream))
+	c.Assert(err, IsNil)
+	c.Assert(er.Event.CorrelationID, Equals, "request-5")
+	c.Assert(er.Event.CausationID, Equals, "order-5")
+}
+
+func (s *LineageSuite) TestEventsWithoutEnvelopeAreUnchanged(c *C) {
+	e := NewEvent("", "Foo", nil, "not an object")
+	got, err := stampEnvelope(e)
+	c.Assert(err, IsNil)
+	c.Assert(got, Equals, e)
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+)
+
+// StreamsStream is the stream maintained by the $streams system projection,
+// which links to the first event of every stream.
+const StreamsStream = "$streams"
+
+// ListStreamsOptions filters and pages the streams returned by ListStreams.
+//
+// Prefix and Category restrict the streams to those whose names start with
+// Prefix, and to those in the category, whose names start with the category
+// followed by a hyphen. From is the position in the $streams stream to start
+// from, which is 0 for the first page and Next of the previous page after
+// that. Limit is the maximum number of streams returned; if it is 0 all of the
+// remaining streams are returned.
+type ListStreamsOptions struct {
+	Prefix   string
+	Category string
+	From     int
+	Limit    int
+}
+
+// StreamPage is a page of stream names returned by ListStreams.
+//
+// Next is the position from which to read the next page, or -1 if there are no
+// more streams.
+type StreamPage struct {
+	Streams []string
+	Next    int
+}
+
+// ListStreams returns the names of the streams in the eventstore in the order
+// they were created.
+//
+// The streams are read from the $streams stream maintained by the $streams
+// system projection, which must be running. Links to streams that have since
+// been deleted cannot be resolved and are skipped. If the $streams stream does
+// not exist an empty page is returned. opts may be nil to list all streams.
+func (c *Client) ListStreams(ctx context.Context, opts *ListStreamsOptions) (*StreamPage, error) {
+	o := ListStreamsOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.From < 0 {
+		o.From = 0
+	}
+	page := &StreamPage{Streams: []string{}, Next: -1}
+
+	url, err := c.GetFeedPath(StreamsStream, "forward", o.From, defaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := o.From
+	for url != "" {
+		f, _, err := c.readFeed(ctx, url)
+		if _, ok := err.(*ErrNotFound); ok {
+			return page, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Entry) == 0 {
+			break
+		}
+
+		// Entries are ordered most recent first.
+		for i := len(f.Entry) - 1; i >= 0; i-- {
+			if o.Limit > 0 && len(page.Streams) == o.Limit {
+				page.Next = pos
+				return page, nil
+			}
+			name, err := c.linkedStream(ctx, f.Entry[i])
+			if err != nil {
+				return nil, err
+			}
+			pos++
+			if name == "" || !strings.HasPrefix(name, o.Prefix) {
+				continue
+			}
+			if o.Category != "" && !strings.HasPrefix(name, o.Category+"-") {
+				continue
+			}
+			page.Streams = append(page.Streams, name)
+		}
+
+		url = ""
+		if l := f.GetLink("previous"); l != nil {
+			url = l.Href
+		}
+	}
+	return page, nil
+}
+
+// linkedStream returns the name of the stream to which an entry of the
+// $streams stream links, or an empty string if the link cannot be resolved.
+//
+// The eventstore resolves the links in the feed, so the title of the entry is
+// the number and stream of the linked event. If the title is not that of a
+// resolved event the linked event is read.
+func (c *Client) linkedStream(ctx context.Context, entry *atom.Entry) (string, error) {
+	if i := strings.Index(entry.Title, "@"); i > 0 {
+		if _, err := strconv.Atoi(entry.Title[:i]); err == nil && entry.Title[i+1:] != StreamsStream {
+			return entry.Title[i+1:], nil
+		}
+	}
+
+	er, err := c.entryEvent(ctx, entry)
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound, *ErrDeleted:
+		return "", nil
+	default:
+		return "", err
+	}
+	if er == nil || er.Event == nil {
+		return "", nil
+	}
+	return er.Event.EventStreamID, nil
+}
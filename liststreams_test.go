@@ -0,0 +1,126 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ListStreamsSuite{})
+
+type ListStreamsSuite struct{}
+
+func (s *ListStreamsSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *ListStreamsSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+var linkedEventURL = regexp.MustCompile(`^/streams/([^/]+)/(\d+)/?$`)
+
+// serveStreamsStream serves a $streams stream linking to the streams
+// provided. The entries of the feed link to the events they resolve to.
+// Streams named in deleted return 404 Not Found.
+func serveStreamsStream(c *C, streams []string, deleted ...string) {
+	es := make([]*Event, len(streams))
+	for i, stream := range streams {
+		es[i] = CreateTestEvent(stream, server.URL, "$>", i, nil, nil)
+	}
+	mux.Handle("/streams/$streams/", newTestSimulator(es, nil))
+
+	gone := map[string]bool{}
+	for _, stream := range deleted {
+		gone[stream] = true
+	}
+	mux.HandleFunc("/streams/", func(w http.ResponseWriter, r *http.Request) {
+		m := linkedEventURL.FindStringSubmatch(r.URL.Path)
+		if m == nil || gone[m[1]] {
+			http.NotFound(w, r)
+			return
+		}
+		n, _ := strconv.Atoi(m[2])
+		er, err := CreateTestEventAtomResponse(CreateTestEvent(m[1], server.URL, "Foo", n, nil, nil), nil)
+		c.Assert(err, IsNil)
+		json.NewEncoder(w).Encode(er)
+	})
+}
+
+var listedStreams = []string{"order-1", "user-1", "order-2", "order-3", "user-2", "orderline-1"}
+
+func (s *ListStreamsSuite) TestListStreams(c *C) {
+	serveStreamsStream(c, listedStreams, "order-2")
+
+	page, err := client.ListStreams(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(page, DeepEquals, &StreamPage{
+		Streams: []string{"order-1", "user-1", "order-3", "user-2", "orderline-1"},
+		Next:    -1,
+	})
+}
+
+func (s *ListStreamsSuite) TestListStreamsFiltered(c *C) {
+	serveStreamsStream(c, listedStreams)
+
+	page, err := client.ListStreams(context.Background(), &ListStreamsOptions{Category: "order"})
+	c.Assert(err, IsNil)
+	c.Assert(page.Streams, DeepEquals, []string{"order-1", "order-2", "order-3"})
+
+	page, err = client.ListStreams(context.Background(), &ListStreamsOptions{Prefix: "order"})
+	c.Assert(err, IsNil)
+	c.Assert(page.Streams, DeepEquals, []string{"order-1", "order-2", "order-3", "orderline-1"})
+}
+
+func (s *ListStreamsSuite) TestListStreamsPaged(c *C) {
+	serveStreamsStream(c, listedStreams)
+
+	all := []string{}
+	opts := &ListStreamsOptions{Limit: 4}
+	pages := 0
+	for {
+		page, err := client.ListStreams(context.Background(), opts)
+		c.Assert(err, IsNil)
+		c.Assert(len(page.Streams) <= 4, Equals, true)
+		all = append(all, page.Streams...)
+		pages++
+		if page.Next == -1 {
+			break
+		}
+		opts.From = page.Next
+	}
+	c.Assert(all, DeepEquals, listedStreams)
+	c.Assert(pages, Equals, 2)
+}
+
+func (s *ListStreamsSuite) TestListStreamsWithoutStreamsStream(c *C) {
+	mux.HandleFunc("/streams/$streams/", http.NotFound)
+
+	page, err := client.ListStreams(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(page, DeepEquals, &StreamPage{Streams: []string{}, Next: -1})
+}
+
+func (s *ListStreamsSuite) TestListStreamsIsCancelled(c *C) {
+	serveStreamsStream(c, listedStreams)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.ListStreams(ctx, nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *ListStreamsSuite) TestResolvedEntryTitlesAreUsed(c *C) {
+	name, err := client.linkedStream(context.Background(), &atom.Entry{Title: "0@order-9"})
+	c.Assert(err, IsNil)
+	c.Assert(name, Equals, "order-9")
+}
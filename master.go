@@ -0,0 +1,105 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// SetRequireMaster sets whether writes made by the client must be handled by
+// the master of the cluster.
+//
+// When the master is required the client sends the ES-RequireMaster header with
+// every write. A node that is not the master redirects such a write to the
+// master rather than forwarding it, and the client sends the write again to
+// the location of the redirect. Reads are not affected.
+func (c *Client) SetRequireMaster(require bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requireMaster = require
+}
+
+// SetPreferMaster sets whether writes are sent directly to the master once a
+// write has been redirected to it.
+//
+// When a write is redirected to the master, the master becomes the preferred
+// node for the writes of the client that follow, saving a redirect for each of
+// them. Writes go back to the server of the client if the preferred node cannot
+// be reached. Writes with absolute urls, such as those of links in feeds, are
+// sent to the node in the url.
+func (c *Client) SetPreferMaster(prefer bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.preferMaster = prefer
+	if !prefer {
+		c.master = nil
+	}
+}
+
+// Master returns the base url of the node that writes are sent to after being
+// redirected to the master, or an empty string if writes are sent to the
+// server of the client.
+func (c *Client) Master() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.master == nil {
+		return ""
+	}
+	return c.master.String()
+}
+
+// isWrite returns true if requests with the method change the database.
+func isWrite(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodDelete
+}
+
+// noteMaster makes the node of the url, to which a write was redirected, the
+// preferred node for writes if the client prefers the master.
+func (c *Client) noteMaster(u *url.URL) {
+	c.mu.Lock()
+	if !c.preferMaster {
+		c.mu.Unlock()
+		return
+	}
+	previous := c.writeNode()
+	if u.Host == c.baseURL.Host {
+		c.master = nil
+	} else {
+		c.master = &url.URL{Scheme: u.Scheme, Host: u.Host, User: u.User}
+	}
+	current := c.writeNode()
+	c.mu.Unlock()
+
+	if current != previous {
+		c.notify(&ConnectionEvent{Kind: NodeSwitched, Node: current, Previous: previous})
+	}
+}
+
+// forgetMaster sends writes to the server of the client again if the url is
+// that of the preferred node.
+func (c *Client) forgetMaster(u *url.URL) {
+	c.mu.Lock()
+	if c.master == nil || u.Host != c.master.Host {
+		c.mu.Unlock()
+		return
+	}
+	previous := c.writeNode()
+	c.master = nil
+	current := c.writeNode()
+	c.mu.Unlock()
+
+	c.notify(&ConnectionEvent{Kind: NodeSwitched, Node: current, Previous: previous})
+}
+
+// writeNode returns the base url of the node writes are sent to. The caller
+// must hold the lock.
+func (c *Client) writeNode() string {
+	if c.master != nil {
+		return nodeOf(c.master)
+	}
+	return nodeOf(c.baseURL)
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&MasterSuite{})
+
+type MasterSuite struct{}
+
+func (s *MasterSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *MasterSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// masterNode is a test server standing in for the master of a cluster. It
+// records the events written to it.
+type masterNode struct {
+	*httptest.Server
+	mu     sync.Mutex
+	writes [][]map[string]interface{}
+}
+
+func newMasterNode(c *C) *masterNode {
+	m := &masterNode{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("ES-RequireMaster"), Equals, "True")
+		body := []map[string]interface{}{}
+		c.Check(json.NewDecoder(r.Body).Decode(&body), IsNil)
+		m.mu.Lock()
+		m.writes = append(m.writes, body)
+		m.mu.Unlock()
+		w.Header().Set("Location", m.URL+r.URL.Path+"/0")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	return m
+}
+
+func (m *masterNode) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.writes)
+}
+
+// redirectTo makes the server a follower that redirects writes requiring the
+// master to the master, and returns a function returning the number of writes
+// the follower received.
+func redirectTo(master *masterNode) func() int {
+	var mu sync.Mutex
+	n := 0
+	mux.HandleFunc("/streams/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n++
+		mu.Unlock()
+		if r.Header.Get("ES-RequireMaster") != "True" {
+			http.Error(w, "Not master", http.StatusServiceUnavailable)
+			return
+		}
+		http.Redirect(w, r, master.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	})
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return n
+	}
+}
+
+func (s *MasterSuite) TestRequireMasterIsSentWithWritesOnly(c *C) {
+	var headers []string
+	mux.HandleFunc("/streams/", func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Method+" "+r.Header.Get("ES-RequireMaster"))
+		w.Header().Set("Location", server.URL+"/streams/master-1/0")
+		w.WriteHeader(http.StatusCreated)
+	})
+	client.SetRequireMaster(true)
+
+	c.Assert(client.NewStreamWriter("master-1").Append(ExpectAny, CreateTestEvents(1, "master-1", server.URL, "Foo")...), IsNil)
+	client.GetStreamHeadVersion("master-1")
+	c.Assert(headers, DeepEquals, []string{"POST True", "GET "})
+
+	client.SetRequireMaster(false)
+	headers = nil
+	_, err := client.AppendToStream("master-1", CreateTestEvents(1, "master-1", server.URL, "Foo"), WithRequireMaster())
+	c.Assert(err, IsNil)
+	c.Assert(headers, DeepEquals, []string{"POST True"})
+}
+
+func (s *MasterSuite) TestWriteIsRedirectedToMaster(c *C) {
+	master := newMasterNode(c)
+	defer master.Close()
+	follower := redirectTo(master)
+	client.SetRequireMaster(true)
+
+	events := CreateTestEvents(2, "master-2", server.URL, "Foo")
+	c.Assert(client.NewStreamWriter("master-2").Append(ExpectAny, events...), IsNil)
+	c.Assert(client.NewStreamWriter("master-2").Append(ExpectAny, events...), IsNil)
+
+	c.Assert(follower(), Equals, 2)
+	c.Assert(master.count(), Equals, 2)
+	c.Assert(master.writes[0], HasLen, 2)
+	c.Assert(master.writes[0][1]["eventId"], Equals, events[1].EventID)
+	c.Assert(client.Master(), Equals, "")
+}
+
+func (s *MasterSuite) TestPreferMasterSendsWritesToMaster(c *C) {
+	master := newMasterNode(c)
+	follower := redirectTo(master)
+	client.SetRequireMaster(true)
+	client.SetPreferMaster(true)
+
+	events := CreateTestEvents(1, "master-3", server.URL, "Foo")
+	c.Assert(client.NewStreamWriter("master-3").Append(ExpectAny, events...), IsNil)
+	c.Assert(client.Master(), Equals, master.URL)
+	c.Assert(client.NewStreamWriter("master-3").Append(ExpectAny, events...), IsNil)
+	c.Assert(follower(), Equals, 1)
+	c.Assert(master.count(), Equals, 2)
+
+	// Writes go back to the server once the master cannot be reached.
+	master.Close()
+	c.Assert(client.NewStreamWriter("master-3").Append(ExpectAny, events...), NotNil)
+	c.Assert(client.Master(), Equals, "")
+	client.NewStreamWriter("master-3").Append(ExpectAny, events...)
+	c.Assert(follower(), Equals, 2)
+}
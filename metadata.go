@@ -0,0 +1,247 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StreamMetadata is a typed representation of stream metadata.
+//
+// The fields correspond to the reserved metadata keys of the eventstore. Nil
+// fields are not written. Custom contains any other metadata keys.
+//
+// For more information on stream metadata see:
+// http://docs.geteventstore.com/http-api/3.7.0/stream-metadata/
+type StreamMetadata struct {
+	MaxCount       *int                   `json:"$maxCount,omitempty"`
+	MaxAge         *int                   `json:"$maxAge,omitempty"`
+	TruncateBefore *int                   `json:"$tb,omitempty"`
+	CacheControl   *int                   `json:"$cacheControl,omitempty"`
+	ACL            *StreamACL             `json:"$acl,omitempty"`
+	Custom         map[string]interface{} `json:"-"`
+}
+
+// StreamACL is the access control list of a stream.
+type StreamACL struct {
+	Read      Roles `json:"$r,omitempty"`
+	Write     Roles `json:"$w,omitempty"`
+	Delete    Roles `json:"$d,omitempty"`
+	MetaRead  Roles `json:"$mr,omitempty"`
+	MetaWrite Roles `json:"$mw,omitempty"`
+}
+
+// Roles is a list of users or groups in a StreamACL. The eventstore accepts
+// either a single role as a string or an array of roles.
+type Roles []string
+
+// UnmarshalJSON accepts either a string or an array of strings.
+func (r *Roles) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*r = Roles{s}
+		return nil
+	}
+	var a []string
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	*r = Roles(a)
+	return nil
+}
+
+// streamMetadataFields is used to marshal the reserved fields of StreamMetadata
+// without recursing into its MarshalJSON method.
+type streamMetadataFields StreamMetadata
+
+// MarshalJSON renders the reserved fields and the custom fields as a single
+// JSON object.
+func (m *StreamMetadata) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.toMap())
+}
+
+// UnmarshalJSON parses the reserved fields and collects all other keys into
+// Custom.
+func (m *StreamMetadata) UnmarshalJSON(b []byte) error {
+	f := streamMetadataFields{}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	all := make(map[string]interface{})
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	for k, v := range all {
+		if isReservedMetadataKey(k) {
+			continue
+		}
+		if f.Custom == nil {
+			f.Custom = make(map[string]interface{})
+		}
+		f.Custom[k] = v
+	}
+	*m = StreamMetadata(f)
+	return nil
+}
+
+// toMap returns the metadata as a map of JSON values.
+func (m *StreamMetadata) toMap() map[string]interface{} {
+	ret := make(map[string]interface{})
+	for k, v := range m.Custom {
+		ret[k] = v
+	}
+	b, _ := json.Marshal((*streamMetadataFields)(m))
+	reserved := make(map[string]interface{})
+	json.Unmarshal(b, &reserved)
+	for k, v := range reserved {
+		ret[k] = v
+	}
+	return ret
+}
+
+// isReservedMetadataKey returns true for metadata keys reserved by the
+// eventstore. Reserved keys begin with $.
+func isReservedMetadataKey(k string) bool {
+	return strings.HasPrefix(k, "$")
+}
+
+// ReadStreamMetadata reads the metadata of a stream.
+//
+// If the stream has no metadata an empty *StreamMetadata is returned.
+func (c *Client) ReadStreamMetadata(stream string) (*StreamMetadata, error) {
+	er, err := c.NewStreamReader(stream).MetaData()
+	if err != nil {
+		return nil, err
+	}
+	m := &StreamMetadata{}
+	if er == nil {
+		return m, nil
+	}
+	if err := c.decodeEvent(er, m, nil); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MetaDataResult is the metadata of a stream read with GetStreamMetaData.
+//
+// Exists is false if no metadata has been written for the stream, in which
+// case Version is -1 and Metadata is empty. Metadata that has been written
+// with no keys exists and is empty. Version is the version of the metadata
+// stream and ETag is the entity tag the server returned with the metadata, if
+// any.
+//
+// A result can be passed to WriteStreamMetadataIfMatch to write new metadata
+// only if the metadata has not changed since it was read.
+type MetaDataResult struct {
+	Stream   string
+	Exists   bool
+	Version  int
+	ETag     string
+	Metadata *StreamMetadata
+}
+
+// GetStreamMetaData reads the metadata of a stream along with its version.
+//
+// Unlike ReadStreamMetadata, the result distinguishes a stream without metadata
+// from a stream whose metadata is empty. A stream that does not exist has no
+// metadata.
+func (c *Client) GetStreamMetaData(stream string) (*MetaDataResult, error) {
+	result := &MetaDataResult{Stream: stream, Version: -1, Metadata: &StreamMetadata{}}
+	er, resp, err := c.GetEvent(fmt.Sprintf("/streams/%s/metadata", stream))
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound:
+		return result, nil
+	default:
+		return nil, err
+	}
+	if er == nil {
+		return result, nil
+	}
+	if err := c.decodeEvent(er, result.Metadata, nil); err != nil {
+		return nil, err
+	}
+	result.Exists = true
+	result.Version = er.Event.EventNumber
+	if resp != nil {
+		result.ETag = resp.ETag
+	}
+	return result, nil
+}
+
+// WriteStreamMetadataIfMatch replaces the metadata of the stream read into
+// current only if it has not been changed since it was read. If it has been
+// changed an *ErrConcurrencyViolation is returned; read the metadata again and
+// reapply the change.
+//
+// The write is made conditional with the version of the metadata stream, so
+// concurrent edits of the metadata of a stream do not overwrite each other.
+func (c *Client) WriteStreamMetadataIfMatch(current *MetaDataResult, m *StreamMetadata) error {
+	return c.postMetaData(fmt.Sprintf("/streams/%s/metadata", current.Stream), m, ExpectedVersion(current.Version))
+}
+
+// MetadataVersion is a version of the metadata of a stream.
+//
+// Version is the event number of the version in the metadata stream. Updated
+// is the time the version was written, or the zero time if the server did not
+// report it.
+type MetadataVersion struct {
+	Version  int
+	Updated  time.Time
+	Metadata *StreamMetadata
+}
+
+// MetadataStreamName returns the name of the stream in which the metadata of a
+// stream is stored.
+func MetadataStreamName(stream string) string {
+	return "$$" + stream
+}
+
+// ReadStreamMetadataHistory reads every version of the metadata of a stream,
+// oldest first, so that changes to access control and retention can be
+// audited.
+//
+// The history is read from the metadata stream of the stream. Versions removed
+// by the $maxCount or $maxAge of the metadata stream itself are not returned.
+// If the stream has never had metadata an empty slice is returned.
+func (c *Client) ReadStreamMetadataHistory(stream string) ([]*MetadataVersion, error) {
+	history := []*MetadataVersion{}
+	err := c.ForEachEvent(MetadataStreamName(stream), 0, "forward", func(er *EventResponse) error {
+		m := &StreamMetadata{}
+		if err := c.decodeEvent(er, m, nil); err != nil {
+			return err
+		}
+		v := &MetadataVersion{Version: er.Event.EventNumber, Metadata: m}
+		if t, err := er.Updated.Time(); err == nil {
+			v.Updated = t
+		}
+		history = append(history, v)
+		return nil
+	})
+	if _, ok := err.(*ErrNotFound); ok {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// WriteStreamMetadata writes the metadata of a stream, replacing the current
+// metadata.
+func (c *Client) WriteStreamMetadata(stream string, m *StreamMetadata) error {
+	return c.NewStreamWriter(stream).WriteMetaData(stream, m)
+}
+
+// Int returns a pointer to the int provided. It is a helper for setting the
+// optional fields of StreamMetadata.
+func Int(v int) *int {
+	return &v
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&MetadataSuite{})
+
+type MetadataSuite struct{}
+
+func (s *MetadataSuite) TestStreamMetadataRoundTrip(c *C) {
+	in := `{"$maxCount":10,"$acl":{"$r":"$all","$w":["ops","admin"]},"owner":"billing"}`
+
+	m := &StreamMetadata{}
+	c.Assert(json.Unmarshal([]byte(in), m), IsNil)
+	c.Assert(*m.MaxCount, Equals, 10)
+	c.Assert(m.MaxAge, IsNil)
+	c.Assert(m.ACL.Read, DeepEquals, Roles{"$all"})
+	c.Assert(m.ACL.Write, DeepEquals, Roles{"ops", "admin"})
+	c.Assert(m.Custom, DeepEquals, map[string]interface{}{"owner": "billing"})
+
+	out, err := json.Marshal(m)
+	c.Assert(err, IsNil)
+	got := map[string]interface{}{}
+	c.Assert(json.Unmarshal(out, &got), IsNil)
+	c.Assert(got, DeepEquals, map[string]interface{}{
+		"$maxCount": float64(10),
+		"$acl":      map[string]interface{}{"$r": []interface{}{"$all"}, "$w": []interface{}{"ops", "admin"}},
+		"owner":     "billing",
+	})
+}
+
+func (s *MetadataSuite) TestEmptyStreamMetadataMarshalsToEmptyObject(c *C) {
+	out, err := json.Marshal(&StreamMetadata{})
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, "{}")
+}
+
+var _ = Suite(&MetadataHistorySuite{})
+
+type MetadataHistorySuite struct{}
+
+func (s *MetadataHistorySuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *MetadataHistorySuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *MetadataHistorySuite) TestReadStreamMetadataHistory(c *C) {
+	stream := MetadataStreamName("audited")
+	versions := []*StreamMetadata{
+		{MaxCount: Int(10)},
+		{MaxCount: Int(10), ACL: &StreamACL{Read: Roles{"ops"}}},
+		{MaxAge: Int(3600)},
+	}
+	es := []*Event{}
+	for i, m := range versions {
+		e := CreateTestEventFromData(stream, server.URL, i, m, nil)
+		e.EventType = "$metadata"
+		es = append(es, e)
+	}
+	setupSimulator(es, nil)
+
+	history, err := client.ReadStreamMetadataHistory("audited")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	for i, v := range history {
+		c.Assert(v.Version, Equals, i)
+		c.Assert(v.Metadata, DeepEquals, versions[i])
+		c.Assert(v.Updated.IsZero(), Equals, false)
+	}
+}
+
+func (s *MetadataHistorySuite) TestReadStreamMetadataHistoryWithoutMetadata(c *C) {
+	mux.HandleFunc("/streams/$$plain/", http.NotFound)
+
+	history, err := client.ReadStreamMetadataHistory("plain")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 0)
+}
+
+func (s *MetadataHistorySuite) TestGetStreamMetaDataWithoutMetadata(c *C) {
+	serveMetadataStore(c, "bare")
+
+	got, err := client.GetStreamMetaData("bare")
+	c.Assert(err, IsNil)
+	c.Assert(got.Exists, Equals, false)
+	c.Assert(got.Version, Equals, -1)
+	c.Assert(got.ETag, Equals, "")
+	c.Assert(got.Metadata, DeepEquals, &StreamMetadata{})
+}
+
+func (s *MetadataHistorySuite) TestGetStreamMetaDataEmptyMetadataExists(c *C) {
+	serveMetadataStore(c, "emptied")
+	got, err := client.GetStreamMetaData("emptied")
+	c.Assert(err, IsNil)
+	c.Assert(client.WriteStreamMetadataIfMatch(got, &StreamMetadata{}), IsNil)
+
+	got, err = client.GetStreamMetaData("emptied")
+	c.Assert(err, IsNil)
+	c.Assert(got.Exists, Equals, true)
+	c.Assert(got.Version, Equals, 0)
+	c.Assert(got.ETag, Equals, `"0;-2060438500"`)
+	c.Assert(got.Metadata, DeepEquals, &StreamMetadata{})
+}
+
+func (s *MetadataHistorySuite) TestWriteStreamMetadataIfMatch(c *C) {
+	st := serveMetadataStore(c, "guarded")
+
+	first, err := client.GetStreamMetaData("guarded")
+	c.Assert(err, IsNil)
+	c.Assert(client.WriteStreamMetadataIfMatch(first, &StreamMetadata{MaxCount: Int(10)}), IsNil)
+
+	second, err := client.GetStreamMetaData("guarded")
+	c.Assert(err, IsNil)
+	c.Assert(*second.Metadata.MaxCount, Equals, 10)
+	c.Assert(second.Version, Equals, 0)
+
+	// A write based on the stale read conflicts.
+	err = client.WriteStreamMetadataIfMatch(first, &StreamMetadata{MaxAge: Int(60)})
+	c.Assert(err, FitsTypeOf, &ErrConcurrencyViolation{})
+
+	c.Assert(client.WriteStreamMetadataIfMatch(second, &StreamMetadata{MaxAge: Int(60)}), IsNil)
+	meta, writes := st.state()
+	c.Assert(writes, Equals, 2)
+	c.Assert(meta, DeepEquals, map[string]interface{}{"$maxAge": float64(60)})
+}
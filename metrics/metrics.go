@@ -0,0 +1,262 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+// Package metrics collects metrics of the requests made by a goes.Client for
+// Prometheus.
+//
+// A Collector records, by operation and status, the number and duration of
+// the requests made by the clients it instruments and the bytes they read and
+// write. It also counts the retries the server asks for, the connection events
+// of the clients and reports the lag of the consumers tracked by
+// goes.LagMonitor.
+//
+// A Collector is a prometheus.Collector and is registered with a registry
+// supplied by the application, which serves it with the rest of the metrics of
+// the application:
+//
+//	m := metrics.NewCollector("")
+//	m.Instrument(client)
+//	registry.MustRegister(m)
+//	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector collects the metrics of the clients it instruments.
+//
+// A Collector is safe for concurrent use and can instrument any number of
+// clients, whose metrics are added together.
+type Collector struct {
+	requests  *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+	read      *prometheus.CounterVec
+	written   *prometheus.CounterVec
+	retries   prometheus.Counter
+	events    *prometheus.CounterVec
+	lag       *prometheus.Desc
+
+	mu   sync.Mutex
+	lags []*goes.LagMonitor
+}
+
+// NewCollector returns a *Collector whose metric names begin with namespace
+// and an underscore. If namespace is empty "goes" is used.
+//
+// The request durations are observed in the default buckets of the Prometheus
+// client, prometheus.DefBuckets.
+func NewCollector(namespace string) *Collector {
+	if namespace == "" {
+		namespace = "goes"
+	}
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Requests made to the eventstore by operation and status.",
+		}, []string{"operation", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time until the response headers of a request were received, by operation and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "status"}),
+		read: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "read_bytes_total",
+			Help:      "Bytes received from the eventstore by operation.",
+		}, []string{"operation"}),
+		written: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "written_bytes_total",
+			Help:      "Bytes sent to the eventstore by operation.",
+		}, []string{"operation"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Times the eventstore asked the client to wait before retrying.",
+		}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "connection_events_total",
+			Help:      "Connection events by kind.",
+		}, []string{"kind"}),
+		lag: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "subscription_lag_events"),
+			"Events between the position of a consumer and the head of its stream.",
+			[]string{"consumer", "stream"}, nil),
+	}
+}
+
+// Instrument adds the middleware of the collector to the client and sets the
+// collector as its connection listener, replacing any listener the client had.
+// To keep another listener, add the Middleware and call OnConnectionEvent from
+// that listener instead.
+func (m *Collector) Instrument(c *goes.Client) {
+	c.Use(m.Middleware())
+	c.SetConnectionListener(m)
+}
+
+// Middleware returns a goes.Middleware that records the requests of a client.
+//
+// The duration of a request is the time until the headers of the response are
+// received. Bytes are counted as they are sent and received, so compressed
+// bodies are counted compressed. Requests that fail without a response have
+// the status "error".
+func (m *Collector) Middleware() goes.Middleware {
+	return func(req *http.Request, next goes.RoundTripFunc) (*http.Response, error) {
+		op := Operation(req)
+		if req.Body != nil && req.Body != http.NoBody {
+			written := m.written.WithLabelValues(op)
+			req.Body = &countingBody{ReadCloser: req.Body, add: func(n int) { written.Add(float64(n)) }}
+		}
+
+		start := time.Now()
+		resp, err := next(req)
+		status := "error"
+		if resp != nil {
+			status = strconv.Itoa(resp.StatusCode)
+			if resp.Body != nil {
+				read := m.read.WithLabelValues(op)
+				resp.Body = &countingBody{ReadCloser: resp.Body, add: func(n int) { read.Add(float64(n)) }}
+			}
+		}
+		m.requests.WithLabelValues(op, status).Inc()
+		m.durations.WithLabelValues(op, status).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// Operation returns the name of the operation made by a request of a client,
+// which is used to label its metrics: "append", "read_feed", "read_event",
+// "read_metadata", "write_metadata", "delete_stream",
+// "persistent_subscription", "projection", "info" or "other".
+func Operation(req *http.Request) string {
+	// The server may be mounted under a base path, so the operation is given
+	// by the first segment that names a resource of the server.
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, seg := range segments {
+		rest := segments[i+1:]
+		switch seg {
+		case "streams":
+			if len(rest) == 0 {
+				return "other"
+			}
+			return streamOperation(req.Method, rest)
+		case "subscriptions":
+			return "persistent_subscription"
+		case "projection", "projections":
+			return "projection"
+		case "info", "ping":
+			return "info"
+		}
+	}
+	return "other"
+}
+
+// streamOperation returns the operation of a request to the path of a stream
+// given by its segments after /streams.
+func streamOperation(method string, segments []string) string {
+	metadata := len(segments) == 2 && segments[1] == "metadata"
+	switch method {
+	case http.MethodPost:
+		if metadata {
+			return "write_metadata"
+		}
+		return "append"
+	case http.MethodDelete:
+		return "delete_stream"
+	case http.MethodGet, http.MethodHead:
+		if metadata {
+			return "read_metadata"
+		}
+		if len(segments) == 2 {
+			if _, err := strconv.Atoi(segments[1]); err == nil {
+				return "read_event"
+			}
+		}
+		return "read_feed"
+	}
+	return "other"
+}
+
+// OnConnectionEvent implements goes.ConnectionListener. It counts the event
+// and, for goes.RetryScheduled, the retry.
+func (m *Collector) OnConnectionEvent(e *goes.ConnectionEvent) {
+	m.events.WithLabelValues(string(e.Kind)).Inc()
+	if e.Kind == goes.RetryScheduled {
+		m.retries.Inc()
+	}
+}
+
+// TrackLag adds the consumers of the monitor to the subscription lag reported
+// by the collector. The lag is measured when the metrics are collected. A
+// consumer of a stream must be tracked by only one of the monitors.
+func (m *Collector) TrackLag(monitor *goes.LagMonitor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lags = append(m.lags, monitor)
+}
+
+// Describe implements prometheus.Collector.
+func (m *Collector) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.durations.Describe(ch)
+	m.read.Describe(ch)
+	m.written.Describe(ch)
+	m.retries.Describe(ch)
+	m.events.Describe(ch)
+	ch <- m.lag
+}
+
+// Collect implements prometheus.Collector.
+//
+// The lag of the consumers tracked with TrackLag is measured by reading the
+// heads of their streams. If that fails the lag of the consumers of the
+// monitor is left out.
+func (m *Collector) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.durations.Collect(ch)
+	m.read.Collect(ch)
+	m.written.Collect(ch)
+	m.retries.Collect(ch)
+	m.events.Collect(ch)
+
+	m.mu.Lock()
+	lags := append([]*goes.LagMonitor(nil), m.lags...)
+	m.mu.Unlock()
+	for _, monitor := range lags {
+		consumers, err := monitor.Lag()
+		if err != nil {
+			continue
+		}
+		for _, cl := range consumers {
+			ch <- prometheus.MustNewConstMetric(m.lag, prometheus.GaugeValue, float64(cl.Lag), cl.Consumer, cl.Stream)
+		}
+	}
+}
+
+// countingBody counts the bytes read from a request or response body.
+type countingBody struct {
+	io.ReadCloser
+	add func(n int)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.add(n)
+	}
+	return n, err
+}
@@ -0,0 +1,201 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jetbasrawi/go.geteventstore"
+	"github.com/jetbasrawi/go.geteventstore/estest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&MetricsSuite{})
+
+type MetricsSuite struct {
+	sim    *estest.Simulator
+	server *httptest.Server
+	client *goes.Client
+}
+
+type OrderPlaced struct {
+	ID int `json:"id"`
+}
+
+func (s *MetricsSuite) SetUpTest(c *C) {
+	s.sim = estest.NewSimulator()
+	s.server = httptest.NewServer(s.sim)
+	client, err := goes.NewClient(nil, s.server.URL)
+	c.Assert(err, IsNil)
+	s.client = client
+}
+
+func (s *MetricsSuite) TearDownTest(c *C) {
+	s.server.Close()
+}
+
+// family registers the collector with a new registry and returns the family
+// gathered with the name.
+func family(c *C, m *Collector, name string) *dto.MetricFamily {
+	reg := prometheus.NewPedanticRegistry()
+	c.Assert(reg.Register(m), IsNil)
+	mfs, err := reg.Gather()
+	c.Assert(err, IsNil)
+	for _, f := range mfs {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	c.Fatalf("no family %s", name)
+	return nil
+}
+
+// metric returns the metric of the family with the label values.
+func metric(c *C, f *dto.MetricFamily, labels ...string) *dto.Metric {
+	for _, m := range f.GetMetric() {
+		values := []string{}
+		for _, l := range m.GetLabel() {
+			values = append(values, l.GetValue())
+		}
+		if strings.Join(values, ",") == strings.Join(labels, ",") {
+			return m
+		}
+	}
+	c.Fatalf("no metric %v in %s", labels, f.GetName())
+	return nil
+}
+
+// value returns the value of the counter or gauge of the family with the
+// label values.
+func value(c *C, f *dto.MetricFamily, labels ...string) float64 {
+	m := metric(c, f, labels...)
+	if f.GetType() == dto.MetricType_GAUGE {
+		return m.GetGauge().GetValue()
+	}
+	return m.GetCounter().GetValue()
+}
+
+func (s *MetricsSuite) TestRequestsAreRecordedByOperationAndStatus(c *C) {
+	m := NewCollector("")
+	m.Instrument(s.client)
+
+	writer := s.client.NewStreamWriter("orders-1")
+	for i := 0; i < 3; i++ {
+		c.Assert(writer.Append(goes.ExpectAny, goes.NewEvent("", "OrderPlaced", &OrderPlaced{ID: i}, nil)), IsNil)
+	}
+	reader := s.client.NewStreamReader("orders-1")
+	for reader.Next() {
+		if reader.Err() != nil {
+			break
+		}
+	}
+	_, _, err := s.client.GetEvent("/streams/orders-2/0")
+	c.Assert(err, NotNil)
+
+	requests := family(c, m, "goes_requests_total")
+	c.Assert(requests.GetType(), Equals, dto.MetricType_COUNTER)
+	c.Assert(value(c, requests, "append", "201"), Equals, 3.0)
+	c.Assert(value(c, requests, "read_event", "200"), Equals, 3.0)
+	c.Assert(value(c, requests, "read_event", "404"), Equals, 1.0)
+
+	durations := family(c, m, "goes_request_duration_seconds")
+	c.Assert(durations.GetType(), Equals, dto.MetricType_HISTOGRAM)
+	h := metric(c, durations, "append", "201").GetHistogram()
+	c.Assert(h.GetSampleCount(), Equals, uint64(3))
+	c.Assert(h.GetBucket(), HasLen, len(prometheus.DefBuckets))
+
+	c.Assert(value(c, family(c, m, "goes_written_bytes_total"), "append") > 0, Equals, true)
+	c.Assert(value(c, family(c, m, "goes_read_bytes_total"), "read_event") > 0, Equals, true)
+	c.Assert(value(c, family(c, m, "goes_connection_events_total"), "NodeConnected"), Equals, 1.0)
+}
+
+func (s *MetricsSuite) TestRetriesAreCounted(c *C) {
+	m := NewCollector("es")
+	m.OnConnectionEvent(&goes.ConnectionEvent{Kind: goes.RetryScheduled})
+	m.OnConnectionEvent(&goes.ConnectionEvent{Kind: goes.RetryScheduled})
+	m.OnConnectionEvent(&goes.ConnectionEvent{Kind: goes.AuthFailed})
+
+	c.Assert(value(c, family(c, m, "es_retries_total")), Equals, 2.0)
+	events := family(c, m, "es_connection_events_total")
+	c.Assert(value(c, events, "RetryScheduled"), Equals, 2.0)
+	c.Assert(value(c, events, "AuthFailed"), Equals, 1.0)
+}
+
+func (s *MetricsSuite) TestSubscriptionLag(c *C) {
+	for i := 0; i < 5; i++ {
+		c.Assert(s.sim.Append("orders-1", goes.NewEvent("", "OrderPlaced", &OrderPlaced{ID: i}, nil)), IsNil)
+	}
+	monitor := s.client.NewLagMonitor()
+	monitor.Track("summary", "orders-1", func() int { return 1 })
+
+	m := NewCollector("")
+	m.TrackLag(monitor)
+	c.Assert(value(c, family(c, m, "goes_subscription_lag_events"), "summary", "orders-1"), Equals, 3.0)
+}
+
+func (s *MetricsSuite) TestCollectorIsRegisteredWithARegistry(c *C) {
+	m := NewCollector("")
+	m.OnConnectionEvent(&goes.ConnectionEvent{Kind: goes.RetryScheduled})
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:2113/streams/orders-1", nil)
+	c.Assert(err, IsNil)
+	_, err = m.Middleware()(req, func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+	c.Assert(err, IsNil)
+
+	reg := prometheus.NewPedanticRegistry()
+	c.Assert(reg.Register(m), IsNil)
+	c.Assert(reg.Register(NewCollector("")), FitsTypeOf, prometheus.AlreadyRegisteredError{})
+
+	expected := `
+# HELP goes_connection_events_total Connection events by kind.
+# TYPE goes_connection_events_total counter
+goes_connection_events_total{kind="RetryScheduled"} 1
+# HELP goes_requests_total Requests made to the eventstore by operation and status.
+# TYPE goes_requests_total counter
+goes_requests_total{operation="read_feed",status="200"} 1
+# HELP goes_retries_total Times the eventstore asked the client to wait before retrying.
+# TYPE goes_retries_total counter
+goes_retries_total 1
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"goes_connection_events_total", "goes_requests_total", "goes_retries_total")
+	c.Assert(err, IsNil)
+	c.Assert(testutil.CollectAndCount(m, "goes_request_duration_seconds"), Equals, 1)
+}
+
+func (s *MetricsSuite) TestOperation(c *C) {
+	cases := []struct {
+		method, path, op string
+	}{
+		{"POST", "/streams/orders-1", "append"},
+		{"GET", "/streams/orders-1/head/backward/20", "read_feed"},
+		{"GET", "/streams/orders-1", "read_feed"},
+		{"GET", "/es/streams/orders-1/12", "read_event"},
+		{"GET", "/streams/orders-1/metadata", "read_metadata"},
+		{"POST", "/streams/orders-1/metadata/", "write_metadata"},
+		{"DELETE", "/streams/orders-1", "delete_stream"},
+		{"GET", "/streams/streams/3", "read_event"},
+		{"PUT", "/subscriptions/orders-1/group", "persistent_subscription"},
+		{"GET", "/projection/summary/state", "projection"},
+		{"GET", "/info", "info"},
+		{"GET", "/", "other"},
+	}
+	for _, t := range cases {
+		req, err := http.NewRequest(t.method, "http://localhost:2113"+t.path, nil)
+		c.Assert(err, IsNil)
+		c.Assert(Operation(req), Equals, t.op, Commentf("%s %s", t.method, t.path))
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+)
+
+// RoundTripFunc sends a request and returns the response.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the sending of every request made by a client.
+//
+// A middleware receives the request and next, which sends the request through
+// the rest of the chain, and returns the response. It can change the request
+// before calling next, for example to sign it, inspect the response, for
+// example to log it, or return a response or error without calling next, for
+// example to inject faults in tests.
+//
+// The response returned is handled as a response from the server, so a
+// middleware that returns a response with an error status causes the client
+// method to return the corresponding error.
+type Middleware func(req *http.Request, next RoundTripFunc) (*http.Response, error)
+
+// Use adds middleware to the client.
+//
+// Every request the client makes passes through the middleware, including the
+// requests made by readers to page through feeds and read events, by writers
+// and by subscriptions. Middleware is called in the order it was added, so the
+// first middleware added sees the request first and the response last.
+//
+// Requests reach the middleware after the client has added its headers and
+// credentials and compressed the body, so the request is as it will be sent.
+// Sessions created with NewSession use the middleware of the client at the time
+// they were created.
+func (c *Client) Use(mw ...Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middleware = append(c.middleware[:len(c.middleware):len(c.middleware)], mw...)
+}
+
+// roundTrip sends the request through the middleware of the client.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.RLock()
+	chain := c.middleware
+	debug := c.debug
+	c.mu.RUnlock()
+
+	// Redirects are followed by followRedirects rather than the http client,
+	// which drops the credentials when redirected to another host.
+	hc := *c.client
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	next := RoundTripFunc(hc.Do)
+	if debug != nil {
+		send := next
+		next = func(req *http.Request) (*http.Response, error) {
+			return debug.roundTrip(req, send)
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		mw, inner := chain[i], next
+		next = func(req *http.Request) (*http.Response, error) {
+			return mw(req, inner)
+		}
+	}
+	resp, err := next(req)
+	c.observe(req, err)
+	return resp, err
+}
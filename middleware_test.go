@@ -0,0 +1,143 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&MiddlewareSuite{})
+
+type MiddlewareSuite struct{}
+
+func (s *MiddlewareSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *MiddlewareSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// recordPaths returns a middleware that records the paths of the requests it
+// sees.
+func recordPaths() (Middleware, func() []string) {
+	var mu sync.Mutex
+	paths := []string{}
+	mw := func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		mu.Lock()
+		paths = append(paths, req.URL.Path)
+		mu.Unlock()
+		return next(req)
+	}
+	return mw, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, paths...)
+	}
+}
+
+func (s *MiddlewareSuite) TestReaderRequestsPassThroughMiddleware(c *C) {
+	stream := "mw-read"
+	setupSimulator(CreateTestEvents(3, stream, server.URL, "Foo"), nil)
+	mw, paths := recordPaths()
+	client.Use(mw)
+
+	got := readAll(c, client.NewStreamReader(stream))
+	c.Assert(got, DeepEquals, sequence(3))
+
+	feeds, events := 0, 0
+	for _, p := range paths() {
+		switch {
+		case strings.Contains(p, "/forward/"):
+			feeds++
+		case strings.HasPrefix(p, "/streams/"+stream+"/"):
+			events++
+		}
+	}
+	c.Assert(feeds > 0, Equals, true)
+	c.Assert(events, Equals, 3)
+}
+
+func (s *MiddlewareSuite) TestMiddlewareOrderAndRequestChanges(c *C) {
+	var signature string
+	mux.HandleFunc("/streams/mw-write", func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Signature")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	order := []string{}
+	client.Use(
+		func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+			order = append(order, "outer")
+			resp, err := next(req)
+			order = append(order, "outer done")
+			return resp, err
+		},
+		func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+			order = append(order, "inner")
+			b, _ := ioutil.ReadAll(req.Body)
+			req.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+			req.Header.Set("X-Signature", "signed:"+strings.ToUpper(req.Method))
+			return next(req)
+		},
+	)
+
+	err := client.NewStreamWriter("mw-write").Append(ExpectAny, NewEvent("", "Foo", map[string]string{"a": "b"}, nil))
+	c.Assert(err, IsNil)
+	c.Assert(signature, Equals, "signed:POST")
+	c.Assert(order, DeepEquals, []string{"outer", "inner", "outer done"})
+}
+
+func (s *MiddlewareSuite) TestMiddlewareInjectsFaults(c *C) {
+	stream := "mw-chaos"
+	setupSimulator(CreateTestEvents(1, stream, server.URL, "Foo"), nil)
+
+	errInjected := errors.New("injected")
+	client.Use(func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		return nil, errInjected
+	})
+	_, err := client.GetStreamHeadVersion(stream)
+	c.Assert(err, Equals, errInjected)
+}
+
+func (s *MiddlewareSuite) TestMiddlewareResponseIsHandledAsServerResponse(c *C) {
+	client.Use(func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Status:     "503 Service Unavailable",
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	})
+	_, err := client.GetStreamHeadVersion("mw-unavailable")
+	c.Assert(err, FitsTypeOf, &ErrTemporarilyUnavailable{})
+}
+
+func (s *MiddlewareSuite) TestSessionUsesClientMiddleware(c *C) {
+	stream := "mw-session"
+	setupSimulator(CreateTestEvents(2, stream, server.URL, "Foo"), nil)
+	mw, paths := recordPaths()
+	client.Use(mw)
+
+	session := client.NewSession()
+	defer session.Close()
+	_, err := session.GetStreamHeadVersion(stream)
+	c.Assert(err, IsNil)
+	c.Assert(paths(), HasLen, 1)
+
+	// Middleware added to the session does not affect the client.
+	other, otherPaths := recordPaths()
+	session.Use(other)
+	_, err = client.GetStreamHeadVersion(stream)
+	c.Assert(err, IsNil)
+	c.Assert(otherPaths(), HasLen, 0)
+}
@@ -0,0 +1,153 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+// ByTimestamp orders events by the time they were written, as reported in the
+// Updated field of the event response.
+//
+// The eventstore reports times to the second, so events written in the same
+// second are equal and are returned by a MultiStreamReader in the order of
+// their streams.
+func ByTimestamp(a, b *EventResponse) bool {
+	ta, erra := a.Updated.Time()
+	tb, errb := b.Updated.Time()
+	if erra != nil || errb != nil {
+		return a.Updated < b.Updated
+	}
+	return ta.Before(tb)
+}
+
+// MultiStreamReader reads several streams and returns their events merged into
+// a single sequence.
+//
+// Each stream is read in the background, so the streams are read concurrently.
+// Events are returned in stream order within each stream. Across streams the
+// event that is returned next is the one that is least by the less function
+// provided to NewMultiStreamReader. When events are equal the event from the
+// stream listed first is returned first. The merge is only correct if each
+// stream is itself ordered by the less function, as streams are when ordered by
+// ByTimestamp.
+//
+// The event responses served over http do not include the position of the
+// event in the $all stream. To merge by another key, such as a sequence number
+// written in the event metadata, provide a less function that compares it.
+//
+// A MultiStreamReader is used in the same way as a StreamReader. When all of
+// the streams have been read Next() returns true and Err() returns an
+// *ErrNoMoreEvents. Streams that do not exist are treated as empty. If any other
+// error occurs reading a stream Err() returns the error and the stream is read
+// again on the next call to Next().
+//
+// A MultiStreamReader should be closed when it is no longer required.
+type MultiStreamReader struct {
+	client        *Client
+	less          func(a, b *EventResponse) bool
+	readers       []*StreamReader
+	heads         []*EventResponse
+	done          []bool
+	eventResponse *EventResponse
+	lasterr       error
+}
+
+// NewMultiStreamReader returns a reader that merges the events of the streams
+// using less to order them. If less is nil ByTimestamp is used.
+func (c *Client) NewMultiStreamReader(streams []string, less func(a, b *EventResponse) bool) *MultiStreamReader {
+	if less == nil {
+		less = ByTimestamp
+	}
+	m := &MultiStreamReader{
+		client:  c,
+		less:    less,
+		readers: make([]*StreamReader, len(streams)),
+		heads:   make([]*EventResponse, len(streams)),
+		done:    make([]bool, len(streams)),
+	}
+	for i, s := range streams {
+		m.readers[i] = c.NewStreamReader(s)
+		m.readers[i].Prefetch(defaultPageSize)
+	}
+	return m
+}
+
+// Err returns any error that is raised as a result of a call to Next().
+func (m *MultiStreamReader) Err() error {
+	return m.lasterr
+}
+
+// EventResponse returns the event response for the event at the current
+// position of the reader.
+func (m *MultiStreamReader) EventResponse() *EventResponse {
+	return m.eventResponse
+}
+
+// Next gets the next event in the merged sequence.
+//
+// Next has the same semantics as StreamReader.Next().
+func (m *MultiStreamReader) Next() bool {
+	m.lasterr = nil
+	m.eventResponse = nil
+
+	// Every stream must have its next event, or be exhausted, before the least
+	// event can be chosen.
+	for i, r := range m.readers {
+		if m.heads[i] != nil || m.done[i] {
+			continue
+		}
+		r.Next()
+		switch err := r.Err().(type) {
+		case nil:
+			m.heads[i] = r.EventResponse()
+		case *ErrNoMoreEvents, *ErrNotFound:
+			m.done[i] = true
+		default:
+			m.lasterr = err
+			return true
+		}
+	}
+
+	next := -1
+	for i, h := range m.heads {
+		if h == nil {
+			continue
+		}
+		if next < 0 || m.less(h, m.heads[next]) {
+			next = i
+		}
+	}
+
+	if next < 0 {
+		// All of the streams have been read. Any events written since are
+		// read on the next call.
+		for i := range m.done {
+			m.done[i] = false
+		}
+		m.lasterr = &ErrNoMoreEvents{}
+		return true
+	}
+
+	m.eventResponse = m.heads[next]
+	m.heads[next] = nil
+	return true
+}
+
+// Scan deserializes the data and metadata of the current event into the types
+// passed in as arguments e and m.
+func (m *MultiStreamReader) Scan(e interface{}, meta interface{}) error {
+	if m.lasterr != nil {
+		return m.lasterr
+	}
+	if m.eventResponse == nil {
+		return &ErrNoMoreEvents{}
+	}
+	return m.client.decodeEvent(m.eventResponse, e, meta)
+}
+
+// Close stops the background reading of the streams.
+func (m *MultiStreamReader) Close() {
+	for _, r := range m.readers {
+		r.Close()
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&MultiStreamSuite{})
+
+type MultiStreamSuite struct{}
+
+func (s *MultiStreamSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *MultiStreamSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+type SeqEvent struct {
+	Seq int `json:"seq"`
+}
+
+// serveSeqStream serves a stream of SeqEvents with the sequence numbers given.
+func serveSeqStream(stream string, seqs ...int) {
+	es := make([]*Event, len(seqs))
+	for i, seq := range seqs {
+		es[i] = CreateTestEventFromData(stream, server.URL, i, &SeqEvent{Seq: seq}, nil)
+	}
+	u, _ := url.Parse(server.URL)
+	sim, _ := NewAtomFeedSimulator(es, u, nil, len(es))
+	mux.Handle("/streams/"+stream, sim)
+	mux.Handle("/streams/"+stream+"/", sim)
+}
+
+func bySeq(a, b *EventResponse) bool {
+	var sa, sb SeqEvent
+	client.decodeEvent(a, &sa, nil)
+	client.decodeEvent(b, &sb, nil)
+	return sa.Seq < sb.Seq
+}
+
+func (s *MultiStreamSuite) TestMergesStreamsInOrder(c *C) {
+	serveSeqStream("merge-a", 0, 3, 4, 8)
+	serveSeqStream("merge-b", 1, 5, 6)
+	serveSeqStream("merge-c", 2, 7, 9, 10, 11)
+	mux.HandleFunc("/streams/merge-missing/", http.NotFound)
+
+	reader := client.NewMultiStreamReader([]string{"merge-a", "merge-b", "merge-missing", "merge-c"}, bySeq)
+	defer reader.Close()
+
+	got := []int{}
+	for reader.Next() {
+		if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+			break
+		}
+		c.Assert(reader.Err(), IsNil)
+		e := SeqEvent{}
+		c.Assert(reader.Scan(&e, nil), IsNil)
+		got = append(got, e.Seq)
+	}
+	c.Assert(got, DeepEquals, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11})
+}
+
+func (s *MultiStreamSuite) TestReturnsErrorsAndRetries(c *C) {
+	serveSeqStream("retry-a", 0, 2)
+	es := []*Event{CreateTestEventFromData("retry-b", server.URL, 0, &SeqEvent{Seq: 1}, nil)}
+	u, _ := url.Parse(server.URL)
+	sim, _ := NewAtomFeedSimulator(es, u, nil, len(es))
+	var requests int32
+	mux.HandleFunc("/streams/retry-b/", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		sim.ServeHTTP(w, r)
+	})
+
+	reader := client.NewMultiStreamReader([]string{"retry-a", "retry-b"}, bySeq)
+	defer reader.Close()
+
+	c.Assert(reader.Next(), Equals, true)
+	c.Assert(reader.Err(), FitsTypeOf, &ErrTemporarilyUnavailable{})
+
+	got := []int{}
+	for reader.Next() {
+		if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+			break
+		}
+		c.Assert(reader.Err(), IsNil)
+		e := SeqEvent{}
+		c.Assert(reader.Scan(&e, nil), IsNil)
+		got = append(got, e.Seq)
+	}
+	c.Assert(got, DeepEquals, []int{0, 1, 2})
+}
+
+func (s *MultiStreamSuite) TestByTimestamp(c *C) {
+	a := &EventResponse{Updated: "2016-03-01T10:00:00+01:00"}
+	b := &EventResponse{Updated: "2016-03-01T09:30:00Z"}
+	c.Assert(ByTimestamp(a, b), Equals, true)
+	c.Assert(ByTimestamp(b, a), Equals, false)
+	c.Assert(ByTimestamp(a, a), Equals, false)
+}
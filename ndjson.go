@@ -0,0 +1,153 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// importBatchSize is the number of events appended in each request by
+// ImportStream.
+const importBatchSize = 100
+
+// ExportStream writes the events of the stream to w as newline delimited JSON,
+// one envelope per event in stream order, and returns the number of events
+// written.
+//
+// Each envelope holds the id, type, event number and stream of the event along
+// with the content types of its data and metadata. JSON data and metadata are
+// written as compacted JSON so that an export can be inspected and filtered
+// with ordinary tools. Other content is written base64 encoded. The envelopes
+// are the records of the chunks written by ExportArchive.
+//
+// If the stream does not exist an *ErrNotFound is returned and nothing is
+// written.
+func (c *Client) ExportStream(w io.Writer, stream string) (int, error) {
+	enc := json.NewEncoder(w)
+	n := 0
+	err := c.ForEachEvent(stream, 0, "forward", func(er *EventResponse) error {
+		e, err := NewRawEvent(er)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(newArchiveRecord(e)); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// ImportStream reads the envelopes written by ExportStream from r and appends
+// the events to the stream, returning the number of events appended.
+//
+// The events keep their original ids, types, data and metadata, including the
+// content types of events written with a codec other than JSON. The stream and
+// event number recorded in an envelope are ignored, so an export can be
+// imported into a stream of another name. Events are appended in batches with
+// no expected version. Because the ids are kept, an import that is run again
+// after it was interrupted is recognized by the eventstore as a repeat of the
+// batches already written. Blank lines are skipped.
+func (c *Client) ImportStream(r io.Reader, stream string) (int, error) {
+	writer := c.NewStreamWriter(stream)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+
+	n, line := 0, 0
+	batch := make([]*Event, 0, importBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := writer.Append(ExpectAny, batch...); err != nil {
+			return err
+		}
+		n += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for scanner.Scan() {
+		line++
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		rec := &archiveRecord{}
+		if err := json.Unmarshal(b, rec); err != nil {
+			return n, fmt.Errorf("Invalid event on line %d of the import: %v", line, err)
+		}
+		e, err := rec.rawEvent().event()
+		if err != nil {
+			return n, fmt.Errorf("Invalid event on line %d of the import: %v", line, err)
+		}
+		batch = append(batch, e)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return n, err
+	}
+	return n, flush()
+}
+
+// event returns the event to write to store the raw event as it was read,
+// serializing data and metadata that are not JSON as the codec for their
+// content type would have.
+func (e *RawEvent) event() (*Event, error) {
+	var data, meta interface{}
+	if len(e.Data) > 0 {
+		data = rawData(e.ContentType, e.Data)
+	}
+	if len(e.MetaData) > 0 {
+		meta = rawData(e.MetaDataContentType, e.MetaData)
+	}
+	if e.EventID != "" && !validEventID(e.EventID) {
+		return nil, &ErrInvalidUUID{Value: e.EventID}
+	}
+	ret := NewEvent(e.EventID, e.EventType, data, meta)
+	return encodeEvent(contentTypeCodec(e.ContentType), contentTypeCodec(e.MetaDataContentType), ret)
+}
+
+// rawData returns the value to write for content of the content type.
+func rawData(contentType string, b []byte) interface{} {
+	if contentType == "" || contentType == "application/json" {
+		m := json.RawMessage(b)
+		return &m
+	}
+	return b
+}
+
+// contentTypeCodec returns the codec that writes content of the content type
+// as it was read.
+func contentTypeCodec(contentType string) Codec {
+	if contentType == "" || contentType == "application/json" {
+		return JSONCodec{}
+	}
+	return passthroughCodec(contentType)
+}
+
+// passthroughCodec is a codec for content that is already serialized. Its
+// value is the content type of the content.
+type passthroughCodec string
+
+func (p passthroughCodec) ContentType() string { return string(p) }
+
+func (p passthroughCodec) Marshal(v interface{}) ([]byte, error) {
+	return RawCodec{}.Marshal(v)
+}
+
+func (p passthroughCodec) Unmarshal(data []byte, v interface{}) error {
+	return RawCodec{}.Unmarshal(data, v)
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&NDJSONSuite{})
+
+type NDJSONSuite struct{}
+
+func (s *NDJSONSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *NDJSONSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// captureImport records the events posted to the stream and the number of
+// requests made.
+func captureImport(c *C, stream string) (*[]*Event, *int) {
+	posted, requests := []*Event{}, 0
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		var batch []json.RawMessage
+		c.Assert(json.Unmarshal(b, &batch), IsNil)
+		for _, raw := range batch {
+			var d, m json.RawMessage
+			e := &Event{Data: &d, MetaData: &m}
+			c.Assert(json.Unmarshal(raw, e), IsNil)
+			posted = append(posted, e)
+		}
+		requests++
+		w.WriteHeader(http.StatusCreated)
+	})
+	return &posted, &requests
+}
+
+func (s *NDJSONSuite) TestExportAndImportStream(c *C) {
+	es := CreateTestEvents(130, "exported", server.URL, "Foo", "Bar")
+	setupSimulator(es, nil)
+
+	var buf bytes.Buffer
+	n, err := client.ExportStream(&buf, "exported")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 130)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	c.Assert(lines, HasLen, 130)
+	first := map[string]interface{}{}
+	c.Assert(json.Unmarshal([]byte(lines[0]), &first), IsNil)
+	c.Assert(first["eventId"], Equals, es[0].EventID)
+	c.Assert(first["stream"], Equals, "exported")
+
+	posted, requests := captureImport(c, "imported")
+	n, err = client.ImportStream(&buf, "imported")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 130)
+	c.Assert(*requests, Equals, 2)
+	c.Assert(*posted, HasLen, 130)
+	for i, e := range *posted {
+		c.Assert(e.EventID, Equals, es[i].EventID)
+		c.Assert(e.EventType, Equals, es[i].EventType)
+		var compact bytes.Buffer
+		c.Assert(json.Compact(&compact, *es[i].Data.(*json.RawMessage)), IsNil)
+		c.Assert([]byte(*e.Data.(*json.RawMessage)), DeepEquals, compact.Bytes())
+	}
+}
+
+func (s *NDJSONSuite) TestExportMissingStream(c *C) {
+	mux.HandleFunc("/streams/missing/", http.NotFound)
+
+	var buf bytes.Buffer
+	n, err := client.ExportStream(&buf, "missing")
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+	c.Assert(n, Equals, 0)
+	c.Assert(buf.Len(), Equals, 0)
+}
+
+func (s *NDJSONSuite) TestImportPreservesEncodedContent(c *C) {
+	payload := []byte{0x00, 0xff, 0x10}
+	er := appendAndCaptureWithMetaData(c, RawCodec{}, upperCodec{}, NewEvent("", "Binary", payload, "quiet"))
+	raw, err := NewRawEvent(er)
+	c.Assert(err, IsNil)
+	line, err := json.Marshal(newArchiveRecord(raw))
+	c.Assert(err, IsNil)
+
+	posted, _ := captureImport(c, "imported")
+	n, err := client.ImportStream(bytes.NewReader(line), "imported")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+
+	// The event is written as it was by the codecs.
+	e := (*posted)[0]
+	c.Assert(e.EventID, Equals, er.Event.EventID)
+	c.Assert(*e.Data.(*json.RawMessage), DeepEquals, *er.Event.Data.(*json.RawMessage))
+	got, err := NewRawEvent(&EventResponse{Event: e})
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, raw)
+}
+
+func (s *NDJSONSuite) TestImportRejectsInvalidLines(c *C) {
+	posted, _ := captureImport(c, "imported")
+	id := NewUUID()
+	input := `{"eventId":"` + id + `","eventType":"Foo","contentType":"application/json","data":{"a":1}}` +
+		"\n\n" + `{"eventId":` + "\n"
+
+	n, err := client.ImportStream(strings.NewReader(input), "imported")
+	c.Assert(err, ErrorMatches, "Invalid event on line 3 of the import: .*")
+	c.Assert(n, Equals, 0)
+	c.Assert(*posted, HasLen, 0)
+
+	_, err = client.ImportStream(strings.NewReader(`{"eventId":"x","eventType":"Foo"}`), "imported")
+	c.Assert(err, ErrorMatches, `Invalid event on line 1 of the import: "x" is not a valid UUID\.`)
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+// AppendWithRetryOnWrongVersion runs a compare-and-append loop against the
+// stream.
+//
+// The events in the stream are read and passed to decide, which returns the
+// events to append. The events are appended with the version of the last event
+// read as the expected version. If another writer has appended to the stream in
+// the meantime, the events written since are read and decide is called again
+// with all of the events in the stream. The events are appended at most
+// attempts times, and at least once.
+//
+// If the stream holds more events than the limit set with
+// SetMaxBufferedEvents an *ErrTooManyEvents is returned and nothing is written.
+// If decide returns an error it is returned and nothing is written. If decide
+// returns no events nothing is written and the result is nil. If the stream
+// still conflicts after the last attempt the *ErrConcurrencyViolation is
+// returned.
+//
+// decide may be called more than once, so it should not have side effects.
+func (s *StreamWriter) AppendWithRetryOnWrongVersion(attempts int, decide func(current []*EventResponse) ([]*Event, error)) (*WriteResult, error) {
+	current := []*EventResponse{}
+	version := -1
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		current, version, err = s.client.readFrom(s.streamName, current, version)
+		if err != nil {
+			return nil, err
+		}
+
+		events, err := decide(current)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			return nil, nil
+		}
+
+		result, err := s.AppendWithResult(ExpectedVersion(version), events...)
+		if _, ok := err.(*ErrConcurrencyViolation); ok && attempt < attempts {
+			continue
+		}
+		return result, err
+	}
+}
+
+// readFrom appends the events in the stream after version to events and
+// returns them with the version of the last event. A stream that does not
+// exist has no events.
+func (c *Client) readFrom(stream string, events []*EventResponse, version int) ([]*EventResponse, int, error) {
+	reader := c.NewStreamReader(stream)
+	reader.NextVersion(version + 1)
+	for reader.Next() {
+		switch err := reader.Err().(type) {
+		case nil:
+		case *ErrNoMoreEvents, *ErrNotFound:
+			return events, version, nil
+		default:
+			return nil, -1, err
+		}
+		er := reader.EventResponse()
+		if err := c.checkBuffered(len(events), stream, er.Event.EventNumber); err != nil {
+			return nil, -1, err
+		}
+		events = append(events, er)
+		version = er.Event.EventNumber
+	}
+	return events, version, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&OptimisticSuite{})
+
+type OptimisticSuite struct{}
+
+func (s *OptimisticSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *OptimisticSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *OptimisticSuite) TestRetriesWithEventsWrittenConcurrently(c *C) {
+	stream := "optimistic-1"
+	serveWritableStream(stream)
+	c.Assert(client.NewStreamWriter(stream).Append(ExpectAny, NewEvent("", "Opened", &FooEvent{}, nil)), IsNil)
+
+	calls := [][]int{}
+	result, err := client.NewStreamWriter(stream).AppendWithRetryOnWrongVersion(3, func(current []*EventResponse) ([]*Event, error) {
+		seen := []int{}
+		for _, er := range current {
+			seen = append(seen, er.Event.EventNumber)
+		}
+		calls = append(calls, seen)
+		if len(calls) == 1 {
+			// Another writer appends before this decision is written.
+			c.Assert(client.NewStreamWriter(stream).Append(ExpectAny, NewEvent("", "Other", &FooEvent{}, nil)), IsNil)
+		}
+		return []*Event{NewEvent("", "Decided", &FooEvent{}, nil)}, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(calls, DeepEquals, [][]int{{0}, {0, 1}})
+	c.Assert(result.NextExpectedVersion, Equals, 2)
+}
+
+func (s *OptimisticSuite) TestNewStreamIsWrittenWithNoStreamExpected(c *C) {
+	stream := "optimistic-2"
+	serveWritableStream(stream)
+
+	result, err := client.NewStreamWriter(stream).AppendWithRetryOnWrongVersion(1, func(current []*EventResponse) ([]*Event, error) {
+		c.Assert(current, HasLen, 0)
+		return []*Event{NewEvent("", "Opened", &FooEvent{}, nil)}, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.NextExpectedVersion, Equals, 0)
+}
+
+func (s *OptimisticSuite) TestGivesUpAfterAttempts(c *C) {
+	stream := "optimistic-3"
+	serveWritableStream(stream)
+
+	calls := 0
+	_, err := client.NewStreamWriter(stream).AppendWithRetryOnWrongVersion(2, func(current []*EventResponse) ([]*Event, error) {
+		calls++
+		c.Assert(client.NewStreamWriter(stream).Append(ExpectAny, NewEvent("", "Other", &FooEvent{}, nil)), IsNil)
+		return []*Event{NewEvent("", "Decided", &FooEvent{}, nil)}, nil
+	})
+	c.Assert(err, FitsTypeOf, &ErrConcurrencyViolation{})
+	c.Assert(calls, Equals, 2)
+}
+
+func (s *OptimisticSuite) TestDecideErrorsAndEmptyDecisions(c *C) {
+	stream := "optimistic-4"
+	serveWritableStream(stream)
+	writer := client.NewStreamWriter(stream)
+
+	_, err := writer.AppendWithRetryOnWrongVersion(3, func(current []*EventResponse) ([]*Event, error) {
+		return nil, errors.New("Rejected")
+	})
+	c.Assert(err, ErrorMatches, "Rejected")
+
+	result, err := writer.AppendWithRetryOnWrongVersion(3, func(current []*EventResponse) ([]*Event, error) {
+		return nil, nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result, IsNil)
+}
@@ -0,0 +1,128 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import "hash/fnv"
+
+// Partitioner maps a key to one of a number of partitions.
+//
+// Events with the same key are always mapped to the same partition for a given
+// number of partitions, so the order of events with the same key is preserved
+// by consumers that process each partition in order. The key is usually the
+// stream ID of an event.
+//
+// Partition must return a value in the range [0, partitions).
+type Partitioner interface {
+	Partition(key string, partitions int) int
+}
+
+// PartitionerFunc is an adapter to allow the use of an ordinary function as a
+// Partitioner.
+type PartitionerFunc func(key string, partitions int) int
+
+// Partition calls f(key, partitions).
+func (f PartitionerFunc) Partition(key string, partitions int) int {
+	return f(key, partitions)
+}
+
+// HashPartitioner maps keys to partitions by the FNV-1a hash of the key modulo
+// the number of partitions.
+//
+// When the number of partitions changes most keys move to a different
+// partition.
+type HashPartitioner struct{}
+
+// Partition returns the partition for the key.
+func (HashPartitioner) Partition(key string, partitions int) int {
+	if partitions <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// ConsistentHashPartitioner maps keys to partitions using jump consistent
+// hashing.
+//
+// When the number of partitions grows from n to n+1 only about 1/(n+1) of the
+// keys move, and they all move to the new partition. This is useful when the
+// number of consumers is scaled and the state held for each key should move as
+// little as possible.
+type ConsistentHashPartitioner struct{}
+
+// Partition returns the partition for the key.
+func (ConsistentHashPartitioner) Partition(key string, partitions int) int {
+	if partitions <= 1 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	k := h.Sum64()
+
+	var b, j int64 = -1, 0
+	for j < int64(partitions) {
+		b = j
+		k = k*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((k>>33)+1)))
+	}
+	return int(b)
+}
+
+// KafkaPartitioner maps keys to partitions in the same way as the default
+// partitioner of the Kafka Java client, which uses the murmur2 hash of the key.
+//
+// Using KafkaPartitioner to partition events by the key used when they are
+// produced to a Kafka topic ensures that a consumer of a partition of the
+// stream and a consumer of the same partition of the topic see the same keys.
+type KafkaPartitioner struct{}
+
+// Partition returns the partition for the key.
+func (KafkaPartitioner) Partition(key string, partitions int) int {
+	if partitions <= 1 {
+		return 0
+	}
+	return int(murmur2([]byte(key))&0x7fffffff) % partitions
+}
+
+// murmur2 is the variant of the murmur2 hash used by Kafka.
+func murmur2(data []byte) int32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+
+	length := len(data)
+	h := uint32(seed) ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
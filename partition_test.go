@@ -0,0 +1,64 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&PartitionSuite{})
+
+type PartitionSuite struct{}
+
+func (s *PartitionSuite) TestPartitionersAreStableAndInRange(c *C) {
+	partitioners := []Partitioner{HashPartitioner{}, ConsistentHashPartitioner{}, KafkaPartitioner{}}
+	for _, p := range partitioners {
+		for i := 0; i < 200; i++ {
+			key := fmt.Sprintf("order-%d", i)
+			n := p.Partition(key, 7)
+			c.Assert(n >= 0 && n < 7, Equals, true)
+			c.Assert(p.Partition(key, 7), Equals, n)
+			c.Assert(p.Partition(key, 1), Equals, 0)
+		}
+	}
+}
+
+func (s *PartitionSuite) TestConsistentHashOnlyMovesKeysToNewPartition(c *C) {
+	p := ConsistentHashPartitioner{}
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("order-%d", i)
+		before, after := p.Partition(key, 4), p.Partition(key, 5)
+		if before != after {
+			c.Assert(after, Equals, 4)
+			moved++
+		}
+	}
+	c.Assert(moved > 100 && moved < 300, Equals, true, Commentf("moved %d", moved))
+}
+
+func (s *PartitionSuite) TestMurmur2MatchesKafka(c *C) {
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for key, want := range cases {
+		c.Assert(murmur2([]byte(key)), Equals, want, Commentf("%s", key))
+	}
+}
+
+func (s *PartitionSuite) TestPartitionerFunc(c *C) {
+	var p Partitioner = PartitionerFunc(func(key string, partitions int) int {
+		return len(key) % partitions
+	})
+	c.Assert(p.Partition("abcde", 3), Equals, 2)
+}
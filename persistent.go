@@ -0,0 +1,200 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PersistentSubscriptionInfo describes a persistent subscription group as
+// reported by the /subscriptions/{stream}/{group}/info endpoint of the server.
+//
+// LastProcessedEventNumber is the event number of the last event acknowledged
+// by the group and LastKnownEventNumber is the event number of the last event
+// in the stream known to the server. AverageItemsPerSecond is the rate at which
+// events are processed by the group.
+type PersistentSubscriptionInfo struct {
+	EventStreamID             string                             `json:"eventStreamId"`
+	GroupName                 string                             `json:"groupName"`
+	Status                    string                             `json:"status"`
+	AverageItemsPerSecond     float64                            `json:"averageItemsPerSecond"`
+	TotalItemsProcessed       int64                              `json:"totalItemsProcessed"`
+	CountSinceLastMeasurement int64                              `json:"countSinceLastMeasurement"`
+	LastProcessedEventNumber  int                                `json:"lastProcessedEventNumber"`
+	LastKnownEventNumber      int                                `json:"lastKnownEventNumber"`
+	ReadBufferCount           int                                `json:"readBufferCount"`
+	LiveBufferCount           int                                `json:"liveBufferCount"`
+	RetryBufferCount          int                                `json:"retryBufferCount"`
+	TotalInFlightMessages     int                                `json:"totalInFlightMessages"`
+	ParkedMessageURI          string                             `json:"parkedMessageUri"`
+	GetMessagesURI            string                             `json:"getMessagesUri"`
+	Connections               []PersistentSubscriptionConnection `json:"connections"`
+}
+
+// PersistentSubscriptionConnection describes a consumer connected to a
+// persistent subscription group.
+type PersistentSubscriptionConnection struct {
+	From                      string  `json:"from"`
+	Username                  string  `json:"username"`
+	AverageItemsPerSecond     float64 `json:"averageItemsPerSecond"`
+	TotalItems                int64   `json:"totalItems"`
+	CountSinceLastMeasurement int64   `json:"countSinceLastMeasurement"`
+	AvailableSlots            int     `json:"availableSlots"`
+	InFlightMessages          int     `json:"inFlightMessages"`
+}
+
+// Lag returns the number of events in the stream after the last event
+// processed by the group.
+func (i *PersistentSubscriptionInfo) Lag() int {
+	if lag := i.LastKnownEventNumber - i.LastProcessedEventNumber; lag > 0 {
+		return lag
+	}
+	return 0
+}
+
+// GetPersistentSubscriptionInfo returns the *PersistentSubscriptionInfo of the
+// persistent subscription group of the stream.
+//
+// If the group does not exist an *ErrNotFound is returned. The request is
+// cancelled when ctx is done.
+func (c *Client) GetPersistentSubscriptionInfo(ctx context.Context, stream, group string) (*PersistentSubscriptionInfo, error) {
+	u := fmt.Sprintf("/subscriptions/%s/%s/info", url.PathEscape(stream), url.PathEscape(group))
+	req, err := c.newRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	var b bytes.Buffer
+	if _, err := c.do(req, &b); err != nil {
+		return nil, err
+	}
+
+	info := &PersistentSubscriptionInfo{}
+	if err := json.Unmarshal(b.Bytes(), info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// NackAction is the action the server takes for a message of a persistent
+// subscription that is negatively acknowledged.
+type NackAction string
+
+const (
+	// NackRetry delivers the message again.
+	NackRetry NackAction = "Retry"
+
+	// NackPark moves the message to the parked message stream of the group.
+	NackPark NackAction = "Park"
+
+	// NackSkip discards the message.
+	NackSkip NackAction = "Skip"
+)
+
+// PersistentMessage is a message delivered by a persistent subscription group.
+//
+// Every message must be acknowledged with AckPersistent or negatively
+// acknowledged with NackPersistent. A message that is neither is delivered
+// again by the server once its message timeout expires.
+//
+// ID is the id of the event and EventURL is the url from which the event is
+// read with GetEvent.
+type PersistentMessage struct {
+	ID       string
+	EventURL string
+	ackURL   string
+	nackURL  string
+}
+
+// ReadPersistentSubscription reads up to count messages from the persistent
+// subscription group of the stream.
+//
+// An empty slice is returned if there are no messages waiting. If the group
+// does not exist an *ErrNotFound is returned. The request is cancelled when
+// ctx is done.
+func (c *Client) ReadPersistentSubscription(ctx context.Context, stream, group string, count int) ([]*PersistentMessage, error) {
+	u := fmt.Sprintf("/subscriptions/%s/%s/%d", url.PathEscape(stream), url.PathEscape(group), count)
+	req, err := c.newRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/vnd.eventstore.competingatom+xml")
+
+	var b bytes.Buffer
+	resp, err := c.do(req, &b)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseFeed(resp.Header.Get("Content-Type"), b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*PersistentMessage, 0, len(f.Entry))
+	for _, e := range f.Entry {
+		m := &PersistentMessage{}
+		for _, l := range e.Link {
+			switch l.Rel {
+			case "ack":
+				m.ackURL = l.Href
+			case "nack":
+				m.nackURL = l.Href
+			case "alternate":
+				m.EventURL = strings.TrimRight(l.Href, "/")
+			case "edit":
+				if m.EventURL == "" {
+					m.EventURL = strings.TrimRight(l.Href, "/")
+				}
+			}
+		}
+		m.ID = path.Base(m.ackURL)
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// AckPersistent acknowledges that the messages have been handled, so that they
+// are not delivered again.
+func (c *Client) AckPersistent(ctx context.Context, msgs ...*PersistentMessage) error {
+	for _, m := range msgs {
+		if err := c.postPersistent(ctx, m.ackURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NackPersistent negatively acknowledges the messages, asking the server to
+// take the action for them.
+func (c *Client) NackPersistent(ctx context.Context, action NackAction, msgs ...*PersistentMessage) error {
+	for _, m := range msgs {
+		if err := c.postPersistent(ctx, m.nackURL+"?action="+string(action)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postPersistent posts an empty request to an ack or nack url.
+func (c *Client) postPersistent(ctx context.Context, u string) error {
+	req, err := c.newRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	_, err = c.do(req, nil)
+	return err
+}
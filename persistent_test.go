@@ -0,0 +1,89 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&PersistentSuite{})
+
+type PersistentSuite struct{}
+
+func (s *PersistentSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *PersistentSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+const persistentInfo = `{
+  "eventStreamId": "$ce-order",
+  "groupName": "billing",
+  "status": "Live",
+  "averageItemsPerSecond": 12.5,
+  "totalItemsProcessed": 420,
+  "countSinceLastMeasurement": 25,
+  "lastProcessedEventNumber": 419,
+  "lastKnownEventNumber": 450,
+  "readBufferCount": 0,
+  "liveBufferCount": 31,
+  "retryBufferCount": 1,
+  "totalInFlightMessages": 10,
+  "parkedMessageUri": "%[1]s/streams/$persistentsubscription-$ce-order::billing-parked",
+  "getMessagesUri": "%[1]s/subscriptions/%%24ce-order/billing/1",
+  "connections": [{
+    "from": "127.0.0.1:50123",
+    "username": "admin",
+    "averageItemsPerSecond": 12.5,
+    "totalItems": 420,
+    "countSinceLastMeasurement": 25,
+    "availableSlots": 0,
+    "inFlightMessages": 10
+  }]
+}`
+
+func (s *PersistentSuite) TestGetPersistentSubscriptionInfo(c *C) {
+	mux.HandleFunc("/subscriptions/$ce-order/billing/info", func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("Accept"), Equals, "application/json")
+		fmt.Fprintf(w, persistentInfo, server.URL)
+	})
+
+	info, err := client.GetPersistentSubscriptionInfo(context.Background(), "$ce-order", "billing")
+	c.Assert(err, IsNil)
+	c.Assert(info.EventStreamID, Equals, "$ce-order")
+	c.Assert(info.GroupName, Equals, "billing")
+	c.Assert(info.Status, Equals, "Live")
+	c.Assert(info.AverageItemsPerSecond, Equals, 12.5)
+	c.Assert(info.TotalItemsProcessed, Equals, int64(420))
+	c.Assert(info.LiveBufferCount, Equals, 31)
+	c.Assert(info.TotalInFlightMessages, Equals, 10)
+	c.Assert(info.Lag(), Equals, 31)
+	c.Assert(info.Connections, DeepEquals, []PersistentSubscriptionConnection{{
+		From:                      "127.0.0.1:50123",
+		Username:                  "admin",
+		AverageItemsPerSecond:     12.5,
+		TotalItems:                420,
+		CountSinceLastMeasurement: 25,
+		InFlightMessages:          10,
+	}})
+}
+
+func (s *PersistentSuite) TestGetPersistentSubscriptionInfoOfMissingGroup(c *C) {
+	mux.HandleFunc("/subscriptions/", http.NotFound)
+
+	_, err := client.GetPersistentSubscriptionInfo(context.Background(), "orders", "missing")
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+}
+
+func (s *PersistentSuite) TestLagIsNeverNegative(c *C) {
+	info := &PersistentSubscriptionInfo{LastProcessedEventNumber: 10, LastKnownEventNumber: -1}
+	c.Assert(info.Lag(), Equals, 0)
+}
@@ -0,0 +1,371 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultPersistentBatch is the number of messages read from a persistent
+// subscription group in each request by default.
+const defaultPersistentBatch = 10
+
+// AckPolicy decides how a PersistentSubscriptionConsumer acknowledges the
+// messages it handles.
+//
+// Messages are acknowledged when the handler succeeds. When the handler returns
+// an error the message is negatively acknowledged with the action returned by
+// Decide, or with OnFailure if Decide is nil or returns an empty action.
+// OnFailure defaults to NackRetry, so the server delivers the message again
+// until the maximum retry count of the group is reached and it is parked.
+type AckPolicy struct {
+	OnFailure NackAction
+	Decide    func(er *EventResponse, err error) NackAction
+}
+
+// action returns the action for a message whose handler returned err.
+func (p AckPolicy) action(er *EventResponse, err error) NackAction {
+	if p.Decide != nil {
+		if a := p.Decide(er, err); a != "" {
+			return a
+		}
+	}
+	if p.OnFailure != "" {
+		return p.OnFailure
+	}
+	return NackRetry
+}
+
+// PersistentSubscriptionConsumer handles the messages of a persistent
+// subscription group.
+//
+// The consumer reads messages from the group in batches and hands them to a
+// pool of workers, each of which reads the event of a message, calls the
+// handler with it and then acknowledges the message according to the
+// AckPolicy. The number of messages read but not yet acknowledged is limited,
+// so the consumer does not read more messages than it can handle before they
+// time out on the server.
+//
+// Errors reading messages are retried after a back off. Errors reading an
+// event or acknowledging a message are reported and the message is left to
+// time out, after which the server delivers it again. Messages may therefore be
+// handled more than once and handlers should be idempotent. If the server
+// refuses access to the group the consumer stops and Err returns the error.
+type PersistentSubscriptionConsumer struct {
+	client     *Client
+	stream     string
+	group      string
+	handler    func(context.Context, *EventResponse) error
+	policy     AckPolicy
+	batch      int
+	workers    int
+	inFlight   int
+	poll       time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	reporter   ErrorReporter
+	mu         sync.Mutex
+	handled    int64
+	err        error
+	stop       chan struct{}
+	drain      chan struct{}
+	done       chan struct{}
+}
+
+// NewPersistentSubscriptionConsumer returns a consumer that delivers the
+// messages of the persistent subscription group of the stream to handler.
+func (c *Client) NewPersistentSubscriptionConsumer(stream, group string, handler func(context.Context, *EventResponse) error) *PersistentSubscriptionConsumer {
+	return &PersistentSubscriptionConsumer{
+		client:     c,
+		stream:     stream,
+		group:      group,
+		handler:    handler,
+		batch:      defaultPersistentBatch,
+		workers:    1,
+		poll:       defaultPollInterval,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+}
+
+// SetAckPolicy sets the policy used to acknowledge messages.
+func (p *PersistentSubscriptionConsumer) SetAckPolicy(policy AckPolicy) {
+	p.policy = policy
+}
+
+// SetBatchSize sets the maximum number of messages read in each request. The
+// default is 10.
+func (p *PersistentSubscriptionConsumer) SetBatchSize(n int) {
+	if n < 1 {
+		n = 1
+	}
+	p.batch = n
+}
+
+// SetConcurrency sets the number of messages handled at once. The default is 1.
+func (p *PersistentSubscriptionConsumer) SetConcurrency(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	p.workers = workers
+}
+
+// SetMaxInFlight sets the maximum number of messages read but not yet
+// acknowledged. The default, or a value of 0 or less, is the batch size
+// multiplied by the concurrency.
+func (p *PersistentSubscriptionConsumer) SetMaxInFlight(n int) {
+	p.inFlight = n
+}
+
+// SetPollInterval sets the time to wait before reading again when there are no
+// messages waiting. The default is one second.
+func (p *PersistentSubscriptionConsumer) SetPollInterval(d time.Duration) {
+	p.poll = d
+}
+
+// SetBackoff sets the minimum and maximum time to wait before reading again
+// after reading messages fails. The wait doubles after each failed attempt.
+func (p *PersistentSubscriptionConsumer) SetBackoff(min, max time.Duration) {
+	p.minBackoff = min
+	p.maxBackoff = max
+}
+
+// Handled returns the number of messages handled successfully and
+// acknowledged.
+func (p *PersistentSubscriptionConsumer) Handled() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.handled
+}
+
+// Err returns the error that stopped the consumer, or nil.
+func (p *PersistentSubscriptionConsumer) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Start starts reading and handling messages.
+func (p *PersistentSubscriptionConsumer) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.drain = make(chan struct{})
+	p.done = make(chan struct{})
+	p.err = nil
+	go p.run(p.stop, p.drain, p.done)
+}
+
+// Stop stops the consumer and waits for the messages being handled to be
+// acknowledged. Messages read but not yet handled are left to time out on the
+// server.
+func (p *PersistentSubscriptionConsumer) Stop() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop = nil
+	p.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Shutdown stops the consumer gracefully. It stops reading messages, handles
+// and acknowledges the messages already read and returns Err once the consumer
+// has stopped.
+//
+// If ctx is done before then the consumer is stopped as with Stop and
+// ctx.Err() is returned.
+func (p *PersistentSubscriptionConsumer) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	stop, drain, done := p.stop, p.drain, p.done
+	p.stop = nil
+	p.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	close(drain)
+	select {
+	case <-done:
+		return p.Err()
+	case <-ctx.Done():
+		close(stop)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed when the consumer stops.
+func (p *PersistentSubscriptionConsumer) Done() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done
+}
+
+func (p *PersistentSubscriptionConsumer) component() string {
+	return "PersistentSubscriptionConsumer " + p.stream + "::" + p.group
+}
+
+// run reads messages and hands them to the workers until stop or drain is
+// closed or access to the group is refused.
+func (p *PersistentSubscriptionConsumer) run(stop, drain, done chan struct{}) {
+	defer close(done)
+
+	// Requests in progress are cancelled when the consumer is stopped or
+	// drained. The workers finish handling their messages with a context that
+	// is not cancelled, so that the messages are acknowledged.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-drain:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	limit := p.inFlight
+	if limit <= 0 {
+		limit = p.batch * p.workers
+	}
+	slots := make(chan struct{}, limit)
+	msgs := make(chan *PersistentMessage)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range msgs {
+				p.handle(m)
+				<-slots
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(msgs)
+
+	wait := func(d time.Duration) bool {
+		select {
+		case <-stop:
+			return false
+		case <-drain:
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+
+	backoff := p.minBackoff
+	for {
+		// A message is only read when there is a slot for it.
+		select {
+		case slots <- struct{}{}:
+		case <-stop:
+			return
+		case <-drain:
+			return
+		}
+		n := 1
+	fill:
+		for n < p.batch {
+			select {
+			case slots <- struct{}{}:
+				n++
+			default:
+				break fill
+			}
+		}
+
+		read, err := p.client.ReadPersistentSubscription(ctx, p.stream, p.group, n)
+		for i := len(read); i < n; i++ {
+			<-slots
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if _, ok := err.(*ErrUnauthorized); ok {
+				p.fail(err)
+				return
+			}
+			p.reporter.Report(p.component(), err, false)
+			d := backoff
+			if after, ok := RetryAfter(err); ok && after > d {
+				d = after
+			}
+			if !wait(d) {
+				return
+			}
+			backoff *= 2
+			if backoff > p.maxBackoff {
+				backoff = p.maxBackoff
+			}
+			continue
+		}
+		backoff = p.minBackoff
+
+		// Once the consumer is draining the messages read are still handed
+		// to the workers, unless it is stopped.
+		for i, m := range read {
+			select {
+			case msgs <- m:
+			case <-stop:
+				// The slots of the messages that are not handled are
+				// released so that the workers are not held up.
+				for range read[i:] {
+					<-slots
+				}
+				return
+			}
+		}
+		if len(read) == 0 && !wait(p.poll) {
+			return
+		}
+	}
+}
+
+// handle reads the event of the message, calls the handler and acknowledges
+// the message according to the AckPolicy.
+func (p *PersistentSubscriptionConsumer) handle(m *PersistentMessage) {
+	ctx := context.Background()
+	er, _, err := p.client.getEvent(ctx, m.EventURL)
+	if err != nil {
+		p.reporter.Report(p.component(), err, false)
+		return
+	}
+
+	if herr := p.handler(ctx, er); herr != nil {
+		p.reporter.Report(p.component(), herr, false)
+		err = p.client.NackPersistent(ctx, p.policy.action(er, herr), m)
+	} else {
+		err = p.client.AckPersistent(ctx, m)
+		if err == nil {
+			p.mu.Lock()
+			p.handled++
+			p.mu.Unlock()
+		}
+	}
+	if err != nil {
+		p.reporter.Report(p.component(), err, false)
+	}
+}
+
+// fail records the error that stopped the consumer and reports it.
+func (p *PersistentSubscriptionConsumer) fail(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+	p.reporter.Report(p.component(), err, true)
+}
@@ -0,0 +1,320 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&PersistentConsumerSuite{})
+
+type PersistentConsumerSuite struct{}
+
+func (s *PersistentConsumerSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *PersistentConsumerSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// persistentGroup serves a persistent subscription group of a stream of
+// events. Messages are delivered in event number order and those negatively
+// acknowledged with Retry are delivered again.
+type persistentGroup struct {
+	mu          sync.Mutex
+	stream      string
+	events      []*Event
+	pending     []int
+	inFlight    map[string]int
+	maxInFlight int
+	reads       []int
+	acked       []int
+	nacked      map[NackAction][]int
+	refuse      bool
+}
+
+func servePersistentGroup(c *C, stream, group string, n int) *persistentGroup {
+	g := &persistentGroup{
+		stream:   stream,
+		inFlight: make(map[string]int),
+		nacked:   make(map[NackAction][]int),
+	}
+	for i := 0; i < n; i++ {
+		g.events = append(g.events, CreateTestEvent(stream, server.URL, "Foo", i, nil, nil))
+		g.pending = append(g.pending, i)
+	}
+	base := "/subscriptions/" + stream + "/" + group + "/"
+
+	mux.HandleFunc(base, func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.refuse {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, base)
+		switch {
+		case r.Method == http.MethodGet:
+			c.Check(r.Header.Get("Accept"), Equals, "application/vnd.eventstore.competingatom+xml")
+			count, err := strconv.Atoi(rest)
+			c.Assert(err, IsNil)
+			g.reads = append(g.reads, count)
+			f := &atom.Feed{Title: "Messages for '" + group + "'"}
+			for len(g.pending) > 0 && len(f.Entry) < count {
+				e := g.events[g.pending[0]]
+				g.pending = g.pending[1:]
+				g.inFlight[e.EventID] = e.EventNumber
+				eventURL := fmt.Sprintf("%s/streams/%s/%d", server.URL, stream, e.EventNumber)
+				f.Entry = append(f.Entry, &atom.Entry{
+					Title: fmt.Sprintf("%d@%s", e.EventNumber, stream),
+					Link: []atom.Link{
+						{Rel: "edit", Href: eventURL},
+						{Rel: "alternate", Href: eventURL},
+						{Rel: "ack", Href: server.URL + base + "ack/" + e.EventID},
+						{Rel: "nack", Href: server.URL + base + "nack/" + e.EventID},
+					},
+				})
+			}
+			if len(g.inFlight) > g.maxInFlight {
+				g.maxInFlight = len(g.inFlight)
+			}
+			c.Assert(xml.NewEncoder(w).Encode(f), IsNil)
+		case strings.HasPrefix(rest, "ack/"):
+			n := g.take(path.Base(rest))
+			g.acked = append(g.acked, n)
+			w.WriteHeader(http.StatusAccepted)
+		case strings.HasPrefix(rest, "nack/"):
+			n := g.take(path.Base(rest))
+			action := NackAction(r.URL.Query().Get("action"))
+			g.nacked[action] = append(g.nacked[action], n)
+			if action == NackRetry {
+				g.pending = append(g.pending, n)
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/streams/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(path.Base(r.URL.Path))
+		c.Assert(err, IsNil)
+		er, err := CreateTestEventAtomResponse(g.events[n], nil)
+		c.Assert(err, IsNil)
+		json.NewEncoder(w).Encode(er)
+	})
+	return g
+}
+
+// take removes the message with the id from the messages in flight and returns
+// its event number. The caller must hold the lock.
+func (g *persistentGroup) take(id string) int {
+	n, ok := g.inFlight[id]
+	if !ok {
+		return -1
+	}
+	delete(g.inFlight, id)
+	return n
+}
+
+func (g *persistentGroup) ackedCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.acked)
+}
+
+func (s *PersistentConsumerSuite) TestReadAckAndNack(c *C) {
+	g := servePersistentGroup(c, "orders", "billing", 3)
+	ctx := context.Background()
+
+	msgs, err := client.ReadPersistentSubscription(ctx, "orders", "billing", 2)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 2)
+	c.Assert(msgs[0].ID, Equals, g.events[0].EventID)
+	c.Assert(msgs[1].EventURL, Equals, server.URL+"/streams/orders/1")
+
+	c.Assert(client.AckPersistent(ctx, msgs[0]), IsNil)
+	c.Assert(client.NackPersistent(ctx, NackPark, msgs[1]), IsNil)
+	c.Assert(g.acked, DeepEquals, []int{0})
+	c.Assert(g.nacked[NackPark], DeepEquals, []int{1})
+
+	msgs, err = client.ReadPersistentSubscription(ctx, "orders", "billing", 2)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	msgs, err = client.ReadPersistentSubscription(ctx, "orders", "billing", 2)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 0)
+}
+
+func (s *PersistentConsumerSuite) TestConsumerAcksHandledMessages(c *C) {
+	g := servePersistentGroup(c, "orders", "billing", 25)
+	var mu sync.Mutex
+	handled := []int{}
+
+	p := client.NewPersistentSubscriptionConsumer("orders", "billing", func(ctx context.Context, er *EventResponse) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, er.Event.EventNumber)
+		return nil
+	})
+	p.SetConcurrency(3)
+	p.SetPollInterval(5 * time.Millisecond)
+	p.Start()
+	eventually(func() bool { return g.ackedCount() == 25 })
+	p.Stop()
+	c.Assert(p.Err(), IsNil)
+	c.Assert(p.Handled(), Equals, int64(25))
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Ints(handled)
+	c.Assert(handled, DeepEquals, sequence(25))
+}
+
+func (s *PersistentConsumerSuite) TestAckPolicy(c *C) {
+	g := servePersistentGroup(c, "orders", "billing", 6)
+	var mu sync.Mutex
+	attempts := map[int]int{}
+
+	p := client.NewPersistentSubscriptionConsumer("orders", "billing", func(ctx context.Context, er *EventResponse) error {
+		mu.Lock()
+		defer mu.Unlock()
+		n := er.Event.EventNumber
+		attempts[n]++
+		switch {
+		case n == 1 && attempts[n] == 1:
+			return errors.New("try again")
+		case n == 2:
+			return errors.New("poison")
+		case n == 4:
+			return errors.New("obsolete")
+		}
+		return nil
+	})
+	p.SetAckPolicy(AckPolicy{
+		Decide: func(er *EventResponse, err error) NackAction {
+			switch err.Error() {
+			case "poison":
+				return NackPark
+			case "obsolete":
+				return NackSkip
+			}
+			return ""
+		},
+	})
+	p.SetPollInterval(5 * time.Millisecond)
+	p.Start()
+	eventually(func() bool { return g.ackedCount() == 4 })
+	p.Stop()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sort.Ints(g.acked)
+	c.Assert(g.acked, DeepEquals, []int{0, 1, 3, 5})
+	c.Assert(g.nacked, DeepEquals, map[NackAction][]int{
+		NackRetry: {1},
+		NackPark:  {2},
+		NackSkip:  {4},
+	})
+	c.Assert(attempts[1], Equals, 2)
+}
+
+func (s *PersistentConsumerSuite) TestMessagesInFlightAreLimited(c *C) {
+	g := servePersistentGroup(c, "orders", "billing", 12)
+	p := client.NewPersistentSubscriptionConsumer("orders", "billing", func(ctx context.Context, er *EventResponse) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	p.SetBatchSize(10)
+	p.SetConcurrency(2)
+	p.SetMaxInFlight(3)
+	p.SetPollInterval(5 * time.Millisecond)
+	p.Start()
+	eventually(func() bool { return g.ackedCount() == 12 })
+	p.Stop()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c.Assert(g.maxInFlight <= 3, Equals, true, Commentf("%d", g.maxInFlight))
+	for _, n := range g.reads {
+		c.Assert(n <= 3, Equals, true)
+	}
+}
+
+func (s *PersistentConsumerSuite) TestUnauthorizedStopsConsumer(c *C) {
+	g := servePersistentGroup(c, "orders", "billing", 1)
+	g.refuse = true
+
+	p := client.NewPersistentSubscriptionConsumer("orders", "billing", func(ctx context.Context, er *EventResponse) error {
+		return nil
+	})
+	p.Start()
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		c.Fatal("consumer did not stop")
+	}
+	c.Assert(p.Err(), FitsTypeOf, &ErrUnauthorized{})
+	err := <-p.Errors()
+	c.Assert(IsFatal(err), Equals, true)
+}
+
+func (s *PersistentConsumerSuite) TestShutdownHandlesMessagesRead(c *C) {
+	g := servePersistentGroup(c, "orders", "billing", 12)
+	release := make(chan struct{})
+
+	p := client.NewPersistentSubscriptionConsumer("orders", "billing", func(ctx context.Context, er *EventResponse) error {
+		if er.Event.EventNumber == 0 {
+			<-release
+		}
+		return nil
+	})
+	p.SetBatchSize(5)
+	p.Start()
+	eventually(func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return len(g.reads) == 1
+	})
+
+	shutdown := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- p.Shutdown(ctx)
+	}()
+	eventually(func() bool {
+		select {
+		case <-p.drain:
+			return true
+		default:
+			return false
+		}
+	})
+	close(release)
+	c.Assert(<-shutdown, IsNil)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c.Assert(g.reads, DeepEquals, []int{5})
+	sort.Ints(g.acked)
+	c.Assert(g.acked, DeepEquals, sequence(5))
+	c.Assert(p.Handled(), Equals, int64(5))
+}
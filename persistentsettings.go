@@ -0,0 +1,179 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ConsumerStrategy is the strategy a persistent subscription group uses to
+// distribute messages among its consumers.
+type ConsumerStrategy string
+
+const (
+	// ConsumerRoundRobin distributes messages to each consumer in turn.
+	ConsumerRoundRobin ConsumerStrategy = "RoundRobin"
+
+	// ConsumerDispatchToSingle sends messages to a single consumer until it is
+	// full, then to the next.
+	ConsumerDispatchToSingle ConsumerStrategy = "DispatchToSingle"
+
+	// ConsumerPinned sends the messages of the same stream to the same
+	// consumer, which is useful for subscriptions to projected streams.
+	ConsumerPinned ConsumerStrategy = "Pinned"
+)
+
+// PersistentSubscriptionSettings are the settings of a persistent
+// subscription group.
+//
+// StartFrom is the event number the group starts from, or -1 to start with the
+// events written after the group is created. MessageTimeout is the time after
+// which a message that has not been acknowledged is delivered again, up to
+// MaxRetryCount times before it is parked.
+//
+// LiveBufferSize, ReadBatchSize and HistoryBufferSize size the buffers the
+// server uses while the group is live and while it catches up. The group
+// checkpoints its position after CheckPointAfter, once at least
+// MinCheckPointCount messages have been processed, and no later than after
+// MaxCheckPointCount messages. MaxSubscriberCount limits the number of
+// consumers, 0 meaning no limit.
+//
+// Use NewPersistentSubscriptionSettings for the defaults of the server.
+type PersistentSubscriptionSettings struct {
+	ResolveLinkTos     bool
+	StartFrom          int
+	ExtraStatistics    bool
+	MessageTimeout     time.Duration
+	MaxRetryCount      int
+	LiveBufferSize     int
+	ReadBatchSize      int
+	HistoryBufferSize  int
+	CheckPointAfter    time.Duration
+	MinCheckPointCount int
+	MaxCheckPointCount int
+	MaxSubscriberCount int
+	ConsumerStrategy   ConsumerStrategy
+}
+
+// NewPersistentSubscriptionSettings returns the default settings of a
+// persistent subscription group, which starts with the events written after
+// it is created.
+func NewPersistentSubscriptionSettings() *PersistentSubscriptionSettings {
+	return &PersistentSubscriptionSettings{
+		StartFrom:          -1,
+		MessageTimeout:     30 * time.Second,
+		MaxRetryCount:      10,
+		LiveBufferSize:     500,
+		ReadBatchSize:      20,
+		HistoryBufferSize:  500,
+		CheckPointAfter:    2 * time.Second,
+		MinCheckPointCount: 10,
+		MaxCheckPointCount: 1000,
+		ConsumerStrategy:   ConsumerRoundRobin,
+	}
+}
+
+// persistentSettingsJSON is the representation of the settings used by the
+// server.
+type persistentSettingsJSON struct {
+	ResolveLinkTos              bool             `json:"resolveLinktos"`
+	StartFrom                   int              `json:"startFrom"`
+	ExtraStatistics             bool             `json:"extraStatistics"`
+	MessageTimeoutMilliseconds  int64            `json:"messageTimeoutMilliseconds"`
+	MaxRetryCount               int              `json:"maxRetryCount"`
+	LiveBufferSize              int              `json:"liveBufferSize"`
+	ReadBatchSize               int              `json:"readBatchSize"`
+	BufferSize                  int              `json:"bufferSize"`
+	CheckPointAfterMilliseconds int64            `json:"checkPointAfterMilliseconds"`
+	MinCheckPointCount          int              `json:"minCheckPointCount"`
+	MaxCheckPointCount          int              `json:"maxCheckPointCount"`
+	MaxSubscriberCount          int              `json:"maxSubscriberCount"`
+	NamedConsumerStrategy       ConsumerStrategy `json:"namedConsumerStrategy"`
+}
+
+// MarshalJSON encodes the settings as the server expects them.
+func (s PersistentSubscriptionSettings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(persistentSettingsJSON{
+		ResolveLinkTos:              s.ResolveLinkTos,
+		StartFrom:                   s.StartFrom,
+		ExtraStatistics:             s.ExtraStatistics,
+		MessageTimeoutMilliseconds:  int64(s.MessageTimeout / time.Millisecond),
+		MaxRetryCount:               s.MaxRetryCount,
+		LiveBufferSize:              s.LiveBufferSize,
+		ReadBatchSize:               s.ReadBatchSize,
+		BufferSize:                  s.HistoryBufferSize,
+		CheckPointAfterMilliseconds: int64(s.CheckPointAfter / time.Millisecond),
+		MinCheckPointCount:          s.MinCheckPointCount,
+		MaxCheckPointCount:          s.MaxCheckPointCount,
+		MaxSubscriberCount:          s.MaxSubscriberCount,
+		NamedConsumerStrategy:       s.ConsumerStrategy,
+	})
+}
+
+// UnmarshalJSON decodes settings in the representation used by the server.
+func (s *PersistentSubscriptionSettings) UnmarshalJSON(b []byte) error {
+	j := persistentSettingsJSON{}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = PersistentSubscriptionSettings{
+		ResolveLinkTos:     j.ResolveLinkTos,
+		StartFrom:          j.StartFrom,
+		ExtraStatistics:    j.ExtraStatistics,
+		MessageTimeout:     time.Duration(j.MessageTimeoutMilliseconds) * time.Millisecond,
+		MaxRetryCount:      j.MaxRetryCount,
+		LiveBufferSize:     j.LiveBufferSize,
+		ReadBatchSize:      j.ReadBatchSize,
+		HistoryBufferSize:  j.BufferSize,
+		CheckPointAfter:    time.Duration(j.CheckPointAfterMilliseconds) * time.Millisecond,
+		MinCheckPointCount: j.MinCheckPointCount,
+		MaxCheckPointCount: j.MaxCheckPointCount,
+		MaxSubscriberCount: j.MaxSubscriberCount,
+		ConsumerStrategy:   j.NamedConsumerStrategy,
+	}
+	return nil
+}
+
+// CreatePersistentSubscription creates a persistent subscription group of the
+// stream with the settings. If settings is nil the defaults of
+// NewPersistentSubscriptionSettings are used.
+//
+// The request is cancelled when ctx is done.
+func (c *Client) CreatePersistentSubscription(ctx context.Context, stream, group string, settings *PersistentSubscriptionSettings) error {
+	return c.putPersistentSettings(ctx, http.MethodPut, stream, group, settings)
+}
+
+// UpdatePersistentSubscription replaces the settings of an existing persistent
+// subscription group of the stream. If settings is nil the defaults of
+// NewPersistentSubscriptionSettings are used. If the group does not exist an
+// *ErrNotFound is returned.
+//
+// The request is cancelled when ctx is done.
+func (c *Client) UpdatePersistentSubscription(ctx context.Context, stream, group string, settings *PersistentSubscriptionSettings) error {
+	return c.putPersistentSettings(ctx, http.MethodPost, stream, group, settings)
+}
+
+// putPersistentSettings sends the settings of a group with the method.
+func (c *Client) putPersistentSettings(ctx context.Context, method, stream, group string, settings *PersistentSubscriptionSettings) error {
+	if settings == nil {
+		settings = NewPersistentSubscriptionSettings()
+	}
+	u := fmt.Sprintf("/subscriptions/%s/%s", url.PathEscape(stream), url.PathEscape(group))
+	req, err := c.newRequest(method, u, settings)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.do(req, nil)
+	return err
+}
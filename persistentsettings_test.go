@@ -0,0 +1,102 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&PersistentSettingsSuite{})
+
+type PersistentSettingsSuite struct{}
+
+func (s *PersistentSettingsSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *PersistentSettingsSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// serveGroups records the settings sent for persistent subscription groups
+// of the stream. Only groups in the map can be updated.
+func serveGroups(c *C, stream string, groups map[string]map[string]interface{}) {
+	mux.HandleFunc("/subscriptions/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("Content-Type"), Equals, "application/json")
+		group := r.URL.Path[len("/subscriptions/"+stream+"/"):]
+		if _, ok := groups[group]; !ok && r.Method == http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		body := map[string]interface{}{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&body), IsNil)
+		groups[group] = body
+		w.WriteHeader(http.StatusCreated)
+	})
+}
+
+func (s *PersistentSettingsSuite) TestCreateWithDefaultSettings(c *C) {
+	groups := map[string]map[string]interface{}{}
+	serveGroups(c, "orders", groups)
+
+	c.Assert(client.CreatePersistentSubscription(context.Background(), "orders", "billing", nil), IsNil)
+	c.Assert(groups["billing"], DeepEquals, map[string]interface{}{
+		"resolveLinktos":              false,
+		"startFrom":                   float64(-1),
+		"extraStatistics":             false,
+		"messageTimeoutMilliseconds":  float64(30000),
+		"maxRetryCount":               float64(10),
+		"liveBufferSize":              float64(500),
+		"readBatchSize":               float64(20),
+		"bufferSize":                  float64(500),
+		"checkPointAfterMilliseconds": float64(2000),
+		"minCheckPointCount":          float64(10),
+		"maxCheckPointCount":          float64(1000),
+		"maxSubscriberCount":          float64(0),
+		"namedConsumerStrategy":       "RoundRobin",
+	})
+}
+
+func (s *PersistentSettingsSuite) TestCreateAndUpdateWithSettings(c *C) {
+	groups := map[string]map[string]interface{}{}
+	serveGroups(c, "orders", groups)
+	ctx := context.Background()
+
+	settings := NewPersistentSubscriptionSettings()
+	settings.ResolveLinkTos = true
+	settings.StartFrom = 0
+	settings.MessageTimeout = 5 * time.Second
+	settings.ConsumerStrategy = ConsumerPinned
+	c.Assert(client.CreatePersistentSubscription(ctx, "orders", "shipping", settings), IsNil)
+	c.Assert(groups["shipping"]["resolveLinktos"], Equals, true)
+	c.Assert(groups["shipping"]["startFrom"], Equals, float64(0))
+	c.Assert(groups["shipping"]["messageTimeoutMilliseconds"], Equals, float64(5000))
+	c.Assert(groups["shipping"]["namedConsumerStrategy"], Equals, "Pinned")
+
+	settings.MaxRetryCount = 3
+	c.Assert(client.UpdatePersistentSubscription(ctx, "orders", "shipping", settings), IsNil)
+	c.Assert(groups["shipping"]["maxRetryCount"], Equals, float64(3))
+
+	err := client.UpdatePersistentSubscription(ctx, "orders", "missing", settings)
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+}
+
+func (s *PersistentSettingsSuite) TestSettingsRoundTrip(c *C) {
+	settings := NewPersistentSubscriptionSettings()
+	settings.ExtraStatistics = true
+	settings.CheckPointAfter = 1500 * time.Millisecond
+	settings.MaxSubscriberCount = 4
+
+	b, err := json.Marshal(settings)
+	c.Assert(err, IsNil)
+	got := &PersistentSubscriptionSettings{}
+	c.Assert(json.Unmarshal(b, got), IsNil)
+	c.Assert(got, DeepEquals, settings)
+}
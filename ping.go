@@ -0,0 +1,151 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ServerInfo describes an eventstore server as reported by its /info endpoint.
+//
+// Version is the version of the server, such as 3.9.4.0. State is the state of
+// the node in the cluster, such as master, slave or clone. ProjectionsMode is
+// the projections mode the server was started with, such as None, System or
+// All.
+type ServerInfo struct {
+	Version         string `json:"esVersion"`
+	State           string `json:"state"`
+	ProjectionsMode string `json:"projectionsMode"`
+}
+
+// featureVersions contains the earliest server version that supports each
+// feature.
+var featureVersions = map[Feature]string{
+	FeatureEmbedBody: "3.0.0",
+	FeatureJSONFeeds: "3.0.0",
+}
+
+// longPollVersion is the earliest server version that supports ES-LongPoll.
+const longPollVersion = "3.0.0"
+
+// VersionAtLeast returns true if the version of the server is the version
+// provided or later. Versions are compared component by component, so
+// 3.10.0 is later than 3.9.4.0. If the version of the server cannot be parsed
+// VersionAtLeast returns false.
+func (i *ServerInfo) VersionAtLeast(version string) bool {
+	have, ok := parseVersion(i.Version)
+	if !ok {
+		return false
+	}
+	want, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	for len(have) < len(want) {
+		have = append(have, 0)
+	}
+	for k := range want {
+		if have[k] != want[k] {
+			return have[k] > want[k]
+		}
+	}
+	return true
+}
+
+// Supports returns true if the server supports the feature.
+func (i *ServerInfo) Supports(f Feature) bool {
+	v, ok := featureVersions[f]
+	return ok && i.VersionAtLeast(v)
+}
+
+// parseVersion parses a dotted version number.
+func parseVersion(s string) ([]int, bool) {
+	if s == "" {
+		return nil, false
+	}
+	parts := strings.Split(s, ".")
+	ret := make([]int, len(parts))
+	for k, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		ret[k] = n
+	}
+	return ret, true
+}
+
+// Ping checks that the server is available and returns its *ServerInfo.
+//
+// The information is read from the /info endpoint of the server and is cached
+// on the client, where it is returned by ServerInfo and used to choose between
+// behaviours that depend on the version of the server: long polling is not
+// requested and event bodies are not embedded in feed pages when the server
+// does not support them. See also EnableExperimentalIfSupported. Ping can be
+// used as a health check and is cancelled when ctx is done.
+func (c *Client) Ping(ctx context.Context) (*ServerInfo, error) {
+	req, err := c.newRequest(http.MethodGet, "/info", nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	var b bytes.Buffer
+	if _, err := c.do(req, &b); err != nil {
+		return nil, err
+	}
+
+	info := &ServerInfo{}
+	if err := json.Unmarshal(b.Bytes(), info); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.serverInfo = info
+	c.mu.Unlock()
+	return info, nil
+}
+
+// ServerInfo returns the *ServerInfo cached by the most recent successful call
+// to Ping, or nil if Ping has not succeeded.
+func (c *Client) ServerInfo() *ServerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverInfo
+}
+
+// EnableExperimentalIfSupported enables those of the features provided that are
+// supported by the server and returns them.
+//
+// The version of the server is taken from the information cached by Ping. If
+// Ping has not succeeded no features are enabled.
+func (c *Client) EnableExperimentalIfSupported(features ...Feature) []Feature {
+	info := c.ServerInfo()
+	if info == nil {
+		return nil
+	}
+	ret := []Feature{}
+	for _, f := range features {
+		if info.Supports(f) {
+			ret = append(ret, f)
+		}
+	}
+	c.EnableExperimental(ret...)
+	return ret
+}
+
+// supportsLongPoll returns false if the server is known not to support
+// ES-LongPoll.
+func (c *Client) supportsLongPoll() bool {
+	info := c.ServerInfo()
+	return info == nil || info.VersionAtLeast(longPollVersion)
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&PingSuite{})
+
+type PingSuite struct{}
+
+func (s *PingSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *PingSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func serveInfo(version string) {
+	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"esVersion":%q,"state":"master","projectionsMode":"All"}`, version)
+	})
+}
+
+func (s *PingSuite) TestPingReturnsServerInfo(c *C) {
+	serveInfo("3.9.4.0")
+	c.Assert(client.ServerInfo(), IsNil)
+
+	info, err := client.Ping(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(*info, DeepEquals, ServerInfo{Version: "3.9.4.0", State: "master", ProjectionsMode: "All"})
+	c.Assert(client.ServerInfo(), DeepEquals, info)
+}
+
+func (s *PingSuite) TestPingReturnsErrorWhenServerUnavailable(c *C) {
+	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unavailable", http.StatusServiceUnavailable)
+	})
+
+	_, err := client.Ping(context.Background())
+	c.Assert(err, NotNil)
+	c.Assert(client.ServerInfo(), IsNil)
+}
+
+func (s *PingSuite) TestPingIsCancelledByContext(c *C) {
+	serveInfo("3.9.4.0")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.Ping(ctx)
+	c.Assert(err, NotNil)
+}
+
+func (s *PingSuite) TestVersionAtLeast(c *C) {
+	info := &ServerInfo{Version: "3.9.4.0"}
+	c.Assert(info.VersionAtLeast("3.0.0"), Equals, true)
+	c.Assert(info.VersionAtLeast("3.9.4"), Equals, true)
+	c.Assert(info.VersionAtLeast("3.10.0"), Equals, false)
+	c.Assert(info.VersionAtLeast("4"), Equals, false)
+	c.Assert((&ServerInfo{Version: "dev"}).VersionAtLeast("3.0.0"), Equals, false)
+}
+
+func (s *PingSuite) TestEnableExperimentalIfSupported(c *C) {
+	c.Assert(client.EnableExperimentalIfSupported(FeatureEmbedBody), HasLen, 0)
+
+	serveInfo("2.0.1.0")
+	_, err := client.Ping(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(client.EnableExperimentalIfSupported(FeatureEmbedBody, FeatureJSONFeeds), HasLen, 0)
+	c.Assert(client.Experimental(FeatureEmbedBody), Equals, false)
+}
+
+func (s *PingSuite) TestEnableExperimentalIfSupportedEnablesFeatures(c *C) {
+	serveInfo("3.9.4.0")
+	_, err := client.Ping(context.Background())
+	c.Assert(err, IsNil)
+
+	got := client.EnableExperimentalIfSupported(FeatureEmbedBody)
+	c.Assert(got, DeepEquals, []Feature{FeatureEmbedBody})
+	c.Assert(client.Experimental(FeatureEmbedBody), Equals, true)
+	c.Assert(client.Experimental(FeatureJSONFeeds), Equals, false)
+}
+
+func (s *PingSuite) TestLongPollNotSentToOldServers(c *C) {
+	serveInfo("2.0.1.0")
+	_, err := client.Ping(context.Background())
+	c.Assert(err, IsNil)
+
+	stream := "ping-longpoll"
+	headers := make(chan string, 10)
+	mux.HandleFunc("/streams/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("ES-LongPoll")
+		http.NotFound(w, r)
+	})
+
+	reader := client.NewStreamReader(stream)
+	reader.LongPoll(5)
+	reader.Next()
+	c.Assert(<-headers, Equals, "")
+}
+
+func (s *PingSuite) TestEmbedBodyNotRequestedFromOldServers(c *C) {
+	client.EnableExperimental(FeatureEmbedBody)
+	c.Assert(client.embedBody(), Equals, true)
+
+	serveInfo("2.0.1.0")
+	_, err := client.Ping(context.Background())
+	c.Assert(err, IsNil)
+
+	stream := "ping-embed"
+	requests := make(chan *http.Request, 10)
+	mux.HandleFunc("/streams/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		http.NotFound(w, r)
+	})
+
+	client.NewStreamReader(stream).Next()
+	r := <-requests
+	c.Assert(r.URL.Query().Get("embed"), Equals, "")
+	c.Assert(r.Header.Get("Accept"), Equals, string(FeedXML))
+}
@@ -0,0 +1,200 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// OperationKind identifies the kind of a PlannedOperation.
+type OperationKind string
+
+const (
+	// OpWriteMetadata replaces the metadata of a stream.
+	OpWriteMetadata OperationKind = "writeMetadata"
+
+	// OpAppend appends events to a stream.
+	OpAppend OperationKind = "append"
+
+	// OpDelete deletes a stream.
+	OpDelete OperationKind = "delete"
+)
+
+// PlannedOperation is a single operation in a Plan.
+//
+// Each operation records the state of the stream it was planned against.
+// OpWriteMetadata operations record the metadata of the stream in
+// CurrentMetadata, and OpAppend and OpDelete operations record the version of
+// the stream in ExpectedVersion. An operation is only executed if the stream is
+// still in that state. ExpectedVersion is ExpectAny for operations that are not
+// conditional on the version of the stream.
+type PlannedOperation struct {
+	Kind            OperationKind          `json:"kind"`
+	Stream          string                 `json:"stream"`
+	ExpectedVersion ExpectedVersion        `json:"expectedVersion"`
+	CurrentMetadata map[string]interface{} `json:"currentMetadata,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Events          []*Event               `json:"events,omitempty"`
+	HardDelete      bool                   `json:"hardDelete,omitempty"`
+}
+
+// Plan is a reviewable list of operations on the eventstore.
+//
+// Tools that change data can produce a Plan instead of making their changes,
+// for example Reconciler.Plan. The plan can be saved with WritePlan, reviewed,
+// and later loaded with ReadPlan and executed with Client.ExecutePlan.
+//
+// WritePlan records a digest of the operations and ReadPlan rejects a plan
+// whose operations do not match its digest, so the operations executed are the
+// operations that were reviewed. Because each operation is conditional on the
+// state of its stream when it was planned, a plan cannot be executed against
+// data that has changed since the review.
+type Plan struct {
+	Operations []PlannedOperation `json:"operations"`
+	Digest     string             `json:"digest,omitempty"`
+}
+
+// WriteStreamMetadata adds an operation to the plan that replaces the metadata
+// of the stream with metadata. current is the metadata of the stream when the
+// plan is made.
+func (p *Plan) WriteStreamMetadata(stream string, current, metadata map[string]interface{}) {
+	p.Operations = append(p.Operations, PlannedOperation{
+		Kind:            OpWriteMetadata,
+		Stream:          stream,
+		ExpectedVersion: ExpectAny,
+		CurrentMetadata: current,
+		Metadata:        metadata,
+	})
+}
+
+// Append adds an operation to the plan that appends events to the stream.
+//
+// expectedVersion has the same meaning as for StreamWriter.Append.
+func (p *Plan) Append(stream string, expectedVersion ExpectedVersion, events ...*Event) {
+	p.Operations = append(p.Operations, PlannedOperation{
+		Kind:            OpAppend,
+		Stream:          stream,
+		ExpectedVersion: expectedVersion,
+		Events:          events,
+	})
+}
+
+// DeleteStream adds an operation to the plan that deletes the stream.
+//
+// Unless expectedVersion is ExpectAny the stream is only deleted if its version
+// matches.
+func (p *Plan) DeleteStream(stream string, expectedVersion ExpectedVersion, hardDelete bool) {
+	p.Operations = append(p.Operations, PlannedOperation{
+		Kind:            OpDelete,
+		Stream:          stream,
+		ExpectedVersion: expectedVersion,
+		HardDelete:      hardDelete,
+	})
+}
+
+// digest returns the SHA-256 digest of the JSON representation of the
+// operations in the plan.
+func (p *Plan) digest() (string, error) {
+	b, err := json.Marshal(p.Operations)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// WritePlan writes the plan to w as indented JSON with the digest of its
+// operations.
+func WritePlan(w io.Writer, p *Plan) error {
+	d, err := p.digest()
+	if err != nil {
+		return err
+	}
+	p.Digest = d
+
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadPlan reads a plan written by WritePlan.
+//
+// An error is returned if the plan has no digest or if its operations do not
+// match its digest.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	p := &Plan{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+	d, err := p.digest()
+	if err != nil {
+		return nil, err
+	}
+	if p.Digest == "" || p.Digest != d {
+		return nil, fmt.Errorf("The plan does not match its digest")
+	}
+	return p, nil
+}
+
+// ExecutePlan executes the operations in the plan in order.
+//
+// Execution stops at the first operation that fails. The number of operations
+// executed is returned. If the stream of an operation has changed since the
+// plan was made an *ErrPlanConflict is returned.
+func (c *Client) ExecutePlan(p *Plan) (int, error) {
+	for i := range p.Operations {
+		if err := c.executeOperation(i, &p.Operations[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(p.Operations), nil
+}
+
+// executeOperation executes the planned operation at index i of a plan.
+func (c *Client) executeOperation(i int, op *PlannedOperation) error {
+	switch op.Kind {
+	case OpWriteMetadata:
+		current, err := c.readMetaDataMap(op.Stream)
+		if err != nil {
+			return err
+		}
+		planned := op.CurrentMetadata
+		if planned == nil {
+			planned = map[string]interface{}{}
+		}
+		if !jsonEqual(current, planned) {
+			return &ErrPlanConflict{
+				Operation: i,
+				Stream:    op.Stream,
+				Err:       fmt.Errorf("The stream metadata has changed"),
+			}
+		}
+		return c.NewStreamWriter(op.Stream).WriteMetaData(op.Stream, op.Metadata)
+
+	case OpAppend:
+		err := c.NewStreamWriter(op.Stream).Append(op.ExpectedVersion, op.Events...)
+		if _, ok := err.(*ErrConcurrencyViolation); ok {
+			return &ErrPlanConflict{Operation: i, Stream: op.Stream, Err: err}
+		}
+		return err
+
+	case OpDelete:
+		_, err := c.deleteStream(context.Background(), op.Stream, op.HardDelete, op.ExpectedVersion)
+		if _, ok := err.(*ErrBadRequest); ok && op.ExpectedVersion != ExpectAny {
+			return &ErrPlanConflict{Operation: i, Stream: op.Stream, Err: err}
+		}
+		return err
+	}
+	return fmt.Errorf("Unknown operation kind %q", op.Kind)
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&PlanSuite{})
+
+type PlanSuite struct{}
+
+func (s *PlanSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *PlanSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *PlanSuite) TestReconcilerPlanRoundTripsAndExecutes(c *C) {
+	writes, _ := serveStreams(c, map[string]interface{}{
+		"order-1": map[string]interface{}{"$maxCount": 5, "owner": "sales"},
+		"order-2": map[string]interface{}{"$maxCount": 100},
+	})
+
+	r := client.NewReconciler()
+	r.AddPolicy(CategoryPolicy{Pattern: "order", Metadata: StreamMetadata{MaxCount: Int(100)}})
+
+	plan, err := r.Plan()
+	c.Assert(err, IsNil)
+	c.Assert(writes, HasLen, 0)
+	c.Assert(plan.Operations, HasLen, 1)
+	c.Assert(plan.Operations[0].Kind, Equals, OpWriteMetadata)
+	c.Assert(plan.Operations[0].Stream, Equals, "order-1")
+
+	var buf bytes.Buffer
+	c.Assert(WritePlan(&buf, plan), IsNil)
+	reviewed, err := ReadPlan(&buf)
+	c.Assert(err, IsNil)
+
+	n, err := client.ExecutePlan(reviewed)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 1)
+	c.Assert(writes, HasLen, 1)
+	c.Assert(writes["order-1"], DeepEquals, map[string]interface{}{"$maxCount": float64(100), "owner": "sales"})
+}
+
+func (s *PlanSuite) TestExecutePlanReturnsConflictWhenMetadataChanged(c *C) {
+	writes, _ := serveStreams(c, map[string]interface{}{
+		"order-1": map[string]interface{}{"$maxCount": 5},
+	})
+
+	plan := &Plan{}
+	plan.WriteStreamMetadata("order-1", map[string]interface{}{"$maxCount": 10}, map[string]interface{}{"$maxCount": 100})
+
+	n, err := client.ExecutePlan(plan)
+	c.Assert(n, Equals, 0)
+	c.Assert(err, FitsTypeOf, &ErrPlanConflict{})
+	c.Assert(writes, HasLen, 0)
+}
+
+func (s *PlanSuite) TestReadPlanRejectsModifiedPlan(c *C) {
+	plan := &Plan{}
+	plan.DeleteStream("order-1", 3, false)
+
+	var buf bytes.Buffer
+	c.Assert(WritePlan(&buf, plan), IsNil)
+	modified := strings.Replace(buf.String(), "order-1", "order-2", 1)
+
+	_, err := ReadPlan(strings.NewReader(modified))
+	c.Assert(err, NotNil)
+}
+
+func (s *PlanSuite) TestExecutePlanAppendsAndDeletes(c *C) {
+	var appended, deleted bool
+	mux.HandleFunc("/streams/order-1", func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		c.Assert(r.Header.Get("ES-ExpectedVersion"), Equals, "-1")
+		appended = true
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/streams/order-2", func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodDelete)
+		if r.Header.Get("ES-ExpectedVersion") != "7" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	plan := &Plan{}
+	plan.Append("order-1", ExpectNoStream, NewEvent("", "OrderPlaced", nil, nil))
+	plan.DeleteStream("order-2", 7, false)
+	n, err := client.ExecutePlan(plan)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, 2)
+	c.Assert(appended, Equals, true)
+	c.Assert(deleted, Equals, true)
+
+	plan = &Plan{}
+	plan.DeleteStream("order-2", 6, false)
+	_, err = client.ExecutePlan(plan)
+	c.Assert(err, FitsTypeOf, &ErrPlanConflict{})
+}
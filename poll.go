@@ -0,0 +1,116 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"math/rand"
+	"time"
+)
+
+// PollStrategy decides how long to wait before reading the head of a stream
+// again when the previous read found no new events.
+//
+// Wait is called with the number of consecutive reads that have found no new
+// events, starting at 1. The count is reset when an event is read.
+// Implementations must be safe for concurrent use.
+type PollStrategy interface {
+	Wait(attempt int) time.Duration
+}
+
+// FixedPoll waits for the same interval after every read.
+type FixedPoll struct {
+	Interval time.Duration
+}
+
+// Wait returns the interval.
+func (p FixedPoll) Wait(attempt int) time.Duration {
+	return p.Interval
+}
+
+// ExponentialPoll waits for Min after the first read that finds no events and
+// doubles the wait after each further read, up to Max.
+//
+// Readers of streams that are written to in bursts poll quickly while the
+// burst lasts and back off when the stream is quiet.
+type ExponentialPoll struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Wait returns Min doubled for each attempt after the first, up to Max.
+func (p ExponentialPoll) Wait(attempt int) time.Duration {
+	d := p.Min
+	for i := 1; i < attempt && d < p.Max; i++ {
+		d *= 2
+	}
+	if d > p.Max {
+		d = p.Max
+	}
+	return d
+}
+
+// JitteredPoll varies the wait of another strategy at random by up to the
+// fraction Jitter of the wait in either direction, so that many readers
+// started together do not poll the server at the same moments.
+type JitteredPoll struct {
+	Strategy PollStrategy
+	Jitter   float64
+}
+
+// Wait returns the wait of the strategy with jitter applied.
+func (p JitteredPoll) Wait(attempt int) time.Duration {
+	d := p.Strategy.Wait(attempt)
+	if p.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	j := p.Jitter
+	if j > 1 {
+		j = 1
+	}
+	return time.Duration(float64(d) * (1 + j*(2*rand.Float64()-1)))
+}
+
+// SetPollStrategy sets the strategy used to wait at the head of the stream.
+//
+// When a strategy is set, a call to Next() that follows a call that returned an
+// *ErrNoMoreEvents waits for the time chosen by the strategy before reading the
+// stream, so callers can call Next() in a loop without sleeping. The wait is
+// abandoned if the reader is closed. A nil strategy disables waiting, which is
+// the default.
+//
+// A poll strategy is an alternative to LongPoll for servers that do not support
+// long polling. The two should not be used together.
+func (s *StreamReader) SetPollStrategy(p PollStrategy) {
+	s.poll = p
+	s.idle = 0
+}
+
+// pollWait waits before a read if the previous read found no new events. It
+// returns false if the reader was closed while waiting.
+func (s *StreamReader) pollWait() bool {
+	if s.poll == nil || s.idle == 0 {
+		return true
+	}
+	t := time.NewTimer(s.poll.Wait(s.idle))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-s.context().Done():
+		return false
+	}
+}
+
+// SetPollStrategy sets the strategy used to wait before reading the head of the
+// stream again when there are no new events. The default is a FixedPoll of one
+// second.
+//
+// If long polling is enabled with SetLongPoll the strategy is used only if the
+// client has detected with Client.Ping that the server does not support long
+// polling.
+func (s *Subscription) SetPollStrategy(p PollStrategy) {
+	s.poll = p
+}
@@ -0,0 +1,123 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&PollSuite{})
+
+type PollSuite struct{}
+
+func (s *PollSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *PollSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// recordingPoll is a PollStrategy that records the attempts it is called with
+// and waits for a millisecond.
+type recordingPoll struct {
+	mu       sync.Mutex
+	attempts []int
+}
+
+func (p *recordingPoll) Wait(attempt int) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts = append(p.attempts, attempt)
+	return time.Millisecond
+}
+
+func (p *recordingPoll) recorded() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int(nil), p.attempts...)
+}
+
+func (s *PollSuite) TestFixedPoll(c *C) {
+	p := FixedPoll{Interval: time.Second}
+	c.Assert(p.Wait(1), Equals, time.Second)
+	c.Assert(p.Wait(10), Equals, time.Second)
+}
+
+func (s *PollSuite) TestExponentialPoll(c *C) {
+	p := ExponentialPoll{Min: 100 * time.Millisecond, Max: time.Second}
+	c.Assert(p.Wait(1), Equals, 100*time.Millisecond)
+	c.Assert(p.Wait(2), Equals, 200*time.Millisecond)
+	c.Assert(p.Wait(4), Equals, 800*time.Millisecond)
+	c.Assert(p.Wait(5), Equals, time.Second)
+	c.Assert(p.Wait(1000), Equals, time.Second)
+}
+
+func (s *PollSuite) TestJitteredPoll(c *C) {
+	p := JitteredPoll{Strategy: FixedPoll{Interval: time.Second}, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		d := p.Wait(1)
+		c.Assert(d >= 800*time.Millisecond && d <= 1200*time.Millisecond, Equals, true, Commentf("wait %v", d))
+	}
+
+	p.Jitter = 0
+	c.Assert(p.Wait(1), Equals, time.Second)
+}
+
+func (s *PollSuite) TestReaderWaitsAtHeadWithPollStrategy(c *C) {
+	stream := "poll-reader"
+	es := CreateTestEvents(2, stream, server.URL, "Foo")
+	setupSimulator(es, nil)
+
+	p := &recordingPoll{}
+	reader := client.NewStreamReader(stream)
+	reader.SetPollStrategy(p)
+
+	c.Assert(readAll(c, reader), DeepEquals, sequence(2))
+	c.Assert(p.recorded(), HasLen, 0)
+
+	for i := 0; i < 3; i++ {
+		reader.Next()
+		c.Assert(reader.Err(), FitsTypeOf, &ErrNoMoreEvents{})
+	}
+	c.Assert(p.recorded(), DeepEquals, []int{1, 2, 3})
+}
+
+func (s *PollSuite) TestCloseAbandonsPollWait(c *C) {
+	stream := "poll-close"
+	es := CreateTestEvents(1, stream, server.URL, "Foo")
+	setupSimulator(es, nil)
+
+	reader := client.NewStreamReader(stream)
+	reader.SetPollStrategy(FixedPoll{Interval: time.Hour})
+	readAll(c, reader)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		reader.Close()
+	}()
+	reader.Next()
+	c.Assert(reader.Err(), Equals, context.Canceled)
+}
+
+func (s *PollSuite) TestSubscriptionUsesPollStrategy(c *C) {
+	stream := "poll-sub"
+	es := CreateTestEvents(1, stream, server.URL, "Foo")
+	setupSimulator(es, nil)
+
+	p := &recordingPoll{}
+	sub := client.NewCatchUpSubscription(stream, 0, func(er *EventResponse) error { return nil })
+	sub.SetPollStrategy(p)
+	sub.Start()
+	defer sub.Stop()
+
+	eventually(func() bool { return len(p.recorded()) >= 3 })
+	got := p.recorded()
+	c.Assert(got[:3], DeepEquals, []int{1, 2, 3})
+}
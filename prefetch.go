@@ -0,0 +1,290 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// prefetchItem carries either an event or an error from the prefetcher to
+// the reader along with the url of the feed page it came from. skip is the
+// number of events before it that were skipped because of their type or
+// because they were missing, and skipped describes those that were missing.
+type prefetchItem struct {
+	event   *EventResponse
+	url     string
+	err     error
+	skip    int
+	skipped []*SkippedEvent
+}
+
+// prefetcher reads feed pages and events ahead of the consumer.
+//
+// Events are delivered in stream order on the items channel. The capacity of the
+// channel bounds the number of events held in memory. When the channel is full the
+// prefetcher blocks until the consumer catches up.
+//
+// The prefetcher stops after delivering the first error it encounters, including
+// *ErrNoMoreEvents when the head of the stream is reached, or when it is stopped.
+type prefetcher struct {
+	client      *Client
+	ctx         context.Context
+	concurrency int
+	types       eventTypes
+	skipMissing bool
+	items       chan prefetchItem
+	done        chan struct{}
+	once        sync.Once
+}
+
+// newPrefetcher returns a running prefetcher that begins reading at the feed
+// page url and buffers up to size events. The events on each page are fetched
+// using up to concurrency requests and events of types other than types are
+// skipped. If skipMissing is true events that are not found are skipped and
+// delivered with the next item. Requests in progress are
+// cancelled when ctx is done.
+func newPrefetcher(ctx context.Context, client *Client, url string, size int, concurrency int, types eventTypes, skipMissing bool) *prefetcher {
+	p := &prefetcher{
+		client:      client,
+		ctx:         ctx,
+		concurrency: concurrency,
+		types:       types,
+		skipMissing: skipMissing,
+		items:       make(chan prefetchItem, size),
+		done:        make(chan struct{}),
+	}
+	go p.run(url)
+	return p
+}
+
+func (p *prefetcher) run(url string) {
+	defer close(p.items)
+
+	skip := 0
+	var missing []*SkippedEvent
+	for {
+		f, _, err := p.client.readFeed(p.ctx, url)
+		if err != nil {
+			p.send(prefetchItem{url: url, err: requestError(p.ctx, err), skip: skip, skipped: missing})
+			return
+		}
+
+		if len(f.Entry) <= 0 {
+			p.send(prefetchItem{url: url, err: &ErrNoMoreEvents{}, skip: skip, skipped: missing})
+			return
+		}
+
+		var events []*EventResponse
+		var errs []error
+		if p.concurrency > 1 {
+			events, errs = p.client.fetchEntries(p.ctx, f.Entry, p.concurrency, p.types)
+		}
+
+		// Entries are ordered most recent first.
+		for i := len(f.Entry) - 1; i >= 0; i-- {
+			if p.types.skipsEntry(f.Entry[i]) {
+				skip++
+				continue
+			}
+			var e *EventResponse
+			var err error
+			if events != nil {
+				e, err = events[i], errs[i]
+			} else {
+				e, err = p.client.entryEvent(p.ctx, f.Entry[i])
+			}
+			if _, ok := err.(*ErrNotFound); ok && p.skipMissing {
+				href := strings.TrimRight(f.Entry[i].Link[1].Href, "/")
+				missing = append(missing, &SkippedEvent{EventNumber: eventNumberFromURL(href), URL: href, Err: err})
+				skip++
+				continue
+			}
+			if err != nil {
+				p.send(prefetchItem{url: url, err: requestError(p.ctx, err), skip: skip, skipped: missing})
+				return
+			}
+			if p.types.skipsEvent(e) {
+				skip++
+				continue
+			}
+			if !p.send(prefetchItem{url: url, event: e, skip: skip, skipped: missing}) {
+				return
+			}
+			skip = 0
+			missing = nil
+		}
+
+		l := f.GetLink("previous")
+		if l == nil {
+			p.send(prefetchItem{url: url, err: &ErrNoMoreEvents{}, skip: skip, skipped: missing})
+			return
+		}
+		url = l.Href
+	}
+}
+
+// send delivers the item to the consumer. It returns false if the prefetcher
+// was stopped before the item could be delivered.
+func (p *prefetcher) send(item prefetchItem) bool {
+	select {
+	case p.items <- item:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// stop signals the prefetcher to exit. It is safe to call stop more than once.
+func (p *prefetcher) stop() {
+	p.once.Do(func() { close(p.done) })
+}
+
+// Prefetch enables reading ahead of the consumer.
+//
+// When prefetching is enabled the reader fetches feed pages and events in the
+// background while the consumer processes the events already returned by Next().
+// size is the maximum number of events that will be buffered ahead of the consumer.
+// Setting size to 0 or below disables prefetching.
+//
+// The semantics of Next(), Err() and Scan() are unchanged. When an error occurs,
+// including reaching the head of the stream, background fetching stops and resumes
+// from the reader's position on the next call to Next().
+//
+// A reader that is prefetching should be closed when it is no longer required.
+func (s *StreamReader) Prefetch(size int) {
+	s.stopPrefetch()
+	s.prefetch = size
+}
+
+// Close stops any background activity on the reader and cancels any request
+// that is in progress.
+//
+// Close may be called from another goroutine while Next() is running, for
+// example while it waits for a long poll to return. Next() then returns with
+// Err() returning context.Canceled. Cancelled requests do not affect other
+// requests made by the client. The connection used by a cancelled request is
+// closed rather than returned to the client's connection pool.
+//
+// The reader may still be used after Close. The next call to Next() resumes
+// from the reader's position and, if prefetching is enabled, prefetching
+// resumes.
+func (s *StreamReader) Close() {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if !s.active {
+		s.ctx, s.cancel = nil, nil
+	}
+	s.mu.Unlock()
+	s.stopPrefetch()
+}
+
+// begin is called at the start of Next() and creates the context used for the
+// requests made by the reader if the reader does not have one.
+func (s *StreamReader) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = true
+	if s.ctx == nil {
+		parent := s.parent
+		if parent == nil {
+			parent = context.Background()
+		}
+		s.ctx, s.cancel = context.WithCancel(parent)
+	}
+}
+
+// end is called when Next() returns. If the reader was closed while Next() was
+// running the cancelled context is discarded so that the reader can be used
+// again.
+func (s *StreamReader) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = false
+	if s.ctx != nil && s.ctx.Err() != nil {
+		s.ctx, s.cancel = nil, nil
+	}
+}
+
+// context returns the context used for the requests made by the reader.
+func (s *StreamReader) context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+func (s *StreamReader) stopPrefetch() {
+	s.mu.Lock()
+	f := s.fetcher
+	s.fetcher = nil
+	s.mu.Unlock()
+	if f != nil {
+		f.stop()
+	}
+}
+
+// nextPrefetched is the implementation of Next() used when prefetching is enabled.
+func (s *StreamReader) nextPrefetched() bool {
+	s.mu.Lock()
+	f := s.fetcher
+	s.mu.Unlock()
+
+	if f == nil {
+		url, err := s.client.GetFeedPath(s.streamName, "forward", s.nextVersion, s.pageSize)
+		if err != nil {
+			s.lasterr = err
+			return false
+		}
+		s.currentURL = url
+		f = newPrefetcher(s.context(), s.client, url, s.prefetch, s.fetchConcurrency, s.eventTypes, s.skipsMissing())
+		s.mu.Lock()
+		s.fetcher = f
+		s.mu.Unlock()
+	}
+
+	item, ok := <-f.items
+	if !ok {
+		// The prefetcher was stopped, either by Close or because of an earlier
+		// error.
+		item.err = requestError(f.ctx, &ErrNoMoreEvents{})
+	}
+
+	if item.url != "" {
+		s.currentURL = item.url
+	}
+	s.nextVersion += item.skip
+	for _, e := range item.skipped {
+		s.reportSkipped(e)
+	}
+
+	if item.err != nil {
+		s.stopPrefetch()
+		if _, ok := item.err.(*ErrNoMoreEvents); ok {
+			s.eventResponse = nil
+		}
+		s.lasterr = item.err
+		return true
+	}
+
+	s.eventResponse = item.event
+	s.version = s.nextVersion
+	s.nextVersion++
+
+	return true
+}
+
+// requestError returns the error of ctx if it is done, otherwise err. A
+// request cancelled by StreamReader.Close is reported as context.Canceled
+// rather than as the transport error returned by the http client.
+func requestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,215 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&PrefetchSuite{})
+
+type PrefetchSuite struct{}
+
+func (s *PrefetchSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *PrefetchSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// Test that a prefetching reader returns all events in order across several
+// feed pages and then reports that there are no more events.
+func (s *PrefetchSuite) TestPrefetchReturnsEventsInOrder(c *C) {
+	streamName := "prefetch-stream"
+	ne := 45
+	es := CreateTestEvents(ne, streamName, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	reader := client.NewStreamReader(streamName)
+	reader.Prefetch(5)
+	defer reader.Close()
+
+	count := 0
+	for reader.Next() {
+		if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+			break
+		}
+		c.Assert(reader.Err(), IsNil)
+		c.Assert(reader.EventResponse().Event.EventNumber, Equals, count)
+		c.Assert(reader.Version(), Equals, count)
+		count++
+	}
+	c.Assert(count, Equals, ne)
+	c.Assert(reader.EventResponse(), IsNil)
+}
+
+// Test that the prefetcher does not read further ahead than the buffer allows.
+func (s *PrefetchSuite) TestPrefetchIsBoundedByBufferSize(c *C) {
+	streamName := "prefetch-bounded"
+	es := CreateTestEvents(40, streamName, server.URL, "FooEvent")
+
+	sim := newTestSimulator(es, nil)
+	var requests int32
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		sim.ServeHTTP(w, r)
+	})
+
+	reader := client.NewStreamReader(streamName)
+	reader.Prefetch(3)
+	defer reader.Close()
+
+	reader.Next()
+	c.Assert(reader.Err(), IsNil)
+
+	// One feed page, the event returned, the events buffered and the event
+	// the prefetcher is blocked trying to deliver.
+	eventually(func() bool { return atomic.LoadInt32(&requests) == 6 })
+	c.Assert(atomic.LoadInt32(&requests), Equals, int32(6))
+}
+
+// Test that seeking a prefetching reader restarts reading at the new position.
+func (s *PrefetchSuite) TestPrefetchSeek(c *C) {
+	streamName := "prefetch-seek"
+	es := CreateTestEvents(30, streamName, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	reader := client.NewStreamReader(streamName)
+	reader.Prefetch(10)
+	defer reader.Close()
+
+	reader.Next()
+	c.Assert(reader.EventResponse().Event.EventNumber, Equals, 0)
+
+	reader.Seek(25)
+	reader.Next()
+	c.Assert(reader.Err(), IsNil)
+	c.Assert(reader.EventResponse().Event.EventNumber, Equals, 25)
+}
+
+// serveLongPoll registers a handler for the stream that holds each request open
+// until the client cancels it, as the server does during a long poll when
+// there are no new events.
+// The counts of open and total requests are returned.
+func serveLongPoll(stream string) (open, total *int32) {
+	open, total = new(int32), new(int32)
+	mux.HandleFunc("/streams/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(open, 1)
+		atomic.AddInt32(total, 1)
+		defer atomic.AddInt32(open, -1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	return open, total
+}
+
+// nextAsync calls Next on the reader and returns a channel that receives the
+// reader's error when Next returns.
+func nextAsync(reader *StreamReader) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		reader.Next()
+		ch <- reader.Err()
+	}()
+	return ch
+}
+
+// Test that closing a reader cancels a request in progress and that the reader
+// can be used again after it is closed.
+func (s *PrefetchSuite) TestCloseCancelsRequestInProgress(c *C) {
+	open, _ := serveLongPoll("hanging")
+
+	reader := client.NewStreamReader("hanging")
+	done := nextAsync(reader)
+	eventually(func() bool { return atomic.LoadInt32(open) == 1 })
+	c.Assert(atomic.LoadInt32(open), Equals, int32(1))
+
+	reader.Close()
+	select {
+	case err := <-done:
+		c.Assert(err, Equals, context.Canceled)
+	case <-time.After(time.Second):
+		c.Fatal("Next did not return after Close")
+	}
+	eventually(func() bool { return atomic.LoadInt32(open) == 0 })
+	c.Assert(atomic.LoadInt32(open), Equals, int32(0))
+
+	done = nextAsync(reader)
+	eventually(func() bool { return atomic.LoadInt32(open) == 1 })
+	c.Assert(atomic.LoadInt32(open), Equals, int32(1))
+	reader.Close()
+	c.Assert(<-done, Equals, context.Canceled)
+}
+
+// Test that closing a prefetching reader cancels the request made by the
+// prefetcher.
+func (s *PrefetchSuite) TestCloseCancelsPrefetch(c *C) {
+	open, _ := serveLongPoll("hanging-prefetch")
+
+	reader := client.NewStreamReader("hanging-prefetch")
+	reader.Prefetch(5)
+	done := nextAsync(reader)
+	eventually(func() bool { return atomic.LoadInt32(open) == 1 })
+
+	reader.Close()
+	select {
+	case err := <-done:
+		c.Assert(err, Equals, context.Canceled)
+	case <-time.After(time.Second):
+		c.Fatal("Next did not return after Close")
+	}
+	eventually(func() bool { return atomic.LoadInt32(open) == 0 })
+	c.Assert(atomic.LoadInt32(open), Equals, int32(0))
+}
+
+// Test that readers can be opened and closed rapidly while requests are in
+// progress without affecting other requests made by the client.
+func (s *PrefetchSuite) TestRapidOpenCloseCycles(c *C) {
+	open, total := serveLongPoll("hanging-cycles")
+	streamName := "cycles-stream"
+	es := CreateTestEvents(25, streamName, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		reader := client.NewStreamReader("hanging-cycles")
+		if i%2 == 1 {
+			reader.Prefetch(5)
+		}
+		done := nextAsync(reader)
+		eventually(func() bool { return atomic.LoadInt32(total) == int32(i+1) })
+		reader.Close()
+		select {
+		case err := <-done:
+			c.Assert(err, Equals, context.Canceled)
+		case <-time.After(time.Second):
+			c.Fatalf("Next did not return after Close on cycle %d", i)
+		}
+	}
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+
+	eventually(func() bool { return atomic.LoadInt32(open) == 0 })
+	c.Assert(atomic.LoadInt32(open), Equals, int32(0))
+
+	reader := client.NewStreamReader(streamName)
+	count := 0
+	for reader.Next() {
+		if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+			break
+		}
+		c.Assert(reader.Err(), IsNil)
+		c.Assert(reader.EventResponse().Event.EventNumber, Equals, count)
+		count++
+	}
+	c.Assert(count, Equals, 25)
+}
@@ -0,0 +1,561 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// partitionQueue is the number of events queued for each worker of a
+// partitioned projector.
+const partitionQueue = 64
+
+var (
+	contextType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	eventMetaType = reflect.TypeOf(EventMeta{})
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// EventMeta describes the event passed to a Projector handler.
+//
+// Updated is the time the event was written, or the zero time if the server
+// did not report it. MetaData is the metadata of the event as it was written.
+type EventMeta struct {
+	Stream        string
+	EventNumber   int
+	EventID       string
+	EventType     string
+	Updated       time.Time
+	CorrelationID string
+	CausationID   string
+	MetaData      json.RawMessage
+}
+
+// partitions holds the state of the workers of a partitioned projector. The
+// fields other than queues, ctx and failed are guarded by the mutex of the
+// projector. inflight and handled hold the positions of the events in the
+// stream, as events in a stream such as $ce-order can have the same event
+// number.
+type partitions struct {
+	queues   []chan *queuedEvent
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	inflight []int
+	handled  map[int]bool
+	failed   chan struct{}
+	err      error
+}
+
+// queuedEvent is an event queued for a worker of a partitioned projector with
+// its position in the stream.
+type queuedEvent struct {
+	er  *EventResponse
+	pos int
+}
+
+// projection is a handler registered with a Projector.
+type projection struct {
+	typ reflect.Type
+	ptr bool
+	fn  reflect.Value
+}
+
+// Projector builds a read model from the events in a stream by calling the
+// handler registered for the type of each event with the event decoded.
+//
+// The projector reads the stream with a catch-up subscription, so events are
+// handled one at a time in stream order. It records the position in the stream
+// of the last event handled, as returned by FeedPosition, in a CheckpointStore
+// under its name and resumes after it when it is started again. Events handled
+// after the last checkpoint stored are handled again after a restart, so
+// handlers should be idempotent.
+//
+// Events whose type has no handler are skipped. When a handler returns an
+// error the EventErrorPolicy of the projector is applied, so the event can be
+// retried, skipped or sent to a dead letter stream. By default the projector
+// stops and Err returns the error.
+//
+// Events are handled one at a time unless SetPartitions is used to handle
+// events with different keys in parallel.
+//
+//	p := client.NewProjector("order-summary", "$ce-order", store)
+//	p.On("OrderPlaced", func(ctx context.Context, e OrderPlaced, meta goes.EventMeta) error {
+//		return summaries.Add(e.OrderID, e.Total)
+//	})
+//	err := p.Start()
+type Projector struct {
+	client   *Client
+	name     string
+	stream   string
+	store    CheckpointStore
+	handlers map[string]*projection
+	onError  EventErrorPolicy
+	every    int
+	workers  int
+	key      func(*EventResponse) string
+	reporter ErrorReporter
+	storeMu  sync.Mutex
+	stored   int
+	mu       sync.Mutex
+	pending  int
+	last     int
+	parts    *partitions
+	err      error
+	stop     chan struct{}
+	drain    chan struct{}
+	done     chan struct{}
+}
+
+// NewProjector returns a projector named name that handles the events in the
+// stream and records its checkpoints in store under name.
+func (c *Client) NewProjector(name, stream string, store CheckpointStore) *Projector {
+	return &Projector{
+		client:   c,
+		name:     name,
+		stream:   stream,
+		store:    store,
+		handlers: make(map[string]*projection),
+		every:    1,
+		last:     -1,
+	}
+}
+
+// On registers the handler for events of the event type.
+//
+// handler must be a function of the form
+//
+//	func(ctx context.Context, e T, meta goes.EventMeta) error
+//
+// where T is the type the data of the event is decoded into, or a pointer to
+// it. If eventType is empty the name of T is used, matching the event type
+// assigned by NewEvent. The context carries the Lineage of the event, so
+// events appended with StreamWriter.AppendContext record the event that caused
+// them.
+//
+// On panics if handler is not a function of that form. Handlers must be
+// registered before the projector is started.
+func (p *Projector) On(eventType string, handler interface{}) {
+	fn := reflect.ValueOf(handler)
+	t := fn.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 3 || t.NumOut() != 1 ||
+		t.In(0) != contextType || t.In(2) != eventMetaType || t.Out(0) != errorType {
+		panic(fmt.Sprintf("goes: handler for %s must be a func(context.Context, T, goes.EventMeta) error, not %s", eventType, t))
+	}
+
+	h := &projection{typ: t.In(1), fn: fn}
+	if h.typ.Kind() == reflect.Ptr {
+		h.typ = h.typ.Elem()
+		h.ptr = true
+	}
+	if eventType == "" {
+		eventType = h.typ.Name()
+	}
+	p.handlers[eventType] = h
+}
+
+// SetEventErrorPolicy sets the policy applied when a handler returns an error.
+// See Subscription.SetEventErrorPolicy.
+func (p *Projector) SetEventErrorPolicy(policy EventErrorPolicy) {
+	p.onError = policy
+}
+
+// SetCheckpointInterval sets the number of events handled between
+// checkpoints. A larger interval stores fewer checkpoints, at the cost of more
+// events being handled again after a restart. The default is 1. A checkpoint
+// is also stored when the projector is stopped.
+func (p *Projector) SetCheckpointInterval(n int) {
+	if n < 1 {
+		n = 1
+	}
+	p.every = n
+}
+
+// SetPartitions makes the projector handle events concurrently with the
+// number of workers provided.
+//
+// Each event is assigned to a worker by the key returned by key using a
+// HashPartitioner, so events with the same key are handled in stream order by
+// the same worker while events with different keys are handled in parallel.
+// The key is usually the id of the aggregate the event belongs to. If key is
+// nil the stream id of the event is used, which for the events of a category
+// stream such as $ce-order identifies the aggregate. Handlers must be safe to
+// call concurrently.
+//
+// The EventErrorPolicy is applied by each worker, and an error that is not
+// skipped or dead lettered stops the projector. The checkpoint is the last
+// event before which every event has been handled, so events handled ahead of
+// it by other workers are handled again after a restart. A number of workers
+// of 1 or less handles events one at a time, which is the default.
+func (p *Projector) SetPartitions(workers int, key func(*EventResponse) string) {
+	if key == nil {
+		key = func(er *EventResponse) string { return er.Event.EventStreamID }
+	}
+	p.workers = workers
+	p.key = key
+}
+
+// LastProcessed returns the position in the stream of the last event handled
+// by the projector, including events whose type has no handler. When events are
+// handled in parallel it is the last event before which every event has been
+// handled.
+func (p *Projector) LastProcessed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+// Err returns the error that stopped the projector, or nil.
+func (p *Projector) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Start loads the checkpoint of the projector and starts handling the events
+// after it. An error is returned if the checkpoint cannot be loaded.
+func (p *Projector) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return nil
+	}
+	cp, err := p.store.Load(p.name)
+	if err != nil {
+		return err
+	}
+	p.stop = make(chan struct{})
+	p.drain = make(chan struct{})
+	p.done = make(chan struct{})
+	p.err = nil
+	p.pending = 0
+	p.last = cp
+	p.stored = cp
+	p.parts = nil
+
+	sub := p.client.NewCatchUpSubscription(p.stream, cp+1, nil)
+	if p.workers > 1 {
+		p.startWorkers()
+		sub.SetContextHandler(p.dispatch)
+	} else {
+		sub.SetContextHandler(p.project)
+		sub.SetEventErrorPolicy(p.onError)
+	}
+	sub.Start()
+	go p.run(sub, p.stop, p.drain, p.done)
+	return nil
+}
+
+// Stop stops the projector and stores the checkpoint of the last event
+// handled.
+func (p *Projector) Stop() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop = nil
+	p.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Shutdown stops the projector gracefully. The event being handled is
+// finished and, when events are handled in parallel, the events already queued
+// for the workers are handled too. The checkpoint of the last event handled is
+// then stored and Shutdown returns Err, which reports a failure to store it.
+//
+// If ctx is done before then the projector is stopped as with Stop, storing the
+// checkpoint of the events handled so far, and ctx.Err() is returned.
+func (p *Projector) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	stop, drain, done := p.stop, p.drain, p.done
+	p.stop = nil
+	p.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	close(drain)
+	select {
+	case <-done:
+		return p.Err()
+	case <-ctx.Done():
+		close(stop)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed when the projector stops.
+func (p *Projector) Done() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done
+}
+
+func (p *Projector) run(sub *Subscription, stop, drain, done chan struct{}) {
+	defer close(done)
+
+	p.mu.Lock()
+	parts := p.parts
+	p.mu.Unlock()
+	var failed chan struct{}
+	if parts != nil {
+		failed = parts.failed
+	}
+
+	var err error
+	draining := false
+	for stopped := false; !stopped; {
+		select {
+		case <-stop:
+			stopped = true
+		case <-drain:
+			draining = true
+			stopped = true
+		case <-sub.Done():
+			err = sub.Err()
+			stopped = true
+		case <-failed:
+			stopped = true
+		case e := <-sub.Errors():
+			if !IsFatal(e) {
+				p.reporter.Report(p.component(), e.(*ErrBackground).Err, false)
+			}
+		}
+	}
+
+	// The last event processed by the subscription is the last event that was
+	// handled successfully or skipped by the error policy. When events are
+	// handled in parallel the workers are stopped, or finish their queues when
+	// the projector is shut down, and the last event before which every event
+	// was handled is used instead.
+	checkpoint := func() int { return sub.LastProcessed() }
+	if draining {
+		// The subscription and the workers finish the events they have
+		// unless the shutdown is cut short by Stop.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+				if parts != nil {
+					parts.cancel()
+				}
+			case <-ctx.Done():
+			}
+		}()
+		sub.Shutdown(ctx)
+		err = sub.Err()
+	}
+	if parts != nil {
+		if !draining {
+			parts.cancel()
+		}
+		sub.Stop()
+		for _, q := range parts.queues {
+			close(q)
+		}
+		parts.wg.Wait()
+		p.mu.Lock()
+		if parts.err != nil {
+			err = parts.err
+		}
+		p.mu.Unlock()
+		checkpoint = p.LastProcessed
+	}
+	sub.Stop()
+
+	p.mu.Lock()
+	pending := p.pending
+	p.mu.Unlock()
+	if pending > 0 {
+		if cerr := p.storeCheckpoint(checkpoint()); err == nil {
+			err = cerr
+		}
+	}
+
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+	if err != nil {
+		p.reporter.Report(p.component(), err, true)
+	}
+}
+
+func (p *Projector) component() string {
+	return "Projector " + p.name
+}
+
+// storeCheckpoint stores the checkpoint unless a later one has been stored.
+func (p *Projector) storeCheckpoint(cp int) error {
+	p.storeMu.Lock()
+	defer p.storeMu.Unlock()
+	if cp <= p.stored {
+		return nil
+	}
+	if err := p.store.Store(p.name, cp); err != nil {
+		return err
+	}
+	p.stored = cp
+	return nil
+}
+
+// project calls the handler for the event and stores a checkpoint every
+// checkpoint interval.
+func (p *Projector) project(ctx context.Context, er *EventResponse) error {
+	if err := p.handle(ctx, er); err != nil {
+		return err
+	}
+
+	pos, _ := FeedPosition(ctx)
+	p.mu.Lock()
+	p.last = pos
+	p.pending++
+	due := p.pending >= p.every
+	if due {
+		p.pending = 0
+	}
+	p.mu.Unlock()
+	if due {
+		return p.storeCheckpoint(pos)
+	}
+	return nil
+}
+
+// handle calls the handler for the type of the event, if there is one.
+func (p *Projector) handle(ctx context.Context, er *EventResponse) error {
+	if h, ok := p.handlers[er.Event.EventType]; ok {
+		return p.call(ctx, h, er)
+	}
+	return nil
+}
+
+// startWorkers starts the workers of a partitioned projector. The caller must
+// hold the lock.
+func (p *Projector) startWorkers() {
+	parts := &partitions{
+		queues:  make([]chan *queuedEvent, p.workers),
+		handled: make(map[int]bool),
+		failed:  make(chan struct{}),
+	}
+	parts.ctx, parts.cancel = context.WithCancel(context.Background())
+	for i := range parts.queues {
+		parts.queues[i] = make(chan *queuedEvent, partitionQueue)
+		parts.wg.Add(1)
+		go p.work(parts, parts.queues[i])
+	}
+	p.parts = parts
+}
+
+// dispatch queues the event for the worker of its partition. It returns the
+// error that stopped a worker, which stops the subscription.
+func (p *Projector) dispatch(ctx context.Context, er *EventResponse) error {
+	pos, _ := FeedPosition(ctx)
+	p.mu.Lock()
+	parts := p.parts
+	if parts.err != nil {
+		p.mu.Unlock()
+		return parts.err
+	}
+	parts.inflight = append(parts.inflight, pos)
+	p.mu.Unlock()
+
+	q := parts.queues[HashPartitioner{}.Partition(p.key(er), len(parts.queues))]
+	select {
+	case q <- &queuedEvent{er: er, pos: pos}:
+	case <-parts.ctx.Done():
+	}
+	return nil
+}
+
+// work handles the events queued for a worker. Once the projector is stopping
+// the remaining events are discarded.
+func (p *Projector) work(parts *partitions, q chan *queuedEvent) {
+	defer parts.wg.Done()
+	for qe := range q {
+		if parts.ctx.Err() != nil {
+			continue
+		}
+		er := qe.er
+		err := p.client.applyEventErrorPolicy(parts.ctx, p.onError, p.stream, er, func() error {
+			return p.handle(WithLineage(parts.ctx, LineageOf(er)), er)
+		})
+		if err == nil {
+			err = p.handled(parts, qe.pos)
+		}
+		if err != nil {
+			p.mu.Lock()
+			if parts.err == nil {
+				parts.err = err
+				close(parts.failed)
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// handled records that the event at position pos has been handled, advances
+// the last event before which every event has been handled and stores a
+// checkpoint every checkpoint interval.
+func (p *Projector) handled(parts *partitions, pos int) error {
+	p.mu.Lock()
+	parts.handled[pos] = true
+	for len(parts.inflight) > 0 && parts.handled[parts.inflight[0]] {
+		delete(parts.handled, parts.inflight[0])
+		p.last = parts.inflight[0]
+		parts.inflight = parts.inflight[1:]
+		p.pending++
+	}
+	due := p.pending >= p.every
+	if due {
+		p.pending = 0
+	}
+	last := p.last
+	p.mu.Unlock()
+	if due {
+		return p.storeCheckpoint(last)
+	}
+	return nil
+}
+
+// call decodes the event and calls the handler with it.
+func (p *Projector) call(ctx context.Context, h *projection, er *EventResponse) error {
+	v := reflect.New(h.typ)
+	if err := p.client.decodeEvent(er, v.Interface(), nil); err != nil {
+		return err
+	}
+	if !h.ptr {
+		v = v.Elem()
+	}
+
+	meta := EventMeta{
+		Stream:        er.Event.EventStreamID,
+		EventNumber:   er.Event.EventNumber,
+		EventID:       er.Event.EventID,
+		EventType:     er.Event.EventType,
+		CorrelationID: er.Event.CorrelationID,
+		CausationID:   er.Event.CausationID,
+	}
+	if t, err := er.Updated.Time(); err == nil {
+		meta.Updated = t
+	}
+	if raw, ok := er.Event.MetaData.(*json.RawMessage); ok && raw != nil {
+		meta.MetaData = *raw
+	}
+
+	out := h.fn.Call([]reflect.Value{reflect.ValueOf(ctx), v, reflect.ValueOf(meta)})
+	if err, _ := out[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ProjectorSuite{})
+
+type ProjectorSuite struct{}
+
+func (s *ProjectorSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *ProjectorSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+type OrderPlaced struct {
+	OrderID string `json:"orderId"`
+	Total   int    `json:"total"`
+}
+
+type OrderShipped struct {
+	OrderID string `json:"orderId"`
+}
+
+type OrderAudited struct{}
+
+// orderEvents serves a stream of orders placed and shipped, with an event of a
+// type that has no handler between them.
+func orderEvents(stream string) []*Event {
+	es := []*Event{
+		CreateTestEventFromData(stream, server.URL, 0, &OrderPlaced{OrderID: "o-1", Total: 10}, nil),
+		CreateTestEventFromData(stream, server.URL, 1, &OrderAudited{}, nil),
+		CreateTestEventFromData(stream, server.URL, 2, &OrderPlaced{OrderID: "o-2", Total: 5}, nil),
+		CreateTestEventFromData(stream, server.URL, 3, &OrderShipped{OrderID: "o-1"},
+			map[string]string{CorrelationIDMetaDataKey: "request-1"}),
+	}
+	setupSimulator(es, nil)
+	return es
+}
+
+// summary is a read model built by the projector tests.
+type summary struct {
+	mu      sync.Mutex
+	totals  map[string]int
+	shipped []string
+	metas   []EventMeta
+}
+
+func newSummary() *summary {
+	return &summary{totals: make(map[string]int)}
+}
+
+func (m *summary) placed(ctx context.Context, e OrderPlaced, meta EventMeta) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.totals[e.OrderID] += e.Total
+	m.metas = append(m.metas, meta)
+	return nil
+}
+
+func (m *summary) ship(ctx context.Context, e *OrderShipped, meta EventMeta) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shipped = append(m.shipped, e.OrderID)
+	m.metas = append(m.metas, meta)
+	return nil
+}
+
+func (s *ProjectorSuite) TestProjectsEventsByType(c *C) {
+	es := orderEvents("orders-p1")
+	store := NewMemoryCheckpointStore()
+	m := newSummary()
+
+	p := client.NewProjector("summary", "orders-p1", store)
+	p.On("", m.placed)
+	p.On("OrderShipped", m.ship)
+	c.Assert(p.Start(), IsNil)
+	eventually(func() bool { return p.LastProcessed() == 3 })
+	p.Stop()
+	c.Assert(p.Err(), IsNil)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c.Assert(m.totals, DeepEquals, map[string]int{"o-1": 10, "o-2": 5})
+	c.Assert(m.shipped, DeepEquals, []string{"o-1"})
+	c.Assert(m.metas, HasLen, 3)
+	c.Assert(m.metas[2].Stream, Equals, "orders-p1")
+	c.Assert(m.metas[2].EventNumber, Equals, 3)
+	c.Assert(m.metas[2].EventID, Equals, es[3].EventID)
+	c.Assert(m.metas[2].EventType, Equals, "OrderShipped")
+	c.Assert(m.metas[2].CorrelationID, Equals, "request-1")
+	c.Assert(m.metas[2].Updated.IsZero(), Equals, false)
+
+	cp, err := store.Load("summary")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 3)
+}
+
+func (s *ProjectorSuite) TestResumesFromCheckpoint(c *C) {
+	orderEvents("orders-p2")
+	store := NewMemoryCheckpointStore()
+	c.Assert(store.Store("summary", 1), IsNil)
+	m := newSummary()
+
+	p := client.NewProjector("summary", "orders-p2", store)
+	p.On("OrderPlaced", m.placed)
+	p.SetCheckpointInterval(10)
+	c.Assert(p.Start(), IsNil)
+	eventually(func() bool { return p.LastProcessed() == 3 })
+	p.Stop()
+
+	m.mu.Lock()
+	c.Assert(m.totals, DeepEquals, map[string]int{"o-2": 5})
+	m.mu.Unlock()
+
+	// The checkpoint is stored when the projector stops.
+	cp, err := store.Load("summary")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 3)
+}
+
+// categoryOrders serves the $ce-order stream linking to n orders placed in
+// each of the order streams in turn. The total of each order is its number in
+// its stream.
+func categoryOrders(n int, streams ...string) {
+	es := []*Event{}
+	for i := 0; i < n; i++ {
+		for _, stream := range streams {
+			es = append(es, CreateTestEventFromData(stream, server.URL, i, &OrderPlaced{OrderID: stream, Total: i}, nil))
+		}
+	}
+	serveLinkedStream("$ce-order", es)
+}
+
+func (s *ProjectorSuite) TestCheckpointsFeedPositionOfCategoryStream(c *C) {
+	categoryOrders(3, "order-1", "order-2")
+	store := NewMemoryCheckpointStore()
+	c.Assert(store.Store("summary", 1), IsNil)
+	m := newSummary()
+
+	p := client.NewProjector("summary", "$ce-order", store)
+	p.On("", m.placed)
+	c.Assert(p.Start(), IsNil)
+	eventually(func() bool { return p.LastProcessed() == 5 })
+	time.Sleep(20 * time.Millisecond)
+	p.Stop()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c.Assert(m.totals, DeepEquals, map[string]int{"order-1": 3, "order-2": 3})
+	c.Assert(m.metas, HasLen, 4)
+	cp, err := store.Load("summary")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 5)
+}
+
+func (s *ProjectorSuite) TestHandlerErrorStopsProjector(c *C) {
+	orderEvents("orders-p3")
+	store := NewMemoryCheckpointStore()
+	errFull := errors.New("read model full")
+
+	p := client.NewProjector("summary", "orders-p3", store)
+	p.On("OrderPlaced", func(ctx context.Context, e OrderPlaced, meta EventMeta) error {
+		if e.OrderID == "o-2" {
+			return errFull
+		}
+		return nil
+	})
+	c.Assert(p.Start(), IsNil)
+	<-p.Done()
+
+	c.Assert(p.Err(), Equals, errFull)
+	c.Assert(p.LastProcessed(), Equals, 1)
+	cp, err := store.Load("summary")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 1)
+
+	err = <-p.Errors()
+	c.Assert(IsFatal(err), Equals, true)
+	c.Assert(err, ErrorMatches, "Projector summary stopped: read model full")
+}
+
+func (s *ProjectorSuite) TestRetriesWithErrorPolicy(c *C) {
+	orderEvents("orders-p4")
+	attempts := 0
+
+	p := client.NewProjector("summary", "orders-p4", NewMemoryCheckpointStore())
+	p.On("OrderShipped", func(ctx context.Context, e OrderShipped, meta EventMeta) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("busy")
+		}
+		return nil
+	})
+	p.SetEventErrorPolicy(EventErrorPolicy{Action: EventErrorStop, Retries: 1})
+	c.Assert(p.Start(), IsNil)
+	eventually(func() bool { return p.LastProcessed() == 3 })
+	p.Stop()
+
+	c.Assert(p.Err(), IsNil)
+	c.Assert(attempts, Equals, 2)
+}
+
+func (s *ProjectorSuite) TestOnRejectsInvalidHandlers(c *C) {
+	p := client.NewProjector("summary", "orders-p5", NewMemoryCheckpointStore())
+	c.Assert(func() { p.On("OrderPlaced", func(e OrderPlaced) error { return nil }) },
+		PanicMatches, `goes: handler for OrderPlaced must be a func\(context.Context, T, goes.EventMeta\) error, not func\(goes.OrderPlaced\) error`)
+	c.Assert(func() { p.On("OrderPlaced", "not a func") }, PanicMatches, `goes: handler for OrderPlaced must be .*, not string`)
+}
+
+// placedEvents serves a stream of n orders placed for four orders in turn.
+func placedEvents(stream string, n int) {
+	es := make([]*Event, n)
+	for i := range es {
+		es[i] = CreateTestEventFromData(stream, server.URL, i,
+			&OrderPlaced{OrderID: fmt.Sprintf("o-%d", i%4), Total: i}, nil)
+	}
+	setupSimulator(es, nil)
+}
+
+// orderKey returns the order id of an OrderPlaced event.
+func orderKey(er *EventResponse) string {
+	e := OrderPlaced{}
+	json.Unmarshal(*er.Event.Data.(*json.RawMessage), &e)
+	return e.OrderID
+}
+
+func (s *ProjectorSuite) TestPartitionsPreserveOrderPerKey(c *C) {
+	placedEvents("orders-p6", 40)
+	store := NewMemoryCheckpointStore()
+	c.Assert(HashPartitioner{}.Partition("o-0", 4), Not(Equals), HashPartitioner{}.Partition("o-1", 4))
+
+	var mu sync.Mutex
+	seen := map[string][]int{}
+	secondStarted := make(chan struct{})
+	p := client.NewProjector("summary", "orders-p6", store)
+	p.On("", func(ctx context.Context, e OrderPlaced, meta EventMeta) error {
+		switch e.Total {
+		case 0:
+			// The first event waits for the event after it, which is only
+			// handled while it waits if the keys are handled in parallel.
+			select {
+			case <-secondStarted:
+			case <-time.After(5 * time.Second):
+				return errors.New("events were not handled in parallel")
+			}
+		case 1:
+			close(secondStarted)
+		}
+		mu.Lock()
+		seen[e.OrderID] = append(seen[e.OrderID], e.Total)
+		mu.Unlock()
+		return nil
+	})
+	p.SetPartitions(4, orderKey)
+	c.Assert(p.Start(), IsNil)
+	eventually(func() bool { return p.LastProcessed() == 39 })
+	p.Stop()
+	c.Assert(p.Err(), IsNil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(seen, HasLen, 4)
+	for key, totals := range seen {
+		c.Assert(totals, HasLen, 10)
+		c.Assert(sort.IntsAreSorted(totals), Equals, true, Commentf("%s: %v", key, totals))
+	}
+	cp, err := store.Load("summary")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 39)
+}
+
+func (s *ProjectorSuite) TestPartitionErrorStopsProjector(c *C) {
+	placedEvents("orders-p7", 12)
+	store := NewMemoryCheckpointStore()
+	errFull := errors.New("read model full")
+
+	p := client.NewProjector("summary", "orders-p7", store)
+	p.On("", func(ctx context.Context, e OrderPlaced, meta EventMeta) error {
+		if e.Total == 5 {
+			return errFull
+		}
+		return nil
+	})
+	p.SetPartitions(4, orderKey)
+	c.Assert(p.Start(), IsNil)
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		c.Fatal("projector did not stop")
+	}
+
+	c.Assert(p.Err(), Equals, errFull)
+	c.Assert(p.LastProcessed() < 5, Equals, true)
+	cp, err := store.Load("summary")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, p.LastProcessed())
+}
+
+func (s *ProjectorSuite) TestPartitionsOfCategoryStreamCheckpointFeedPosition(c *C) {
+	categoryOrders(3, "order-1", "order-2")
+	store := NewMemoryCheckpointStore()
+	c.Assert(HashPartitioner{}.Partition("order-1", 2), Not(Equals), HashPartitioner{}.Partition("order-2", 2))
+
+	// The first event of order-1 is held while the events of order-2, which
+	// have the same event numbers, are handled.
+	release := make(chan struct{})
+	var mu sync.Mutex
+	handled := 0
+	p := client.NewProjector("summary", "$ce-order", store)
+	p.On("", func(ctx context.Context, e OrderPlaced, meta EventMeta) error {
+		if meta.Stream == "order-1" && meta.EventNumber == 0 {
+			<-release
+		}
+		mu.Lock()
+		handled++
+		mu.Unlock()
+		return nil
+	})
+	p.SetPartitions(2, nil)
+	c.Assert(p.Start(), IsNil)
+
+	eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return handled == 3
+	})
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	c.Assert(handled, Equals, 3)
+	mu.Unlock()
+	c.Assert(p.LastProcessed(), Equals, -1)
+
+	close(release)
+	eventually(func() bool { return p.LastProcessed() == 5 })
+	p.Stop()
+	c.Assert(p.Err(), IsNil)
+	cp, err := store.Load("summary")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, 5)
+}
+
+func (s *ProjectorSuite) TestStopCancelsPartitionHandlers(c *C) {
+	placedEvents("orders-p10", 4)
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	p := client.NewProjector("summary", "orders-p10", NewMemoryCheckpointStore())
+	p.On("", func(ctx context.Context, e OrderPlaced, meta EventMeta) error {
+		if e.Total != 0 {
+			return nil
+		}
+		_, ok := LineageFromContext(ctx)
+		c.Check(ok, Equals, true)
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return ctx.Err()
+	})
+	p.SetPartitions(2, nil)
+	c.Assert(p.Start(), IsNil)
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(stopped)
+	}()
+	select {
+	case err := <-cancelled:
+		c.Assert(err, Equals, context.Canceled)
+	case <-time.After(5 * time.Second):
+		c.Fatal("handler was not cancelled")
+	}
+	<-stopped
+	c.Assert(p.LastProcessed(), Equals, -1)
+}
+
+func (s *ProjectorSuite) TestPartitionsApplyErrorPolicy(c *C) {
+	placedEvents("orders-p8", 8)
+	var mu sync.Mutex
+	attempts := 0
+
+	p := client.NewProjector("summary", "orders-p8", NewMemoryCheckpointStore())
+	p.On("", func(ctx context.Context, e OrderPlaced, meta EventMeta) error {
+		if e.Total != 3 {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		return errors.New("always fails")
+	})
+	p.SetEventErrorPolicy(EventErrorPolicy{Action: EventErrorSkip, Retries: 2})
+	p.SetPartitions(2, nil)
+	c.Assert(p.Start(), IsNil)
+	eventually(func() bool { return p.LastProcessed() == 7 })
+	p.Stop()
+
+	c.Assert(p.Err(), IsNil)
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(attempts, Equals, 3)
+}
+
+func (s *ProjectorSuite) TestShutdownHandlesQueuedEvents(c *C) {
+	placedEvents("orders-p9", 40)
+	store := NewMemoryCheckpointStore()
+	release := make(chan struct{})
+	var mu sync.Mutex
+	handled := map[int]bool{}
+
+	p := client.NewProjector("summary", "orders-p9", store)
+	p.On("", func(ctx context.Context, e OrderPlaced, meta EventMeta) error {
+		if e.Total == 0 {
+			<-release
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		handled[e.Total] = true
+		return nil
+	})
+	p.SetPartitions(2, orderKey)
+	c.Assert(p.Start(), IsNil)
+
+	// Events are queued for the workers while the first event is blocked.
+	queued := func() int {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.parts.inflight)
+	}
+	eventually(func() bool { return queued() > 5 })
+
+	shutdown := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- p.Shutdown(ctx)
+	}()
+	eventually(func() bool {
+		select {
+		case <-p.drain:
+			return true
+		default:
+			return false
+		}
+	})
+	close(release)
+	c.Assert(<-shutdown, IsNil)
+
+	last := p.LastProcessed()
+	c.Assert(last >= 5, Equals, true, Commentf("%d", last))
+	c.Assert(queued(), Equals, 0)
+	mu.Lock()
+	c.Assert(handled, HasLen, last+1)
+	mu.Unlock()
+	cp, err := store.Load("summary")
+	c.Assert(err, IsNil)
+	c.Assert(cp, Equals, last)
+}
@@ -0,0 +1,133 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MetadataChange is a change made to a metadata key by EnsureStream.
+//
+// Old is the previous value of the key, or nil if the key was not set. New is
+// the value the key was set to. Values are JSON values, so numbers are
+// float64 and roles are []interface{}.
+type MetadataChange struct {
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+// EnsureResult reports the outcome of EnsureStream.
+//
+// Status is the status of the stream before EnsureStream was called. Changes
+// are ordered by key and are empty if the metadata already matched. Metadata
+// is the metadata of the stream after the call.
+type EnsureResult struct {
+	Status   StreamStatus
+	Changes  []MetadataChange
+	Metadata *StreamMetadata
+}
+
+// Changed returns true if EnsureStream wrote the metadata of the stream.
+func (r *EnsureResult) Changed() bool {
+	return len(r.Changes) > 0
+}
+
+// EnsureStream makes the metadata of a stream match the metadata provided.
+//
+// The metadata is applied as a patch. Each key set in m, including the
+// reserved keys such as $maxAge, $maxCount and $acl and the keys in Custom,
+// replaces the current value of the key, and keys not set in m are left as
+// they are. The metadata is written only if a key changes, so EnsureStream can
+// be called every time an application starts to declare the streams it needs.
+//
+// A stream that does not exist is provisioned by writing its metadata, which
+// the eventstore applies when the stream is created. If the stream has been
+// hard deleted an *ErrDeleted is returned.
+func (c *Client) EnsureStream(stream string, m *StreamMetadata) (*EnsureResult, error) {
+	status, err := c.StreamStatus(stream)
+	if err != nil {
+		return nil, err
+	}
+	if status == StreamHardDeleted {
+		return nil, &ErrDeleted{}
+	}
+
+	mURL := fmt.Sprintf("/streams/%s/metadata", stream)
+	cur := &StreamMetadata{}
+	er, _, err := c.GetEvent(mURL)
+	switch err.(type) {
+	case nil:
+		if er != nil {
+			if err := c.decodeEvent(er, cur, nil); err != nil {
+				return nil, err
+			}
+		}
+	case *ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	have, err := jsonMap(cur)
+	if err != nil {
+		return nil, err
+	}
+	want, err := jsonMap(m)
+	if err != nil {
+		return nil, err
+	}
+	result := &EnsureResult{Status: status, Changes: []MetadataChange{}, Metadata: cur}
+
+	keys := make([]string, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if reflect.DeepEqual(have[k], want[k]) {
+			continue
+		}
+		result.Changes = append(result.Changes, MetadataChange{Key: k, Old: have[k], New: want[k]})
+		have[k] = want[k]
+	}
+	if !result.Changed() {
+		return result, nil
+	}
+
+	// The patched metadata is round tripped through JSON so that the result
+	// holds the typed metadata that was written.
+	b, err := json.Marshal(have)
+	if err != nil {
+		return nil, err
+	}
+	patched := &StreamMetadata{}
+	if err := json.Unmarshal(b, patched); err != nil {
+		return nil, err
+	}
+	if err := c.postMetaData(mURL, patched, ExpectAny); err != nil {
+		return nil, err
+	}
+	result.Metadata = patched
+	return result, nil
+}
+
+// jsonMap returns the metadata as a map of the JSON values it serializes to,
+// so that metadata read from the server and metadata built in Go compare
+// equal.
+func jsonMap(m *StreamMetadata) (map[string]interface{}, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]interface{})
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ProvisionSuite{})
+
+type ProvisionSuite struct{}
+
+func (s *ProvisionSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *ProvisionSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// metadataStore serves the metadata of a stream that has no events.
+type metadataStore struct {
+	mu     sync.Mutex
+	meta   map[string]interface{}
+	writes int
+}
+
+func serveMetadataStore(c *C, stream string) *metadataStore {
+	st := &metadataStore{}
+	mux.HandleFunc("/streams/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		if r.URL.Path != "/streams/"+stream+"/metadata" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPost {
+			if ev := r.Header.Get("ES-ExpectedVersion"); ev != "" && ev != strconv.Itoa(st.writes-1) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			st.meta = nil
+			decodeWrittenMetaData(c, r, &st.meta)
+			st.writes++
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if st.meta == nil {
+			http.NotFound(w, r)
+			return
+		}
+		e := CreateTestEventFromData("$$"+stream, server.URL, st.writes-1, &st.meta, nil)
+		er, err := CreateTestEventAtomResponse(e, nil)
+		c.Assert(err, IsNil)
+		w.Header().Set("ETag", fmt.Sprintf("\"%d;-2060438500\"", st.writes-1))
+		json.NewEncoder(w).Encode(er)
+	})
+	return st
+}
+
+func (st *metadataStore) state() (map[string]interface{}, int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.meta, st.writes
+}
+
+func (s *ProvisionSuite) TestEnsureStreamCreatesMetadata(c *C) {
+	st := serveMetadataStore(c, "provisioned")
+
+	want := &StreamMetadata{MaxCount: Int(10), ACL: &StreamACL{Read: Roles{"ops"}}}
+	result, err := client.EnsureStream("provisioned", want)
+	c.Assert(err, IsNil)
+	c.Assert(result.Status, Equals, StreamNotFound)
+	c.Assert(result.Changed(), Equals, true)
+	c.Assert(result.Changes, DeepEquals, []MetadataChange{
+		{Key: "$acl", New: map[string]interface{}{"$r": []interface{}{"ops"}}},
+		{Key: "$maxCount", New: 10.0},
+	})
+	c.Assert(result.Metadata, DeepEquals, want)
+
+	meta, writes := st.state()
+	c.Assert(writes, Equals, 1)
+	c.Assert(meta["$maxCount"], Equals, 10.0)
+}
+
+func (s *ProvisionSuite) TestEnsureStreamIsIdempotent(c *C) {
+	st := serveMetadataStore(c, "idempotent")
+
+	want := &StreamMetadata{MaxAge: Int(3600), Custom: map[string]interface{}{"owner": "billing", "tier": 2}}
+	_, err := client.EnsureStream("idempotent", want)
+	c.Assert(err, IsNil)
+
+	result, err := client.EnsureStream("idempotent", want)
+	c.Assert(err, IsNil)
+	c.Assert(result.Changed(), Equals, false)
+	c.Assert(result.Changes, HasLen, 0)
+
+	_, writes := st.state()
+	c.Assert(writes, Equals, 1)
+}
+
+func (s *ProvisionSuite) TestEnsureStreamPatchesMetadata(c *C) {
+	st := serveMetadataStore(c, "patched")
+
+	_, err := client.EnsureStream("patched", &StreamMetadata{MaxCount: Int(10), MaxAge: Int(60)})
+	c.Assert(err, IsNil)
+
+	result, err := client.EnsureStream("patched", &StreamMetadata{MaxAge: Int(120)})
+	c.Assert(err, IsNil)
+	c.Assert(result.Changes, DeepEquals, []MetadataChange{{Key: "$maxAge", Old: 60.0, New: 120.0}})
+	c.Assert(result.Metadata, DeepEquals, &StreamMetadata{MaxCount: Int(10), MaxAge: Int(120)})
+
+	meta, writes := st.state()
+	c.Assert(writes, Equals, 2)
+	c.Assert(meta, DeepEquals, map[string]interface{}{"$maxCount": 10.0, "$maxAge": 120.0})
+}
+
+func (s *ProvisionSuite) TestEnsureStreamHardDeleted(c *C) {
+	serveDeletedStream(c, "gone", http.StatusGone, nil)
+
+	_, err := client.EnsureStream("gone", &StreamMetadata{MaxCount: Int(1)})
+	c.Assert(err, FitsTypeOf, &ErrDeleted{})
+}
@@ -0,0 +1,156 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// RawEvent is an event with its data and metadata as bytes, for forwarding
+// events to other systems without decoding them.
+//
+// Data holds the data as it was written, in the format given by ContentType.
+// Events written as JSON have the content type application/json and their data
+// is the JSON served by the eventstore. Events written with another codec have
+// the content type of the codec and their data is the bytes produced by the
+// codec. MetaData and MetaDataContentType describe the metadata in the same
+// way. MetaData is nil if the event has no metadata.
+type RawEvent struct {
+	EventID             string
+	EventType           string
+	EventNumber         int
+	Stream              string
+	ContentType         string
+	Data                []byte
+	MetaDataContentType string
+	MetaData            []byte
+}
+
+// NewRawEvent returns the *RawEvent for the event in the EventResponse.
+//
+// The data and metadata are not decoded. The only processing done is to
+// extract the bytes of events written with a codec other than JSON from the
+// JSON document in which the eventstore serves them.
+func NewRawEvent(er *EventResponse) (*RawEvent, error) {
+	e := er.Event
+	ret := &RawEvent{
+		EventID:             e.EventID,
+		EventType:           e.EventType,
+		EventNumber:         e.EventNumber,
+		Stream:              e.EventStreamID,
+		ContentType:         "application/json",
+		MetaDataContentType: "application/json",
+	}
+
+	data, _ := e.Data.(*json.RawMessage)
+	meta, _ := e.MetaData.(*json.RawMessage)
+	if data != nil {
+		ret.Data = []byte(*data)
+	}
+	if meta == nil || len(*meta) == 0 {
+		return ret, nil
+	}
+	ret.MetaData = []byte(*meta)
+
+	env := codecEnvelope{}
+	if json.Unmarshal(*meta, &env) != nil || (env.ContentType == "" && env.MetaData == nil) {
+		return ret, nil
+	}
+
+	if env.ContentType != "" && data != nil {
+		var b []byte
+		if err := json.Unmarshal(*data, &b); err != nil {
+			return nil, err
+		}
+		ret.ContentType = env.ContentType
+		ret.Data = b
+	}
+
+	if env.MetaData != nil {
+		ret.MetaData = env.MetaData
+		ret.MetaDataContentType = env.MetaDataContentType
+		if ret.MetaDataContentType == "" {
+			ret.MetaDataContentType = env.ContentType
+		}
+		return ret, nil
+	}
+
+	// The metadata was written as JSON alongside the content type of the
+	// data, which is removed.
+	plain := map[string]json.RawMessage{}
+	if err := json.Unmarshal(*meta, &plain); err != nil {
+		return nil, err
+	}
+	delete(plain, ContentTypeMetaDataKey)
+	ret.MetaData = nil
+	if len(plain) > 0 {
+		b, err := json.Marshal(plain)
+		if err != nil {
+			return nil, err
+		}
+		ret.MetaData = b
+	}
+	return ret, nil
+}
+
+// RawEvent returns the event at the current position of the reader as a
+// *RawEvent, without decoding its data or metadata.
+func (s *StreamReader) RawEvent() (*RawEvent, error) {
+	if s.lasterr != nil {
+		return nil, s.lasterr
+	}
+	if s.eventResponse == nil {
+		return nil, &ErrNoMoreEvents{}
+	}
+	return NewRawEvent(s.eventResponse)
+}
+
+// EventBody is a representation of an event as served by the eventstore,
+// untouched. ContentType is the Content-Type of the response.
+type EventBody struct {
+	ContentType string
+	Body        []byte
+}
+
+// GetEventBody reads the event at url in the representation given by accept
+// and returns the body of the response without decoding it. For example
+// application/atom+xml returns the atom entry of the event and
+// application/vnd.eventstore.atom+json the document GetEvent decodes.
+//
+// If accept is empty the server chooses the representation.
+func (c *Client) GetEventBody(url, accept string) (*EventBody, *Response, error) {
+	return c.getEventBody(context.Background(), strings.TrimRight(url, "/"), accept)
+}
+
+// GetEventData reads the data of the event at url from the data endpoint of
+// the event, {url}/data, which serves the data as it was written rather than
+// embedded in a JSON document. This returns binary and custom encoded data
+// untouched. The content type of the data is the ContentType of the result.
+func (c *Client) GetEventData(url string) (*EventBody, *Response, error) {
+	return c.getEventBody(context.Background(), strings.TrimRight(url, "/")+"/data", "")
+}
+
+func (c *Client) getEventBody(ctx context.Context, url, accept string) (*EventBody, *Response, error) {
+	req, err := c.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	var b bytes.Buffer
+	resp, err := c.do(req, &b)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &EventBody{ContentType: resp.Header.Get("Content-Type"), Body: b.Bytes()}, resp, nil
+}
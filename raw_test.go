@@ -0,0 +1,133 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&RawSuite{})
+
+type RawSuite struct{}
+
+func (s *RawSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *RawSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *RawSuite) TestJSONEventIsPassedThrough(c *C) {
+	data := json.RawMessage(`{ "b": 2,  "a": 1 }`)
+	meta := json.RawMessage(`{"k":"v"}`)
+	er := &EventResponse{Event: &Event{
+		EventID:       "id-1",
+		EventType:     "Foo",
+		EventNumber:   3,
+		EventStreamID: "raw",
+		Data:          &data,
+		MetaData:      &meta,
+	}}
+
+	raw, err := NewRawEvent(er)
+	c.Assert(err, IsNil)
+	c.Assert(raw, DeepEquals, &RawEvent{
+		EventID:             "id-1",
+		EventType:           "Foo",
+		EventNumber:         3,
+		Stream:              "raw",
+		ContentType:         "application/json",
+		Data:                []byte(`{ "b": 2,  "a": 1 }`),
+		MetaDataContentType: "application/json",
+		MetaData:            []byte(`{"k":"v"}`),
+	})
+}
+
+func (s *RawSuite) TestEncodedDataIsPassedThrough(c *C) {
+	payload := []byte{0x00, 0xff, 0x10}
+	er := appendAndCaptureWithMetaData(c, RawCodec{}, JSONCodec{}, NewEvent("", "Binary", payload, map[string]interface{}{"k": "v"}))
+
+	raw, err := NewRawEvent(er)
+	c.Assert(err, IsNil)
+	c.Assert(raw.ContentType, Equals, "application/octet-stream")
+	c.Assert(raw.Data, DeepEquals, payload)
+	c.Assert(raw.MetaDataContentType, Equals, "application/json")
+	c.Assert(string(raw.MetaData), Equals, `{"k":"v"}`)
+}
+
+func (s *RawSuite) TestEncodedDataWithoutMetaData(c *C) {
+	er := appendAndCapture(c, RawCodec{}, NewEvent("", "Binary", []byte("x"), nil))
+
+	raw, err := NewRawEvent(er)
+	c.Assert(err, IsNil)
+	c.Assert(raw.Data, DeepEquals, []byte("x"))
+	c.Assert(raw.MetaData, IsNil)
+}
+
+func (s *RawSuite) TestEncodedMetaDataIsPassedThrough(c *C) {
+	er := appendAndCaptureWithMetaData(c, nil, upperCodec{}, NewEvent("", "Foo", &FooEvent{Foo: "x"}, "hello"))
+
+	raw, err := NewRawEvent(er)
+	c.Assert(err, IsNil)
+	c.Assert(raw.ContentType, Equals, "application/json")
+	c.Assert(string(raw.Data), Equals, `{"foo":"x"}`)
+	c.Assert(raw.MetaDataContentType, Equals, "text/x-upper")
+	c.Assert(string(raw.MetaData), Equals, "HELLO")
+}
+
+func (s *RawSuite) TestStreamReaderRawEvent(c *C) {
+	stream := "raw-reader"
+	es := CreateTestEvents(1, stream, server.URL, "Foo")
+	setupSimulator(es, nil)
+
+	reader := client.NewStreamReader(stream)
+	_, err := reader.RawEvent()
+	c.Assert(err, FitsTypeOf, &ErrNoMoreEvents{})
+
+	reader.Next()
+	c.Assert(reader.Err(), IsNil)
+	raw, err := reader.RawEvent()
+	c.Assert(err, IsNil)
+	c.Assert(raw.EventID, Equals, es[0].EventID)
+	got, want := map[string]interface{}{}, map[string]interface{}{}
+	c.Assert(json.Unmarshal(raw.Data, &got), IsNil)
+	c.Assert(json.Unmarshal(*es[0].Data.(*json.RawMessage), &want), IsNil)
+	c.Assert(got, DeepEquals, want)
+}
+
+func (s *RawSuite) TestGetEventDataReturnsDataUntouched(c *C) {
+	payload := []byte{0x00, 0xff, 0x10, 0x7b}
+	mux.HandleFunc("/streams/binary/0/data", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(payload)
+	})
+
+	got, resp, err := client.GetEventData(server.URL + "/streams/binary/0/")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(got.ContentType, Equals, "application/octet-stream")
+	c.Assert(got.Body, DeepEquals, payload)
+}
+
+func (s *RawSuite) TestGetEventBodyRequestsTheRepresentation(c *C) {
+	mux.HandleFunc("/streams/entry/3", func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("Accept"), Equals, "application/atom+xml")
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		fmt.Fprint(w, "<entry><title>3@entry</title></entry>")
+	})
+
+	got, _, err := client.GetEventBody("/streams/entry/3", "application/atom+xml")
+	c.Assert(err, IsNil)
+	c.Assert(got.ContentType, Equals, "application/atom+xml; charset=utf-8")
+	c.Assert(string(got.Body), Equals, "<entry><title>3@entry</title></entry>")
+
+	_, _, err = client.GetEventData("/streams/entry/4")
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+}
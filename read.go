@@ -0,0 +1,78 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+// MaxPageSize is the largest number of events the eventstore returns in a
+// feed page.
+const MaxPageSize = 4096
+
+// ReadOption configures a read made with a StreamReader, ReadFeedForward or
+// ReadFeedBackward.
+type ReadOption func(*readOptions)
+
+// readOptions holds the configuration of a read.
+type readOptions struct {
+	pageSize   int
+	eventTypes eventTypes
+	headers    map[string]string
+}
+
+func newReadOptions(opts []ReadOption) *readOptions {
+	o := &readOptions{pageSize: defaultPageSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithPageSize sets the number of events requested in each feed page.
+//
+// Larger pages need fewer requests to read a stream, smaller pages return
+// sooner and use less memory. The page size must be between 1 and MaxPageSize;
+// reads made with another page size fail. The default is 20.
+func WithPageSize(n int) ReadOption {
+	return func(o *readOptions) {
+		o.pageSize = n
+	}
+}
+
+// ReadFeedForward reads the feed page of the stream that begins at the event
+// number from and continues towards the head of the stream.
+//
+// The entries of the page are ordered most recent first, as they are in all
+// feed pages. Use FollowPrevious to read the next page forward.
+//
+// If the page size is larger than the limit set with SetMaxBufferedEvents an
+// *ErrTooManyEvents is returned without reading the page.
+func (c *Client) ReadFeedForward(stream string, from int, opts ...ReadOption) (*Feed, *Response, error) {
+	if from < 0 {
+		from = 0
+	}
+	return c.readFeedPage(stream, "forward", from, opts)
+}
+
+// ReadFeedBackward reads the feed page of the stream that ends at the event
+// number from, or at the head of the stream if from is negative.
+//
+// Use FollowNext to read the next page backward. The page size is limited in
+// the same way as for ReadFeedForward.
+func (c *Client) ReadFeedBackward(stream string, from int, opts ...ReadOption) (*Feed, *Response, error) {
+	return c.readFeedPage(stream, "backward", from, opts)
+}
+
+func (c *Client) readFeedPage(stream, direction string, from int, opts []ReadOption) (*Feed, *Response, error) {
+	o := newReadOptions(opts)
+	// A page holds up to pageSize events in memory, so a page that is larger
+	// than the limit set with SetMaxBufferedEvents is not read.
+	if err := c.checkBuffered(o.pageSize-1, stream, from); err != nil {
+		return nil, nil, err
+	}
+	url, err := c.GetFeedPath(stream, direction, from, o.pageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.readFeed(o.context(), url)
+}
@@ -0,0 +1,115 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ReadSuite{})
+
+type ReadSuite struct{}
+
+func (s *ReadSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *ReadSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// serveRecordingPaths serves the events and returns a function that returns
+// the paths of the feed pages requested.
+func serveRecordingPaths(es []*Event) func() []string {
+	var mu sync.Mutex
+	paths := []string{}
+	sim := newTestSimulator(es, nil)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/forward/") || strings.Contains(r.URL.Path, "/backward/") {
+			mu.Lock()
+			paths = append(paths, r.URL.Path)
+			mu.Unlock()
+		}
+		sim.ServeHTTP(w, r)
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func (s *ReadSuite) TestStreamReaderWithPageSize(c *C) {
+	stream := "paged"
+	es := CreateTestEvents(12, stream, server.URL, "Foo")
+	paths := serveRecordingPaths(es)
+
+	reader := client.NewStreamReader(stream, WithPageSize(5))
+	count := 0
+	for reader.Next() {
+		if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+			break
+		}
+		c.Assert(reader.Err(), IsNil)
+		count++
+	}
+	c.Assert(count, Equals, 12)
+
+	got := paths()
+	c.Assert(len(got) > 0, Equals, true)
+	for _, p := range got {
+		c.Assert(strings.HasSuffix(p, "/5"), Equals, true, Commentf("path %s", p))
+	}
+}
+
+func (s *ReadSuite) TestReadFeedForward(c *C) {
+	stream := "paged-forward"
+	es := CreateTestEvents(10, stream, server.URL, "Foo")
+	paths := serveRecordingPaths(es)
+
+	f, _, err := client.ReadFeedForward(stream, 2, WithPageSize(3))
+	c.Assert(err, IsNil)
+	c.Assert(f.Entry, HasLen, 3)
+	c.Assert(paths(), DeepEquals, []string{"/streams/paged-forward/2/forward/3"})
+}
+
+func (s *ReadSuite) TestReadFeedBackwardFromHead(c *C) {
+	stream := "paged-backward"
+	es := CreateTestEvents(10, stream, server.URL, "Foo")
+	paths := serveRecordingPaths(es)
+
+	f, _, err := client.ReadFeedBackward(stream, -1, WithPageSize(4))
+	c.Assert(err, IsNil)
+	c.Assert(f.Entry, HasLen, 4)
+	c.Assert(paths(), DeepEquals, []string{"/streams/paged-backward/head/backward/4"})
+}
+
+func (s *ReadSuite) TestReadFeedUsesDefaultPageSize(c *C) {
+	stream := "paged-default"
+	es := CreateTestEvents(3, stream, server.URL, "Foo")
+	paths := serveRecordingPaths(es)
+
+	_, _, err := client.ReadFeedForward(stream, 0)
+	c.Assert(err, IsNil)
+	c.Assert(paths(), DeepEquals, []string{"/streams/paged-default/0/forward/20"})
+}
+
+func (s *ReadSuite) TestInvalidPageSize(c *C) {
+	for _, n := range []int{0, -1, MaxPageSize + 1} {
+		_, _, err := client.ReadFeedForward("any", 0, WithPageSize(n))
+		c.Assert(err, NotNil)
+
+		reader := client.NewStreamReader("any", WithPageSize(n))
+		reader.Next()
+		c.Assert(reader.Err(), NotNil)
+	}
+
+	_, err := client.GetFeedPath("any", "forward", 0, MaxPageSize)
+	c.Assert(err, IsNil)
+}
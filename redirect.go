@@ -0,0 +1,84 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// maxHTTPRedirects is the number of times a request is redirected before the
+// redirect is returned to the caller.
+const maxHTTPRedirects = 3
+
+// isRedirect returns true if the response redirects the request to a location
+// the client follows.
+//
+// Writes are only followed when redirected with 307 Temporary Redirect or 308
+// Permanent Redirect, which keep the method and body of the request, as the
+// eventstore does when it redirects a request to the master. Reads are followed
+// for any redirect.
+func isRedirect(req *http.Request, resp *http.Response) bool {
+	if resp.Header.Get("Location") == "" {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther:
+		return !isWrite(req.Method)
+	}
+	return false
+}
+
+// followRedirects sends a request that has been redirected again to the
+// location of the redirect, with the body provided, and returns the response
+// from the node that handled the request.
+//
+// The http client drops the Authorization header when a request is redirected
+// to another host, which a node of a cluster does when it redirects a request
+// to the master. Requests are sent again with all of their headers, including
+// the credentials and ES-* headers of the client, up to maxHTTPRedirects
+// times. A redirect from https to http is not followed, so that the credentials
+// are not sent in the clear, and is returned like any other redirect that is
+// not followed.
+//
+// A write that is redirected to the master makes it the preferred node for
+// writes if the client prefers the master.
+func (c *Client) followRedirects(req *http.Request, resp *http.Response, body []byte) (*http.Response, error) {
+	hops := 0
+	for ; isRedirect(req, resp) && hops < maxHTTPRedirects; hops++ {
+		loc, err := resp.Location()
+		if err != nil {
+			return resp, nil
+		}
+		if req.URL.Scheme == "https" && loc.Scheme != "https" {
+			return resp, nil
+		}
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrain))
+		resp.Body.Close()
+
+		req.URL = loc
+		req.Host = loc.Host
+		if body != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		resp, err = c.roundTrip(req)
+		if err != nil {
+			if isWrite(req.Method) {
+				c.forgetMaster(req.URL)
+			}
+			return nil, err
+		}
+	}
+
+	if hops > 0 && isWrite(req.Method) && resp.StatusCode < 300 {
+		c.noteMaster(req.URL)
+	}
+	return resp, nil
+}
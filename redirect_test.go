@@ -0,0 +1,112 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&RedirectSuite{})
+
+type RedirectSuite struct{}
+
+func (s *RedirectSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *RedirectSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// otherHost returns the url of the test server with a host name other than
+// that of server, so that the http client treats it as another host.
+func otherHost(c *C, ts *httptest.Server) string {
+	u, err := url.Parse(ts.URL)
+	c.Assert(err, IsNil)
+	return "http://localhost:" + u.Port()
+}
+
+func (s *RedirectSuite) TestRedirectToAnotherHostKeepsCredentialsAndHeaders(c *C) {
+	stream := "redirect-1"
+	e := CreateTestEvent(stream, server.URL, "Foo", 0, nil, nil)
+	seen := []string{}
+	master := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		seen = append(seen, r.Method+" "+user+":"+pass+" "+r.Header.Get("ES-ResolveLinkTos"))
+		if r.Method == http.MethodPost {
+			body, _ := ioutil.ReadAll(r.Body)
+			c.Check(strings.Contains(string(body), e.EventID), Equals, true)
+			w.Header().Set("Location", server.URL+"/streams/"+stream+"/0")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		er, err := CreateTestEventAtomResponse(e, nil)
+		c.Check(err, IsNil)
+		json.NewEncoder(w).Encode(er)
+	}))
+	defer master.Close()
+	target := otherHost(c, master)
+
+	mux.HandleFunc("/streams/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	})
+	client.SetBasicAuth("admin", "changeit")
+	client.SetHeader("ES-ResolveLinkTos", "false")
+
+	er, _, err := client.GetEvent("/streams/" + stream + "/0")
+	c.Assert(err, IsNil)
+	c.Assert(er.Event.EventID, Equals, e.EventID)
+	c.Assert(client.NewStreamWriter(stream).Append(ExpectAny, e), IsNil)
+
+	c.Assert(seen, DeepEquals, []string{
+		"GET admin:changeit false",
+		"POST admin:changeit false",
+	})
+}
+
+func (s *RedirectSuite) TestRedirectsAreLimited(c *C) {
+	requests := 0
+	mux.HandleFunc("/streams/redirect-2", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Redirect(w, r, server.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	})
+
+	err := client.NewStreamWriter("redirect-2").Append(ExpectAny, CreateTestEvents(1, "redirect-2", server.URL, "Foo")...)
+	c.Assert(err, FitsTypeOf, &ErrUnexpected{})
+	c.Assert(err.(*ErrUnexpected).ErrorResponse.StatusCode, Equals, http.StatusTemporaryRedirect)
+	c.Assert(requests, Equals, maxHTTPRedirects+1)
+}
+
+func (s *RedirectSuite) TestWritesAreNotRedirectedToReads(c *C) {
+	mux.HandleFunc("/streams/redirect-3", func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, http.MethodPost)
+		http.Redirect(w, r, server.URL+"/streams/redirect-3/0", http.StatusSeeOther)
+	})
+
+	err := client.NewStreamWriter("redirect-3").Append(ExpectAny, CreateTestEvents(1, "redirect-3", server.URL, "Foo")...)
+	c.Assert(err, FitsTypeOf, &ErrUnexpected{})
+}
+
+func (s *RedirectSuite) TestRedirectFromHTTPSToHTTPIsNotFollowed(c *C) {
+	req, err := http.NewRequest("GET", "https://node-1:2113/streams/foo/0", nil)
+	c.Assert(err, IsNil)
+	resp := &http.Response{
+		StatusCode: http.StatusTemporaryRedirect,
+		Header:     http.Header{"Location": {"http://node-2:2113/streams/foo/0"}},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}
+
+	got, err := client.followRedirects(req, resp, nil)
+	c.Assert(err, IsNil)
+	c.Assert(got, Equals, resp)
+	c.Assert(req.URL.Host, Equals, "node-1:2113")
+}
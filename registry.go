@@ -0,0 +1,150 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"reflect"
+	"sync"
+)
+
+// decodePlan holds what is needed to create a value for an event type without
+// inspecting the registered type again.
+type decodePlan struct {
+	typ     reflect.Type
+	factory func() interface{}
+}
+
+// TypeRegistry maps event types to the Go types their data is decoded into.
+//
+// The decoding plan for each event type is computed once when the type is
+// registered, so decoding events of registered types does not repeat the
+// reflection needed to find and construct the target type.
+//
+// A TypeRegistry is safe for concurrent use.
+type TypeRegistry struct {
+	mu          sync.RWMutex
+	plans       map[string]*decodePlan
+	versions    map[string]int
+	minVersions map[string]int
+	upcasters   map[upcasterKey]Upcaster
+}
+
+// NewTypeRegistry returns a new *TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		plans:       make(map[string]*decodePlan),
+		versions:    make(map[string]int),
+		minVersions: make(map[string]int),
+		upcasters:   make(map[upcasterKey]Upcaster),
+	}
+}
+
+// Register registers the type of prototype for the event type. prototype may be
+// a value or a pointer; events are always decoded into a pointer to a new value
+// of the type.
+//
+// If eventType is empty the name of the type is used, matching the event type
+// assigned by NewEvent.
+func (r *TypeRegistry) Register(eventType string, prototype interface{}) {
+	t := reflect.TypeOf(prototype)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if eventType == "" {
+		eventType = t.Name()
+	}
+	r.set(eventType, &decodePlan{
+		typ:     t,
+		factory: func() interface{} { return reflect.New(t).Interface() },
+	})
+}
+
+// RegisterFactory registers a function that returns a new pointer to decode
+// events of the event type into. Using a factory avoids reflection entirely.
+func (r *TypeRegistry) RegisterFactory(eventType string, factory func() interface{}) {
+	r.set(eventType, &decodePlan{
+		typ:     reflect.TypeOf(factory()),
+		factory: factory,
+	})
+}
+
+func (r *TypeRegistry) set(eventType string, p *decodePlan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.plans[eventType] = p
+}
+
+// New returns a pointer to a new value of the type registered for the event
+// type. The second value returned is false if no type is registered.
+func (r *TypeRegistry) New(eventType string) (interface{}, bool) {
+	r.mu.RLock()
+	p, ok := r.plans[eventType]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return p.factory(), true
+}
+
+// Type returns the type registered for the event type.
+func (r *TypeRegistry) Type(eventType string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.plans[eventType]
+	if !ok {
+		return nil, false
+	}
+	return p.typ, true
+}
+
+// SetTypeRegistry sets the registry used by StreamReader.ScanEvent.
+func (c *Client) SetTypeRegistry(r *TypeRegistry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registry = r
+}
+
+// DecodeEvent decodes the data of the event in the EventResponse into a new
+// value of the type registered for its event type in the client's registry.
+//
+// If no type is registered an *ErrUnregisteredType is returned. Events with a
+// schema version below the minimum version set in the registry are upcast
+// before they are decoded, or an *ErrSchemaVersion is returned if they cannot
+// be. See TypeRegistry.SetMinSchemaVersion.
+func (c *Client) DecodeEvent(er *EventResponse) (interface{}, error) {
+	c.mu.RLock()
+	r := c.registry
+	c.mu.RUnlock()
+
+	if r == nil {
+		return nil, &ErrUnregisteredType{EventType: er.Event.EventType}
+	}
+	er, err := c.upcast(er)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := r.New(er.Event.EventType)
+	if !ok {
+		return nil, &ErrUnregisteredType{EventType: er.Event.EventType}
+	}
+	if err := c.decodeEvent(er, v, nil); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ScanEvent returns the data of the current event decoded into a new value of
+// the type registered for its event type. The client must have a TypeRegistry
+// set with SetTypeRegistry.
+func (s *StreamReader) ScanEvent() (interface{}, error) {
+	if s.lasterr != nil {
+		return nil, s.lasterr
+	}
+	if s.eventResponse == nil {
+		return nil, &ErrNoMoreEvents{}
+	}
+	return s.client.DecodeEvent(s.eventResponse)
+}
@@ -0,0 +1,153 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&RegistrySuite{})
+
+type RegistrySuite struct{}
+
+func (s *RegistrySuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *RegistrySuite) TearDownTest(c *C) {
+	teardown()
+}
+
+type BarEvent struct {
+	Bar int `json:"bar"`
+}
+
+func rawEventResponse(eventType string, data interface{}) *EventResponse {
+	b, _ := json.Marshal(data)
+	raw := json.RawMessage(b)
+	return &EventResponse{Event: &Event{EventType: eventType, Data: &raw}}
+}
+
+func (s *RegistrySuite) TestRegisterAndNew(c *C) {
+	r := NewTypeRegistry()
+	r.Register("", FooEvent{})
+	r.Register("Bar", &BarEvent{})
+	r.RegisterFactory("Baz", func() interface{} { return &map[string]int{} })
+
+	v, ok := r.New("FooEvent")
+	c.Assert(ok, Equals, true)
+	c.Assert(v, FitsTypeOf, &FooEvent{})
+
+	v, ok = r.New("Bar")
+	c.Assert(ok, Equals, true)
+	c.Assert(v, FitsTypeOf, &BarEvent{})
+
+	t, ok := r.Type("Baz")
+	c.Assert(ok, Equals, true)
+	c.Assert(t, Equals, reflect.TypeOf(&map[string]int{}))
+
+	_, ok = r.New("Unknown")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *RegistrySuite) TestDecodeEventUsesRegisteredType(c *C) {
+	r := NewTypeRegistry()
+	r.Register("", &FooEvent{})
+	client.SetTypeRegistry(r)
+
+	v, err := client.DecodeEvent(rawEventResponse("FooEvent", &FooEvent{Foo: "x"}))
+	c.Assert(err, IsNil)
+	c.Assert(v, DeepEquals, &FooEvent{Foo: "x"})
+
+	_, err = client.DecodeEvent(rawEventResponse("BarEvent", &BarEvent{Bar: 1}))
+	c.Assert(err, DeepEquals, &ErrUnregisteredType{EventType: "BarEvent"})
+}
+
+func (s *RegistrySuite) TestScanEvent(c *C) {
+	es := CreateTestEvents(2, "registered", server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	r := NewTypeRegistry()
+	r.Register("", &FooEvent{})
+	client.SetTypeRegistry(r)
+
+	reader := client.NewStreamReader("registered")
+	c.Assert(reader.Next(), Equals, true)
+	c.Assert(reader.Err(), IsNil)
+
+	v, err := reader.ScanEvent()
+	c.Assert(err, IsNil)
+	c.Assert(v, FitsTypeOf, &FooEvent{})
+	c.Assert(v.(*FooEvent).Foo, Not(Equals), "")
+}
+
+// mixedResponses returns event responses alternating between two event types.
+func mixedResponses(n int) []*EventResponse {
+	ret := make([]*EventResponse, n)
+	for i := range ret {
+		if i%2 == 0 {
+			ret[i] = rawEventResponse("FooEvent", &FooEvent{Foo: "foo"})
+		} else {
+			ret[i] = rawEventResponse("BarEvent", &BarEvent{Bar: i})
+		}
+	}
+	return ret
+}
+
+// BenchmarkNewWithoutPlan constructs values by inspecting the registered
+// prototype on every event, which is what the registry avoids.
+func BenchmarkNewWithoutPlan(b *testing.B) {
+	prototypes := map[string]interface{}{"FooEvent": &FooEvent{}, "BarEvent": &BarEvent{}}
+	types := []string{"FooEvent", "BarEvent"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		t := reflect.TypeOf(prototypes[types[i%2]])
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		_ = reflect.New(t).Interface()
+	}
+}
+
+func BenchmarkTypeRegistryNew(b *testing.B) {
+	r := NewTypeRegistry()
+	r.Register("", &FooEvent{})
+	r.Register("", &BarEvent{})
+	types := []string{"FooEvent", "BarEvent"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.New(types[i%2])
+	}
+}
+
+func BenchmarkTypeRegistryNewFactory(b *testing.B) {
+	r := NewTypeRegistry()
+	r.RegisterFactory("FooEvent", func() interface{} { return &FooEvent{} })
+	r.RegisterFactory("BarEvent", func() interface{} { return &BarEvent{} })
+	types := []string{"FooEvent", "BarEvent"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.New(types[i%2])
+	}
+}
+
+func BenchmarkDecodeEventMixedTypes(b *testing.B) {
+	r := NewTypeRegistry()
+	r.Register("", &FooEvent{})
+	r.Register("", &BarEvent{})
+	c := &Client{registry: r}
+	responses := mixedResponses(64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.DecodeEvent(responses[i%len(responses)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RedirectMetaDataKey is the stream metadata key used to record that a stream
+// has been renamed. The value of the key is the name of the new stream.
+const RedirectMetaDataKey = "goesRedirectTo"
+
+// maxRedirects is the maximum number of redirects that will be followed when
+// resolving a stream name.
+const maxRedirects = 10
+
+// RenameStream copies the events in the stream oldName to the stream newName
+// and marks the old stream as redirecting to the new stream.
+//
+// Event ids, event types, data and metadata are preserved, while the events are
+// numbered from 0 in the new stream. The new stream must not exist. The copy is
+// optimistic: if events are appended to the old stream while it is being copied
+// an *ErrConcurrencyViolation is returned and the old stream is not marked as
+// redirecting.
+//
+// The redirect is recorded in the stream metadata of the old stream under the
+// RedirectMetaDataKey. Any existing metadata on the old stream is retained.
+// Readers configured with FollowRedirects() will read from the new stream when
+// opened with the old name.
+func (c *Client) RenameStream(oldName, newName string) error {
+	if oldName == newName {
+		return fmt.Errorf("Cannot rename stream %s to itself", oldName)
+	}
+
+	reader := c.NewStreamReader(oldName)
+	writer := c.NewStreamWriter(newName)
+
+	written, head := 0, -1
+	batch := []*Event{}
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := writer.Append(ExpectedVersion(written-1), batch...); err != nil {
+			return err
+		}
+		written += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for reader.Next() {
+		if reader.Err() != nil {
+			if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+				break
+			}
+			return reader.Err()
+		}
+
+		head = reader.EventResponse().Event.EventNumber
+		batch = append(batch, copyEvent(reader.EventResponse().Event))
+		if len(batch) >= reader.pageSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	last, err := c.ReadLast(oldName, nil)
+	if err != nil {
+		if _, ok := err.(*ErrNoMoreEvents); !ok {
+			return err
+		}
+	}
+	if last != nil && last.Event.EventNumber != head {
+		return &ErrConcurrencyViolation{}
+	}
+
+	meta, err := c.NewStreamReader(oldName).MetaData()
+	if err != nil {
+		return err
+	}
+
+	m := make(map[string]interface{})
+	if meta != nil {
+		if err := c.decodeEvent(meta, &m, nil); err != nil {
+			return err
+		}
+	}
+	m[RedirectMetaDataKey] = newName
+
+	return c.NewStreamWriter(oldName).WriteMetaData(oldName, m)
+}
+
+// ResolveStream returns the name of the stream that should be read in place
+// of the stream provided.
+//
+// If the stream has been renamed using RenameStream the name of the new stream
+// is returned. Chains of renames are followed. If the stream has not been renamed
+// the name provided is returned.
+func (c *Client) ResolveStream(stream string) (string, error) {
+	name := stream
+	for i := 0; i < maxRedirects; i++ {
+		meta, err := c.NewStreamReader(name).MetaData()
+		if err != nil {
+			return "", err
+		}
+		if meta == nil {
+			return name, nil
+		}
+
+		m := make(map[string]interface{})
+		if err := c.decodeEvent(meta, &m, nil); err != nil {
+			return "", err
+		}
+
+		to, ok := m[RedirectMetaDataKey].(string)
+		if !ok || to == "" {
+			return name, nil
+		}
+		name = to
+	}
+	return "", fmt.Errorf("Too many redirects resolving stream %s", stream)
+}
+
+// FollowRedirects causes the reader to read from the stream that the reader's
+// stream has been renamed to, if any.
+//
+// The stream name is resolved using the stream metadata on the first call to
+// Next().
+func (s *StreamReader) FollowRedirects() {
+	s.followRedirects = true
+}
+
+// copyEvent returns a new event containing the id, type, data and metadata of
+// the event provided, ready to be written to another stream.
+func copyEvent(e *Event) *Event {
+	meta := e.MetaData
+	if raw, ok := meta.(*json.RawMessage); ok && (raw == nil || len(*raw) == 0) {
+		meta = nil
+	}
+	return NewEvent(e.EventID, e.EventType, e.Data, meta)
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&RenameSuite{})
+
+type RenameSuite struct{}
+
+func (s *RenameSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *RenameSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *RenameSuite) TestRenameStreamCopiesEventsAndWritesRedirect(c *C) {
+	oldName := "old-stream"
+	newName := "new-stream"
+	es := CreateTestEvents(25, oldName, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	copied := []Event{}
+	expectedVersions := []string{}
+	mux.HandleFunc("/streams/"+newName, func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		expectedVersions = append(expectedVersions, r.Header.Get("ES-ExpectedVersion"))
+		batch := []Event{}
+		err := json.NewDecoder(r.Body).Decode(&batch)
+		c.Assert(err, IsNil)
+		copied = append(copied, batch...)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var meta map[string]interface{}
+	mux.HandleFunc("/streams/"+oldName+"/metadata", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, "{}")
+			return
+		}
+		decodeWrittenMetaData(c, r, &meta)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := client.RenameStream(oldName, newName)
+	c.Assert(err, IsNil)
+
+	c.Assert(expectedVersions, DeepEquals, []string{"-1", "19"})
+	c.Assert(copied, HasLen, len(es))
+	for i, e := range copied {
+		c.Assert(e.EventID, Equals, es[i].EventID)
+		c.Assert(e.EventType, Equals, es[i].EventType)
+	}
+	c.Assert(meta[RedirectMetaDataKey], Equals, newName)
+}
+
+func (s *RenameSuite) TestRenameTruncatedStream(c *C) {
+	oldName := "truncated-stream"
+	newName := "new-stream"
+	es := []*Event{}
+	for i := 5; i < 10; i++ {
+		es = append(es, CreateTestEvent(oldName, server.URL, "FooEvent", i, nil, nil))
+	}
+	setupSimulator(es, nil)
+
+	copied := 0
+	mux.HandleFunc("/streams/"+newName, func(w http.ResponseWriter, r *http.Request) {
+		batch := []Event{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&batch), IsNil)
+		copied += len(batch)
+		w.WriteHeader(http.StatusCreated)
+	})
+	var meta map[string]interface{}
+	mux.HandleFunc("/streams/"+oldName+"/metadata", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, "{}")
+			return
+		}
+		decodeWrittenMetaData(c, r, &meta)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	c.Assert(client.RenameStream(oldName, newName), IsNil)
+	c.Assert(copied, Equals, 5)
+	c.Assert(meta[RedirectMetaDataKey], Equals, newName)
+}
+
+func (s *RenameSuite) TestRenameStreamToItselfReturnsError(c *C) {
+	err := client.RenameStream("a-stream", "a-stream")
+	c.Assert(err, NotNil)
+}
+
+func (s *RenameSuite) TestReaderFollowsRedirects(c *C) {
+	oldName := "orig-stream"
+	newName := "renamed-stream"
+	es := CreateTestEvents(3, newName, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	raw := json.RawMessage(fmt.Sprintf("{\"%s\":\"%s\"}", RedirectMetaDataKey, newName))
+	m := CreateTestEvent(oldName, server.URL, "metadata", 0, &raw, nil)
+	mux.HandleFunc("/streams/"+oldName+"/metadata", func(w http.ResponseWriter, r *http.Request) {
+		er, _ := CreateTestEventAtomResponse(m, nil)
+		fmt.Fprint(w, er.PrettyPrint())
+	})
+
+	name, err := client.ResolveStream(oldName)
+	c.Assert(err, IsNil)
+	c.Assert(name, Equals, newName)
+
+	reader := client.NewStreamReader(oldName)
+	reader.FollowRedirects()
+	reader.Next()
+	c.Assert(reader.Err(), IsNil)
+	c.Assert(reader.CurrentURL(), Equals, "/streams/renamed-stream/0/forward/20")
+	c.Assert(reader.EventResponse().Event.EventStreamID, Equals, newName)
+}
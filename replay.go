@@ -0,0 +1,253 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"errors"
+	"sync"
+)
+
+// ReplayPhase is the phase of a PriorityReplay.
+type ReplayPhase int
+
+const (
+	// ReplayRecent is the phase in which the most recent events are delivered.
+	ReplayRecent ReplayPhase = iota
+
+	// ReplayBackfill is the phase in which the older events are delivered in
+	// the background while new events are delivered as they are written. The
+	// read model is approximately current but incomplete.
+	ReplayBackfill
+
+	// ReplayComplete is the phase in which all of the history of the stream has
+	// been delivered and new events are delivered as they are written.
+	ReplayComplete
+)
+
+func (p ReplayPhase) String() string {
+	switch p {
+	case ReplayRecent:
+		return "recent"
+	case ReplayBackfill:
+		return "backfill"
+	case ReplayComplete:
+		return "complete"
+	}
+	return "unknown"
+}
+
+// errReplayStopped stops the iteration of a stream when the replay is stopped.
+var errReplayStopped = errors.New("Replay stopped")
+
+// PriorityReplay rebuilds a read model from a stream by delivering the most
+// recent events first, so that the read model is usable quickly, and then
+// backfilling the older history.
+//
+// The replay begins with the most recent events in the stream, up to the number
+// provided to NewPriorityReplay. They are read newest first from the head of the
+// stream and delivered to the handler in stream order. The replay then moves to
+// the ReplayBackfill phase, in which events written after the replay started
+// are delivered as they are written, as by a catch-up subscription, and the
+// events older than the recent events are delivered in stream order in the
+// background. When the backfill is done the replay moves to the ReplayComplete
+// phase and continues to deliver new events.
+//
+// Calls to the handler are never concurrent, but during the backfill calls for
+// new and old events are interleaved, so the handler receives events out of
+// stream order. Read models built with a priority replay must accept events in
+// any order, for example by keeping the latest state by event number. Use
+// Phase or SetPhaseHandler to tell users that the read model is incomplete.
+type PriorityReplay struct {
+	client    *Client
+	stream    string
+	recent    int
+	handler   func(*EventResponse) error
+	onPhase   func(ReplayPhase)
+	deliverMu sync.Mutex
+	mu        sync.Mutex
+	phase     ReplayPhase
+	err       error
+	reporter  ErrorReporter
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewPriorityReplay returns a replay of the stream that delivers the recent
+// most recent events before the rest of the history.
+func (c *Client) NewPriorityReplay(stream string, recent int, handler func(*EventResponse) error) *PriorityReplay {
+	return &PriorityReplay{
+		client:  c,
+		stream:  stream,
+		recent:  recent,
+		handler: handler,
+	}
+}
+
+// SetPhaseHandler sets a function that is called when the replay moves to a
+// new phase.
+func (p *PriorityReplay) SetPhaseHandler(fn func(ReplayPhase)) {
+	p.onPhase = fn
+}
+
+// Phase returns the current phase of the replay.
+func (p *PriorityReplay) Phase() ReplayPhase {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.phase
+}
+
+// Err returns the error that stopped the replay, or nil.
+func (p *PriorityReplay) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Start starts the replay.
+func (p *PriorityReplay) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	p.phase = ReplayRecent
+	p.err = nil
+	go p.run(p.stop, p.done)
+}
+
+// Stop stops the replay and waits for any call to the handler in progress to
+// return.
+func (p *PriorityReplay) Stop() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop = nil
+	p.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Done returns a channel that is closed when the replay stops.
+func (p *PriorityReplay) Done() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done
+}
+
+func (p *PriorityReplay) run(stop, done chan struct{}) {
+	defer close(done)
+
+	head, err := p.client.GetStreamHeadVersion(p.stream)
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound, *ErrNoEvents:
+		head = -1
+	default:
+		p.fail(err)
+		return
+	}
+
+	from := head - p.recent + 1
+	if from < 0 {
+		from = 0
+	}
+
+	// The recent events are read newest first and delivered oldest first.
+	recent := []*EventResponse{}
+	if head >= 0 {
+		err = p.client.ForEachEvent(p.stream, head, "backward", func(er *EventResponse) error {
+			if er.Event.EventNumber < from {
+				return errReplayStopped
+			}
+			recent = append(recent, er)
+			return p.stopped(stop)
+		})
+		if err != nil && err != errReplayStopped {
+			p.fail(err)
+			return
+		}
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		if p.stopped(stop) != nil {
+			return
+		}
+		if err := p.deliver(recent[i]); err != nil {
+			p.fail(err)
+			return
+		}
+	}
+
+	sub := p.client.NewCatchUpSubscription(p.stream, head+1, p.deliver)
+	sub.Start()
+	defer sub.Stop()
+
+	if from > 0 {
+		p.setPhase(ReplayBackfill)
+		err = p.client.ForEachEvent(p.stream, 0, "forward", func(er *EventResponse) error {
+			if er.Event.EventNumber >= from {
+				return errReplayStopped
+			}
+			if err := p.stopped(stop); err != nil {
+				return err
+			}
+			return p.deliver(er)
+		})
+		if err != nil && err != errReplayStopped {
+			p.fail(err)
+			return
+		}
+		if p.stopped(stop) != nil {
+			return
+		}
+	}
+	p.setPhase(ReplayComplete)
+
+	select {
+	case <-stop:
+	case <-sub.Done():
+		p.fail(sub.Err())
+	}
+}
+
+// deliver calls the handler, serializing the calls made for new and old
+// events.
+func (p *PriorityReplay) deliver(er *EventResponse) error {
+	p.deliverMu.Lock()
+	defer p.deliverMu.Unlock()
+	return p.handler(er)
+}
+
+// stopped returns errReplayStopped if stop is closed.
+func (p *PriorityReplay) stopped(stop chan struct{}) error {
+	select {
+	case <-stop:
+		return errReplayStopped
+	default:
+		return nil
+	}
+}
+
+func (p *PriorityReplay) setPhase(phase ReplayPhase) {
+	p.mu.Lock()
+	p.phase = phase
+	p.mu.Unlock()
+	if p.onPhase != nil {
+		p.onPhase(phase)
+	}
+}
+
+func (p *PriorityReplay) fail(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+	if err != nil {
+		p.reporter.Report("Priority replay of "+p.stream, err, true)
+	}
+}
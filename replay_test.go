@@ -0,0 +1,147 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&ReplaySuite{})
+
+type ReplaySuite struct{}
+
+func (s *ReplaySuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *ReplaySuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// replayRecorder records the event numbers delivered and the phases of a
+// replay.
+type replayRecorder struct {
+	mu     sync.Mutex
+	events []int
+	phases []ReplayPhase
+}
+
+func (r *replayRecorder) handle(er *EventResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, er.Event.EventNumber)
+	return nil
+}
+
+func (r *replayRecorder) phase(p ReplayPhase) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phases = append(r.phases, p)
+}
+
+func (r *replayRecorder) recorded() ([]int, []ReplayPhase) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.events...), append([]ReplayPhase(nil), r.phases...)
+}
+
+func (s *ReplaySuite) TestRecentEventsAreDeliveredFirst(c *C) {
+	stream := "replay"
+	es := CreateTestEvents(10, stream, server.URL, "Foo")
+	setupSimulator(es, nil)
+
+	r := &replayRecorder{}
+	replay := client.NewPriorityReplay(stream, 3, r.handle)
+	replay.SetPhaseHandler(r.phase)
+	replay.Start()
+	defer replay.Stop()
+
+	eventually(func() bool { return replay.Phase() == ReplayComplete })
+	events, phases := r.recorded()
+	c.Assert(events, DeepEquals, []int{7, 8, 9, 0, 1, 2, 3, 4, 5, 6})
+	c.Assert(phases, DeepEquals, []ReplayPhase{ReplayBackfill, ReplayComplete})
+	c.Assert(replay.Err(), IsNil)
+}
+
+func (s *ReplaySuite) TestShortStreamHasNoBackfill(c *C) {
+	stream := "replay-short"
+	es := CreateTestEvents(2, stream, server.URL, "Foo")
+	setupSimulator(es, nil)
+
+	r := &replayRecorder{}
+	replay := client.NewPriorityReplay(stream, 5, r.handle)
+	replay.SetPhaseHandler(r.phase)
+	replay.Start()
+	defer replay.Stop()
+
+	eventually(func() bool { return replay.Phase() == ReplayComplete })
+	events, phases := r.recorded()
+	c.Assert(events, DeepEquals, []int{0, 1})
+	c.Assert(phases, DeepEquals, []ReplayPhase{ReplayComplete})
+}
+
+func (s *ReplaySuite) TestNewEventsAreDelivered(c *C) {
+	stream := "replay-live"
+	serveWritableStream(stream)
+	writer := client.NewStreamWriter(stream)
+	for i := 0; i < 4; i++ {
+		c.Assert(writer.Append(ExpectAny, NewEvent("", "Foo", &FooEvent{}, nil)), IsNil)
+	}
+
+	r := &replayRecorder{}
+	replay := client.NewPriorityReplay(stream, 2, r.handle)
+	replay.Start()
+	defer replay.Stop()
+
+	eventually(func() bool { return replay.Phase() == ReplayComplete })
+	c.Assert(writer.Append(ExpectAny, NewEvent("", "Foo", &FooEvent{}, nil)), IsNil)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if events, _ := r.recorded(); len(events) == 5 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	events, _ := r.recorded()
+	c.Assert(events, DeepEquals, []int{2, 3, 0, 1, 4})
+}
+
+func (s *ReplaySuite) TestMissingStreamIsComplete(c *C) {
+	mux.HandleFunc("/streams/replay-missing/", http.NotFound)
+
+	r := &replayRecorder{}
+	replay := client.NewPriorityReplay("replay-missing", 5, r.handle)
+	replay.Start()
+	defer replay.Stop()
+
+	eventually(func() bool { return replay.Phase() == ReplayComplete })
+	c.Assert(replay.Phase(), Equals, ReplayComplete)
+	events, _ := r.recorded()
+	c.Assert(events, HasLen, 0)
+}
+
+func (s *ReplaySuite) TestHandlerErrorStopsReplay(c *C) {
+	stream := "replay-fail"
+	es := CreateTestEvents(5, stream, server.URL, "Foo")
+	setupSimulator(es, nil)
+
+	boom := errors.New("boom")
+	replay := client.NewPriorityReplay(stream, 2, func(er *EventResponse) error {
+		if er.Event.EventNumber == 1 {
+			return boom
+		}
+		return nil
+	})
+	replay.Start()
+	<-replay.Done()
+	c.Assert(replay.Err(), Equals, boom)
+	c.Assert(replay.Phase(), Equals, ReplayBackfill)
+}
@@ -0,0 +1,221 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReplicateOptions configures ReplicateStream and ReplicateAll.
+//
+// BatchSize is the number of events read and written in each request, 20 by
+// default and at most MaxPageSize. EventsPerSecond limits the rate at which
+// events are copied; 0 means no limit. Checkpoints, if not nil, records the
+// last event copied of each stream under the name returned by
+// ReplicationCheckpointName, so that a replication that is interrupted resumes
+// where it stopped. Prefix and Category restrict the streams copied by
+// ReplicateAll as in ListStreamsOptions.
+type ReplicateOptions struct {
+	BatchSize       int
+	EventsPerSecond float64
+	Checkpoints     CheckpointStore
+	Prefix          string
+	Category        string
+}
+
+// ReplicationCheckpointName returns the name under which the checkpoint of the
+// replication of the stream is stored.
+func ReplicationCheckpointName(stream string) string {
+	return "replicate-" + stream
+}
+
+// ReplicateStream copies the events of the stream read through src to the
+// stream of the same name written through dst, and returns the number of
+// events copied.
+//
+// Event ids, event types, data and metadata are preserved. The destination
+// stream must not exist or must hold events copied from the source by an
+// earlier run. Events are appended after them with the expected version of the
+// destination, so a write made by anyone else to the destination stream stops
+// the copy with an *ErrConcurrencyViolation. Writes are idempotent: the events
+// already copied, including a batch that was written but not checkpointed
+// before the copy was interrupted, are recognized by the id of the last event
+// in the destination and are not written again. Without a checkpoint store
+// the source is read from the beginning to find that event.
+//
+// The copy stops at the head of the source stream and can be run again to copy
+// the events appended since. It stops early with ctx.Err() when ctx is done.
+// opts may be nil.
+func ReplicateStream(ctx context.Context, src, dst *Client, stream string, opts *ReplicateOptions) (int, error) {
+	o := newReplicateOptions(opts)
+	return replicateStream(ctx, src, dst, stream, o, o.bucket())
+}
+
+// newReplicateOptions returns a copy of opts with the defaults applied.
+func newReplicateOptions(opts *ReplicateOptions) ReplicateOptions {
+	o := ReplicateOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultPageSize
+	}
+	return o
+}
+
+// bucket returns the rate limiter of the replication, or nil.
+func (o ReplicateOptions) bucket() *tokenBucket {
+	if o.EventsPerSecond <= 0 {
+		return nil
+	}
+	return newTokenBucket(o.EventsPerSecond, o.BatchSize)
+}
+
+// replicateStream copies the stream, throttled by bucket.
+func replicateStream(ctx context.Context, src, dst *Client, stream string, o ReplicateOptions, bucket *tokenBucket) (int, error) {
+	name := ReplicationCheckpointName(stream)
+
+	ro := newReadOptions(nil)
+	head, err := dst.readSlice(ctx, stream, ReadBackward, -1, 1, ro)
+	if _, ok := err.(*ErrNotFound); ok {
+		head, err = &StreamSlice{LastEventNumber: -1}, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version := head.LastEventNumber
+	lastID := ""
+	if len(head.Events) > 0 {
+		lastID = head.Events[0].Event.EventID
+	}
+
+	// When the destination has events the copy starts at the checkpoint
+	// rather than after it, so that the last event copied is found again.
+	from := 0
+	if o.Checkpoints != nil {
+		cp, err := o.Checkpoints.Load(name)
+		if err != nil {
+			return 0, err
+		}
+		from = cp + 1
+		if lastID != "" && cp >= 0 {
+			from = cp
+		}
+	}
+
+	writer := dst.NewStreamWriter(stream)
+	copied := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return copied, err
+		}
+		slice, err := src.readSlice(ctx, stream, ReadForward, from, o.BatchSize, ro)
+		if err != nil {
+			return copied, err
+		}
+
+		batch := make([]*Event, 0, len(slice.Events))
+		for _, er := range slice.Events {
+			batch = append(batch, copyEvent(er.Event))
+		}
+
+		// The events up to the last event in the destination have been
+		// copied already, including any written by an earlier run that
+		// stopped before storing its checkpoint.
+		if lastID != "" {
+			skip := len(batch)
+			for i, e := range batch {
+				if e.EventID == lastID {
+					skip = i + 1
+					lastID = ""
+					break
+				}
+			}
+			batch = batch[skip:]
+		}
+
+		if len(batch) > 0 {
+			if err := throttle(ctx, bucket, len(batch)); err != nil {
+				return copied, err
+			}
+			if err := writer.Append(ExpectedVersion(version), batch...); err != nil {
+				return copied, err
+			}
+			version += len(batch)
+			copied += len(batch)
+		}
+
+		if len(slice.Events) > 0 && o.Checkpoints != nil {
+			if err := o.Checkpoints.Store(name, slice.NextEventNumber-1); err != nil {
+				return copied, err
+			}
+		}
+		if slice.IsEndOfStream || slice.NextEventNumber == from {
+			if lastID != "" {
+				return copied, fmt.Errorf("Stream %s in the destination has events that are not in the source", stream)
+			}
+			return copied, nil
+		}
+		from = slice.NextEventNumber
+	}
+}
+
+// ReplicateAll copies every stream listed by src.ListStreams, other than
+// system streams, to dst with ReplicateStream and returns the number of events
+// copied. The streams can be restricted with the Prefix and Category of opts.
+// The rate limit applies to the replication as a whole.
+//
+// Streams are copied one at a time in the order they were created. If copying
+// a stream fails the error is returned and the streams that follow are not
+// copied; with a checkpoint store the replication resumes from that stream
+// when it is run again.
+func ReplicateAll(ctx context.Context, src, dst *Client, opts *ReplicateOptions) (int, error) {
+	o := newReplicateOptions(opts)
+	bucket := o.bucket()
+	page, err := src.ListStreams(ctx, &ListStreamsOptions{Prefix: o.Prefix, Category: o.Category})
+	if err != nil {
+		return 0, err
+	}
+
+	copied := 0
+	for _, stream := range page.Streams {
+		if strings.HasPrefix(stream, "$") {
+			continue
+		}
+		n, err := replicateStream(ctx, src, dst, stream, o, bucket)
+		copied += n
+		if err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
+
+// throttle waits until the bucket permits n events. It returns ctx.Err() if
+// ctx is done first.
+func throttle(ctx context.Context, bucket *tokenBucket, n int) error {
+	if bucket == nil {
+		return nil
+	}
+	var d time.Duration
+	for i := 0; i < n; i++ {
+		d = bucket.reserve()
+	}
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+// Aggregate is implemented by types whose state is built from the events in a
+// stream.
+//
+// Apply is called for each event in the stream in order when the aggregate is
+// loaded. decode deserializes the event data into the value provided.
+//
+// Changes returns the events raised by the aggregate that have not yet been
+// saved and ClearChanges is called once they have been saved.
+type Aggregate interface {
+	Apply(eventType string, decode func(v interface{}) error) error
+	Changes() []*Event
+	ClearChanges()
+}
+
+// Repository loads and saves aggregates.
+//
+// Aggregates are rebuilt by replaying the events in their stream. Optionally,
+// snapshots of an aggregate can be written to a snapshot stream so that only
+// the events written after the most recent snapshot need to be replayed. When
+// snapshots are enabled the aggregate is serialized to JSON, so any state that
+// should be restored from a snapshot must be held in exported fields.
+type Repository struct {
+	client        *Client
+	factory       func() Aggregate
+	snapshotEvery int
+}
+
+// NewRepository returns a new *Repository.
+//
+// factory must return a new aggregate in its initial state.
+func (c *Client) NewRepository(factory func() Aggregate) *Repository {
+	return &Repository{
+		client:  c,
+		factory: factory,
+	}
+}
+
+// SetSnapshotFrequency causes a snapshot to be written each time the number of
+// events in a stream passes a multiple of n. A value of 0 or less disables
+// snapshots, which is the default.
+func (r *Repository) SetSnapshotFrequency(n int) {
+	r.snapshotEvery = n
+}
+
+// Load returns the aggregate in the stream and the version of the stream.
+//
+// The version can be passed to Save as the expected version to ensure that the
+// stream has not been changed since the aggregate was loaded.
+//
+// If the stream does not exist an *ErrNotFound is returned.
+func (r *Repository) Load(stream string) (Aggregate, int, error) {
+	return r.load(stream, r.snapshotEvery > 0)
+}
+
+// load folds the events in the stream into a new aggregate, starting from the
+// latest snapshot if fromSnapshot is true.
+func (r *Repository) load(stream string, fromSnapshot bool) (Aggregate, int, error) {
+	a := r.factory()
+	version := -1
+
+	if fromSnapshot {
+		v, err := r.client.ReadLatestSnapshot(stream, a)
+		if err != nil {
+			return nil, -1, err
+		}
+		version = v
+	}
+
+	reader := r.client.NewStreamReader(stream)
+	reader.NextVersion(version + 1)
+
+	decode := func(v interface{}) error {
+		return reader.Scan(v, nil)
+	}
+
+	for reader.Next() {
+		if reader.Err() != nil {
+			if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+				break
+			}
+			return nil, -1, reader.Err()
+		}
+
+		e := reader.EventResponse().Event
+		if err := a.Apply(e.EventType, decode); err != nil {
+			return nil, -1, err
+		}
+		version = e.EventNumber
+	}
+
+	return a, version, nil
+}
+
+// Save appends the changes of the aggregate to the stream.
+//
+// expectedVersion has the same meaning as for StreamWriter.Append. Usually it
+// will be the version returned from Load, or -1 for a new aggregate. If the
+// stream has been changed an *ErrConcurrencyViolation is returned.
+//
+// If snapshots are enabled and the changes cause the number of events in the
+// stream to pass a multiple of the snapshot frequency, a snapshot of the
+// aggregate is written after the changes. The aggregate should already reflect
+// its changes when Save is called. If writing the snapshot fails the error is
+// returned, however the changes will have been saved.
+func (r *Repository) Save(stream string, a Aggregate, expectedVersion int) error {
+	changes := a.Changes()
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := r.client.NewStreamWriter(stream).Append(ExpectedVersion(expectedVersion), changes...); err != nil {
+		return err
+	}
+	a.ClearChanges()
+
+	if r.snapshotEvery <= 0 || expectedVersion < -1 {
+		return nil
+	}
+
+	version := expectedVersion + len(changes)
+	if (expectedVersion+1)/r.snapshotEvery == (version+1)/r.snapshotEvery {
+		return nil
+	}
+
+	return r.client.WriteSnapshot(stream, version, a)
+}
+
+// SnapshotVersion returns the version of the latest snapshot of the aggregate
+// in the stream, or -1 if there is no snapshot.
+func (r *Repository) SnapshotVersion(stream string) (int, error) {
+	return r.client.ReadLatestSnapshot(stream, nil)
+}
+
+// Snapshot loads the aggregate in the stream from its latest snapshot, writes a
+// snapshot of the aggregate at the version of the stream and returns the
+// version.
+//
+// Repository implements Snapshotter, so it can be passed to
+// Client.NewSnapshotRefresher to keep the snapshots of a category fresh.
+// Snapshot is used whether or not a snapshot frequency has been set.
+func (r *Repository) Snapshot(stream string) (int, error) {
+	a, version, err := r.load(stream, true)
+	if err != nil {
+		return -1, err
+	}
+	if err := r.client.WriteSnapshot(stream, version, a); err != nil {
+		return -1, err
+	}
+	return version, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&RepositorySuite{})
+
+type RepositorySuite struct{}
+
+func (s *RepositorySuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *RepositorySuite) TearDownTest(c *C) {
+	teardown()
+}
+
+type Deposited struct {
+	Amount int `json:"amount"`
+}
+
+type testAccount struct {
+	Balance int
+	Applied int
+	changes []*Event
+}
+
+func (a *testAccount) Apply(eventType string, decode func(v interface{}) error) error {
+	d := &Deposited{}
+	if err := decode(d); err != nil {
+		return err
+	}
+	a.Balance += d.Amount
+	a.Applied++
+	return nil
+}
+
+func (a *testAccount) Deposit(amount int) {
+	a.Balance += amount
+	a.changes = append(a.changes, NewEvent("", "", &Deposited{Amount: amount}, nil))
+}
+
+func (a *testAccount) Changes() []*Event { return a.changes }
+func (a *testAccount) ClearChanges()     { a.changes = nil }
+
+func newTestAccount() Aggregate { return &testAccount{} }
+
+func depositEvents(stream string, n int) []*Event {
+	es := make([]*Event, n)
+	for i := range es {
+		es[i] = CreateTestEventFromData(stream, server.URL, i, &Deposited{Amount: i + 1}, nil)
+	}
+	return es
+}
+
+func (s *RepositorySuite) TestLoadReplaysEvents(c *C) {
+	setupSimulator(depositEvents("account-1", 25), nil)
+
+	repo := client.NewRepository(newTestAccount)
+	a, version, err := repo.Load("account-1")
+	c.Assert(err, IsNil)
+	c.Assert(version, Equals, 24)
+	c.Assert(a.(*testAccount).Balance, Equals, 325)
+	c.Assert(a.(*testAccount).Applied, Equals, 25)
+}
+
+func (s *RepositorySuite) TestLoadMissingStreamReturnsErrNotFound(c *C) {
+	mux.HandleFunc("/streams/missing/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	repo := client.NewRepository(newTestAccount)
+	_, _, err := repo.Load("missing")
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+}
+
+func (s *RepositorySuite) TestLoadFromSnapshot(c *C) {
+	setupSimulator(depositEvents("account-2", 25), nil)
+
+	snap := CreateTestEventFromData(SnapshotStreamName("account-2"), server.URL, 0,
+		&testAccount{Balance: 210}, &snapshotMetaData{Version: 19})
+	sim := newTestSimulator([]*Event{snap}, nil)
+	mux.Handle("/streams/account-2-snapshots", sim)
+	mux.Handle("/streams/account-2-snapshots/", sim)
+
+	repo := client.NewRepository(newTestAccount)
+	repo.SetSnapshotFrequency(10)
+	a, version, err := repo.Load("account-2")
+	c.Assert(err, IsNil)
+	c.Assert(version, Equals, 24)
+	c.Assert(a.(*testAccount).Balance, Equals, 325)
+	c.Assert(a.(*testAccount).Applied, Equals, 5)
+}
+
+func (s *RepositorySuite) TestSaveAppendsChangesAndWritesSnapshot(c *C) {
+	var expected string
+	var written []Event
+	mux.HandleFunc("/streams/account-3", func(w http.ResponseWriter, r *http.Request) {
+		expected = r.Header.Get("ES-ExpectedVersion")
+		c.Assert(json.NewDecoder(r.Body).Decode(&written), IsNil)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var snapshots []map[string]interface{}
+	mux.HandleFunc("/streams/account-3-snapshots", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("ES-ExpectedVersion") == "-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batch := []map[string]interface{}{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&batch), IsNil)
+		snapshots = append(snapshots, batch...)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	repo := client.NewRepository(newTestAccount)
+	repo.SetSnapshotFrequency(10)
+
+	a := &testAccount{Balance: 100}
+	a.Deposit(5)
+	a.Deposit(7)
+	c.Assert(repo.Save("account-3", a, 7), IsNil)
+
+	c.Assert(expected, Equals, "7")
+	c.Assert(written, HasLen, 2)
+	c.Assert(written[0].EventType, Equals, "Deposited")
+	c.Assert(a.Changes(), HasLen, 0)
+
+	c.Assert(snapshots, HasLen, 1)
+	c.Assert(snapshots[0]["data"].(map[string]interface{})["Balance"], Equals, float64(112))
+	c.Assert(snapshots[0]["metadata"].(map[string]interface{})["version"], Equals, float64(9))
+
+	a.Deposit(1)
+	c.Assert(repo.Save("account-3", a, 9), IsNil)
+	c.Assert(snapshots, HasLen, 1)
+}
+
+func (s *RepositorySuite) TestSaveReturnsConcurrencyViolation(c *C) {
+	mux.HandleFunc("/streams/account-4", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	repo := client.NewRepository(newTestAccount)
+	a := &testAccount{}
+	a.Deposit(1)
+	err := repo.Save("account-4", a, 3)
+	c.Assert(err, FitsTypeOf, &ErrConcurrencyViolation{})
+	c.Assert(a.Changes(), HasLen, 1)
+}
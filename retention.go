@@ -0,0 +1,312 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CategoryPolicy is a stream metadata template applied to all streams in the
+// categories matching Pattern.
+//
+// Pattern is matched against the category of each stream using path.Match, so
+// "order" matches only the order category and "order*" matches all categories
+// beginning with order. Only the fields set in Metadata are applied. Other
+// stream metadata is retained.
+type CategoryPolicy struct {
+	Pattern  string
+	Metadata StreamMetadata
+}
+
+// MetadataDrift describes a stream whose metadata does not conform to the
+// policy for its category.
+//
+// Current and Desired contain only the metadata keys set by the policy.
+type MetadataDrift struct {
+	Stream  string
+	Pattern string
+	Current map[string]interface{}
+	Desired map[string]interface{}
+}
+
+// ReconcileReport is the result of a reconciliation.
+//
+// Checked is the number of streams matched by a policy. Drift contains the
+// streams that did not conform. Applied is true if the drift was corrected.
+// Errors contains any errors that occurred for individual streams by stream name.
+type ReconcileReport struct {
+	Checked int
+	Drift   []MetadataDrift
+	Applied bool
+	Errors  map[string]error
+}
+
+// CategoryOf returns the category of a stream, which is the part of the stream
+// name before the first dash. This is the convention used by the eventstore
+// category projections.
+func CategoryOf(stream string) string {
+	if i := strings.Index(stream, "-"); i >= 0 {
+		return stream[:i]
+	}
+	return stream
+}
+
+// Reconciler applies stream metadata policies declared per category.
+//
+// Streams are enumerated by reading the $streams stream, which is maintained by
+// the $streams system projection. The projection must be running for the
+// reconciler to find streams. System streams beginning with $ are ignored.
+//
+// When a stream matches more than one policy the policy added first is applied.
+type Reconciler struct {
+	client   *Client
+	mu       sync.Mutex
+	policies []CategoryPolicy
+	dryRun   bool
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	reporter ErrorReporter
+}
+
+// NewReconciler returns a new *Reconciler.
+func (c *Client) NewReconciler() *Reconciler {
+	return &Reconciler{client: c}
+}
+
+// AddPolicy adds a policy to the reconciler.
+func (r *Reconciler) AddPolicy(p CategoryPolicy) error {
+	if _, err := path.Match(p.Pattern, ""); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policies = append(r.policies, p)
+	return nil
+}
+
+// SetDryRun causes Reconcile to report drift without correcting it.
+func (r *Reconciler) SetDryRun(dryRun bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dryRun = dryRun
+}
+
+// Reconcile checks the metadata of every stream matched by a policy and, unless
+// the reconciler is in dry run mode, writes the metadata of streams that do not
+// conform.
+//
+// An error is returned if the streams cannot be enumerated. Errors reading or
+// writing the metadata of individual streams are reported in the report.
+func (r *Reconciler) Reconcile() (*ReconcileReport, error) {
+	r.mu.Lock()
+	policies := append([]CategoryPolicy(nil), r.policies...)
+	dryRun := r.dryRun
+	r.mu.Unlock()
+
+	streams, err := r.client.listStreams()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{Applied: !dryRun, Errors: make(map[string]error)}
+	for _, stream := range streams {
+		p := matchPolicy(policies, stream)
+		if p == nil {
+			continue
+		}
+		report.Checked++
+
+		drift, current, err := r.check(stream, p)
+		if err != nil {
+			report.Errors[stream] = err
+			continue
+		}
+		if drift == nil {
+			continue
+		}
+		report.Drift = append(report.Drift, *drift)
+
+		if dryRun {
+			continue
+		}
+		for k, v := range drift.Desired {
+			current[k] = v
+		}
+		if err := r.client.NewStreamWriter(stream).WriteMetaData(stream, current); err != nil {
+			report.Errors[stream] = err
+		}
+	}
+	return report, nil
+}
+
+// check compares the metadata of the stream with the policy. It returns the
+// drift, or nil if the stream conforms, and the current metadata of the stream.
+func (r *Reconciler) check(stream string, p *CategoryPolicy) (*MetadataDrift, map[string]interface{}, error) {
+	current, err := r.client.readMetaDataMap(stream)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	desired := p.Metadata.toMap()
+	drift := &MetadataDrift{
+		Stream:  stream,
+		Pattern: p.Pattern,
+		Current: make(map[string]interface{}),
+		Desired: desired,
+	}
+	conforms := true
+	for k, v := range desired {
+		if cv, ok := current[k]; ok {
+			drift.Current[k] = cv
+		}
+		if !jsonEqual(current[k], v) {
+			conforms = false
+		}
+	}
+	if conforms {
+		return nil, current, nil
+	}
+	return drift, current, nil
+}
+
+// Plan returns a plan of the metadata writes that Reconcile would make, without
+// making them.
+//
+// The plan can be reviewed and then executed with Client.ExecutePlan. Each
+// write in the plan is conditional on the metadata of the stream being
+// unchanged since the plan was made. Unlike Reconcile, an error reading the
+// metadata of any stream is returned so that the plan is never incomplete.
+func (r *Reconciler) Plan() (*Plan, error) {
+	r.mu.Lock()
+	policies := append([]CategoryPolicy(nil), r.policies...)
+	r.mu.Unlock()
+
+	streams, err := r.client.listStreams()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	for _, stream := range streams {
+		p := matchPolicy(policies, stream)
+		if p == nil {
+			continue
+		}
+		drift, current, err := r.check(stream, p)
+		if err != nil {
+			return nil, err
+		}
+		if drift == nil {
+			continue
+		}
+		desired := make(map[string]interface{}, len(current))
+		for k, v := range current {
+			desired[k] = v
+		}
+		for k, v := range drift.Desired {
+			desired[k] = v
+		}
+		plan.WriteStreamMetadata(stream, current, desired)
+	}
+	return plan, nil
+}
+
+// Start runs Reconcile every interval until Stop is called. report is called
+// with the result of each reconciliation, and its errors are also sent on the
+// Errors channel.
+func (r *Reconciler) Start(interval time.Duration, report func(*ReconcileReport, error)) {
+	r.mu.Lock()
+	if r.stop != nil {
+		r.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			rep, err := r.Reconcile()
+			var errs map[string]error
+			if rep != nil {
+				errs = rep.Errors
+			}
+			r.reporter.reportRun("Reconciler", err, errs)
+			report(rep, err)
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops a reconciler started with Start and waits for any reconciliation
+// in progress to complete.
+func (r *Reconciler) Stop() {
+	r.mu.Lock()
+	stop := r.stop
+	r.stop = nil
+	r.mu.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+	r.wg.Wait()
+}
+
+// readMetaDataMap returns the metadata of the stream as a map. If the stream
+// has no metadata an empty map is returned.
+func (c *Client) readMetaDataMap(stream string) (map[string]interface{}, error) {
+	current := make(map[string]interface{})
+	er, err := c.NewStreamReader(stream).MetaData()
+	if err != nil {
+		return nil, err
+	}
+	if er != nil {
+		if err := c.decodeEvent(er, &current, nil); err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+// matchPolicy returns the first policy matching the category of the stream.
+func matchPolicy(policies []CategoryPolicy, stream string) *CategoryPolicy {
+	if strings.HasPrefix(stream, "$") {
+		return nil
+	}
+	category := CategoryOf(stream)
+	for i := range policies {
+		if ok, _ := path.Match(policies[i].Pattern, category); ok {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+// jsonEqual returns true if a and b have the same JSON representation.
+func jsonEqual(a, b interface{}) bool {
+	norm := func(v interface{}) interface{} {
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var ret interface{}
+		json.Unmarshal(bs, &ret)
+		return ret
+	}
+	return reflect.DeepEqual(norm(a), norm(b))
+}
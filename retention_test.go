@@ -0,0 +1,152 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&RetentionSuite{})
+
+type RetentionSuite struct{}
+
+func (s *RetentionSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *RetentionSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// serveStreams serves a $streams stream listing the streams provided and the
+// stream metadata for each stream. Metadata written to a stream is returned
+// in the writes map.
+func serveStreams(c *C, meta map[string]interface{}) (writes map[string]map[string]interface{}, mu *sync.Mutex) {
+	names := []string{}
+	for name := range meta {
+		names = append(names, name)
+	}
+
+	// Entries in $streams link to the first event of each stream.
+	es := CreateTestEvents(len(names), "$streams", server.URL, "$>")
+	firsts := make(map[string]*Event)
+	for i, name := range names {
+		firsts[name] = CreateTestEvents(1, name, server.URL, "FooEvent")[0]
+		es[i].Links = firsts[name].Links
+	}
+	setupSimulator(es, nil)
+
+	writes = make(map[string]map[string]interface{})
+	mu = &sync.Mutex{}
+	for name, m := range meta {
+		var me *Event
+		if m != nil {
+			b, _ := json.Marshal(m)
+			raw := json.RawMessage(b)
+			me = CreateTestEvent(name, server.URL, "$metadata", 0, &raw, nil)
+		}
+		sim := newTestSimulator([]*Event{firsts[name]}, me)
+		name := name
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				got := map[string]interface{}{}
+				decodeWrittenMetaData(c, r, &got)
+				mu.Lock()
+				writes[name] = got
+				mu.Unlock()
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+			sim.ServeHTTP(w, r)
+		}
+		mux.HandleFunc("/streams/"+name, handler)
+		mux.HandleFunc("/streams/"+name+"/", handler)
+	}
+	return writes, mu
+}
+
+func (s *RetentionSuite) TestReconcileAppliesPoliciesByCategory(c *C) {
+	writes, _ := serveStreams(c, map[string]interface{}{
+		"order-1":   map[string]interface{}{"$maxCount": 5, "owner": "sales"},
+		"order-2":   map[string]interface{}{"$maxCount": 100},
+		"invoice-1": nil,
+		"user-1":    nil,
+	})
+
+	r := client.NewReconciler()
+	c.Assert(r.AddPolicy(CategoryPolicy{Pattern: "order", Metadata: StreamMetadata{MaxCount: Int(100)}}), IsNil)
+	c.Assert(r.AddPolicy(CategoryPolicy{Pattern: "inv*", Metadata: StreamMetadata{MaxAge: Int(3600)}}), IsNil)
+
+	report, err := r.Reconcile()
+	c.Assert(err, IsNil)
+	c.Assert(report.Errors, HasLen, 0)
+	c.Assert(report.Checked, Equals, 3)
+	c.Assert(report.Applied, Equals, true)
+	c.Assert(report.Drift, HasLen, 2)
+	c.Assert(report.Drift[0].Stream, Equals, "invoice-1")
+	c.Assert(report.Drift[1].Stream, Equals, "order-1")
+	c.Assert(report.Drift[1].Current, DeepEquals, map[string]interface{}{"$maxCount": float64(5)})
+
+	c.Assert(writes, HasLen, 2)
+	c.Assert(writes["order-1"], DeepEquals, map[string]interface{}{"$maxCount": float64(100), "owner": "sales"})
+	c.Assert(writes["invoice-1"], DeepEquals, map[string]interface{}{"$maxAge": float64(3600)})
+}
+
+func (s *RetentionSuite) TestDryRunReportsDriftWithoutWriting(c *C) {
+	writes, _ := serveStreams(c, map[string]interface{}{
+		"order-1": nil,
+	})
+
+	r := client.NewReconciler()
+	r.AddPolicy(CategoryPolicy{Pattern: "order", Metadata: StreamMetadata{MaxCount: Int(1)}})
+	r.SetDryRun(true)
+
+	report, err := r.Reconcile()
+	c.Assert(err, IsNil)
+	c.Assert(report.Applied, Equals, false)
+	c.Assert(report.Drift, HasLen, 1)
+	c.Assert(writes, HasLen, 0)
+}
+
+func (s *RetentionSuite) TestStartReconcilesOnSchedule(c *C) {
+	serveStreams(c, map[string]interface{}{
+		"order-1": nil,
+	})
+
+	r := client.NewReconciler()
+	r.AddPolicy(CategoryPolicy{Pattern: "order", Metadata: StreamMetadata{MaxCount: Int(1)}})
+	r.SetDryRun(true)
+
+	var mu sync.Mutex
+	runs := 0
+	r.Start(10*time.Millisecond, func(report *ReconcileReport, err error) {
+		c.Check(err, IsNil)
+		mu.Lock()
+		runs++
+		mu.Unlock()
+	})
+	eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return runs >= 2
+	})
+	r.Stop()
+	c.Assert(runs >= 2, Equals, true)
+}
+
+func (s *RetentionSuite) TestAddPolicyRejectsInvalidPattern(c *C) {
+	err := client.NewReconciler().AddPolicy(CategoryPolicy{Pattern: "[order"})
+	c.Assert(err, NotNil)
+}
+
+func (s *RetentionSuite) TestCategoryOf(c *C) {
+	c.Assert(CategoryOf("order-123-abc"), Equals, "order")
+	c.Assert(CategoryOf("order"), Equals, "order")
+}
@@ -0,0 +1,211 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+)
+
+// SchemaVersionMetaDataKey is the event metadata key used to record the schema
+// version of the data of an event.
+const SchemaVersionMetaDataKey = "schemaVersion"
+
+// Upcaster converts the JSON data of an event from one schema version to the
+// next.
+type Upcaster func(data json.RawMessage) (json.RawMessage, error)
+
+type upcasterKey struct {
+	eventType string
+	from      int
+}
+
+// SetSchemaVersion sets the current schema version of the event type.
+//
+// When the client has the registry set with SetTypeRegistry the version is
+// written in the metadata of every event of the type appended by a
+// StreamWriter, under SchemaVersionMetaDataKey. A version already present in
+// the metadata of an event is not replaced.
+func (r *TypeRegistry) SetSchemaVersion(eventType string, version int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.versions[eventType] = version
+}
+
+// SchemaVersion returns the current schema version of the event type. The
+// second value returned is false if no version has been set.
+func (r *TypeRegistry) SchemaVersion(eventType string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.versions[eventType]
+	return v, ok
+}
+
+// SetMinSchemaVersion sets the lowest schema version of the event type that
+// the reader supports.
+//
+// Events of the type with a lower version are upcast with the upcasters
+// registered with RegisterUpcaster before they are decoded by DecodeEvent or
+// delivered by a Subscription. If there is no upcaster for one of the versions
+// in between the event cannot be read and an *ErrSchemaVersion is returned, or
+// the event is moved to the hold stream of the subscription. See
+// Subscription.SetHoldStream.
+func (r *TypeRegistry) SetMinSchemaVersion(eventType string, min int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.minVersions[eventType] = min
+}
+
+// RegisterUpcaster registers a function that converts the data of events of the
+// event type from schema version from to version from+1.
+func (r *TypeRegistry) RegisterUpcaster(eventType string, from int, fn Upcaster) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.upcasters[upcasterKey{eventType: eventType, from: from}] = fn
+}
+
+func (r *TypeRegistry) minSchemaVersion(eventType string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.minVersions[eventType]
+	return v, ok
+}
+
+func (r *TypeRegistry) upcaster(eventType string, from int) (Upcaster, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.upcasters[upcasterKey{eventType: eventType, from: from}]
+	return fn, ok
+}
+
+// SchemaVersionOf returns the schema version recorded in the metadata of the
+// event, or 0 if the event has no schema version.
+func SchemaVersionOf(er *EventResponse) int {
+	raw, ok := er.Event.MetaData.(*json.RawMessage)
+	if !ok || raw == nil {
+		return 0
+	}
+	m := struct {
+		Version int `json:"schemaVersion"`
+	}{}
+	if json.Unmarshal(*raw, &m) != nil {
+		return 0
+	}
+	return m.Version
+}
+
+// typeRegistry returns the registry set on the client, or nil.
+func (c *Client) typeRegistry() *TypeRegistry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.registry
+}
+
+// stampSchemaVersion returns a copy of the event with the schema version of
+// its event type merged into its metadata. If no version is set the event is
+// returned unchanged.
+func (c *Client) stampSchemaVersion(e *Event) (*Event, error) {
+	r := c.typeRegistry()
+	if r == nil {
+		return e, nil
+	}
+	v, ok := r.SchemaVersion(e.EventType)
+	if !ok {
+		return e, nil
+	}
+	return mergeMetaData(map[string]interface{}{SchemaVersionMetaDataKey: v}, e)
+}
+
+// upcast returns the event response with the data of the event upcast to the
+// minimum schema version of its event type. If the event is at or above the
+// minimum version it is returned unchanged.
+func (c *Client) upcast(er *EventResponse) (*EventResponse, error) {
+	r := c.typeRegistry()
+	if r == nil {
+		return er, nil
+	}
+	min, ok := r.minSchemaVersion(er.Event.EventType)
+	if !ok {
+		return er, nil
+	}
+	version := SchemaVersionOf(er)
+	if version >= min {
+		return er, nil
+	}
+
+	var data json.RawMessage
+	if raw, ok := er.Event.Data.(*json.RawMessage); ok && raw != nil {
+		data = *raw
+	}
+	for v := version; v < min; v++ {
+		fn, ok := r.upcaster(er.Event.EventType, v)
+		if !ok {
+			return nil, &ErrSchemaVersion{
+				EventType:  er.Event.EventType,
+				Version:    version,
+				MinVersion: min,
+			}
+		}
+		var err error
+		if data, err = fn(data); err != nil {
+			return nil, err
+		}
+	}
+
+	e := *er.Event
+	e.Data = &data
+
+	// The metadata records the version the data was upcast to. Metadata that
+	// is not a JSON object is left unchanged.
+	meta := map[string]interface{}{}
+	if raw, ok := er.Event.MetaData.(*json.RawMessage); ok && raw != nil && len(*raw) > 0 {
+		if json.Unmarshal(*raw, &meta) != nil {
+			meta = nil
+		}
+	}
+	if meta != nil {
+		meta[SchemaVersionMetaDataKey] = min
+		b, err := json.Marshal(meta)
+		if err != nil {
+			return nil, err
+		}
+		rawMeta := json.RawMessage(b)
+		e.MetaData = &rawMeta
+	}
+
+	ret := *er
+	ret.Event = &e
+	return &ret, nil
+}
+
+// SetHoldStream sets a stream to which events that cannot be upcast to the
+// minimum schema version of their event type are moved.
+//
+// The client must have a TypeRegistry set with SetTypeRegistry. Events are
+// upcast before they are delivered to the handler. When an event cannot be
+// upcast it is appended to the hold stream and the subscription continues with
+// the next event, so that the event can be processed once a consumer that
+// supports its version is deployed. If no hold stream is set the subscription
+// stops with an *ErrSchemaVersion.
+func (s *Subscription) SetHoldStream(stream string) {
+	s.holdStream = stream
+}
+
+// upcast upcasts the event for delivery to the handler. The event response
+// returned is nil if the event was moved to the hold stream.
+func (s *Subscription) upcast(er *EventResponse) (*EventResponse, error) {
+	up, err := s.client.upcast(er)
+	if _, ok := err.(*ErrSchemaVersion); !ok || s.holdStream == "" {
+		return up, err
+	}
+
+	// The version is recorded explicitly so that an event without one is not
+	// stamped with the current version when it is appended.
+	held := copyEvent(er.Event)
+	if m, err := mergeMetaData(map[string]interface{}{SchemaVersionMetaDataKey: SchemaVersionOf(er)}, held); err == nil {
+		held = m
+	}
+	return nil, s.client.NewStreamWriter(s.holdStream).Append(ExpectAny, held)
+}
@@ -0,0 +1,207 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SchemaSuite{})
+
+type SchemaSuite struct{}
+
+func (s *SchemaSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *SchemaSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+type FooV2 struct {
+	Name string `json:"name"`
+}
+
+// versionedEventResponse returns an event response with the schema version
+// in its metadata, or no version if version is negative.
+func versionedEventResponse(eventType string, data interface{}, version int) *EventResponse {
+	er := rawEventResponse(eventType, data)
+	meta := map[string]interface{}{"tenant": "t1"}
+	if version >= 0 {
+		meta[SchemaVersionMetaDataKey] = version
+	}
+	b, _ := json.Marshal(meta)
+	raw := json.RawMessage(b)
+	er.Event.MetaData = &raw
+	return er
+}
+
+// renameFoo upcasts {"foo": x} to {"name": x}.
+func renameFoo(data json.RawMessage) (json.RawMessage, error) {
+	v1 := FooEvent{}
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return nil, err
+	}
+	return json.Marshal(FooV2{Name: v1.Foo})
+}
+
+func (s *SchemaSuite) TestAppendStampsSchemaVersion(c *C) {
+	r := NewTypeRegistry()
+	r.SetSchemaVersion("FooEvent", 3)
+	client.SetTypeRegistry(r)
+
+	var posted []map[string]interface{}
+	mux.HandleFunc("/streams/versioned", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		c.Assert(json.Unmarshal(b, &posted), IsNil)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	writer := client.NewStreamWriter("versioned")
+	err := writer.Append(ExpectAny,
+		NewEvent("", "FooEvent", &FooEvent{Foo: "a"}, nil),
+		NewEvent("", "FooEvent", &FooEvent{Foo: "b"}, map[string]interface{}{SchemaVersionMetaDataKey: 2}),
+		NewEvent("", "BarEvent", &BarEvent{Bar: 1}, nil))
+	c.Assert(err, IsNil)
+	c.Assert(posted, HasLen, 3)
+
+	c.Assert(posted[0]["metadata"], DeepEquals, map[string]interface{}{SchemaVersionMetaDataKey: 3.0})
+	c.Assert(posted[1]["metadata"], DeepEquals, map[string]interface{}{SchemaVersionMetaDataKey: 2.0})
+	c.Assert(posted[2]["metadata"], IsNil)
+}
+
+func (s *SchemaSuite) TestSchemaVersionOf(c *C) {
+	c.Assert(SchemaVersionOf(versionedEventResponse("FooEvent", &FooEvent{}, 4)), Equals, 4)
+	c.Assert(SchemaVersionOf(versionedEventResponse("FooEvent", &FooEvent{}, -1)), Equals, 0)
+	c.Assert(SchemaVersionOf(rawEventResponse("FooEvent", &FooEvent{})), Equals, 0)
+}
+
+func (s *SchemaSuite) TestDecodeEventUpcastsOldVersions(c *C) {
+	r := NewTypeRegistry()
+	r.Register("FooEvent", &FooV2{})
+	r.SetMinSchemaVersion("FooEvent", 2)
+	r.RegisterUpcaster("FooEvent", 0, func(data json.RawMessage) (json.RawMessage, error) {
+		return data, nil
+	})
+	r.RegisterUpcaster("FooEvent", 1, renameFoo)
+	client.SetTypeRegistry(r)
+
+	v, err := client.DecodeEvent(versionedEventResponse("FooEvent", &FooEvent{Foo: "x"}, -1))
+	c.Assert(err, IsNil)
+	c.Assert(v, DeepEquals, &FooV2{Name: "x"})
+
+	v, err = client.DecodeEvent(versionedEventResponse("FooEvent", &FooV2{Name: "y"}, 2))
+	c.Assert(err, IsNil)
+	c.Assert(v, DeepEquals, &FooV2{Name: "y"})
+}
+
+func (s *SchemaSuite) TestUpcastRecordsVersionInMetaData(c *C) {
+	r := NewTypeRegistry()
+	r.SetMinSchemaVersion("FooEvent", 2)
+	r.RegisterUpcaster("FooEvent", 1, renameFoo)
+	client.SetTypeRegistry(r)
+
+	er := versionedEventResponse("FooEvent", &FooEvent{Foo: "x"}, 1)
+	up, err := client.upcast(er)
+	c.Assert(err, IsNil)
+	c.Assert(SchemaVersionOf(up), Equals, 2)
+	c.Assert(SchemaVersionOf(er), Equals, 1)
+
+	meta := map[string]interface{}{}
+	c.Assert(json.Unmarshal(*up.Event.MetaData.(*json.RawMessage), &meta), IsNil)
+	c.Assert(meta["tenant"], Equals, "t1")
+}
+
+func (s *SchemaSuite) TestDecodeEventReturnsErrSchemaVersion(c *C) {
+	r := NewTypeRegistry()
+	r.Register("FooEvent", &FooV2{})
+	r.SetMinSchemaVersion("FooEvent", 2)
+	r.RegisterUpcaster("FooEvent", 1, renameFoo)
+	client.SetTypeRegistry(r)
+
+	_, err := client.DecodeEvent(versionedEventResponse("FooEvent", &FooEvent{Foo: "x"}, 0))
+	c.Assert(err, DeepEquals, &ErrSchemaVersion{EventType: "FooEvent", Version: 0, MinVersion: 2})
+}
+
+func (s *SchemaSuite) TestSubscriptionMovesEventsToHoldStream(c *C) {
+	stream := "schema-sub"
+	es := []*Event{}
+	for i, v := range []int{0, 2, 1} {
+		er := versionedEventResponse("FooEvent", &FooEvent{Foo: "x"}, v)
+		raw := er.Event.Data.(*json.RawMessage)
+		meta := er.Event.MetaData.(*json.RawMessage)
+		es = append(es, CreateTestEvent(stream, server.URL, "FooEvent", i, raw, meta))
+	}
+	u, _ := url.Parse(server.URL)
+	sim, _ := NewAtomFeedSimulator(es, u, nil, len(es))
+	mux.Handle("/streams/"+stream+"/", sim)
+
+	var mu sync.Mutex
+	held := []map[string]interface{}{}
+	mux.HandleFunc("/streams/held", func(w http.ResponseWriter, r *http.Request) {
+		posted := []map[string]interface{}{}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &posted)
+		mu.Lock()
+		held = append(held, posted...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	r := NewTypeRegistry()
+	r.SetSchemaVersion("FooEvent", 2)
+	r.SetMinSchemaVersion("FooEvent", 2)
+	r.RegisterUpcaster("FooEvent", 1, renameFoo)
+	client.SetTypeRegistry(r)
+
+	got := make(chan *EventResponse, 10)
+	sub := client.NewCatchUpSubscription(stream, 0, func(er *EventResponse) error {
+		got <- er
+		return nil
+	})
+	sub.SetHoldStream("held")
+	sub.Start()
+	defer sub.Stop()
+
+	first, second := <-got, <-got
+	c.Assert(first.Event.EventNumber, Equals, 1)
+	c.Assert(second.Event.EventNumber, Equals, 2)
+	c.Assert(string(*second.Event.Data.(*json.RawMessage)), Equals, `{"name":"x"}`)
+
+	eventually(func() bool { return sub.LastProcessed() == 2 })
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(held, HasLen, 1)
+	c.Assert(held[0]["eventId"], Equals, es[0].EventID)
+	c.Assert(held[0]["metadata"], DeepEquals, map[string]interface{}{"tenant": "t1", SchemaVersionMetaDataKey: 0.0})
+}
+
+func (s *SchemaSuite) TestSubscriptionStopsWithoutHoldStream(c *C) {
+	stream := "schema-stop"
+	er := versionedEventResponse("FooEvent", &FooEvent{Foo: "x"}, 0)
+	es := []*Event{CreateTestEvent(stream, server.URL, "FooEvent", 0,
+		er.Event.Data.(*json.RawMessage), er.Event.MetaData.(*json.RawMessage))}
+	u, _ := url.Parse(server.URL)
+	sim, _ := NewAtomFeedSimulator(es, u, nil, len(es))
+	mux.Handle("/streams/"+stream+"/", sim)
+
+	r := NewTypeRegistry()
+	r.SetMinSchemaVersion("FooEvent", 1)
+	client.SetTypeRegistry(r)
+
+	sub := client.NewCatchUpSubscription(stream, 0, func(er *EventResponse) error {
+		c.Error("Event delivered")
+		return nil
+	})
+	sub.Start()
+	<-sub.Done()
+	c.Assert(sub.Err(), DeepEquals, &ErrSchemaVersion{EventType: "FooEvent", Version: 0, MinVersion: 1})
+}
@@ -0,0 +1,196 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"container/list"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+)
+
+const (
+	// sessionPages is the number of feed pages cached by a session.
+	sessionPages = 64
+
+	// sessionEvents is the number of events cached by a session.
+	sessionEvents = 1024
+)
+
+// Session is a client for a short unit of work, such as serving one API
+// request, that makes several related reads.
+//
+// A session starts with the configuration of the client it was created from,
+// including its credentials, headers, codecs and type registry, and shares its
+// http client, any concurrency and rate limits and any wait asked for by a busy
+// server. Changes made to the session,
+// such as setting credentials with SetBasicAuth or pinning the session to a
+// node with SetNode, do not affect the client, and changes made to the client
+// after the session is created do not affect the session.
+//
+// A session caches the feed pages and events it reads, so readers created from
+// the session reuse the pages and events read by earlier readers instead of
+// requesting them again. Events are cached by the URL of the event, including
+// the events that link events in streams such as $ce-<category> resolve to.
+// Cached event responses are shared and should not be modified. The stream
+// metadata is not cached.
+//
+// A session should be closed when the unit of work is complete to release its
+// caches.
+type Session struct {
+	*Client
+}
+
+// NewSession returns a new *Session.
+func (c *Client) NewSession() *Session {
+	sc := c.derive()
+	sc.feedCache = NewLRUFeedCache(sessionPages)
+	sc.events = newEventCache(sessionEvents)
+	return &Session{Client: sc}
+}
+
+// SetNode sends the requests of the session to the node at serverURL rather
+// than the server of the client, so that consecutive reads see the same copy
+// of the database. Links in the feed pages returned by the node are absolute,
+// so pages and events reached by following them are also read from the node.
+func (s *Session) SetNode(serverURL string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = u
+	return nil
+}
+
+// Close releases the caches of the session. The session may still be used
+// after it is closed, without caching.
+func (s *Session) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feedCache = nil
+	s.events = nil
+}
+
+// derive returns a new client with a copy of the configuration of the client.
+func (c *Client) derive() *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	d := &Client{
+		client:        c.client,
+		credentials:   c.credentials,
+		trustedAuth:   c.trustedAuth,
+		headers:       make(map[string]string, len(c.headers)),
+		codecs:        make(map[string]Codec, len(c.codecs)),
+		sem:           c.sem,
+		bucket:        c.bucket,
+		registry:      c.registry,
+		compression:   c.compression,
+		compressAbove: c.compressAbove,
+		serverInfo:    c.serverInfo,
+		middleware:    c.middleware,
+		credProvider:  c.credProvider,
+		busy:          c.busy,
+		requireMaster: c.requireMaster,
+		preferMaster:  c.preferMaster,
+		master:        c.master,
+		debug:         c.debug,
+		feedFormat:    c.feedFormat,
+		rewriteLinks:  c.rewriteLinks,
+		listener:      c.listener,
+		nodes:         c.nodes,
+		strict:        c.strict,
+		maxBuffered:   c.maxBuffered,
+	}
+	u := *c.baseURL
+	d.baseURL = &u
+	for k, v := range c.headers {
+		d.headers[k] = v
+	}
+	for k, v := range c.codecs {
+		d.codecs[k] = v
+	}
+	if c.features != nil {
+		d.features = make(map[Feature]bool, len(c.features))
+		for k, v := range c.features {
+			d.features[k] = v
+		}
+	}
+	return d
+}
+
+// getEventCache returns the event cache of the client or nil.
+func (c *Client) getEventCache() *eventCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.events
+}
+
+// eventCache holds a limited number of event responses by URL and evicts the
+// least recently used.
+//
+// Only the URLs of numbered events are cached, as the event at such a URL never
+// changes.
+type eventCache struct {
+	mu     sync.Mutex
+	size   int
+	order  *list.List
+	events map[string]*list.Element
+}
+
+type eventCacheEntry struct {
+	url string
+	er  *EventResponse
+}
+
+func newEventCache(size int) *eventCache {
+	return &eventCache{
+		size:   size,
+		order:  list.New(),
+		events: make(map[string]*list.Element),
+	}
+}
+
+// cacheable returns true if the url identifies a numbered event.
+func (e *eventCache) cacheable(rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return false
+	}
+	_, err = strconv.Atoi(path.Base(u.Path))
+	return err == nil
+}
+
+func (e *eventCache) get(url string) (*EventResponse, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	el, ok := e.events[url]
+	if !ok {
+		return nil, false
+	}
+	e.order.MoveToFront(el)
+	return el.Value.(*eventCacheEntry).er, true
+}
+
+func (e *eventCache) add(url string, er *EventResponse) {
+	if !e.cacheable(url) {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.events[url]; ok {
+		return
+	}
+	e.events[url] = e.order.PushFront(&eventCacheEntry{url: url, er: er})
+	for e.order.Len() > e.size {
+		el := e.order.Back()
+		e.order.Remove(el)
+		delete(e.events, el.Value.(*eventCacheEntry).url)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SessionSuite{})
+
+type SessionSuite struct{}
+
+func (s *SessionSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *SessionSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// serveCounting serves the events and returns the number of requests made.
+func serveCounting(es []*Event) *int32 {
+	var n int32
+	sim := newTestSimulator(es, nil)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		sim.ServeHTTP(w, r)
+	})
+	return &n
+}
+
+func (s *SessionSuite) TestSessionReusesEvents(c *C) {
+	stream := "session-events"
+	es := CreateTestEvents(5, stream, server.URL, "Foo")
+	n := serveCounting(es)
+
+	sess := client.NewSession()
+	defer sess.Close()
+
+	c.Assert(readAll(c, sess.NewStreamReader(stream)), DeepEquals, sequence(5))
+	first := atomic.LoadInt32(n)
+
+	c.Assert(readAll(c, sess.NewStreamReader(stream)), DeepEquals, sequence(5))
+	second := atomic.LoadInt32(n) - first
+	c.Assert(second < first, Equals, true, Commentf("first %d, second %d", first, second))
+
+	er, resp, err := sess.GetEvent(server.URL + "/streams/" + stream + "/2")
+	c.Assert(err, IsNil)
+	c.Assert(resp, IsNil)
+	c.Assert(er.Event.EventID, Equals, es[2].EventID)
+}
+
+func (s *SessionSuite) TestClientDoesNotCacheEvents(c *C) {
+	stream := "session-client"
+	es := CreateTestEvents(1, stream, server.URL, "Foo")
+	serveCounting(es)
+
+	_, resp, err := client.GetEvent(server.URL + "/streams/" + stream + "/0")
+	c.Assert(err, IsNil)
+	c.Assert(resp, NotNil)
+	_, resp, err = client.GetEvent(server.URL + "/streams/" + stream + "/0")
+	c.Assert(err, IsNil)
+	c.Assert(resp, NotNil)
+}
+
+func (s *SessionSuite) TestClosedSessionDoesNotCache(c *C) {
+	stream := "session-closed"
+	es := CreateTestEvents(1, stream, server.URL, "Foo")
+	serveCounting(es)
+
+	sess := client.NewSession()
+	u := server.URL + "/streams/" + stream + "/0"
+	_, _, err := sess.GetEvent(u)
+	c.Assert(err, IsNil)
+
+	sess.Close()
+	_, resp, err := sess.GetEvent(u)
+	c.Assert(err, IsNil)
+	c.Assert(resp, NotNil)
+}
+
+func (s *SessionSuite) TestSessionCredentialsDoNotAffectClient(c *C) {
+	users := make(chan string, 2)
+	mux.HandleFunc("/streams/auth/0", func(w http.ResponseWriter, r *http.Request) {
+		u, _, _ := r.BasicAuth()
+		users <- u
+		http.NotFound(w, r)
+	})
+
+	client.SetBasicAuth("admin", "changeit")
+	sess := client.NewSession()
+	sess.SetBasicAuth("alice", "secret")
+
+	sess.GetEvent("/streams/auth/0")
+	client.GetEvent("/streams/auth/0")
+	c.Assert(<-users, Equals, "alice")
+	c.Assert(<-users, Equals, "admin")
+}
+
+func (s *SessionSuite) TestSetNode(c *C) {
+	hits := make(chan string, 1)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits <- r.URL.Path
+		http.NotFound(w, r)
+	}))
+	defer node.Close()
+
+	sess := client.NewSession()
+	c.Assert(sess.SetNode(node.URL), IsNil)
+	sess.GetEvent("/streams/pinned/0")
+	c.Assert(<-hits, Equals, "/streams/pinned/0")
+	c.Assert(client.baseURL.String(), Equals, server.URL)
+}
+
+func (s *SessionSuite) TestEventCacheOnlyCachesNumberedEvents(c *C) {
+	cache := newEventCache(2)
+	cache.add("http://x/streams/foo/metadata", &EventResponse{})
+	_, ok := cache.get("http://x/streams/foo/metadata")
+	c.Assert(ok, Equals, false)
+
+	for _, u := range []string{"http://x/streams/foo/0", "http://x/streams/foo/1", "http://x/streams/foo/2"} {
+		cache.add(u, &EventResponse{})
+	}
+	_, ok = cache.get("http://x/streams/foo/0")
+	c.Assert(ok, Equals, false)
+	_, ok = cache.get("http://x/streams/foo/2")
+	c.Assert(ok, Equals, true)
+}
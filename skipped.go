@@ -0,0 +1,61 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SkippedEvent describes an event that a StreamReader skipped because it
+// could not be read, usually because it was scavenged after expiring through
+// the maximum age or maximum count of the stream.
+//
+// EventNumber is the event number of the event, or -1 if it could not be
+// determined from URL. Err is the error returned when reading the event.
+type SkippedEvent struct {
+	Stream      string
+	EventNumber int
+	URL         string
+	Err         error
+}
+
+// SetSkippedEventHandler makes the reader skip events that are listed in the
+// feed of the stream but are not found when read, rather than returning an
+// *ErrNotFound and stopping, and calls fn with each event skipped. fn is
+// called from the goroutine calling Next, before Next returns the event that
+// follows. Setting fn to nil restores the default.
+//
+// Missing events are also skipped, without a handler, once metadata that
+// removes events has been loaded with LoadMetaData.
+func (s *StreamReader) SetSkippedEventHandler(fn func(*SkippedEvent)) {
+	s.stopPrefetch()
+	s.skipped = fn
+}
+
+// skipsMissing returns true if events that are not found are skipped.
+func (s *StreamReader) skipsMissing() bool {
+	return s.skipped != nil || s.metadata.removesEvents()
+}
+
+// reportSkipped calls the skipped event handler, if any, with the event.
+func (s *StreamReader) reportSkipped(e *SkippedEvent) {
+	e.Stream = s.streamName
+	if s.skipped != nil {
+		s.skipped(e)
+	}
+}
+
+// eventNumberFromURL returns the event number at the end of an event url, or
+// -1 if the url does not end with one.
+func eventNumberFromURL(url string) int {
+	url = strings.TrimRight(url, "/")
+	n, err := strconv.Atoi(url[strings.LastIndex(url, "/")+1:])
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SkippedEventSuite{})
+
+type SkippedEventSuite struct{}
+
+func (s *SkippedEventSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *SkippedEventSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// scavenged serves a stream of n events of which those numbered missing are
+// not found.
+func scavenged(stream string, n int, missing ...int) {
+	setupSimulator(CreateTestEvents(n, stream, server.URL, "Foo"), nil)
+	for _, m := range missing {
+		mux.HandleFunc(fmt.Sprintf("/streams/%s/%d", stream, m), func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+	}
+}
+
+func (s *SkippedEventSuite) TestMissingEventsAreSkippedAndReported(c *C) {
+	scavenged("scavenged", 30, 2, 3, 25)
+
+	for _, prefetch := range []int{0, 4} {
+		skipped := []int{}
+		reader := client.NewStreamReader("scavenged")
+		reader.Prefetch(prefetch)
+		reader.SetSkippedEventHandler(func(e *SkippedEvent) {
+			c.Check(e.Stream, Equals, "scavenged")
+			c.Check(e.URL, Equals, fmt.Sprintf("%s/streams/scavenged/%d", server.URL, e.EventNumber))
+			c.Check(e.Err, FitsTypeOf, &ErrNotFound{})
+			skipped = append(skipped, e.EventNumber)
+		})
+
+		read := 0
+		for reader.Next() && reader.Err() == nil {
+			read++
+			if n := reader.EventResponse().Event.EventNumber; n == 4 {
+				c.Assert(skipped, DeepEquals, []int{2, 3})
+				c.Assert(reader.Version(), Equals, 4)
+			}
+		}
+		reader.Close()
+		c.Assert(reader.Err(), FitsTypeOf, &ErrNoMoreEvents{})
+		c.Assert(read, Equals, 27)
+		c.Assert(skipped, DeepEquals, []int{2, 3, 25})
+	}
+}
+
+func (s *SkippedEventSuite) TestMissingEventsStopTheReadByDefault(c *C) {
+	scavenged("scavenged", 5, 1)
+
+	reader := client.NewStreamReader("scavenged")
+	reader.Next()
+	c.Assert(reader.Err(), IsNil)
+	reader.Next()
+	c.Assert(reader.Err(), FitsTypeOf, &ErrNotFound{})
+}
+
+func (s *SkippedEventSuite) TestEventNumberFromURL(c *C) {
+	c.Assert(eventNumberFromURL("http://localhost:2113/streams/foo/12/"), Equals, 12)
+	c.Assert(eventNumberFromURL("http://localhost:2113/streams/foo/head"), Equals, -1)
+}
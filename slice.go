@@ -0,0 +1,164 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// ReadDirection is the direction of a read of a stream.
+type ReadDirection string
+
+const (
+	// ReadForward reads from older events towards the head of the stream.
+	ReadForward ReadDirection = "forward"
+
+	// ReadBackward reads from the head of the stream towards older events.
+	ReadBackward ReadDirection = "backward"
+)
+
+// StreamSlice is the result of reading a range of events from a stream, as
+// returned by the official clients of the eventstore.
+//
+// Events are in the order of the read, so oldest first when reading forward
+// and most recent first when reading backward. FromEventNumber is the event
+// number the read started from and NextEventNumber is the event number to
+// start the next read in the same direction from. LastEventNumber is the event
+// number of the most recent event in the stream, or -1 if the stream has no
+// events. IsEndOfStream is true when there are no more events in the direction
+// of the read: forward when the head of the stream has been read, backward when
+// the first event has been read.
+type StreamSlice struct {
+	Stream          string
+	Direction       ReadDirection
+	FromEventNumber int
+	Events          []*EventResponse
+	NextEventNumber int
+	LastEventNumber int
+	IsEndOfStream   bool
+}
+
+// ReadStreamEventsForward reads up to count events of the stream starting at
+// the event number start.
+//
+// count must be between 1 and MaxPageSize. Events can be filtered by type with
+// WithEventTypes; events filtered out are not returned but are still counted.
+// WithPageSize has no effect. If the stream does not exist an *ErrNotFound is
+// returned.
+func (c *Client) ReadStreamEventsForward(stream string, start, count int, opts ...ReadOption) (*StreamSlice, error) {
+	if start < 0 {
+		start = 0
+	}
+	o := newReadOptions(opts)
+	return c.readSlice(o.context(), stream, ReadForward, start, count, o)
+}
+
+// ReadStreamEventsBackward reads up to count events of the stream starting at
+// the event number start, or at the head of the stream if start is negative,
+// and continuing towards the first event. It is otherwise the same as
+// ReadStreamEventsForward.
+func (c *Client) ReadStreamEventsBackward(stream string, start, count int, opts ...ReadOption) (*StreamSlice, error) {
+	if start < 0 {
+		start = -1
+	}
+	o := newReadOptions(opts)
+	return c.readSlice(o.context(), stream, ReadBackward, start, count, o)
+}
+
+// readSlice reads a page of the stream and the events it links to.
+func (c *Client) readSlice(ctx context.Context, stream string, dir ReadDirection, start, count int, o *readOptions) (*StreamSlice, error) {
+	u, err := c.GetFeedPath(stream, string(dir), start, count)
+	if err != nil {
+		return nil, err
+	}
+	f, _, err := c.readFeed(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	slice := &StreamSlice{
+		Stream:          stream,
+		Direction:       dir,
+		FromEventNumber: start,
+		Events:          []*EventResponse{},
+	}
+
+	// Entries are ordered most recent first, so they are read in reverse when
+	// reading forward.
+	lowest, highest := -1, -1
+	for i := range f.Entry {
+		e := f.Entry[i]
+		if dir == ReadForward {
+			e = f.Entry[len(f.Entry)-1-i]
+		}
+		href := strings.TrimRight(e.Link[1].Href, "/")
+		n, err := strconv.Atoi(href[strings.LastIndex(href, "/")+1:])
+		if err != nil {
+			return nil, err
+		}
+		if lowest < 0 || n < lowest {
+			lowest = n
+		}
+		if n > highest {
+			highest = n
+		}
+		if o.eventTypes.skipsEntry(e) {
+			continue
+		}
+		er, err := c.entryEvent(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		if er != nil && !o.eventTypes.skipsEvent(er) {
+			slice.Events = append(slice.Events, er)
+		}
+	}
+
+	// The head of the stream is known from the page when it includes the most
+	// recent event, otherwise it is read.
+	slice.LastEventNumber = highest
+	if !f.HeadOfStream || highest < 0 {
+		slice.LastEventNumber, err = c.lastEventNumber(ctx, stream)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch dir {
+	case ReadForward:
+		slice.NextEventNumber = start
+		if highest >= 0 {
+			slice.NextEventNumber = highest + 1
+		}
+		slice.IsEndOfStream = slice.NextEventNumber > slice.LastEventNumber
+	case ReadBackward:
+		slice.NextEventNumber = -1
+		if lowest > 0 {
+			slice.NextEventNumber = lowest - 1
+		}
+		slice.IsEndOfStream = slice.NextEventNumber < 0
+	}
+	return slice, nil
+}
+
+// lastEventNumber returns the event number of the most recent event in the
+// stream, or -1 if the stream has no events.
+func (c *Client) lastEventNumber(ctx context.Context, stream string) (int, error) {
+	u, err := c.GetFeedPath(stream, "backward", -1, 1)
+	if err != nil {
+		return -1, err
+	}
+	f, _, err := c.readFeed(ctx, u)
+	if err != nil {
+		return -1, err
+	}
+	if len(f.Entry) == 0 {
+		return -1, nil
+	}
+	return eventNumberFromURL(f.Entry[0].Link[1].Href), nil
+}
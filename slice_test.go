@@ -0,0 +1,80 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SliceSuite{})
+
+type SliceSuite struct{}
+
+func (s *SliceSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *SliceSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// sliceNumbers returns the event numbers of the events of the slice.
+func sliceNumbers(slice *StreamSlice) []int {
+	ns := []int{}
+	for _, e := range slice.Events {
+		ns = append(ns, e.Event.EventNumber)
+	}
+	return ns
+}
+
+func (s *SliceSuite) TestReadStreamEventsForward(c *C) {
+	stream := "slice-forward"
+	setupSimulator(CreateTestEvents(10, stream, server.URL, "Foo"), nil)
+
+	slice, err := client.ReadStreamEventsForward(stream, 0, 4)
+	c.Assert(err, IsNil)
+	c.Assert(sliceNumbers(slice), DeepEquals, []int{0, 1, 2, 3})
+	c.Assert(slice.Direction, Equals, ReadForward)
+	c.Assert(slice.FromEventNumber, Equals, 0)
+	c.Assert(slice.NextEventNumber, Equals, 4)
+	c.Assert(slice.LastEventNumber, Equals, 9)
+	c.Assert(slice.IsEndOfStream, Equals, false)
+
+	slice, err = client.ReadStreamEventsForward(stream, 8, 4)
+	c.Assert(err, IsNil)
+	c.Assert(sliceNumbers(slice), DeepEquals, []int{8, 9})
+	c.Assert(slice.NextEventNumber, Equals, 10)
+	c.Assert(slice.LastEventNumber, Equals, 9)
+	c.Assert(slice.IsEndOfStream, Equals, true)
+}
+
+func (s *SliceSuite) TestReadStreamEventsBackward(c *C) {
+	stream := "slice-backward"
+	setupSimulator(CreateTestEvents(10, stream, server.URL, "Foo"), nil)
+
+	slice, err := client.ReadStreamEventsBackward(stream, -1, 4)
+	c.Assert(err, IsNil)
+	c.Assert(sliceNumbers(slice), DeepEquals, []int{9, 8, 7, 6})
+	c.Assert(slice.Direction, Equals, ReadBackward)
+	c.Assert(slice.FromEventNumber, Equals, -1)
+	c.Assert(slice.NextEventNumber, Equals, 5)
+	c.Assert(slice.LastEventNumber, Equals, 9)
+	c.Assert(slice.IsEndOfStream, Equals, false)
+
+	slice, err = client.ReadStreamEventsBackward(stream, 2, 4)
+	c.Assert(err, IsNil)
+	c.Assert(sliceNumbers(slice), DeepEquals, []int{2, 1, 0})
+	c.Assert(slice.NextEventNumber, Equals, -1)
+	c.Assert(slice.LastEventNumber, Equals, 9)
+	c.Assert(slice.IsEndOfStream, Equals, true)
+}
+
+func (s *SliceSuite) TestReadStreamEventsOfMissingStream(c *C) {
+	_, err := client.ReadStreamEventsForward("slice-missing", 0, 4)
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+
+	_, err = client.ReadStreamEventsForward("slice-missing", 0, MaxPageSize+1)
+	c.Assert(err, ErrorMatches, "Invalid page size.*")
+}
@@ -0,0 +1,65 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+// snapshotMetaData is the metadata written with each snapshot. Version is the
+// event number of the last event in the source stream reflected in the snapshot.
+type snapshotMetaData struct {
+	Version int `json:"version"`
+}
+
+// SnapshotStreamName returns the name of the stream that snapshots of the
+// stream are written to.
+func SnapshotStreamName(stream string) string {
+	return stream + "-snapshots"
+}
+
+// WriteSnapshot writes a snapshot of state for the stream.
+//
+// version is the event number of the last event in the stream that is reflected
+// in the state. state is serialized to JSON.
+//
+// Snapshots are written to the stream named by SnapshotStreamName. When the
+// snapshot stream is created its $maxCount is set to 1 so that only the latest
+// snapshot is retained.
+func (c *Client) WriteSnapshot(stream string, version int, state interface{}) error {
+	name := SnapshotStreamName(stream)
+	writer := c.NewStreamWriter(name)
+	snap := NewEvent("", "Snapshot", state, &snapshotMetaData{Version: version})
+
+	err := writer.Append(ExpectNoStream, snap)
+	if _, ok := err.(*ErrConcurrencyViolation); ok {
+		return writer.Append(ExpectAny, snap)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writer.WriteMetaData(name, map[string]int{"$maxCount": 1})
+}
+
+// ReadLatestSnapshot reads the latest snapshot for the stream into state and
+// returns the version of the snapshot.
+//
+// Replay of the stream can resume from the version returned plus one. If there
+// is no snapshot the version returned is -1 and state is unchanged. state may
+// be nil if only the version is needed.
+func (c *Client) ReadLatestSnapshot(stream string, state interface{}) (int, error) {
+	er, err := c.ReadLast(SnapshotStreamName(stream), state)
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound, *ErrNoMoreEvents:
+		return -1, nil
+	default:
+		return -1, err
+	}
+
+	m := &snapshotMetaData{}
+	if err := c.decodeEvent(er, nil, m); err != nil {
+		return -1, err
+	}
+	return m.Version, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SnapshotSuite{})
+
+type SnapshotSuite struct{}
+
+func (s *SnapshotSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *SnapshotSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *SnapshotSuite) TestWriteSnapshotCreatesStreamWithMaxCount(c *C) {
+	stream := "snapped-snapshots"
+	var expected []string
+	var written []Event
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		expected = append(expected, r.Header.Get("ES-ExpectedVersion"))
+		c.Assert(json.NewDecoder(r.Body).Decode(&written), IsNil)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	path := fmt.Sprintf("/streams/%s/0/forward/1", stream)
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		es := CreateTestEvents(1, stream, server.URL, "Snapshot")
+		f, _ := CreateTestFeed(es, server.URL+path)
+		fmt.Fprint(w, f.PrettyPrint())
+	})
+
+	var meta map[string]int
+	mux.HandleFunc("/streams/"+stream+"/metadata", func(w http.ResponseWriter, r *http.Request) {
+		decodeWrittenMetaData(c, r, &meta)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := client.WriteSnapshot("snapped", 41, map[string]int{"total": 3})
+	c.Assert(err, IsNil)
+	c.Assert(expected, DeepEquals, []string{"-1"})
+	c.Assert(written, HasLen, 1)
+	c.Assert(written[0].EventType, Equals, "Snapshot")
+	c.Assert(written[0].MetaData, DeepEquals, map[string]interface{}{"version": float64(41)})
+	c.Assert(meta, DeepEquals, map[string]int{"$maxCount": 1})
+}
+
+func (s *SnapshotSuite) TestWriteSnapshotToExistingStream(c *C) {
+	var expected []string
+	mux.HandleFunc("/streams/existing-snapshots", func(w http.ResponseWriter, r *http.Request) {
+		expected = append(expected, r.Header.Get("ES-ExpectedVersion"))
+		if r.Header.Get("ES-ExpectedVersion") == "-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := client.WriteSnapshot("existing", 3, map[string]int{"total": 3})
+	c.Assert(err, IsNil)
+	c.Assert(expected, DeepEquals, []string{"-1", ""})
+}
+
+func (s *SnapshotSuite) TestReadLatestSnapshot(c *C) {
+	stream := SnapshotStreamName("read")
+	es := []*Event{
+		CreateTestEventFromData(stream, server.URL, 0, &testAccount{Balance: 1}, &snapshotMetaData{Version: 9}),
+		CreateTestEventFromData(stream, server.URL, 1, &testAccount{Balance: 2}, &snapshotMetaData{Version: 19}),
+	}
+	setupSimulator(es, nil)
+
+	state := &testAccount{}
+	version, err := client.ReadLatestSnapshot("read", state)
+	c.Assert(err, IsNil)
+	c.Assert(version, Equals, 19)
+	c.Assert(state.Balance, Equals, 2)
+}
+
+func (s *SnapshotSuite) TestReadLatestSnapshotWithNoSnapshot(c *C) {
+	mux.HandleFunc("/streams/none-snapshots/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	state := &testAccount{Balance: 5}
+	version, err := client.ReadLatestSnapshot("none", state)
+	c.Assert(err, IsNil)
+	c.Assert(version, Equals, -1)
+	c.Assert(state.Balance, Equals, 5)
+}
@@ -0,0 +1,248 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshotter reads and writes the snapshots of the aggregates in streams.
+//
+// SnapshotVersion returns the event number of the last event in the stream
+// reflected in the latest snapshot, or -1 if there is no snapshot. Snapshot
+// folds the events of the stream into the aggregate, starting from its latest
+// snapshot, writes a new snapshot and returns the version of the snapshot
+// written.
+type Snapshotter interface {
+	SnapshotVersion(stream string) (int, error)
+	Snapshot(stream string) (int, error)
+}
+
+// SnapshotRefreshReport is the result of a snapshot refresh.
+//
+// Checked is the number of streams in the category that were checked.
+// Refreshed contains the version of the snapshot written for each stream that
+// was refreshed, by stream name. Errors contains any errors that occurred for
+// individual streams by stream name.
+type SnapshotRefreshReport struct {
+	Checked   int
+	Refreshed map[string]int
+	Errors    map[string]error
+}
+
+// SnapshotRefresher writes fresh snapshots of the aggregates in a category
+// whose latest snapshot is too far behind their stream.
+//
+// Streams are enumerated by reading the $streams stream, which is maintained by
+// the $streams system projection. The projection must be running for the
+// refresher to find streams. Snapshot streams, named by SnapshotStreamName,
+// are ignored. The snapshots are read and written by the Snapshotter provided,
+// usually a Repository.
+//
+// A SnapshotRefresher is safe for concurrent use.
+type SnapshotRefresher struct {
+	client      *Client
+	category    string
+	snapshotter Snapshotter
+	mu          sync.Mutex
+	threshold   int
+	concurrency int
+	stop        chan struct{}
+	wg          sync.WaitGroup
+	reporter    ErrorReporter
+}
+
+// NewSnapshotRefresher returns a new *SnapshotRefresher for the aggregates in
+// the category.
+func (c *Client) NewSnapshotRefresher(category string, snapshotter Snapshotter) *SnapshotRefresher {
+	return &SnapshotRefresher{
+		client:      c,
+		category:    category,
+		snapshotter: snapshotter,
+		concurrency: 1,
+	}
+}
+
+// SetThreshold sets the number of events an aggregate's stream may have after
+// its latest snapshot before the snapshot is refreshed. The default is 0, so
+// that any stream with events after its latest snapshot is refreshed.
+func (s *SnapshotRefresher) SetThreshold(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.threshold = n
+}
+
+// SetConcurrency sets the number of streams checked and refreshed at once. The
+// default is 1.
+func (s *SnapshotRefresher) SetConcurrency(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.concurrency = n
+}
+
+// Refresh checks every stream in the category and refreshes the snapshots that
+// lag by more than the threshold.
+//
+// An error is returned if the streams cannot be enumerated. Errors reading
+// streams or refreshing snapshots are reported in the report. Streams not yet
+// started when ctx is cancelled are not checked.
+func (s *SnapshotRefresher) Refresh(ctx context.Context) (*SnapshotRefreshReport, error) {
+	streams, err := s.client.listStreams()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	threshold := s.threshold
+	workers := s.concurrency
+	s.mu.Unlock()
+	if workers < 1 {
+		workers = 1
+	}
+
+	report := &SnapshotRefreshReport{
+		Refreshed: make(map[string]int),
+		Errors:    make(map[string]error),
+	}
+	var mu sync.Mutex
+
+	names := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for stream := range names {
+				version, refreshed, err := s.refresh(stream, threshold)
+				mu.Lock()
+				report.Checked++
+				switch {
+				case err != nil:
+					report.Errors[stream] = err
+				case refreshed:
+					report.Refreshed[stream] = version
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, stream := range streams {
+		if !strings.HasPrefix(stream, s.category+"-") || strings.HasSuffix(stream, SnapshotStreamName("")) {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		names <- stream
+	}
+	close(names)
+	wg.Wait()
+
+	return report, nil
+}
+
+// Start runs Refresh every interval until Stop is called. report is called
+// with the result of each refresh, and its errors are also sent on the Errors
+// channel.
+func (s *SnapshotRefresher) Start(interval time.Duration, report func(*SnapshotRefreshReport, error)) {
+	s.mu.Lock()
+	if s.stop != nil {
+		s.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	s.stop = stop
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			rep, err := s.Refresh(context.Background())
+			var errs map[string]error
+			if rep != nil {
+				errs = rep.Errors
+			}
+			s.reporter.reportRun("Snapshot refresher of "+s.category, err, errs)
+			report(rep, err)
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops a refresher started with Start and waits for any refresh in
+// progress to complete.
+func (s *SnapshotRefresher) Stop() {
+	s.mu.Lock()
+	stop := s.stop
+	s.stop = nil
+	s.mu.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+	s.wg.Wait()
+}
+
+// refresh writes a snapshot of the aggregate in the stream if the stream has
+// more than threshold events after its latest snapshot. It returns the version
+// of the snapshot written and true if the snapshot was refreshed.
+func (s *SnapshotRefresher) refresh(stream string, threshold int) (int, bool, error) {
+	er, err := s.client.ReadLast(stream, nil)
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound, *ErrDeleted, *ErrNoMoreEvents:
+		return -1, false, nil
+	default:
+		return -1, false, err
+	}
+
+	snapshot, err := s.snapshotter.SnapshotVersion(stream)
+	if err != nil {
+		return -1, false, err
+	}
+	if er.Event.EventNumber-snapshot <= threshold {
+		return -1, false, nil
+	}
+
+	version, err := s.snapshotter.Snapshot(stream)
+	if err != nil {
+		return -1, false, err
+	}
+	return version, true, nil
+}
+
+// listStreams returns the names of all streams listed in the $streams stream
+// in sorted order.
+func (c *Client) listStreams() ([]string, error) {
+	seen := make(map[string]bool)
+	reader := c.NewStreamReader("$streams")
+	for reader.Next() {
+		if reader.Err() != nil {
+			if _, ok := reader.Err().(*ErrNoMoreEvents); ok {
+				break
+			}
+			return nil, reader.Err()
+		}
+		seen[reader.EventResponse().Event.EventStreamID] = true
+	}
+
+	ret := make([]string, 0, len(seen))
+	for s := range seen {
+		ret = append(ret, s)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SnapshotRefreshSuite{})
+
+type SnapshotRefreshSuite struct{}
+
+func (s *SnapshotRefreshSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *SnapshotRefreshSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// memorySnapshotter holds the versions of snapshots in memory. Snapshot takes
+// a snapshot at the version of the last event in the stream. Streams in fail
+// return the error provided.
+type memorySnapshotter struct {
+	mu       sync.Mutex
+	versions map[string]int
+	taken    int
+	fail     map[string]error
+}
+
+func (m *memorySnapshotter) SnapshotVersion(stream string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.versions[stream]; ok {
+		return v, nil
+	}
+	return -1, nil
+}
+
+func (m *memorySnapshotter) Snapshot(stream string) (int, error) {
+	if err := m.fail[stream]; err != nil {
+		return -1, err
+	}
+	er, err := client.ReadLast(stream, nil)
+	if err != nil {
+		return -1, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[stream] = er.Event.EventNumber
+	m.taken++
+	return er.Event.EventNumber, nil
+}
+
+// serveStreamsOf serves a $streams stream linking to the first event of
+// each of the streams provided.
+func serveStreamsOf(names ...string) {
+	es := CreateTestEvents(len(names), "$streams", server.URL, "$>")
+	for i, name := range names {
+		es[i].Links = CreateTestEvent(name, server.URL, "Foo", 0, nil, nil).Links
+	}
+	setupSimulator(es, nil)
+}
+
+// serveCategoryStreams serves a $streams stream listing the streams provided,
+// and the streams, which contain the number of events given.
+func serveCategoryStreams(streams map[string]int) {
+	names := []string{}
+	for name, n := range streams {
+		names = append(names, name)
+		mux.Handle("/streams/"+name+"/", newTestSimulator(CreateTestEvents(n, name, server.URL, "Foo"), nil))
+	}
+	serveStreamsOf(names...)
+}
+
+// serveAccount serves an account stream of n deposits. If snapshot is not -1
+// the snapshot stream of the account holds a snapshot of the account at that
+// version. The snapshots written to the stream are returned by written.
+func serveAccount(c *C, stream string, n, snapshot int) (written func() []map[string]interface{}) {
+	mux.Handle("/streams/"+stream+"/", newTestSimulator(depositEvents(stream, n), nil))
+
+	name := SnapshotStreamName(stream)
+	var sim http.Handler = http.NotFoundHandler()
+	if snapshot >= 0 {
+		balance := (snapshot + 1) * (snapshot + 2) / 2
+		sim = newTestSimulator([]*Event{
+			CreateTestEventFromData(name, server.URL, 0, &testAccount{Balance: balance}, &snapshotMetaData{Version: snapshot}),
+		}, nil)
+	}
+
+	var mu sync.Mutex
+	snapshots := []map[string]interface{}{}
+	h := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sim.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("ES-ExpectedVersion") == "-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batch := []map[string]interface{}{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&batch), IsNil)
+		mu.Lock()
+		snapshots = append(snapshots, batch...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}
+	mux.HandleFunc("/streams/"+name, h)
+	mux.HandleFunc("/streams/"+name+"/", h)
+
+	return func() []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return snapshots
+	}
+}
+
+func (s *SnapshotRefreshSuite) TestRefreshSnapshotsLaggingStreams(c *C) {
+	serveCategoryStreams(map[string]int{"account-1": 25, "account-2": 25, "account-3": 8, "user-1": 3})
+	snapshotter := &memorySnapshotter{versions: map[string]int{"account-1": 19, "account-2": 22}}
+
+	refresher := client.NewSnapshotRefresher("account", snapshotter)
+	refresher.SetThreshold(2)
+	refresher.SetConcurrency(3)
+
+	report, err := refresher.Refresh(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(report.Checked, Equals, 3)
+	c.Assert(report.Errors, HasLen, 0)
+	c.Assert(report.Refreshed, DeepEquals, map[string]int{"account-1": 24, "account-3": 7})
+	c.Assert(snapshotter.taken, Equals, 2)
+	c.Assert(snapshotter.versions["account-2"], Equals, 22)
+}
+
+func (s *SnapshotRefreshSuite) TestRefreshSnapshotsThroughRepository(c *C) {
+	serveStreamsOf("account-1", "account-1-snapshots", "account-2")
+	written1 := serveAccount(c, "account-1", 25, 19)
+	written2 := serveAccount(c, "account-2", 8, 7)
+
+	repo := client.NewRepository(newTestAccount)
+	report, err := client.NewSnapshotRefresher("account", repo).Refresh(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(report.Checked, Equals, 2)
+	c.Assert(report.Errors, HasLen, 0)
+	c.Assert(report.Refreshed, DeepEquals, map[string]int{"account-1": 24})
+
+	c.Assert(written1(), HasLen, 1)
+	snap := written1()[0]
+	c.Assert(snap["data"].(map[string]interface{})["Balance"], Equals, float64(325))
+	c.Assert(snap["data"].(map[string]interface{})["Applied"], Equals, float64(5))
+	c.Assert(snap["metadata"].(map[string]interface{})["version"], Equals, float64(24))
+	c.Assert(written2(), HasLen, 0)
+}
+
+func (s *SnapshotRefreshSuite) TestRefreshReportsStreamErrors(c *C) {
+	serveCategoryStreams(map[string]int{"account-1": 3, "account-2": 3})
+	failed := errors.New("snapshot failed")
+	snapshotter := &memorySnapshotter{
+		versions: map[string]int{},
+		fail:     map[string]error{"account-2": failed},
+	}
+
+	report, err := client.NewSnapshotRefresher("account", snapshotter).Refresh(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(report.Checked, Equals, 2)
+	c.Assert(report.Refreshed, DeepEquals, map[string]int{"account-1": 2})
+	c.Assert(report.Errors, DeepEquals, map[string]error{"account-2": failed})
+}
+
+func (s *SnapshotRefreshSuite) TestStartRefreshesUntilStopped(c *C) {
+	serveCategoryStreams(map[string]int{"account-1": 3})
+	snapshotter := &memorySnapshotter{versions: map[string]int{}}
+
+	refresher := client.NewSnapshotRefresher("account", snapshotter)
+	reports := make(chan *SnapshotRefreshReport, 10)
+	refresher.Start(time.Hour, func(report *SnapshotRefreshReport, err error) {
+		c.Check(err, IsNil)
+		reports <- report
+	})
+
+	select {
+	case report := <-reports:
+		c.Assert(report.Refreshed, DeepEquals, map[string]int{"account-1": 2})
+	case <-time.After(time.Second):
+		c.Fatal("refresh did not run")
+	}
+	refresher.Stop()
+	c.Assert(snapshotter.taken, Equals, 1)
+}
@@ -0,0 +1,309 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultLeaseTTL is the time after which a lease that has not been renewed
+	// can be taken over.
+	defaultLeaseTTL = 10 * time.Second
+
+	// leaseHistory is the $maxCount set on lease streams.
+	leaseHistory = 10
+)
+
+// lease is the data of an event in a lease stream.
+//
+// Holder is the instance holding the lease, or empty if the lease has been
+// released. Checkpoint is the position in the stream of the last event processed
+// by the holder when the lease was last written.
+type lease struct {
+	Holder     string `json:"holder"`
+	Checkpoint int    `json:"checkpoint"`
+}
+
+// LeaseStreamName returns the name of the stream used by the instances of a
+// standby consumer to coordinate.
+func LeaseStreamName(consumer string) string {
+	return "lease-" + consumer
+}
+
+// StandbyConsumer is one instance of an active/passive pair, or larger group,
+// of consumers of a stream.
+//
+// The instances of a consumer coordinate through a lease stream so that only
+// the instance holding the lease processes events. The other instances stay
+// warm, checking the lease, and one of them takes over if the holder stops
+// renewing it. A lease is acquired or renewed by appending to the lease stream
+// with the expected version of the lease that was read, so only one instance
+// can succeed.
+//
+// The holder renews the lease every renewal interval, recording the event
+// number of the last event it processed. An instance that takes over starts
+// processing after that checkpoint, so events processed after the final renewal
+// of a failed holder are processed again. Handlers should therefore be
+// idempotent. When a holder is stopped with Stop it releases the lease with its
+// final checkpoint and a standby takes over at its next check.
+//
+// An instance takes over a lease that it has seen unchanged for the lease TTL,
+// measured by its own clock, so clocks do not need to be synchronized. The
+// holder stops processing if it cannot renew the lease within the TTL or finds
+// that the lease is held by another instance. After the holder fails a standby
+// takes over within the TTL plus the renewal interval.
+type StandbyConsumer struct {
+	client      *Client
+	consumer    string
+	instance    string
+	stream      string
+	handler     func(*EventResponse) error
+	ttl         time.Duration
+	renew       time.Duration
+	activated   func(from int)
+	deactivated func()
+	mu          sync.Mutex
+	active      bool
+	err         error
+	reporter    ErrorReporter
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewStandbyConsumer returns an instance of the consumer named consumer that
+// delivers the events in the stream to handler while it holds the lease.
+//
+// instance must be unique among the instances of the consumer.
+func (c *Client) NewStandbyConsumer(consumer, instance, stream string, handler func(*EventResponse) error) *StandbyConsumer {
+	return &StandbyConsumer{
+		client:   c,
+		consumer: consumer,
+		instance: instance,
+		stream:   stream,
+		handler:  handler,
+		ttl:      defaultLeaseTTL,
+		renew:    defaultLeaseTTL / 3,
+	}
+}
+
+// SetLease sets the lease TTL and the interval at which the lease is renewed
+// by the holder and checked by the standby instances. renew should be well
+// under ttl. The defaults are 10 seconds and a third of the TTL.
+func (s *StandbyConsumer) SetLease(ttl, renew time.Duration) {
+	s.ttl = ttl
+	s.renew = renew
+}
+
+// SetActivatedHandler sets a function that is called when the instance
+// acquires the lease, with the event number of the first event it will process.
+func (s *StandbyConsumer) SetActivatedHandler(fn func(from int)) {
+	s.activated = fn
+}
+
+// SetDeactivatedHandler sets a function that is called when the instance stops
+// processing events because it lost or released the lease.
+func (s *StandbyConsumer) SetDeactivatedHandler(fn func()) {
+	s.deactivated = fn
+}
+
+// IsActive returns true if the instance holds the lease and is processing
+// events.
+func (s *StandbyConsumer) IsActive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Err returns the error that stopped the instance, or nil.
+func (s *StandbyConsumer) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Start starts the instance. It processes events when it holds the lease and
+// otherwise stays on standby.
+func (s *StandbyConsumer) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.err = nil
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops the instance. If it holds the lease it stops processing events
+// and releases the lease.
+func (s *StandbyConsumer) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop = nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Done returns a channel that is closed when the instance stops.
+func (s *StandbyConsumer) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// run checks or renews the lease every renewal interval until stop is closed.
+func (s *StandbyConsumer) run(stop, done chan struct{}) {
+	defer close(done)
+
+	var sub *Subscription
+	version := -1
+	renewed := time.Time{}
+
+	// seen and seenAt record the version of the lease last read while on
+	// standby and when it was first seen.
+	seen, seenAt := -2, time.Time{}
+
+	deactivate := func() {
+		sub.Stop()
+		sub = nil
+		s.setActive(false)
+		if s.deactivated != nil {
+			s.deactivated()
+		}
+	}
+
+	ticker := time.NewTicker(s.renew)
+	defer ticker.Stop()
+	for {
+		switch {
+		case sub != nil:
+			cur, v, err := s.readLease()
+			switch {
+			case err != nil:
+				s.reporter.Report(s.component(), err, false)
+			case cur.Holder != s.instance:
+				deactivate()
+			default:
+				err = s.writeLease(v, "LeaseRenewed", lease{Holder: s.instance, Checkpoint: sub.LastProcessed()})
+				if err == nil {
+					version, renewed = v+1, time.Now()
+					break
+				}
+				if _, ok := err.(*ErrConcurrencyViolation); ok {
+					deactivate()
+				} else {
+					s.reporter.Report(s.component(), err, false)
+				}
+			}
+			if sub != nil && time.Since(renewed) > s.ttl {
+				deactivate()
+			}
+
+		default:
+			cur, v, err := s.readLease()
+			if err != nil {
+				s.reporter.Report(s.component(), err, false)
+				break
+			}
+			now := time.Now()
+			if v != seen {
+				seen, seenAt = v, now
+			}
+			expired := now.Sub(seenAt) >= s.ttl
+			if v >= 0 && cur.Holder != "" && cur.Holder != s.instance && !expired {
+				break
+			}
+			if err := s.writeLease(v, "LeaseAcquired", lease{Holder: s.instance, Checkpoint: cur.Checkpoint}); err != nil {
+				break
+			}
+			version, renewed = v+1, now
+			from := cur.Checkpoint + 1
+			sub = s.client.NewCatchUpSubscription(s.stream, from, s.handler)
+			sub.Start()
+			s.setActive(true)
+			if s.activated != nil {
+				s.activated(from)
+			}
+		}
+
+		var subDone <-chan struct{}
+		if sub != nil {
+			subDone = sub.Done()
+		}
+
+		select {
+		case <-stop:
+			if sub != nil {
+				checkpoint := sub.LastProcessed()
+				deactivate()
+				// The release is conditional on the lease being unchanged since
+				// the last renewal so that a lease taken over is not released.
+				s.writeLease(version, "LeaseReleased", lease{Checkpoint: checkpoint})
+			}
+			return
+		case <-subDone:
+			// The handler failed. The lease is released so that another
+			// instance can take over from the last event processed.
+			err := sub.Err()
+			checkpoint := sub.LastProcessed()
+			deactivate()
+			s.writeLease(version, "LeaseReleased", lease{Checkpoint: checkpoint})
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			s.reporter.Report(s.component(), err, true)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// component returns the name of the instance used in errors.
+func (s *StandbyConsumer) component() string {
+	return "Standby consumer " + s.consumer + " instance " + s.instance
+}
+
+func (s *StandbyConsumer) setActive(active bool) {
+	s.mu.Lock()
+	s.active = active
+	s.mu.Unlock()
+}
+
+// readLease returns the current lease and the version of the lease stream, or
+// a zero lease and -1 if the lease stream does not exist.
+func (s *StandbyConsumer) readLease() (lease, int, error) {
+	cur := lease{Checkpoint: -1}
+	_, version, err := s.client.readLast(LeaseStreamName(s.consumer), &cur)
+	switch err.(type) {
+	case nil:
+		return cur, version, nil
+	case *ErrNotFound, *ErrNoMoreEvents:
+		return lease{Checkpoint: -1}, -1, nil
+	}
+	return cur, -1, err
+}
+
+// writeLease appends the lease to the lease stream if the version of the
+// stream is expected. When the stream is created its $maxCount is set so that
+// only recent leases are retained.
+func (s *StandbyConsumer) writeLease(expected int, eventType string, l lease) error {
+	name := LeaseStreamName(s.consumer)
+	writer := s.client.NewStreamWriter(name)
+	if err := writer.Append(ExpectedVersion(expected), NewEvent("", eventType, &l, nil)); err != nil {
+		return err
+	}
+	if expected == -1 {
+		return writer.WriteMetaData(name, &StreamMetadata{MaxCount: Int(leaseHistory)})
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&StandbySuite{})
+
+type StandbySuite struct {
+	servers []*httptest.Server
+}
+
+func (s *StandbySuite) SetUpTest(c *C) {
+	setup()
+	s.servers = nil
+}
+func (s *StandbySuite) TearDownTest(c *C) {
+	for _, srv := range s.servers {
+		srv.Close()
+	}
+	teardown()
+}
+
+// serveWritableStream serves a stream that can be appended to with expected
+// version checks and read back.
+func serveWritableStream(stream string) {
+	var mu sync.Mutex
+	es := []*Event{}
+	u, _ := url.Parse(server.URL)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == http.MethodPost {
+			if r.URL.Path != "/streams/"+stream {
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+			if ev := r.Header.Get("ES-ExpectedVersion"); ev != "" {
+				if n, _ := strconv.Atoi(ev); n != len(es)-1 {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+			}
+			in := []*Event{}
+			json.NewDecoder(r.Body).Decode(&in)
+			w.Header().Set("Location", server.URL+"/streams/"+stream+"/"+strconv.Itoa(len(es)))
+			for _, e := range in {
+				b, _ := json.Marshal(e.Data)
+				raw := json.RawMessage(b)
+				es = append(es, CreateTestEvent(stream, server.URL, e.EventType, len(es), &raw, nil))
+			}
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		if len(es) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		sim, _ := NewAtomFeedSimulator(es, u, nil, len(es))
+		sim.ServeHTTP(w, r)
+	}
+	mux.HandleFunc("/streams/"+stream, handler)
+	mux.HandleFunc("/streams/"+stream+"/", handler)
+}
+
+// instanceClient returns a client that reaches the test server through a
+// proxy that can be taken down to simulate the failure of an instance.
+func (s *StandbySuite) instanceClient(c *C) (*Client, *int32) {
+	down := new(int32)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	s.servers = append(s.servers, srv)
+	cl, err := NewClient(nil, srv.URL)
+	c.Assert(err, IsNil)
+	return cl, down
+}
+
+// newStandbyPair serves a consumed stream of five events and the lease
+// stream, and returns two instances of a consumer with the counts of the
+// events each has handled.
+func (s *StandbySuite) newStandbyPair(c *C) ([]*StandbyConsumer, []*int32, []*int32) {
+	es := CreateTestEvents(5, "standby-orders", server.URL, "FooEvent")
+	sim := newTestSimulator(es, nil)
+	mux.Handle("/streams/standby-orders", sim)
+	mux.Handle("/streams/standby-orders/", sim)
+	return s.newStandbyConsumers(c, "standby-orders")
+}
+
+// newStandbyConsumers serves the lease stream and returns two instances of a
+// consumer of the stream with the counts of the events each has handled.
+func (s *StandbySuite) newStandbyConsumers(c *C, stream string) ([]*StandbyConsumer, []*int32, []*int32) {
+	serveWritableStream(LeaseStreamName("projector"))
+
+	consumers := []*StandbyConsumer{}
+	handled := []*int32{}
+	downs := []*int32{}
+	for _, name := range []string{"a", "b"} {
+		cl, down := s.instanceClient(c)
+		n := new(int32)
+		sc := cl.NewStandbyConsumer("projector", name, stream, func(er *EventResponse) error {
+			atomic.AddInt32(n, 1)
+			return nil
+		})
+		sc.SetLease(150*time.Millisecond, 10*time.Millisecond)
+		consumers = append(consumers, sc)
+		handled = append(handled, n)
+		downs = append(downs, down)
+	}
+	return consumers, handled, downs
+}
+
+func (s *StandbySuite) TestOnlyOneInstanceIsActive(c *C) {
+	sc, handled, _ := s.newStandbyPair(c)
+	sc[0].Start()
+	defer sc[0].Stop()
+	eventually(func() bool { return sc[0].IsActive() })
+	c.Assert(sc[0].IsActive(), Equals, true)
+
+	sc[1].Start()
+	defer sc[1].Stop()
+
+	eventually(func() bool { return atomic.LoadInt32(handled[0]) == 5 })
+	time.Sleep(300 * time.Millisecond)
+	c.Assert(sc[0].IsActive(), Equals, true)
+	c.Assert(sc[1].IsActive(), Equals, false)
+	c.Assert(atomic.LoadInt32(handled[0]), Equals, int32(5))
+	c.Assert(atomic.LoadInt32(handled[1]), Equals, int32(0))
+}
+
+func (s *StandbySuite) TestStandbyTakesOverFromCheckpoint(c *C) {
+	sc, handled, downs := s.newStandbyPair(c)
+	from := make(chan int, 1)
+	sc[1].SetActivatedHandler(func(f int) { from <- f })
+
+	sc[0].Start()
+	defer sc[0].Stop()
+	eventually(func() bool { return atomic.LoadInt32(handled[0]) == 5 })
+	sc[1].Start()
+	defer sc[1].Stop()
+
+	// Allow the checkpoint to be renewed before the active instance fails.
+	time.Sleep(50 * time.Millisecond)
+	atomic.StoreInt32(downs[0], 1)
+
+	select {
+	case f := <-from:
+		c.Assert(f, Equals, 5)
+	case <-time.After(time.Second):
+		c.Fatal("The standby did not take over")
+	}
+	eventually(func() bool { return !sc[0].IsActive() })
+	c.Assert(sc[0].IsActive(), Equals, false)
+	c.Assert(atomic.LoadInt32(handled[1]), Equals, int32(0))
+}
+
+func (s *StandbySuite) TestStopReleasesLease(c *C) {
+	sc, handled, _ := s.newStandbyPair(c)
+	sc[1].SetLease(time.Hour, 10*time.Millisecond)
+
+	sc[0].Start()
+	eventually(func() bool { return atomic.LoadInt32(handled[0]) == 5 })
+	sc[1].Start()
+	defer sc[1].Stop()
+
+	sc[0].Stop()
+	c.Assert(sc[0].IsActive(), Equals, false)
+	eventually(func() bool { return sc[1].IsActive() })
+	c.Assert(sc[1].IsActive(), Equals, true)
+	c.Assert(atomic.LoadInt32(handled[1]), Equals, int32(0))
+}
+
+func (s *StandbySuite) TestStandbyTakesOverCategoryStreamFromFeedPosition(c *C) {
+	serveLinkedStream("$ce-order", interleaved(3, "order-1", "order-2"))
+	sc, handled, downs := s.newStandbyConsumers(c, "$ce-order")
+	from := make(chan int, 1)
+	sc[1].SetActivatedHandler(func(f int) { from <- f })
+
+	sc[0].Start()
+	defer sc[0].Stop()
+	eventually(func() bool { return atomic.LoadInt32(handled[0]) == 6 })
+	sc[1].Start()
+	defer sc[1].Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	atomic.StoreInt32(downs[0], 1)
+
+	select {
+	case f := <-from:
+		c.Assert(f, Equals, 6)
+	case <-time.After(time.Second):
+		c.Fatal("The standby did not take over")
+	}
+	c.Assert(atomic.LoadInt32(handled[1]), Equals, int32(0))
+}
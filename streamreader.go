@@ -6,27 +6,49 @@
 package goes
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
-
-	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+	"sync"
+	"time"
 )
 
 // StreamReader provides methods for reading events and event metadata.
 type StreamReader struct {
-	streamName    string
-	client        *Client
-	version       int
-	nextVersion   int
-	index         int
-	currentURL    string
-	pageSize      int
-	eventResponse *EventResponse
-	feedPage      *atom.Feed
-	lasterr       error
-	loadFeedPage  bool
+	streamName       string
+	client           *Client
+	version          int
+	nextVersion      int
+	index            int
+	currentURL       string
+	pageSize         int
+	eventResponse    *EventResponse
+	feedPage         *Feed
+	lasterr          error
+	loadFeedPage     bool
+	prefetch         int
+	fetcher          *prefetcher
+	followRedirects  bool
+	resolved         bool
+	token            ConsistencyToken
+	tokenTimeout     time.Duration
+	fetchConcurrency int
+	pageEvents       []*EventResponse
+	poll             PollStrategy
+	idle             int
+	eventTypes       eventTypes
+	metadata         *StreamMetadata
+	skipped          func(*SkippedEvent)
+
+	// mu guards the fields used to cancel requests in progress, which may be
+	// accessed by Close from another goroutine.
+	mu     sync.Mutex
+	active bool
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // Err returns any error that is raised as a result of a call to Next().
@@ -44,6 +66,35 @@ func (s *StreamReader) NextVersion(version int) {
 	s.nextVersion = version
 }
 
+// Seek repositions the reader so that the next call to Next() will return the
+// event at the version specified.
+//
+// Any feed page held by the reader is discarded and the feed page containing the
+// version will be loaded on the next call to Next(). Other configuration on the
+// reader is retained.
+func (s *StreamReader) Seek(version int) {
+	s.stopPrefetch()
+	s.nextVersion = version
+	s.feedPage = nil
+	s.pageEvents = nil
+	s.eventResponse = nil
+	s.lasterr = nil
+}
+
+// Position returns the version of the event that will be returned by the next
+// call to Next().
+func (s *StreamReader) Position() int {
+	return s.nextVersion
+}
+
+// CurrentURL returns the url of the feed page the reader is currently reading.
+//
+// The url is intended for diagnostic purposes. It will be empty until the first
+// call to Next().
+func (s *StreamReader) CurrentURL() string {
+	return s.currentURL
+}
+
 // EventResponse returns the container for the event that is returned from a call to Next().
 func (s *StreamReader) EventResponse() *EventResponse {
 	return s.eventResponse
@@ -64,8 +115,110 @@ func (s *StreamReader) EventResponse() *EventResponse {
 // When next is called, it will go to the eventstore and get a single event at the
 // current reader's stream version.
 func (s *StreamReader) Next() bool {
+	s.begin()
+	defer s.end()
 	s.lasterr = nil
 
+	if !s.pollWait() {
+		s.eventResponse = nil
+		s.lasterr = context.Canceled
+		return true
+	}
+
+	ret := s.next()
+	switch s.lasterr.(type) {
+	case nil:
+		s.idle = 0
+	case *ErrNoMoreEvents:
+		s.idle++
+	}
+	return ret
+}
+
+// next reads the next event.
+func (s *StreamReader) next() bool {
+	if s.followRedirects && !s.resolved {
+		name, err := s.client.ResolveStream(s.streamName)
+		if err != nil {
+			s.lasterr = err
+			return true
+		}
+		s.streamName = name
+		s.resolved = true
+	}
+
+	if s.token != "" {
+		if err := s.client.WaitForToken(s.token, s.tokenTimeout); err != nil {
+			s.lasterr = err
+			return true
+		}
+		s.token = ""
+	}
+
+	// Events before the truncate before version of the stream are not read.
+	if tb := s.metadata.truncateBefore(); s.nextVersion < tb {
+		s.Seek(tb)
+	}
+
+	if s.prefetch > 0 {
+		return s.nextPrefetched()
+	}
+
+	for {
+		if found, next := s.nextEntry(); !found {
+			return next
+		}
+
+		// Entries for events of types that are not read are skipped without
+		// reading their events.
+		entry := s.feedPage.Entry[s.index]
+		if s.eventTypes.skipsEntry(entry) {
+			s.nextVersion++
+			s.index--
+			continue
+		}
+
+		// Events fetched concurrently with the page are used if available. If
+		// fetching the event failed it is fetched again so that the error is
+		// returned and the read can be retried.
+		var e *EventResponse
+		if s.pageEvents != nil {
+			e = s.pageEvents[s.index]
+		}
+		if e == nil {
+			url := strings.TrimRight(entry.Link[1].Href, "/")
+			ctx := s.context()
+			ev, err := s.client.entryEvent(ctx, entry)
+			if _, ok := err.(*ErrNotFound); ok && s.skipsMissing() {
+				s.reportSkipped(&SkippedEvent{EventNumber: s.nextVersion, URL: url, Err: err})
+				s.nextVersion++
+				s.index--
+				continue
+			}
+			if err != nil {
+				s.lasterr = requestError(ctx, err)
+				return true
+			}
+			e = ev
+		}
+		if s.eventTypes.skipsEvent(e) {
+			s.nextVersion++
+			s.index--
+			continue
+		}
+
+		s.eventResponse = e
+		s.version = s.nextVersion
+		s.nextVersion++
+		s.index--
+		return true
+	}
+}
+
+// nextEntry positions the reader at the feed page entry of the next event,
+// loading the next feed page if required. If there is no entry found is false,
+// the error of the reader is set and next is the value to return from Next().
+func (s *StreamReader) nextEntry() (found bool, next bool) {
 	numEntries := 0
 	if s.feedPage != nil {
 		numEntries = len(s.feedPage.Entry)
@@ -79,7 +232,7 @@ func (s *StreamReader) Next() bool {
 		url, err := s.client.GetFeedPath(s.streamName, "forward", s.nextVersion, s.pageSize)
 		if err != nil {
 			s.lasterr = err
-			return false
+			return false, false
 		}
 		s.currentURL = url
 	}
@@ -98,38 +251,31 @@ func (s *StreamReader) Next() bool {
 		}
 
 		//Read the feedpage at the current url
-		f, _, err := s.client.ReadFeed(s.currentURL)
+		ctx := s.context()
+		f, _, err := s.client.readFeed(ctx, s.currentURL)
 		if err != nil {
-			s.lasterr = err
-			return true
+			s.lasterr = requestError(ctx, err)
+			return false, true
 		}
 
 		s.feedPage = f
+		s.pageEvents = nil
 		numEntries = len(f.Entry)
 		s.index = numEntries - 1
+
+		if s.fetchConcurrency > 1 {
+			s.pageEvents, _ = s.client.fetchEntries(ctx, f.Entry, s.fetchConcurrency, s.eventTypes)
+		}
 	}
 
 	//If there are no events returned at the url return an error
 	if numEntries <= 0 {
 		s.eventResponse = nil
 		s.lasterr = &ErrNoMoreEvents{}
-		return true
-	}
-
-	//There are events returned, get the event for the current version
-	entry := s.feedPage.Entry[s.index]
-	url := strings.TrimRight(entry.Link[1].Href, "/")
-	e, _, err := s.client.GetEvent(url)
-	if err != nil {
-		s.lasterr = err
-		return true
+		return false, true
 	}
-	s.eventResponse = e
-	s.version = s.nextVersion
-	s.nextVersion++
-	s.index--
 
-	return true
+	return true, true
 }
 
 // Scan deserializes event and event metadata into the types passed in
@@ -144,9 +290,28 @@ func (s *StreamReader) Scan(e interface{}, m interface{}) error {
 		return &ErrNoMoreEvents{}
 	}
 
+	return s.client.decodeEvent(s.eventResponse, e, m)
+}
+
+// decodeEvent deserializes the data and metadata of the event contained in
+// the EventResponse into the types passed in as arguments e and m.
+//
+// Either e or m may be nil in which case the corresponding part of the event
+// is not decoded. Events written with a codec other than JSON are decoded with
+// the codec registered on the client for the content type of the event.
+func (c *Client) decodeEvent(er *EventResponse, e interface{}, m interface{}) error {
+
+	data, _ := er.Event.Data.(*json.RawMessage)
+	meta, _ := er.Event.MetaData.(*json.RawMessage)
+
+	if data != nil {
+		if handled, err := c.decodeEncoded(data, meta, e, m); handled {
+			return err
+		}
+	}
+
 	if e != nil {
-		data, ok := s.eventResponse.Event.Data.(*json.RawMessage)
-		if !ok {
+		if data == nil {
 			return fmt.Errorf("Could not unmarshal the event. Event data is not of type *json.RawMessage")
 		}
 
@@ -155,12 +320,15 @@ func (s *StreamReader) Scan(e interface{}, m interface{}) error {
 		}
 	}
 
-	if m != nil && s.EventResponse().Event.MetaData != nil {
-		meta, ok := s.eventResponse.Event.MetaData.(*json.RawMessage)
-		if !ok {
+	if m != nil && er.Event.MetaData != nil {
+		if meta == nil {
 			return fmt.Errorf("Could not unmarshal the event. Event data is not of type *json.RawMessage")
 		}
 
+		if len(*meta) == 0 {
+			return nil
+		}
+
 		if err := json.Unmarshal(*meta, &m); err != nil {
 			return err
 		}
@@ -182,8 +350,11 @@ func (s *StreamReader) Scan(e interface{}, m interface{}) error {
 // request to be made with ES-LongPoll set to that value. Any value 0 or below
 // will cause the request to be made without ES-LongPoll and the server will not
 // wait to return.
+//
+// If the client has detected with Client.Ping that the server does not
+// support long polling the request is made without ES-LongPoll.
 func (s *StreamReader) LongPoll(seconds int) {
-	if seconds > 0 {
+	if seconds > 0 && s.client.supportsLongPoll() {
 		s.client.SetHeader("ES-LongPoll", strconv.Itoa(seconds))
 	} else {
 		s.client.DeleteHeader("ES-LongPoll")
@@ -207,3 +378,36 @@ func (s *StreamReader) MetaData() (*EventResponse, error) {
 	}
 	return ev, nil
 }
+
+// LoadMetaData reads the metadata of the stream and keeps it with the reader.
+//
+// Once the metadata is loaded the reader takes it into account: if the
+// metadata has a truncate before version the reader starts reading at that
+// version rather than requesting events that have been removed, and if events
+// can expire because of a maximum age, maximum count or truncate before
+// version, events that are no longer found are skipped rather than returned as
+// an *ErrNotFound. See also SetSkippedEventHandler. LoadMetaData can be called
+// again to refresh the metadata.
+func (s *StreamReader) LoadMetaData() (*StreamMetadata, error) {
+	m, err := s.client.ReadStreamMetadata(s.streamName)
+	if err != nil {
+		return nil, err
+	}
+	s.metadata = m
+	s.stopPrefetch()
+	return m, nil
+}
+
+// truncateBefore returns the truncate before version of the metadata, or 0.
+func (m *StreamMetadata) truncateBefore() int {
+	if m == nil || m.TruncateBefore == nil {
+		return 0
+	}
+	return *m.TruncateBefore
+}
+
+// removesEvents returns true if the metadata causes events of the stream to be
+// removed.
+func (m *StreamMetadata) removesEvents() bool {
+	return m != nil && (m.MaxAge != nil || m.MaxCount != nil || m.TruncateBefore != nil)
+}
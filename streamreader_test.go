@@ -360,3 +360,83 @@ func (s *StreamReaderSuite) TestGetMetaDataReturnsErrTemporarilyUnavailableWhenG
 	c.Assert(typeOf(err), Equals, "ErrTemporarilyUnavailable")
 	c.Assert(m, IsNil)
 }
+
+// Test that seeking repositions the reader to the version requested.
+func (s *StreamReaderSuite) TestSeek(c *C) {
+	streamName := "SeekStream"
+	es := CreateTestEvents(50, streamName, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	stream := client.NewStreamReader(streamName)
+	stream.Next()
+	c.Assert(stream.Err(), IsNil)
+	c.Assert(stream.EventResponse().Event.EventNumber, Equals, 0)
+
+	stream.Seek(32)
+	c.Assert(stream.Position(), Equals, 32)
+	stream.Next()
+	c.Assert(stream.Err(), IsNil)
+	c.Assert(stream.EventResponse().Event.EventNumber, Equals, 32)
+	c.Assert(stream.Position(), Equals, 33)
+
+	stream.Seek(3)
+	stream.Next()
+	c.Assert(stream.Err(), IsNil)
+	c.Assert(stream.EventResponse().Event.EventNumber, Equals, 3)
+}
+
+func (s *StreamReaderSuite) TestCurrentURL(c *C) {
+	streamName := "CurrentURLStream"
+	es := CreateTestEvents(5, streamName, server.URL, "FooEvent")
+	setupSimulator(es, nil)
+
+	stream := client.NewStreamReader(streamName)
+	c.Assert(stream.CurrentURL(), Equals, "")
+	stream.Next()
+	c.Assert(stream.CurrentURL(), Equals, "/streams/CurrentURLStream/0/forward/20")
+}
+
+func (s *StreamReaderSuite) TestLoadMetaDataStartsAtTruncateBefore(c *C) {
+	stream := "truncated"
+	es := CreateTestEvents(10, stream, server.URL, "FooEvent")
+	raw := json.RawMessage(`{"$tb":6}`)
+	setupSimulator(es, CreateTestEvent(stream, server.URL, "$metadata", 0, &raw, nil))
+
+	reader := client.NewStreamReader(stream)
+	m, err := reader.LoadMetaData()
+	c.Assert(err, IsNil)
+	c.Assert(*m.TruncateBefore, Equals, 6)
+
+	reader.Next()
+	c.Assert(reader.Err(), IsNil)
+	c.Assert(reader.EventResponse().Event.EventNumber, Equals, 6)
+}
+
+func (s *StreamReaderSuite) TestLoadMetaDataSkipsExpiredEvents(c *C) {
+	stream := "expiring"
+	es := CreateTestEvents(5, stream, server.URL, "FooEvent")
+	raw := json.RawMessage(`{"$maxAge":60}`)
+	setupSimulator(es, CreateTestEvent(stream, server.URL, "$metadata", 0, &raw, nil))
+	mux.HandleFunc("/streams/expiring/1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	reader := client.NewStreamReader(stream)
+	reader.Next()
+	reader.Next()
+	c.Assert(reader.Err(), FitsTypeOf, &ErrNotFound{})
+
+	for _, prefetch := range []int{0, 3} {
+		reader = client.NewStreamReader(stream)
+		reader.Prefetch(prefetch)
+		_, err := reader.LoadMetaData()
+		c.Assert(err, IsNil)
+		got := []int{}
+		for reader.Next() && reader.Err() == nil {
+			got = append(got, reader.EventResponse().Event.EventNumber)
+		}
+		reader.Close()
+		c.Assert(reader.Err(), FitsTypeOf, &ErrNoMoreEvents{})
+		c.Assert(got, DeepEquals, []int{0, 2, 3, 4})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"math"
+)
+
+// StreamStatus describes whether a stream exists or has been deleted.
+type StreamStatus int
+
+const (
+	// StreamStatusUnknown is returned with an error when the status of the
+	// stream could not be determined.
+	StreamStatusUnknown StreamStatus = iota
+
+	// StreamExists indicates that the stream exists.
+	StreamExists
+
+	// StreamNotFound indicates that the stream has never been written to.
+	StreamNotFound
+
+	// StreamSoftDeleted indicates that the stream has been soft deleted. Its
+	// metadata is retained and writing to it again will undelete it.
+	StreamSoftDeleted
+
+	// StreamHardDeleted indicates that the stream has been hard deleted and can
+	// never be written to again.
+	StreamHardDeleted
+)
+
+func (s StreamStatus) String() string {
+	switch s {
+	case StreamExists:
+		return "Exists"
+	case StreamNotFound:
+		return "NotFound"
+	case StreamSoftDeleted:
+		return "SoftDeleted"
+	case StreamHardDeleted:
+		return "HardDeleted"
+	}
+	return "Unknown"
+}
+
+// StreamStatus returns the status of a stream.
+//
+// The eventstore returns 410 Gone for a hard deleted stream. A soft deleted
+// stream returns 404 Not Found like a stream that does not exist, however the
+// eventstore marks it as deleted by setting the $tb metadata of the stream to
+// the maximum event number, so the metadata of the stream is read to tell them
+// apart.
+//
+// Any other error is returned with StreamStatusUnknown.
+func (c *Client) StreamStatus(stream string) (StreamStatus, error) {
+	url, err := c.GetFeedPath(stream, "backward", -1, 1)
+	if err != nil {
+		return StreamStatusUnknown, err
+	}
+
+	_, _, err = c.ReadFeed(url)
+	switch err.(type) {
+	case nil:
+		return StreamExists, nil
+	case *ErrDeleted:
+		return StreamHardDeleted, nil
+	case *ErrNotFound:
+	default:
+		return StreamStatusUnknown, err
+	}
+
+	er, _, err := c.GetEvent(fmt.Sprintf("/streams/%s/metadata", stream))
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound:
+		return StreamNotFound, nil
+	default:
+		return StreamStatusUnknown, err
+	}
+	if er == nil {
+		return StreamNotFound, nil
+	}
+
+	m := struct {
+		TruncateBefore *int64 `json:"$tb"`
+	}{}
+	if err := c.decodeEvent(er, &m, nil); err != nil {
+		return StreamStatusUnknown, err
+	}
+	if m.TruncateBefore != nil && *m.TruncateBefore == math.MaxInt64 {
+		return StreamSoftDeleted, nil
+	}
+	return StreamNotFound, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&StreamStatusSuite{})
+
+type StreamStatusSuite struct{}
+
+func (s *StreamStatusSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *StreamStatusSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// serveDeletedStream serves a stream that returns code with the metadata
+// provided. If meta is nil the metadata also returns code.
+func serveDeletedStream(c *C, stream string, code int, meta interface{}) {
+	mux.HandleFunc("/streams/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/streams/"+stream+"/metadata" || meta == nil {
+			w.WriteHeader(code)
+			return
+		}
+		e := CreateTestEventFromData("$$"+stream, server.URL, 0, meta, nil)
+		er, err := CreateTestEventAtomResponse(e, nil)
+		c.Assert(err, IsNil)
+		json.NewEncoder(w).Encode(er)
+	})
+}
+
+func (s *StreamStatusSuite) TestStreamExists(c *C) {
+	stream := "exists-1"
+	setupSimulator(CreateTestEvents(2, stream, server.URL, "Foo"), nil)
+
+	status, err := client.StreamStatus(stream)
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, StreamExists)
+}
+
+func (s *StreamStatusSuite) TestStreamNotFound(c *C) {
+	serveDeletedStream(c, "missing-1", http.StatusNotFound, nil)
+
+	status, err := client.StreamStatus("missing-1")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, StreamNotFound)
+}
+
+func (s *StreamStatusSuite) TestStreamNotFoundWithMetadata(c *C) {
+	serveDeletedStream(c, "missing-2", http.StatusNotFound, &map[string]interface{}{"$maxCount": 5})
+
+	status, err := client.StreamStatus("missing-2")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, StreamNotFound)
+}
+
+func (s *StreamStatusSuite) TestStreamSoftDeleted(c *C) {
+	serveDeletedStream(c, "deleted-1", http.StatusNotFound, &map[string]interface{}{"$tb": int64(math.MaxInt64)})
+
+	status, err := client.StreamStatus("deleted-1")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, StreamSoftDeleted)
+}
+
+func (s *StreamStatusSuite) TestStreamHardDeleted(c *C) {
+	serveDeletedStream(c, "deleted-2", http.StatusGone, nil)
+
+	status, err := client.StreamStatus("deleted-2")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, StreamHardDeleted)
+}
+
+func (s *StreamStatusSuite) TestStreamStatusReturnsOtherErrors(c *C) {
+	serveDeletedStream(c, "secret-1", http.StatusUnauthorized, nil)
+
+	status, err := client.StreamStatus("secret-1")
+	c.Assert(err, FitsTypeOf, &ErrUnauthorized{})
+	c.Assert(status, Equals, StreamStatusUnknown)
+}
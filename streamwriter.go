@@ -6,9 +6,9 @@
 package goes
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
 )
 
 // StreamWriter provides methods for writing events and metadata to an
@@ -16,41 +16,226 @@ import (
 type StreamWriter struct {
 	client     *Client
 	streamName string
+	codec      Codec
+	metaCodec  Codec
+	typeIndex  bool
+	defaults   map[string]interface{}
+	recreate   bool
 }
 
-// Append writes an event to the head of the stream.
+// SetDefaultMetaData sets a metadata value that is written with every event
+// appended by the writer, such as a correlation id, schema version or tenant.
 //
-// If the stream does not exist, it will be created.
+// Defaults are merged into the metadata of each event when the events are
+// serialized. Keys in the metadata of an event take precedence over the
+// defaults. The metadata of the events themselves is not modified. When
+// defaults are set the metadata of each event must be nil or serialize to a
+// JSON object.
 //
-// There are some special version numbers that can be provided.
-// http://docs.geteventstore.com/http-api/3.7.0/writing-to-a-stream/
+// Setting a value of nil removes the default.
+func (s *StreamWriter) SetDefaultMetaData(key string, value interface{}) {
+	if value == nil {
+		delete(s.defaults, key)
+		return
+	}
+	if s.defaults == nil {
+		s.defaults = make(map[string]interface{})
+	}
+	s.defaults[key] = value
+}
+
+// SetRecreateSoftDeleted sets whether a soft deleted stream is treated as a
+// stream that does not exist when events are appended with an expected version
+// of ExpectNoStream. It is the StreamWriter equivalent of the
+// WithRecreateSoftDeleted option of Client.AppendToStream.
 //
-// -2 : The write should never conflict with anything and should always succeed.
+// Appending to a soft deleted stream recreates it from its tombstone, and the
+// events written are numbered from the event number after the last event
+// before the stream was deleted. By default an append with an expected version
+// of ExpectNoStream that is rejected because the stream has been soft deleted
+// returns an *ErrConcurrencyViolation. When recreation is enabled the writer
+// checks the status of the stream and, if it has been soft deleted, appends the
+// events again with an expected version of ExpectAny. A write made by another
+// writer between the check and the second append is not detected.
+func (s *StreamWriter) SetRecreateSoftDeleted(recreate bool) {
+	s.recreate = recreate
+}
+
+// Append writes an event to the head of the stream.
 //
-// -1 : The stream should not exist at the time of writing. This write will create it.
+// If the stream does not exist, it will be created. If the stream has been hard
+// deleted an *ErrStreamHardDeleted is returned.
 //
-// 0 : The stream should exist but it should be empty.
-func (s *StreamWriter) Append(expectedVersion *int, events ...*Event) error {
-	u := fmt.Sprintf("/streams/%s", s.streamName)
-	req, err := s.client.newRequest(http.MethodPost, u, events)
+// Unless expectedVersion is ExpectAny the write is conditional on the version
+// of the stream, and an *ErrConcurrencyViolation is returned if it does not
+// match. A version that is neither an event number nor a named version returns
+// an *ErrInvalidExpectedVersion without writing.
+// http://docs.geteventstore.com/http-api/3.7.0/writing-to-a-stream/
+func (s *StreamWriter) Append(expectedVersion ExpectedVersion, events ...*Event) error {
+	return s.appendIndexed(expectedVersion, events, nil)
+}
+
+// appendIndexed writes the events with additional request headers and updates
+// the type index if it is enabled.
+func (s *StreamWriter) appendIndexed(expectedVersion ExpectedVersion, events []*Event, headers map[string]string) error {
+	resp, err := s.appendWithHeaders(expectedVersion, events, headers)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/vnd.eventstore.events+json")
-	if expectedVersion != nil {
-		req.Header.Set("ES-ExpectedVersion", strconv.Itoa(*expectedVersion))
+	if s.typeIndex {
+		return s.writeTypeIndex(resp, events)
 	}
 
-	_, err = s.client.do(req, nil)
+	return nil
+}
+
+// WriteResult describes a successful append.
+//
+// NextExpectedVersion is the version of the stream after the write, which is
+// the expected version to use for the next optimistic write to the stream.
+// Location is the URL of the first event written. CommitPosition is the
+// position of the write in the transaction log if the server reports it in the
+// ES-CommitPosition header, otherwise it is -1.
+type WriteResult struct {
+	NextExpectedVersion int
+	Location            string
+	CommitPosition      int64
+}
+
+// AppendWithResult appends events to the stream like Append and returns a
+// *WriteResult describing the write.
+//
+// If the events were written but the type index could not be updated the result
+// is returned with an *ErrTypeIndex.
+func (s *StreamWriter) AppendWithResult(expectedVersion ExpectedVersion, events ...*Event) (*WriteResult, error) {
+	return s.appendWithResult(expectedVersion, events, nil)
+}
+
+// appendWithResult writes the events with additional request headers and
+// returns a *WriteResult describing the write.
+func (s *StreamWriter) appendWithResult(expectedVersion ExpectedVersion, events []*Event, headers map[string]string) (*WriteResult, error) {
+	resp, err := s.appendWithHeaders(expectedVersion, events, headers)
 	if err != nil {
-		if e, ok := err.(*ErrBadRequest); ok {
-			return &ErrConcurrencyViolation{ErrorResponse: e.ErrorResponse}
+		return nil, err
+	}
+
+	first, ok := locationVersion(resp)
+	if !ok {
+		return nil, fmt.Errorf("No event number in response location")
+	}
+	result := &WriteResult{
+		NextExpectedVersion: first + len(events) - 1,
+		Location:            resp.Header.Get("Location"),
+		CommitPosition:      resp.CommitPosition,
+	}
+
+	if s.typeIndex {
+		return result, s.writeTypeIndex(resp, events)
+	}
+	return result, nil
+}
+
+// appendWithHeaders writes the events to the stream with additional request
+// headers and returns the response from the server.
+func (s *StreamWriter) appendWithHeaders(expectedVersion ExpectedVersion, events []*Event, headers map[string]string) (*Response, error) {
+	encoded := make([]*Event, len(events))
+	for i, e := range events {
+		if err := validateWrite(i, e); err != nil {
+			return nil, err
 		}
-		return err
+		ev, err := s.client.stampSchemaVersion(e)
+		if err != nil {
+			return nil, err
+		}
+		ev, err = stampEnvelope(ev)
+		if err != nil {
+			return nil, err
+		}
+		ev, err = mergeMetaData(s.defaults, ev)
+		if err != nil {
+			return nil, err
+		}
+		ev, err = encodeEvent(s.codec, s.metaCodec, ev)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = ev
+	}
+	body, err := newWriteEvents(encoded)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	resp, err := s.post(expectedVersion, body, headers)
+	if _, ok := err.(*ErrConcurrencyViolation); ok && s.recreate && expectedVersion == ExpectNoStream {
+		status, serr := s.client.StreamStatus(s.streamName)
+		if serr == nil && status == StreamSoftDeleted {
+			return s.post(ExpectAny, body, headers)
+		}
+	}
+	return resp, err
+}
+
+// post sends the events+json body to the stream.
+func (s *StreamWriter) post(expectedVersion ExpectedVersion, body []*writeEvent, headers map[string]string) (*Response, error) {
+	u := fmt.Sprintf("/streams/%s", s.streamName)
+	req, err := s.client.newRequest(http.MethodPost, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", eventsContentType)
+	if err := setExpectedVersion(req, expectedVersion); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.do(req, nil)
+	switch e := err.(type) {
+	case nil:
+		return resp, nil
+	case *ErrBadRequest:
+		return resp, &ErrConcurrencyViolation{ErrorResponse: e.ErrorResponse}
+	case *ErrDeleted:
+		return resp, &ErrStreamHardDeleted{ErrDeleted: e, Stream: s.streamName}
+	}
+	return resp, err
+}
+
+// mergeMetaData returns a copy of the event with the defaults merged into its
+// metadata. If there are no defaults the event is returned unchanged.
+func mergeMetaData(defaults map[string]interface{}, e *Event) (*Event, error) {
+	if len(defaults) == 0 {
+		return e, nil
+	}
+
+	meta := make(map[string]interface{}, len(defaults))
+	for k, v := range defaults {
+		meta[k] = v
+	}
+
+	if e.MetaData != nil {
+		m, ok := e.MetaData.(map[string]interface{})
+		if !ok {
+			b, err := json.Marshal(e.MetaData)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(b, &m); err != nil || m == nil {
+				return nil, fmt.Errorf("Cannot merge default metadata into metadata of type %T", e.MetaData)
+			}
+		}
+		for k, v := range m {
+			meta[k] = v
+		}
+	}
+
+	ret := *e
+	ret.MetaData = meta
+	return &ret, nil
 }
 
 // WriteMetaData writes the metadata for a stream.
@@ -69,20 +254,37 @@ func (s *StreamWriter) Append(expectedVersion *int, events ...*Event) error {
 // If an error occurred outside of the http request another type of error will be returned
 // such as a *url.Error in cases where the streamwriter is unable to connect to the server.
 func (s *StreamWriter) WriteMetaData(stream string, metadata interface{}) error {
-	m := NewEvent("", "MetaData", metadata, nil)
 	mURL, _, err := s.client.GetMetadataURL(stream)
 	if err != nil {
 		return err
 	}
-	req, err := s.client.newRequest(http.MethodPost, mURL, m)
+	return s.client.postMetaData(mURL, metadata, ExpectAny)
+}
+
+// postMetaData writes the metadata to the metadata url of a stream. Unless
+// expectedVersion is ExpectAny the write is conditional on the version of the
+// metadata stream and an *ErrConcurrencyViolation is returned if it does not
+// match.
+func (c *Client) postMetaData(mURL string, metadata interface{}, expectedVersion ExpectedVersion) error {
+	body, err := newWriteEvents([]*Event{NewEvent("", "MetaData", metadata, nil)})
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(http.MethodPost, mURL, body)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/vnd.eventstore.events+json")
+	req.Header.Set("Content-Type", eventsContentType)
+	if err := setExpectedVersion(req, expectedVersion); err != nil {
+		return err
+	}
 
-	_, err = s.client.do(req, nil)
+	_, err = c.do(req, nil)
 	if err != nil {
+		if e, ok := err.(*ErrBadRequest); ok && expectedVersion != ExpectAny {
+			return &ErrConcurrencyViolation{ErrorResponse: e.ErrorResponse}
+		}
 		return err
 	}
 
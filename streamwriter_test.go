@@ -8,8 +8,10 @@ package goes
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -51,7 +53,7 @@ func (s *StreamWriterSuite) TestAppendEventsSingle(c *C) {
 	})
 
 	streamWriter := client.NewStreamWriter(streamName)
-	err := streamWriter.Append(nil, ev)
+	err := streamWriter.Append(ExpectAny, ev)
 
 	c.Assert(err, IsNil)
 }
@@ -81,7 +83,7 @@ func (s *StreamWriterSuite) TestAppendEventsMultiple(c *C) {
 	})
 
 	streamWriter := client.NewStreamWriter(stream)
-	err := streamWriter.Append(nil, ev1, ev2)
+	err := streamWriter.Append(ExpectAny, ev1, ev2)
 
 	c.Assert(err, IsNil)
 }
@@ -109,7 +111,7 @@ func (s *StreamWriterSuite) TestAppendEventsWithErrConcurrencyViolation(c *C) {
 	})
 
 	streamWriter := client.NewStreamWriter(stream)
-	err := streamWriter.Append(&expectedVersion, ev)
+	err := streamWriter.Append(ExpectedVersion(expectedVersion), ev)
 	c.Assert(err, NotNil)
 	c.Assert(typeOf(err), DeepEquals, "ErrConcurrencyViolation")
 }
@@ -138,15 +140,9 @@ func (s *StreamWriterSuite) TestAppendStreamMetadata(c *C) {
 		c.Assert(r.Method, Equals, http.MethodPost)
 
 		var got json.RawMessage
-		ev := &Event{Data: &got}
-		err := json.NewDecoder(r.Body).Decode(ev)
-		c.Assert(err, IsNil)
+		decodeWrittenMetaData(c, r, &got)
 		c.Assert(got, DeepEquals, want)
 
-		mt := "application/vnd.eventstore.events+json"
-		mediaType := r.Header.Get("Content-Type")
-		c.Assert(mt, Equals, mediaType)
-
 		w.WriteHeader(http.StatusCreated)
 		fmt.Fprint(w, "")
 	})
@@ -332,3 +328,155 @@ func (s *StreamWriterSuite) TestAppendStreamMetadataReturnsErrTemporarilyUnavail
 		c.Error("Error returned is not of type *ErrTemporarilyUnavailable")
 	}
 }
+
+func (s *StreamWriterSuite) TestAppendMergesDefaultMetaData(c *C) {
+	stream := "defaults-1"
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		se := []struct {
+			MetaData map[string]interface{} `json:"metadata"`
+		}{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&se), IsNil)
+		c.Assert(se, HasLen, 3)
+		c.Assert(se[0].MetaData, DeepEquals, map[string]interface{}{
+			"correlationId": "abc", "tenant": "acme",
+		})
+		c.Assert(se[1].MetaData, DeepEquals, map[string]interface{}{
+			"correlationId": "abc", "tenant": "other", "schema": float64(2),
+		})
+		c.Assert(se[2].MetaData, DeepEquals, map[string]interface{}{
+			"correlationId": "abc", "tenant": "acme", "my_field_1": float64(1), "my_field_2": "x",
+		})
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	override := map[string]interface{}{"tenant": "other", "schema": 2}
+	events := []*Event{
+		NewEvent("", "Foo", nil, nil),
+		NewEvent("", "Foo", nil, override),
+		NewEvent("", "Foo", nil, &MyDataType{Field1: 1, Field2: "x"}),
+	}
+
+	writer := client.NewStreamWriter(stream)
+	writer.SetDefaultMetaData("correlationId", "abc")
+	writer.SetDefaultMetaData("tenant", "acme")
+	writer.SetDefaultMetaData("removed", "value")
+	writer.SetDefaultMetaData("removed", nil)
+	err := writer.Append(ExpectAny, events...)
+	c.Assert(err, IsNil)
+	c.Assert(override, DeepEquals, map[string]interface{}{"tenant": "other", "schema": 2})
+	c.Assert(events[0].MetaData, IsNil)
+}
+
+func (s *StreamWriterSuite) TestAppendDefaultMetaDataRequiresObjectMetaData(c *C) {
+	writer := client.NewStreamWriter("defaults-2")
+	writer.SetDefaultMetaData("tenant", "acme")
+	err := writer.Append(ExpectAny, NewEvent("", "Foo", nil, "not an object"))
+	c.Assert(err, NotNil)
+}
+
+func (s *StreamWriterSuite) TestAppendWithResult(c *C) {
+	stream := "result-1"
+	location := server.URL + "/streams/" + stream + "/12"
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	result, err := client.NewStreamWriter(stream).AppendWithResult(ExpectAny,
+		NewEvent("", "Foo", nil, nil),
+		NewEvent("", "Foo", nil, nil),
+	)
+	c.Assert(err, IsNil)
+	c.Assert(result, DeepEquals, &WriteResult{
+		NextExpectedVersion: 13,
+		Location:            location,
+		CommitPosition:      -1,
+	})
+}
+
+func (s *StreamWriterSuite) TestAppendWithResultReadsCommitPosition(c *C) {
+	stream := "result-2"
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", server.URL+"/streams/"+stream+"/0")
+		w.Header().Set("ES-CommitPosition", "48213")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	result, err := client.NewStreamWriter(stream).AppendWithResult(ExpectAny, NewEvent("", "Foo", nil, nil))
+	c.Assert(err, IsNil)
+	c.Assert(result.NextExpectedVersion, Equals, 0)
+	c.Assert(result.CommitPosition, Equals, int64(48213))
+}
+
+// serveTombstone serves a stream that is soft or hard deleted. Appends with an
+// expected version of -1 are rejected and the expected versions of the appends
+// are recorded.
+func serveTombstone(c *C, stream string, hard bool) *[]string {
+	versions := []string{}
+	if hard {
+		serveDeletedStream(c, stream, http.StatusGone, nil)
+	} else {
+		serveDeletedStream(c, stream, http.StatusNotFound, &map[string]interface{}{"$tb": int64(math.MaxInt64)})
+	}
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		v := r.Header.Get("ES-ExpectedVersion")
+		versions = append(versions, v)
+		switch {
+		case hard:
+			w.WriteHeader(http.StatusGone)
+		case v == "-1":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.Header().Set("Location", server.URL+"/streams/"+stream+"/5")
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+	return &versions
+}
+
+func (s *StreamWriterSuite) TestAppendToSoftDeletedStreamIsAConflictByDefault(c *C) {
+	versions := serveTombstone(c, "tombstone-1", false)
+
+	err := client.NewStreamWriter("tombstone-1").Append(ExpectNoStream, NewEvent("", "Foo", nil, nil))
+	c.Assert(err, FitsTypeOf, &ErrConcurrencyViolation{})
+	c.Assert(*versions, DeepEquals, []string{"-1"})
+}
+
+func (s *StreamWriterSuite) TestAppendRecreatesSoftDeletedStream(c *C) {
+	versions := serveTombstone(c, "tombstone-2", false)
+
+	writer := client.NewStreamWriter("tombstone-2")
+	writer.SetRecreateSoftDeleted(true)
+	result, err := writer.AppendWithResult(ExpectNoStream, NewEvent("", "Foo", nil, nil))
+	c.Assert(err, IsNil)
+	c.Assert(result.NextExpectedVersion, Equals, 5)
+	c.Assert(*versions, DeepEquals, []string{"-1", ""})
+}
+
+func (s *StreamWriterSuite) TestAppendRecreateKeepsConflictsOnExistingStreams(c *C) {
+	stream := "tombstone-3"
+	setupSimulator(CreateTestEvents(2, stream, server.URL, "Foo"), nil)
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	_, err := client.AppendToStream(stream, []*Event{NewEvent("", "Foo", nil, nil)},
+		WithExpectedVersion(ExpectNoStream), WithRecreateSoftDeleted())
+	c.Assert(err, FitsTypeOf, &ErrConcurrencyViolation{})
+}
+
+func (s *StreamWriterSuite) TestAppendToHardDeletedStream(c *C) {
+	versions := serveTombstone(c, "tombstone-4", true)
+
+	writer := client.NewStreamWriter("tombstone-4")
+	writer.SetRecreateSoftDeleted(true)
+	err := writer.Append(ExpectNoStream, NewEvent("", "Foo", nil, nil))
+	c.Assert(err, FitsTypeOf, &ErrStreamHardDeleted{})
+	c.Assert(err.(*ErrStreamHardDeleted).Stream, Equals, "tombstone-4")
+	c.Assert(err, ErrorMatches, "Stream tombstone-4 has been hard deleted and cannot be written to.")
+	c.Assert(*versions, DeepEquals, []string{"-1"})
+
+	var deleted *ErrDeleted
+	c.Assert(errors.As(err, &deleted), Equals, true)
+	c.Assert(err.(*ErrStreamHardDeleted).ErrorResponse, NotNil)
+}
@@ -0,0 +1,133 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SetStrict sets whether the client validates the feed pages and events
+// returned by the server. The default is false.
+//
+// In strict mode a feed page must have a self link, its navigation links must
+// be links of the same stream, every entry must link to its event, with the
+// edit and alternate links agreeing, no event may appear twice and the entries
+// of each stream must be ordered most recent first. An event must have a
+// stream, a type, a UUID as its id and the event number of its url. A response
+// that fails these checks is rejected with an *ErrProtocol rather than being
+// returned to the reader.
+func (c *Client) SetStrict(strict bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.strict = strict
+}
+
+// isStrict returns true if the client validates responses.
+func (c *Client) isStrict() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.strict
+}
+
+// checkFeed validates the feed page read from url in strict mode.
+func (c *Client) checkFeed(url string, f *Feed) error {
+	if !c.isStrict() {
+		return nil
+	}
+	fail := func(format string, a ...interface{}) error {
+		return &ErrProtocol{URL: url, Reason: fmt.Sprintf(format, a...)}
+	}
+
+	self := f.Links.Self
+	if self == "" {
+		return fail("the feed has no self link")
+	}
+	if i := strings.Index(self, "?"); i >= 0 {
+		self = self[:i]
+	}
+	self = strings.TrimRight(self, "/")
+	links := []struct{ rel, href string }{
+		{"first", f.Links.First},
+		{"last", f.Links.Last},
+		{"next", f.Links.Next},
+		{"previous", f.Links.Previous},
+		{"metadata", f.Links.Metadata},
+	}
+	for _, l := range links {
+		if l.href != "" && !strings.HasPrefix(l.href, self+"/") {
+			return fail("the %s link %s is not a link of the stream %s", l.rel, l.href, self)
+		}
+	}
+
+	seen := make(map[string]bool, len(f.Entry))
+	last := make(map[string]int)
+	for i, e := range f.Entry {
+		var edit, alternate string
+		for _, l := range e.Link {
+			switch l.Rel {
+			case "edit":
+				edit = strings.TrimRight(l.Href, "/")
+			case "alternate":
+				alternate = strings.TrimRight(l.Href, "/")
+			}
+		}
+		href := alternate
+		if href == "" {
+			href = edit
+		}
+		if href == "" {
+			return fail("entry %d has no link to its event", i)
+		}
+		if edit != "" && alternate != "" && edit != alternate {
+			return fail("the edit link %s and alternate link %s of entry %d differ", edit, alternate, i)
+		}
+		if seen[href] {
+			return fail("the event %s appears more than once", href)
+		}
+		seen[href] = true
+
+		j := strings.LastIndex(href, "/")
+		n, err := strconv.Atoi(href[j+1:])
+		if err != nil {
+			return fail("the link %s of entry %d has no event number", href, i)
+		}
+		stream := href[:j]
+		if prev, ok := last[stream]; ok && n >= prev {
+			return fail("event %d of %s follows event %d", n, stream, prev)
+		}
+		last[stream] = n
+	}
+	return nil
+}
+
+// checkEvent validates the event read from url in strict mode.
+func (c *Client) checkEvent(url string, er *EventResponse) error {
+	if !c.isStrict() {
+		return nil
+	}
+	fail := func(format string, a ...interface{}) error {
+		return &ErrProtocol{URL: url, Reason: fmt.Sprintf(format, a...)}
+	}
+
+	e := er.Event
+	switch {
+	case e == nil:
+		return fail("the response has no event")
+	case e.EventStreamID == "":
+		return fail("the event has no stream")
+	case e.EventType == "":
+		return fail("the event has no type")
+	case !validEventID(e.EventID):
+		return fail("the event id %q is not a UUID", e.EventID)
+	}
+	u := strings.TrimRight(url, "/")
+	if n, err := strconv.Atoi(u[strings.LastIndex(u, "/")+1:]); err == nil && n != e.EventNumber {
+		return fail("the event number %d does not match the url", e.EventNumber)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jetbasrawi/go.geteventstore/internal/atom"
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&StrictSuite{})
+
+type StrictSuite struct{}
+
+func (s *StrictSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *StrictSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// serveCorruptFeed serves a page of the stream that has been changed by
+// corrupt and returns its path.
+func serveCorruptFeed(c *C, stream string, corrupt func(f *atom.Feed)) string {
+	path := fmt.Sprintf("/streams/%s/head/backward/20", stream)
+	f, err := CreateTestFeed(CreateTestEvents(3, stream, server.URL, "Foo"), server.URL+path)
+	c.Assert(err, IsNil)
+	corrupt(f)
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, f.PrettyPrint())
+	})
+	return path
+}
+
+func (s *StrictSuite) TestValidStreamIsReadInStrictMode(c *C) {
+	stream := "strict-valid"
+	setupSimulator(CreateTestEvents(30, stream, server.URL, "Foo"), nil)
+	client.SetStrict(true)
+
+	reader := client.NewStreamReader(stream)
+	n := 0
+	for reader.Next() {
+		if reader.Err() != nil {
+			break
+		}
+		n++
+	}
+	c.Assert(reader.Err(), FitsTypeOf, &ErrNoMoreEvents{})
+	c.Assert(n, Equals, 30)
+}
+
+func (s *StrictSuite) TestCorruptFeedsAreRejected(c *C) {
+	cases := []struct {
+		corrupt func(f *atom.Feed)
+		reason  string
+	}{
+		{func(f *atom.Feed) {
+			f.Link = f.Link[1:]
+		}, "the feed has no self link"},
+		{func(f *atom.Feed) {
+			f.Link = append(f.Link, atom.Link{Rel: "next", Href: server.URL + "/streams/other/0/forward/20"})
+		}, "the next link .* is not a link of the stream .*"},
+		{func(f *atom.Feed) {
+			f.Entry[1].Link = nil
+		}, "entry 1 has no link to its event"},
+		{func(f *atom.Feed) {
+			f.Entry[0].Link[0].Href = f.Entry[1].Link[0].Href
+		}, "the edit link .* and alternate link .* of entry 0 differ"},
+		{func(f *atom.Feed) {
+			f.Entry = append(f.Entry, f.Entry[0])
+		}, "the event .*/2 appears more than once"},
+		{func(f *atom.Feed) {
+			f.Entry[0], f.Entry[1] = f.Entry[1], f.Entry[0]
+		}, "event 2 of .* follows event 1"},
+	}
+
+	client.SetStrict(true)
+	for i, t := range cases {
+		path := serveCorruptFeed(c, fmt.Sprintf("strict-%d", i), t.corrupt)
+		_, _, err := client.ReadFeed(path)
+		c.Assert(err, FitsTypeOf, &ErrProtocol{}, Commentf("case %d", i))
+		c.Assert(err.(*ErrProtocol).Reason, Matches, t.reason)
+	}
+
+	client.SetStrict(false)
+	path := serveCorruptFeed(c, "strict-off", cases[4].corrupt)
+	f, _, err := client.ReadFeed(path)
+	c.Assert(err, IsNil)
+	c.Assert(f.Entry, HasLen, 4)
+}
+
+func (s *StrictSuite) TestCorruptEventsAreRejected(c *C) {
+	e := CreateTestEvent("strict-events", server.URL, "Foo", 3, &json.RawMessage{'1'}, nil)
+	mux.HandleFunc("/streams/strict-events/", func(w http.ResponseWriter, r *http.Request) {
+		er, err := CreateTestEventAtomResponse(e, nil)
+		c.Assert(err, IsNil)
+		fmt.Fprint(w, er.PrettyPrint())
+	})
+	client.SetStrict(true)
+
+	_, _, err := client.GetEvent("/streams/strict-events/3")
+	c.Assert(err, IsNil)
+
+	_, _, err = client.GetEvent("/streams/strict-events/4")
+	c.Assert(err, DeepEquals, &ErrProtocol{
+		URL:    "/streams/strict-events/4",
+		Reason: "the event number 3 does not match the url",
+	})
+	c.Assert(err, ErrorMatches, "Invalid response from /streams/strict-events/4: the event number 3 does not match the url.")
+
+	e.EventID = "not-a-uuid"
+	_, _, err = client.GetEvent("/streams/strict-events/3")
+	c.Assert(err, ErrorMatches, ".*the event id \"not-a-uuid\" is not a UUID.")
+
+	e.EventType = ""
+	_, _, err = client.GetEvent("/streams/strict-events/3")
+	c.Assert(err, ErrorMatches, ".*the event has no type.")
+
+	client.SetStrict(false)
+	_, _, err = client.GetEvent("/streams/strict-events/3")
+	c.Assert(err, IsNil)
+}
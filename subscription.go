@@ -0,0 +1,405 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMinBackoff   = 500 * time.Millisecond
+	defaultMaxBackoff   = 30 * time.Second
+	defaultPollInterval = time.Second
+)
+
+// Subscription delivers the events in a stream to a handler as they are
+// written, recovering from errors.
+//
+// A catch-up subscription delivers the events in the stream from a given event
+// number and then continues to deliver new events. A volatile subscription
+// delivers only the events written after it was started.
+//
+// When reading the stream fails, for example because the connection to the
+// server is lost, the subscription calls the dropped handler, backs off and
+// then resumes reading from the event after the last event delivered. When
+// reading succeeds again the reconnected handler is called. Each attempt makes
+// new requests through the http client of the Client, so a server address that
+// resolves to a different node is resolved again.
+//
+// If the handler returns an error, or the server refuses access to the stream,
+// the subscription stops and Err returns the error.
+type Subscription struct {
+	client      *Client
+	stream      string
+	from        int
+	volatile    bool
+	handler     func(*EventResponse) error
+	ctxHandler  func(context.Context, *EventResponse) error
+	timeout     TimeoutPolicy
+	onError     EventErrorPolicy
+	reporter    ErrorReporter
+	holdStream  string
+	dropped     func(error)
+	reconnected func(int)
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	poll        PollStrategy
+	longPoll    int
+	mu          sync.Mutex
+	last        int
+	processed   int64
+	meter       rateMeter
+	err         error
+	stop        chan struct{}
+	drain       chan struct{}
+	done        chan struct{}
+}
+
+// NewCatchUpSubscription returns a subscription that delivers the events in
+// the stream to handler starting from the event number from.
+func (c *Client) NewCatchUpSubscription(stream string, from int, handler func(*EventResponse) error) *Subscription {
+	return c.newSubscription(stream, from, false, handler)
+}
+
+// NewVolatileSubscription returns a subscription that delivers the events
+// written to the stream after the subscription is started to handler.
+func (c *Client) NewVolatileSubscription(stream string, handler func(*EventResponse) error) *Subscription {
+	return c.newSubscription(stream, 0, true, handler)
+}
+
+func (c *Client) newSubscription(stream string, from int, volatile bool, handler func(*EventResponse) error) *Subscription {
+	return &Subscription{
+		client:     c,
+		stream:     stream,
+		from:       from,
+		volatile:   volatile,
+		handler:    handler,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+		poll:       FixedPoll{Interval: defaultPollInterval},
+		last:       from - 1,
+	}
+}
+
+// SetDroppedHandler sets a function that is called with the error when reading
+// the stream fails. The subscription will retry unless it has stopped.
+func (s *Subscription) SetDroppedHandler(fn func(err error)) {
+	s.dropped = fn
+}
+
+// SetReconnectedHandler sets a function that is called when reading succeeds
+// after the subscription was dropped. It is called with the position of the
+// last event delivered. See LastProcessed.
+func (s *Subscription) SetReconnectedHandler(fn func(lastProcessed int)) {
+	s.reconnected = fn
+}
+
+// SetBackoff sets the minimum and maximum time to wait before retrying after
+// the subscription is dropped. The wait doubles after each failed attempt. If
+// the server is busy and asks for a longer wait with Retry-After the
+// subscription waits for that long instead.
+func (s *Subscription) SetBackoff(min, max time.Duration) {
+	s.minBackoff = min
+	s.maxBackoff = max
+}
+
+// SetPollInterval sets the time to wait before reading the head of the stream
+// again when there are no new events. The default is one second.
+//
+// It is equivalent to setting a FixedPoll with SetPollStrategy.
+func (s *Subscription) SetPollInterval(d time.Duration) {
+	s.poll = FixedPoll{Interval: d}
+}
+
+// SetLongPoll causes the subscription to long poll the head of the stream for
+// the number of seconds provided instead of waiting for the poll interval.
+//
+// The ES-LongPoll header is sent only with the requests of the subscription,
+// not with the other requests of the client. See StreamReader.LongPoll.
+func (s *Subscription) SetLongPoll(seconds int) {
+	s.longPoll = seconds
+}
+
+// LastProcessed returns the position in the stream of the last event delivered
+// to the handler, from which the subscription resumes.
+//
+// The position is the version of the stream at the event. For a stream such as
+// $ce-order, whose entries link to events in other streams, it is not the event
+// number of the event delivered, which is its number in the stream it was
+// written to.
+func (s *Subscription) LastProcessed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Err returns the error that stopped the subscription, or nil if the
+// subscription has not failed.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Start starts delivering events to the handler.
+func (s *Subscription) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.drain = make(chan struct{})
+	s.done = make(chan struct{})
+	s.err = nil
+	go s.run(s.stop, s.drain, s.done)
+}
+
+// Stop stops the subscription and waits for any call to the handler in
+// progress to return.
+func (s *Subscription) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop = nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Shutdown stops the subscription gracefully. It stops reading the stream,
+// lets the event being delivered finish, including any retries of the
+// EventErrorPolicy, and returns Err once the subscription has stopped.
+//
+// If ctx is done before then the subscription is stopped as with Stop and
+// ctx.Err() is returned.
+func (s *Subscription) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	stop, drain, done := s.stop, s.drain, s.done
+	s.stop = nil
+	s.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	close(drain)
+	select {
+	case <-done:
+		return s.Err()
+	case <-ctx.Done():
+		close(stop)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed when the subscription stops.
+func (s *Subscription) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// run reads the stream until stop or drain is closed or a permanent error
+// occurs.
+func (s *Subscription) run(stop, drain, done chan struct{}) {
+	defer close(done)
+
+	// Requests in progress, including long polls, are cancelled when the
+	// subscription is stopped or drained. Retries of the event being
+	// delivered are only abandoned when it is stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	readCtx, cancelRead := context.WithCancel(ctx)
+	defer cancelRead()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-drain:
+			cancelRead()
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		case <-ctx.Done():
+		}
+	}()
+
+	backoff := s.minBackoff
+	failed := false
+	idle := 0
+
+	wait := func(d time.Duration) bool {
+		select {
+		case <-stop:
+			return false
+		case <-drain:
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+
+	drop := func(err error) bool {
+		s.reporter.Report(s.component(), err, false)
+		if s.dropped != nil {
+			s.dropped(err)
+		}
+		failed = true
+		// A busy server is given at least the time it asked for.
+		d := backoff
+		if after, ok := RetryAfter(err); ok && after > d {
+			d = after
+		}
+		if !wait(d) {
+			return false
+		}
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+		return true
+	}
+
+	recovered := func() {
+		if failed && s.reconnected != nil {
+			s.reconnected(s.LastProcessed())
+		}
+		failed = false
+		backoff = s.minBackoff
+	}
+
+	if s.volatile {
+		for {
+			v, err := s.client.GetStreamHeadVersion(s.stream)
+			switch err.(type) {
+			case nil:
+			case *ErrNotFound, *ErrNoEvents:
+				v = -1
+			default:
+				if s.permanent(err) || !drop(err) {
+					return
+				}
+				continue
+			}
+			s.mu.Lock()
+			s.last = v
+			s.mu.Unlock()
+			recovered()
+			break
+		}
+	}
+
+	for {
+		reader := s.client.NewStreamReader(s.stream)
+		reader.parent = s.readContext(readCtx)
+		reader.NextVersion(s.LastProcessed() + 1)
+
+		for reader.Next() {
+			select {
+			case <-stop:
+				return
+			case <-drain:
+				return
+			default:
+			}
+
+			if err := reader.Err(); err != nil {
+				switch err.(type) {
+				case *ErrNoMoreEvents, *ErrNotFound:
+					recovered()
+					idle++
+					// The requests of the next reader long poll the head
+					// of the stream if long polling is enabled.
+					if !s.longPolls() && !wait(s.poll.Wait(idle)) {
+						return
+					}
+				default:
+					if s.permanent(err) || !drop(err) {
+						return
+					}
+				}
+				break
+			}
+
+			recovered()
+			idle = 0
+			er := reader.EventResponse()
+			if err := s.process(ctx, reader.Version(), er); err != nil {
+				s.fail(err)
+				return
+			}
+			s.mu.Lock()
+			s.last = reader.Version()
+			s.processed++
+			s.mu.Unlock()
+			s.meter.mark(time.Now())
+		}
+	}
+}
+
+// longPolls returns true if the subscription long polls the head of the
+// stream.
+func (s *Subscription) longPolls() bool {
+	return s.longPoll > 0 && s.client.supportsLongPoll()
+}
+
+// readContext returns the context for the requests of a reader of the
+// subscription. When the subscription long polls the requests carry the
+// ES-LongPoll header, rather than setting it on the client for every request.
+func (s *Subscription) readContext(ctx context.Context) context.Context {
+	if !s.longPolls() {
+		return ctx
+	}
+	return WithRequestHeaders(ctx, map[string]string{"ES-LongPoll": strconv.Itoa(s.longPoll)})
+}
+
+type feedPositionKey struct{}
+
+// FeedPosition returns the position in the stream of the event delivered to a
+// handler set with Subscription.SetContextHandler, and false if ctx is not the
+// context of a subscription handler.
+//
+// The position is the version of the subscribed stream at the event, which is
+// the value to checkpoint in order to resume after it. For a stream such as
+// $ce-order it differs from the event number of the event.
+func FeedPosition(ctx context.Context) (int, bool) {
+	pos, ok := ctx.Value(feedPositionKey{}).(int)
+	return pos, ok
+}
+
+// permanent stops the subscription if the error cannot be recovered by
+// retrying and returns true if it did.
+func (s *Subscription) permanent(err error) bool {
+	if _, ok := err.(*ErrUnauthorized); ok {
+		s.fail(err)
+		return true
+	}
+	return false
+}
+
+// fail records the error that stopped the subscription and reports it to the
+// dropped handler.
+func (s *Subscription) fail(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	s.reporter.Report(s.component(), err, true)
+	if s.dropped != nil {
+		s.dropped(err)
+	}
+}
+
+// component returns the name of the subscription used in errors.
+func (s *Subscription) component() string {
+	return "Subscription to " + s.stream
+}
@@ -0,0 +1,302 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SubscriptionSuite{})
+
+type SubscriptionSuite struct{}
+
+func (s *SubscriptionSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *SubscriptionSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// received collects the event numbers delivered to a subscription.
+type received struct {
+	mu     sync.Mutex
+	events []int
+}
+
+func (r *received) handle(er *EventResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, er.Event.EventNumber)
+	return nil
+}
+
+func (r *received) get() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int{}, r.events...)
+}
+
+func (s *SubscriptionSuite) TestCatchUpSubscriptionDeliversNewEvents(c *C) {
+	// The simulator serves the first five events and then serves one more
+	// event in response to each long poll.
+	es := CreateTestEvents(8, "sub-1", server.URL, "Foo")
+	u, _ := url.Parse(server.URL)
+	sim, _ := NewAtomFeedSimulator(es, u, nil, 5)
+	mux.Handle("/", sim)
+
+	got := &received{}
+	sub := client.NewCatchUpSubscription("sub-1", 2, got.handle)
+	sub.SetLongPoll(1)
+	sub.Start()
+	defer sub.Stop()
+
+	eventually(func() bool { return len(got.get()) == 6 })
+
+	c.Assert(got.get(), DeepEquals, []int{2, 3, 4, 5, 6, 7})
+	c.Assert(sub.LastProcessed(), Equals, 7)
+}
+
+func (s *SubscriptionSuite) TestSubscriptionReconnectsFromLastProcessed(c *C) {
+	es := CreateTestEvents(10, "sub-2", server.URL, "Foo")
+	sim := newTestSimulator(es, nil)
+
+	var mu sync.Mutex
+	failures := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := failures > 0
+		if fail {
+			failures--
+		}
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		sim.ServeHTTP(w, r)
+	})
+
+	got := &received{}
+	dropped := 0
+	reconnected := -2
+	sub := client.NewCatchUpSubscription("sub-2", 0, func(er *EventResponse) error {
+		if er.Event.EventNumber == 4 {
+			mu.Lock()
+			failures = 3
+			mu.Unlock()
+		}
+		return got.handle(er)
+	})
+	sub.SetBackoff(time.Millisecond, 4*time.Millisecond)
+	sub.SetPollInterval(5 * time.Millisecond)
+	sub.SetDroppedHandler(func(err error) {
+		c.Assert(err, FitsTypeOf, &ErrTemporarilyUnavailable{})
+		dropped++
+	})
+	sub.SetReconnectedHandler(func(last int) {
+		reconnected = last
+	})
+	sub.Start()
+
+	eventually(func() bool { return len(got.get()) == 10 })
+	sub.Stop()
+
+	c.Assert(got.get(), DeepEquals, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	c.Assert(dropped, Equals, 3)
+	c.Assert(reconnected, Equals, 4)
+	c.Assert(sub.Err(), IsNil)
+}
+
+func (s *SubscriptionSuite) TestHandlerErrorStopsSubscription(c *C) {
+	setupSimulator(CreateTestEvents(5, "sub-3", server.URL, "Foo"), nil)
+
+	var dropped error
+	sub := client.NewCatchUpSubscription("sub-3", 0, func(er *EventResponse) error {
+		if er.Event.EventNumber == 2 {
+			return errors.New("bad event")
+		}
+		return nil
+	})
+	sub.SetDroppedHandler(func(err error) { dropped = err })
+	sub.Start()
+	<-sub.Done()
+
+	c.Assert(sub.Err(), ErrorMatches, "bad event")
+	c.Assert(dropped, Equals, sub.Err())
+	c.Assert(sub.LastProcessed(), Equals, 1)
+}
+
+func (s *SubscriptionSuite) TestUnauthorizedStopsSubscription(c *C) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	sub := client.NewCatchUpSubscription("sub-4", 0, func(er *EventResponse) error { return nil })
+	sub.Start()
+	<-sub.Done()
+	c.Assert(sub.Err(), FitsTypeOf, &ErrUnauthorized{})
+}
+
+func (s *SubscriptionSuite) TestVolatileSubscriptionStartsAtHead(c *C) {
+	es := CreateTestEvents(8, "sub-5", server.URL, "Foo")
+	u, _ := url.Parse(server.URL)
+	sim, _ := NewAtomFeedSimulator(es, u, nil, 5)
+	mux.Handle("/", sim)
+
+	got := &received{}
+	sub := client.NewVolatileSubscription("sub-5", got.handle)
+	sub.SetLongPoll(1)
+	sub.Start()
+	defer sub.Stop()
+
+	eventually(func() bool { return len(got.get()) == 3 })
+
+	c.Assert(got.get(), DeepEquals, []int{5, 6, 7})
+}
+
+func (s *SubscriptionSuite) TestStopCancelsLongPoll(c *C) {
+	open, _ := serveLongPoll("sub-6")
+
+	sub := client.NewCatchUpSubscription("sub-6", 0, func(er *EventResponse) error { return nil })
+	sub.Start()
+	eventually(func() bool { return atomic.LoadInt32(open) == 1 })
+	c.Assert(atomic.LoadInt32(open), Equals, int32(1))
+
+	stopped := make(chan struct{})
+	go func() {
+		sub.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		c.Fatal("Stop did not cancel the long poll")
+	}
+	c.Assert(sub.Err(), IsNil)
+}
+
+func (s *SubscriptionSuite) TestShutdownFinishesEventInProgress(c *C) {
+	setupSimulator(CreateTestEvents(5, "sub-7", server.URL, "Foo"), nil)
+
+	got := &received{}
+	failing := make(chan struct{})
+	attempts := 0
+	sub := client.NewCatchUpSubscription("sub-7", 0, func(er *EventResponse) error {
+		if er.Event.EventNumber == 1 {
+			attempts++
+			if attempts == 1 {
+				close(failing)
+				return errors.New("not yet")
+			}
+		}
+		return got.handle(er)
+	})
+	sub.SetEventErrorPolicy(EventErrorPolicy{Action: EventErrorStop, Retries: 1, RetryDelay: 50 * time.Millisecond})
+	sub.Start()
+	<-failing
+
+	// The retry of the event is delayed and is still made after the
+	// subscription is shut down.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c.Assert(sub.Shutdown(ctx), IsNil)
+	c.Assert(got.get(), DeepEquals, []int{0, 1})
+	c.Assert(sub.LastProcessed(), Equals, 1)
+	c.Assert(sub.Shutdown(ctx), IsNil)
+}
+
+func (s *SubscriptionSuite) TestShutdownStopsWhenContextIsDone(c *C) {
+	setupSimulator(CreateTestEvents(5, "sub-8", server.URL, "Foo"), nil)
+
+	failing := make(chan struct{})
+	sub := client.NewCatchUpSubscription("sub-8", 0, func(er *EventResponse) error {
+		if er.Event.EventNumber == 1 {
+			close(failing)
+			return errors.New("bad event")
+		}
+		return nil
+	})
+	sub.SetEventErrorPolicy(EventErrorPolicy{Action: EventErrorStop, Retries: 1, RetryDelay: time.Hour})
+	sub.Start()
+	<-failing
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c.Assert(sub.Shutdown(ctx), Equals, context.DeadlineExceeded)
+	c.Assert(sub.Err(), ErrorMatches, "bad event")
+	c.Assert(sub.LastProcessed(), Equals, 0)
+}
+
+func (s *SubscriptionSuite) TestSubscriptionToLinkedStreamResumesFromFeedPosition(c *C) {
+	// Both events are event 0 of their own streams.
+	serveLinkedStream("$ce-order", interleaved(1, "order-1", "order-2"))
+
+	type delivered struct {
+		stream   string
+		position int
+	}
+	var mu sync.Mutex
+	got := []delivered{}
+	sub := client.NewCatchUpSubscription("$ce-order", 0, nil)
+	sub.SetContextHandler(func(ctx context.Context, er *EventResponse) error {
+		pos, ok := FeedPosition(ctx)
+		c.Check(ok, Equals, true)
+		mu.Lock()
+		got = append(got, delivered{er.Event.EventStreamID, pos})
+		mu.Unlock()
+		return nil
+	})
+	sub.SetPollInterval(5 * time.Millisecond)
+	sub.Start()
+
+	eventually(func() bool { return sub.LastProcessed() == 1 })
+	time.Sleep(50 * time.Millisecond)
+	sub.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(got, DeepEquals, []delivered{{"order-1", 0}, {"order-2", 1}})
+	c.Assert(sub.LastProcessed(), Equals, 1)
+	lag, err := sub.Lag()
+	c.Assert(err, IsNil)
+	c.Assert(lag, Equals, 0)
+}
+
+func (s *SubscriptionSuite) TestLongPollIsNotSetOnTheClient(c *C) {
+	headers := make(chan string, 10)
+	sim := newTestSimulator(CreateTestEvents(2, "sub-9", server.URL, "Foo"), nil)
+	mux.HandleFunc("/streams/sub-9/", func(w http.ResponseWriter, r *http.Request) {
+		if eventPath.MatchString(r.URL.Path) {
+			sim.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case headers <- r.Header.Get("ES-LongPoll"):
+		default:
+		}
+		sim.ServeHTTP(w, r)
+	})
+
+	sub := client.NewCatchUpSubscription("sub-9", 0, func(er *EventResponse) error { return nil })
+	sub.SetLongPoll(1)
+	sub.Start()
+	defer sub.Stop()
+	eventually(func() bool { return sub.LastProcessed() == 1 })
+
+	c.Assert(<-headers, Equals, "1")
+	c.Assert(client.headers["ES-LongPoll"], Equals, "")
+	req, err := client.newRequest(http.MethodGet, "/streams/sub-9", nil)
+	c.Assert(err, IsNil)
+	c.Assert(req.Header.Get("ES-LongPoll"), Equals, "")
+}
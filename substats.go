@@ -0,0 +1,116 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"sync"
+	"time"
+)
+
+// rateWindow is the number of seconds over which processing rates are
+// measured.
+const rateWindow = 10
+
+// rateMeter measures the rate of events over the last rateWindow seconds.
+//
+// Events are counted in one bucket per second. A bucket is reused for a later
+// second once the second it counted has left the window.
+type rateMeter struct {
+	mu      sync.Mutex
+	start   time.Time
+	seconds [rateWindow]int64
+	counts  [rateWindow]int64
+}
+
+// mark counts an event at now.
+func (m *rateMeter) mark(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.start.IsZero() {
+		m.start = now
+	}
+	sec := now.Unix()
+	i := sec % rateWindow
+	if m.seconds[i] != sec {
+		m.seconds[i] = sec
+		m.counts[i] = 0
+	}
+	m.counts[i]++
+}
+
+// rate returns the number of events per second counted in the window ending at
+// now. The rate is measured over the time since the first event if the window
+// is longer.
+func (m *rateMeter) rate(now time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.start.IsZero() {
+		return 0
+	}
+
+	sec := now.Unix()
+	var n int64
+	for i := range m.seconds {
+		if age := sec - m.seconds[i]; age >= 0 && age < rateWindow {
+			n += m.counts[i]
+		}
+	}
+
+	span := now.Sub(m.start).Seconds()
+	if span > rateWindow {
+		span = rateWindow
+	}
+	if span < 1 {
+		span = 1
+	}
+	return float64(n) / span
+}
+
+// SubscriptionStats describes the progress of a subscription.
+//
+// LastProcessed is the event number of the last event delivered to the
+// handler. Processed is the number of events delivered since the subscription
+// was created and Rate is the number of events per second delivered over the
+// last ten seconds.
+type SubscriptionStats struct {
+	Stream        string
+	LastProcessed int
+	Processed     int64
+	Rate          float64
+}
+
+// Stats returns the statistics of the subscription.
+func (s *Subscription) Stats() SubscriptionStats {
+	s.mu.Lock()
+	stats := SubscriptionStats{
+		Stream:        s.stream,
+		LastProcessed: s.last,
+		Processed:     s.processed,
+	}
+	s.mu.Unlock()
+	stats.Rate = s.meter.rate(time.Now())
+	return stats
+}
+
+// Lag returns the number of events in the stream after the last event
+// delivered to the handler.
+//
+// The head of the stream is read from the server. A stream that does not
+// exist or has no events has no lag.
+func (s *Subscription) Lag() (int, error) {
+	head, err := s.client.GetStreamHeadVersion(s.stream)
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound, *ErrNoEvents:
+		return 0, nil
+	default:
+		return 0, err
+	}
+	if lag := head - s.LastProcessed(); lag > 0 {
+		return lag, nil
+	}
+	return 0, nil
+}
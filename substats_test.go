@@ -0,0 +1,88 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&SubscriptionStatsSuite{})
+
+type SubscriptionStatsSuite struct{}
+
+func (s *SubscriptionStatsSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *SubscriptionStatsSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *SubscriptionStatsSuite) TestLagAndStats(c *C) {
+	setupSimulator(CreateTestEvents(10, "stats-1", server.URL, "Foo"), nil)
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	sub := client.NewCatchUpSubscription("stats-1", 0, func(er *EventResponse) error {
+		if er.Event.EventNumber == 6 {
+			close(blocked)
+			<-release
+		}
+		return nil
+	})
+	sub.Start()
+	defer sub.Stop()
+
+	<-blocked
+	lag, err := sub.Lag()
+	c.Assert(err, IsNil)
+	c.Assert(lag, Equals, 4)
+	stats := sub.Stats()
+	c.Assert(stats.Stream, Equals, "stats-1")
+	c.Assert(stats.LastProcessed, Equals, 5)
+	c.Assert(stats.Processed, Equals, int64(6))
+	c.Assert(stats.Rate > 0, Equals, true)
+
+	close(release)
+	eventually(func() bool { return sub.LastProcessed() == 9 })
+	lag, err = sub.Lag()
+	c.Assert(err, IsNil)
+	c.Assert(lag, Equals, 0)
+	c.Assert(sub.Stats().Processed, Equals, int64(10))
+}
+
+func (s *SubscriptionStatsSuite) TestLagOfMissingStream(c *C) {
+	mux.HandleFunc("/", http.NotFound)
+	sub := client.NewCatchUpSubscription("stats-2", 0, func(*EventResponse) error { return nil })
+
+	lag, err := sub.Lag()
+	c.Assert(err, IsNil)
+	c.Assert(lag, Equals, 0)
+	c.Assert(sub.Stats().Rate, Equals, 0.0)
+}
+
+func (s *SubscriptionStatsSuite) TestRateMeter(c *C) {
+	m := &rateMeter{}
+	start := time.Unix(1000, 0)
+	c.Assert(m.rate(start), Equals, 0.0)
+
+	// 10 events in the first second, then 5 per second for four seconds.
+	for i := 0; i < 10; i++ {
+		m.mark(start.Add(time.Duration(i) * 100 * time.Millisecond))
+	}
+	c.Assert(m.rate(start.Add(500*time.Millisecond)), Equals, 10.0)
+	for sec := 1; sec < 5; sec++ {
+		for i := 0; i < 5; i++ {
+			m.mark(start.Add(time.Duration(sec)*time.Second + time.Duration(i)*100*time.Millisecond))
+		}
+	}
+	c.Assert(m.rate(start.Add(5*time.Second)), Equals, 6.0)
+
+	// Events leave the window after rateWindow seconds.
+	c.Assert(m.rate(start.Add(12*time.Second)), Equals, 1.0)
+	c.Assert(m.rate(start.Add(time.Minute)), Equals, 0.0)
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// TimeFormat is the layout of the times formatted by FormatTime. It is the
+// layout of the times reported by the eventstore, which have up to seven
+// digits of fractional seconds.
+const TimeFormat = "2006-01-02T15:04:05.9999999Z07:00"
+
+// timeLayouts are the layouts accepted by ParseTime. Times without a time zone
+// are in UTC.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+}
+
+// ParseTime parses a time reported by the eventstore and returns it in UTC.
+//
+// Times with and without fractional seconds and with and without a time zone
+// are accepted. Times without a time zone are taken to be in UTC.
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Cannot parse %q as an eventstore time", s)
+}
+
+// FormatTime returns t in UTC as a TimeStr in the format of the eventstore.
+// Unlike Time, fractional seconds are kept.
+func FormatTime(t time.Time) TimeStr {
+	return TimeStr(t.UTC().Format(TimeFormat))
+}
+
+// Time returns the time parsed with ParseTime.
+func (t TimeStr) Time() (time.Time, error) {
+	return ParseTime(string(t))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The text is
+// the time as it was reported.
+func (t TimeStr) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The text is
+// kept as it is, so that a time that cannot be parsed does not prevent the
+// rest of a response being read. Use Time to parse it.
+func (t *TimeStr) UnmarshalText(text []byte) error {
+	*t = TimeStr(text)
+	return nil
+}
+
+// Value implements the driver.Valuer interface. The time is stored as a
+// time.Time in UTC, and an empty TimeStr is stored as NULL.
+func (t TimeStr) Value() (driver.Value, error) {
+	if t == "" {
+		return nil, nil
+	}
+	return t.Time()
+}
+
+// Scan implements the sql.Scanner interface. A time.Time is formatted with
+// FormatTime, a string or []byte is kept as it is and NULL is scanned as an
+// empty TimeStr.
+func (t *TimeStr) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*t = ""
+	case time.Time:
+		*t = FormatTime(src)
+	case string:
+		*t = TimeStr(src)
+	case []byte:
+		*t = TimeStr(src)
+	default:
+		return fmt.Errorf("Cannot scan %T into a TimeStr", src)
+	}
+	return nil
+}
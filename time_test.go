@@ -0,0 +1,113 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&TimeSuite{})
+
+type TimeSuite struct{}
+
+func (s *TimeSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *TimeSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+var eventStoreTime = time.Date(2016, 6, 1, 14, 0, 0, 123456700, time.UTC)
+
+func (s *TimeSuite) TestParseTime(c *C) {
+	for _, in := range []string{
+		"2016-06-01T14:00:00.1234567Z",
+		"2016-06-01T16:00:00.1234567+02:00",
+		"2016-06-01T14:00:00.1234567",
+	} {
+		t, err := ParseTime(in)
+		c.Assert(err, IsNil, Commentf("%s", in))
+		c.Assert(t, Equals, eventStoreTime, Commentf("%s", in))
+	}
+
+	t, err := ParseTime("2016-06-01T14:00:00+00:00")
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, eventStoreTime.Truncate(time.Second))
+
+	_, err = ParseTime("yesterday")
+	c.Assert(err, ErrorMatches, `Cannot parse "yesterday" as an eventstore time`)
+}
+
+func (s *TimeSuite) TestFormatTime(c *C) {
+	local := eventStoreTime.In(time.FixedZone("CEST", 2*60*60))
+	c.Assert(FormatTime(local), Equals, TimeStr("2016-06-01T14:00:00.1234567Z"))
+	c.Assert(Time(local), Equals, TimeStr("2016-06-01T14:00:00Z"))
+
+	t, err := FormatTime(local).Time()
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, eventStoreTime)
+}
+
+func (s *TimeSuite) TestTextMarshaling(c *C) {
+	v := struct {
+		Updated TimeStr `json:"updated"`
+	}{}
+	in := `{"updated":"2016-06-01T16:00:00.1234567+02:00"}`
+	c.Assert(json.Unmarshal([]byte(in), &v), IsNil)
+	c.Assert(v.Updated, Equals, TimeStr("2016-06-01T16:00:00.1234567+02:00"))
+
+	out, err := json.Marshal(v)
+	c.Assert(err, IsNil)
+	c.Assert(string(out), Equals, in)
+}
+
+func (s *TimeSuite) TestValueAndScan(c *C) {
+	var _ driver.Valuer = TimeStr("")
+
+	v, err := TimeStr("2016-06-01T16:00:00.1234567+02:00").Value()
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, eventStoreTime)
+
+	v, err = TimeStr("").Value()
+	c.Assert(err, IsNil)
+	c.Assert(v, IsNil)
+
+	_, err = TimeStr("yesterday").Value()
+	c.Assert(err, NotNil)
+
+	var t TimeStr
+	c.Assert(t.Scan(eventStoreTime), IsNil)
+	c.Assert(t, Equals, TimeStr("2016-06-01T14:00:00.1234567Z"))
+	c.Assert(t.Scan([]byte("2016-06-01T14:00:00Z")), IsNil)
+	c.Assert(t, Equals, TimeStr("2016-06-01T14:00:00Z"))
+	c.Assert(t.Scan(nil), IsNil)
+	c.Assert(t, Equals, TimeStr(""))
+	c.Assert(t.Scan(42), ErrorMatches, "Cannot scan int into a TimeStr")
+}
+
+func (s *TimeSuite) TestReaderNormalizesUpdated(c *C) {
+	stream := "times-1"
+	e := CreateTestEvent(stream, server.URL, "Foo", 0, nil, nil)
+	updated := TimeStr("2016-06-01T16:00:00.1234567+02:00")
+	mux.HandleFunc("/streams/"+stream+"/0", func(w http.ResponseWriter, r *http.Request) {
+		er, err := CreateTestEventAtomResponse(e, &updated)
+		c.Assert(err, IsNil)
+		json.NewEncoder(w).Encode(er)
+	})
+
+	er, _, err := client.GetEvent("/streams/" + stream + "/0")
+	c.Assert(err, IsNil)
+	c.Assert(er.Updated, Equals, TimeStr("2016-06-01T14:00:00.1234567Z"))
+
+	t, ok := EventTime(er)
+	c.Assert(ok, Equals, true)
+	c.Assert(t, Equals, eventStoreTime)
+}
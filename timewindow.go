@@ -0,0 +1,193 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// EventTime returns the time the event was written, as reported in the Updated
+// field of the event response. The second value returned is false if the time
+// cannot be parsed.
+func EventTime(er *EventResponse) (time.Time, bool) {
+	t, err := er.Updated.Time()
+	return t, err == nil
+}
+
+// ReadCategoryTimeWindow returns the events in all of the streams of a
+// category that were written at or after from and before to, merged in time
+// order.
+//
+// The streams of the category are listed from the $category-<category> stream
+// maintained by the $stream_by_category system projection, which must be
+// running. In each stream the first event in the window is found by a binary
+// search on the times of the events, and the events are then read forward until
+// the end of the window, so only a few events outside the window are read
+// however long the streams are. The search relies on the events of a stream
+// being written in time order, which they are unless the clock of the server
+// has gone backwards.
+//
+// Events written at the same time are returned in the order of their streams,
+// by name, and in stream order within a stream. Events whose time cannot be
+// determined, such as events removed by truncation, are skipped. If the window
+// holds more events than the limit set with SetMaxBufferedEvents an
+// *ErrTooManyEvents is returned with no events, identifying the stream and event
+// at which the read stopped.
+func (c *Client) ReadCategoryTimeWindow(category string, from, to time.Time) ([]*EventResponse, error) {
+	streams, err := c.categoryStreams(category)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []*EventResponse{}
+	for _, stream := range streams {
+		es, err := c.readTimeWindow(stream, from, to, len(ret))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, es...)
+	}
+
+	// The events of each stream are in time order and the streams are in order
+	// of name, so a stable sort merges them.
+	sort.SliceStable(ret, func(i, j int) bool {
+		ti, _ := EventTime(ret[i])
+		tj, _ := EventTime(ret[j])
+		return ti.Before(tj)
+	})
+	return ret, nil
+}
+
+// categoryStreams returns the names of the streams in the category in sorted
+// order.
+func (c *Client) categoryStreams(category string) ([]string, error) {
+	seen := make(map[string]bool)
+	err := c.ForEachEvent("$category-"+category, 0, "forward", func(er *EventResponse) error {
+		seen[er.Event.EventStreamID] = true
+		return nil
+	})
+	if _, ok := err.(*ErrNotFound); ok {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]string, 0, len(seen))
+	for s := range seen {
+		ret = append(ret, s)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// ReadFeedSince returns a *StreamReader positioned at the first event in the
+// stream written at or after t, so that the first call to Next returns that
+// event and the reader then reads forward as usual.
+//
+// The event is found by a binary search on the times of the events, as in
+// ReadCategoryTimeWindow, so only a few events are read however long the
+// stream is. If every event was written before t the reader is positioned
+// after the head of the stream and returns the events written from then on.
+// If the stream does not exist or has no events the reader is positioned at
+// the start of the stream, where Next reports the error as it would for any
+// reader. The opts are applied to the reader as in NewStreamReader.
+func (c *Client) ReadFeedSince(stream string, t time.Time, opts ...ReadOption) (*StreamReader, error) {
+	reader := c.NewStreamReader(stream, opts...)
+	head, err := c.GetStreamHeadVersion(stream)
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound, *ErrNoEvents:
+		reader.NextVersion(0)
+		return reader, nil
+	default:
+		return nil, err
+	}
+
+	start, err := c.searchTime(stream, head, t)
+	if err != nil {
+		return nil, err
+	}
+	reader.NextVersion(start)
+	return reader, nil
+}
+
+// readTimeWindow returns the events in the stream written at or after from
+// and before to. held is the number of events already held by the read.
+func (c *Client) readTimeWindow(stream string, from, to time.Time, held int) ([]*EventResponse, error) {
+	head, err := c.GetStreamHeadVersion(stream)
+	switch err.(type) {
+	case nil:
+	case *ErrNotFound, *ErrNoEvents:
+		return []*EventResponse{}, nil
+	default:
+		return nil, err
+	}
+
+	start, err := c.searchTime(stream, head, from)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []*EventResponse{}
+	for n := start; n <= head; n++ {
+		er, t, err := c.timedEventAt(stream, n)
+		if err != nil {
+			return nil, err
+		}
+		if er == nil {
+			continue
+		}
+		if !t.Before(to) {
+			break
+		}
+		if err := c.checkBuffered(held+len(ret), stream, n); err != nil {
+			return nil, err
+		}
+		ret = append(ret, er)
+	}
+	return ret, nil
+}
+
+// searchTime returns the number of the first event in the stream up to head
+// written at or after t, or head+1 if there is none.
+//
+// Events that have been removed are treated as written before t, as truncation
+// removes the oldest events.
+func (c *Client) searchTime(stream string, head int, t time.Time) (int, error) {
+	var searchErr error
+	start := sort.Search(head+1, func(n int) bool {
+		if searchErr != nil {
+			return true
+		}
+		er, et, err := c.timedEventAt(stream, n)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return er != nil && !et.Before(t)
+	})
+	return start, searchErr
+}
+
+// timedEventAt returns the event in the stream with the event number and its
+// time, or nil if the event has been removed or has no time.
+func (c *Client) timedEventAt(stream string, n int) (*EventResponse, time.Time, error) {
+	er, _, err := c.GetEvent(fmt.Sprintf("/streams/%s/%d", stream, n))
+	if _, ok := err.(*ErrNotFound); ok {
+		return nil, time.Time{}, nil
+	}
+	if err != nil || er == nil {
+		return nil, time.Time{}, err
+	}
+	t, ok := EventTime(er)
+	if !ok {
+		return nil, time.Time{}, nil
+	}
+	return er, t, nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&TimeWindowSuite{})
+
+type TimeWindowSuite struct{}
+
+func (s *TimeWindowSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *TimeWindowSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+var (
+	windowStart = time.Date(2016, 6, 1, 14, 0, 0, 0, time.UTC)
+	eventURL    = regexp.MustCompile(`/streams/[^/]+/(\d+)/?$`)
+)
+
+// timedStream serves a stream whose events were written at the times
+// provided and counts the events requested.
+type timedStream struct {
+	mu    sync.Mutex
+	reads int
+}
+
+func serveTimedStream(c *C, stream string, times []time.Time) *timedStream {
+	es := CreateTestEvents(len(times), stream, server.URL, "Foo")
+	sim := newTestSimulator(es, nil)
+	ts := &timedStream{}
+	mux.HandleFunc("/streams/"+stream+"/", func(w http.ResponseWriter, r *http.Request) {
+		m := eventURL.FindStringSubmatch(r.URL.Path)
+		if m == nil {
+			sim.ServeHTTP(w, r)
+			return
+		}
+		n, _ := strconv.Atoi(m[1])
+		ts.mu.Lock()
+		ts.reads++
+		ts.mu.Unlock()
+		updated := Time(times[n])
+		er, err := CreateTestEventAtomResponse(es[n], &updated)
+		c.Assert(err, IsNil)
+		w.Header().Set("Content-Type", "application/vnd.eventstore.atom+json")
+		json.NewEncoder(w).Encode(er)
+	})
+	return ts
+}
+
+func (ts *timedStream) count() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.reads
+}
+
+// serveCategory serves the $category-<category> stream listing the streams.
+func serveCategory(category string, streams ...string) {
+	es := []*Event{}
+	for _, stream := range streams {
+		es = append(es, CreateTestEvent(stream, server.URL, "$>", 0, nil, nil))
+	}
+	mux.Handle("/streams/$category-"+category+"/", newTestSimulator(es, nil))
+}
+
+// minutes returns times at the minute offsets from windowStart.
+func minutes(offsets ...int) []time.Time {
+	ret := make([]time.Time, len(offsets))
+	for i, m := range offsets {
+		ret[i] = windowStart.Add(time.Duration(m) * time.Minute)
+	}
+	return ret
+}
+
+func (s *TimeWindowSuite) TestReadCategoryTimeWindowMergesStreams(c *C) {
+	serveCategory("order", "order-1", "order-2")
+	serveTimedStream(c, "order-1", minutes(-30, -5, 10, 40, 70))
+	serveTimedStream(c, "order-2", minutes(-10, 0, 20, 59, 60, 90))
+
+	got, err := client.ReadCategoryTimeWindow("order", windowStart, windowStart.Add(time.Hour))
+	c.Assert(err, IsNil)
+
+	type ref struct {
+		stream string
+		number int
+	}
+	refs := []ref{}
+	for _, er := range got {
+		refs = append(refs, ref{er.Event.EventStreamID, er.Event.EventNumber})
+	}
+	c.Assert(refs, DeepEquals, []ref{
+		{"order-2", 1},
+		{"order-1", 2},
+		{"order-2", 2},
+		{"order-1", 3},
+		{"order-2", 3},
+	})
+}
+
+func (s *TimeWindowSuite) TestReadCategoryTimeWindowUsesBinarySearch(c *C) {
+	offsets := make([]int, 1000)
+	for i := range offsets {
+		offsets[i] = i - 500
+	}
+	serveCategory("big", "big-1")
+	ts := serveTimedStream(c, "big-1", minutes(offsets...))
+
+	got, err := client.ReadCategoryTimeWindow("big", windowStart, windowStart.Add(5*time.Minute))
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 5)
+	c.Assert(got[0].Event.EventNumber, Equals, 500)
+
+	// About log2(1000) reads to find the start and one read past the end.
+	c.Assert(ts.count() < 25, Equals, true, Commentf("%d events read", ts.count()))
+}
+
+func (s *TimeWindowSuite) TestReadCategoryTimeWindowWithoutStreams(c *C) {
+	mux.HandleFunc("/streams/$category-none/", http.NotFound)
+
+	got, err := client.ReadCategoryTimeWindow("none", windowStart, windowStart.Add(time.Hour))
+	c.Assert(err, IsNil)
+	c.Assert(got, HasLen, 0)
+}
+
+func (s *TimeWindowSuite) TestEventTime(c *C) {
+	t, ok := EventTime(&EventResponse{Updated: Time(windowStart)})
+	c.Assert(ok, Equals, true)
+	c.Assert(t.Equal(windowStart), Equals, true)
+
+	_, ok = EventTime(&EventResponse{Updated: "not a time"})
+	c.Assert(ok, Equals, false)
+}
+
+func (s *TimeWindowSuite) TestReadFeedSince(c *C) {
+	offsets := make([]int, 200)
+	for i := range offsets {
+		offsets[i] = i - 100
+	}
+	ts := serveTimedStream(c, "since-1", minutes(offsets...))
+
+	reader, err := client.ReadFeedSince("since-1", windowStart.Add(-30*time.Second))
+	c.Assert(err, IsNil)
+	c.Assert(reader.Position(), Equals, 100)
+	c.Assert(ts.count() < 15, Equals, true, Commentf("%d events read", ts.count()))
+
+	numbers := []int{}
+	for reader.Next() {
+		if reader.Err() != nil {
+			break
+		}
+		numbers = append(numbers, reader.EventResponse().Event.EventNumber)
+	}
+	c.Assert(reader.Err(), FitsTypeOf, &ErrNoMoreEvents{})
+	c.Assert(numbers, HasLen, 100)
+	c.Assert(numbers[0], Equals, 100)
+	c.Assert(numbers[99], Equals, 199)
+}
+
+func (s *TimeWindowSuite) TestReadFeedSinceAfterHead(c *C) {
+	serveTimedStream(c, "since-2", minutes(-3, -2, -1))
+
+	reader, err := client.ReadFeedSince("since-2", windowStart)
+	c.Assert(err, IsNil)
+	c.Assert(reader.Position(), Equals, 3)
+
+	mux.HandleFunc("/streams/since-missing/", http.NotFound)
+	reader, err = client.ReadFeedSince("since-missing", windowStart)
+	c.Assert(err, IsNil)
+	c.Assert(reader.Next(), Equals, true)
+	c.Assert(reader.Err(), FitsTypeOf, &ErrNotFound{})
+}
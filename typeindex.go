@@ -0,0 +1,177 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// typeIndexEntry is the data of an event in a type index stream.
+type typeIndexEntry struct {
+	EventNumber int `json:"eventNumber"`
+}
+
+// TypeIndexStreamName returns the name of the stream that indexes the events of
+// eventType in the stream.
+func TypeIndexStreamName(stream, eventType string) string {
+	return fmt.Sprintf("typeindex-%s-%s", eventType, stream)
+}
+
+// EnableTypeIndex causes the writer to maintain an index of the events appended
+// to the stream by event type.
+//
+// For each event appended an entry containing the event number is appended to
+// the index stream for the event type named by TypeIndexStreamName. Readers can
+// then use ForEachEventOfType to read only the events of particular types without
+// scanning the whole stream. The index only contains events appended by writers
+// with the index enabled.
+//
+// The index is written after the events, so it is possible for the events to be
+// written and the index update to fail. In this case Append returns an
+// *ErrTypeIndex.
+func (s *StreamWriter) EnableTypeIndex() {
+	s.typeIndex = true
+}
+
+// writeTypeIndex appends index entries for events written in the response.
+func (s *StreamWriter) writeTypeIndex(resp *Response, events []*Event) error {
+	first, ok := locationVersion(resp)
+	if !ok {
+		return &ErrTypeIndex{Err: fmt.Errorf("No event number in response location")}
+	}
+
+	entries := make(map[string][]*Event)
+	types := []string{}
+	for i, e := range events {
+		if _, ok := entries[e.EventType]; !ok {
+			types = append(types, e.EventType)
+		}
+		entry := NewEvent("", e.EventType, &typeIndexEntry{EventNumber: first + i}, nil)
+		entries[e.EventType] = append(entries[e.EventType], entry)
+	}
+
+	for _, t := range types {
+		name := TypeIndexStreamName(s.streamName, t)
+		if err := s.client.NewStreamWriter(name).Append(ExpectAny, entries[t]...); err != nil {
+			return &ErrTypeIndex{Err: err}
+		}
+	}
+	return nil
+}
+
+// locationVersion returns the event number at the end of the Location header
+// of an append response, which is the number of the first event written.
+func locationVersion(resp *Response) (int, bool) {
+	if resp == nil || resp.Response == nil {
+		return -1, false
+	}
+	loc := strings.TrimRight(resp.Header.Get("Location"), "/")
+	if loc == "" {
+		return -1, false
+	}
+	v, err := strconv.Atoi(loc[strings.LastIndex(loc, "/")+1:])
+	if err != nil {
+		return -1, false
+	}
+	return v, true
+}
+
+// ForEachEventOfType calls fn in stream order for each event in the stream with
+// one of the event types provided, using the type index of the stream.
+//
+// Only the index streams of the event types and the matching events are read.
+// Events that are in the index but are no longer in the stream, for example
+// because they were removed by $maxCount, are skipped. If fn returns an error
+// the iteration stops and the error is returned.
+//
+// Index entries are expected to be in event number order, which is the case
+// when appends to the stream are not made concurrently. See
+// StreamWriter.EnableTypeIndex.
+func (c *Client) ForEachEventOfType(stream string, eventTypes []string, fn func(*EventResponse) error) error {
+	cursors := make([]*typeIndexCursor, 0, len(eventTypes))
+	for _, t := range eventTypes {
+		cur := &typeIndexCursor{
+			client: c,
+			reader: c.NewStreamReader(TypeIndexStreamName(stream, t)),
+		}
+		if err := cur.advance(); err != nil {
+			return err
+		}
+		cursors = append(cursors, cur)
+	}
+
+	last := -1
+	for {
+		var next *typeIndexCursor
+		for _, cur := range cursors {
+			if cur.done {
+				continue
+			}
+			if next == nil || cur.head < next.head {
+				next = cur
+			}
+		}
+		if next == nil {
+			return nil
+		}
+
+		n := next.head
+		if err := next.advance(); err != nil {
+			return err
+		}
+		if n == last {
+			continue
+		}
+		last = n
+
+		e, _, err := c.GetEvent(fmt.Sprintf("/streams/%s/%d", stream, n))
+		if err != nil {
+			if _, ok := err.(*ErrNotFound); ok {
+				continue
+			}
+			return err
+		}
+		if e == nil {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// typeIndexCursor reads the entries of a type index stream in order.
+type typeIndexCursor struct {
+	client *Client
+	reader *StreamReader
+	head   int
+	done   bool
+}
+
+// advance moves the cursor to the next entry in the index.
+func (cur *typeIndexCursor) advance() error {
+	if !cur.reader.Next() {
+		cur.done = true
+		return nil
+	}
+	switch cur.reader.Err().(type) {
+	case nil:
+	case *ErrNoMoreEvents, *ErrNotFound:
+		cur.done = true
+		return nil
+	default:
+		return cur.reader.Err()
+	}
+
+	entry := &typeIndexEntry{}
+	if err := cur.reader.Scan(entry, nil); err != nil {
+		return err
+	}
+	cur.head = entry.EventNumber
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&TypeIndexSuite{})
+
+type TypeIndexSuite struct{}
+
+func (s *TypeIndexSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *TypeIndexSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *TypeIndexSuite) TestAppendWritesTypeIndex(c *C) {
+	mux.HandleFunc("/streams/policy-1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", server.URL+"/streams/policy-1/10")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	indexed := make(map[string][]int)
+	mux.HandleFunc("/streams/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/streams/")
+		var entries []struct {
+			EventType string          `json:"eventType"`
+			Data      *typeIndexEntry `json:"data"`
+		}
+		c.Assert(json.NewDecoder(r.Body).Decode(&entries), IsNil)
+		for _, e := range entries {
+			c.Assert(name, Equals, TypeIndexStreamName("policy-1", e.EventType))
+			indexed[e.EventType] = append(indexed[e.EventType], e.Data.EventNumber)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	writer := client.NewStreamWriter("policy-1")
+	writer.EnableTypeIndex()
+	err := writer.Append(ExpectAny,
+		NewEvent("", "PolicyIssued", nil, nil),
+		NewEvent("", "PolicyRenewed", nil, nil),
+		NewEvent("", "PolicyRenewed", nil, nil),
+		NewEvent("", "PolicyCancelled", nil, nil),
+	)
+	c.Assert(err, IsNil)
+	c.Assert(indexed, DeepEquals, map[string][]int{
+		"PolicyIssued":    {10},
+		"PolicyRenewed":   {11, 12},
+		"PolicyCancelled": {13},
+	})
+}
+
+func (s *TypeIndexSuite) TestAppendWithoutLocationReturnsErrTypeIndex(c *C) {
+	mux.HandleFunc("/streams/policy-2", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	writer := client.NewStreamWriter("policy-2")
+	writer.EnableTypeIndex()
+	err := writer.Append(ExpectAny, NewEvent("", "PolicyIssued", nil, nil))
+	c.Assert(err, FitsTypeOf, &ErrTypeIndex{})
+}
+
+// serveTypeIndex serves a type index stream containing the event numbers.
+func serveTypeIndex(stream, eventType string, numbers ...int) {
+	name := TypeIndexStreamName(stream, eventType)
+	es := make([]*Event, len(numbers))
+	for i, n := range numbers {
+		es[i] = CreateTestEventFromData(name, server.URL, i, &typeIndexEntry{EventNumber: n}, nil)
+	}
+	sim := newTestSimulator(es, nil)
+	mux.Handle("/streams/"+name, sim)
+	mux.Handle("/streams/"+name+"/", sim)
+}
+
+func (s *TypeIndexSuite) TestForEachEventOfTypeMergesIndexes(c *C) {
+	es := CreateTestEvents(100, "policy-3", server.URL, "PolicyRenewed")
+	setupSimulator(es, nil)
+	serveTypeIndex("policy-3", "PolicyCancelled", 12, 57, 80)
+	serveTypeIndex("policy-3", "PolicyLapsed", 3, 57, 99)
+	mux.HandleFunc("/streams/"+TypeIndexStreamName("policy-3", "NeverIndexed")+"/", http.NotFound)
+
+	got := []int{}
+	err := client.ForEachEventOfType("policy-3", []string{"PolicyCancelled", "PolicyLapsed", "NeverIndexed"}, func(e *EventResponse) error {
+		got = append(got, e.Event.EventNumber)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, []int{3, 12, 57, 80, 99})
+}
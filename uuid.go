@@ -0,0 +1,79 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"github.com/jetbasrawi/go.geteventstore/internal/uuid"
+)
+
+// UUID is a universally unique identifier, such as the id of an event.
+//
+// The zero value is the nil UUID, which is not a valid event id. A UUID is
+// written to JSON as a string in the canonical form returned by String.
+type UUID [16]byte
+
+// NilUUID is the UUID with all bits set to zero.
+var NilUUID = UUID{}
+
+// NamespaceURL is the namespace for names that are URLs, for use with
+// NewUUIDv5.
+var NamespaceURL = UUID(uuid.NamespaceURL)
+
+// NewUUIDv4 returns a new random UUID.
+func NewUUIDv4() UUID {
+	return UUID(uuid.NewV4())
+}
+
+// NewUUIDv5 returns the UUID derived from the namespace and name with SHA-1. The
+// same namespace and name always return the same UUID, which makes it suitable
+// for the id of an event that may be written more than once, so that the
+// server can recognise the duplicate.
+func NewUUIDv5(namespace UUID, name string) UUID {
+	return UUID(uuid.NewV5(uuid.UUID(namespace), name))
+}
+
+// ParseUUID parses a UUID in the canonical form
+// "6ba7b810-9dad-11d1-80b4-00c04fd430c8", optionally enclosed in braces or
+// prefixed with "urn:uuid:". If s is not a UUID an *ErrInvalidUUID is returned.
+func ParseUUID(s string) (UUID, error) {
+	u, err := uuid.FromString(s)
+	if err != nil {
+		return NilUUID, &ErrInvalidUUID{Value: s}
+	}
+	return UUID(u), nil
+}
+
+// Version returns the version of the UUID, which is 4 for random UUIDs and 5
+// for UUIDs returned by NewUUIDv5.
+func (u UUID) Version() int {
+	return int(uuid.UUID(u).Version())
+}
+
+// String returns the canonical form of the UUID.
+func (u UUID) String() string {
+	return uuid.UUID(u).String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (u *UUID) UnmarshalText(text []byte) error {
+	v, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// validEventID reports whether id can be used as the id of an event.
+func validEventID(id string) bool {
+	u, err := ParseUUID(id)
+	return err == nil && u != NilUUID
+}
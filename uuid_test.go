@@ -0,0 +1,105 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&UUIDSuite{})
+
+type UUIDSuite struct{}
+
+func (s *UUIDSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *UUIDSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+func (s *UUIDSuite) TestParseUUID(c *C) {
+	u, err := ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	c.Assert(err, IsNil)
+	c.Assert(u.String(), Equals, "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	c.Assert(u.Version(), Equals, 1)
+
+	u, err = ParseUUID("{6ba7b810-9dad-11d1-80b4-00c04fd430c8}")
+	c.Assert(err, IsNil)
+	c.Assert(u.String(), Equals, "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	for _, bad := range []string{"", "order-1", "6ba7b8109dad11d180b400c04fd430c8", "6ba7b810-9dad-11d1-80b4-00c04fd430zz"} {
+		_, err := ParseUUID(bad)
+		c.Assert(err, DeepEquals, &ErrInvalidUUID{Value: bad})
+	}
+}
+
+func (s *UUIDSuite) TestNewUUIDs(c *C) {
+	u := NewUUIDv4()
+	c.Assert(u.Version(), Equals, 4)
+	c.Assert(u, Not(Equals), NewUUIDv4())
+
+	v := NewUUIDv5(NamespaceURL, "http://example.com/orders/1")
+	c.Assert(v.Version(), Equals, 5)
+	c.Assert(v, Equals, NewUUIDv5(NamespaceURL, "http://example.com/orders/1"))
+	c.Assert(v, Not(Equals), NewUUIDv5(NamespaceURL, "http://example.com/orders/2"))
+
+	parsed, err := ParseUUID(NewUUID())
+	c.Assert(err, IsNil)
+	c.Assert(parsed.Version(), Equals, 4)
+}
+
+func (s *UUIDSuite) TestJSON(c *C) {
+	in := struct{ ID UUID }{NewUUIDv4()}
+	b, err := json.Marshal(in)
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"ID":"`+in.ID.String()+`"}`)
+
+	out := struct{ ID UUID }{}
+	c.Assert(json.Unmarshal(b, &out), IsNil)
+	c.Assert(out.ID, Equals, in.ID)
+	c.Assert(json.Unmarshal([]byte(`{"ID":"nope"}`), &out), NotNil)
+}
+
+func (s *UUIDSuite) TestNewEventWithID(c *C) {
+	id := NewUUIDv4()
+	e, err := NewEventWithID(id, "Foo", nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(e.EventID, Equals, id.String())
+	got, err := e.UUID()
+	c.Assert(err, IsNil)
+	c.Assert(got, Equals, id)
+
+	_, err = NewEventWithID(NilUUID, "Foo", nil, nil)
+	c.Assert(err, DeepEquals, &ErrInvalidUUID{Value: NilUUID.String()})
+}
+
+func (s *UUIDSuite) TestNewEventRejectsInvalidID(c *C) {
+	for _, id := range []string{"order-1", NilUUID.String(), "5bfb0bd3-5d6a-4b0d-9b1e"} {
+		c.Assert(func() { NewEvent(id, "Foo", nil, nil) }, Panics, &ErrInvalidUUID{Value: id})
+	}
+
+	id := NewUUID()
+	c.Assert(NewEvent(id, "Foo", nil, nil).EventID, Equals, id)
+	c.Assert(validEventID(NewEvent("", "Foo", nil, nil).EventID), Equals, true)
+}
+
+func (s *UUIDSuite) TestInvalidEventIDIsNotWritten(c *C) {
+	stream := "uuid-invalid"
+	mux.HandleFunc("/streams/"+stream, func(w http.ResponseWriter, r *http.Request) {
+		c.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+
+	valid := NewEvent("", "Foo", nil, nil)
+	for _, id := range []string{"order-1", NilUUID.String()} {
+		err := client.NewStreamWriter(stream).Append(ExpectAny, valid, &Event{EventID: id, EventType: "Foo"})
+		c.Assert(err, DeepEquals, &ErrInvalidUUID{Value: id})
+	}
+	_, err := client.AppendToStream(stream, []*Event{{EventType: "Foo"}})
+	c.Assert(err, ErrorMatches, `"" is not a valid UUID\.`)
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Window is a range of event numbers in a stream, From to To inclusive.
+//
+// Windows are planned with PlanWindows and read with ReadWindow. A window can be
+// serialized as JSON so that it can be handed to a worker in another process.
+type Window struct {
+	Stream string `json:"stream"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+}
+
+// Count returns the number of event numbers in the window.
+func (w Window) Count() int {
+	return w.To - w.From + 1
+}
+
+// PlanWindows divides the events in a stream, from the start of the stream to
+// its current head, into windows of windowSize event numbers.
+//
+// The windows do not overlap and together cover every event number up to the
+// head, so that a large stream can be processed as independent tasks, for
+// example by a pool of workers or a batch job. The last window may be smaller
+// than windowSize. Events written after the windows are planned are not
+// covered; plan again from the end of the last window to process them.
+//
+// Windows are ranges of event numbers, so a window of a stream that has been
+// truncated, or from which events have expired, may contain fewer events than
+// its Count. If the stream has no events no windows are returned.
+func (c *Client) PlanWindows(stream string, windowSize int) ([]Window, error) {
+	if windowSize < 1 {
+		return nil, fmt.Errorf("Invalid window size %d. The window size must be at least 1", windowSize)
+	}
+	head, err := c.GetStreamHeadVersion(stream)
+	if err != nil {
+		if _, ok := err.(*ErrNoEvents); ok {
+			return []Window{}, nil
+		}
+		return nil, err
+	}
+
+	ret := make([]Window, 0, head/windowSize+1)
+	for from := 0; from <= head; from += windowSize {
+		to := from + windowSize - 1
+		if to > head {
+			to = head
+		}
+		ret = append(ret, Window{Stream: stream, From: from, To: to})
+	}
+	return ret, nil
+}
+
+// ReadWindow reads the events in the window in order and calls fn for each.
+//
+// Reading stops at the end of the window, so no event outside it is delivered
+// and no page beyond it is requested. The page size can be set with
+// WithPageSize; pages are never larger than the part of the window that
+// remains. Events can be filtered by type with WithEventTypes. If fn returns an
+// error reading stops and the error is returned.
+func (c *Client) ReadWindow(w Window, fn func(*EventResponse) error, opts ...ReadOption) error {
+	o := newReadOptions(opts)
+	ctx := o.context()
+	from := w.From
+	if from < 0 {
+		from = 0
+	}
+	for from <= w.To {
+		size := w.To - from + 1
+		if size > o.pageSize {
+			size = o.pageSize
+		}
+		u, err := c.GetFeedPath(w.Stream, "forward", from, size)
+		if err != nil {
+			return err
+		}
+		f, _, err := c.readFeed(ctx, u)
+		if err != nil {
+			return err
+		}
+		if len(f.Entry) == 0 {
+			return nil
+		}
+		start := from
+
+		// Entries are ordered most recent first.
+		for i := len(f.Entry) - 1; i >= 0; i-- {
+			href := strings.TrimRight(f.Entry[i].Link[1].Href, "/")
+			n, err := strconv.Atoi(href[strings.LastIndex(href, "/")+1:])
+			if err != nil {
+				return err
+			}
+			if n > w.To {
+				return nil
+			}
+			if o.eventTypes.skipsEntry(f.Entry[i]) {
+				from = n + 1
+				continue
+			}
+			e, err := c.entryEvent(ctx, f.Entry[i])
+			if err != nil {
+				return err
+			}
+			if e != nil && !o.eventTypes.skipsEvent(e) {
+				if err := fn(e); err != nil {
+					return err
+				}
+			}
+			from = n + 1
+		}
+		if from == start {
+			return nil
+		}
+	}
+	return nil
+}
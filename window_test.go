@@ -0,0 +1,122 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&WindowSuite{})
+
+type WindowSuite struct{}
+
+func (s *WindowSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *WindowSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// readWindow returns the event numbers read from the window.
+func readWindow(c *C, w Window, opts ...ReadOption) []int {
+	got := []int{}
+	err := client.ReadWindow(w, func(er *EventResponse) error {
+		got = append(got, er.Event.EventNumber)
+		return nil
+	}, opts...)
+	c.Assert(err, IsNil)
+	return got
+}
+
+func (s *WindowSuite) TestPlanWindows(c *C) {
+	stream := "windowed"
+	setupSimulator(CreateTestEvents(25, stream, server.URL, "Foo"), nil)
+
+	ws, err := client.PlanWindows(stream, 10)
+	c.Assert(err, IsNil)
+	c.Assert(ws, DeepEquals, []Window{
+		{Stream: stream, From: 0, To: 9},
+		{Stream: stream, From: 10, To: 19},
+		{Stream: stream, From: 20, To: 24},
+	})
+	c.Assert(ws[2].Count(), Equals, 5)
+}
+
+func (s *WindowSuite) TestPlanWindowsOfMissingStream(c *C) {
+	mux.HandleFunc("/streams/missing/", http.NotFound)
+	_, err := client.PlanWindows("missing", 10)
+	c.Assert(err, FitsTypeOf, &ErrNotFound{})
+}
+
+func (s *WindowSuite) TestPlanWindowsInvalidSize(c *C) {
+	_, err := client.PlanWindows("windowed", 0)
+	c.Assert(err, ErrorMatches, "Invalid window size 0.*")
+}
+
+func (s *WindowSuite) TestWindowsCoverStreamExactly(c *C) {
+	stream := "windowed"
+	setupSimulator(CreateTestEvents(25, stream, server.URL, "Foo"), nil)
+
+	ws, err := client.PlanWindows(stream, 7)
+	c.Assert(err, IsNil)
+	got := []int{}
+	for _, w := range ws {
+		got = append(got, readWindow(c, w, WithPageSize(3))...)
+	}
+	c.Assert(got, DeepEquals, sequence(25))
+}
+
+func (s *WindowSuite) TestReadWindowDoesNotRequestBeyondWindow(c *C) {
+	stream := "windowed"
+	paths := serveRecordingPaths(CreateTestEvents(30, stream, server.URL, "Foo"))
+
+	got := readWindow(c, Window{Stream: stream, From: 5, To: 11}, WithPageSize(5))
+	c.Assert(got, DeepEquals, []int{5, 6, 7, 8, 9, 10, 11})
+	c.Assert(paths(), DeepEquals, []string{
+		"/streams/windowed/5/forward/5",
+		"/streams/windowed/10/forward/2",
+	})
+}
+
+func (s *WindowSuite) TestReadWindowPastHead(c *C) {
+	stream := "windowed"
+	setupSimulator(CreateTestEvents(8, stream, server.URL, "Foo"), nil)
+
+	got := readWindow(c, Window{Stream: stream, From: 5, To: 14})
+	c.Assert(got, DeepEquals, []int{5, 6, 7})
+}
+
+func (s *WindowSuite) TestReadWindowHandlerError(c *C) {
+	stream := "windowed"
+	setupSimulator(CreateTestEvents(8, stream, server.URL, "Foo"), nil)
+
+	errStop := errors.New("stop")
+	count := 0
+	err := client.ReadWindow(Window{Stream: stream, From: 0, To: 7}, func(er *EventResponse) error {
+		count++
+		if count == 3 {
+			return errStop
+		}
+		return nil
+	})
+	c.Assert(err, Equals, errStop)
+	c.Assert(count, Equals, 3)
+}
+
+func (s *WindowSuite) TestWindowJSON(c *C) {
+	b, err := json.Marshal(Window{Stream: "orders", From: 10, To: 19})
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(b), `"from":10`), Equals, true)
+
+	w := Window{}
+	c.Assert(json.Unmarshal(b, &w), IsNil)
+	c.Assert(w, Equals, Window{Stream: "orders", From: 10, To: 19})
+}
@@ -0,0 +1,56 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+// eventsContentType is the media type of the body of a write.
+const eventsContentType = "application/vnd.eventstore.events+json"
+
+// writeEvent is an element of the application/vnd.eventstore.events+json array
+// posted to a stream. It carries only the fields the server accepts for a
+// write, so that the stream, number and links of an event that was read are
+// not sent back.
+type writeEvent struct {
+	EventID   string      `json:"eventId"`
+	EventType string      `json:"eventType"`
+	Data      interface{} `json:"data"`
+	MetaData  interface{} `json:"metadata,omitempty"`
+}
+
+// newWriteEvents returns the body of a write of the events.
+//
+// Every event must have a valid UUID as its id and an event type. An
+// *ErrInvalidUUID or an *ErrInvalidEvent is returned otherwise, before anything
+// is sent to the server. An event with nil data is written with null data.
+func newWriteEvents(events []*Event) ([]*writeEvent, error) {
+	ws := make([]*writeEvent, len(events))
+	for i, e := range events {
+		if err := validateWrite(i, e); err != nil {
+			return nil, err
+		}
+		ws[i] = &writeEvent{
+			EventID:   e.EventID,
+			EventType: e.EventType,
+			Data:      e.Data,
+			MetaData:  e.MetaData,
+		}
+	}
+	return ws, nil
+}
+
+// validateWrite checks that the event at index i of a write has the fields the
+// server requires.
+func validateWrite(i int, e *Event) error {
+	if e == nil {
+		return &ErrInvalidEvent{Index: i, Field: "event"}
+	}
+	if !validEventID(e.EventID) {
+		return &ErrInvalidUUID{Value: e.EventID}
+	}
+	if e.EventType == "" {
+		return &ErrInvalidEvent{Index: i, Field: "eventType"}
+	}
+	return nil
+}
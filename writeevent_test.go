@@ -0,0 +1,94 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&WriteEventSuite{})
+
+type WriteEventSuite struct{}
+
+func (s *WriteEventSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *WriteEventSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// decodeWrittenMetaData decodes the single event of a metadata write and
+// unmarshals its data into v.
+func decodeWrittenMetaData(c *C, r *http.Request, v interface{}) {
+	c.Assert(r.Header.Get("Content-Type"), Equals, eventsContentType)
+	body := []map[string]json.RawMessage{}
+	c.Assert(json.NewDecoder(r.Body).Decode(&body), IsNil)
+	c.Assert(body, HasLen, 1)
+	c.Assert(string(body[0]["eventType"]), Equals, `"MetaData"`)
+	c.Assert(json.Unmarshal(body[0]["data"], v), IsNil)
+}
+
+func (s *WriteEventSuite) TestAppendPostsEventsJSONArray(c *C) {
+	var got []map[string]json.RawMessage
+	mux.HandleFunc("/streams/events-json", func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Header.Get("Content-Type"), Equals, eventsContentType)
+		c.Assert(json.NewDecoder(r.Body).Decode(&got), IsNil)
+		w.Header().Set("Location", server.URL+"/streams/events-json/0")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	read := CreateTestEvent("other-stream", server.URL, "Read", 7, &json.RawMessage{'1'}, nil)
+	es := []*Event{
+		NewEvent("", "Foo", map[string]string{"foo": "bar"}, map[string]string{"baz": "boo"}),
+		NewEvent("", "Bar", nil, nil),
+		read,
+	}
+	err := client.NewStreamWriter("events-json").Append(ExpectAny, es...)
+	c.Assert(err, IsNil)
+
+	c.Assert(got, HasLen, 3)
+	c.Assert(got[0], DeepEquals, map[string]json.RawMessage{
+		"eventId":   json.RawMessage(`"` + es[0].EventID + `"`),
+		"eventType": json.RawMessage(`"Foo"`),
+		"data":      json.RawMessage(`{"foo":"bar"}`),
+		"metadata":  json.RawMessage(`{"baz":"boo"}`),
+	})
+	c.Assert(string(got[1]["data"]), Equals, "null")
+	c.Assert(got[1]["metadata"], IsNil)
+
+	// Only the fields of a write are sent for an event that was read.
+	for _, k := range []string{"eventStreamId", "eventNumber", "links"} {
+		_, ok := got[2][k]
+		c.Assert(ok, Equals, false, Commentf("%s", k))
+	}
+	c.Assert(string(got[2]["eventType"]), Equals, `"Read"`)
+}
+
+func (s *WriteEventSuite) TestAppendRejectsEventsWithoutRequiredFields(c *C) {
+	requests := 0
+	mux.HandleFunc("/streams/invalid-writes", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	writer := client.NewStreamWriter("invalid-writes")
+
+	err := writer.Append(ExpectAny, NewEvent("", "Foo", nil, nil), &Event{EventID: NewUUID()})
+	c.Assert(err, DeepEquals, &ErrInvalidEvent{Index: 1, Field: "eventType"})
+	c.Assert(err, ErrorMatches, "Event 1 of the write has no eventType.")
+
+	err = writer.Append(ExpectAny, &Event{EventType: "Foo"})
+	c.Assert(err, DeepEquals, &ErrInvalidUUID{Value: ""})
+
+	err = writer.Append(ExpectAny, NewEvent("", "Foo", nil, nil), nil)
+	c.Assert(err, DeepEquals, &ErrInvalidEvent{Index: 1, Field: "event"})
+
+	c.Assert(requests, Equals, 0)
+}
@@ -0,0 +1,178 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultWriterIdleTimeout is the time a stream writer in a WriterPool may be
+// idle before it is stopped.
+const defaultWriterIdleTimeout = time.Minute
+
+// WriterPool serializes writes to many streams.
+//
+// The pool owns one writer goroutine for each stream that is being written to.
+// Appends to the same stream are written one at a time in the order they are
+// received by the pool, while appends to different streams are written
+// concurrently. Writer goroutines are stopped when they have been idle for the
+// idle timeout so the number of goroutines is bounded by the number of streams
+// being actively written to.
+//
+// A WriterPool is safe for concurrent use.
+type WriterPool struct {
+	client      *Client
+	mu          sync.Mutex
+	writers     map[string]*poolWriter
+	idleTimeout time.Duration
+	closed      bool
+	quit        chan struct{}
+	wg          sync.WaitGroup
+	reporter    ErrorReporter
+}
+
+// poolWriter is the writer goroutine for a single stream.
+type poolWriter struct {
+	stream  string
+	writer  *StreamWriter
+	queue   chan *poolRequest
+	pending int
+}
+
+// poolRequest is a request to append events to a stream.
+type poolRequest struct {
+	expectedVersion ExpectedVersion
+	events          []*Event
+	done            chan error
+}
+
+// NewWriterPool returns a new *WriterPool.
+func (c *Client) NewWriterPool() *WriterPool {
+	return &WriterPool{
+		client:      c,
+		writers:     make(map[string]*poolWriter),
+		idleTimeout: defaultWriterIdleTimeout,
+		quit:        make(chan struct{}),
+	}
+}
+
+// SetIdleTimeout sets the time a stream writer may be idle before it is stopped.
+//
+// The timeout applies to writers started after the call.
+func (p *WriterPool) SetIdleTimeout(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idleTimeout = d
+}
+
+// Append writes events to the head of the stream and blocks until the write
+// has completed.
+//
+// Appends to the same stream are written in the order they are received. The
+// expectedVersion and the error returned have the same meaning as for
+// StreamWriter.Append.
+func (p *WriterPool) Append(stream string, expectedVersion ExpectedVersion, events ...*Event) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return fmt.Errorf("WriterPool is closed")
+	}
+	w, ok := p.writers[stream]
+	if !ok {
+		w = &poolWriter{
+			stream: stream,
+			writer: p.client.NewStreamWriter(stream),
+			queue:  make(chan *poolRequest),
+		}
+		p.writers[stream] = w
+		p.wg.Add(1)
+		go p.run(w, p.idleTimeout)
+	}
+	w.pending++
+	p.mu.Unlock()
+
+	req := &poolRequest{
+		expectedVersion: expectedVersion,
+		events:          events,
+		done:            make(chan error, 1),
+	}
+	w.queue <- req
+	return <-req.done
+}
+
+// Len returns the number of stream writers that are running.
+func (p *WriterPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.writers)
+}
+
+// Close stops the pool.
+//
+// Appends that were received before Close was called are completed before Close
+// returns. Appends made after Close is called return an error.
+func (p *WriterPool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.quit)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *WriterPool) run(w *poolWriter, idle time.Duration) {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(idle)
+	defer timer.Stop()
+
+	quit := p.quit
+	for {
+		select {
+		case req := <-w.queue:
+			err := w.writer.Append(req.expectedVersion, req.events...)
+			if err != nil {
+				p.reporter.Report("WriterPool writer for "+w.stream, err, false)
+			}
+			req.done <- err
+			p.mu.Lock()
+			w.pending--
+			p.mu.Unlock()
+
+			if quit == nil && p.evict(w) {
+				return
+			}
+			timer.Reset(idle)
+
+		case <-timer.C:
+			if p.evict(w) {
+				return
+			}
+			timer.Reset(idle)
+
+		case <-quit:
+			if p.evict(w) {
+				return
+			}
+			quit = nil
+		}
+	}
+}
+
+// evict removes the writer from the pool if it has no pending requests. The
+// writer goroutine must exit if evict returns true.
+func (p *WriterPool) evict(w *poolWriter) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if w.pending > 0 {
+		return false
+	}
+	delete(p.writers, w.stream)
+	return true
+}
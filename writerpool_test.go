@@ -0,0 +1,127 @@
+// Copyright 2016 Jet Basrawi. All rights reserved.
+//
+// Use of this source code is governed by a permissive BSD 3 Clause License
+// that can be found in the license file.
+
+package goes
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+var _ = Suite(&WriterPoolSuite{})
+
+type WriterPoolSuite struct{}
+
+func (s *WriterPoolSuite) SetUpTest(c *C) {
+	setup()
+}
+func (s *WriterPoolSuite) TearDownTest(c *C) {
+	teardown()
+}
+
+// Test that appends to the same stream are never written concurrently while
+// appends to different streams are.
+func (s *WriterPoolSuite) TestAppendsAreSerializedPerStream(c *C) {
+	var inflight, maxInflight, total int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInflight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInflight, m, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		atomic.AddInt32(&inflight, -1)
+		atomic.AddInt32(&total, 1)
+		w.WriteHeader(http.StatusCreated)
+	}
+	mux.HandleFunc("/streams/pool-stream", handler)
+
+	pool := client.NewWriterPool()
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check(pool.Append("pool-stream", ExpectAny, NewEvent("", "Foo", nil, nil)), IsNil)
+		}()
+	}
+	wg.Wait()
+
+	c.Assert(atomic.LoadInt32(&total), Equals, int32(10))
+	c.Assert(atomic.LoadInt32(&maxInflight), Equals, int32(1))
+}
+
+func (s *WriterPoolSuite) TestDifferentStreamsAreWrittenConcurrently(c *C) {
+	release := make(chan struct{})
+	mux.HandleFunc("/streams/blocked", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/streams/free", func(w http.ResponseWriter, r *http.Request) {
+		close(release)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	pool := client.NewWriterPool()
+	defer pool.Close()
+
+	done := make(chan error)
+	go func() { done <- pool.Append("blocked", ExpectAny, NewEvent("", "Foo", nil, nil)) }()
+
+	c.Assert(pool.Append("free", ExpectAny, NewEvent("", "Foo", nil, nil)), IsNil)
+	c.Assert(<-done, IsNil)
+}
+
+func (s *WriterPoolSuite) TestAppendReturnsWriteErrors(c *C) {
+	mux.HandleFunc("/streams/conflict", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	pool := client.NewWriterPool()
+	defer pool.Close()
+
+	v := 5
+	err := pool.Append("conflict", ExpectedVersion(v), NewEvent("", "Foo", nil, nil))
+	c.Assert(err, FitsTypeOf, &ErrConcurrencyViolation{})
+}
+
+func (s *WriterPoolSuite) TestIdleWritersAreEvicted(c *C) {
+	for i := 0; i < 3; i++ {
+		mux.HandleFunc(fmt.Sprintf("/streams/idle-%d", i), func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+	}
+
+	pool := client.NewWriterPool()
+	pool.SetIdleTimeout(20 * time.Millisecond)
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		c.Assert(pool.Append(fmt.Sprintf("idle-%d", i), ExpectAny, NewEvent("", "Foo", nil, nil)), IsNil)
+	}
+	c.Assert(pool.Len() > 0, Equals, true)
+
+	eventually(func() bool { return pool.Len() == 0 })
+	c.Assert(pool.Len(), Equals, 0)
+
+	c.Assert(pool.Append("idle-0", ExpectAny, NewEvent("", "Foo", nil, nil)), IsNil)
+}
+
+func (s *WriterPoolSuite) TestAppendAfterCloseReturnsError(c *C) {
+	pool := client.NewWriterPool()
+	pool.Close()
+
+	err := pool.Append("closed", ExpectAny, NewEvent("", "Foo", nil, nil))
+	c.Assert(err, ErrorMatches, "WriterPool is closed")
+}